@@ -0,0 +1,306 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// NewField describes a field to add with Workspace.AddField.
+type NewField struct {
+	Name string
+	// The field's type, as it is written in the field declaration: a scalar
+	// type, such as "string", the name of a message or enum, which is
+	// resolved relative to the message like other type names, or a map type,
+	// such as "map<string, Other>".
+	Type string
+	// The field's cardinality. The zero value is the same as
+	// protoreflect.Optional. Map fields must use the zero value.
+	Cardinality protoreflect.Cardinality
+	// If true and the file uses proto3, an optional field is declared with
+	// the "optional" keyword so that it has explicit presence. In proto2,
+	// optional fields are always declared with the keyword, and in files
+	// that use editions, presence is controlled by features instead.
+	ExplicitPresence bool
+	// The field's number. If zero, the next free number is chosen: the one
+	// after the largest number used by the message's fields and reserved
+	// ranges, skipping numbers in extension ranges and in the range reserved
+	// for the protobuf implementation.
+	Number protoreflect.FieldNumber
+}
+
+// FieldOptionTemplate is a set of options that are added to new fields, such
+// as options that an organization requires on all fields of its APIs.
+type FieldOptionTemplate struct {
+	// The options, as they are written in the field's compact options, such
+	// as "(google.api.field_behavior) = REQUIRED".
+	Options []string
+	// The files that must be imported to use the options.
+	Imports []string
+	// If not nil, the template only applies to new fields for which Match
+	// returns true.
+	Match func(msg protoreflect.MessageDescriptor, field NewField) bool
+}
+
+// AddField returns the edit that inserts a new field into the message with the
+// given name. The field is declared after the message's last element, with the
+// same indentation. The options of the templates that match the field are
+// added to its declaration, and imports are added to the message's file for
+// the field's type and for the templates' options, if needed.
+func (w *Workspace) AddField(msgName protoreflect.FullName, field NewField, templates ...FieldOptionTemplate) (*WorkspaceEdit, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var md protoreflect.MessageDescriptor
+	for _, f := range w.files {
+		if d, ok := f.FindDescriptorByName(msgName).(protoreflect.MessageDescriptor); ok {
+			md = d
+			break
+		}
+	}
+	if md == nil {
+		return nil, fmt.Errorf("message %s not found", msgName)
+	}
+	path := ResolvedPath(md.ParentFile().Path())
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil, fmt.Errorf("%s: %w", path, linker.ErrNoAST)
+	}
+	msgNode, ok := res.Node(protoutil.ProtoFromMessageDescriptor(md)).(*ast.MessageNode)
+	if !ok || msgNode.GetOpenBrace() == nil || msgNode.GetCloseBrace() == nil {
+		return nil, fmt.Errorf("%s is not declared by a complete message declaration", msgName)
+	}
+	if !protoreflect.Name(field.Name).IsValid() {
+		return nil, fmt.Errorf("%q is not a valid identifier", field.Name)
+	}
+	if md.Fields().ByName(protoreflect.Name(field.Name)) != nil {
+		return nil, fmt.Errorf("message %s already has a field named %s", msgName, field.Name)
+	}
+
+	// the type that must be resolved, which for a map is its value type
+	typeName := field.Type
+	isMap := strings.HasPrefix(field.Type, "map<")
+	if isMap {
+		if field.Cardinality != 0 && field.Cardinality != protoreflect.Optional {
+			return nil, fmt.Errorf("map fields cannot be %s", field.Cardinality)
+		}
+		key, value, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(field.Type, "map<"), ">"), ",")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || !strings.HasSuffix(field.Type, ">") || value == "" {
+			return nil, fmt.Errorf("%s is not a valid map type", field.Type)
+		}
+		if _, ok := protointernal.FieldTypes[key]; !ok || key == "float" || key == "double" || key == "bytes" {
+			return nil, fmt.Errorf("%s is not a valid map key type", key)
+		}
+		typeName = value
+	}
+	var imports []string
+	if _, ok := protointernal.FieldTypes[typeName]; !ok {
+		typ := w.resolveTypeName(md.FullName(), typeName)
+		if typ == nil {
+			return nil, fmt.Errorf("type %s not found", typeName)
+		}
+		imports = append(imports, typ.ParentFile().Path())
+	}
+
+	number := field.Number
+	if number == 0 {
+		number = nextFieldNumber(md)
+		if number == 0 {
+			return nil, fmt.Errorf("message %s has no free field numbers", msgName)
+		}
+	} else if number < 1 || number > maxFieldNumber(md) ||
+		(number >= protointernal.SpecialReservedStart && number <= protointernal.SpecialReservedEnd) {
+		return nil, fmt.Errorf("field number %d is not a valid field number", number)
+	} else if md.Fields().ByNumber(number) != nil || md.ReservedRanges().Has(number) || md.ExtensionRanges().Has(number) {
+		return nil, fmt.Errorf("field number %d is already used in message %s", number, msgName)
+	}
+
+	var label string
+	switch field.Cardinality {
+	case protoreflect.Repeated:
+		label = "repeated"
+	case protoreflect.Required:
+		if res.Syntax() != protoreflect.Proto2 {
+			return nil, fmt.Errorf("required fields are only allowed in proto2")
+		}
+		label = "required"
+	default:
+		if isMap {
+			break
+		}
+		if res.Syntax() == protoreflect.Proto2 || (res.Syntax() == protoreflect.Proto3 && field.ExplicitPresence) {
+			label = "optional"
+		}
+	}
+	var options []string
+	for _, tmpl := range templates {
+		if tmpl.Match == nil || tmpl.Match(md, field) {
+			options = append(options, tmpl.Options...)
+			imports = append(imports, tmpl.Imports...)
+		}
+	}
+	var sb strings.Builder
+	if label != "" {
+		sb.WriteString(label + " ")
+	}
+	fmt.Fprintf(&sb, "%s %s = %d", field.Type, field.Name, number)
+	if len(options) > 0 {
+		sb.WriteString(" [" + strings.Join(options, ", ") + "]")
+	}
+	sb.WriteByte(';')
+
+	file := res.AST()
+	edit := &WorkspaceEdit{}
+	edit.addEdit(path, appendToMessage(file, msgNode, sb.String()))
+
+	seen := map[string]struct{}{}
+	for _, imp := range imports {
+		if _, ok := seen[imp]; ok || imp == string(path) || importsFile(res, imp) {
+			continue
+		}
+		seen[imp] = struct{}{}
+		edit.addEdit(path, transform.Insert(file, res.ImportInsertionPoint().Offset+1, fmt.Sprintf("\nimport %q;", imp)))
+	}
+	return edit, nil
+}
+
+// appendToMessage returns the edit that inserts the given declarations after
+// the last element of the given message, with the same indentation, or as its
+// first elements, indented one level more than the message. The message must
+// have both of its braces.
+func appendToMessage(file *ast.FileNode, msgNode *ast.MessageNode, decls ...string) transform.TextEdit {
+	elems := msgNode.GetDecls()
+	if len(elems) > 0 {
+		last := file.NodeInfo(elems[len(elems)-1])
+		end := last.Start().Offset + len(last.RawText())
+		if comments := last.TrailingComments(); comments.Len() > 0 {
+			end = comments.Index(comments.Len()-1).End().Offset + 1
+		}
+		indent := lineIndent(last)
+		return transform.Insert(file, end, "\n"+indent+strings.Join(decls, "\n"+indent))
+	}
+	msgIndent := lineIndent(file.NodeInfo(msgNode))
+	open := file.NodeInfo(msgNode.GetOpenBrace())
+	indent := msgIndent + "  "
+	text := "\n" + indent + strings.Join(decls, "\n"+indent)
+	if file.NodeInfo(msgNode.GetCloseBrace()).Start().Line == open.Start().Line {
+		text += "\n" + msgIndent
+	}
+	return transform.Insert(file, open.Start().Offset+1, text)
+}
+
+// resolveTypeName returns the message or enum that the given type name refers
+// to when used in the given scope, such as the message that declares a field,
+// or nil if there is none. Unlike the linker, this considers all files in the
+// workspace, not only those visible to the scope's file, so that the caller
+// can add the missing import. w.mu must be held.
+func (w *Workspace) resolveTypeName(scope protoreflect.FullName, name string) protoreflect.Descriptor {
+	find := func(fullName protoreflect.FullName) protoreflect.Descriptor {
+		for _, f := range w.files {
+			switch d := f.FindDescriptorByName(fullName).(type) {
+			case protoreflect.MessageDescriptor, protoreflect.EnumDescriptor:
+				return d
+			}
+		}
+		return nil
+	}
+	if strings.HasPrefix(name, ".") {
+		return find(protoreflect.FullName(name[1:]))
+	}
+	for ; ; scope = scope.Parent() {
+		candidate := protoreflect.FullName(name)
+		if scope != "" {
+			candidate = scope + "." + candidate
+		}
+		if d := find(candidate); d != nil {
+			return d
+		}
+		if scope == "" {
+			return nil
+		}
+	}
+}
+
+// nextFieldNumber returns the number after the largest one that is used by a
+// field or reserved range of md, skipping extension ranges and the range that
+// is reserved for the implementation. It returns zero if there is no such
+// number.
+func nextFieldNumber(md protoreflect.MessageDescriptor) protoreflect.FieldNumber {
+	var largest protoreflect.FieldNumber
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		largest = max(largest, fields.Get(i).Number())
+	}
+	reserved := md.ReservedRanges()
+	for i := 0; i < reserved.Len(); i++ {
+		// the end of a range is exclusive
+		largest = max(largest, reserved.Get(i)[1]-1)
+	}
+	maxTag := maxFieldNumber(md)
+	for number := largest + 1; number <= maxTag; number++ {
+		if number >= protointernal.SpecialReservedStart && number <= protointernal.SpecialReservedEnd {
+			number = protointernal.SpecialReservedEnd
+			continue
+		}
+		extensions := md.ExtensionRanges()
+		if i := rangeIndex(extensions, number); i >= 0 {
+			number = extensions.Get(i)[1] - 1
+			continue
+		}
+		return number
+	}
+	return 0
+}
+
+// maxFieldNumber returns the largest field number that md's fields may use,
+// which is higher for messages that use the message-set wire format.
+func maxFieldNumber(md protoreflect.MessageDescriptor) protoreflect.FieldNumber {
+	if opts, _ := md.Options().(*descriptorpb.MessageOptions); opts.GetMessageSetWireFormat() {
+		return protointernal.MaxMessageSetTag
+	}
+	return protointernal.MaxNormalTag
+}
+
+// rangeIndex returns the index of the range in ranges that contains number,
+// or -1 if there is none.
+func rangeIndex(ranges protoreflect.FieldRanges, number protoreflect.FieldNumber) int {
+	for i := 0; i < ranges.Len(); i++ {
+		if r := ranges.Get(i); number >= r[0] && number < r[1] {
+			return i
+		}
+	}
+	return -1
+}
+
+// lineIndent returns the whitespace that precedes the given element on its
+// line.
+func lineIndent(info ast.NodeInfo) string {
+	ws := info.LeadingWhitespace()
+	if i := strings.LastIndexByte(ws, '\n'); i >= 0 {
+		return ws[i+1:]
+	}
+	return ""
+}
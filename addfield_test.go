@@ -0,0 +1,149 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast/transform"
+)
+
+func TestWorkspaceAddField(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Msg {
+  message Inner {
+    optional string name = 1; // the name
+    reserved 2 to 5;
+    extensions 6 to 10;
+  }
+  message Empty {}
+}
+extend google.protobuf.FieldOptions { optional bool sensitive = 50000; }
+`,
+		"b.proto": `syntax = "proto3";
+package foo.bar;
+message Other {}
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(sources)),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto"))
+
+	apply := func(edit *WorkspaceEdit, path ResolvedPath) string {
+		return string(transform.ApplyEdits([]byte(sources[UnresolvedPath(path)]), edit.Edits[path]))
+	}
+	sensitive := FieldOptionTemplate{
+		Options: []string{"(foo.sensitive) = true"},
+		Match: func(_ protoreflect.MessageDescriptor, field NewField) bool {
+			return field.Type == "string"
+		},
+	}
+
+	edit, err := ws.AddField("foo.Msg.Inner", NewField{Name: "other", Type: "bar.Other", Cardinality: protoreflect.Repeated}, sensitive)
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+import "b.proto";
+message Msg {
+  message Inner {
+    optional string name = 1; // the name
+    reserved 2 to 5;
+    extensions 6 to 10;
+    repeated bar.Other other = 11;
+  }
+  message Empty {}
+}
+extend google.protobuf.FieldOptions { optional bool sensitive = 50000; }
+`, apply(edit, "a.proto"))
+
+	edit, err = ws.AddField("foo.Msg.Empty", NewField{Name: "label", Type: "string"}, sensitive)
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Msg {
+  message Inner {
+    optional string name = 1; // the name
+    reserved 2 to 5;
+    extensions 6 to 10;
+  }
+  message Empty {
+    optional string label = 1 [(foo.sensitive) = true];
+  }
+}
+extend google.protobuf.FieldOptions { optional bool sensitive = 50000; }
+`, apply(edit, "a.proto"))
+
+	edit, err = ws.AddField("foo.bar.Other", NewField{Name: "id", Type: "int64", ExplicitPresence: true, Number: 5})
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+package foo.bar;
+message Other {
+  optional int64 id = 5;
+}
+`, apply(edit, "b.proto"))
+
+	edit, err = ws.AddField("foo.Msg.Empty", NewField{Name: "others", Type: "map<string, bar.Other>"})
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+import "b.proto";
+message Msg {
+  message Inner {
+    optional string name = 1; // the name
+    reserved 2 to 5;
+    extensions 6 to 10;
+  }
+  message Empty {
+    map<string, bar.Other> others = 1;
+  }
+}
+extend google.protobuf.FieldOptions { optional bool sensitive = 50000; }
+`, apply(edit, "a.proto"))
+
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "name", Type: "string"})
+	assert.ErrorContains(t, err, "already has a field named name")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "string", Number: 3})
+	assert.ErrorContains(t, err, "field number 3 is already used")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "string", Number: 19000})
+	assert.ErrorContains(t, err, "not a valid field number")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "string", Number: 536870912})
+	assert.ErrorContains(t, err, "not a valid field number")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "Missing"})
+	assert.ErrorContains(t, err, "type Missing not found")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "map<string, Missing>"})
+	assert.ErrorContains(t, err, "type Missing not found")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "map<bar.Other, string>"})
+	assert.ErrorContains(t, err, "not a valid map key type")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "x", Type: "map<string, string>", Cardinality: protoreflect.Repeated})
+	assert.ErrorContains(t, err, "map fields cannot be repeated")
+	_, err = ws.AddField("foo.Msg.Inner", NewField{Name: "1x", Type: "string"})
+	assert.ErrorContains(t, err, `"1x" is not a valid identifier`)
+	_, err = ws.AddField("foo.bar.Other", NewField{Name: "x", Type: "string", Cardinality: protoreflect.Required})
+	assert.ErrorContains(t, err, "only allowed in proto2")
+}
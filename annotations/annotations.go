@@ -0,0 +1,412 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package annotations provides typed accessors for a curated set of widely
+// used options, such as google.api.http and google.api.field_behavior. The
+// accessors read the interpreted options of compiled descriptors, so they do
+// not require generated Go code for the options to be linked in, and they
+// return the source span of each option when it is available.
+// ReportDeprecations builds on these to list deprecated elements and their
+// usages across a set of files.
+//
+// Spans come from the file's source code info, if present, or else from the
+// file's AST, if the file is a linker.Result whose AST was retained. Otherwise
+// spans are unknown.
+package annotations
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+const (
+	httpOptionName          = protoreflect.FullName("google.api.http")
+	fieldBehaviorOptionName = protoreflect.FullName("google.api.field_behavior")
+)
+
+// HTTPRule is the value of the google.api.http method option, which maps an
+// RPC method to an HTTP endpoint.
+type HTTPRule struct {
+	// The HTTP method, such as "GET" or "POST". For custom rules, this is the
+	// custom method's kind.
+	Method string
+	// The URL path template.
+	Path string
+	// The request field that is mapped to the HTTP request body, "*" for
+	// the whole request, or empty if there is no body.
+	Body string
+	// The response field that is mapped to the HTTP response body, or empty
+	// for the whole response.
+	ResponseBody string
+	// Additional bindings for the method. These do not themselves have
+	// additional bindings.
+	AdditionalBindings []HTTPRule
+	// The span of the option. Additional bindings have the same span as the
+	// rule that contains them.
+	Span ast.SourceSpan
+}
+
+// GetHTTPRule returns the google.api.http option of the given method, and
+// false if the option is not set.
+func GetHTTPRule(mtd protoreflect.MethodDescriptor) (HTTPRule, bool) {
+	fld, val, ok := findOption(mtd, httpOptionName)
+	if !ok || fld.Message() == nil {
+		return HTTPRule{}, false
+	}
+	span := optionSpan(mtd, fld)
+	rule := httpRuleFromMessage(val.Message(), span)
+	if bindings := messageField(val.Message(), "additional_bindings"); bindings != nil {
+		list := val.Message().Get(bindings).List()
+		for i := 0; i < list.Len(); i++ {
+			rule.AdditionalBindings = append(rule.AdditionalBindings, httpRuleFromMessage(list.Get(i).Message(), span))
+		}
+	}
+	return rule, true
+}
+
+var httpMethods = []struct {
+	field  protoreflect.Name
+	method string
+}{
+	{"get", "GET"},
+	{"put", "PUT"},
+	{"post", "POST"},
+	{"delete", "DELETE"},
+	{"patch", "PATCH"},
+}
+
+func httpRuleFromMessage(msg protoreflect.Message, span ast.SourceSpan) HTTPRule {
+	rule := HTTPRule{
+		Body:         stringField(msg, "body"),
+		ResponseBody: stringField(msg, "response_body"),
+		Span:         span,
+	}
+	for _, m := range httpMethods {
+		if fld := messageField(msg, m.field); fld != nil && msg.Has(fld) {
+			rule.Method, rule.Path = m.method, msg.Get(fld).String()
+			return rule
+		}
+	}
+	if fld := messageField(msg, "custom"); fld != nil && msg.Has(fld) {
+		custom := msg.Get(fld).Message()
+		rule.Method, rule.Path = stringField(custom, "kind"), stringField(custom, "path")
+	}
+	return rule
+}
+
+// FieldBehavior is a value of the google.api.field_behavior field option.
+type FieldBehavior int32
+
+// The values of the google.api.FieldBehavior enum.
+const (
+	FieldBehaviorUnspecified     = FieldBehavior(0)
+	FieldBehaviorOptional        = FieldBehavior(1)
+	FieldBehaviorRequired        = FieldBehavior(2)
+	FieldBehaviorOutputOnly      = FieldBehavior(3)
+	FieldBehaviorInputOnly       = FieldBehavior(4)
+	FieldBehaviorImmutable       = FieldBehavior(5)
+	FieldBehaviorUnorderedList   = FieldBehavior(6)
+	FieldBehaviorNonEmptyDefault = FieldBehavior(7)
+	FieldBehaviorIdentifier      = FieldBehavior(8)
+)
+
+var fieldBehaviorNames = map[FieldBehavior]string{
+	FieldBehaviorUnspecified:     "FIELD_BEHAVIOR_UNSPECIFIED",
+	FieldBehaviorOptional:        "OPTIONAL",
+	FieldBehaviorRequired:        "REQUIRED",
+	FieldBehaviorOutputOnly:      "OUTPUT_ONLY",
+	FieldBehaviorInputOnly:       "INPUT_ONLY",
+	FieldBehaviorImmutable:       "IMMUTABLE",
+	FieldBehaviorUnorderedList:   "UNORDERED_LIST",
+	FieldBehaviorNonEmptyDefault: "NON_EMPTY_DEFAULT",
+	FieldBehaviorIdentifier:      "IDENTIFIER",
+}
+
+func (b FieldBehavior) String() string {
+	if name, ok := fieldBehaviorNames[b]; ok {
+		return name
+	}
+	return "FieldBehavior(" + strconv.Itoa(int(b)) + ")"
+}
+
+// FieldBehaviors is the value of the google.api.field_behavior option.
+type FieldBehaviors struct {
+	Behaviors []FieldBehavior
+	// The span of the option. If the option is set by multiple declarations,
+	// this is the span of the first one.
+	Span ast.SourceSpan
+}
+
+// Has returns true if b includes the given behavior.
+func (b FieldBehaviors) Has(behavior FieldBehavior) bool {
+	for _, v := range b.Behaviors {
+		if v == behavior {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFieldBehaviors returns the google.api.field_behavior option of the given
+// field, and false if the option is not set.
+func GetFieldBehaviors(fld protoreflect.FieldDescriptor) (FieldBehaviors, bool) {
+	optFld, val, ok := findOption(fld, fieldBehaviorOptionName)
+	if !ok || !optFld.IsList() || optFld.Kind() != protoreflect.EnumKind {
+		return FieldBehaviors{}, false
+	}
+	list := val.List()
+	behaviors := FieldBehaviors{Span: optionSpan(fld, optFld)}
+	for i := 0; i < list.Len(); i++ {
+		behaviors.Behaviors = append(behaviors.Behaviors, FieldBehavior(list.Get(i).Enum()))
+	}
+	return behaviors, true
+}
+
+// Deprecation describes an element that is marked as deprecated with the
+// standard "deprecated" option.
+type Deprecation struct {
+	// The deprecation message, which is the text that follows "Deprecated:"
+	// in the element's leading comments, with surrounding whitespace removed.
+	// This is empty if there is no such text or if comments are unavailable.
+	Message string
+	// The span of the "deprecated" option.
+	Span ast.SourceSpan
+}
+
+// GetDeprecation returns the deprecation of the given element, and false if it
+// is not deprecated. Elements that cannot be deprecated, such as oneofs,
+// are never deprecated.
+func GetDeprecation(d protoreflect.Descriptor) (Deprecation, bool) {
+	opts, ok := d.Options().(interface{ GetDeprecated() bool })
+	if !ok || !opts.GetDeprecated() {
+		return Deprecation{}, false
+	}
+	optsMsg := d.Options().ProtoReflect()
+	fld := optsMsg.Descriptor().Fields().ByName("deprecated")
+	return Deprecation{
+		Message: deprecationMessage(d),
+		Span:    optionSpan(d, fld),
+	}, true
+}
+
+func deprecationMessage(d protoreflect.Descriptor) string {
+	var comments string
+	if loc := d.ParentFile().SourceLocations().ByDescriptor(d); !protointernal.IsZeroSourceLocation(loc) {
+		comments = loc.LeadingComments
+	} else if res, node := astNode(d); node != nil {
+		leading := res.FileNode().NodeInfo(node).LeadingComments()
+		var sb strings.Builder
+		for i := 0; i < leading.Len(); i++ {
+			sb.WriteString(leading.Index(i).RawText())
+			sb.WriteByte('\n')
+		}
+		comments = sb.String()
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+		if msg, ok := strings.CutPrefix(line, "Deprecated:"); ok {
+			return strings.TrimSpace(msg)
+		}
+	}
+	return ""
+}
+
+// findOption returns the value of the option with the given name on the given
+// element.
+func findOption(d protoreflect.Descriptor, name protoreflect.FullName) (protoreflect.FieldDescriptor, protoreflect.Value, bool) {
+	var fld protoreflect.FieldDescriptor
+	var val protoreflect.Value
+	d.Options().ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.FullName() == name {
+			fld, val = fd, v
+			return false
+		}
+		return true
+	})
+	return fld, val, fld != nil
+}
+
+// optionSpan returns the span of the option declaration that sets the given
+// field of d's options.
+func optionSpan(d protoreflect.Descriptor, fld protoreflect.FieldDescriptor) ast.SourceSpan {
+	file := d.ParentFile()
+	if path, ok := descpath.ForDescriptor(d); ok {
+		if tag := optionsTag(d); tag != 0 {
+			optPath := append(path, tag, int32(fld.Number()))
+			if loc, ok := findSourceLocation(file.SourceLocations(), optPath); ok {
+				return locationSpan(file, loc)
+			}
+		}
+	}
+	if res, node := astNode(d); node != nil {
+		for _, opt := range optionNodes(node) {
+			if opt.IsIncomplete() {
+				continue
+			}
+			ref := opt.Name.Parts[0].GetFieldRef()
+			if ref == nil {
+				continue
+			}
+			if optFld := res.FindFieldDescriptorByFieldReferenceNode(ref); optFld != nil && optFld.FullName() == fld.FullName() {
+				return res.FileNode().NodeInfo(opt)
+			}
+		}
+	}
+	return ast.UnknownSpan(file.Path())
+}
+
+// locationSpan converts the given source location of file into a span.
+func locationSpan(file protoreflect.FileDescriptor, loc protoreflect.SourceLocation) ast.SourceSpan {
+	// source locations are zero-based
+	return ast.NewSourceSpan(
+		ast.SourcePos{Filename: file.Path(), Line: loc.StartLine + 1, Col: loc.StartColumn + 1},
+		ast.SourcePos{Filename: file.Path(), Line: loc.EndLine + 1, Col: loc.EndColumn + 1},
+	)
+}
+
+// findSourceLocation returns the location with the given path or, if there is
+// none, the first location whose path starts with it. The latter is the case
+// for options that set a field of a message option or an element of a
+// repeated option.
+func findSourceLocation(locs protoreflect.SourceLocations, path protoreflect.SourcePath) (protoreflect.SourceLocation, bool) {
+	if loc := locs.ByPath(path); !protointernal.IsZeroSourceLocation(loc) {
+		return loc, true
+	}
+	for i := 0; i < locs.Len(); i++ {
+		loc := locs.Get(i)
+		if len(loc.Path) > len(path) && slices.Equal(loc.Path[:len(path)], path) {
+			return loc, true
+		}
+	}
+	return protoreflect.SourceLocation{}, false
+}
+
+// astNode returns the AST node for d, if d's file is a linker.Result whose
+// AST is available.
+func astNode(d protoreflect.Descriptor) (linker.Result, ast.Node) {
+	res, ok := d.ParentFile().(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil, nil
+	}
+	if _, isFile := d.(protoreflect.FileDescriptor); isFile {
+		return res, res.AST()
+	}
+	node := res.Node(protoutil.ProtoFromDescriptor(d))
+	if node == nil {
+		return nil, nil
+	}
+	return res, node
+}
+
+// optionNodes returns the option declarations of the element with the given
+// AST node.
+func optionNodes(node ast.Node) []*ast.OptionNode {
+	var opts []*ast.OptionNode
+	switch node := node.(type) {
+	case *ast.FileNode:
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case *ast.MessageNode:
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case *ast.GroupNode:
+		// The same node is used for the group's field and message. Since
+		// the field and message options are different types, returning
+		// both is not ambiguous.
+		opts = append(opts, node.GetOptions().GetOptions()...)
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case *ast.OneofNode:
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case *ast.EnumNode:
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case *ast.ServiceNode:
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case *ast.RPCNode:
+		for _, decl := range node.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				opts = append(opts, opt)
+			}
+		}
+	case interface {
+		GetOptions() *ast.CompactOptionsNode
+	}:
+		opts = append(opts, node.GetOptions().GetOptions()...)
+	}
+	return opts
+}
+
+func optionsTag(d protoreflect.Descriptor) int32 {
+	switch d.(type) {
+	case protoreflect.FileDescriptor:
+		return descpath.FileOptionsTag
+	case protoreflect.MessageDescriptor:
+		return descpath.MessageOptionsTag
+	case protoreflect.FieldDescriptor:
+		return descpath.FieldOptionsTag
+	case protoreflect.OneofDescriptor:
+		return descpath.OneofOptionsTag
+	case protoreflect.EnumDescriptor:
+		return descpath.EnumOptionsTag
+	case protoreflect.EnumValueDescriptor:
+		return descpath.EnumValOptionsTag
+	case protoreflect.ServiceDescriptor:
+		return descpath.ServiceOptionsTag
+	case protoreflect.MethodDescriptor:
+		return descpath.MethodOptionsTag
+	}
+	return 0
+}
+
+func messageField(msg protoreflect.Message, name protoreflect.Name) protoreflect.FieldDescriptor {
+	return msg.Descriptor().Fields().ByName(name)
+}
+
+func stringField(msg protoreflect.Message, name protoreflect.Name) string {
+	fld := messageField(msg, name)
+	if fld == nil || fld.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return msg.Get(fld).String()
+}
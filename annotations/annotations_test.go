@@ -0,0 +1,160 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/annotations"
+	"github.com/kralicky/protocompile/linker"
+)
+
+var sources = map[string]string{
+	"google/api/http.proto": `
+		syntax = "proto3";
+		package google.api;
+		message HttpRule {
+			string selector = 1;
+			oneof pattern {
+				string get = 2;
+				string put = 3;
+				string post = 4;
+				string delete = 5;
+				string patch = 6;
+				CustomHttpPattern custom = 8;
+			}
+			string body = 7;
+			string response_body = 12;
+			repeated HttpRule additional_bindings = 11;
+		}
+		message CustomHttpPattern {
+			string kind = 1;
+			string path = 2;
+		}
+		`,
+	"google/api/annotations.proto": `
+		syntax = "proto3";
+		package google.api;
+		import "google/api/http.proto";
+		import "google/protobuf/descriptor.proto";
+		extend google.protobuf.MethodOptions {
+			HttpRule http = 72295728;
+		}
+		`,
+	"google/api/field_behavior.proto": `
+		syntax = "proto3";
+		package google.api;
+		import "google/protobuf/descriptor.proto";
+		extend google.protobuf.FieldOptions {
+			repeated google.api.FieldBehavior field_behavior = 1052 [packed = false];
+		}
+		enum FieldBehavior {
+			FIELD_BEHAVIOR_UNSPECIFIED = 0;
+			OPTIONAL = 1;
+			REQUIRED = 2;
+			OUTPUT_ONLY = 3;
+		}
+		`,
+	"test.proto": `
+		syntax = "proto3";
+		package foo;
+		import "google/api/annotations.proto";
+		import "google/api/field_behavior.proto";
+		message Req {
+			string name = 1 [(google.api.field_behavior) = REQUIRED, (google.api.field_behavior) = OUTPUT_ONLY];
+			// Deprecated: use name instead.
+			string id = 2 [deprecated = true];
+		}
+		service Foo {
+			rpc Get(Req) returns (Req) {
+				option (google.api.http) = {
+					get: "/v1/{name=things/*}"
+					additional_bindings { post: "/v1/things:get" body: "*" }
+				};
+			}
+			rpc Custom(Req) returns (Req) {
+				option (google.api.http).custom = { kind: "HEAD" path: "/v1/things" };
+			}
+			rpc Plain(Req) returns (Req);
+		}
+		`,
+}
+
+func TestAnnotations(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name     string
+		compiler *protocompile.Compiler
+	}{
+		{name: "source info", compiler: &protocompile.Compiler{SourceInfoMode: protocompile.SourceInfoStandard}},
+		{name: "ast", compiler: &protocompile.Compiler{RetainASTs: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.compiler.Resolver = protocompile.WithStandardImports(&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(sources),
+			})
+			files, err := tc.compiler.Compile(context.Background(), "test.proto")
+			require.NoError(t, err)
+			checkAnnotations(t, files.Files[0])
+		})
+	}
+}
+
+func checkAnnotations(t *testing.T, file linker.File) {
+	t.Helper()
+	methods := file.Services().ByName("Foo").Methods()
+
+	rule, ok := annotations.GetHTTPRule(methods.ByName("Get"))
+	require.True(t, ok)
+	assert.Equal(t, "GET", rule.Method)
+	assert.Equal(t, "/v1/{name=things/*}", rule.Path)
+	assert.Equal(t, "test.proto:13:5", rule.Span.Start().String())
+	require.Len(t, rule.AdditionalBindings, 1)
+	assert.Equal(t, "POST", rule.AdditionalBindings[0].Method)
+	assert.Equal(t, "/v1/things:get", rule.AdditionalBindings[0].Path)
+	assert.Equal(t, "*", rule.AdditionalBindings[0].Body)
+
+	rule, ok = annotations.GetHTTPRule(methods.ByName("Custom"))
+	require.True(t, ok)
+	assert.Equal(t, "HEAD", rule.Method)
+	assert.Equal(t, "/v1/things", rule.Path)
+	assert.Equal(t, "test.proto:19:5", rule.Span.Start().String())
+
+	_, ok = annotations.GetHTTPRule(methods.ByName("Plain"))
+	assert.False(t, ok)
+
+	fields := file.Messages().ByName("Req").Fields()
+	behaviors, ok := annotations.GetFieldBehaviors(fields.ByName("name"))
+	require.True(t, ok)
+	assert.Equal(t, []annotations.FieldBehavior{annotations.FieldBehaviorRequired, annotations.FieldBehaviorOutputOnly}, behaviors.Behaviors)
+	assert.True(t, behaviors.Has(annotations.FieldBehaviorRequired))
+	assert.False(t, behaviors.Has(annotations.FieldBehaviorImmutable))
+	assert.Equal(t, "test.proto:7:21", behaviors.Span.Start().String())
+	_, ok = annotations.GetFieldBehaviors(fields.ByName("id"))
+	assert.False(t, ok)
+
+	deprecation, ok := annotations.GetDeprecation(fields.ByName("id"))
+	require.True(t, ok)
+	assert.Equal(t, "use name instead.", deprecation.Message)
+	assert.Equal(t, "test.proto:9:19", deprecation.Span.Start().String())
+	_, ok = annotations.GetDeprecation(fields.ByName("name"))
+	assert.False(t, ok)
+}
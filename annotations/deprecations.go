@@ -0,0 +1,291 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// DeprecationReport lists deprecated elements and their usages. See
+// ReportDeprecations.
+type DeprecationReport struct {
+	// The deprecated elements that are declared in the given files.
+	Deprecated []DeprecatedElement
+	// The usages of deprecated elements in the given files. The elements
+	// that are used may be declared in any file, including files that were
+	// not given, such as dependencies.
+	Usages []DeprecatedUsage
+}
+
+// UsagesOf returns the usages of the given element in the report.
+func (r DeprecationReport) UsagesOf(d protoreflect.Descriptor) []DeprecatedUsage {
+	var usages []DeprecatedUsage
+	for _, u := range r.Usages {
+		if u.Element.FullName() == d.FullName() && u.Element.ParentFile().Path() == d.ParentFile().Path() {
+			usages = append(usages, u)
+		}
+	}
+	return usages
+}
+
+// DeprecatedElement is an element that is marked as deprecated.
+type DeprecatedElement struct {
+	Descriptor protoreflect.Descriptor
+	Deprecation
+}
+
+// UsageKind describes how a deprecated element is used.
+type UsageKind int
+
+const (
+	// UsageImport is an import of a deprecated file.
+	UsageImport UsageKind = iota + 1
+	// UsageFieldType is a field whose type is a deprecated message or enum.
+	// For a map field, this is the type of its value.
+	UsageFieldType
+	// UsageExtendee is an extension of a deprecated message.
+	UsageExtendee
+	// UsageDefaultValue is a field whose default value is a deprecated enum
+	// value.
+	UsageDefaultValue
+	// UsageMethodInput is a method whose request type is a deprecated
+	// message.
+	UsageMethodInput
+	// UsageMethodOutput is a method whose response type is a deprecated
+	// message.
+	UsageMethodOutput
+	// UsageOption is an option that sets a deprecated field of an options
+	// message, which may be a custom option or a standard one, or a
+	// deprecated field of a message in an option's name or value.
+	UsageOption
+	// UsageOptionValue is an option whose value is, or contains in a message
+	// literal, a deprecated enum value.
+	UsageOptionValue
+)
+
+func (k UsageKind) String() string {
+	switch k {
+	case UsageImport:
+		return "import"
+	case UsageFieldType:
+		return "field type"
+	case UsageExtendee:
+		return "extendee"
+	case UsageDefaultValue:
+		return "default value"
+	case UsageMethodInput:
+		return "method input"
+	case UsageMethodOutput:
+		return "method output"
+	case UsageOption:
+		return "option"
+	case UsageOptionValue:
+		return "option value"
+	default:
+		return "unknown"
+	}
+}
+
+// DeprecatedUsage is a usage of a deprecated element.
+type DeprecatedUsage struct {
+	// The deprecated element that is used.
+	Element protoreflect.Descriptor
+	// The element whose declaration contains the usage. For UsageImport,
+	// this is the importing file.
+	User protoreflect.Descriptor
+	Kind UsageKind
+	// The span of the usage. If source code info is not available, this is
+	// the span of the user's whole declaration, when its AST is available.
+	Span ast.SourceSpan
+}
+
+// ReportDeprecations lists every deprecated element declared in the given
+// files and every usage of a deprecated element in the given files. Both
+// lists are ordered by file, in the order given, and then by the order of
+// declarations in each file. Files that appear more than once are only
+// reported once.
+//
+// Usages inside options, other than the fields that options set directly,
+// are only reported for files whose AST is available. These are deprecated
+// fields in option names and message literals, and deprecated enum values
+// in option values.
+func ReportDeprecations(files linker.Files) DeprecationReport {
+	var report DeprecationReport
+	seen := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		if _, ok := seen[file.Path()]; ok {
+			continue
+		}
+		seen[file.Path()] = struct{}{}
+		report.addElement(file)
+		report.addUsages(file)
+		_ = walk.Descriptors(file, func(d protoreflect.Descriptor) error {
+			report.addElement(d)
+			report.addUsages(d)
+			return nil
+		})
+	}
+	return report
+}
+
+func (r *DeprecationReport) addElement(d protoreflect.Descriptor) {
+	if deprecation, ok := GetDeprecation(d); ok {
+		r.Deprecated = append(r.Deprecated, DeprecatedElement{Descriptor: d, Deprecation: deprecation})
+	}
+}
+
+func (r *DeprecationReport) addUsages(d protoreflect.Descriptor) {
+	switch d := d.(type) {
+	case protoreflect.FileDescriptor:
+		imports := d.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			r.addUsage(imports.Get(i).FileDescriptor, d, UsageImport, descpath.FileDependencyTag, int32(i))
+		}
+	case protoreflect.FieldDescriptor:
+		if msg, ok := d.Parent().(protoreflect.MessageDescriptor); ok && msg.IsMapEntry() {
+			// usages are attributed to the map field instead
+			break
+		}
+		if d.IsExtension() {
+			r.addUsage(d.ContainingMessage(), d, UsageExtendee, descpath.FieldExtendeeTag)
+		}
+		typ := d
+		if d.IsMap() {
+			typ = d.MapValue()
+		}
+		if typ.Message() != nil {
+			r.addUsage(typ.Message(), d, UsageFieldType, descpath.FieldTypeNameTag)
+		} else if typ.Enum() != nil {
+			r.addUsage(typ.Enum(), d, UsageFieldType, descpath.FieldTypeNameTag)
+		}
+		if d.Kind() == protoreflect.EnumKind && d.HasDefault() {
+			r.addUsage(d.DefaultEnumValue(), d, UsageDefaultValue, descpath.FieldDefaultTag)
+		}
+	case protoreflect.MethodDescriptor:
+		r.addUsage(d.Input(), d, UsageMethodInput, descpath.MethodInputTag)
+		r.addUsage(d.Output(), d, UsageMethodOutput, descpath.MethodOutputTag)
+	}
+
+	d.Options().ProtoReflect().Range(func(fld protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if opts, ok := fld.Options().(*descriptorpb.FieldOptions); ok && opts.GetDeprecated() {
+			r.Usages = append(r.Usages, DeprecatedUsage{Element: fld, User: d, Kind: UsageOption, Span: optionSpan(d, fld)})
+		}
+		return true
+	})
+	r.addOptionValueUsages(d)
+}
+
+// addOptionValueUsages records the usages of deprecated elements inside the
+// options of d, other than the fields of d's options message that they set.
+// These are found with the descriptors that were recorded for the options'
+// AST nodes when they were interpreted, so nothing is recorded if d's AST is
+// not available.
+func (r *DeprecationReport) addOptionValueUsages(d protoreflect.Descriptor) {
+	res, node := astNode(d)
+	if node == nil {
+		return
+	}
+	index := res.OptionDescriptorIndex()
+	for _, opt := range optionNodes(node) {
+		if opt.IsIncomplete() || len(opt.GetName().FilterFieldReferences()) == 0 || isPseudoOption(opt) {
+			continue
+		}
+		// the field set by the option was already recorded
+		first := opt.GetName().FilterFieldReferences()[0]
+		ast.Inspect(opt, func(n ast.Node) bool {
+			if n == first {
+				return true
+			}
+			if fld, ok := index.FieldReferenceNodesToFieldDescriptors[n]; ok && isDeprecated(fld) {
+				span := res.FileNode().NodeInfo(n)
+				if fldNode, ok := n.(*ast.MessageFieldNode); ok {
+					// fields of message literals are recorded for the whole
+					// "name: value" node
+					span = res.FileNode().NodeInfo(fldNode.GetName())
+				}
+				r.Usages = append(r.Usages, DeprecatedUsage{Element: fld, User: d, Kind: UsageOption, Span: span})
+			}
+			if ident, ok := n.(*ast.IdentNode); ok {
+				if val := index.EnumValueIdentNodesToEnumValueDescriptors[ident]; val != nil && isDeprecated(val) {
+					r.Usages = append(r.Usages, DeprecatedUsage{Element: val, User: d, Kind: UsageOptionValue, Span: res.FileNode().NodeInfo(n)})
+				}
+			}
+			return true
+		})
+	}
+}
+
+// isPseudoOption reports whether opt is the json_name or default option of a
+// field, which are stored in the field's descriptor instead of its options.
+func isPseudoOption(opt *ast.OptionNode) bool {
+	parts := opt.GetName().FilterFieldReferences()
+	if len(parts) != 1 || parts[0].IsExtension() {
+		return false
+	}
+	switch parts[0].GetName().AsIdentifier() {
+	case "json_name", "default":
+		return true
+	default:
+		return false
+	}
+}
+
+// isDeprecated reports whether the given element is marked as deprecated.
+func isDeprecated(d protoreflect.Descriptor) bool {
+	if d == nil || d.IsPlaceholder() {
+		return false
+	}
+	opts, ok := d.Options().(interface{ GetDeprecated() bool })
+	return ok && opts.GetDeprecated()
+}
+
+// addUsage records a usage of the given element by user, if the element is
+// deprecated. The path elements identify the usage within the user's
+// declaration in source code info.
+func (r *DeprecationReport) addUsage(element, user protoreflect.Descriptor, kind UsageKind, path ...int32) {
+	if !isDeprecated(element) {
+		return
+	}
+	r.Usages = append(r.Usages, DeprecatedUsage{Element: element, User: user, Kind: kind, Span: usageSpan(user, kind, path)})
+}
+
+func usageSpan(user protoreflect.Descriptor, kind UsageKind, path []int32) ast.SourceSpan {
+	file := user.ParentFile()
+	if userPath, ok := descpath.ForDescriptor(user); ok {
+		if loc, ok := findSourceLocation(file.SourceLocations(), append(userPath, path...)); ok {
+			return locationSpan(file, loc)
+		}
+	}
+	if res, node := astNode(user); node != nil {
+		if kind == UsageImport {
+			// the user is the whole file, so find the import statement
+			imp := file.Imports().Get(int(path[1]))
+			for _, decl := range res.AST().GetDecls() {
+				if impNode := decl.GetImport(); impNode != nil && !impNode.IsIncomplete() && impNode.Name.AsString() == imp.Path() {
+					return res.FileNode().NodeInfo(impNode)
+				}
+			}
+		} else {
+			return res.FileNode().NodeInfo(node)
+		}
+	}
+	return ast.UnknownSpan(file.Path())
+}
@@ -0,0 +1,132 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/annotations"
+)
+
+func TestReportDeprecations(t *testing.T) {
+	t.Parallel()
+	depSources := map[string]string{
+		"old.proto": `
+			syntax = "proto3";
+			package old;
+			import "google/protobuf/descriptor.proto";
+			option deprecated = true;
+			// Deprecated: use Thing instead.
+			message OldThing {
+				option deprecated = true;
+			}
+			enum Color {
+				RED = 0;
+				BLUE = 1 [deprecated = true];
+			}
+			extend google.protobuf.FieldOptions {
+				string legacy = 50001 [deprecated = true];
+				Opts opts = 50002;
+			}
+			message Opts {
+				string note = 1 [deprecated = true];
+				Color color = 2;
+			}
+			`,
+		"test.proto": `
+			syntax = "proto2";
+			package foo;
+			import "old.proto";
+			message Req {
+				optional old.OldThing thing = 1;
+				optional old.Color color = 2 [default = BLUE, (old.legacy) = "x"];
+				map<string, old.OldThing> things = 3;
+				optional string a = 4 [(old.opts) = { note: "x" color: BLUE }];
+				optional string b = 5 [(old.opts).note = "y"];
+			}
+			service Foo {
+				rpc Get(old.OldThing) returns (Req);
+			}
+			`,
+	}
+	for _, tc := range []struct {
+		name     string
+		compiler *protocompile.Compiler
+	}{
+		{name: "source info", compiler: &protocompile.Compiler{SourceInfoMode: protocompile.SourceInfoStandard}},
+		{name: "ast", compiler: &protocompile.Compiler{RetainASTs: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.compiler.Resolver = protocompile.WithStandardImports(&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(depSources),
+			})
+			files, err := tc.compiler.Compile(context.Background(), "old.proto", "test.proto", "old.proto")
+			require.NoError(t, err)
+			report := annotations.ReportDeprecations(files.Files)
+
+			var deprecated []string
+			for _, elem := range report.Deprecated {
+				deprecated = append(deprecated, string(elem.Descriptor.FullName())+"@"+elem.Span.Start().String())
+			}
+			assert.Equal(t, []string{
+				"old@old.proto:5:4",
+				"old.OldThing@old.proto:8:5",
+				"old.Opts.note@old.proto:19:22",
+				"old.BLUE@old.proto:12:15",
+				"old.legacy@old.proto:15:28",
+			}, deprecated)
+			assert.Equal(t, "use Thing instead.", report.Deprecated[1].Message)
+
+			type usage struct {
+				element, user string
+				kind          annotations.UsageKind
+				pos           string
+			}
+			var usages []usage
+			for _, u := range report.Usages {
+				usages = append(usages, usage{string(u.Element.FullName()), string(u.User.FullName()), u.Kind, u.Span.Start().String()})
+			}
+			expected := []usage{
+				{"old", "foo", annotations.UsageImport, "test.proto:4:4"},
+				{"old.OldThing", "foo.Req.thing", annotations.UsageFieldType, "test.proto:6:14"},
+				{"old.BLUE", "foo.Req.color", annotations.UsageDefaultValue, "test.proto:7:35"},
+				{"old.legacy", "foo.Req.color", annotations.UsageOption, "test.proto:7:51"},
+				{"old.OldThing", "foo.Req.things", annotations.UsageFieldType, "test.proto:8:5"},
+				{"old.OldThing", "foo.Foo.Get", annotations.UsageMethodInput, "test.proto:13:13"},
+			}
+			if tc.name == "ast" {
+				// without source info, usages span the user's whole declaration
+				expected[1].pos = "test.proto:6:5"
+				expected[2].pos = "test.proto:7:5"
+				expected[5].pos = "test.proto:13:5"
+				// usages inside options are only found with the AST
+				expected = append(expected[:5:5],
+					usage{"old.Opts.note", "foo.Req.a", annotations.UsageOption, "test.proto:9:43"},
+					usage{"old.BLUE", "foo.Req.a", annotations.UsageOptionValue, "test.proto:9:60"},
+					usage{"old.Opts.note", "foo.Req.b", annotations.UsageOption, "test.proto:10:39"},
+					expected[5],
+				)
+			}
+			assert.Equal(t, expected, usages)
+			assert.Len(t, report.UsagesOf(report.Deprecated[1].Descriptor), 3)
+		})
+	}
+}
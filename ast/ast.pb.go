@@ -2760,6 +2760,7 @@ type MessageNode struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	Visibility *IdentNode        `protobuf:"bytes,7,opt,name=visibility,proto3" json:"visibility,omitempty"`
 	Keyword    *IdentNode        `protobuf:"bytes,1,opt,name=keyword,proto3" json:"keyword,omitempty"`
 	Name       *IdentNode        `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
 	OpenBrace  *RuneNode         `protobuf:"bytes,3,opt,name=openBrace,proto3" json:"openBrace,omitempty"`
@@ -2800,6 +2801,13 @@ func (*MessageNode) Descriptor() ([]byte, []int) {
 	return file_github_com_kralicky_protocompile_ast_ast_proto_rawDescGZIP(), []int{32}
 }
 
+func (x *MessageNode) GetVisibility() *IdentNode {
+	if x != nil {
+		return x.Visibility
+	}
+	return nil
+}
+
 func (x *MessageNode) GetKeyword() *IdentNode {
 	if x != nil {
 		return x.Keyword
@@ -3569,6 +3577,7 @@ type EnumNode struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	Visibility *IdentNode     `protobuf:"bytes,7,opt,name=visibility,proto3" json:"visibility,omitempty"`
 	Keyword    *IdentNode     `protobuf:"bytes,1,opt,name=keyword,proto3" json:"keyword,omitempty"`
 	Name       *IdentNode     `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
 	OpenBrace  *RuneNode      `protobuf:"bytes,3,opt,name=openBrace,proto3" json:"openBrace,omitempty"`
@@ -3609,6 +3618,13 @@ func (*EnumNode) Descriptor() ([]byte, []int) {
 	return file_github_com_kralicky_protocompile_ast_ast_proto_rawDescGZIP(), []int{39}
 }
 
+func (x *EnumNode) GetVisibility() *IdentNode {
+	if x != nil {
+		return x.Visibility
+	}
+	return nil
+}
+
 func (x *EnumNode) GetKeyword() *IdentNode {
 	if x != nil {
 		return x.Keyword
@@ -5796,8 +5812,11 @@ var file_github_com_kralicky_protocompile_ast_ast_proto_rawDesc = []byte{
 	0x75, 0x70, 0x12, 0x2f, 0x0a, 0x08, 0x6d, 0x61, 0x70, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x03,
 	0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x61, 0x73, 0x74, 0x2e, 0x4d, 0x61, 0x70, 0x46, 0x69,
 	0x65, 0x6c, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x48, 0x00, 0x52, 0x08, 0x6d, 0x61, 0x70, 0x46, 0x69,
-	0x65, 0x6c, 0x64, 0x42, 0x05, 0x0a, 0x03, 0x76, 0x61, 0x6c, 0x22, 0x8f, 0x02, 0x0a, 0x0b, 0x4d,
-	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x28, 0x0a, 0x07, 0x6b, 0x65,
+	0x65, 0x6c, 0x64, 0x42, 0x05, 0x0a, 0x03, 0x76, 0x61, 0x6c, 0x22, 0xbf, 0x02, 0x0a, 0x0b, 0x4d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x2e, 0x0a, 0x0a, 0x76, 0x69,
+	0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e,
+	0x2e, 0x61, 0x73, 0x74, 0x2e, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x0a,
+	0x76, 0x69, 0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x28, 0x0a, 0x07, 0x6b, 0x65,
 	0x79, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x61, 0x73,
 	0x74, 0x2e, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x07, 0x6b, 0x65, 0x79,
 	0x77, 0x6f, 0x72, 0x64, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
@@ -5936,8 +5955,11 @@ var file_github_com_kralicky_protocompile_ast_ast_proto_rawDesc = []byte{
 	0x4e, 0x6f, 0x64, 0x65, 0x52, 0x0a, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x42, 0x72, 0x61, 0x63, 0x65,
 	0x12, 0x2b, 0x0a, 0x09, 0x73, 0x65, 0x6d, 0x69, 0x63, 0x6f, 0x6c, 0x6f, 0x6e, 0x18, 0x0a, 0x20,
 	0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x61, 0x73, 0x74, 0x2e, 0x52, 0x75, 0x6e, 0x65, 0x4e, 0x6f,
-	0x64, 0x65, 0x52, 0x09, 0x73, 0x65, 0x6d, 0x69, 0x63, 0x6f, 0x6c, 0x6f, 0x6e, 0x22, 0x89, 0x02,
-	0x0a, 0x08, 0x45, 0x6e, 0x75, 0x6d, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x28, 0x0a, 0x07, 0x6b, 0x65,
+	0x64, 0x65, 0x52, 0x09, 0x73, 0x65, 0x6d, 0x69, 0x63, 0x6f, 0x6c, 0x6f, 0x6e, 0x22, 0xb9, 0x02,
+	0x0a, 0x08, 0x45, 0x6e, 0x75, 0x6d, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x2e, 0x0a, 0x0a, 0x76, 0x69,
+	0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e,
+	0x2e, 0x61, 0x73, 0x74, 0x2e, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x0a,
+	0x76, 0x69, 0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x28, 0x0a, 0x07, 0x6b, 0x65,
 	0x79, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x61, 0x73,
 	0x74, 0x2e, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x07, 0x6b, 0x65, 0x79,
 	0x77, 0x6f, 0x72, 0x64, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
@@ -6367,139 +6389,141 @@ var file_github_com_kralicky_protocompile_ast_ast_proto_depIdxs = []int32{
 	34,  // 93: ast.MessageDeclNode.message:type_name -> ast.MessageNode
 	40,  // 94: ast.MessageDeclNode.group:type_name -> ast.GroupNode
 	38,  // 95: ast.MessageDeclNode.mapField:type_name -> ast.MapFieldNode
-	5,   // 96: ast.MessageNode.keyword:type_name -> ast.IdentNode
-	5,   // 97: ast.MessageNode.name:type_name -> ast.IdentNode
-	16,  // 98: ast.MessageNode.openBrace:type_name -> ast.RuneNode
-	35,  // 99: ast.MessageNode.decls:type_name -> ast.MessageElement
-	16,  // 100: ast.MessageNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 101: ast.MessageNode.semicolon:type_name -> ast.RuneNode
-	13,  // 102: ast.MessageElement.option:type_name -> ast.OptionNode
-	37,  // 103: ast.MessageElement.field:type_name -> ast.FieldNode
-	38,  // 104: ast.MessageElement.mapField:type_name -> ast.MapFieldNode
-	39,  // 105: ast.MessageElement.oneof:type_name -> ast.OneofNode
-	40,  // 106: ast.MessageElement.group:type_name -> ast.GroupNode
-	34,  // 107: ast.MessageElement.message:type_name -> ast.MessageNode
-	41,  // 108: ast.MessageElement.enum:type_name -> ast.EnumNode
-	42,  // 109: ast.MessageElement.extend:type_name -> ast.ExtendNode
-	43,  // 110: ast.MessageElement.extensionRange:type_name -> ast.ExtensionRangeNode
-	45,  // 111: ast.MessageElement.reserved:type_name -> ast.ReservedNode
-	6,   // 112: ast.MessageElement.empty:type_name -> ast.EmptyDeclNode
-	37,  // 113: ast.ExtendElement.field:type_name -> ast.FieldNode
-	40,  // 114: ast.ExtendElement.group:type_name -> ast.GroupNode
-	6,   // 115: ast.ExtendElement.empty:type_name -> ast.EmptyDeclNode
-	5,   // 116: ast.FieldNode.label:type_name -> ast.IdentNode
-	21,  // 117: ast.FieldNode.fieldType:type_name -> ast.IdentValueNode
-	5,   // 118: ast.FieldNode.name:type_name -> ast.IdentNode
-	16,  // 119: ast.FieldNode.equals:type_name -> ast.RuneNode
-	12,  // 120: ast.FieldNode.tag:type_name -> ast.UintLiteralNode
-	32,  // 121: ast.FieldNode.options:type_name -> ast.CompactOptionsNode
-	16,  // 122: ast.FieldNode.semicolon:type_name -> ast.RuneNode
-	47,  // 123: ast.MapFieldNode.mapType:type_name -> ast.MapTypeNode
-	5,   // 124: ast.MapFieldNode.name:type_name -> ast.IdentNode
-	16,  // 125: ast.MapFieldNode.equals:type_name -> ast.RuneNode
-	12,  // 126: ast.MapFieldNode.tag:type_name -> ast.UintLiteralNode
-	32,  // 127: ast.MapFieldNode.options:type_name -> ast.CompactOptionsNode
-	16,  // 128: ast.MapFieldNode.semicolon:type_name -> ast.RuneNode
-	5,   // 129: ast.OneofNode.keyword:type_name -> ast.IdentNode
-	5,   // 130: ast.OneofNode.name:type_name -> ast.IdentNode
-	16,  // 131: ast.OneofNode.openBrace:type_name -> ast.RuneNode
-	48,  // 132: ast.OneofNode.decls:type_name -> ast.OneofElement
-	16,  // 133: ast.OneofNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 134: ast.OneofNode.semicolon:type_name -> ast.RuneNode
-	5,   // 135: ast.GroupNode.label:type_name -> ast.IdentNode
-	5,   // 136: ast.GroupNode.keyword:type_name -> ast.IdentNode
-	5,   // 137: ast.GroupNode.name:type_name -> ast.IdentNode
-	16,  // 138: ast.GroupNode.equals:type_name -> ast.RuneNode
-	12,  // 139: ast.GroupNode.tag:type_name -> ast.UintLiteralNode
-	32,  // 140: ast.GroupNode.options:type_name -> ast.CompactOptionsNode
-	16,  // 141: ast.GroupNode.openBrace:type_name -> ast.RuneNode
-	35,  // 142: ast.GroupNode.decls:type_name -> ast.MessageElement
-	16,  // 143: ast.GroupNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 144: ast.GroupNode.semicolon:type_name -> ast.RuneNode
-	5,   // 145: ast.EnumNode.keyword:type_name -> ast.IdentNode
-	5,   // 146: ast.EnumNode.name:type_name -> ast.IdentNode
-	16,  // 147: ast.EnumNode.openBrace:type_name -> ast.RuneNode
-	49,  // 148: ast.EnumNode.decls:type_name -> ast.EnumElement
-	16,  // 149: ast.EnumNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 150: ast.EnumNode.semicolon:type_name -> ast.RuneNode
-	5,   // 151: ast.ExtendNode.keyword:type_name -> ast.IdentNode
-	21,  // 152: ast.ExtendNode.extendee:type_name -> ast.IdentValueNode
-	16,  // 153: ast.ExtendNode.openBrace:type_name -> ast.RuneNode
-	36,  // 154: ast.ExtendNode.decls:type_name -> ast.ExtendElement
-	16,  // 155: ast.ExtendNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 156: ast.ExtendNode.semicolon:type_name -> ast.RuneNode
-	5,   // 157: ast.ExtensionRangeNode.keyword:type_name -> ast.IdentNode
-	44,  // 158: ast.ExtensionRangeNode.elements:type_name -> ast.RangeElement
-	32,  // 159: ast.ExtensionRangeNode.options:type_name -> ast.CompactOptionsNode
-	16,  // 160: ast.ExtensionRangeNode.semicolon:type_name -> ast.RuneNode
-	51,  // 161: ast.RangeElement.range:type_name -> ast.RangeNode
-	16,  // 162: ast.RangeElement.comma:type_name -> ast.RuneNode
-	5,   // 163: ast.ReservedNode.keyword:type_name -> ast.IdentNode
-	46,  // 164: ast.ReservedNode.elements:type_name -> ast.ReservedElement
-	16,  // 165: ast.ReservedNode.semicolon:type_name -> ast.RuneNode
-	51,  // 166: ast.ReservedElement.range:type_name -> ast.RangeNode
-	17,  // 167: ast.ReservedElement.name:type_name -> ast.StringValueNode
-	5,   // 168: ast.ReservedElement.identifier:type_name -> ast.IdentNode
-	16,  // 169: ast.ReservedElement.comma:type_name -> ast.RuneNode
-	5,   // 170: ast.MapTypeNode.keyword:type_name -> ast.IdentNode
-	16,  // 171: ast.MapTypeNode.openAngle:type_name -> ast.RuneNode
-	5,   // 172: ast.MapTypeNode.keyType:type_name -> ast.IdentNode
-	16,  // 173: ast.MapTypeNode.comma:type_name -> ast.RuneNode
-	21,  // 174: ast.MapTypeNode.valueType:type_name -> ast.IdentValueNode
-	16,  // 175: ast.MapTypeNode.closeAngle:type_name -> ast.RuneNode
-	16,  // 176: ast.MapTypeNode.semicolon:type_name -> ast.RuneNode
-	13,  // 177: ast.OneofElement.option:type_name -> ast.OptionNode
-	37,  // 178: ast.OneofElement.field:type_name -> ast.FieldNode
-	40,  // 179: ast.OneofElement.group:type_name -> ast.GroupNode
-	13,  // 180: ast.EnumElement.option:type_name -> ast.OptionNode
-	50,  // 181: ast.EnumElement.enumValue:type_name -> ast.EnumValueNode
-	45,  // 182: ast.EnumElement.reserved:type_name -> ast.ReservedNode
-	5,   // 183: ast.EnumValueNode.name:type_name -> ast.IdentNode
-	16,  // 184: ast.EnumValueNode.equals:type_name -> ast.RuneNode
-	52,  // 185: ast.EnumValueNode.number:type_name -> ast.IntValueNode
-	32,  // 186: ast.EnumValueNode.options:type_name -> ast.CompactOptionsNode
-	16,  // 187: ast.EnumValueNode.semicolon:type_name -> ast.RuneNode
-	52,  // 188: ast.RangeNode.startVal:type_name -> ast.IntValueNode
-	5,   // 189: ast.RangeNode.to:type_name -> ast.IdentNode
-	52,  // 190: ast.RangeNode.endVal:type_name -> ast.IntValueNode
-	5,   // 191: ast.RangeNode.max:type_name -> ast.IdentNode
-	12,  // 192: ast.IntValueNode.uintLiteral:type_name -> ast.UintLiteralNode
-	14,  // 193: ast.IntValueNode.negativeIntLiteral:type_name -> ast.NegativeIntLiteralNode
-	5,   // 194: ast.ServiceNode.keyword:type_name -> ast.IdentNode
-	5,   // 195: ast.ServiceNode.name:type_name -> ast.IdentNode
-	16,  // 196: ast.ServiceNode.openBrace:type_name -> ast.RuneNode
-	54,  // 197: ast.ServiceNode.decls:type_name -> ast.ServiceElement
-	16,  // 198: ast.ServiceNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 199: ast.ServiceNode.semicolon:type_name -> ast.RuneNode
-	13,  // 200: ast.ServiceElement.option:type_name -> ast.OptionNode
-	55,  // 201: ast.ServiceElement.rpc:type_name -> ast.RPCNode
-	5,   // 202: ast.RPCNode.keyword:type_name -> ast.IdentNode
-	5,   // 203: ast.RPCNode.name:type_name -> ast.IdentNode
-	56,  // 204: ast.RPCNode.input:type_name -> ast.RPCTypeNode
-	5,   // 205: ast.RPCNode.returns:type_name -> ast.IdentNode
-	56,  // 206: ast.RPCNode.output:type_name -> ast.RPCTypeNode
-	16,  // 207: ast.RPCNode.openBrace:type_name -> ast.RuneNode
-	57,  // 208: ast.RPCNode.decls:type_name -> ast.RPCElement
-	16,  // 209: ast.RPCNode.closeBrace:type_name -> ast.RuneNode
-	16,  // 210: ast.RPCNode.semicolon:type_name -> ast.RuneNode
-	16,  // 211: ast.RPCTypeNode.openParen:type_name -> ast.RuneNode
-	5,   // 212: ast.RPCTypeNode.stream:type_name -> ast.IdentNode
-	21,  // 213: ast.RPCTypeNode.messageType:type_name -> ast.IdentValueNode
-	16,  // 214: ast.RPCTypeNode.closeParen:type_name -> ast.RuneNode
-	16,  // 215: ast.RPCTypeNode.semicolon:type_name -> ast.RuneNode
-	13,  // 216: ast.RPCElement.option:type_name -> ast.OptionNode
-	5,   // 217: ast.SyntheticMapField.name:type_name -> ast.IdentNode
-	21,  // 218: ast.SyntheticMapField.fieldType:type_name -> ast.IdentValueNode
-	12,  // 219: ast.SyntheticMapField.tag:type_name -> ast.UintLiteralNode
-	37,  // 220: ast.FieldDeclNode.field:type_name -> ast.FieldNode
-	38,  // 221: ast.FieldDeclNode.mapField:type_name -> ast.MapFieldNode
-	58,  // 222: ast.FieldDeclNode.syntheticMapField:type_name -> ast.SyntheticMapField
-	40,  // 223: ast.FieldDeclNode.group:type_name -> ast.GroupNode
-	224, // [224:224] is the sub-list for method output_type
-	224, // [224:224] is the sub-list for method input_type
-	224, // [224:224] is the sub-list for extension type_name
-	224, // [224:224] is the sub-list for extension extendee
-	0,   // [0:224] is the sub-list for field type_name
+	5,   // 96: ast.MessageNode.visibility:type_name -> ast.IdentNode
+	5,   // 97: ast.MessageNode.keyword:type_name -> ast.IdentNode
+	5,   // 98: ast.MessageNode.name:type_name -> ast.IdentNode
+	16,  // 99: ast.MessageNode.openBrace:type_name -> ast.RuneNode
+	35,  // 100: ast.MessageNode.decls:type_name -> ast.MessageElement
+	16,  // 101: ast.MessageNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 102: ast.MessageNode.semicolon:type_name -> ast.RuneNode
+	13,  // 103: ast.MessageElement.option:type_name -> ast.OptionNode
+	37,  // 104: ast.MessageElement.field:type_name -> ast.FieldNode
+	38,  // 105: ast.MessageElement.mapField:type_name -> ast.MapFieldNode
+	39,  // 106: ast.MessageElement.oneof:type_name -> ast.OneofNode
+	40,  // 107: ast.MessageElement.group:type_name -> ast.GroupNode
+	34,  // 108: ast.MessageElement.message:type_name -> ast.MessageNode
+	41,  // 109: ast.MessageElement.enum:type_name -> ast.EnumNode
+	42,  // 110: ast.MessageElement.extend:type_name -> ast.ExtendNode
+	43,  // 111: ast.MessageElement.extensionRange:type_name -> ast.ExtensionRangeNode
+	45,  // 112: ast.MessageElement.reserved:type_name -> ast.ReservedNode
+	6,   // 113: ast.MessageElement.empty:type_name -> ast.EmptyDeclNode
+	37,  // 114: ast.ExtendElement.field:type_name -> ast.FieldNode
+	40,  // 115: ast.ExtendElement.group:type_name -> ast.GroupNode
+	6,   // 116: ast.ExtendElement.empty:type_name -> ast.EmptyDeclNode
+	5,   // 117: ast.FieldNode.label:type_name -> ast.IdentNode
+	21,  // 118: ast.FieldNode.fieldType:type_name -> ast.IdentValueNode
+	5,   // 119: ast.FieldNode.name:type_name -> ast.IdentNode
+	16,  // 120: ast.FieldNode.equals:type_name -> ast.RuneNode
+	12,  // 121: ast.FieldNode.tag:type_name -> ast.UintLiteralNode
+	32,  // 122: ast.FieldNode.options:type_name -> ast.CompactOptionsNode
+	16,  // 123: ast.FieldNode.semicolon:type_name -> ast.RuneNode
+	47,  // 124: ast.MapFieldNode.mapType:type_name -> ast.MapTypeNode
+	5,   // 125: ast.MapFieldNode.name:type_name -> ast.IdentNode
+	16,  // 126: ast.MapFieldNode.equals:type_name -> ast.RuneNode
+	12,  // 127: ast.MapFieldNode.tag:type_name -> ast.UintLiteralNode
+	32,  // 128: ast.MapFieldNode.options:type_name -> ast.CompactOptionsNode
+	16,  // 129: ast.MapFieldNode.semicolon:type_name -> ast.RuneNode
+	5,   // 130: ast.OneofNode.keyword:type_name -> ast.IdentNode
+	5,   // 131: ast.OneofNode.name:type_name -> ast.IdentNode
+	16,  // 132: ast.OneofNode.openBrace:type_name -> ast.RuneNode
+	48,  // 133: ast.OneofNode.decls:type_name -> ast.OneofElement
+	16,  // 134: ast.OneofNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 135: ast.OneofNode.semicolon:type_name -> ast.RuneNode
+	5,   // 136: ast.GroupNode.label:type_name -> ast.IdentNode
+	5,   // 137: ast.GroupNode.keyword:type_name -> ast.IdentNode
+	5,   // 138: ast.GroupNode.name:type_name -> ast.IdentNode
+	16,  // 139: ast.GroupNode.equals:type_name -> ast.RuneNode
+	12,  // 140: ast.GroupNode.tag:type_name -> ast.UintLiteralNode
+	32,  // 141: ast.GroupNode.options:type_name -> ast.CompactOptionsNode
+	16,  // 142: ast.GroupNode.openBrace:type_name -> ast.RuneNode
+	35,  // 143: ast.GroupNode.decls:type_name -> ast.MessageElement
+	16,  // 144: ast.GroupNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 145: ast.GroupNode.semicolon:type_name -> ast.RuneNode
+	5,   // 146: ast.EnumNode.visibility:type_name -> ast.IdentNode
+	5,   // 147: ast.EnumNode.keyword:type_name -> ast.IdentNode
+	5,   // 148: ast.EnumNode.name:type_name -> ast.IdentNode
+	16,  // 149: ast.EnumNode.openBrace:type_name -> ast.RuneNode
+	49,  // 150: ast.EnumNode.decls:type_name -> ast.EnumElement
+	16,  // 151: ast.EnumNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 152: ast.EnumNode.semicolon:type_name -> ast.RuneNode
+	5,   // 153: ast.ExtendNode.keyword:type_name -> ast.IdentNode
+	21,  // 154: ast.ExtendNode.extendee:type_name -> ast.IdentValueNode
+	16,  // 155: ast.ExtendNode.openBrace:type_name -> ast.RuneNode
+	36,  // 156: ast.ExtendNode.decls:type_name -> ast.ExtendElement
+	16,  // 157: ast.ExtendNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 158: ast.ExtendNode.semicolon:type_name -> ast.RuneNode
+	5,   // 159: ast.ExtensionRangeNode.keyword:type_name -> ast.IdentNode
+	44,  // 160: ast.ExtensionRangeNode.elements:type_name -> ast.RangeElement
+	32,  // 161: ast.ExtensionRangeNode.options:type_name -> ast.CompactOptionsNode
+	16,  // 162: ast.ExtensionRangeNode.semicolon:type_name -> ast.RuneNode
+	51,  // 163: ast.RangeElement.range:type_name -> ast.RangeNode
+	16,  // 164: ast.RangeElement.comma:type_name -> ast.RuneNode
+	5,   // 165: ast.ReservedNode.keyword:type_name -> ast.IdentNode
+	46,  // 166: ast.ReservedNode.elements:type_name -> ast.ReservedElement
+	16,  // 167: ast.ReservedNode.semicolon:type_name -> ast.RuneNode
+	51,  // 168: ast.ReservedElement.range:type_name -> ast.RangeNode
+	17,  // 169: ast.ReservedElement.name:type_name -> ast.StringValueNode
+	5,   // 170: ast.ReservedElement.identifier:type_name -> ast.IdentNode
+	16,  // 171: ast.ReservedElement.comma:type_name -> ast.RuneNode
+	5,   // 172: ast.MapTypeNode.keyword:type_name -> ast.IdentNode
+	16,  // 173: ast.MapTypeNode.openAngle:type_name -> ast.RuneNode
+	5,   // 174: ast.MapTypeNode.keyType:type_name -> ast.IdentNode
+	16,  // 175: ast.MapTypeNode.comma:type_name -> ast.RuneNode
+	21,  // 176: ast.MapTypeNode.valueType:type_name -> ast.IdentValueNode
+	16,  // 177: ast.MapTypeNode.closeAngle:type_name -> ast.RuneNode
+	16,  // 178: ast.MapTypeNode.semicolon:type_name -> ast.RuneNode
+	13,  // 179: ast.OneofElement.option:type_name -> ast.OptionNode
+	37,  // 180: ast.OneofElement.field:type_name -> ast.FieldNode
+	40,  // 181: ast.OneofElement.group:type_name -> ast.GroupNode
+	13,  // 182: ast.EnumElement.option:type_name -> ast.OptionNode
+	50,  // 183: ast.EnumElement.enumValue:type_name -> ast.EnumValueNode
+	45,  // 184: ast.EnumElement.reserved:type_name -> ast.ReservedNode
+	5,   // 185: ast.EnumValueNode.name:type_name -> ast.IdentNode
+	16,  // 186: ast.EnumValueNode.equals:type_name -> ast.RuneNode
+	52,  // 187: ast.EnumValueNode.number:type_name -> ast.IntValueNode
+	32,  // 188: ast.EnumValueNode.options:type_name -> ast.CompactOptionsNode
+	16,  // 189: ast.EnumValueNode.semicolon:type_name -> ast.RuneNode
+	52,  // 190: ast.RangeNode.startVal:type_name -> ast.IntValueNode
+	5,   // 191: ast.RangeNode.to:type_name -> ast.IdentNode
+	52,  // 192: ast.RangeNode.endVal:type_name -> ast.IntValueNode
+	5,   // 193: ast.RangeNode.max:type_name -> ast.IdentNode
+	12,  // 194: ast.IntValueNode.uintLiteral:type_name -> ast.UintLiteralNode
+	14,  // 195: ast.IntValueNode.negativeIntLiteral:type_name -> ast.NegativeIntLiteralNode
+	5,   // 196: ast.ServiceNode.keyword:type_name -> ast.IdentNode
+	5,   // 197: ast.ServiceNode.name:type_name -> ast.IdentNode
+	16,  // 198: ast.ServiceNode.openBrace:type_name -> ast.RuneNode
+	54,  // 199: ast.ServiceNode.decls:type_name -> ast.ServiceElement
+	16,  // 200: ast.ServiceNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 201: ast.ServiceNode.semicolon:type_name -> ast.RuneNode
+	13,  // 202: ast.ServiceElement.option:type_name -> ast.OptionNode
+	55,  // 203: ast.ServiceElement.rpc:type_name -> ast.RPCNode
+	5,   // 204: ast.RPCNode.keyword:type_name -> ast.IdentNode
+	5,   // 205: ast.RPCNode.name:type_name -> ast.IdentNode
+	56,  // 206: ast.RPCNode.input:type_name -> ast.RPCTypeNode
+	5,   // 207: ast.RPCNode.returns:type_name -> ast.IdentNode
+	56,  // 208: ast.RPCNode.output:type_name -> ast.RPCTypeNode
+	16,  // 209: ast.RPCNode.openBrace:type_name -> ast.RuneNode
+	57,  // 210: ast.RPCNode.decls:type_name -> ast.RPCElement
+	16,  // 211: ast.RPCNode.closeBrace:type_name -> ast.RuneNode
+	16,  // 212: ast.RPCNode.semicolon:type_name -> ast.RuneNode
+	16,  // 213: ast.RPCTypeNode.openParen:type_name -> ast.RuneNode
+	5,   // 214: ast.RPCTypeNode.stream:type_name -> ast.IdentNode
+	21,  // 215: ast.RPCTypeNode.messageType:type_name -> ast.IdentValueNode
+	16,  // 216: ast.RPCTypeNode.closeParen:type_name -> ast.RuneNode
+	16,  // 217: ast.RPCTypeNode.semicolon:type_name -> ast.RuneNode
+	13,  // 218: ast.RPCElement.option:type_name -> ast.OptionNode
+	5,   // 219: ast.SyntheticMapField.name:type_name -> ast.IdentNode
+	21,  // 220: ast.SyntheticMapField.fieldType:type_name -> ast.IdentValueNode
+	12,  // 221: ast.SyntheticMapField.tag:type_name -> ast.UintLiteralNode
+	37,  // 222: ast.FieldDeclNode.field:type_name -> ast.FieldNode
+	38,  // 223: ast.FieldDeclNode.mapField:type_name -> ast.MapFieldNode
+	58,  // 224: ast.FieldDeclNode.syntheticMapField:type_name -> ast.SyntheticMapField
+	40,  // 225: ast.FieldDeclNode.group:type_name -> ast.GroupNode
+	226, // [226:226] is the sub-list for method output_type
+	226, // [226:226] is the sub-list for method input_type
+	226, // [226:226] is the sub-list for extension type_name
+	226, // [226:226] is the sub-list for extension extendee
+	0,   // [0:226] is the sub-list for field type_name
 }
 
 func init() { file_github_com_kralicky_protocompile_ast_ast_proto_init() }
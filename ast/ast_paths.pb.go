@@ -468,6 +468,9 @@ func (p messageFieldNodePathBuilder) Val() valueNodePathBuilder {
 func (p messageFieldNodePathBuilder) Semicolon() runeNodePathBuilder {
 	return runeNodePathBuilder(append(p, protopath.FieldAccess(((*MessageFieldNode)(nil)).ProtoReflect().Descriptor().Fields().ByNumber(4))))
 }
+func (p messageNodePathBuilder) Visibility() identNodePathBuilder {
+	return identNodePathBuilder(append(p, protopath.FieldAccess(((*MessageNode)(nil)).ProtoReflect().Descriptor().Fields().ByNumber(7))))
+}
 func (p messageNodePathBuilder) Keyword() identNodePathBuilder {
 	return identNodePathBuilder(append(p, protopath.FieldAccess(((*MessageNode)(nil)).ProtoReflect().Descriptor().Fields().ByNumber(1))))
 }
@@ -648,6 +651,9 @@ func (p groupNodePathBuilder) CloseBrace() runeNodePathBuilder {
 func (p groupNodePathBuilder) Semicolon() runeNodePathBuilder {
 	return runeNodePathBuilder(append(p, protopath.FieldAccess(((*GroupNode)(nil)).ProtoReflect().Descriptor().Fields().ByNumber(10))))
 }
+func (p enumNodePathBuilder) Visibility() identNodePathBuilder {
+	return identNodePathBuilder(append(p, protopath.FieldAccess(((*EnumNode)(nil)).ProtoReflect().Descriptor().Fields().ByNumber(7))))
+}
 func (p enumNodePathBuilder) Keyword() identNodePathBuilder {
 	return identNodePathBuilder(append(p, protopath.FieldAccess(((*EnumNode)(nil)).ProtoReflect().Descriptor().Fields().ByNumber(1))))
 }
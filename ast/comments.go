@@ -0,0 +1,345 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"strings"
+)
+
+// CommentKind classifies a comment according to the element, if any, that it
+// describes.
+type CommentKind int
+
+const (
+	// CommentKindLeading is a comment that is attached to the element that
+	// follows it, such as the doc comment of a declaration.
+	CommentKindLeading CommentKind = iota + 1
+	// CommentKindTrailing is a comment that is attached to the element that
+	// precedes it, usually on the same line.
+	CommentKindTrailing
+	// CommentKindDetached is a comment that is not attached to any element,
+	// usually because it is separated from the surrounding elements by blank
+	// lines.
+	CommentKindDetached
+	// CommentKindDirective is a line comment that is a directive to a tool
+	// rather than documentation, such as "//pragma:key value". See
+	// Comment.IsDirective.
+	CommentKindDirective
+)
+
+func (k CommentKind) String() string {
+	switch k {
+	case CommentKindLeading:
+		return "leading"
+	case CommentKindTrailing:
+		return "trailing"
+	case CommentKindDetached:
+		return "detached"
+	case CommentKindDirective:
+		return "directive"
+	default:
+		return "unknown"
+	}
+}
+
+// CommentAttribution describes how the comments between two adjacent tokens
+// are attributed. See AttributeComments.
+type CommentAttribution struct {
+	// Comments attached to the previous token.
+	Trailing Comments
+	// Groups of comments that are not attached to either token. A group is a
+	// run of consecutive line comments, or a single block comment.
+	Detached []Comments
+	// Comments attached to the next token.
+	Leading Comments
+}
+
+// AttributeComments decides which of the comments between two adjacent
+// tokens belong to the previous token, which belong to the next token, and
+// which belong to neither, using the same rules as protoc. These are the
+// decisions used when generating source code info, so tools that present
+// comments should use this to agree with the comments in descriptors.
+//
+// The prev argument may be invalid (e.g. a zero value) if next is the first
+// token in the file.
+//
+// Comments before a token that is a closing symbol (one of "}]),;") or EOF
+// are donated to the previous token, since such tokens do not correspond to
+// a location in source code info. Like protoc, this is not done if the
+// comments are on the same lines as both tokens, since it is ambiguous which
+// one they describe, unless extraComments is true (see
+// sourceinfo.WithExtraComments).
+func AttributeComments(prev, next NodeInfo, extraComments bool) CommentAttribution {
+	detached := groupComments(next.LeadingComments())
+	var trail Comments
+	if prev.IsValid() {
+		trail = prev.TrailingComments()
+		if trail.Len() == 0 {
+			trail, detached = maybeDonate(prev, next, detached, extraComments)
+		}
+	}
+	detached, lead := maybeAttach(prev, next, trail.Len() > 0, detached)
+	return CommentAttribution{Trailing: trail, Detached: detached, Leading: lead}
+}
+
+func maybeDonate(prevInfo, info NodeInfo, lead []Comments, extraComments bool) (t Comments, l []Comments) {
+	if len(lead) == 0 {
+		// nothing to donate
+		return EmptyComments, nil
+	}
+	firstCommentPos := lead[0].Index(0)
+	if firstCommentPos.Start().Line > prevInfo.End().Line+1 {
+		// first comment is detached from previous token, so can't be a trailing comment
+		return EmptyComments, lead
+	}
+	if len(lead) > 1 {
+		// multiple groups? then donate first comment to previous token
+		return lead[0], lead[1:]
+	}
+	// there is only one element in lead
+	comment := lead[0]
+	lastCommentPos := comment.Index(comment.Len() - 1)
+	if lastCommentPos.End().Line < info.Start().Line-1 {
+		// there is a blank line between the comments and subsequent token, so
+		// we can donate the comment to previous token
+		return comment, nil
+	}
+	if txt := info.RawText(); txt == "" || (len(txt) == 1 && strings.ContainsAny(txt, "}]),;")) {
+		// token is a symbol for the end of a scope or EOF, which doesn't need a leading comment
+		if !extraComments && txt != "" &&
+			firstCommentPos.Start().Line == prevInfo.End().Line &&
+			lastCommentPos.End().Line == info.Start().Line {
+			// protoc does not donate if prev and next token are on the same line since it's
+			// ambiguous which one should get the comment; so we mirror that here
+			return EmptyComments, lead
+		}
+		// But with extra comments, we always donate in this situation in order to capture
+		// more comments. Because otherwise, these comments are lost since these symbols
+		// don't map to a location in source code info.
+		return comment, nil
+	}
+	// cannot donate
+	return EmptyComments, lead
+}
+
+func maybeAttach(prevInfo, info NodeInfo, hasTrail bool, lead []Comments) (d []Comments, l Comments) {
+	if len(lead) == 0 {
+		return nil, EmptyComments
+	}
+
+	if len(lead) == 1 && !hasTrail && prevInfo.IsValid() {
+		// If the one comment appears attached to both previous and next tokens,
+		// don't attach to either.
+		comment := lead[0]
+		attachedToPrevious := comment.Index(0).Start().Line == prevInfo.End().Line
+		attachedToNext := comment.Index(comment.Len()-1).End().Line == info.Start().Line
+		if attachedToPrevious && attachedToNext {
+			// Since attachment is ambiguous, leave it detached.
+			return lead, EmptyComments
+		}
+	}
+
+	lastComment := lead[len(lead)-1]
+	if lastComment.Index(lastComment.Len()-1).End().Line >= info.Start().Line-1 {
+		return lead[:len(lead)-1], lastComment
+	}
+
+	return lead, EmptyComments
+}
+
+// LeadingCommentGroups returns the comments returned by LeadingComments,
+// split into groups as described by Comments.Groups. Unlike
+// AttributedComments, this does not decide which of the groups are attached
+// to the element or to the previous one.
+func (n NodeInfo) LeadingCommentGroups() []Comments {
+	return n.LeadingComments().Groups()
+}
+
+// DetachedComments returns the groups of comments before the element that
+// are not attached to it or to the previous element. This is the same as the
+// Detached field of AttributedComments.
+func (n NodeInfo) DetachedComments() []Comments {
+	return n.AttributedComments().Detached
+}
+
+var _ SourceSpan = Comments{}
+
+// Groups splits the comments into groups, each of which is a run of
+// consecutive line comments or a single block comment. A blank line between
+// two line comments starts a new group. These are the groups that become
+// detached comments in source code info, so they separate, for example, a
+// license header from the doc comment of the first declaration.
+func (c Comments) Groups() []Comments {
+	return groupComments(c)
+}
+
+// Start returns the position of the first character of the first comment.
+// If c is empty, this returns a zero value.
+func (c Comments) Start() SourcePos {
+	if c.num == 0 {
+		return SourcePos{}
+	}
+	return c.Index(0).Start()
+}
+
+// End returns the position just after the last character of the last
+// comment, like the end of a token and unlike Comment.End. If c is empty,
+// this returns a zero value.
+func (c Comments) End() SourcePos {
+	if c.num == 0 {
+		return SourcePos{}
+	}
+	span := c.fileInfo.ItemList[c.Index(c.num-1).AsItem()]
+	return c.fileInfo.SourcePos(int(span.Offset + span.Length))
+}
+
+func (c Comments) String() string {
+	if c.num == 0 {
+		return ""
+	}
+	return NewSourceSpan(c.Start(), c.End()).String()
+}
+
+// RawText returns the source text from the start of the first comment to the
+// end of the last one, including any whitespace between the comments.
+func (c Comments) RawText() string {
+	if c.num == 0 {
+		return ""
+	}
+	first := c.fileInfo.ItemList[c.Index(0).AsItem()]
+	last := c.fileInfo.ItemList[c.Index(c.num-1).AsItem()]
+	return string(c.fileInfo.Data[first.Offset : last.Offset+last.Length])
+}
+
+// groupComments splits the given comments into groups, each of which is a run
+// of consecutive line comments or a single block comment.
+func groupComments(cmts Comments) []Comments {
+	if cmts.Len() == 0 {
+		return nil
+	}
+	var groups []Comments
+	singleLineStyle := cmts.Index(0).RawText()[:2] == "//"
+	line := cmts.Index(0).End().Line
+	start := 0
+	for i := 1; i < cmts.Len(); i++ {
+		c := cmts.Index(i)
+		prevSingleLine := singleLineStyle
+		singleLineStyle = strings.HasPrefix(c.RawText(), "//")
+		if !singleLineStyle || prevSingleLine != singleLineStyle || c.Start().Line > line+1 {
+			// new group!
+			groups = append(groups, cmts.slice(start, i))
+			start = i
+		}
+		line = c.End().Line
+	}
+	// don't forget last group
+	groups = append(groups, cmts.slice(start, cmts.Len()))
+	return groups
+}
+
+// slice returns the comments in c from index start (inclusive) to end
+// (exclusive).
+func (c Comments) slice(start, end int) Comments {
+	if start == end {
+		return EmptyComments
+	}
+	sub := Comments{fileInfo: c.fileInfo, first: c.first + start, num: end - start}
+	for _, v := range c.virtual {
+		if v >= start && v < end {
+			sub.virtual = append(sub.virtual, v-start)
+		}
+	}
+	return sub
+}
+
+func (c Comments) contains(comment Comment) bool {
+	if c.num == 0 || comment.info == nil {
+		return false
+	}
+	first, last := c.fileInfo.Comments[c.first].Index, c.fileInfo.Comments[c.first+c.num-1].Index
+	return comment.info.Index >= first && comment.info.Index <= last
+}
+
+// IsDirective returns true if c is a directive to a tool, rather than
+// documentation. Following the Go convention, a directive is a line comment
+// with no space after the "//", where the text begins with a lower case
+// name and a colon, such as "//pragma:key value" or "//buf:lint:ignore".
+func (c Comment) IsDirective() bool {
+	txt := c.RawText()
+	name, _, found := strings.Cut(strings.TrimPrefix(txt, "//"), ":")
+	if !found || !strings.HasPrefix(txt, "//") || name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if ch := name[i]; (ch < 'a' || ch > 'z') && (ch < '0' || ch > '9') && ch != '_' && ch != '-' && ch != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind classifies the comment. Directives are always CommentKindDirective.
+// Other comments are classified according to AttributeComments, with
+// extraComments false, so the result agrees with the comments in source
+// code info.
+func (c Comment) Kind() CommentKind {
+	if !c.IsValid() {
+		return 0
+	}
+	if c.IsDirective() {
+		return CommentKindDirective
+	}
+	f := c.fileInfo
+	var prev, next NodeInfo
+	if i, ok := f.itemBackward(c.AsItem(), false); ok {
+		prev = f.TokenInfo(Token(i))
+	}
+	if i, ok := f.itemForward(c.AsItem(), false); ok {
+		next = f.TokenInfo(Token(i))
+	}
+	attr := AttributeComments(prev, next, false)
+	switch {
+	case attr.Trailing.contains(c):
+		return CommentKindTrailing
+	case attr.Leading.contains(c):
+		return CommentKindLeading
+	default:
+		return CommentKindDetached
+	}
+}
+
+// AttributedComments returns the comments attributed to the element, as
+// described by AttributeComments with extraComments false. Its trailing
+// comments are those attributed to its last token. Note that when generating
+// source code info, the trailing comments of a block, such as a message
+// declaration, are instead those that follow its open brace.
+func (n NodeInfo) AttributedComments() CommentAttribution {
+	if n.fileInfo.isDummyFile() || !n.IsValid() {
+		return CommentAttribution{}
+	}
+	f := n.fileInfo
+	var attr CommentAttribution
+	first := f.TokenInfo(Token(n.startIndex))
+	var prev NodeInfo
+	if p, ok := f.Tokens().Previous(Token(n.startIndex)); ok {
+		prev = f.TokenInfo(p)
+	}
+	lead := AttributeComments(prev, first, false)
+	attr.Detached, attr.Leading = lead.Detached, lead.Leading
+	if next, ok := f.Tokens().Next(Token(n.endIndex)); ok {
+		attr.Trailing = AttributeComments(f.TokenInfo(Token(n.endIndex)), f.TokenInfo(next), false).Trailing
+	}
+	return attr
+}
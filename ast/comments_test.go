@@ -0,0 +1,129 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestCommentKind(t *testing.T) {
+	t.Parallel()
+	source := `syntax = "proto3";
+
+// detached
+
+//pragma:foo bar
+// Foo is a message.
+message Foo { // trailing brace
+  // leading for name
+  string name = 1; // trailing for name
+  /* leading for x */ int32 x = 2; /* ambiguous */ int32 z = 4;
+  // leading for y
+  int32 y = 3;
+
+  // before close
+}
+`
+	root, err := parser.Parse("test.proto", bytes.NewReader([]byte(source)), reporter.NewHandler(nil), 0)
+	require.NoError(t, err)
+
+	want := map[string]ast.CommentKind{
+		"// detached":          ast.CommentKindDetached,
+		"//pragma:foo bar":     ast.CommentKindDirective,
+		"// Foo is a message.": ast.CommentKindLeading,
+		"// trailing brace":    ast.CommentKindTrailing,
+		"// leading for name":  ast.CommentKindLeading,
+		"// trailing for name": ast.CommentKindTrailing,
+		"/* leading for x */":  ast.CommentKindLeading,
+		"/* ambiguous */":      ast.CommentKindDetached,
+		"// leading for y":     ast.CommentKindLeading,
+		"// before close":      ast.CommentKindLeading, // attached to the close brace
+	}
+	got := map[string]ast.CommentKind{}
+	for item, ok := root.Items().First(); ok; item, ok = root.Items().Next(item) {
+		if _, c := root.GetItem(item); c.IsValid() {
+			got[c.RawText()] = c.Kind()
+		}
+	}
+	assert.Equal(t, want, got)
+
+	msg := root.Decls[0].GetMessage()
+	require.NotNil(t, msg)
+	attr := root.NodeInfo(msg).AttributedComments()
+	require.Len(t, attr.Detached, 1)
+	assert.Equal(t, []string{"// detached"}, commentTexts(attr.Detached[0]))
+	assert.Equal(t, []string{"//pragma:foo bar", "// Foo is a message."}, commentTexts(attr.Leading))
+	assert.Equal(t, 0, attr.Trailing.Len())
+}
+
+func TestCommentIsDirective(t *testing.T) {
+	t.Parallel()
+	source := "//go:generate foo\n// not:directive\n//Not:directive\n//buf:lint:ignore X\n/*x:y*/\nsyntax = \"proto3\";\n"
+	root, err := parser.Parse("test.proto", bytes.NewReader([]byte(source)), reporter.NewHandler(nil), 0)
+	require.NoError(t, err)
+	var got []bool
+	for item, ok := root.Items().First(); ok; item, ok = root.Items().Next(item) {
+		if _, c := root.GetItem(item); c.IsValid() {
+			got = append(got, c.IsDirective())
+		}
+	}
+	assert.Equal(t, []bool{true, false, false, true, false}, got)
+}
+
+func TestCommentGroups(t *testing.T) {
+	t.Parallel()
+	source := `// Copyright header
+// second line
+
+/* block one */ /* block two */
+// Foo is a message.
+// More docs.
+message Foo {}
+`
+	root, err := parser.Parse("test.proto", bytes.NewReader([]byte(source)), reporter.NewHandler(nil), 0)
+	require.NoError(t, err)
+	msg := root.Decls[0].GetMessage()
+	require.NotNil(t, msg)
+	info := root.NodeInfo(msg)
+
+	groups := info.LeadingCommentGroups()
+	require.Len(t, groups, 4)
+	assert.Equal(t, []string{"// Copyright header", "// second line"}, commentTexts(groups[0]))
+	assert.Equal(t, "// Copyright header\n// second line", groups[0].RawText())
+	assert.Equal(t, ast.SourcePos{Filename: "test.proto", Line: 1, Col: 1}, groups[0].Start())
+	assert.Equal(t, ast.SourcePos{Filename: "test.proto", Line: 2, Col: 15, Offset: 34}, groups[0].End())
+	assert.Equal(t, []string{"/* block one */"}, commentTexts(groups[1]))
+	assert.Equal(t, []string{"/* block two */"}, commentTexts(groups[2]))
+	assert.Equal(t, "test.proto:4:17-32", groups[2].String())
+	assert.Equal(t, []string{"// Foo is a message.", "// More docs."}, commentTexts(groups[3]))
+
+	// The last group is attached to the message, so it is not detached.
+	detached := info.DetachedComments()
+	require.Len(t, detached, 3)
+	assert.Equal(t, groups[:3], detached)
+	assert.Equal(t, commentTexts(groups[3]), commentTexts(info.AttributedComments().Leading))
+
+	assert.Empty(t, ast.EmptyComments.Groups())
+	assert.Equal(t, "", ast.EmptyComments.RawText())
+	assert.Equal(t, ast.SourcePos{}, ast.EmptyComments.Start())
+}
@@ -48,9 +48,9 @@
 // respectively.
 //
 // A [Comment] value corresponds to a line ("//") or block ("/*") style
-// comment in the source. These have no bearing on the grammar and are
-// effectively ignored as the parser is determining the shape of the
-// syntax tree.
+// comment in the source, or a "#" comment in the protobuf text format.
+// These have no bearing on the grammar and are effectively ignored as the
+// parser is determining the shape of the syntax tree.
 //
 // A [Token] value corresponds to a component of the grammar, that is
 // used to produce an AST. They correspond to leaves in the AST (i.e.
@@ -60,6 +60,17 @@
 // and iterating through all the items or tokens in the file. They also
 // include a method for resolving an [Item] into a [Token] or [Comment].
 //
+// # Trivia
+//
+// The text between items is [Trivia]: runs of whitespace and, in files that
+// could not be lexed without errors, runs of input that the lexer skipped.
+// Since every other character of the file belongs to an item, the items and
+// trivia together cover the whole file, so tools that rewrite source, such as
+// formatters, can account for every character. Trivia is not stored by the
+// lexer; it is computed on demand from the gaps between items, using the
+// TriviaBefore and Trivia methods, so it costs nothing unless it is used.
+// Each run of trivia is addressed by the item that follows it.
+//
 // # Factory Functions
 //
 // Creation of AST nodes should use the factory functions in this
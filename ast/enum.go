@@ -14,7 +14,7 @@
 
 package ast
 
-func (e *EnumNode) Start() Token { return e.Keyword.Start() }
+func (e *EnumNode) Start() Token { return startToken(e.Visibility, e.Keyword) }
 func (e *EnumNode) End() Token   { return e.Semicolon.GetToken() }
 
 func (*EnumNode) fileElement() {}
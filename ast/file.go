@@ -14,7 +14,11 @@
 
 package ast
 
-import "google.golang.org/protobuf/proto"
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+)
 
 // NewFileNode creates a new *FileNode. The syntax parameter is optional. If it
 // is absent, it means the file had no syntax declaration.
@@ -144,6 +148,58 @@ func (f *FileNode) Tokens() Sequence[Token] {
 	return f.fileInfo().Tokens()
 }
 
+// TokenCount returns the number of tokens in the file, including the EOF
+// token but not including comments.
+func (f *FileNode) TokenCount() int {
+	info := f.fileInfo()
+	return len(info.GetItemList()) - len(info.GetComments())
+}
+
+// SourceSize returns the size of the file's source code, in bytes.
+func (f *FileNode) SourceSize() int {
+	return len(f.fileInfo().GetData())
+}
+
+// EOFInfo returns details for the file's EOF token. Its LeadingComments are
+// the comments at the end of the file that are attributed to EOF, which
+// excludes any trailing comments of the last token (see FinalComments). Its
+// LeadingWhitespace is the whitespace at the end of the file.
+func (f *FileNode) EOFInfo() NodeInfo {
+	return f.NodeInfo(f.EOF)
+}
+
+// FinalComments returns all comments that follow the last token in the file
+// (other than EOF), including any trailing comments of that token. If the file
+// has no tokens other than EOF, this returns all comments in the file.
+// Formatters can use this, along with FinalWhitespace, to preserve the trivia
+// at the end of a file.
+func (f *FileNode) FinalComments() Comments {
+	info := f.fileInfo()
+	after := int32(-1)
+	if last, ok := f.Tokens().Previous(f.EOF.GetToken()); ok {
+		after = int32(last)
+	}
+	start := sort.Search(len(info.Comments), func(i int) bool {
+		return info.Comments[i].Index > after
+	})
+	if start == len(info.Comments) {
+		return EmptyComments
+	}
+	comments := Comments{fileInfo: info, first: start, num: len(info.Comments) - start}
+	for i, c := range info.Comments[start:] {
+		if c.VirtualIndex > 0 {
+			comments.virtual = append(comments.virtual, i)
+		}
+	}
+	return comments
+}
+
+// FinalWhitespace returns the whitespace at the end of the file, after the
+// last token or comment.
+func (f *FileNode) FinalWhitespace() string {
+	return f.EOFInfo().LeadingWhitespace()
+}
+
 func (f *FileNode) SourcePos(offset int) SourcePos {
 	return f.fileInfo().SourcePos(offset)
 }
@@ -382,6 +382,10 @@ func (f *FileInfo) itemBackward(i Item, allowComment bool) (Item, bool) {
 // (If it returns false, i refers to a token.)
 func (f *FileInfo) isComment(i Item) bool {
 	item := f.ItemList[i]
+	if item.Length > 0 && f.Data[item.Offset] == '#' {
+		// text format comment; '#' is never a valid token
+		return true
+	}
 	if item.Length < 2 {
 		return false
 	}
@@ -397,16 +401,26 @@ func (f *FileInfo) SourcePos(offset int) SourcePos {
 	lineNumber := sort.Search(len(f.Lines), func(n int) bool {
 		return f.Lines[n] > int32(offset)
 	})
+	return f.sourcePosOnLine(offset, lineNumber, f.lineStart(lineNumber), 0)
+}
+
+func (f *FileInfo) lineStart(lineNumber int) int {
+	if lineNumber == 0 {
+		return 0
+	}
+	return int(f.Lines[lineNumber-1])
+}
 
-	var col int
+// sourcePosOnLine returns the position of the given offset, which must be on
+// the given line. The column is computed by scanning forward from the offset
+// from, which must be on the same line and at or before offset, and which has
+// the given zero-based column.
+func (f *FileInfo) sourcePosOnLine(offset, lineNumber, from, col int) SourcePos {
 	switch f.PositionEncoding {
 	case FileInfo_PositionEncodingByteOffset:
-		col = offset
-		if lineNumber > 0 {
-			col -= int(f.Lines[lineNumber-1])
-		}
+		col = offset - f.lineStart(lineNumber)
 	case FileInfo_PositionEncodingProtocCompatible:
-		for i := f.Lines[lineNumber-1]; i < int32(offset); i++ {
+		for i := from; i < offset; i++ {
 			if f.Data[i] == '\t' {
 				nextTabStop := 8 - (col % 8)
 				col += nextTabStop
@@ -610,6 +624,47 @@ func (n NodeInfo) End() SourcePos {
 	return pos
 }
 
+// StartOffset returns the byte offset in the source file of the first
+// character of the element. It returns -1 if n is not valid.
+func (n NodeInfo) StartOffset() int {
+	if n.fileInfo.isDummyFile() || !n.IsValid() {
+		return -1
+	}
+	return int(n.fileInfo.ItemList[n.startIndex].Offset)
+}
+
+// EndOffset returns the byte offset in the source file just after the last
+// character of the element, so that the source text of the element is
+// data[StartOffset():EndOffset()]. It returns -1 if n is not valid.
+func (n NodeInfo) EndOffset() int {
+	if n.fileInfo.isDummyFile() || !n.IsValid() {
+		return -1
+	}
+	tok := n.fileInfo.ItemList[n.endIndex]
+	return int(tok.Offset + tok.Length)
+}
+
+// StartRuneOffset is like StartOffset, but returns the offset in runes
+// (Unicode code points) instead of bytes. Computing it requires decoding
+// the source file up to the start of the element.
+func (n NodeInfo) StartRuneOffset() int {
+	return n.fileInfo.runeOffset(n.StartOffset())
+}
+
+// EndRuneOffset is like EndOffset, but returns the offset in runes
+// (Unicode code points) instead of bytes. Computing it requires decoding
+// the source file up to the end of the element.
+func (n NodeInfo) EndRuneOffset() int {
+	return n.fileInfo.runeOffset(n.EndOffset())
+}
+
+func (f *FileInfo) runeOffset(offset int) int {
+	if offset < 0 {
+		return -1
+	}
+	return utf8.RuneCount(f.Data[:offset])
+}
+
 // LeadingWhitespace returns any whitespace prior to the element. If there
 // were comments in between this element and the previous one, this will
 // return the whitespace between the last such comment in the element. If
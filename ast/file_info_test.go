@@ -0,0 +1,109 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestNodeInfoOffsets(t *testing.T) {
+	t.Parallel()
+	data := []byte("syntax = \"proto3\";\n// ñandú\nmessage Foo { string u = 1 [json_name = \"ü\"]; }\n")
+	root, err := parser.Parse("test.proto", bytes.NewReader(data), reporter.NewHandler(nil), 0)
+	require.NoError(t, err)
+	msg := root.Decls[0].GetMessage()
+	require.NotNil(t, msg)
+
+	info := root.NodeInfo(msg)
+	assert.Equal(t, 30, info.StartOffset())
+	assert.Equal(t, 78, info.EndOffset())
+	assert.Equal(t, info.RawText(), string(data[info.StartOffset():info.EndOffset()]))
+	assert.Equal(t, info.Start().Offset, info.StartOffset())
+	// the comment contains two 2-byte runes
+	assert.Equal(t, 28, info.StartRuneOffset())
+	assert.Equal(t, 75, info.EndRuneOffset())
+
+	var str *ast.StringLiteralNode
+	ast.Inspect(msg, func(n ast.Node) bool {
+		if s, ok := n.(*ast.StringLiteralNode); ok {
+			str = s
+		}
+		return true
+	})
+	require.NotNil(t, str)
+	info = root.NodeInfo(str)
+	assert.Equal(t, `"ü"`, string(data[info.StartOffset():info.EndOffset()]))
+	assert.Equal(t, 3, info.EndRuneOffset()-info.StartRuneOffset())
+
+	var invalid ast.NodeInfo
+	assert.Equal(t, -1, invalid.StartOffset())
+	assert.Equal(t, -1, invalid.EndOffset())
+	assert.Equal(t, -1, invalid.StartRuneOffset())
+	assert.Equal(t, -1, invalid.EndRuneOffset())
+}
+
+func TestFinalTrivia(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		source     string
+		final      []string
+		eof        []string
+		whitespace string
+	}{
+		{
+			source:     "syntax = \"proto3\"; // a\n// b\n\n/* c */ \n",
+			final:      []string{"// a", "// b", "/* c */"},
+			eof:        []string{"// b", "/* c */"},
+			whitespace: " \n",
+		},
+		{
+			source: "syntax = \"proto3\"; /* a */",
+			final:  []string{"/* a */"},
+		},
+		{
+			source:     "syntax = \"proto3\";\n\n",
+			whitespace: "\n\n",
+		},
+		{
+			source:     "// only\n",
+			final:      []string{"// only"},
+			eof:        []string{"// only"},
+			whitespace: "\n",
+		},
+	}
+	for _, tc := range testCases {
+		root, err := parser.Parse("test.proto", bytes.NewReader([]byte(tc.source)), reporter.NewHandler(nil), 0)
+		require.NoError(t, err)
+		assert.Equal(t, tc.final, commentTexts(root.FinalComments()), "%q", tc.source)
+		assert.Equal(t, tc.eof, commentTexts(root.EOFInfo().LeadingComments()), "%q", tc.source)
+		assert.Equal(t, tc.whitespace, root.FinalWhitespace(), "%q", tc.source)
+	}
+}
+
+func commentTexts(cmts ast.Comments) []string {
+	var texts []string
+	for i := 0; i < cmts.Len(); i++ {
+		texts = append(texts, cmts.Index(i).RawText())
+	}
+	return texts
+}
@@ -35,7 +35,7 @@ func (n *MessageDeclNode) GetName() *IdentNode {
 	return nil
 }
 
-func (m *MessageNode) Start() Token { return startToken(m.Keyword) }
+func (m *MessageNode) Start() Token { return startToken(m.Visibility, m.Keyword) }
 func (m *MessageNode) End() Token   { return endToken(m.Semicolon, m.CloseBrace) }
 
 func (*MessageNode) fileElement() {}
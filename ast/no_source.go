@@ -14,6 +14,8 @@
 
 package ast
 
+import "google.golang.org/protobuf/reflect/protoreflect"
+
 // UnknownPos is a placeholder position when only the source file
 // name is known.
 func UnknownPos(filename string) SourcePos {
@@ -42,6 +44,31 @@ func (n unknownSpan) String() string {
 	return n.filename
 }
 
+// PathSpan is a placeholder span for an element of a file descriptor that
+// has no source. Instead of a position, it identifies the element by its
+// path, in the same form as the path of a source code info location.
+type PathSpan struct {
+	Filename string
+	Path     protoreflect.SourcePath
+}
+
+func (s PathSpan) Start() SourcePos {
+	return UnknownPos(s.Filename)
+}
+
+func (s PathSpan) End() SourcePos {
+	return UnknownPos(s.Filename)
+}
+
+// String returns the filename and a human-readable form of the path, such
+// as "test.proto:.message_type[0].field[1].number".
+func (s PathSpan) String() string {
+	if len(s.Path) == 0 {
+		return s.Filename
+	}
+	return s.Filename + ":" + s.Path.String()
+}
+
 func (n *NoSourceNode) Start() Token {
 	return TokenError
 }
@@ -0,0 +1,117 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeSpan is a NodeInfo whose start and end positions have already been
+// computed. NodeSpans are returned by NodeInfoBatch.
+type NodeSpan struct {
+	NodeInfo
+	start, end SourcePos
+}
+
+var _ SourceSpan = NodeSpan{}
+
+// Start returns the starting position of the element. See NodeInfo.Start.
+func (n NodeSpan) Start() SourcePos {
+	return n.start
+}
+
+// End returns the ending position of the element. See NodeInfo.End.
+func (n NodeSpan) End() SourcePos {
+	return n.end
+}
+
+func (n NodeSpan) String() string {
+	return fmt.Sprintf("%s:%d:%d-%d", n.start.Filename, n.start.Line, n.start.Col, n.end.Col)
+}
+
+// NodeInfoBatch returns details for each of the given nodes. The result is
+// equivalent to calling NodeInfo for each node, except that the start and end
+// positions of every node are computed up front, sharing work between nodes
+// that are near each other in the file. When nodes are given in source order,
+// as when visiting a file with Inspect, this is much cheaper than computing
+// positions one node at a time. This is useful when a large number of nodes
+// is needed, such as when computing semantic tokens for a whole file.
+func (f *FileNode) NodeInfoBatch(nodes []Node) []NodeSpan {
+	return f.fileInfo().NodeInfoBatch(nodes)
+}
+
+// NodeInfoBatch returns details for each of the given nodes. See
+// FileNode.NodeInfoBatch.
+func (f *FileInfo) NodeInfoBatch(nodes []Node) []NodeSpan {
+	spans := make([]NodeSpan, len(nodes))
+	cursor := posCursor{f: f, line: -1}
+	for i, n := range nodes {
+		info := f.NodeInfo(n)
+		spans[i].NodeInfo = info
+		if f.isDummyFile() || !info.IsValid() {
+			spans[i].start, spans[i].end = info.Start(), info.End()
+			continue
+		}
+		spans[i].start = cursor.pos(int(f.ItemList[info.startIndex].Offset))
+		tok := f.ItemList[info.endIndex]
+		if tok.Length == 0 {
+			spans[i].end = cursor.pos(int(tok.Offset))
+			continue
+		}
+		// find offset of last character in the span, and then return the
+		// position after it
+		spans[i].end = cursor.pos(int(tok.Offset + tok.Length - 1))
+		spans[i].end.Col++
+	}
+	return spans
+}
+
+// posCursor computes source positions, remembering the last position that
+// it computed. Consecutive offsets are usually close together, so the line
+// of the next offset can usually be found without a search, and the column
+// can be computed by scanning forward from the previous offset instead of
+// from the start of the line.
+type posCursor struct {
+	f                 *FileInfo
+	line, offset, col int
+}
+
+func (c *posCursor) pos(offset int) SourcePos {
+	line := c.line
+	switch {
+	case c.onLine(offset, line):
+	case c.onLine(offset, line+1):
+		line++
+	default:
+		line = sort.Search(len(c.f.Lines), func(n int) bool {
+			return c.f.Lines[n] > int32(offset)
+		})
+	}
+	from, col := c.f.lineStart(line), 0
+	if line == c.line && offset >= c.offset {
+		from, col = c.offset, c.col
+	}
+	pos := c.f.sourcePosOnLine(offset, line, from, col)
+	c.line, c.offset, c.col = line, offset, pos.Col-1
+	return pos
+}
+
+func (c *posCursor) onLine(offset, line int) bool {
+	lines := c.f.Lines
+	return line >= 0 && line <= len(lines) &&
+		c.f.lineStart(line) <= offset &&
+		(line == len(lines) || int(lines[line]) > offset)
+}
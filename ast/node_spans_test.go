@@ -0,0 +1,105 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func collectNodes(root *ast.FileNode) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(root, func(n ast.Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	return nodes
+}
+
+func TestNodeInfoBatch(t *testing.T) {
+	t.Parallel()
+	sources := map[string][]byte{
+		"tabs.proto": []byte("syntax = \"proto3\";\n\tmessage\tFoo {\n\t\tstring  name = 1; // ñame\n  /* ü */ int32 x = 2;\n}\n"),
+	}
+	paths, err := filepath.Glob("../internal/testdata/*.proto")
+	require.NoError(t, err)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		sources[path] = data
+	}
+	for name, data := range sources {
+		root, err := parser.Parse(name, bytes.NewReader(data), reporter.NewHandler(nil), 0)
+		if err != nil {
+			continue
+		}
+		for _, encoding := range []ast.FileInfo_PositionEncoding{
+			ast.FileInfo_PositionEncodingByteOffset,
+			ast.FileInfo_PositionEncodingProtocCompatible,
+		} {
+			proto.GetExtension(root, ast.E_FileInfo).(*ast.FileInfo).PositionEncoding = encoding
+			nodes := collectNodes(root)
+			// put some nodes out of source order
+			nodes[0], nodes[len(nodes)-1] = nodes[len(nodes)-1], nodes[0]
+			nodes = append(nodes, nil)
+			spans := root.NodeInfoBatch(nodes)
+			require.Len(t, spans, len(nodes))
+			for i, n := range nodes {
+				info := root.NodeInfo(n)
+				assert.Equal(t, info, spans[i].NodeInfo, "%s: node %d", name, i)
+				assert.Equal(t, info.Start(), spans[i].Start(), "%s: node %d (%v)", name, i, encoding)
+				assert.Equal(t, info.End(), spans[i].End(), "%s: node %d (%v)", name, i, encoding)
+				assert.Equal(t, info.String(), spans[i].String())
+			}
+		}
+	}
+}
+
+func BenchmarkNodeInfo(b *testing.B) {
+	data, err := os.ReadFile("../internal/testdata/desc_test_complex.proto")
+	require.NoError(b, err)
+	root, err := parser.Parse("desc_test_complex.proto", bytes.NewReader(data), reporter.NewHandler(nil), 0)
+	require.NoError(b, err)
+	proto.GetExtension(root, ast.E_FileInfo).(*ast.FileInfo).PositionEncoding = ast.FileInfo_PositionEncodingProtocCompatible
+	nodes := collectNodes(root)
+
+	b.Run("individual", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, n := range nodes {
+				info := root.NodeInfo(n)
+				_, _ = info.Start(), info.End()
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, span := range root.NodeInfoBatch(nodes) {
+				_, _ = span.Start(), span.End()
+			}
+		}
+	})
+}
@@ -0,0 +1,98 @@
+// Package transform contains source-level transformations of proto files.
+// Transformations do not modify the AST. Instead, they return a set of text
+// edits that, when applied to the original source, produce the rewritten file.
+// Since edits only replace the text that changes, the formatting and comments
+// of the rest of the file are preserved.
+//
+// Individual transforms implement the Transform interface, and can be composed
+// using a Pipeline, which combines their edits and detects conflicts between
+// them.
+package transform
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// TextEdit describes a replacement of a range of text in a source file. If
+// Start and End are the same position, the edit is an insertion.
+type TextEdit struct {
+	// The start of the range to replace, inclusive.
+	Start ast.SourcePos
+	// The end of the range to replace, exclusive.
+	End ast.SourcePos
+	// The text that replaces the range.
+	NewText string
+}
+
+// Replace returns an edit that replaces the text of the given node, including
+// any interior comments, with the given text.
+func Replace(file *ast.FileNode, n ast.Node, newText string) TextEdit {
+	info := file.NodeInfo(n)
+	return TextEdit{
+		Start:   info.Start(),
+		End:     file.SourcePos(endOffset(info)),
+		NewText: newText,
+	}
+}
+
+// Insert returns an edit that inserts the given text at the given offset.
+func Insert(file *ast.FileNode, offset int, text string) TextEdit {
+	pos := file.SourcePos(offset)
+	return TextEdit{Start: pos, End: pos, NewText: text}
+}
+
+// ApplyEdits applies the given edits to the given source contents and returns
+// the result. The edits must not overlap. Edits that insert text at the same
+// position are applied in the order given.
+func ApplyEdits(contents []byte, edits []TextEdit) []byte {
+	offsetEdits := make([]edit, len(edits))
+	for i, e := range edits {
+		offsetEdits[i] = edit{start: e.Start.Offset, end: e.End.Offset, text: e.NewText}
+	}
+	return []byte(applyEdits(string(contents), 0, offsetEdits))
+}
+
+// edit is a TextEdit in terms of byte offsets.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// editLess orders edits by position, with insertions before a replacement
+// that starts at the same position.
+func editLess(a, b TextEdit) bool {
+	if a.Start.Offset != b.Start.Offset {
+		return a.Start.Offset < b.Start.Offset
+	}
+	return a.Start.Offset == a.End.Offset && b.Start.Offset != b.End.Offset
+}
+
+// endOffset returns the offset just past the end of the given element.
+func endOffset(info ast.NodeInfo) int {
+	return info.Start().Offset + len(info.RawText())
+}
+
+// applyEdits applies the given edits to text, which starts at the given offset
+// in the file. The edits must not overlap. Edits that insert text at the same
+// position are applied in the order given.
+func applyEdits(text string, offset int, edits []edit) string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		// insertions go before a replacement at the same position
+		return edits[i].start == edits[i].end && edits[j].start != edits[j].end
+	})
+	var sb strings.Builder
+	last := 0
+	for _, e := range edits {
+		sb.WriteString(text[last : e.start-offset])
+		sb.WriteString(e.text)
+		last = e.end - offset
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
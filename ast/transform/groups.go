@@ -0,0 +1,187 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// RewriteGroupsOption is an option for RewriteGroups.
+type RewriteGroupsOption func(*groupRewriter)
+
+// WithDelimitedEncoding returns an option that causes the fields produced by
+// RewriteGroups to use the "features.message_encoding = DELIMITED" option, so
+// they keep the group wire format. Groups can only be declared in proto2
+// files and this feature can only be used in files that use editions, so this
+// option is intended for use as part of migrating a file to editions; the
+// caller is responsible for also updating the file's syntax declaration.
+//
+// Without this option, the resulting fields are ordinary message fields, which
+// are length-prefixed on the wire and therefore not wire compatible with the
+// original groups.
+func WithDelimitedEncoding() RewriteGroupsOption {
+	return func(r *groupRewriter) {
+		r.delimited = true
+	}
+}
+
+// RewriteGroups returns the edits that rewrite all groups in the given file
+// into a nested message plus a field of that message type. The field keeps the
+// group's label, field number, and options. Its name is the lower-cased group
+// name, which is the name protoc gives to the field that a group declares, so
+// the names and types in the resulting descriptors are unchanged. Comments and
+// the contents of the group body are preserved.
+//
+// For groups declared directly in a message, the group is rewritten in place
+// to a message declaration, followed by the field. Messages cannot be declared
+// inside extend blocks or oneofs, so for groups declared in those, the message
+// declaration is moved immediately before the extend block or oneof, which is
+// the scope in which the group's message is defined.
+//
+// Groups with syntax errors, such as a missing field number, are ignored.
+func RewriteGroups(file *ast.FileNode, opts ...RewriteGroupsOption) []TextEdit {
+	r := &groupRewriter{file: file}
+	for _, opt := range opts {
+		opt(r)
+	}
+	var edits []edit
+	for _, decl := range file.GetDecls() {
+		switch decl := decl.Unwrap().(type) {
+		case *ast.MessageNode:
+			edits = append(edits, r.messageEdits(decl.GetDecls())...)
+		case *ast.ExtendNode:
+			edits = append(edits, r.extendEdits(decl)...)
+		}
+	}
+	textEdits := make([]TextEdit, len(edits))
+	for i, e := range edits {
+		textEdits[i] = TextEdit{
+			Start:   file.SourcePos(e.start),
+			End:     file.SourcePos(e.end),
+			NewText: e.text,
+		}
+	}
+	return textEdits
+}
+
+type groupRewriter struct {
+	file      *ast.FileNode
+	delimited bool
+}
+
+func (r *groupRewriter) messageEdits(decls []*ast.MessageElement) []edit {
+	var edits []edit
+	for _, decl := range decls {
+		switch decl := decl.Unwrap().(type) {
+		case *ast.MessageNode:
+			edits = append(edits, r.messageEdits(decl.GetDecls())...)
+		case *ast.ExtendNode:
+			edits = append(edits, r.extendEdits(decl)...)
+		case *ast.OneofNode:
+			var groups []*ast.GroupNode
+			for _, elem := range decl.GetDecls() {
+				if grp := elem.GetGroup(); grp != nil {
+					groups = append(groups, grp)
+				}
+			}
+			edits = append(edits, r.movedGroupEdits(decl, groups)...)
+		case *ast.GroupNode:
+			edits = append(edits, r.inPlaceGroupEdits(decl)...)
+		}
+	}
+	return edits
+}
+
+func (r *groupRewriter) extendEdits(ext *ast.ExtendNode) []edit {
+	var groups []*ast.GroupNode
+	for _, elem := range ext.GetDecls() {
+		if grp := elem.GetGroup(); grp != nil {
+			groups = append(groups, grp)
+		}
+	}
+	return r.movedGroupEdits(ext, groups)
+}
+
+// inPlaceGroupEdits rewrites a group declared directly in a message. The group
+// header is replaced with a message header, and the field is inserted after
+// the group's closing brace.
+func (r *groupRewriter) inPlaceGroupEdits(grp *ast.GroupNode) []edit {
+	if !isComplete(grp) {
+		return nil
+	}
+	start := r.offset(grp)
+	openBrace := r.file.TokenInfo(grp.OpenBrace.GetToken()).Start().Offset
+	end := endOffset(r.file.NodeInfo(grp))
+	edits := []edit{{start: start, end: openBrace, text: "message " + grp.Name.Val + " "}}
+	edits = append(edits, r.messageEdits(grp.GetDecls())...)
+	edits = append(edits, edit{start: end, end: end, text: "\n" + r.indent(grp) + r.fieldText(grp)})
+	return edits
+}
+
+// movedGroupEdits rewrites groups declared in the given extend block or oneof.
+// Each group is replaced with a field, and its message is declared right
+// before the container.
+func (r *groupRewriter) movedGroupEdits(container ast.Node, groups []*ast.GroupNode) []edit {
+	var edits []edit
+	// insert before the container's leading comments, if any
+	containerStart := r.offset(container)
+	if comments := r.file.NodeInfo(container).LeadingComments(); comments.Len() > 0 {
+		containerStart = comments.Index(0).Start().Offset
+	}
+	indent := r.indent(container)
+	for _, grp := range groups {
+		if !isComplete(grp) {
+			continue
+		}
+		info := r.file.NodeInfo(grp)
+		start, end := info.Start().Offset, endOffset(info)
+		raw := info.RawText()
+		openBrace := r.file.TokenInfo(grp.OpenBrace.GetToken()).Start().Offset
+		closeBrace := endOffset(r.file.TokenInfo(grp.CloseBrace.GetToken()))
+		body := applyEdits(raw[openBrace-start:closeBrace-start], openBrace, r.messageEdits(grp.GetDecls()))
+		edits = append(edits,
+			edit{start: containerStart, end: containerStart, text: "message " + grp.Name.Val + " " + body + "\n" + indent},
+			edit{start: start, end: end, text: r.fieldText(grp)},
+		)
+	}
+	return edits
+}
+
+func (r *groupRewriter) fieldText(grp *ast.GroupNode) string {
+	var sb strings.Builder
+	if grp.Label != nil {
+		sb.WriteString(grp.Label.Val)
+		sb.WriteByte(' ')
+	}
+	fmt.Fprintf(&sb, "%s %s = %s", grp.Name.Val, strings.ToLower(grp.Name.Val), r.file.NodeInfo(grp.Tag).RawText())
+	const delimited = "features.message_encoding = DELIMITED"
+	switch {
+	case grp.Options != nil && r.delimited:
+		opts := strings.TrimSuffix(r.file.NodeInfo(grp.Options).RawText(), "]")
+		sb.WriteString(" " + strings.TrimRight(opts, " \t\r\n") + ", " + delimited + "]")
+	case grp.Options != nil:
+		sb.WriteString(" " + r.file.NodeInfo(grp.Options).RawText())
+	case r.delimited:
+		sb.WriteString(" [" + delimited + "]")
+	}
+	sb.WriteByte(';')
+	return sb.String()
+}
+
+func (r *groupRewriter) offset(n ast.Node) int {
+	return r.file.NodeInfo(n).Start().Offset
+}
+
+// indent returns the whitespace that precedes the given node on its line.
+func (r *groupRewriter) indent(n ast.Node) string {
+	ws := r.file.NodeInfo(n).LeadingWhitespace()
+	if i := strings.LastIndexByte(ws, '\n'); i >= 0 {
+		return ws[i+1:]
+	}
+	return ""
+}
+
+func isComplete(grp *ast.GroupNode) bool {
+	return grp.Name != nil && grp.Tag != nil && grp.OpenBrace != nil && grp.CloseBrace != nil
+}
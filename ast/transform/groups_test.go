@@ -0,0 +1,95 @@
+package transform_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestRewriteGroups(t *testing.T) {
+	t.Parallel()
+	source := `syntax = "proto2";
+message Foo {
+  // Leading comment
+  optional group Bar = 1 [deprecated = true] {
+    optional string name = 1;
+    repeated group Baz = 2 {
+      optional int32 id = 1;
+    }
+  } // Trailing comment
+  oneof choice {
+    group Qux = 3 {
+      optional bool b = 1;
+    }
+  }
+  extensions 100 to 200;
+}
+// Extension comment
+extend Foo {
+  optional group Ext = 100 {
+    optional bytes data = 1;
+  }
+}
+`
+	expected := `syntax = "proto2";
+message Foo {
+  // Leading comment
+  message Bar {
+    optional string name = 1;
+    message Baz {
+      optional int32 id = 1;
+    }
+    repeated Baz baz = 2;
+  }
+  optional Bar bar = 1 [deprecated = true]; // Trailing comment
+  message Qux {
+      optional bool b = 1;
+    }
+  oneof choice {
+    Qux qux = 3;
+  }
+  extensions 100 to 200;
+}
+message Ext {
+    optional bytes data = 1;
+  }
+// Extension comment
+extend Foo {
+  optional Ext ext = 100;
+}
+`
+	assert.Equal(t, expected, rewriteGroups(t, source))
+}
+
+func TestRewriteGroupsDelimited(t *testing.T) {
+	t.Parallel()
+	source := `syntax = "proto2";
+message Foo {
+  optional group Bar = 1 [deprecated = true] {}
+  repeated group Baz = 2 {};
+}
+`
+	expected := `syntax = "proto2";
+message Foo {
+  message Bar {}
+  optional Bar bar = 1 [deprecated = true, features.message_encoding = DELIMITED];
+  message Baz {};
+  repeated Baz baz = 2 [features.message_encoding = DELIMITED];
+}
+`
+	assert.Equal(t, expected, rewriteGroups(t, source, transform.WithDelimitedEncoding()))
+}
+
+func rewriteGroups(t *testing.T, source string, opts ...transform.RewriteGroupsOption) string {
+	t.Helper()
+	file, err := parser.Parse("test.proto", strings.NewReader(source), reporter.NewHandler(nil), 0)
+	require.NoError(t, err)
+	edits := transform.RewriteGroups(file, opts...)
+	return string(transform.ApplyEdits([]byte(source), edits))
+}
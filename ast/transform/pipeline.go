@@ -0,0 +1,160 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Transform is a source-to-source transformation of a proto file. It inspects
+// the AST and returns the text edits that perform the transformation. Edits
+// should only touch the text that actually changes, so that the formatting and
+// comments in the rest of the file are preserved.
+type Transform interface {
+	// Name identifies the transform in error messages.
+	Name() string
+	// Edits returns the edits that perform the transformation on the given
+	// file. The edits must not overlap.
+	Edits(file *ast.FileNode) ([]TextEdit, error)
+}
+
+// Func returns a Transform with the given name that computes edits using the
+// given function.
+func Func(name string, fn func(file *ast.FileNode) ([]TextEdit, error)) Transform {
+	return funcTransform{name: name, fn: fn}
+}
+
+type funcTransform struct {
+	name string
+	fn   func(file *ast.FileNode) ([]TextEdit, error)
+}
+
+func (t funcTransform) Name() string {
+	return t.name
+}
+
+func (t funcTransform) Edits(file *ast.FileNode) ([]TextEdit, error) {
+	return t.fn(file)
+}
+
+// GroupsToMessages returns a Transform that rewrites groups into messages. See
+// RewriteGroups for details.
+func GroupsToMessages(opts ...RewriteGroupsOption) Transform {
+	return Func("groups-to-messages", func(file *ast.FileNode) ([]TextEdit, error) {
+		return RewriteGroups(file, opts...), nil
+	})
+}
+
+// ConflictError is returned from a Pipeline when edits produced by its
+// transforms overlap.
+type ConflictError struct {
+	// The names of the transforms that produced the conflicting edits. They
+	// are the same if a single transform produced overlapping edits.
+	Transform, OtherTransform string
+	// The conflicting edits.
+	Edit, OtherEdit TextEdit
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("edit from %s at %v conflicts with edit from %s at %v",
+		e.OtherTransform, e.OtherEdit.Start, e.Transform, e.Edit.Start)
+}
+
+// Pipeline composes several transforms. All transforms in the pipeline are run
+// against the same AST, and their edits are accumulated into a single set of
+// edits. If edits from any transforms overlap, the pipeline fails with a
+// *ConflictError, except that identical edits are merged into one. Insertions at the same position are applied in the order
+// in which their transforms were added to the pipeline.
+//
+// Transforms that depend on the output of another transform should be run
+// in separate pipelines, applying the edits of one before running the next.
+type Pipeline struct {
+	transforms []Transform
+}
+
+// NewPipeline returns a pipeline that runs the given transforms.
+func NewPipeline(transforms ...Transform) *Pipeline {
+	return &Pipeline{transforms: transforms}
+}
+
+// Add adds the given transforms to the end of the pipeline.
+func (p *Pipeline) Add(transforms ...Transform) {
+	p.transforms = append(p.transforms, transforms...)
+}
+
+// Edits runs all transforms in the pipeline on the given file and returns
+// their combined edits, sorted by position.
+func (p *Pipeline) Edits(file *ast.FileNode) ([]TextEdit, error) {
+	type sourcedEdit struct {
+		TextEdit
+		transform string
+	}
+	var all []sourcedEdit
+	for _, t := range p.transforms {
+		edits, err := t.Edits(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name(), err)
+		}
+		for _, e := range edits {
+			all = append(all, sourcedEdit{TextEdit: e, transform: t.Name()})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return editLess(all[i].TextEdit, all[j].TextEdit)
+	})
+
+	result := make([]TextEdit, 0, len(all))
+	var prev *sourcedEdit
+	for i := range all {
+		e := &all[i]
+		if len(result) > 0 && e.TextEdit == result[len(result)-1] {
+			continue
+		}
+		if prev != nil {
+			if overlaps(prev.TextEdit, e.TextEdit) {
+				return nil, &ConflictError{
+					Transform:      e.transform,
+					OtherTransform: prev.transform,
+					Edit:           e.TextEdit,
+					OtherEdit:      prev.TextEdit,
+				}
+			}
+		}
+		// Since edits are sorted by start, an edit can only overlap the
+		// preceding edit with the furthest end.
+		if prev == nil || e.End.Offset >= prev.End.Offset {
+			prev = e
+		}
+		result = append(result, e.TextEdit)
+	}
+	return result, nil
+}
+
+// Apply parses the given file contents, runs all transforms in the pipeline,
+// and returns the contents with the resulting edits applied.
+func (p *Pipeline) Apply(filename string, contents []byte) ([]byte, error) {
+	file, err := parser.Parse(filename, bytes.NewReader(contents), reporter.NewHandler(nil), 0)
+	if err != nil {
+		return nil, err
+	}
+	edits, err := p.Edits(file)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyEdits(contents, edits), nil
+}
+
+// overlaps returns true if b, which does not start before a, overlaps a. An
+// insertion overlaps a replacement if it is strictly inside the replaced
+// range. Two replacements overlap if they share any text.
+func overlaps(a, b TextEdit) bool {
+	if a.Start.Offset == a.End.Offset || b.Start.Offset == b.End.Offset {
+		// insertions only conflict with text that is being replaced
+		return b.Start.Offset > a.Start.Offset && b.Start.Offset < a.End.Offset
+	}
+	return b.Start.Offset < a.End.Offset
+}
@@ -0,0 +1,68 @@
+package transform_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/transform"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+	source := `syntax = "proto2";
+message Foo {
+  optional group Bar = 1 {}
+}
+`
+	// renames the message Foo to Renamed
+	rename := transform.Func("rename", func(file *ast.FileNode) ([]transform.TextEdit, error) {
+		msg := file.GetDecls()[0].GetMessage()
+		return []transform.TextEdit{transform.Replace(file, msg.Name, "Renamed")}, nil
+	})
+	// appends a comment to the end of the file
+	comment := transform.Func("comment", func(file *ast.FileNode) ([]transform.TextEdit, error) {
+		return []transform.TextEdit{transform.Insert(file, file.SourceSize(), "// done\n")}, nil
+	})
+
+	p := transform.NewPipeline(rename, transform.GroupsToMessages())
+	p.Add(comment, comment)
+	result, err := p.Apply("test.proto", []byte(source))
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto2";
+message Renamed {
+  message Bar {}
+  optional Bar bar = 1;
+}
+// done
+`, string(result))
+}
+
+func TestPipelineConflicts(t *testing.T) {
+	t.Parallel()
+	source := `syntax = "proto2";
+message Foo {
+  optional group Bar = 1 {}
+}
+`
+	// replaces the whole group with a field
+	replaceGroup := transform.Func("replace-group", func(file *ast.FileNode) ([]transform.TextEdit, error) {
+		grp := file.GetDecls()[0].GetMessage().GetDecls()[0].GetGroup()
+		return []transform.TextEdit{transform.Replace(file, grp, "optional int32 bar = 1;")}, nil
+	})
+	_, err := transform.NewPipeline(transform.GroupsToMessages(), replaceGroup).Apply("test.proto", []byte(source))
+	var conflict *transform.ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "groups-to-messages", conflict.OtherTransform)
+	assert.Equal(t, "replace-group", conflict.Transform)
+	assert.Equal(t, "edit from groups-to-messages at test.proto:3:3 conflicts with edit from replace-group at test.proto:3:3", err.Error())
+
+	failing := transform.Func("failing", func(*ast.FileNode) ([]transform.TextEdit, error) {
+		return nil, errors.New("oops")
+	})
+	_, err = transform.NewPipeline(failing).Apply("test.proto", []byte(source))
+	require.EqualError(t, err, "failing: oops")
+}
@@ -0,0 +1,186 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TriviaKind describes the contents of a Trivia.
+type TriviaKind int
+
+const (
+	// TriviaWhitespace is a run of whitespace characters: spaces, tabs,
+	// newlines, carriage returns, form feeds, and vertical tabs.
+	TriviaWhitespace TriviaKind = iota + 1
+	// TriviaSkipped is a run of other characters that the lexer could not
+	// tokenize and skipped after reporting an error, such as an invalid
+	// character or an unterminated string literal. Files that were lexed
+	// without errors never contain skipped trivia.
+	TriviaSkipped
+)
+
+func (k TriviaKind) String() string {
+	switch k {
+	case TriviaWhitespace:
+		return "whitespace"
+	case TriviaSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// Trivia is a run of source text that is not part of any item: whitespace,
+// or input that could not be lexed. See the package documentation for how
+// trivia relates to items.
+type Trivia struct {
+	fileInfo   *FileInfo
+	kind       TriviaKind
+	start, end int
+	next       Item
+}
+
+var _ SourceSpan = Trivia{}
+
+// IsValid returns true if t is valid. If t is a zero-value struct, it is not
+// valid.
+func (t Trivia) IsValid() bool {
+	return t.fileInfo != nil
+}
+
+// Kind returns the kind of characters in the trivia.
+func (t Trivia) Kind() TriviaKind {
+	return t.kind
+}
+
+// NextItem returns the item that immediately follows the trivia. Since the
+// last item in a file is always EOF, all trivia is followed by an item.
+func (t Trivia) NextItem() Item {
+	return t.next
+}
+
+// StartOffset returns the byte offset of the first character of the trivia.
+func (t Trivia) StartOffset() int {
+	return t.start
+}
+
+// EndOffset returns the byte offset just after the last character of the
+// trivia.
+func (t Trivia) EndOffset() int {
+	return t.end
+}
+
+// Start returns the position of the first character of the trivia.
+func (t Trivia) Start() SourcePos {
+	return t.fileInfo.SourcePos(t.start)
+}
+
+// End returns the position just after the last character of the trivia. Like
+// NodeInfo.End, this is on the same line as the last character, even if that
+// character is a newline.
+func (t Trivia) End() SourcePos {
+	pos := t.fileInfo.SourcePos(t.end - 1)
+	pos.Col++
+	return pos
+}
+
+// RawText returns the text of the trivia.
+func (t Trivia) RawText() string {
+	return string(t.fileInfo.Data[t.start:t.end])
+}
+
+// Newlines returns the number of line breaks in the trivia.
+func (t Trivia) Newlines() int {
+	return strings.Count(t.RawText(), "\n")
+}
+
+func (t Trivia) String() string {
+	start, end := t.Start(), t.End()
+	return fmt.Sprintf("%s:%d:%d-%d:%d: %s %q", start.Filename, start.Line, start.Col, end.Line, end.Col, t.kind, t.RawText())
+}
+
+// TriviaBefore returns the trivia between the given item and the item before
+// it, in source order. This is empty if the two items are adjacent. Unlike
+// LeadingWhitespace, which skips over zero-length virtual tokens, this only
+// considers the immediately preceding item, so every character of trivia in
+// the file is reported before exactly one item.
+func (f *FileInfo) TriviaBefore(i Item) []Trivia {
+	if f.isDummyFile() || i < 0 || int(i) >= len(f.ItemList) {
+		return nil
+	}
+	span := f.ItemList[i]
+	var start int32
+	if i > 0 {
+		prev := f.ItemList[i-1]
+		start = prev.Offset + prev.Length
+	}
+	return f.splitTrivia(int(start), int(span.Offset), i, nil)
+}
+
+// Trivia returns all of the trivia in the file, in source order.
+func (f *FileInfo) Trivia() []Trivia {
+	if f.isDummyFile() {
+		return nil
+	}
+	var trivia []Trivia
+	var start int32
+	for i, span := range f.ItemList {
+		trivia = f.splitTrivia(int(start), int(span.Offset), Item(i), trivia)
+		if end := span.Offset + span.Length; end > start {
+			start = end
+		}
+	}
+	return trivia
+}
+
+// splitTrivia appends the runs of whitespace and skipped input in the given
+// range to trivia.
+func (f *FileInfo) splitTrivia(start, end int, next Item, trivia []Trivia) []Trivia {
+	for start < end {
+		kind := TriviaSkipped
+		if isTriviaSpace(f.Data[start]) {
+			kind = TriviaWhitespace
+		}
+		runEnd := start + 1
+		for runEnd < end && isTriviaSpace(f.Data[runEnd]) == (kind == TriviaWhitespace) {
+			runEnd++
+		}
+		trivia = append(trivia, Trivia{fileInfo: f, kind: kind, start: start, end: runEnd, next: next})
+		start = runEnd
+	}
+	return trivia
+}
+
+func isTriviaSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '\v':
+		return true
+	default:
+		return false
+	}
+}
+
+// TriviaBefore returns the trivia between the given item and the item before
+// it. See FileInfo.TriviaBefore.
+func (f *FileNode) TriviaBefore(i Item) []Trivia {
+	return f.fileInfo().TriviaBefore(i)
+}
+
+// Trivia returns all of the trivia in the file, in source order.
+func (f *FileNode) Trivia() []Trivia {
+	return f.fileInfo().Trivia()
+}
@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestTrivia(t *testing.T) {
+	t.Parallel()
+	source := "syntax = \"proto3\";\n\n// comment\nmessage Foo {\n\tint32 x = 1; $ \n}\n"
+	h := reporter.NewHandler(reporter.NewReporter(func(reporter.ErrorWithPos) error { return nil }, nil))
+	root, _ := parser.Parse("test.proto", bytes.NewReader([]byte(source)), h, 0)
+	require.NotNil(t, root)
+
+	// items and trivia together reproduce the file
+	trivia := root.Trivia()
+	var buf strings.Builder
+	var skipped []string
+	next := 0
+	for item, ok := root.Items().First(); ok; item, ok = root.Items().Next(item) {
+		for next < len(trivia) && trivia[next].NextItem() == item {
+			buf.WriteString(trivia[next].RawText())
+			if trivia[next].Kind() == ast.TriviaSkipped {
+				skipped = append(skipped, trivia[next].RawText())
+			}
+			next++
+		}
+		buf.WriteString(root.ItemInfo(item).RawText())
+	}
+	assert.Equal(t, len(trivia), next)
+	assert.Equal(t, source, buf.String())
+	assert.Equal(t, []string{"$"}, skipped)
+
+	tok, ok := root.Tokens().First()
+	require.True(t, ok)
+	tok, _ = root.Tokens().Next(tok)
+	before := root.TriviaBefore(tok.AsItem())
+	require.Len(t, before, 1)
+	assert.Equal(t, ast.TriviaWhitespace, before[0].Kind())
+	assert.Equal(t, " ", before[0].RawText())
+	assert.Equal(t, ast.SourcePos{Filename: "test.proto", Line: 1, Col: 7, Offset: 6}, before[0].Start())
+	assert.Equal(t, ast.SourcePos{Filename: "test.proto", Line: 1, Col: 8, Offset: 6}, before[0].End())
+
+	item := ast.Item(-1)
+	for tok, ok := root.Tokens().First(); ok; tok, ok = root.Tokens().Next(tok) {
+		if root.TokenInfo(tok).RawText() == "message" {
+			item = tok.AsItem()
+			break
+		}
+	}
+	require.True(t, item.IsValid())
+	assert.Equal(t, []string{"\n"}, triviaTexts(root.TriviaBefore(item)))
+	comment, ok := root.Items().Previous(item)
+	require.True(t, ok)
+	assert.Equal(t, []string{"\n\n"}, triviaTexts(root.TriviaBefore(comment)))
+	assert.Equal(t, 2, root.TriviaBefore(comment)[0].Newlines())
+}
+
+func triviaTexts(trivia []ast.Trivia) []string {
+	var texts []string
+	for _, t := range trivia {
+		texts = append(texts, t.RawText())
+	}
+	return texts
+}
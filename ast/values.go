@@ -17,6 +17,7 @@ package ast
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 )
 
@@ -80,6 +81,30 @@ func (n *StringLiteralNode) AsString() string {
 	return n.Val
 }
 
+// IsRaw returns true if this is a non-standard triple-quoted raw string
+// literal, such as """a\b""". These are only produced by the parser when
+// raw strings are enabled along with extended syntax. For raw literals,
+// Raw contains the whole literal, including the delimiters.
+func (n *StringLiteralNode) IsRaw() bool {
+	raw := n.GetRaw()
+	if len(raw) < 6 {
+		return false
+	}
+	for _, delim := range []string{`"""`, `'''`} {
+		if strings.HasPrefix(string(raw), delim) && strings.HasSuffix(string(raw), delim) {
+			return true
+		}
+	}
+	return false
+}
+
+// StandardLiteral returns the value of this literal as a standard
+// double-quoted string literal, with escapes where needed. Formatters can
+// use it to replace raw string literals with their standard equivalent.
+func (n *StringLiteralNode) StandardLiteral() string {
+	return strconv.Quote(n.Val)
+}
+
 func (n *CompoundStringLiteralNode) Start() Token {
 	if len(n.Elements) == 0 {
 		return TokenError
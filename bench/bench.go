@@ -0,0 +1,295 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bench provides standardized benchmarks that run against a corpus
+// of proto source files, so that users can track the performance of this
+// module on their own sources over time.
+//
+// A corpus is loaded into memory with LoadCorpus, so that reading files from
+// disk is not measured. The benchmarks can be run with Run, which produces
+// machine-readable results (see Report), or from a Go benchmark with
+// Benchmark:
+//
+//	func BenchmarkCorpus(b *testing.B) {
+//		corpus, err := bench.LoadCorpus("protos")
+//		if err != nil {
+//			b.Fatal(err)
+//		}
+//		for _, kind := range bench.Kinds() {
+//			b.Run(kind.String(), func(b *testing.B) {
+//				bench.Benchmark(b, corpus, kind, bench.Options{})
+//			})
+//		}
+//	}
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Corpus is a set of proto source files, held in memory.
+type Corpus struct {
+	// The paths of the files in the corpus, relative to the directory from
+	// which it was loaded, using forward slashes. The paths are sorted.
+	Paths []string
+	// The contents of the files, keyed by path.
+	Sources map[string]string
+	// Directories in which to find imports that are not in the corpus. The
+	// standard imports are always available.
+	ImportPaths []string
+}
+
+// LoadCorpus loads all .proto files in the given directory and its
+// subdirectories into memory. Imports are resolved relative to dir, and then
+// relative to the given import paths, which are read from disk when
+// needed.
+func LoadCorpus(dir string, importPaths ...string) (*Corpus, error) {
+	corpus := &Corpus{
+		Sources:     map[string]string{},
+		ImportPaths: importPaths,
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".proto" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		corpus.Paths = append(corpus.Paths, rel)
+		corpus.Sources[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(corpus.Paths) == 0 {
+		return nil, fmt.Errorf("no .proto files found in %s", dir)
+	}
+	sort.Strings(corpus.Paths)
+	return corpus, nil
+}
+
+// Kind identifies a benchmark.
+type Kind int
+
+const (
+	// Parse parses each file in the corpus into an AST, without producing
+	// descriptors.
+	Parse Kind = iota + 1
+	// Compile compiles all files in the corpus with a new compiler, which
+	// includes parsing, linking, interpreting options, and generating source
+	// code info, depending on the compiler's configuration.
+	Compile
+	// IncrementalEdit compiles all files in the corpus with a workspace, and
+	// then measures recompiling after each edit of a single file. See
+	// Options.EditPath.
+	IncrementalEdit
+)
+
+// Kinds returns all kinds of benchmarks, in the order they should be run.
+func Kinds() []Kind {
+	return []Kind{Parse, Compile, IncrementalEdit}
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Parse:
+		return "Parse"
+	case Compile:
+		return "Compile"
+	case IncrementalEdit:
+		return "IncrementalEdit"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Options configure a benchmark.
+type Options struct {
+	// If non-nil, this returns a compiler with the configuration to measure.
+	// Its resolver is replaced with one that provides the corpus. If nil,
+	// compilers produce standard source code info.
+	NewCompiler func() *protocompile.Compiler
+	// The file that is edited by the IncrementalEdit benchmark. If empty,
+	// the first file in the corpus is used. Files that many other files
+	// import are slower to recompile than leaves.
+	EditPath string
+	// The minimum number of iterations and the minimum duration for which Run
+	// measures each benchmark. If both are zero, a benchmark runs for at
+	// least one second.
+	MinIterations int
+	MinDuration   time.Duration
+}
+
+func (o *Options) newCompiler(corpus *Corpus) *protocompile.Compiler {
+	var c *protocompile.Compiler
+	if o.NewCompiler != nil {
+		c = o.NewCompiler()
+	} else {
+		c = &protocompile.Compiler{SourceInfoMode: protocompile.SourceInfoStandard}
+	}
+	c.Resolver = protocompile.WithStandardImports(protocompile.CompositeResolver{
+		&protocompile.SourceResolver{Accessor: protocompile.SourceAccessorFromMap(corpus.Sources)},
+		&protocompile.SourceResolver{ImportPaths: corpus.ImportPaths},
+	})
+	return c
+}
+
+// op is a single iteration of a benchmark.
+type op func(ctx context.Context, i int) error
+
+func prepare(ctx context.Context, corpus *Corpus, kind Kind, opts Options) (op, error) {
+	paths := make([]protocompile.ResolvedPath, len(corpus.Paths))
+	for i, path := range corpus.Paths {
+		paths[i] = protocompile.ResolvedPath(path)
+	}
+	switch kind {
+	case Parse:
+		return func(context.Context, int) error {
+			for _, path := range corpus.Paths {
+				src := corpus.Sources[path]
+				if _, err := parser.Parse(path, bytes.NewReader([]byte(src)), reporter.NewHandler(nil), 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case Compile:
+		return func(ctx context.Context, _ int) error {
+			_, err := opts.newCompiler(corpus).Compile(ctx, paths...)
+			return err
+		}, nil
+	case IncrementalEdit:
+		editPath := opts.EditPath
+		if editPath == "" {
+			editPath = corpus.Paths[0]
+		}
+		original, ok := corpus.Sources[editPath]
+		if !ok {
+			return nil, fmt.Errorf("file to edit %q is not in the corpus", editPath)
+		}
+		ws := protocompile.NewWorkspace(opts.newCompiler(corpus))
+		if err := ws.Open(ctx, paths...); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, i int) error {
+			// each edit differs from the last, so that the file and its
+			// dependents are always recompiled
+			contents := fmt.Sprintf("%s\n// edit %d\n", original, i)
+			return ws.UpdateFile(ctx, protocompile.ResolvedPath(editPath), contents)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown benchmark kind %v", kind)
+	}
+}
+
+// Benchmark runs the given benchmark as a Go benchmark, which allows it to
+// be run with "go test -bench" and compared with tools such as benchstat.
+// Setup, such as the initial compilation for IncrementalEdit, is excluded
+// from the measurement. The MinIterations and MinDuration options are
+// ignored; use the -benchtime flag instead.
+func Benchmark(b *testing.B, corpus *Corpus, kind Kind, opts Options) {
+	b.Helper()
+	ctx := context.Background()
+	run, err := prepare(ctx, corpus, kind, opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := run(ctx, i); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Run runs the given benchmarks against the corpus and returns a report of
+// the results. If no kinds are given, all kinds are run. It returns an error
+// if any file in the corpus fails to parse or compile.
+func Run(ctx context.Context, corpus *Corpus, opts Options, kinds ...Kind) (*Report, error) {
+	if len(kinds) == 0 {
+		kinds = Kinds()
+	}
+	report := newReport(corpus)
+	for _, kind := range kinds {
+		result, err := measure(ctx, corpus, kind, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", kind, err)
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+func measure(ctx context.Context, corpus *Corpus, kind Kind, opts Options) (Result, error) {
+	run, err := prepare(ctx, corpus, kind, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	minIterations, minDuration := opts.MinIterations, opts.MinDuration
+	if minIterations <= 0 && minDuration <= 0 {
+		minDuration = time.Second
+	}
+	if minIterations <= 0 {
+		minIterations = 1
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var n int
+	for n < minIterations || time.Since(start) < minDuration {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		if err := run(ctx, n); err != nil {
+			return Result{}, err
+		}
+		n++
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return Result{
+		Name:        kind.String(),
+		Iterations:  n,
+		NsPerOp:     elapsed.Nanoseconds() / int64(n),
+		AllocsPerOp: int64((after.Mallocs - before.Mallocs) / uint64(n)),
+		BytesPerOp:  int64((after.TotalAlloc - before.TotalAlloc) / uint64(n)),
+	}, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/bench"
+)
+
+func BenchmarkCorpus(b *testing.B) {
+	corpus, err := bench.LoadCorpus("testdata")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, kind := range bench.Kinds() {
+		b.Run(kind.String(), func(b *testing.B) {
+			bench.Benchmark(b, corpus, kind, bench.Options{})
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	corpus, err := bench.LoadCorpus("testdata")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo/common.proto", "service.proto"}, corpus.Paths)
+
+	report, err := bench.Run(context.Background(), corpus, bench.Options{MinIterations: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Files)
+	require.Len(t, report.Results, 3)
+	for i, kind := range bench.Kinds() {
+		result := report.Results[i]
+		assert.Equal(t, kind.String(), result.Name)
+		assert.GreaterOrEqual(t, result.Iterations, 3)
+		assert.Positive(t, result.NsPerOp)
+		assert.Positive(t, result.AllocsPerOp)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	roundTripped, err := bench.ReadReport(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, report, roundTripped)
+
+	buf.Reset()
+	require.NoError(t, report.WriteBenchFormat(&buf))
+	assert.Regexp(t, `(?m)^BenchmarkIncrementalEdit-\d+\t\d+\t\d+ ns/op\t\d+ B/op\t\d+ allocs/op$`, buf.String())
+
+	_, err = bench.Run(context.Background(), corpus, bench.Options{EditPath: "missing.proto"}, bench.IncrementalEdit)
+	assert.ErrorContains(t, err, `IncrementalEdit: file to edit "missing.proto" is not in the corpus`)
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	baseline := &bench.Report{Results: []bench.Result{
+		{Name: "Parse", NsPerOp: 1000, BytesPerOp: 100, AllocsPerOp: 10},
+		{Name: "Compile", NsPerOp: 1000, BytesPerOp: 100, AllocsPerOp: 10},
+	}}
+	current := &bench.Report{Results: []bench.Result{
+		{Name: "Parse", NsPerOp: 1050, BytesPerOp: 200, AllocsPerOp: 10},
+		{Name: "Compile", NsPerOp: 2000, BytesPerOp: 90, AllocsPerOp: 10},
+		{Name: "IncrementalEdit", NsPerOp: 5000},
+	}}
+	regressions := bench.Compare(baseline, current, 0.1)
+	require.Len(t, regressions, 2)
+	assert.Equal(t, "Parse: B/op regressed from 100 to 200 (+100.0%)", regressions[0].String())
+	assert.Equal(t, "Compile: ns/op regressed from 1000 to 2000 (+100.0%)", regressions[1].String())
+	assert.InDelta(t, 2.0, regressions[1].Ratio(), 0.001)
+}
@@ -0,0 +1,148 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// Report is the machine-readable result of Run. It is serialized as JSON by
+// WriteJSON and can be read back with ReadReport, so reports from different
+// runs can be compared with Compare.
+type Report struct {
+	GoVersion string `json:"goVersion"`
+	GOOS      string `json:"goos"`
+	GOARCH    string `json:"goarch"`
+	NumCPU    int    `json:"numCPU"`
+	// The number of files and the total size in bytes of the corpus.
+	Files   int      `json:"files"`
+	Bytes   int      `json:"bytes"`
+	Results []Result `json:"results"`
+}
+
+// Result is the measurement of a single benchmark.
+type Result struct {
+	// The name of the benchmark, which is the String() of its Kind.
+	Name        string `json:"name"`
+	Iterations  int    `json:"iterations"`
+	NsPerOp     int64  `json:"nsPerOp"`
+	AllocsPerOp int64  `json:"allocsPerOp"`
+	BytesPerOp  int64  `json:"bytesPerOp"`
+}
+
+func newReport(corpus *Corpus) *Report {
+	report := &Report{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		Files:     len(corpus.Paths),
+	}
+	for _, src := range corpus.Sources {
+		report.Bytes += len(src)
+	}
+	return report
+}
+
+// Result returns the result with the given name, and false if there is none.
+func (r *Report) Result(name string) (Result, bool) {
+	for _, result := range r.Results {
+		if result.Name == name {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+// WriteJSON writes the report to w as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport reads a report that was written with WriteJSON.
+func ReadReport(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// WriteBenchFormat writes the results to w in the format of "go test -bench"
+// output, so that they can be compared with tools such as benchstat.
+func (r *Report) WriteBenchFormat(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "goos: %s\ngoarch: %s\n", r.GOOS, r.GOARCH); err != nil {
+		return err
+	}
+	for _, result := range r.Results {
+		_, err := fmt.Fprintf(w, "Benchmark%s-%d\t%d\t%d ns/op\t%d B/op\t%d allocs/op\n",
+			result.Name, r.NumCPU, result.Iterations, result.NsPerOp, result.BytesPerOp, result.AllocsPerOp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Regression describes a metric of a benchmark that got worse.
+type Regression struct {
+	Name string
+	// The metric that regressed: "ns/op", "B/op", or "allocs/op".
+	Metric   string
+	Baseline int64
+	Current  int64
+}
+
+// Ratio returns the current value relative to the baseline value.
+func (r Regression) Ratio() float64 {
+	return float64(r.Current) / float64(r.Baseline)
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s regressed from %d to %d (%+.1f%%)", r.Name, r.Metric, r.Baseline, r.Current, (r.Ratio()-1)*100)
+}
+
+// Compare returns the metrics of the current report that exceed the same
+// metric in the baseline report by more than the given threshold, which is a
+// fraction of the baseline value. For example, a threshold of 0.1 reports
+// metrics that are more than 10% worse. Benchmarks that are missing from the
+// baseline are ignored.
+func Compare(baseline, current *Report, threshold float64) []Regression {
+	var regressions []Regression
+	for _, cur := range current.Results {
+		base, ok := baseline.Result(cur.Name)
+		if !ok {
+			continue
+		}
+		for _, m := range []struct {
+			metric         string
+			baseline, curr int64
+		}{
+			{"ns/op", base.NsPerOp, cur.NsPerOp},
+			{"B/op", base.BytesPerOp, cur.BytesPerOp},
+			{"allocs/op", base.AllocsPerOp, cur.AllocsPerOp},
+		} {
+			if m.baseline > 0 && float64(m.curr) > float64(m.baseline)*(1+threshold) {
+				regressions = append(regressions, Regression{Name: cur.Name, Metric: m.metric, Baseline: m.baseline, Current: m.curr})
+			}
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,124 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/annotations"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// CodeLens holds facts about a declaration that editors display alongside
+// it, such as with LSP code lenses. See Workspace.CodeLenses.
+type CodeLens struct {
+	Descriptor protoreflect.Descriptor
+	// The span of the declaration.
+	Span ast.SourceSpan
+	// The number of references to the element in all files in the workspace,
+	// including dependencies of tracked files.
+	References int
+	// For a message, the methods in the workspace that use it as their
+	// request or response type, sorted by name.
+	Methods []protoreflect.MethodDescriptor
+	// The element's deprecation, or nil if it is not deprecated.
+	Deprecation *annotations.Deprecation
+}
+
+// CodeLenses returns a code lens for each declaration in the given file, in
+// the order they are declared. Map entry messages and the synthetic oneofs
+// of proto3 optional fields have no declarations, so they have no code lens.
+// It returns nil if the file has not been compiled or has no AST.
+func (w *Workspace) CodeLenses(path ResolvedPath) []CodeLens {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil
+	}
+
+	type key struct {
+		file string
+		name protoreflect.FullName
+	}
+	keyOf := func(d protoreflect.Descriptor) key {
+		return key{file: d.ParentFile().Path(), name: d.FullName()}
+	}
+	references := map[key]int{}
+	methods := map[key][]protoreflect.MethodDescriptor{}
+	for _, f := range w.files {
+		if r, ok := f.(linker.Result); ok {
+			r.RangeReferences(func(_ ast.NodeReference, to protoreflect.Descriptor) bool {
+				references[keyOf(to)]++
+				return true
+			})
+		}
+		_ = walk.Descriptors(f, func(d protoreflect.Descriptor) error {
+			if mtd, ok := d.(protoreflect.MethodDescriptor); ok {
+				methods[keyOf(mtd.Input())] = append(methods[keyOf(mtd.Input())], mtd)
+				if mtd.Output().FullName() != mtd.Input().FullName() {
+					methods[keyOf(mtd.Output())] = append(methods[keyOf(mtd.Output())], mtd)
+				}
+			}
+			return nil
+		})
+	}
+	for _, mtds := range methods {
+		sort.Slice(mtds, func(i, j int) bool {
+			return mtds[i].FullName() < mtds[j].FullName()
+		})
+	}
+
+	var lenses []CodeLens
+	_ = walk.Descriptors(res, func(d protoreflect.Descriptor) error {
+		node := res.Node(protoutil.ProtoFromDescriptor(d))
+		if node == nil {
+			return nil
+		}
+		switch d := d.(type) {
+		case protoreflect.MessageDescriptor:
+			if d.IsMapEntry() {
+				return nil
+			}
+		case protoreflect.FieldDescriptor:
+			if msg, ok := d.Parent().(protoreflect.MessageDescriptor); ok && msg.IsMapEntry() {
+				return nil
+			}
+		case protoreflect.OneofDescriptor:
+			if d.IsSynthetic() {
+				return nil
+			}
+		}
+		lens := CodeLens{
+			Descriptor: d,
+			Span:       res.AST().NodeInfo(node),
+			References: references[keyOf(d)],
+		}
+		if _, ok := d.(protoreflect.MessageDescriptor); ok {
+			lens.Methods = methods[keyOf(d)]
+		}
+		if deprecation, ok := annotations.GetDeprecation(d); ok {
+			lens.Deprecation = &deprecation
+		}
+		lenses = append(lenses, lens)
+		return nil
+	})
+	return lenses
+}
@@ -0,0 +1,73 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceCodeLenses(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(map[UnresolvedPath]string{
+			"a.proto": `syntax = "proto3";
+package foo;
+message Req {
+  option deprecated = true;
+  map<string, int32> counts = 1;
+  optional string name = 2;
+}
+message Resp {}
+`,
+			"b.proto": `syntax = "proto3";
+package foo;
+import "a.proto";
+service Svc {
+  rpc Get(Req) returns (Resp);
+  rpc List(Req) returns (stream Resp);
+}
+message Wrapper { Req req = 1; }
+`,
+		})),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto"))
+	assert.Nil(t, ws.CodeLenses("c.proto"))
+
+	var actual []string
+	for _, lens := range ws.CodeLenses("a.proto") {
+		var methods []string
+		for _, mtd := range lens.Methods {
+			methods = append(methods, string(mtd.Name()))
+		}
+		desc := fmt.Sprintf("%s %s refs=%d methods=[%s]", lens.Span.Start(), lens.Descriptor.FullName(), lens.References, strings.Join(methods, ","))
+		if lens.Deprecation != nil {
+			desc += " deprecated"
+		}
+		actual = append(actual, desc)
+	}
+	assert.Equal(t, []string{
+		"a.proto:3:1 foo.Req refs=3 methods=[Get,List] deprecated",
+		"a.proto:5:3 foo.Req.counts refs=0 methods=[]",
+		"a.proto:6:3 foo.Req.name refs=0 methods=[]",
+		"a.proto:8:1 foo.Resp refs=2 methods=[Get,List]",
+	}, actual)
+}
@@ -0,0 +1,373 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package command implements a command-line interface over the compiler that
+// is compatible with a subset of protoc's flags. It is intended to be embedded
+// in other binaries, so it does not call os.Exit or read os.Args itself.
+//
+// The following flags are supported:
+//
+//	-IPATH, --proto_path=PATH   Adds a directory in which to search for imports.
+//	                            May be given multiple times. PATH may also be a
+//	                            list of directories separated by the OS path
+//	                            list separator. Defaults to the current directory.
+//	-oFILE,                     Writes a FileDescriptorSet containing the given
+//	  --descriptor_set_out=FILE files to FILE.
+//	--include_imports           Includes all dependencies of the given files in
+//	                            the descriptor set.
+//	--include_source_info       Retains source code info in the descriptor set.
+//	--NAME_out=[PARAMS:]DIR     Runs the plugin protoc-gen-NAME and writes the
+//	                            files it generates to DIR.
+//	--NAME_opt=PARAMS           Passes additional parameters to protoc-gen-NAME.
+//	--plugin=[protoc-gen-NAME=]PATH
+//	                            Uses the executable at PATH for the plugin NAME,
+//	                            instead of finding protoc-gen-NAME in the PATH.
+//
+// As with protoc, all flags that take a value accept it either in the same
+// argument, after "=", or in the next argument. Any argument that is not a
+// flag is a file to compile. Files given as paths on disk that are inside an
+// import path are compiled relative to that import path. The file "-" is
+// read from standard input; its imports are found in the import paths.
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Command is a protoc-compatible command-line interface.
+type Command struct {
+	// Where the file "-" is read from. If nil, os.Stdin is used.
+	Stdin io.Reader
+	// Where diagnostics are written. If nil, os.Stderr is used.
+	Stderr io.Writer
+	// Runs plugins for --NAME_out flags. If nil, ExecPluginRunner is used.
+	PluginRunner PluginRunner
+	// Optional function for returning a file's contents. If nil, files are
+	// read from the file system. See protocompile.SourceResolver.Accessor.
+	Accessor func(path protocompile.ResolvedPath) (io.ReadCloser, error)
+	// Optional function that can customize the compiler before it is used,
+	// for example to set a Reporter, hooks, or additional checks. Its Resolver
+	// and SourceInfoMode are already set when it is called.
+	ConfigureCompiler func(*protocompile.Compiler)
+}
+
+// Main runs the command with the given arguments, which should not include
+// the program name, and returns the exit code for the process: zero on
+// success and one on failure. Errors are written to stderr.
+func Main(ctx context.Context, args []string, stderr io.Writer) int {
+	cmd := &Command{Stderr: stderr}
+	if err := cmd.Run(ctx, args); err != nil {
+		if !errors.Is(err, reporter.ErrInvalidSource) {
+			// compilation errors were already written
+			fmt.Fprintln(cmd.stderr(), err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// options are the parsed command-line flags.
+type options struct {
+	importPaths       []string
+	files             []string
+	descriptorSetOut  string
+	includeImports    bool
+	includeSourceInfo bool
+	plugins           []*pluginOutput
+	pluginPaths       map[string]string
+}
+
+// pluginOutput is a --NAME_out flag, along with any --NAME_opt flags.
+type pluginOutput struct {
+	name   string
+	params []string
+	outDir string
+}
+
+// Run parses the given arguments, compiles the given files, and writes the
+// requested outputs. Compilation errors and warnings are written to Stderr;
+// in that case the returned error is reporter.ErrInvalidSource. Other errors,
+// such as invalid flags, are returned but not written.
+func (c *Command) Run(ctx context.Context, args []string) error {
+	opts, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(opts.files) == 0 {
+		return errors.New("missing input file")
+	}
+	if opts.descriptorSetOut == "" && len(opts.plugins) == 0 {
+		return errors.New("missing output directives")
+	}
+
+	// "-" is compiled separately, from stdin
+	paths := make([]protocompile.ResolvedPath, 0, len(opts.files))
+	for _, file := range opts.files {
+		if file != "-" {
+			paths = append(paths, protocompile.ResolvedPath(virtualPath(file, opts.importPaths)))
+		}
+	}
+	sourceInfo := protocompile.SourceInfoNone
+	if opts.includeSourceInfo || len(opts.plugins) > 0 {
+		sourceInfo = protocompile.SourceInfoStandard
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: opts.importPaths,
+			Accessor:    c.Accessor,
+		}),
+		SourceInfoMode: sourceInfo,
+		Deterministic:  true,
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				fmt.Fprintln(c.stderr(), err)
+				return nil
+			},
+			func(err reporter.ErrorWithPos) {
+				fmt.Fprintf(c.stderr(), "%v: warning: %v\n", err.GetPosition(), err.Unwrap())
+			},
+		),
+	}
+	if c.ConfigureCompiler != nil {
+		c.ConfigureCompiler(compiler)
+	}
+	var res protocompile.CompileResult
+	if len(paths) > 0 {
+		if res, err = compiler.Compile(ctx, paths...); err != nil {
+			return err
+		}
+	}
+	// Compile de-duplicates the given paths, so results are looked up by
+	// path to put them in the order of the arguments, merged with the result
+	// for stdin. A file given more than once is only output once.
+	roots := make(linker.Files, 0, len(opts.files))
+	seen := map[string]struct{}{}
+	for _, file := range opts.files {
+		if file == "-" {
+			stdinRes, err := compiler.CompileReader(ctx, file, c.stdin())
+			if err != nil {
+				return err
+			}
+			roots = append(roots, stdinRes)
+			continue
+		}
+		path := virtualPath(file, opts.importPaths)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		f := res.Files.FindFileByPath(path)
+		if f == nil {
+			return fmt.Errorf("%s: file not found", file)
+		}
+		roots = append(roots, f)
+	}
+
+	if opts.descriptorSetOut != "" {
+		if err := writeDescriptorSet(opts, roots); err != nil {
+			return err
+		}
+	}
+	runner := c.PluginRunner
+	if runner == nil {
+		runner = ExecPluginRunner{}
+	}
+	for _, plugin := range opts.plugins {
+		if err := runPlugin(ctx, runner, plugin, opts.pluginPaths[plugin.name], roots); err != nil {
+			return fmt.Errorf("--%s_out: %w", plugin.name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Command) stdin() io.Reader {
+	if c.Stdin == nil {
+		return os.Stdin
+	}
+	return c.Stdin
+}
+
+func (c *Command) stderr() io.Writer {
+	if c.Stderr == nil {
+		return os.Stderr
+	}
+	return c.Stderr
+}
+
+func parseArgs(args []string) (*options, error) {
+	opts := &options{pluginPaths: map[string]string{}}
+	pluginsByName := map[string]*pluginOutput{}
+	pluginParams := map[string][]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if arg == "-" && slices.Contains(opts.files, arg) {
+				return nil, errors.New("standard input (-) given more than once")
+			}
+			opts.files = append(opts.files, arg)
+			continue
+		}
+		var name, value string
+		var hasValue bool
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue = strings.Cut(arg, "=")
+		case len(arg) > 2:
+			// short flags take their value in the same argument, like -Ifoo
+			name, value, hasValue = arg[:2], arg[2:], true
+		default:
+			name = arg
+		}
+		// flags without values
+		switch name {
+		case "--include_imports":
+			opts.includeImports = true
+			continue
+		case "--include_source_info":
+			opts.includeSourceInfo = true
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("missing value for flag %s", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch {
+		case name == "-I" || name == "--proto_path":
+			opts.importPaths = append(opts.importPaths, filepath.SplitList(value)...)
+		case name == "-o" || name == "--descriptor_set_out":
+			opts.descriptorSetOut = value
+		case name == "--plugin":
+			pluginName, path, ok := strings.Cut(value, "=")
+			if !ok {
+				path = value
+				pluginName = strings.TrimSuffix(filepath.Base(value), filepath.Ext(value))
+			}
+			pluginName = strings.TrimPrefix(pluginName, "protoc-gen-")
+			opts.pluginPaths[pluginName] = path
+		case strings.HasPrefix(name, "--") && strings.HasSuffix(name, "_out"):
+			pluginName := strings.TrimSuffix(name[2:], "_out")
+			if _, ok := pluginsByName[pluginName]; ok {
+				return nil, fmt.Errorf("flag %s given more than once", name)
+			}
+			plugin := &pluginOutput{name: pluginName, outDir: value}
+			if params, dir, ok := strings.Cut(value, ":"); ok && !isDriveLetter(params) {
+				plugin.params = append(plugin.params, params)
+				plugin.outDir = dir
+			}
+			pluginsByName[pluginName] = plugin
+			opts.plugins = append(opts.plugins, plugin)
+		case strings.HasPrefix(name, "--") && strings.HasSuffix(name, "_opt"):
+			pluginName := strings.TrimSuffix(name[2:], "_opt")
+			pluginParams[pluginName] = append(pluginParams[pluginName], value)
+		default:
+			return nil, fmt.Errorf("unknown flag: %s", name)
+		}
+	}
+	for name, params := range pluginParams {
+		plugin, ok := pluginsByName[name]
+		if !ok {
+			return nil, fmt.Errorf("--%s_opt given without --%s_out", name, name)
+		}
+		plugin.params = append(plugin.params, params...)
+	}
+	if len(opts.importPaths) == 0 {
+		opts.importPaths = []string{"."}
+	}
+	return opts, nil
+}
+
+// isDriveLetter returns true if s is a Windows drive letter, which is part of
+// an output directory rather than plugin parameters.
+func isDriveLetter(s string) bool {
+	return len(s) == 1 && filepath.VolumeName(s+":") != ""
+}
+
+// virtualPath returns the path of the given file relative to the first of the
+// import paths that contains it. If none contains it, the file is returned
+// unchanged, to be resolved relative to the import paths.
+func virtualPath(file string, importPaths []string) string {
+	for _, importPath := range importPaths {
+		if importPath == "." {
+			continue
+		}
+		rel, err := filepath.Rel(importPath, file)
+		if err == nil && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(file))
+}
+
+// fileProtos returns the descriptor protos for the given files, sorted so that
+// each file's dependencies come before it. If includeImports is false, only
+// the given files are returned, in the order given.
+func fileProtos(roots linker.Files, includeImports, includeSourceInfo bool) []*descriptorpb.FileDescriptorProto {
+	files := roots
+	if includeImports {
+		files = linker.ComputeReflexiveTransitiveClosure(roots)
+	}
+	protos := make([]*descriptorpb.FileDescriptorProto, len(files))
+	for i, file := range files {
+		fdp := protoutil.ProtoFromFileDescriptor(file)
+		if !includeSourceInfo && fdp.SourceCodeInfo != nil {
+			// the proto may be shared with the compiler's result, so don't
+			// modify it in place
+			fdp = proto.Clone(fdp).(*descriptorpb.FileDescriptorProto)
+			fdp.SourceCodeInfo = nil
+		}
+		protos[i] = fdp
+	}
+	return protos
+}
+
+func writeDescriptorSet(opts *options, roots linker.Files) error {
+	fds := &descriptorpb.FileDescriptorSet{
+		File: fileProtos(roots, opts.includeImports, opts.includeSourceInfo),
+	}
+	data, err := proto.Marshal(fds)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(opts.descriptorSetOut, data, 0o666); err != nil {
+		return fmt.Errorf("--descriptor_set_out: %w", err)
+	}
+	return nil
+}
+
+// pathsOf returns the paths of the given files.
+func pathsOf(files linker.Files) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path()
+	}
+	return slices.Clip(paths)
+}
@@ -0,0 +1,246 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o777))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o666))
+	}
+}
+
+func readDescriptorSet(t *testing.T, path string) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var fds descriptorpb.FileDescriptorSet
+	require.NoError(t, proto.Unmarshal(data, &fds))
+	return &fds
+}
+
+func testFiles(t *testing.T) string {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"protos/foo/a.proto": `
+			syntax = "proto3";
+			package foo;
+			import "foo/b.proto";
+			import "google/protobuf/empty.proto";
+			message A { B b = 1; }
+			service S { rpc M(A) returns (google.protobuf.Empty); }`,
+		"protos/foo/b.proto": `
+			syntax = "proto3";
+			package foo;
+			message B {}`,
+	})
+	return dir
+}
+
+func TestParseArgs(t *testing.T) {
+	t.Parallel()
+	opts, err := parseArgs([]string{
+		"-Ia", "-I", "b", "--proto_path=c" + string(filepath.ListSeparator) + "d",
+		"-oout.pb", "--include_imports",
+		"--plugin=protoc-gen-x=/bin/gen", "--plugin", "/usr/bin/protoc-gen-y",
+		"--x_out=p1,p2:gen/x", "--x_opt=p3", "--y_out", "gen/y",
+		"foo.proto", "bar.proto",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, opts.importPaths)
+	assert.Equal(t, []string{"foo.proto", "bar.proto"}, opts.files)
+	assert.Equal(t, "out.pb", opts.descriptorSetOut)
+	assert.True(t, opts.includeImports)
+	assert.False(t, opts.includeSourceInfo)
+	assert.Equal(t, map[string]string{"x": "/bin/gen", "y": "/usr/bin/protoc-gen-y"}, opts.pluginPaths)
+	assert.Equal(t, []*pluginOutput{
+		{name: "x", params: []string{"p1,p2", "p3"}, outDir: "gen/x"},
+		{name: "y", outDir: "gen/y"},
+	}, opts.plugins)
+
+	opts, err = parseArgs([]string{"foo.proto"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"."}, opts.importPaths)
+
+	_, err = parseArgs([]string{"--bogus", "foo.proto"})
+	assert.EqualError(t, err, "unknown flag: --bogus")
+	_, err = parseArgs([]string{"foo.proto", "-I"})
+	assert.EqualError(t, err, "missing value for flag -I")
+	_, err = parseArgs([]string{"--x_opt=a", "foo.proto"})
+	assert.EqualError(t, err, "--x_opt given without --x_out")
+	_, err = parseArgs([]string{"-", "foo.proto", "-"})
+	assert.EqualError(t, err, "standard input (-) given more than once")
+}
+
+func TestDescriptorSetOut(t *testing.T) {
+	t.Parallel()
+	dir := testFiles(t)
+	protos := filepath.Join(dir, "protos")
+	out := filepath.Join(dir, "out.pb")
+
+	cmd := &Command{}
+	err := cmd.Run(context.Background(), []string{"-I", protos, "-o", out, filepath.Join(protos, "foo/a.proto")})
+	require.NoError(t, err)
+	fds := readDescriptorSet(t, out)
+	require.Len(t, fds.File, 1)
+	assert.Equal(t, "foo/a.proto", fds.File[0].GetName())
+	assert.Nil(t, fds.File[0].SourceCodeInfo)
+
+	err = cmd.Run(context.Background(), []string{"-I" + protos, "--descriptor_set_out=" + out, "--include_imports", "--include_source_info", "foo/a.proto"})
+	require.NoError(t, err)
+	fds = readDescriptorSet(t, out)
+	var names []string
+	for _, fd := range fds.File {
+		names = append(names, fd.GetName())
+	}
+	assert.Equal(t, []string{"foo/b.proto", "google/protobuf/empty.proto", "foo/a.proto"}, names)
+	assert.NotNil(t, fds.File[2].SourceCodeInfo)
+}
+
+func TestStdin(t *testing.T) {
+	t.Parallel()
+	dir := testFiles(t)
+	protos := filepath.Join(dir, "protos")
+	out := filepath.Join(dir, "out.pb")
+
+	cmd := &Command{Stdin: strings.NewReader(`
+		syntax = "proto3";
+		package bar;
+		import "foo/b.proto";
+		message C { foo.B b = 1; }`)}
+	err := cmd.Run(context.Background(), []string{"-I", protos, "-o", out, "foo/a.proto", "-"})
+	require.NoError(t, err)
+	fds := readDescriptorSet(t, out)
+	require.Len(t, fds.File, 2)
+	assert.Equal(t, "foo/a.proto", fds.File[0].GetName())
+	assert.Equal(t, "-", fds.File[1].GetName())
+	assert.Equal(t, ".foo.B", fds.File[1].MessageType[0].Field[0].GetTypeName())
+}
+
+func TestDuplicateAndMissingFiles(t *testing.T) {
+	t.Parallel()
+	dir := testFiles(t)
+	protos := filepath.Join(dir, "protos")
+	out := filepath.Join(dir, "out.pb")
+
+	cmd := &Command{}
+	err := cmd.Run(context.Background(), []string{"-I", protos, "-o", out, "foo/b.proto", "foo/a.proto", "foo/b.proto"})
+	require.NoError(t, err)
+	fds := readDescriptorSet(t, out)
+	require.Len(t, fds.File, 2)
+	assert.Equal(t, "foo/b.proto", fds.File[0].GetName())
+	assert.Equal(t, "foo/a.proto", fds.File[1].GetName())
+
+	err = cmd.Run(context.Background(), []string{"-I", protos, "-o", filepath.Join(dir, "missing.pb"), "foo/a.proto", "foo/missing.proto"})
+	assert.EqualError(t, err, "foo/missing.proto: file not found")
+	_, err = os.Stat(filepath.Join(dir, "missing.pb"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCompileErrors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"bad.proto": `syntax = "proto3"; message M { Unknown u = 1; }`,
+	})
+	var stderr bytes.Buffer
+	cmd := &Command{Stderr: &stderr}
+	err := cmd.Run(context.Background(), []string{"-I", dir, "-o", filepath.Join(dir, "out.pb"), "bad.proto"})
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Contains(t, stderr.String(), `bad.proto:1:32-39: field M.u: unknown type Unknown`)
+	_, err = os.Stat(filepath.Join(dir, "out.pb"))
+	assert.True(t, os.IsNotExist(err))
+
+	stderr.Reset()
+	assert.Equal(t, 1, Main(context.Background(), []string{"bad.proto"}, &stderr))
+	assert.Equal(t, "missing output directives\n", stderr.String())
+}
+
+type fakeRunner struct {
+	names, paths []string
+	reqs         []*pluginpb.CodeGeneratorRequest
+	resp         *pluginpb.CodeGeneratorResponse
+}
+
+func (r *fakeRunner) RunPlugin(_ context.Context, name, path string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	r.names = append(r.names, name)
+	r.paths = append(r.paths, path)
+	r.reqs = append(r.reqs, req)
+	return r.resp, nil
+}
+
+func TestPlugins(t *testing.T) {
+	t.Parallel()
+	dir := testFiles(t)
+	protos := filepath.Join(dir, "protos")
+	gen := filepath.Join(dir, "gen")
+	runner := &fakeRunner{
+		resp: &pluginpb.CodeGeneratorResponse{
+			File: []*pluginpb.CodeGeneratorResponse_File{
+				{Name: proto.String("foo/a.txt"), Content: proto.String("generated")},
+			},
+		},
+	}
+	cmd := &Command{PluginRunner: runner}
+	err := cmd.Run(context.Background(), []string{
+		"-I", protos, "--plugin=protoc-gen-txt=/path/to/gen",
+		"--txt_out=a=b:" + gen, "--txt_opt=c", "foo/a.proto", "foo/b.proto",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"txt"}, runner.names)
+	assert.Equal(t, []string{"/path/to/gen"}, runner.paths)
+	req := runner.reqs[0]
+	assert.Equal(t, []string{"foo/a.proto", "foo/b.proto"}, req.FileToGenerate)
+	assert.Equal(t, "a=b,c", req.GetParameter())
+	var names []string
+	for _, fd := range req.ProtoFile {
+		names = append(names, fd.GetName())
+	}
+	assert.Equal(t, []string{"foo/b.proto", "google/protobuf/empty.proto", "foo/a.proto"}, names)
+	assert.NotNil(t, req.ProtoFile[2].SourceCodeInfo)
+	data, err := os.ReadFile(filepath.Join(gen, "foo", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "generated", string(data))
+
+	runner.resp = &pluginpb.CodeGeneratorResponse{Error: proto.String("bad parameter")}
+	err = cmd.Run(context.Background(), []string{"-I", protos, "--txt_out=" + gen, "foo/a.proto"})
+	assert.EqualError(t, err, "--txt_out: bad parameter")
+
+	runner.resp = &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("../escape.txt"), Content: proto.String("")},
+		},
+	}
+	err = cmd.Run(context.Background(), []string{"-I", protos, "--txt_out=" + gen, "foo/a.proto"})
+	assert.EqualError(t, err, `--txt_out: plugin generated invalid file name "../escape.txt"`)
+}
@@ -0,0 +1,127 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/kralicky/protocompile/linker"
+)
+
+// PluginRunner runs code generator plugins.
+type PluginRunner interface {
+	// RunPlugin runs the plugin with the given name, such as "go" for
+	// protoc-gen-go, and returns its response. If the plugin's location was
+	// given with a --plugin flag, path is that location; otherwise it is empty.
+	//
+	// An error should only be returned if the plugin could not be run. Errors
+	// reported by the plugin itself belong in the response's Error field.
+	RunPlugin(ctx context.Context, name, path string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+}
+
+// ExecPluginRunner is a PluginRunner that runs plugins as executables, like
+// protoc does: the request is written to the plugin's stdin and the response
+// is read from its stdout. If no path is given for a plugin, an executable
+// named protoc-gen-NAME is searched for in the PATH.
+type ExecPluginRunner struct {
+	// Where the plugin's stderr is written. If nil, os.Stderr is used.
+	Stderr io.Writer
+}
+
+var _ PluginRunner = ExecPluginRunner{}
+
+func (r ExecPluginRunner) RunPlugin(ctx context.Context, name, path string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	if path == "" {
+		path = "protoc-gen-" + name
+	}
+	reqData, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(reqData)
+	cmd.Stdout = &stdout
+	cmd.Stderr = r.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var resp pluginpb.CodeGeneratorResponse
+	if err := proto.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse response: %w", path, err)
+	}
+	return &resp, nil
+}
+
+// compilerVersion is reported to plugins in the CodeGeneratorRequest. The
+// compiler supports the same language as this version of protoc.
+var compilerVersion = &pluginpb.Version{
+	Major: proto.Int32(27),
+	Minor: proto.Int32(0),
+	Patch: proto.Int32(0),
+}
+
+func runPlugin(ctx context.Context, runner PluginRunner, plugin *pluginOutput, path string, roots linker.Files) error {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:  pathsOf(roots),
+		ProtoFile:       fileProtos(roots, true, true),
+		CompilerVersion: compilerVersion,
+	}
+	if len(plugin.params) > 0 {
+		req.Parameter = proto.String(strings.Join(plugin.params, ","))
+	}
+	resp, err := runner.RunPlugin(ctx, plugin.name, path, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return errors.New(resp.GetError())
+	}
+	for _, file := range resp.File {
+		if err := writeGeneratedFile(plugin.outDir, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeGeneratedFile(outDir string, file *pluginpb.CodeGeneratorResponse_File) error {
+	if file.GetInsertionPoint() != "" {
+		return fmt.Errorf("%s: insertion points are not supported", file.GetName())
+	}
+	name := filepath.FromSlash(file.GetName())
+	if name == "" || filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return fmt.Errorf("plugin generated invalid file name %q", file.GetName())
+	}
+	path := filepath.Join(outDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(file.GetContent()), 0o666)
+}
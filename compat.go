@@ -0,0 +1,135 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// CompatibilityMode selects protoc's behavior in the places where a Compiler
+// deliberately differs from it by default. Each field enables protoc's
+// behavior for one such difference. The zero value uses protocompile's
+// behavior for all of them, and ProtocCompatibility uses protoc's.
+//
+// Only observable output is affected: descriptors, source code info, and the
+// formatting of diagnostics. Whether a file compiles successfully is the same
+// in either mode, except that a synthetic oneof name chosen like protoc may
+// conflict with another element (see SyntheticOneofNames).
+type CompatibilityMode struct {
+	// If true, column numbers in source code info and in the positions of
+	// diagnostics are computed like protoc: a tab advances to the next
+	// multiple of 8 columns, and a multi-byte character counts as a single
+	// column. By default, columns count bytes. For source code info only,
+	// this is equivalent to SourceInfoProtocCompatible.
+	Columns bool
+
+	// If true, comments in source code info are always attributed exactly
+	// like protoc, even if SourceInfoExtraComments is set. By default, extra
+	// comments also donate comments that precede a closing symbol, like "}",
+	// to the previous element when protoc would leave them out because they
+	// are on the same line as both. See ast.AttributeComments.
+	CommentAttribution bool
+
+	// If true, the synthetic oneofs of proto3 optional fields are named like
+	// protoc: the name only avoids conflicts with the names of fields and
+	// oneofs. By default, it avoids conflicts with all elements of the
+	// message, since protoc's name can conflict with a nested message, enum,
+	// enum value, or extension, making the file fail to compile. See
+	// parser.WithProtocSyntheticOneofNames. The naming that was used for a
+	// file is reported by parser.SyntheticOneofNamingOf for its result.
+	SyntheticOneofNames bool
+
+	// If true, the position of each diagnostic sent to the Reporter is only
+	// its start, so that diagnostics are formatted like protoc's, as
+	// "file:line:col: message". By default, the position is the full span of
+	// the element, formatted as "file:line:col-col: message".
+	ErrorPositions bool
+}
+
+// ProtocCompatibility is the CompatibilityMode that behaves like protoc in
+// every respect that it controls.
+var ProtocCompatibility = CompatibilityMode{
+	Columns:             true,
+	CommentAttribution:  true,
+	SyntheticOneofNames: true,
+	ErrorPositions:      true,
+}
+
+// IsProtoc returns true if m behaves like protoc in every respect that it
+// controls.
+func (m CompatibilityMode) IsProtoc() bool {
+	return m == ProtocCompatibility
+}
+
+func (m CompatibilityMode) positionEncoding() ast.FileInfo_PositionEncoding {
+	if m.Columns {
+		return ast.FileInfo_PositionEncodingProtocCompatible
+	}
+	return ast.FileInfo_PositionEncodingByteOffset
+}
+
+// reporter returns the reporter to which the compiler sends diagnostics,
+// which formats them according to c.Compatibility.
+func (c *Compiler) reporter() reporter.Reporter {
+	if !c.Compatibility.ErrorPositions {
+		return c.Reporter
+	}
+	rep := c.Reporter
+	if rep == nil {
+		rep = reporter.NewReporter(nil, nil)
+	}
+	return startPositionReporter{rep}
+}
+
+// startPositionReporter reduces the position of every diagnostic to its start.
+type startPositionReporter struct {
+	reporter.Reporter
+}
+
+func (r startPositionReporter) Error(err reporter.ErrorWithPos) error {
+	return r.Reporter.Error(atStartPosition(err))
+}
+
+func (r startPositionReporter) Warning(err reporter.ErrorWithPos) {
+	r.Reporter.Warning(atStartPosition(err))
+}
+
+func atStartPosition(err reporter.ErrorWithPos) reporter.ErrorWithPos {
+	start := err.GetPosition().Start()
+	return startPositionError{ErrorWithPos: err, start: ast.NewSourceSpan(start, start)}
+}
+
+// startPositionError is an error whose position is reduced to its start. It
+// unwraps to the original error, so that its concrete type can still be
+// found with errors.As.
+type startPositionError struct {
+	reporter.ErrorWithPos
+	start ast.SourceSpan
+}
+
+func (e startPositionError) Error() string {
+	return fmt.Sprintf("%s: %v", e.start, e.ErrorWithPos.Unwrap())
+}
+
+func (e startPositionError) GetPosition() ast.SourceSpan {
+	return e.start
+}
+
+func (e startPositionError) Unwrap() error {
+	return e.ErrorWithPos
+}
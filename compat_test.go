@@ -0,0 +1,79 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestCompatibilityMode(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"bad.proto": "syntax = \"proto3\";\nmessage Foo {\n\tUnknown\tu = 1;\n}\n",
+		"opt.proto": "syntax = \"proto3\";\nmessage Foo {\n\toptional int32 foo = 1;\n\tenum _foo { X = 0; }\n}\n",
+	}
+	compile := func(mode CompatibilityMode, path ResolvedPath) (linker.Files, []string, error) {
+		var errs []string
+		compiler := Compiler{
+			Resolver: &SourceResolver{
+				Accessor: SourceAccessorFromMap(sources),
+			},
+			SourceInfoMode: SourceInfoStandard,
+			Compatibility:  mode,
+			Reporter: reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			}, nil),
+		}
+		res, err := compiler.Compile(context.Background(), path)
+		return res.Files, errs, err
+	}
+
+	_, errs, err := compile(CompatibilityMode{}, "bad.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Equal(t, []string{"bad.proto:3:2-9: field Foo.u: unknown type Unknown"}, errs)
+
+	_, errs, err = compile(ProtocCompatibility, "bad.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Equal(t, []string{"bad.proto:3:9: field Foo.u: unknown type Unknown"}, errs)
+
+	files, _, err := compile(CompatibilityMode{}, "opt.proto")
+	require.NoError(t, err)
+	msg := files[0].Messages().Get(0)
+	assert.Equal(t, "X_foo", string(msg.Oneofs().Get(0).Name()))
+	assert.Equal(t, parser.SyntheticOneofNamingDefault, parser.SyntheticOneofNamingOf(files[0].(linker.Result)))
+	// columns count bytes by default
+	assert.Equal(t, 1, files[0].SourceLocations().ByDescriptor(msg.Fields().Get(0)).StartColumn)
+
+	files, _, err = compile(CompatibilityMode{SyntheticOneofNames: true}, "opt.proto")
+	require.Error(t, err)
+	assert.Empty(t, files)
+
+	files, _, err = compile(CompatibilityMode{Columns: true}, "opt.proto")
+	require.NoError(t, err)
+	msg = files[0].Messages().Get(0)
+	assert.Equal(t, 8, files[0].SourceLocations().ByDescriptor(msg.Fields().Get(0)).StartColumn)
+
+	assert.True(t, ProtocCompatibility.IsProtoc())
+	assert.False(t, CompatibilityMode{Columns: true}.IsProtoc())
+}
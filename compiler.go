@@ -17,22 +17,28 @@ package protocompile
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"runtime"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
-	"golang.org/x/sync/semaphore"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 
 	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/linker"
 	"github.com/kralicky/protocompile/options"
 	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/reporter"
 	"github.com/kralicky/protocompile/sourceinfo"
 )
@@ -58,8 +64,22 @@ type Compiler struct {
 	Resolver Resolver
 	// The maximum parallelism to use when compiling. If unspecified or set to
 	// a non-positive value, then min(runtime.NumCPU(), runtime.GOMAXPROCS(-1))
-	// will be used.
+	// will be used. This limits CPU-bound work, such as parsing and linking.
 	MaxParallelism int
+	// If positive, the maximum number of source files that may be read at
+	// once, which is limited separately from MaxParallelism since reading is
+	// usually I/O-bound. When set, each source is read into memory before
+	// the file is parsed. Otherwise, sources are read while they are parsed,
+	// subject to MaxParallelism.
+	MaxIOParallelism int
+	// If true, the limit on reading sources adapts to contention. It starts
+	// at MaxIOParallelism, or at twice the CPU limit if MaxIOParallelism is
+	// not set, and is halved whenever reads become much slower than the
+	// fastest reads seen, which indicates that concurrent reads are
+	// contending for the disk. It is raised again, up to its starting value,
+	// while reads are fast and other reads are waiting. CompileResult.Concurrency
+	// reports how the limits were used.
+	AdaptiveParallelism bool
 	// A custom error and warning reporter. If unspecified a default reporter
 	// is used. A default reporter fails the compilation after encountering any
 	// errors and ignores all warnings.
@@ -98,13 +118,231 @@ type Compiler struct {
 	// only the requested files will be included in the results.
 	IncludeDependenciesInResults bool
 
+	// If not nil and IncludeDependenciesInResults is true, only dependencies
+	// for which this function returns true are included in the results. The
+	// requested files are always included, and all dependencies are still
+	// linked, so excluded dependencies are still reachable from the results
+	// via the requested files' imports. See DirectDependencies and
+	// DependenciesMatching.
+	DependencyFilter DependencyFilter
+
 	Hooks CompilerHooks
 
 	InterpretOptionsLenient bool
 
+	// If non-nil, this file is used as the definition of
+	// "google/protobuf/descriptor.proto" when interpreting options and
+	// validating features, for files that do not import descriptor.proto
+	// themselves. This is typically a custom fork of descriptor.proto, compiled
+	// ahead of time, that defines additional options or features.
+	//
+	// If nil and Resolver returns its own version of descriptor.proto (one
+	// whose resolved path is not the standard import), then that version is
+	// used in the same way.
+	OverrideDescriptorProto linker.File
+
+	// If true, options are not interpreted at all. Resulting descriptors
+	// retain all options in their "uninterpreted_option" fields, including
+	// the json_name and default pseudo-options, for use by downstream tools
+	// that do their own interpretation. If source code info is generated, it
+	// describes the uninterpreted form of the options.
+	//
+	// Since custom options are not resolved, no checks are performed that
+	// require interpreted options, and imports are not reported as unused.
+	SkipOptionInterpretation bool
+
+	// Additional field pseudo-options, keyed by option name, that are consumed
+	// by the given handlers instead of being interpreted. Such options are
+	// removed from the resulting descriptors. This is an opt-in for
+	// experimental annotations that are meant for tooling; it is empty by
+	// default, in which case these options are reported as unknown like any
+	// other unrecognized option. See [options.WithFieldPseudoOption].
+	//
+	// Handlers may be called concurrently when multiple files are compiled.
+	FieldPseudoOptions map[string]options.FieldPseudoOptionHandler
+
+	// Validators for custom options, keyed by the fully-qualified name of the
+	// extension. A validator is called with the interpreted value of the
+	// option for each element that sets it, and may report diagnostics, which
+	// makes the compiler the point where contracts encoded in options, such as
+	// validation rules, are enforced. Validators are called for all files
+	// whose options are interpreted, including dependencies. See
+	// [options.WithOptionValidator].
+	//
+	// Validators may be called concurrently when multiple files are compiled.
+	OptionValidators map[protoreflect.FullName]options.OptionValidator
+
+	// If not off, the field constraints of protovalidate (buf.validate.field)
+	// and protoc-gen-validate (validate.rules) are checked for internal
+	// consistency, such as bounds that are out of order and patterns that
+	// are not valid regular expressions, and problems are reported at this
+	// level. Like OptionValidators, this applies to all files whose options
+	// are interpreted. See options.ConstraintValidators.
+	ConstraintCheck linker.CheckLevel
+
+	// Optional hygiene checks for the field numbers of messages in the files
+	// being compiled, such as fields declared out of numeric order. These are
+	// not performed for files that are only compiled as dependencies. See
+	// linker.FieldNumberChecks.
+	FieldNumberChecks linker.FieldNumberChecks
+
+	// If not off, references in the files being compiled to types that are
+	// declared in files they do not import directly, and that are only
+	// visible through public imports, are reported at this level. Each
+	// finding's underlying error is a linker.ErrorIndirectDependency, which
+	// includes an edit that adds the missing import. Files that are only
+	// compiled as dependencies are not checked. See
+	// linker.CheckDirectDependencies.
+	DirectDependencyCheck linker.CheckLevel
+
+	// If not off, fields in the files being compiled that refer to closed
+	// enums where open semantics are expected, such as map values, are
+	// reported at this level. Each finding's underlying error is a
+	// linker.ErrorClosedEnumUsage, which describes where the enum's
+	// enum_type feature was set. Files that are only compiled as
+	// dependencies are not checked. See linker.CheckEnumSemantics.
+	EnumSemanticsCheck linker.CheckLevel
+
+	// Optional checks that file options, such as go_package, are consistent
+	// across the files being compiled that are in the same package. These are
+	// reported after all files are compiled, so they are sent to the Reporter
+	// but not to Hooks.FileDiagnostics. Files that are only compiled as
+	// dependencies are not checked. When enabled, the ASTs of the files being
+	// compiled are kept until the checks are done, even if RetainASTs is false,
+	// so that positions can be reported. See linker.PackageOptionChecks.
+	PackageOptionChecks linker.PackageOptionChecks
+
+	// Optional limits on the serialized size of the descriptors of the files
+	// being compiled, and of a descriptor set that contains them. Like
+	// PackageOptionChecks, these are reported after all files are compiled,
+	// so they are sent to the Reporter but not to Hooks.FileDiagnostics. See
+	// linker.SizeBudget.
+	SizeBudget linker.SizeBudget
+
+	// If true, errors and warnings are sent to the Reporter in a stable order
+	// that does not depend on the order in which files happened to be compiled:
+	// they are buffered until all files are compiled and then reported sorted
+	// by file name and position. Since the Reporter is not called until the
+	// end, an error returned from it cannot stop compilation early, but it is
+	// still returned from Compile. Diagnostics sent to Hooks.FileDiagnostics
+	// are unaffected.
+	//
+	// Regardless of this setting, the files in a CompileResult are always in
+	// a deterministic order. See CompileResult.Files.
+	Deterministic bool
+
+	// If true (and RetainResults is also true), memory backing the descriptors
+	// of linked files is recycled when those files are invalidated by a later
+	// call to Compile, and reused when linking other files. This reduces GC
+	// pressure for long-lived compilers that recompile continuously.
+	//
+	// When enabled, a result must not be used once it has been invalidated:
+	// its descriptors are zeroed and reused for other files as soon as the
+	// Hooks.PostInvalidate hook (if any) returns for that file. Callers must
+	// drop all references to results (and descriptors obtained from them) that
+	// were returned from prior calls to Compile for invalidated files.
+	PoolDescriptors bool
+
+	// If positive, the maximum number of files that may be parsed but not yet
+	// linked at any one time. When compiling a large number of files, parsing
+	// can otherwise get far ahead of linking, which can use a lot of memory
+	// since every parsed file's AST is retained until it has been linked.
+	// Files that are needed as imports of files already being linked are
+	// never delayed, so this limit may be exceeded in order to make progress.
+	MaxUnlinkedASTs int
+	// If positive, an approximate budget, in bytes of source code, for files
+	// that have been parsed but not yet linked. Like MaxUnlinkedASTs, this
+	// delays parsing of additional files until enough files have been linked
+	// to bring usage under the budget. The memory used by an AST is roughly
+	// proportional to the size of its source.
+	ParseMemoryBudget int64
+
+	// If true, panics that occur while compiling a file are recovered and
+	// reported as an internal error diagnostic for that file, whose underlying
+	// error is a PanicError that includes the stack trace. The file fails to
+	// compile, but other files continue to be compiled. This is intended for
+	// long-running processes, such as language servers, which must not crash
+	// due to a bug triggered by one file.
+	RecoverPanics bool
+
+	// If positive, the maximum time the compiler waits for a call to one of
+	// the Hooks to return. A hook that takes longer is abandoned: a warning,
+	// whose underlying error is a HookError, is reported for the file being
+	// processed, and compilation continues without waiting for the hook. By
+	// default, the compiler waits for hooks indefinitely.
+	HookTimeout time.Duration
+
+	// Limits applied when parsing each file, to guard against pathological
+	// inputs. The zero value applies no limits; parser.DefaultLimits provides
+	// generous limits suitable for long-running processes.
+	ParseLimits parser.Limits
+
+	// Overrides the limits on package names and message nesting depth that
+	// are checked when validating each file. Zero values use protoc's limits;
+	// see parser.ValidationLimits.
+	ValidationLimits parser.ValidationLimits
+
+	// The legacy encoding of source files that are not valid UTF-8. If set,
+	// invalid bytes are transcoded from this encoding, with a warning,
+	// instead of being read as replacement characters.
+	SourceEncoding parser.Encoding
+
+	// If true, source files must be valid UTF-8, and an error is reported at
+	// each invalid byte. By default, like protoc, invalid bytes are accepted.
+	StrictUTF8 bool
+
+	// If true, non-standard triple-quoted raw string literals are accepted
+	// when extended syntax is enabled. See parser.WithRawStrings.
+	RawStrings bool
+
+	// If true, files may use editions that are newer than the latest fully
+	// supported edition, up to editions.MaxExperimentalEdition, such as to
+	// try out the symbol visibility modifiers of edition 2024. See
+	// parser.WithExperimentalEditions.
+	ExperimentalEditions bool
+
+	// Selects protoc's behavior where the compiler otherwise deliberately
+	// differs from it. Use ProtocCompatibility to behave like protoc in every
+	// such respect. See CompatibilityMode.
+	Compatibility CompatibilityMode
+
+	// If true, a weak import that cannot be resolved is reported as a warning
+	// instead of an error, and the importing file is linked without it. Any
+	// references to elements that the missing file would define still fail
+	// to resolve.
+	AllowMissingWeakImports bool
+
+	// Policies for the "weak" and "public" import modifiers, respectively. By
+	// default, both are allowed. Each use of a modifier is otherwise reported,
+	// as a warning or error, at the modifier in the import statement.
+	WeakImportPolicy   ImportPolicy
+	PublicImportPolicy ImportPolicy
+
+	// If not nil, structured debug and trace events are logged here, such as
+	// files being scheduled, resolved, and compiled, cached results being
+	// reused, and the start and end of each compilation phase. Phase events
+	// use LevelTrace. Events are only constructed if the logger is enabled for
+	// their level.
+	Logger *slog.Logger
+
 	exec *executor
 }
 
+// CompilerHooks are callbacks that a Compiler invokes as it compiles files.
+//
+// Hooks are called synchronously, from the goroutine compiling (or
+// invalidating) the file in question. Hooks that accept a context receive the
+// context given to Compile, or one derived from it. If Compiler.HookTimeout
+// is set, each hook is instead called from its own goroutine, and the
+// compiler stops waiting for it once the timeout elapses or the context is
+// done. A hook that is abandoned this way must not assume that any values
+// passed to it remain valid.
+//
+// A panic in a hook never crashes the compiler. It is recovered and reported
+// as an internal error diagnostic for the file in question, whose underlying
+// error is a HookError. (Panics raised while running a phase passed to
+// RunPhase are not hook panics; they are handled according to
+// Compiler.RecoverPanics.)
 type CompilerHooks struct {
 	// If not nil, called before a file is invalidated.
 	// Will be called before any dependencies have been invalidated.
@@ -123,6 +361,19 @@ type CompilerHooks struct {
 	PreCompile func(path ResolvedPath)
 	// If not nil, called after a file has been compiled.
 	PostCompile func(path ResolvedPath)
+	// If not nil, called to run each phase of compiling a file. The hook must
+	// call run exactly once, with the given context or one derived from it.
+	// This can be used to attach pprof labels (see PprofLabels) or to create
+	// tracing spans for each phase.
+	RunPhase func(ctx context.Context, path ResolvedPath, phase Phase, run func(context.Context))
+	// If not nil, called once compiling a file has settled, with all errors
+	// and warnings reported while compiling that file, sorted by position. It
+	// is called before the file's result is made available to dependents and
+	// to Compile. It is called even if there were no diagnostics, so that
+	// stale diagnostics from a previous compilation can be cleared. This lets
+	// callers, such as language servers, publish diagnostics for each file as
+	// soon as it is ready. Diagnostics are still also sent to the Reporter.
+	FileDiagnostics func(path ResolvedPath, diags []reporter.Diagnostic)
 }
 
 // SourceInfoMode indicates how source code info is generated by a Compiler.
@@ -144,13 +395,52 @@ const (
 	// bitwise-OR'ing it with SourceInfoExtraComments.
 	SourceInfoExtraOptionLocations = SourceInfoMode(4)
 
+	// SourceInfoProtocCompatible indicates that column numbers in source code
+	// info are computed like protoc. This can be combined with the above by
+	// bitwise-OR'ing. See CompatibilityMode.Columns, which also applies to
+	// the positions of diagnostics.
 	SourceInfoProtocCompatible = SourceInfoMode(8)
 )
 
+// ImportPolicy indicates how a Compiler treats uses of an import modifier,
+// such as "weak" or "public".
+type ImportPolicy int
+
+const (
+	// ImportPolicyAllow indicates that the modifier is allowed.
+	ImportPolicyAllow = ImportPolicy(0)
+	// ImportPolicyWarn indicates that a warning is reported for each use of
+	// the modifier.
+	ImportPolicyWarn = ImportPolicy(1)
+	// ImportPolicyError indicates that an error is reported for each use of
+	// the modifier.
+	ImportPolicyError = ImportPolicy(2)
+)
+
 type CompileResult struct {
+	// The files that were compiled, in the order their paths were given to
+	// Compile, followed by any other files that had to be recompiled because
+	// they depend on them, sorted by path. If IncludeDependenciesInResults is
+	// true, each file's dependencies come before it.
 	linker.Files
 	PartialLinkResults    map[ResolvedPath]linker.Result
 	UnlinkedParserResults map[ResolvedPath]parser.Result
+	// Metrics about memory held by parsed files while compiling.
+	ParseMetrics ParseMetrics
+	// Metrics about the use of the limits on parallelism while compiling.
+	Concurrency ConcurrencyMetrics
+	// Statistics for every file that was compiled, including dependencies
+	// that were not explicitly requested. Files whose results were already
+	// available from a previous call to Compile are not included.
+	Stats map[ResolvedPath]FileStats
+	// Content hashes for the requested files and all of their dependencies,
+	// which can be saved and later passed to Compiler.CheckUpToDate to find
+	// files that need to be compiled again. Files that failed to compile are
+	// not included.
+	Hashes FileHashes
+
+	// the linked files that were requested to be compiled
+	roots linker.Files
 }
 
 // there are a variety of string identifiers used to refer to compiler results
@@ -195,20 +485,39 @@ func (c *Compiler) Compile(ctx context.Context, paths ...ResolvedPath) (CompileR
 		}
 	}
 
-	h := reporter.NewHandler(c.Reporter)
+	var collector *reporter.Collector
+	var h *reporter.Handler
+	if c.Deterministic {
+		collector = reporter.NewCollector()
+		h = reporter.NewHandler(collector)
+	} else {
+		h = reporter.NewHandler(c.reporter())
+	}
 
 	var e *executor
 	if c.exec == nil {
 		e = &executor{
 			c:       c,
 			h:       h,
-			s:       semaphore.NewWeighted(int64(par)),
+			cpu:     newLimiter(par, false),
 			cancel:  cancel,
 			sym:     linker.NewSymbolTable(),
 			results: map[ResolvedPath]*result{},
 			hooks:   c.Hooks,
 			lenient: c.InterpretOptionsLenient,
 		}
+		e.names = e.sym.Names()
+		e.logger = c.Logger
+		e.parseLimiter = newParseLimiter(c.MaxUnlinkedASTs, c.ParseMemoryBudget)
+		if ioPar := c.MaxIOParallelism; ioPar > 0 || c.AdaptiveParallelism {
+			if ioPar <= 0 {
+				ioPar = 2 * par
+			}
+			e.io = newLimiter(ioPar, c.AdaptiveParallelism)
+		}
+		if c.PoolDescriptors && c.RetainResults {
+			e.descriptorPool = linker.NewDescriptorPool()
+		}
 		if c.RetainResults {
 			c.exec = e
 		}
@@ -216,6 +525,12 @@ func (c *Compiler) Compile(ctx context.Context, paths ...ResolvedPath) (CompileR
 		e = c.exec
 		e.h = h // important: clear any previous errors
 	}
+	e.parseLimiter.reset()
+	e.cpu.reset()
+	if e.io != nil {
+		e.io.reset()
+	}
+	e.stats.reset()
 
 	// We lock now and create all tasks under lock to make sure that no
 	// async task can create a duplicate result. For example, if files
@@ -225,7 +540,7 @@ func (c *Compiler) Compile(ctx context.Context, paths ...ResolvedPath) (CompileR
 	// we need to know if the file is directly requested for compilation,
 	// so we need this loop to define the result. So this loop holds the
 	// lock the whole time so async tasks can't create a result first.
-	needsRecompile := e.invalidate(paths...)
+	needsRecompile := e.invalidate(ctx, paths...)
 	results := make([]*result, 0, len(needsRecompile))
 
 	for _, f := range needsRecompile {
@@ -256,15 +571,50 @@ func (c *Compiler) Compile(ctx context.Context, paths ...ResolvedPath) (CompileR
 		}
 	}
 
+	if c.PackageOptionChecks.Level != linker.CheckLevelOff {
+		if err := linker.CheckPackageOptions(descs, h, c.PackageOptionChecks); err != nil && firstError == nil {
+			firstError = err
+		}
+		if !c.RetainASTs {
+			for _, file := range descs {
+				if res, ok := file.(linker.Result); ok {
+					res.RemoveAST()
+				}
+			}
+		}
+	}
+	if c.SizeBudget.Level != linker.CheckLevelOff {
+		if err := linker.CheckSizeBudget(descs, h, c.SizeBudget); err != nil && firstError == nil {
+			firstError = err
+		}
+	}
+
+	roots := requestedFiles(paths, descs)
 	if c.IncludeDependenciesInResults {
 		descs = linker.ComputeReflexiveTransitiveClosure(descs)
+		if c.DependencyFilter != nil {
+			descs = filterDependencies(roots, descs, c.DependencyFilter)
+		}
 	}
 
-	if err := h.Error(); err != nil {
+	hashes := e.hashes(roots)
+
+	err := h.Error()
+	if collector != nil {
+		if repErr := replayDiagnostics(c.reporter(), collector.Diagnostics()); repErr != nil {
+			err = repErr
+		}
+	}
+	if err != nil {
 		return CompileResult{
 			Files:                 descs,
 			PartialLinkResults:    partiallyLinked,
 			UnlinkedParserResults: unlinked,
+			ParseMetrics:          e.parseLimiter.snapshot(),
+			Concurrency:           e.concurrencyMetrics(),
+			Stats:                 e.stats.snapshot(),
+			Hashes:                hashes,
+			roots:                 roots,
 		}, err
 	}
 	// this should probably never happen; if any task returned an
@@ -273,9 +623,175 @@ func (c *Compiler) Compile(ctx context.Context, paths ...ResolvedPath) (CompileR
 		Files:                 descs,
 		PartialLinkResults:    partiallyLinked,
 		UnlinkedParserResults: unlinked,
+		ParseMetrics:          e.parseLimiter.snapshot(),
+		Concurrency:           e.concurrencyMetrics(),
+		Stats:                 e.stats.snapshot(),
+		Hashes:                hashes,
+		roots:                 roots,
 	}, firstError
 }
 
+// hashes returns the hashes of the given files and all of their dependencies.
+func (e *executor) hashes(roots linker.Files) FileHashes {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return computeHashes(e.c.configHash(), linker.ComputeReflexiveTransitiveClosure(roots), func(path ResolvedPath) ([sha256.Size]byte, bool) {
+		r := e.results[path]
+		if r == nil {
+			return [sha256.Size]byte{}, false
+		}
+		return r.inputHash, r.inputHashed
+	})
+}
+
+// replayDiagnostics sends the given diagnostics to rep, in order, stopping at
+// the first error for which rep returns a non-nil error, which is returned.
+func replayDiagnostics(rep reporter.Reporter, diags []reporter.Diagnostic) error {
+	if rep == nil {
+		rep = reporter.NewReporter(nil, nil)
+	}
+	for _, diag := range diags {
+		if diag.Severity == reporter.SeverityWarning {
+			rep.Warning(diag.ErrorWithPos)
+			continue
+		}
+		if err := rep.Error(diag.ErrorWithPos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Symbols returns the symbol table for all files whose results are retained
+// by the compiler (see RetainResults), or nil if there are none. The table is
+// an immutable snapshot: later calls to Compile never modify it, but instead
+// replace the compiler's table with an updated copy. So it is safe to query
+// the returned table concurrently with compilation. It includes the symbols of
+// every file linked before this method was called; if a call to Compile is in
+// progress, that may include some, but not all, of the files it links.
+// Callers must not modify the returned table.
+func (c *Compiler) Symbols() *linker.Symbols {
+	e := c.exec
+	if e == nil {
+		return nil
+	}
+	e.symTxLock.Lock()
+	defer e.symTxLock.Unlock()
+	return e.sym
+}
+
+// CompileSource compiles a single file from the given in-memory contents,
+// returning its linked result. The file's imports are resolved using the
+// compiler's Resolver, if set, and otherwise only the standard imports (such
+// as "google/protobuf/descriptor.proto") are available.
+//
+// The compiler's configuration is otherwise used as is, but results are
+// never retained: CompileSource does not interact with results retained by
+// prior calls to Compile, even if RetainResults is set.
+func (c *Compiler) CompileSource(ctx context.Context, name, contents string) (linker.File, error) {
+	return c.CompileSourceWithDeps(ctx, name, contents, nil)
+}
+
+// CompileReader is like CompileSource, but reads the file's contents from
+// the given reader, such as os.Stdin. The given path is a virtual path used
+// to refer to the file in its results and in error messages; to mimic protoc
+// when it reads standard input, use "-". The file's imports are resolved
+// using the compiler's Resolver, as with CompileSource.
+func (c *Compiler) CompileReader(ctx context.Context, path string, r io.Reader) (linker.File, error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c.CompileSourceWithDeps(ctx, path, string(contents), nil)
+}
+
+// CompileSourceWithDeps is like CompileSource, but also makes the given
+// in-memory dependencies available to imports. The keys of deps are import
+// paths and the values are the corresponding file contents. In-memory files
+// take precedence over any files provided by the compiler's Resolver.
+func (c *Compiler) CompileSourceWithDeps(ctx context.Context, name, contents string, deps map[string]string) (linker.File, error) {
+	srcs := make(map[string]string, len(deps)+1)
+	for path, src := range deps {
+		srcs[path] = src
+	}
+	srcs[name] = contents
+	var resolver Resolver = &SourceResolver{Accessor: SourceAccessorFromMap(srcs)}
+	if c.Resolver != nil {
+		resolver = CompositeResolver{resolver, c.Resolver}
+	} else {
+		resolver = WithStandardImports(resolver)
+	}
+
+	cc := *c
+	cc.Resolver = resolver
+	cc.RetainResults = false
+	cc.IncludeDependenciesInResults = false
+	cc.exec = nil
+	res, err := cc.Compile(ctx, ResolvedPath(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Files) == 0 {
+		// should not be possible without an error
+		return nil, fmt.Errorf("%s: no result", name)
+	}
+	return res.Files[0], nil
+}
+
+// CompileDescriptorProtos links the given file descriptor protos and
+// interprets their options, as if they had been parsed from source. This
+// upgrades descriptors produced by other tools, whose type references may
+// not be resolved yet and whose options may still be uninterpreted, into
+// fully linked results. The results are returned in the same order as the
+// given files.
+//
+// The given files may import one another. Other imports are resolved using
+// the compiler's Resolver, if set, and otherwise only the standard imports
+// (such as "google/protobuf/descriptor.proto") are available. As with
+// CompileSource, results are never retained. The given descriptor protos
+// are not modified.
+func (c *Compiler) CompileDescriptorProtos(ctx context.Context, files ...*descriptorpb.FileDescriptorProto) ([]linker.Result, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	protos := make(map[string]*descriptorpb.FileDescriptorProto, len(files))
+	paths := make([]ResolvedPath, len(files))
+	for i, fd := range files {
+		if _, ok := protos[fd.GetName()]; ok {
+			return nil, fmt.Errorf("%s: file given more than once", fd.GetName())
+		}
+		protos[fd.GetName()] = fd
+		paths[i] = ResolvedPath(fd.GetName())
+	}
+	var resolver Resolver = ResolverFunc(func(path UnresolvedPath, _ ImportContext) (SearchResult, error) {
+		fd, ok := protos[string(path)]
+		if !ok {
+			return SearchResult{}, protoregistry.NotFound
+		}
+		return SearchResult{ResolvedPath: ResolvedPath(path), Proto: fd}, nil
+	})
+	if c.Resolver != nil {
+		resolver = CompositeResolver{resolver, c.Resolver}
+	} else {
+		resolver = WithStandardImports(resolver)
+	}
+
+	cc := *c
+	cc.Resolver = resolver
+	cc.RetainResults = false
+	cc.IncludeDependenciesInResults = false
+	cc.exec = nil
+	res, err := cc.Compile(ctx, paths...)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]linker.Result, len(res.Files))
+	for i, f := range res.Files {
+		results[i] = f.(linker.Result) //nolint:errcheck
+	}
+	return results, nil
+}
+
 type block struct {
 	// The import path as it appears in the file
 	ImportedAs UnresolvedPath
@@ -292,6 +808,10 @@ type result struct {
 
 	ready chan struct{}
 
+	// closed when another file is waiting on this one as a dependency
+	needed     chan struct{}
+	neededOnce sync.Once
+
 	// true if this file was explicitly provided to the compiler; otherwise
 	// this file is an import that is implicitly included
 	explicitFile bool
@@ -305,6 +825,10 @@ type result struct {
 
 	err error
 
+	// hash of the input provided by the resolver; only valid if inputHashed
+	inputHash   [sha256.Size]byte
+	inputHashed bool
+
 	mu sync.Mutex
 	// the results that are dependencies of this result; this result is
 	// blocked, waiting on these dependencies to complete.
@@ -354,6 +878,15 @@ func (r *result) complete(f linker.Result) {
 	close(r.ready)
 }
 
+// markNeeded indicates that another file is waiting on this one, so its
+// parsing should not be delayed by the compiler's parse limits.
+func (r *result) markNeeded() {
+	if r.needed == nil {
+		return
+	}
+	r.neededOnce.Do(func() { close(r.needed) })
+}
+
 func (r *result) setBlockedOn(blocks []*block) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -369,11 +902,15 @@ func (r *result) getBlockedOn() []*block {
 type executor struct {
 	c      *Compiler
 	h      *reporter.Handler
-	s      *semaphore.Weighted
+	cpu    *limiter
+	io     *limiter // nil if reading sources is not limited separately
 	cancel context.CancelFunc
 
 	symTxLock sync.Mutex
 	sym       *linker.Symbols
+	// the interner shared by sym and all of its clones, which parsed
+	// identifiers are de-duplicated with
+	names *protointernal.Interner
 
 	descriptorProtoCheck    sync.Once
 	descriptorProtoIsCustom bool
@@ -383,11 +920,18 @@ type executor struct {
 
 	hooks   CompilerHooks
 	lenient bool
+
+	// Non-nil if Compiler.PoolDescriptors is enabled.
+	descriptorPool *linker.DescriptorPool
+
+	parseLimiter *parseLimiter
+	stats        statsCollector
+	logger       *slog.Logger
 }
 
 type ImportContext parser.Result
 
-func (e *executor) invalidate(rpaths ...ResolvedPath) []ResolvedPath {
+func (e *executor) invalidate(ctx context.Context, rpaths ...ResolvedPath) []ResolvedPath {
 	// remove the result from the cache, along with any results that depend on it
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -433,27 +977,55 @@ func (e *executor) invalidate(rpaths ...ResolvedPath) []ResolvedPath {
 			}
 		}
 	}
+	// Symbols are deleted from a copy of the symbol table, which replaces the
+	// original when done. So a table returned from Compiler.Symbols is never
+	// mutated, and readers continue to see a consistent snapshot.
+	e.symTxLock.Lock()
+	var cloned bool
 	for _, rpath := range rpaths {
 		r := e.results[rpath]
 		if r == nil {
 			invalidated[rpath] = struct{}{}
 			continue
 		}
+		if !cloned {
+			e.sym = e.sym.Clone()
+			cloned = true
+		}
 
-		e.invalidateLocked(r, blocks, indirect, invalidated, "file was modified")
+		e.invalidateLocked(ctx, r, blocks, indirect, invalidated, "file was modified")
 	}
+	e.symTxLock.Unlock()
 
+	// the requested files come first, in the order given, so that results are
+	// in a deterministic order
 	filenames := make([]ResolvedPath, 0, len(invalidated))
+	names := make([]ResolvedPath, 0, len(invalidated))
+	for _, rpath := range rpaths {
+		if _, ok := invalidated[rpath]; ok {
+			names = append(names, rpath)
+			delete(invalidated, rpath)
+		}
+	}
+	dependents := len(names)
 	for name := range invalidated {
+		names = append(names, name)
+	}
+	slices.Sort(names[dependents:])
+	for _, name := range names {
 		if _, err := e.c.Resolver.FindFileByPath(UnresolvedPath(name), nil); err != nil {
 			// if the file doesn't exist anymore, we don't need to
 			// recompile it
 			if e.hooks.PostInvalidate != nil {
 				if er := e.results[name]; er != nil {
-					if er.res != nil {
-						e.hooks.PostInvalidate(name, er.res, false)
-					} else if er.partialLinkRes != nil {
-						e.hooks.PostInvalidate(name, er.partialLinkRes, false)
+					var prev linker.File = er.res
+					if er.res == nil && er.partialLinkRes != nil {
+						prev = er.partialLinkRes
+					}
+					if prev != nil {
+						e.callHook(ctx, e.h, "PostInvalidate", name, func() {
+							e.hooks.PostInvalidate(name, prev, false)
+						})
 					}
 				}
 			}
@@ -464,25 +1036,33 @@ func (e *executor) invalidate(rpaths ...ResolvedPath) []ResolvedPath {
 	return filenames
 }
 
-func (e *executor) invalidateLocked(r *result, blocks map[ResolvedPath][]*result, indirect map[ResolvedPath][]*result, seen map[ResolvedPath]struct{}, reason string) {
+func (e *executor) invalidateLocked(ctx context.Context, r *result, blocks map[ResolvedPath][]*result, indirect map[ResolvedPath][]*result, seen map[ResolvedPath]struct{}, reason string) {
 	if _, ok := seen[r.resolvedPath]; ok {
 		return
 	}
 	seen[r.resolvedPath] = struct{}{}
 
 	if e.hooks.PreInvalidate != nil {
-		e.hooks.PreInvalidate(r.resolvedPath, reason)
+		e.callHook(ctx, e.h, "PreInvalidate", r.resolvedPath, func() {
+			e.hooks.PreInvalidate(r.resolvedPath, reason)
+		})
 	}
 
 	for _, dep := range blocks[r.resolvedPath] {
-		e.invalidateLocked(dep, blocks, indirect, seen, fmt.Sprintf("file depends on %s", r.resolvedPath))
+		e.invalidateLocked(ctx, dep, blocks, indirect, seen, fmt.Sprintf("file depends on %s", r.resolvedPath))
 	}
 
 	if r.res != nil {
+		if e.descriptorPool != nil {
+			// deferred first so that it runs after the PostInvalidate hook
+			defer e.descriptorPool.Release(r.res)
+		}
 		if e.hooks.PostInvalidate != nil {
 			defer func() {
 				_, err := e.c.Resolver.FindFileByPath(UnresolvedPath(r.resolvedPath), nil)
-				e.hooks.PostInvalidate(r.resolvedPath, r.res, err == nil)
+				e.callHook(ctx, e.h, "PostInvalidate", r.resolvedPath, func() {
+					e.hooks.PostInvalidate(r.resolvedPath, r.res, err == nil)
+				})
 			}()
 		}
 		if err := e.sym.Delete(r.res, e.h); err != nil {
@@ -492,13 +1072,16 @@ func (e *executor) invalidateLocked(r *result, blocks map[ResolvedPath][]*result
 		if err := e.sym.Delete(r.partialLinkRes, e.h); err != nil {
 			panic(err)
 		}
+		if e.descriptorPool != nil {
+			defer e.descriptorPool.Release(r.partialLinkRes)
+		}
 	}
 
 	// order is important here: these might not be dependencies, but the
 	// files will indirectly affect each other, forming a cycle if invalidated
 	// in the wrong order
 	for _, dep := range indirect[r.resolvedPath] {
-		e.invalidateLocked(dep, blocks, indirect, seen, fmt.Sprintf("file indirectly affected by %s", r.resolvedPath))
+		e.invalidateLocked(ctx, dep, blocks, indirect, seen, fmt.Sprintf("file indirectly affected by %s", r.resolvedPath))
 	}
 
 	delete(e.results, r.resolvedPath)
@@ -516,6 +1099,7 @@ func (e *executor) resolveAndCompile(ctx context.Context, dep UnresolvedPath, ex
 
 	sr, err := e.c.Resolver.FindFileByPath(UnresolvedPath(dep), whence)
 	if err != nil {
+		e.log(ctx, slog.LevelDebug, "failed to resolve file", slog.String("path", string(dep)), slog.Any("error", err))
 		return &result{
 			ready: closedChannel,
 			err:   errFailedToResolve{err: err, path: dep},
@@ -537,12 +1121,28 @@ func (e *executor) resolveAndCompile(ctx context.Context, dep UnresolvedPath, ex
 
 	r := e.results[sr.ResolvedPath]
 	if r != nil {
+		e.log(ctx, slog.LevelDebug, "using cached result", slog.String("path", string(sr.ResolvedPath)))
 		return r
 	}
+	if e.logEnabled(ctx, slog.LevelDebug) {
+		attrs := []slog.Attr{
+			slog.String("path", string(sr.ResolvedPath)),
+			slog.String("resolved", searchResultKind(&sr)),
+			slog.Bool("explicit", explicitFile),
+		}
+		if string(dep) != string(sr.ResolvedPath) {
+			attrs = append(attrs, slog.String("importedAs", string(dep)))
+		}
+		if whence != nil {
+			attrs = append(attrs, slog.String("importedBy", whence.FileDescriptorProto().GetName()))
+		}
+		e.log(ctx, slog.LevelDebug, "scheduling file", attrs...)
+	}
 
 	r = &result{
 		resolvedPath: sr.ResolvedPath,
 		ready:        make(chan struct{}),
+		needed:       make(chan struct{}),
 		explicitFile: explicitFile,
 	}
 	e.results[sr.ResolvedPath] = r
@@ -600,20 +1200,52 @@ func (e *executor) hasOverrideDescriptorProto() bool {
 }
 
 func (e *executor) doCompile(ctx context.Context, r *result, sr *SearchResult) {
-	t := task{e: e, h: e.h.SubHandler(), r: r}
-	if err := e.s.Acquire(ctx, 1); err != nil {
+	t := task{e: e, r: r, stats: &FileStats{}}
+	if e.hooks.FileDiagnostics != nil {
+		t.h = e.h.RecordingSubHandler()
+	} else {
+		t.h = e.h.SubHandler()
+	}
+	defer e.stats.put(sr.ResolvedPath, t.stats)
+	if sr.Source != nil && sr.AST == nil && sr.ParseResult == nil && sr.Proto == nil && e.io != nil {
+		if err := e.readSource(ctx, sr); err != nil {
+			r.fail(err)
+			return
+		}
+	}
+	if sr.Source != nil && sr.AST == nil && sr.ParseResult == nil && sr.Proto == nil {
+		// This file will be parsed, so it is subject to the parse limits. This
+		// must happen before acquiring the main semaphore, or else tasks waiting
+		// here could starve the tasks that would free up the limits.
+		size, err := e.parseLimiter.sourceSize(sr)
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		releaseParse, err := e.parseLimiter.acquire(ctx, r.needed, size)
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		defer releaseParse()
+	}
+	if err := e.cpu.acquire(ctx); err != nil {
 		r.fail(err)
 		return
 	}
 	defer t.release()
 
 	if e.hooks.PreCompile != nil {
-		e.hooks.PreCompile(sr.ResolvedPath)
+		e.callHook(ctx, t.h, "PreCompile", sr.ResolvedPath, func() {
+			e.hooks.PreCompile(sr.ResolvedPath)
+		})
 	}
 
 	defer func() {
 		if e.hooks.PostCompile != nil {
-			e.hooks.PostCompile(sr.ResolvedPath)
+			e.callHook(ctx, t.h, "PostCompile", sr.ResolvedPath, func() {
+				e.hooks.PostCompile(sr.ResolvedPath)
+			})
 		}
 		// if results included a result, don't leave it open if it can be closed
 		if sr.Source == nil {
@@ -624,7 +1256,33 @@ func (e *executor) doCompile(ctx context.Context, r *result, sr *SearchResult) {
 		}
 	}()
 
-	desc, err := t.asFile(ctx, sr)
+	fromSource := sr.ParseResult == nil && sr.Proto == nil && sr.AST == nil
+	if !fromSource {
+		// source code is instead hashed while it is parsed
+		r.inputHash, r.inputHashed = hashSearchResult(sr)
+	}
+	desc, err := t.asFileRecoverable(ctx, sr)
+	if fromSource {
+		r.inputHash, r.inputHashed = t.stats.SourceHash, err == nil
+	}
+	if e.logEnabled(ctx, slog.LevelDebug) {
+		attrs := []slog.Attr{
+			slog.String("path", string(sr.ResolvedPath)),
+			slog.Duration("elapsed", t.stats.Total()),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		e.log(ctx, slog.LevelDebug, "finished compiling file", attrs...)
+	}
+	if e.hooks.FileDiagnostics != nil {
+		// called before the result is ready, so that all diagnostics have been
+		// delivered by the time Compile returns
+		diags := t.h.Diagnostics()
+		e.callHook(ctx, t.h, "FileDiagnostics", sr.ResolvedPath, func() {
+			e.hooks.FileDiagnostics(sr.ResolvedPath, diags)
+		})
+	}
 	if err != nil {
 		if desc != nil || sr.ParseResult != nil {
 			r.failPartial(sr.ParseResult, desc, err)
@@ -650,17 +1308,44 @@ type task struct {
 
 	// the result that is populated by this task
 	r *result
+
+	// statistics for this task's file
+	stats *FileStats
 }
 
 func (t *task) release() {
 	if !t.released {
-		t.e.s.Release(1)
+		t.e.cpu.release()
 		t.released = true
 	}
 }
 
 const descriptorProtoPath = "google/protobuf/descriptor.proto"
 
+// asFileRecoverable calls asFile, recovering from any panic if the compiler
+// is configured to do so.
+func (t *task) asFileRecoverable(ctx context.Context, pr *SearchResult) (res linker.Result, err error) {
+	if !t.e.c.RecoverPanics {
+		return t.asFile(ctx, pr)
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			panicErr := PanicError{
+				File:  string(pr.ResolvedPath),
+				Value: v,
+				Stack: string(debug.Stack()),
+			}
+			t.e.log(ctx, slog.LevelError, "recovered from panic", slog.String("path", panicErr.File), slog.Any("panic", v), slog.String("stack", panicErr.Stack))
+			res = nil
+			err = t.h.HandleErrorWithPos(ast.UnknownSpan(panicErr.File), fmt.Errorf("internal compiler error: %w", panicErr))
+			if err == nil {
+				err = t.h.Error()
+			}
+		}
+	}()
+	return t.asFile(ctx, pr)
+}
+
 func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, error) {
 	// r := *pr
 	// if r.Desc != nil {
@@ -670,24 +1355,42 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 	// 	return linker.NewFileRecursive(r.Desc)
 	// }
 
-	parseRes, err := t.asParseResult(pr)
+	var parseRes parser.Result
+	var err error
+	t.runPhase(ctx, PhaseParse, func(context.Context) {
+		parseRes, err = t.asParseResult(pr)
+	})
 	if parseRes == nil {
 		return nil, err
 	}
 	pr.ParseResult = parseRes
+	if fileNode := parseRes.AST(); fileNode != nil {
+		t.stats.SourceBytes = fileNode.SourceSize()
+		t.stats.Tokens = fileNode.TokenCount()
+	}
+	t.stats.Symbols = countSymbols(parseRes.FileDescriptorProto())
 
 	if linkRes, ok := parseRes.(linker.Result); ok {
 		// if resolver returned a parse result that was actually a link result,
-		// use the link result directly (no other steps needed)
+		// use the link result directly (no other steps needed), but add its
+		// symbols to the table, so they are deleted like those of linked files
+		// when the file is invalidated
+		if err := t.importSymbols(linkRes); err != nil {
+			return nil, err
+		}
 		return linkRes, nil
 	}
 
+	if err := t.checkImportPolicies(parseRes); err != nil {
+		return nil, err
+	}
+
 	var deps linker.Files
 	fileDescriptorProto := parseRes.FileDescriptorProto()
 	var wantsDescriptorProto bool
 	protoImports := fileDescriptorProto.Dependency
 
-	if t.e.hasOverrideDescriptorProto() {
+	if t.e.c.OverrideDescriptorProto == nil && t.e.hasOverrideDescriptorProto() {
 		// we only consider implicitly including descriptor.proto if it's overridden
 		if pr.ResolvedPath != descriptorProtoPath {
 			var includesDescriptorProto bool
@@ -709,7 +1412,7 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 		}
 	}
 
-	var overrideDescriptorProto linker.File
+	overrideDescriptorProto := t.e.c.OverrideDescriptorProto
 	if len(protoImports) > 0 {
 		blocks := make([]*block, len(protoImports))
 		for i, imp := range protoImports {
@@ -723,6 +1426,7 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 		results := make([]*result, len(protoImports))
 		for i, dep := range protoImports {
 			res := t.e.resolveAndCompile(ctx, UnresolvedPath(dep), false, parseRes)
+			res.markNeeded()
 			blocks[i].ResolvedPath = res.resolvedPath
 			close(blocks[i].resolved)
 			results[i] = res
@@ -731,10 +1435,11 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 		var descriptorProtoRes *result
 		if wantsDescriptorProto {
 			descriptorProtoRes = t.e.resolveAndCompile(ctx, UnresolvedPath(descriptorProtoPath), false, parseRes)
+			descriptorProtoRes.markNeeded()
 		}
 
 		// release our semaphore so dependencies can be processed w/out risk of deadlock
-		t.e.s.Release(1)
+		t.e.cpu.release()
 		t.released = true
 
 		checked := map[ResolvedPath]struct{}{}
@@ -750,6 +1455,11 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 			case <-res.ready:
 				if res.err != nil {
 					if rerr, ok := res.err.(errFailedToResolve); ok {
+						if t.e.c.AllowMissingWeakImports && slices.Contains(fileDescriptorProto.WeakDependency, int32(i)) {
+							t.h.HandleWarningWithPos(findImportSpan(parseRes, rerr.path), rerr)
+							deps[i] = linker.NewPlaceholderFile(string(rerr.path))
+							continue
+						}
 						// We don't report errors to get file from resolver to handler since
 						// it's usually considered immediately fatal. However, if the reason
 						// we were resolving is due to an import, turn this into an error with
@@ -791,7 +1501,7 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 		// all deps resolved
 		// t.r.setBlockedOn(nil) // todo: logic moved to the complete() and fail() handlers, seems to work fine so far
 		// reacquire semaphore so we can proceed
-		if err := t.e.s.Acquire(ctx, 1); err != nil {
+		if err := t.e.cpu.acquire(ctx); err != nil {
 			return nil, err
 		}
 		t.released = false
@@ -804,8 +1514,38 @@ func (t *task) asFile(ctx context.Context, pr *SearchResult) (linker.Result, err
 	if t.e.lenient {
 		interpretOpts = append(interpretOpts, options.WithInterpretLenient())
 	}
+	if len(t.e.c.FieldPseudoOptions) > 0 {
+		names := make([]string, 0, len(t.e.c.FieldPseudoOptions))
+		for name := range t.e.c.FieldPseudoOptions {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			interpretOpts = append(interpretOpts, options.WithFieldPseudoOption(name, t.e.c.FieldPseudoOptions[name]))
+		}
+	}
+	if len(t.e.c.OptionValidators) > 0 {
+		names := make([]protoreflect.FullName, 0, len(t.e.c.OptionValidators))
+		for name := range t.e.c.OptionValidators {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			interpretOpts = append(interpretOpts, options.WithOptionValidator(name, t.e.c.OptionValidators[name]))
+		}
+	}
+	if validators := options.ConstraintValidators(t.e.c.ConstraintCheck); len(validators) > 0 {
+		names := make([]protoreflect.FullName, 0, len(validators))
+		for name := range validators {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			interpretOpts = append(interpretOpts, options.WithOptionValidator(name, validators[name]))
+		}
+	}
 
-	return t.link(parseRes, deps, interpretOpts...)
+	return t.link(ctx, parseRes, deps, interpretOpts...)
 }
 
 func (e *executor) checkForDependencyCycle(ctx context.Context, res *result, sequence []ResolvedPath, span ast.SourceSpan, checked map[ResolvedPath]struct{}) error {
@@ -875,53 +1615,159 @@ func findImportSpan(res parser.Result, dep UnresolvedPath) ast.SourceSpan {
 	return ast.UnknownSpan(res.FileNode().Name())
 }
 
-func (t *task) link(parseRes parser.Result, deps linker.Files, interpretOpts ...options.InterpreterOption) (linker.Result, error) {
+// checkImportPolicies reports uses of import modifiers that are not allowed
+// by the compiler's WeakImportPolicy and PublicImportPolicy.
+func (t *task) checkImportPolicies(parseRes parser.Result) error {
+	c := t.e.c
+	if c.WeakImportPolicy == ImportPolicyAllow && c.PublicImportPolicy == ImportPolicyAllow {
+		return nil
+	}
+	fd := parseRes.FileDescriptorProto()
+	check := func(policy ImportPolicy, modifier string, indexes []int32) error {
+		if policy == ImportPolicyAllow {
+			return nil
+		}
+		for _, index := range indexes {
+			if int(index) >= len(fd.Dependency) {
+				continue
+			}
+			dep := fd.Dependency[index]
+			span := importModifierSpan(parseRes, dep, modifier)
+			if policy == ImportPolicyWarn {
+				t.h.HandleWarningf(span, "%s imports are discouraged: %q", modifier, dep)
+			} else if err := t.h.HandleErrorf(span, "%s imports are not allowed: %q", modifier, dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(c.WeakImportPolicy, "weak", fd.WeakDependency); err != nil {
+		return err
+	}
+	return check(c.PublicImportPolicy, "public", fd.PublicDependency)
+}
+
+func importModifierSpan(res parser.Result, dep string, modifier string) ast.SourceSpan {
+	root := res.AST()
+	if root == nil {
+		return ast.UnknownSpan(res.FileNode().Name())
+	}
+	for _, decl := range root.Decls {
+		imp := decl.GetImport()
+		if imp == nil || imp.IsIncomplete() || imp.Name.AsString() != dep {
+			continue
+		}
+		if modifier == "weak" && imp.Weak != nil {
+			return root.NodeInfo(imp.Weak)
+		} else if modifier == "public" && imp.Public != nil {
+			return root.NodeInfo(imp.Public)
+		}
+	}
+	return findImportSpan(res, UnresolvedPath(dep))
+}
+
+// importSymbols adds the symbols of the given file, which was linked
+// elsewhere, and of its dependencies to the executor's symbol table.
+func (t *task) importSymbols(file linker.Result) error {
 	t.e.symTxLock.Lock()
+	defer t.e.symTxLock.Unlock()
 	pendingSymtab := t.e.sym.Clone()
-	file, linkError := linker.Link(parseRes, deps, pendingSymtab, t.h)
+	if err := pendingSymtab.Import(file, t.h); err != nil {
+		return err
+	}
+	t.e.sym = pendingSymtab
+	return nil
+}
+
+func (t *task) link(ctx context.Context, parseRes parser.Result, deps linker.Files, interpretOpts ...options.InterpreterOption) (linker.Result, error) {
+	var linkOpts []linker.LinkOption
+	if t.e.descriptorPool != nil {
+		linkOpts = append(linkOpts, linker.WithDescriptorPool(t.e.descriptorPool))
+	}
+	if t.e.c.AllowMissingWeakImports {
+		linkOpts = append(linkOpts, linker.WithAllowMissingWeakImports())
+	}
+	if parseRes.AST() != nil && t.stats.SourceHash != ([sha256.Size]byte{}) {
+		path := ResolvedPath(parseRes.FileDescriptorProto().GetName())
+		linkOpts = append(linkOpts, linker.WithASTLoader(t.e.astLoader(path, t.stats.SourceHash)))
+	}
+	var file linker.Result
+	var linkError error
+	var pendingSymtab *linker.Symbols
+	t.runPhase(ctx, PhaseLink, func(context.Context) {
+		t.e.symTxLock.Lock()
+		// deferred so the lock is released even if linking panics
+		defer t.e.symTxLock.Unlock()
+		pendingSymtab = t.e.sym.Clone()
+		file, linkError = linker.Link(parseRes, deps, pendingSymtab, t.h, linkOpts...)
+		if linkError == nil || (file != nil && linker.IsRecoverable(linkError)) {
+			// commit the updated symbol table; if an unrecoverable link error
+			// occurs, it is not committed, as it may be in an inconsistent state.
+			t.e.sym = pendingSymtab
+		}
+	})
 	var linkIncomplete bool
 	if linkError != nil {
 		if file == nil || !linker.IsRecoverable(linkError) {
-			// If an unrecoverable link error occurs, do not commit the updated symbol
-			// table, as it may be in an inconsistent state.
-			t.e.symTxLock.Unlock()
 			return nil, linkError
 		}
-		// If the error is recoverable, we can commit the updated symbol table.
+		// If the error is recoverable, the updated symbol table was committed.
 		linkIncomplete = true
 	}
-	// commit the updated symbol table
-	t.e.sym = pendingSymtab
-	t.e.symTxLock.Unlock()
 
-	optsIndex, descIndex, err := options.InterpretOptions(file, t.h, interpretOpts...)
-	if err != nil {
-		return file, err
-	}
-	// now that options are interpreted, we can do some additional checks
-	if err := file.ValidateOptions(t.h, linkIncomplete); err != nil {
-		return file, err
+	interpretOpts = append(interpretOpts, options.WithNameInterner(pendingSymtab.Names()))
+	var optsIndex sourceinfo.OptionIndex
+	var descIndex sourceinfo.OptionDescriptorIndex
+	if !t.e.c.SkipOptionInterpretation {
+		var err error
+		t.runPhase(ctx, PhaseOptions, func(context.Context) {
+			optsIndex, descIndex, err = options.InterpretOptions(file, t.h, interpretOpts...)
+			if err != nil {
+				return
+			}
+			file.PopulateOptionDescriptorIndex(descIndex)
+			// now that options are interpreted, we can do some additional checks
+			err = file.ValidateOptions(t.h, linkIncomplete)
+		})
+		if err != nil {
+			return file, err
+		}
+		if t.r.explicitFile && file.AST() != nil {
+			file.CheckForUnusedImports(t.h)
+		}
 	}
-	if t.r.explicitFile && file.AST() != nil {
-		file.CheckForUnusedImports(t.h)
+	if t.r.explicitFile {
+		if err := linker.CheckFieldNumbers(file, t.h, t.e.c.FieldNumberChecks); err != nil {
+			return file, err
+		}
+		if err := linker.CheckDirectDependencies(file, t.h, t.e.c.DirectDependencyCheck); err != nil {
+			return file, err
+		}
+		if err := linker.CheckEnumSemantics(file, t.h, t.e.c.EnumSemanticsCheck); err != nil {
+			return file, err
+		}
 	}
 
 	if needsSourceInfo(parseRes, t.e.c.SourceInfoMode) {
 		var srcInfoOpts []sourceinfo.GenerateOption
-		if t.e.c.SourceInfoMode&SourceInfoExtraComments != 0 {
+		if t.e.c.SourceInfoMode&SourceInfoExtraComments != 0 && !t.e.c.Compatibility.CommentAttribution {
 			srcInfoOpts = append(srcInfoOpts, sourceinfo.WithExtraComments())
 		}
 		if t.e.c.SourceInfoMode&SourceInfoExtraOptionLocations != 0 {
 			srcInfoOpts = append(srcInfoOpts, sourceinfo.WithExtraOptionLocations())
 		}
-		if t.e.c.SourceInfoMode&SourceInfoProtocCompatible != 0 {
+		if t.e.c.SourceInfoMode&SourceInfoProtocCompatible != 0 || t.e.c.Compatibility.Columns {
 			srcInfoOpts = append(srcInfoOpts, sourceinfo.WithProtocCompatMode())
 		}
-		parseRes.FileDescriptorProto().SourceCodeInfo = sourceinfo.GenerateSourceInfo(parseRes, optsIndex, srcInfoOpts...)
-		file.PopulateSourceCodeInfo(optsIndex, descIndex)
+		t.runPhase(ctx, PhaseSourceInfo, func(context.Context) {
+			parseRes.FileDescriptorProto().SourceCodeInfo = sourceinfo.GenerateSourceInfo(parseRes, optsIndex, srcInfoOpts...)
+			file.PopulateSourceCodeInfo(optsIndex, descIndex)
+		})
 	}
 
-	if !t.e.c.RetainASTs {
+	// When checking options across files, the ASTs of the files being compiled
+	// are used to report positions, so they are removed after the checks.
+	if !t.e.c.RetainASTs && !(t.r.explicitFile && t.e.c.PackageOptionChecks.Level != linker.CheckLevelOff) {
 		file.RemoveAST()
 	}
 	if linkIncomplete {
@@ -930,6 +1776,40 @@ func (t *task) link(parseRes parser.Result, deps linker.Files, interpretOpts ...
 	return file, nil
 }
 
+// astLoader returns a function that parses the given file again, for
+// linker.Result.ReloadAST. The file's source, as provided by the compiler's
+// resolver, must have the given hash, or else it has changed since the file
+// was compiled and an error is returned.
+func (e *executor) astLoader(path ResolvedPath, hash [sha256.Size]byte) linker.ASTLoader {
+	return loadAST(e.c.Resolver, path, hash, e.parserOptions())
+}
+
+// loadAST returns a function that parses the source code for the given path,
+// as provided by the given resolver, failing if its hash does not match.
+func loadAST(resolver Resolver, path ResolvedPath, hash [sha256.Size]byte, opts []parser.ParserOption) linker.ASTLoader {
+	return func(_ context.Context) (*ast.FileNode, error) {
+		sr, err := resolver.FindFileByPath(UnresolvedPath(path), nil)
+		if err != nil {
+			return nil, err
+		}
+		if sr.Source == nil {
+			return nil, fmt.Errorf("%s: resolver no longer provides source code", path)
+		}
+		if c, ok := sr.Source.(io.Closer); ok {
+			defer c.Close()
+		}
+		hasher := sha256.New()
+		file, err := parser.Parse(string(path), io.TeeReader(sr.Source, hasher), reporter.NewHandler(nil), sr.Version, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if [sha256.Size]byte(hasher.Sum(nil)) != hash {
+			return nil, fmt.Errorf("%s: source has changed since it was compiled", path)
+		}
+		return file, nil
+	}
+}
+
 func needsSourceInfo(parseRes parser.Result, mode SourceInfoMode) bool {
 	return mode != SourceInfoNone && parseRes.AST() != nil && parseRes.FileDescriptorProto().SourceCodeInfo == nil
 }
@@ -939,6 +1819,10 @@ func (t *task) asParseResult(r *SearchResult) (parser.Result, error) {
 		if r.ParseResult.FileDescriptorProto().GetName() != string(r.ResolvedPath) {
 			return nil, fmt.Errorf("search result for %q returned descriptor for %q", r.ResolvedPath, r.ParseResult.FileDescriptorProto().GetName())
 		}
+		if linkRes, ok := r.ParseResult.(linker.Result); ok {
+			// already linked, so it won't be mutated
+			return linkRes, nil
+		}
 		// If the file descriptor needs linking, it will be mutated during the
 		// next stage. So to make anu mutations thread-safe, we must make a
 		// defensive copy.
@@ -966,7 +1850,7 @@ func (t *task) asParseResult(r *SearchResult) (parser.Result, error) {
 		}
 	}
 
-	return parser.ResultFromAST(file, true, t.h)
+	return parser.ResultFromAST(file, true, t.h, t.e.c.parserOptions()...)
 }
 
 func (t *task) asAST(r *SearchResult) (_ *ast.FileNode, _err error) {
@@ -977,5 +1861,28 @@ func (t *task) asAST(r *SearchResult) (_ *ast.FileNode, _err error) {
 		return r.AST, nil
 	}
 
-	return parser.Parse(string(r.ResolvedPath), r.Source, t.h, r.Version)
+	hash := sha256.New()
+	file, err := parser.Parse(string(r.ResolvedPath), io.TeeReader(r.Source, hash), t.h, r.Version, t.e.parserOptions()...)
+	hash.Sum(t.stats.SourceHash[:0])
+	return file, err
+}
+
+// parserOptions returns the options used to parse source files.
+func (c *Compiler) parserOptions() []parser.ParserOption {
+	return []parser.ParserOption{
+		parser.WithLimits(c.ParseLimits),
+		parser.WithSourceEncoding(c.SourceEncoding),
+		parser.WithStrictUTF8(c.StrictUTF8),
+		parser.WithRawStrings(c.RawStrings),
+		parser.WithPositionEncoding(c.Compatibility.positionEncoding()),
+		parser.WithProtocSyntheticOneofNames(c.Compatibility.SyntheticOneofNames),
+		parser.WithValidationLimits(c.ValidationLimits),
+		parser.WithExperimentalEditions(c.ExperimentalEditions),
+	}
+}
+
+// parserOptions returns the options used to parse source files for this
+// executor. Identifiers share storage with the names in its symbol table.
+func (e *executor) parserOptions() []parser.ParserOption {
+	return append(e.c.parserOptions(), parser.WithNameInterner(e.names))
 }
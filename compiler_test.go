@@ -17,26 +17,36 @@ package protocompile
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/descriptorpb"
 
+	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/internal"
 	"github.com/kralicky/protocompile/linker"
 	"github.com/kralicky/protocompile/parser"
 	"github.com/kralicky/protocompile/protointernal/prototest"
+	"github.com/kralicky/protocompile/protoutil"
 	"github.com/kralicky/protocompile/reporter"
 )
 
@@ -386,6 +396,46 @@ func TestDataRace(t *testing.T) {
 	}
 }
 
+func TestRecoverPanics(t *testing.T) {
+	t.Parallel()
+	var reported []reporter.ErrorWithPos
+	var mu sync.Mutex
+	resolver := WithStandardImports(mkResolver(baseContents))
+	comp := Compiler{
+		Resolver: ResolverFunc(func(path UnresolvedPath, ic ImportContext) (SearchResult, error) {
+			res, err := resolver.FindFileByPath(path, ic)
+			if path == "a/b/b2.proto" {
+				res.Source = panicReader{}
+			}
+			return res, err
+		}),
+		RecoverPanics: true,
+		Reporter: reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, err)
+			return nil
+		}, nil),
+	}
+	res, err := comp.Compile(context.Background(), "a/b/b1.proto", "a/b/b2.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	// the other file still compiled successfully
+	require.Len(t, res.Files, 1)
+	assert.Equal(t, "a/b/b1.proto", res.Files[0].Path())
+
+	require.Len(t, reported, 1)
+	assert.Equal(t, `a/b/b2.proto: internal compiler error: panic handling "a/b/b2.proto": oops`, reported[0].Error())
+	var panicErr PanicError
+	require.ErrorAs(t, reported[0], &panicErr)
+	assert.Equal(t, "oops", panicErr.Value)
+	assert.Contains(t, panicErr.Stack, "asFileRecoverable")
+
+	// the compiler is still usable afterwards
+	comp.Resolver = resolver
+	_, err = comp.Compile(context.Background(), "a/b/b2.proto")
+	require.NoError(t, err)
+}
+
 // func TestPanicHandling(t *testing.T) {
 // 	t.Parallel()
 // 	c := Compiler{
@@ -442,6 +492,12 @@ message See {
 `,
 }
 
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) {
+	panic("oops")
+}
+
 func mkResolver(contents map[UnresolvedPath]string) Resolver {
 	return ResolverFunc(func(name UnresolvedPath, _ ImportContext) (SearchResult, error) {
 		if s, ok := contents[name]; ok {
@@ -512,6 +568,601 @@ message See {
 	assert.NoError(t, err)
 }
 
+func TestIncrementalCompilerPoolDescriptors(t *testing.T) {
+	var released []string
+	comp := Compiler{
+		Resolver:        WithStandardImports(mkResolver(baseContents)),
+		SourceInfoMode:  SourceInfoStandard,
+		RetainResults:   true,
+		PoolDescriptors: true,
+		Hooks: CompilerHooks{
+			PostInvalidate: func(path ResolvedPath, previous linker.File, _ bool) {
+				// previous results are still intact while the hook runs
+				assert.NotEmpty(t, previous.Messages().Get(0).FullName())
+				released = append(released, string(path))
+			},
+		},
+	}
+	paths := []ResolvedPath{"a/b/b1.proto", "a/b/b2.proto", "c/c.proto"}
+	res, err := comp.Compile(context.Background(), paths...)
+	require.NoError(t, err)
+	expected := make(map[string]*descriptorpb.FileDescriptorProto, len(res.Files))
+	for _, f := range res.Files {
+		expected[f.Path()] = proto.Clone(protoutil.ProtoFromFileDescriptor(f)).(*descriptorpb.FileDescriptorProto)
+	}
+
+	// Invalidating b1 also invalidates everything that depends on it, and
+	// all of their descriptors are recycled when they are re-linked.
+	res, err = comp.Compile(context.Background(), "a/b/b1.proto")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/b/b1.proto", "a/b/b2.proto", "c/c.proto"}, released)
+	// all released memory was picked up again by the re-linked files
+	assert.Zero(t, comp.exec.descriptorPool.Len())
+
+	res, err = comp.Compile(context.Background(), paths...)
+	require.NoError(t, err)
+	for _, f := range res.Files {
+		prototest.AssertMessagesEqual(t, expected[f.Path()], protoutil.ProtoFromFileDescriptor(f), f.Path())
+		for i := 0; i < f.Messages().Len(); i++ {
+			md := f.Messages().Get(i)
+			assert.Equal(t, protoreflect.FullName(f.Package()).Append(md.Name()), md.FullName())
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{}
+	var paths []ResolvedPath
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("file%d.proto", i)
+		sources[UnresolvedPath(name)] = fmt.Sprintf(`syntax = "proto3"; package pkg%d; message M {}`, i)
+		paths = append(paths, ResolvedPath(name))
+	}
+	// a chain of imports longer than the limit must not deadlock
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("chain%d.proto", i)
+		src := fmt.Sprintf(`syntax = "proto3"; package chain%d;`, i)
+		if i > 0 {
+			src += fmt.Sprintf(` import "chain%d.proto";`, i-1)
+		}
+		sources[UnresolvedPath(name)] = src
+		paths = append(paths, ResolvedPath(name))
+	}
+
+	t.Run("count", func(t *testing.T) {
+		t.Parallel()
+		comp := Compiler{
+			Resolver:        mkResolver(sources),
+			MaxUnlinkedASTs: 2,
+		}
+		res, err := comp.Compile(context.Background(), paths[:50]...)
+		require.NoError(t, err)
+		require.Len(t, res.Files, 50)
+		assert.LessOrEqual(t, res.ParseMetrics.PeakUnlinkedASTs, 2)
+		assert.Positive(t, res.ParseMetrics.PeakUnlinkedASTs)
+
+		res, err = comp.Compile(context.Background(), paths[50:]...)
+		require.NoError(t, err)
+		require.Len(t, res.Files, 10)
+	})
+	t.Run("bytes", func(t *testing.T) {
+		t.Parallel()
+		comp := Compiler{
+			Resolver:          mkResolver(sources),
+			ParseMemoryBudget: 100,
+		}
+		res, err := comp.Compile(context.Background(), paths...)
+		require.NoError(t, err)
+		require.Len(t, res.Files, 60)
+		assert.Positive(t, res.ParseMetrics.PeakUnlinkedBytes)
+	})
+}
+
+func TestCompileStats(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	phases := map[ResolvedPath][]Phase{}
+	comp := Compiler{
+		Resolver:       WithStandardImports(mkResolver(baseContents)),
+		SourceInfoMode: SourceInfoStandard,
+		Hooks: CompilerHooks{
+			RunPhase: func(ctx context.Context, path ResolvedPath, phase Phase, run func(context.Context)) {
+				mu.Lock()
+				phases[path] = append(phases[path], phase)
+				mu.Unlock()
+				PprofLabels(ctx, path, phase, run)
+			},
+		},
+	}
+	res, err := comp.Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+
+	stats, ok := res.Stats["c/c.proto"]
+	require.True(t, ok)
+	assert.Positive(t, stats.SourceBytes)
+	assert.Positive(t, stats.Tokens)
+	assert.Equal(t, sha256.Sum256([]byte(baseContents["c/c.proto"])), stats.SourceHash)
+	assert.Equal(t, 4, stats.Symbols) // message See and its three fields
+	assert.Positive(t, stats.Total())
+	assert.Equal(t, []Phase{PhaseParse, PhaseLink, PhaseOptions, PhaseSourceInfo}, phases["c/c.proto"])
+	// dependencies are included too
+	assert.Contains(t, res.Stats, ResolvedPath("a/b/b1.proto"))
+	assert.Contains(t, res.Stats, ResolvedPath("google/protobuf/timestamp.proto"))
+}
+
+func TestCompilerLogger(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	comp := Compiler{
+		Resolver: WithStandardImports(mkResolver(baseContents)),
+		Logger: slog.New(slog.NewTextHandler(lockedWriter{&mu, &buf}, &slog.HandlerOptions{
+			Level: LevelTrace,
+		})),
+	}
+	_, err := comp.Compile(context.Background(), "a/b/b2.proto")
+	require.NoError(t, err)
+	mu.Lock()
+	out := buf.String()
+	mu.Unlock()
+	assert.Contains(t, out, `msg="scheduling file" path=a/b/b2.proto resolved=source explicit=true`)
+	assert.Contains(t, out, `msg="scheduling file" path=a/b/b1.proto resolved=source explicit=false importedBy=a/b/b2.proto`)
+	assert.Contains(t, out, `msg="phase finished" path=a/b/b1.proto phase=link`)
+	assert.Contains(t, out, `msg="finished compiling file" path=a/b/b2.proto`)
+}
+
+func TestCompileSource(t *testing.T) {
+	t.Parallel()
+	var comp Compiler
+	fd, err := comp.CompileSource(context.Background(), "test.proto", `
+		syntax = "proto3";
+		import "google/protobuf/timestamp.proto";
+		message Foo { google.protobuf.Timestamp ts = 1; }
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "test.proto", fd.Path())
+	assert.Equal(t, "google.protobuf.Timestamp", string(fd.Messages().ByName("Foo").Fields().ByNumber(1).Message().FullName()))
+
+	fd, err = comp.CompileSourceWithDeps(context.Background(), "test.proto", `
+		syntax = "proto3";
+		import "dep.proto";
+		message Foo { Bar bar = 1; }
+	`, map[string]string{
+		"dep.proto": `syntax = "proto3"; message Bar {}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "dep.proto", fd.Imports().Get(0).Path())
+
+	// the compiler's resolver is consulted for other imports
+	comp = Compiler{Resolver: WithStandardImports(mkResolver(baseContents))}
+	fd, err = comp.CompileSource(context.Background(), "test.proto", `
+		syntax = "proto3";
+		import "a/b/b1.proto";
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "a/b/b1.proto", fd.Imports().Get(0).Path())
+
+	_, err = comp.CompileSource(context.Background(), "test.proto", `
+		syntax = "proto3";
+		import "missing.proto";
+	`)
+	require.Error(t, err)
+}
+
+func TestCompileReader(t *testing.T) {
+	t.Parallel()
+	comp := Compiler{Resolver: WithStandardImports(mkResolver(baseContents))}
+	fd, err := comp.CompileReader(context.Background(), "-", strings.NewReader(`
+		syntax = "proto3";
+		import "a/b/b1.proto";
+		message Foo {}
+	`))
+	require.NoError(t, err)
+	assert.Equal(t, "-", fd.Path())
+	assert.Equal(t, "a/b/b1.proto", fd.Imports().Get(0).Path())
+
+	var errs []string
+	comp.Reporter = reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+		errs = append(errs, err.Error())
+		return nil
+	}, nil)
+	_, err = comp.CompileReader(context.Background(), "-", strings.NewReader(`syntax = "proto3"; message {}`))
+	require.Error(t, err)
+	require.NotEmpty(t, errs)
+	assert.True(t, strings.HasPrefix(errs[0], "-:1:"), errs[0])
+
+	_, err = comp.CompileReader(context.Background(), "-", iotest.ErrReader(io.ErrUnexpectedEOF))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestCompileDescriptorProtos(t *testing.T) {
+	t.Parallel()
+	// Descriptors like those produced by other tools, with relative type
+	// references and uninterpreted options.
+	a := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Package: proto.String("foo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("A"),
+			Options: &descriptorpb.MessageOptions{
+				UninterpretedOption: []*descriptorpb.UninterpretedOption{{
+					Name:            []*descriptorpb.UninterpretedOption_NamePart{{NamePart: proto.String("deprecated"), IsExtension: proto.Bool(false)}},
+					IdentifierValue: proto.String("true"),
+				}},
+			},
+		}},
+	}
+	b := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("b.proto"),
+		Package:    proto.String("foo.bar"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"a.proto", "google/protobuf/empty.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("B"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("a"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					TypeName: proto.String("A"),
+					JsonName: proto.String("a"),
+				},
+				{
+					Name:     proto.String("e"),
+					Number:   proto.Int32(2),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					TypeName: proto.String("google.protobuf.Empty"),
+					JsonName: proto.String("e"),
+				},
+			},
+		}},
+	}
+	origA, origB := proto.Clone(a), proto.Clone(b)
+
+	var comp Compiler
+	res, err := comp.CompileDescriptorProtos(context.Background(), b, a)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "b.proto", res[0].Path())
+	assert.Equal(t, "a.proto", res[1].Path())
+
+	fields := res[0].Messages().ByName("B").Fields()
+	assert.Equal(t, protoreflect.MessageKind, fields.ByName("a").Kind())
+	assert.Equal(t, protoreflect.FullName("foo.A"), fields.ByName("a").Message().FullName())
+	assert.Equal(t, protoreflect.FullName("google.protobuf.Empty"), fields.ByName("e").Message().FullName())
+	assert.Equal(t, ".foo.A", res[0].FileDescriptorProto().MessageType[0].Field[0].GetTypeName())
+	msgOpts := res[1].FileDescriptorProto().MessageType[0].Options
+	assert.True(t, msgOpts.GetDeprecated())
+	assert.Empty(t, msgOpts.GetUninterpretedOption())
+
+	assert.True(t, proto.Equal(origA, a))
+	assert.True(t, proto.Equal(origB, b))
+
+	b.Dependency = []string{"a.proto", "missing.proto"}
+	_, err = comp.CompileDescriptorProtos(context.Background(), b, a)
+	require.Error(t, err)
+
+	_, err = comp.CompileDescriptorProtos(context.Background(), a, a)
+	require.ErrorContains(t, err, "a.proto: file given more than once")
+}
+
+func TestWhyCompiled(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"d.proto": `
+syntax = "proto3";
+import "c/c.proto";
+`,
+	}
+	for k, v := range baseContents {
+		contents[k] = v
+	}
+	comp := Compiler{
+		Resolver:                     WithStandardImports(mkResolver(contents)),
+		IncludeDependenciesInResults: true,
+		RetainASTs:                   true,
+	}
+	res, err := comp.Compile(context.Background(), "d.proto", "a/b/b2.proto")
+	require.NoError(t, err)
+
+	chains := res.WhyCompiled("a/b/b1.proto")
+	require.Len(t, chains, 2)
+	assert.Equal(t, `"d.proto" -> "c/c.proto" -> "a/b/b1.proto"`, chains[0].String())
+	assert.Equal(t, `"a/b/b2.proto" -> "a/b/b1.proto"`, chains[1].String())
+	step := chains[0][1]
+	assert.Equal(t, ResolvedPath("c/c.proto"), step.Importer)
+	assert.Equal(t, UnresolvedPath("a/b/b1.proto"), step.ImportedAs)
+	assert.Equal(t, "c/c.proto:6:1-23", step.Span.String())
+	// without ASTs, spans come from source code info
+	comp.SourceInfoMode = SourceInfoStandard
+	comp.RetainASTs = false
+	res2, err := comp.Compile(context.Background(), "d.proto")
+	require.NoError(t, err)
+	chains = res2.WhyCompiled("a/b/b1.proto")
+	require.Len(t, chains, 1)
+	assert.Equal(t, step.Span.String(), chains[0][1].Span.String())
+
+	chains = res.WhyCompiled("a/b/b2.proto")
+	require.Len(t, chains, 2)
+	assert.Equal(t, `"d.proto" -> "c/c.proto" -> "a/b/b2.proto"`, chains[0].String())
+	assert.Empty(t, chains[1])
+
+	chains = res.WhyCompiled("google/protobuf/timestamp.proto")
+	require.Len(t, chains, 1)
+	assert.Len(t, chains[0], 2)
+
+	assert.Nil(t, res.WhyCompiled("google/protobuf/any.proto"))
+}
+
+func TestImportPolicies(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+import weak "b.proto";
+import public "c.proto";
+message A { C c = 1; }
+`,
+		"b.proto": `syntax = "proto3"; message B {}`,
+		"c.proto": `syntax = "proto3"; message C {}`,
+	}
+	compile := func(comp Compiler) ([]string, []string, error) {
+		var errs, warnings []string
+		comp.Resolver = mkResolver(contents)
+		comp.Reporter = reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+			errs = append(errs, err.Error())
+			return nil
+		}, func(err reporter.ErrorWithPos) {
+			warnings = append(warnings, err.Error())
+		})
+		_, err := comp.Compile(context.Background(), "a.proto")
+		return errs, warnings, err
+	}
+
+	// unused weak imports are reported like any other unused import
+	errs, warnings, err := compile(Compiler{})
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{`a.proto:2:1-23: import "b.proto" not used`}, warnings)
+
+	errs, warnings, err = compile(Compiler{
+		WeakImportPolicy:   ImportPolicyError,
+		PublicImportPolicy: ImportPolicyWarn,
+	})
+	require.Error(t, err)
+	assert.Equal(t, []string{`a.proto:2:8-12: weak imports are not allowed: "b.proto"`}, errs)
+	assert.Equal(t, []string{
+		`a.proto:3:8-14: public imports are discouraged: "c.proto"`,
+		`a.proto:2:1-23: import "b.proto" not used`,
+	}, warnings)
+
+	// missing weak imports
+	delete(contents, "b.proto")
+	errs, _, err = compile(Compiler{})
+	require.Error(t, err)
+	require.NotEmpty(t, errs)
+	assert.Contains(t, errs[0], `a.proto:2:13-22:`)
+
+	errs, warnings, err = compile(Compiler{AllowMissingWeakImports: true})
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], `a.proto:2:13-22:`)
+
+	// public imports are never tolerated when missing
+	delete(contents, "c.proto")
+	_, _, err = compile(Compiler{AllowMissingWeakImports: true})
+	require.Error(t, err)
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	contents := map[UnresolvedPath]string{}
+	for k, v := range baseContents {
+		contents[k] = v
+	}
+	comp := Compiler{
+		Resolver: WithStandardImports(ResolverFunc(func(name UnresolvedPath, whence ImportContext) (SearchResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return mkResolver(contents).FindFileByPath(name, whence)
+		})),
+		RetainResults: true,
+		RetainASTs:    true,
+	}
+	assert.Nil(t, comp.Symbols())
+	res, err := comp.Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	syms := comp.Symbols()
+	require.NotNil(t, syms)
+	span := syms.Lookup("a.b.BeeTwo")
+	require.NotNil(t, span)
+
+	// queries on prior results and symbol tables are safe while recompiling
+	file := res.Files[0]
+	beeTwo := file.FindImportByPath("a/b/b2.proto").FindDescriptorByName("a.b.BeeTwo")
+	require.NotNil(t, beeTwo)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.Equal(t, span, syms.Lookup("a.b.BeeTwo"))
+				d, err := linker.ResolverFromFile(file).FindDescriptorByName("a.b.BeeOne")
+				assert.NoError(t, err)
+				assert.NotNil(t, d)
+				assert.NotEmpty(t, file.(linker.Result).FindReferences(beeTwo))
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		mu.Lock()
+		contents["a/b/b2.proto"] = strings.Replace(baseContents["a/b/b2.proto"], "BeeTwo", fmt.Sprintf("BeeTwo%d", i), 1)
+		mu.Unlock()
+		_, _ = comp.Compile(context.Background(), "a/b/b2.proto")
+	}
+	wg.Wait()
+
+	// the snapshot is unchanged, but the compiler's table was updated
+	assert.Equal(t, span, syms.Lookup("a.b.BeeTwo"))
+	assert.Nil(t, comp.Symbols().Lookup("a.b.BeeTwo"))
+	assert.NotNil(t, comp.Symbols().Lookup("a.b.BeeTwo3"))
+}
+
+func TestFileDiagnosticsHook(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+import "b.proto";
+message A {
+  Unknown u = 1;
+  Other o = 2;
+}
+`,
+		"b.proto": `syntax = "proto3";
+message B {
+  Unknown u = 1;
+}
+`,
+		"c.proto": `syntax = "proto3";
+message C {}
+`,
+	}
+	var mu sync.Mutex
+	got := map[ResolvedPath][]string{}
+	comp := Compiler{
+		Resolver: WithStandardImports(mkResolver(contents)),
+		Reporter: reporter.NewReporter(func(reporter.ErrorWithPos) error { return nil }, nil),
+		Hooks: CompilerHooks{
+			FileDiagnostics: func(path ResolvedPath, diags []reporter.Diagnostic) {
+				mu.Lock()
+				defer mu.Unlock()
+				_, dup := got[path]
+				assert.False(t, dup, "diagnostics delivered twice for %s", path)
+				msgs := []string{}
+				for _, d := range diags {
+					msgs = append(msgs, d.Severity.String()+": "+d.Error())
+				}
+				got[path] = msgs
+			},
+		},
+	}
+	_, err := comp.Compile(context.Background(), "a.proto", "c.proto")
+	require.Error(t, err)
+	assert.Equal(t, map[ResolvedPath][]string{
+		"a.proto": {
+			`warning: a.proto:2:1-18: import "b.proto" not used`,
+			`error: a.proto:4:3-10: field A.u: unknown type Unknown`,
+			`error: a.proto:5:3-8: field A.o: unknown type Other`,
+		},
+		"b.proto": {
+			`error: b.proto:3:3-10: field B.u: unknown type Unknown`,
+		},
+		"c.proto": {},
+	}, got)
+}
+
+func TestSkipOptionInterpretation(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"opts.proto": `syntax = "proto2";
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.FieldOptions { optional string tag = 10101; }
+`,
+		"a.proto": `syntax = "proto2";
+import "opts.proto";
+message A {
+  optional int32 n = 1 [default = 42, json_name = "num", (tag) = "abc"];
+}
+`,
+	}
+	comp := Compiler{
+		Resolver:                 WithStandardImports(mkResolver(contents)),
+		SourceInfoMode:           SourceInfoStandard,
+		SkipOptionInterpretation: true,
+	}
+	res, err := comp.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	fd := res.Files[0].(linker.Result).FileDescriptorProto()
+	fld := fd.GetMessageType()[0].GetField()[0]
+	assert.Nil(t, fld.DefaultValue)
+	assert.Equal(t, "n", fld.GetJsonName())
+	uninterpreted := fld.GetOptions().GetUninterpretedOption()
+	require.Len(t, uninterpreted, 3)
+	assert.Equal(t, ".tag", uninterpreted[2].GetName()[0].GetNamePart())
+	assert.True(t, uninterpreted[2].GetName()[0].GetIsExtension())
+	assert.Equal(t, "abc", string(uninterpreted[2].GetStringValue()))
+
+	// source info describes the uninterpreted options
+	var found bool
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		// message_type[0].field[0].options.uninterpreted_option[2]
+		if slices.Equal(loc.GetPath(), []int32{4, 0, 2, 0, 8, 999, 2}) {
+			found = true
+			assert.Equal(t, []int32{3, 57, 70}, loc.GetSpan())
+		}
+	}
+	assert.True(t, found)
+
+	// without the flag, the same file is interpreted normally
+	comp.SkipOptionInterpretation = false
+	res, err = comp.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	fld = res.Files[0].(linker.Result).FileDescriptorProto().GetMessageType()[0].GetField()[0]
+	assert.Equal(t, "42", fld.GetDefaultValue())
+	assert.Empty(t, fld.GetOptions().GetUninterpretedOption())
+}
+
+func TestOverrideDescriptorProto(t *testing.T) {
+	t.Parallel()
+	override, err := (&Compiler{
+		Resolver: mkResolver(map[UnresolvedPath]string{
+			"google/protobuf/descriptor.proto": `syntax = "proto2";
+package google.protobuf;
+message FileOptions {
+  optional string java_package = 1;
+  optional string pinned = 500;
+  extensions 1000 to max;
+}
+`,
+		}),
+	}).Compile(context.Background(), "google/protobuf/descriptor.proto")
+	require.NoError(t, err)
+
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+option pinned = "abc";
+`,
+	}
+	comp := Compiler{
+		Resolver: WithStandardImports(mkResolver(contents)),
+	}
+	_, err = comp.Compile(context.Background(), "a.proto")
+	require.ErrorContains(t, err, "pinned")
+
+	comp.OverrideDescriptorProto = override.Files[0]
+	res, err := comp.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	fd := res.Files[0].(linker.Result).FileDescriptorProto()
+	assert.Empty(t, fd.GetOptions().GetUninterpretedOption())
+	// the override's field is unknown to the standard FileOptions message
+	assert.NotEmpty(t, fd.GetOptions().ProtoReflect().GetUnknown())
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
 func TestPartialLink(t *testing.T) {
 	files := map[UnresolvedPath]string{
 		"a1.proto": `
@@ -599,3 +1250,94 @@ func requireASTsEqual(t *testing.T, a, b linker.Files, filenames ...string) {
 		}
 	}
 }
+
+func TestReloadAST(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions { string label = 50000; }
+message A {
+  option (label) = "a";
+  B b = 1;
+}
+message B {}
+`,
+	}
+	compiler := &Compiler{
+		Resolver: WithStandardImports(mkResolver(contents)),
+	}
+	res, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	file := res.Files[0].(linker.Result)
+	require.Nil(t, file.AST())
+	msgB := file.Messages().ByName("B")
+
+	require.NoError(t, file.ReloadAST(context.Background()))
+	require.NotNil(t, file.AST())
+	// nodes are associated with the existing descriptor protos
+	node := file.MessageNode(protoutil.ProtoFromMessageDescriptor(msgB))
+	require.NotNil(t, node)
+	assert.Equal(t, 8, file.AST().NodeInfo(node).Start().Line)
+	// references refer to nodes in the new AST
+	refs := file.FindReferences(msgB)
+	require.Len(t, refs, 1)
+	assert.Equal(t, 6, refs[0].NodeInfo.Start().Line)
+	var found bool
+	ast.Inspect(file.AST(), func(n ast.Node) bool {
+		found = found || n == refs[0].Node
+		return !found
+	})
+	assert.True(t, found)
+	// as do option indexes
+	var optRefs int
+	file.RangeFieldReferenceNodesWithDescriptors(func(n ast.Node, fld protoreflect.FieldDescriptor) bool {
+		assert.Equal(t, protoreflect.FullName("label"), fld.FullName())
+		assert.Equal(t, 5, file.AST().NodeInfo(n).Start().Line)
+		optRefs++
+		return true
+	})
+	assert.Equal(t, 1, optRefs)
+	// reloading again does nothing
+	fileNode := file.AST()
+	require.NoError(t, file.ReloadAST(context.Background()))
+	assert.Same(t, fileNode, file.AST())
+
+	// the AST cannot be reloaded once the source changes
+	file.RemoveAST()
+	contents["a.proto"] += "\n"
+	require.ErrorContains(t, file.ReloadAST(context.Background()), "source has changed")
+
+	// results not compiled from source cannot be reloaded
+	desc := res.Files[0].Dependencies()[0].(linker.Result)
+	assert.Equal(t, "google/protobuf/descriptor.proto", desc.Path())
+	assert.ErrorIs(t, desc.ReloadAST(context.Background()), linker.ErrNoASTLoader)
+}
+
+func TestReloadASTParserOptions(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `edition = "2024";
+message A {}
+`,
+		"b.proto": `syntax = "proto3";
+message B {
+  optional string b = 1;
+}
+`,
+	}
+	compiler := &Compiler{
+		Resolver:             WithStandardImports(mkResolver(contents)),
+		ExperimentalEditions: true,
+		Compatibility:        CompatibilityMode{SyntheticOneofNames: true},
+	}
+	res, err := compiler.Compile(context.Background(), "a.proto", "b.proto")
+	require.NoError(t, err)
+	for _, f := range res.Files {
+		file := f.(linker.Result)
+		require.Nil(t, file.AST())
+		require.NoError(t, file.ReloadAST(context.Background()), file.Path())
+		require.NotNil(t, file.AST())
+		assert.Equal(t, parser.SyntheticOneofNamingProtoc, parser.SyntheticOneofNamingOf(file), file.Path())
+	}
+}
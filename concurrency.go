@@ -0,0 +1,220 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// ConcurrencyMetrics describes how the compiler's limits on parallelism were
+// used during a call to Compile. See Compiler.MaxParallelism and
+// Compiler.MaxIOParallelism.
+type ConcurrencyMetrics struct {
+	// The limit on CPU-bound work, such as parsing and linking.
+	CPU LimiterMetrics
+	// The limit on reading sources. Zero if there is no such limit.
+	IO LimiterMetrics
+}
+
+// LimiterMetrics describes the use of a single limit on parallelism.
+type LimiterMetrics struct {
+	// The limit at the end of the call to Compile. With adaptive parallelism,
+	// this may be lower than the configured limit.
+	Limit int
+	// The maximum number of permits in use at any one time.
+	Peak int
+	// The number of permits acquired, and the number of those that had to
+	// wait for another permit to be released.
+	Acquired, Waited int
+	// The total time spent waiting for permits.
+	WaitTime time.Duration
+	// The number of times an adaptive limit was reduced because of
+	// contention, and the number of times it was raised again.
+	Backoffs, Increases int
+}
+
+// limiter is a semaphore that records metrics and whose limit can change.
+// Permits are granted in FIFO order.
+type limiter struct {
+	mu      sync.Mutex
+	max     int
+	limit   int
+	inUse   int
+	waiters list.List // of chan struct{}
+	metrics LimiterMetrics
+
+	// if true, the limit adapts to the observed cost of the work done while
+	// holding permits; see observe
+	adaptive bool
+	// exponentially weighted moving average of the cost per byte of work,
+	// in nanoseconds, the lowest such average seen, and the number of
+	// observations since the limit last changed
+	avgCost, bestCost float64
+	samples           int
+}
+
+func newLimiter(limit int, adaptive bool) *limiter {
+	return &limiter{max: limit, limit: limit, adaptive: adaptive}
+}
+
+// reset clears the metrics at the start of a call to Compile.
+func (l *limiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics = LimiterMetrics{Peak: l.inUse}
+}
+
+func (l *limiter) snapshot() LimiterMetrics {
+	if l == nil {
+		return LimiterMetrics{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	metrics := l.metrics
+	metrics.Limit = l.limit
+	return metrics
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	l.metrics.Acquired++
+	if l.inUse < l.limit && l.waiters.Len() == 0 {
+		l.grantLocked()
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.metrics.Waited++
+	l.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-ready:
+		l.mu.Lock()
+		l.metrics.WaitTime += time.Since(start)
+		l.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-ready:
+			// the permit was granted concurrently, so give it back
+			l.inUse--
+			l.notifyLocked()
+		default:
+			l.waiters.Remove(elem)
+		}
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inUse--
+	l.notifyLocked()
+}
+
+func (l *limiter) grantLocked() {
+	l.inUse++
+	l.metrics.Peak = max(l.metrics.Peak, l.inUse)
+}
+
+// notifyLocked grants permits to waiters, in order, while the limit allows.
+func (l *limiter) notifyLocked() {
+	for l.inUse < l.limit && l.waiters.Len() > 0 {
+		front := l.waiters.Front()
+		l.waiters.Remove(front)
+		l.grantLocked()
+		close(front.Value.(chan struct{})) //nolint:errcheck // list only contains channels
+	}
+}
+
+const (
+	// number of observations needed before an adaptive limit changes
+	limiterSamples = 4
+	// fixed overhead, in bytes, added to each observation, so that the cost
+	// of small files is not dominated by per-file latency
+	limiterOverhead = 4096
+)
+
+// observe records the duration of work done while holding a permit, which
+// processed the given number of bytes. If the limiter is adaptive, it halves
+// the limit when the average cost per byte grows to more than twice the
+// lowest average seen, which indicates that concurrent work is contending
+// for a shared resource, such as a disk. It raises the limit by one, up to
+// its maximum, when the cost is near the lowest seen and there are waiters.
+func (l *limiter) observe(d time.Duration, bytes int) {
+	if !l.adaptive {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cost := float64(d) / float64(bytes+limiterOverhead)
+	if l.samples == 0 {
+		l.avgCost = cost
+	} else {
+		l.avgCost = (l.avgCost*3 + cost) / 4
+	}
+	l.samples++
+	if l.samples < limiterSamples {
+		return
+	}
+	if l.bestCost == 0 || l.avgCost < l.bestCost {
+		l.bestCost = l.avgCost
+	}
+	switch {
+	case l.avgCost > 2*l.bestCost && l.limit > 1:
+		l.limit = max(1, l.limit/2)
+		l.metrics.Backoffs++
+		l.samples = 0
+	case l.avgCost <= 1.5*l.bestCost && l.limit < l.max && l.waiters.Len() > 0:
+		l.limit++
+		l.metrics.Increases++
+		l.samples = 0
+		l.notifyLocked()
+	}
+}
+
+func (e *executor) concurrencyMetrics() ConcurrencyMetrics {
+	return ConcurrencyMetrics{CPU: e.cpu.snapshot(), IO: e.io.snapshot()}
+}
+
+// readSource reads the given search result's source into memory, subject to
+// the limit on reading sources.
+func (e *executor) readSource(ctx context.Context, sr *SearchResult) error {
+	if err := e.io.acquire(ctx); err != nil {
+		return err
+	}
+	defer e.io.release()
+	start := time.Now()
+	data, err := io.ReadAll(sr.Source)
+	if c, ok := sr.Source.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if err != nil {
+		return err
+	}
+	e.io.observe(time.Since(start), len(data))
+	sr.Source = bytes.NewReader(data)
+	return nil
+}
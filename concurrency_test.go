@@ -0,0 +1,132 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l := newLimiter(2, false)
+	require.NoError(t, l.acquire(ctx))
+	require.NoError(t, l.acquire(ctx))
+
+	acquired := make(chan struct{})
+	go func() {
+		if l.acquire(ctx) == nil {
+			close(acquired)
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired permit over limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.release()
+	<-acquired
+
+	// a canceled waiter does not take a permit
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, l.acquire(cancelCtx), context.Canceled)
+	l.release()
+	l.release()
+	require.NoError(t, l.acquire(ctx))
+	require.NoError(t, l.acquire(ctx))
+
+	metrics := l.snapshot()
+	assert.Equal(t, 2, metrics.Limit)
+	assert.Equal(t, 2, metrics.Peak)
+	assert.Equal(t, 6, metrics.Acquired)
+	assert.Equal(t, 2, metrics.Waited)
+}
+
+func TestLimiterAdaptive(t *testing.T) {
+	t.Parallel()
+	l := newLimiter(8, true)
+	for i := 0; i < limiterSamples; i++ {
+		l.observe(time.Millisecond, 1000)
+	}
+	assert.Equal(t, 8, l.snapshot().Limit)
+
+	// a slow read brings the average over twice the best seen
+	l.observe(10*time.Millisecond, 1000)
+	metrics := l.snapshot()
+	assert.Equal(t, 4, metrics.Limit)
+	assert.Equal(t, 1, metrics.Backoffs)
+
+	// the limit is only raised again while reads are fast and there are
+	// waiters
+	for i := 0; i < 2*limiterSamples; i++ {
+		l.observe(time.Millisecond, 1000)
+	}
+	assert.Equal(t, 4, l.snapshot().Limit)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.acquire(ctx))
+	}
+	acquired := make(chan struct{})
+	go func() {
+		if l.acquire(ctx) == nil {
+			close(acquired)
+		}
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.waiters.Len() == 1
+	}, time.Second, time.Millisecond)
+	for i := 0; i < limiterSamples; i++ {
+		l.observe(time.Millisecond, 1000)
+	}
+	<-acquired
+	metrics = l.snapshot()
+	assert.Equal(t, 5, metrics.Limit)
+	assert.Equal(t, 1, metrics.Increases)
+}
+
+func TestCompileConcurrencyMetrics(t *testing.T) {
+	t.Parallel()
+	comp := Compiler{
+		Resolver:         WithStandardImports(mkResolver(baseContents)),
+		MaxParallelism:   2,
+		MaxIOParallelism: 1,
+	}
+	res, err := comp.Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	// c/c.proto, a/b/b1.proto, and a/b/b2.proto are read from source
+	assert.Equal(t, LimiterMetrics{Limit: 1, Peak: 1, Acquired: 3, Waited: res.Concurrency.IO.Waited, WaitTime: res.Concurrency.IO.WaitTime}, res.Concurrency.IO)
+	assert.Equal(t, 2, res.Concurrency.CPU.Limit)
+	assert.GreaterOrEqual(t, res.Concurrency.CPU.Acquired, 3)
+	assert.LessOrEqual(t, res.Concurrency.CPU.Peak, 2)
+
+	comp = Compiler{
+		Resolver:            WithStandardImports(mkResolver(baseContents)),
+		MaxParallelism:      2,
+		AdaptiveParallelism: true,
+	}
+	res, err = comp.Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Concurrency.IO.Acquired)
+	assert.LessOrEqual(t, res.Concurrency.IO.Limit, 4)
+}
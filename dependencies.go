@@ -0,0 +1,82 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"path"
+
+	"github.com/kralicky/protocompile/linker"
+)
+
+// DependencyFilter decides whether a dependency of the requested files is
+// included in compile results. See Compiler.DependencyFilter. The depth is
+// the length of the shortest chain of imports from a requested file to the
+// dependency, so direct dependencies have a depth of one.
+type DependencyFilter func(file linker.File, depth int) bool
+
+// DirectDependencies returns a filter that includes only the direct
+// dependencies of the requested files.
+func DirectDependencies() DependencyFilter {
+	return func(_ linker.File, depth int) bool {
+		return depth <= 1
+	}
+}
+
+// DependenciesMatching returns a filter that includes only dependencies whose
+// paths match at least one of the given patterns, using the syntax of
+// path.Match. Malformed patterns never match.
+func DependenciesMatching(patterns ...string) DependencyFilter {
+	return func(file linker.File, _ int) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, file.Path()); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// filterDependencies returns the files in closure, which must be the
+// reflexive transitive closure of roots, that are either roots or accepted
+// by the given filter, in the same order.
+func filterDependencies(roots, closure linker.Files, filter DependencyFilter) linker.Files {
+	depths := make(map[string]int, len(closure))
+	queue := make(linker.Files, 0, len(closure))
+	for _, root := range roots {
+		if _, ok := depths[root.Path()]; !ok {
+			depths[root.Path()] = 0
+			queue = append(queue, root)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		file := queue[i]
+		for _, dep := range file.Dependencies() {
+			if _, ok := depths[dep.Path()]; ok || dep.IsPlaceholder() {
+				continue
+			}
+			depths[dep.Path()] = depths[file.Path()] + 1
+			queue = append(queue, dep)
+		}
+	}
+
+	filtered := make(linker.Files, 0, len(closure))
+	for _, file := range closure {
+		depth := depths[file.Path()]
+		if depth == 0 || filter(file, depth) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
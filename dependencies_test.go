@@ -0,0 +1,71 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestDependencyFilter(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3"; import "b.proto"; message A { B b = 1; }`,
+		"b.proto": `syntax = "proto3"; import "c.proto"; import "google/protobuf/empty.proto";
+			message B { C c = 1; google.protobuf.Empty e = 2; }`,
+		"c.proto": `syntax = "proto3"; message C {}`,
+	}
+	paths := func(files linker.Files) []string {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path())
+		}
+		return paths
+	}
+	compile := func(filter DependencyFilter) linker.Files {
+		compiler := &Compiler{
+			Resolver:                     WithStandardImports(mkResolver(contents)),
+			RetainASTs:                   true,
+			IncludeDependenciesInResults: true,
+			DependencyFilter:             filter,
+		}
+		res, err := compiler.Compile(context.Background(), "a.proto")
+		require.NoError(t, err)
+		return res.Files
+	}
+
+	files := compile(nil)
+	assert.Equal(t, []string{"c.proto", "google/protobuf/empty.proto", "b.proto", "a.proto"}, paths(files))
+
+	files = compile(DirectDependencies())
+	assert.Equal(t, []string{"b.proto", "a.proto"}, paths(files))
+	assert.NotNil(t, files[0].(linker.Result).AST())
+	// excluded dependencies are still linked, and keep their ASTs since
+	// RetainASTs is set
+	c := files[0].Dependencies()[0]
+	assert.Equal(t, "c.proto", c.Path())
+	assert.NotNil(t, c.(linker.Result).AST())
+
+	files = compile(DependenciesMatching("google/protobuf/*", "c.*"))
+	assert.Equal(t, []string{"c.proto", "google/protobuf/empty.proto", "a.proto"}, paths(files))
+
+	files = compile(func(_ linker.File, depth int) bool { return depth == 2 })
+	assert.Equal(t, []string{"c.proto", "google/protobuf/empty.proto", "a.proto"}, paths(files))
+}
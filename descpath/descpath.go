@@ -0,0 +1,187 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package descpath provides the field numbers of the elements of descriptor
+// protos, along with helpers for constructing and validating paths to those
+// elements.
+//
+// A path is a sequence of field numbers and indexes that identifies an
+// element of a google.protobuf.FileDescriptorProto, starting from the file.
+// Each field number is followed by an index into that field if the field is
+// repeated. Paths are used as the keys of locations in source code info, so
+// tools that inspect or produce SourceCodeInfo can use this package instead
+// of hard-coding the numbers.
+package descpath
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ForDescriptor computes the source location path for the given descriptor.
+// The boolean value indicates whether the result is valid. If the path
+// cannot be computed for d, the function returns nil, false.
+func ForDescriptor(d protoreflect.Descriptor) (protoreflect.SourcePath, bool) {
+	_, ok := d.(protoreflect.FileDescriptor)
+	if ok {
+		return nil, true
+	}
+	var path protoreflect.SourcePath
+	for {
+		p := d.Parent()
+		switch d := d.(type) {
+		case protoreflect.FileDescriptor:
+			slices.Reverse(path)
+			return path, true
+		case protoreflect.MessageDescriptor:
+			path = append(path, int32(d.Index()))
+			switch p.(type) {
+			case protoreflect.FileDescriptor:
+				path = append(path, FileMessagesTag)
+			case protoreflect.MessageDescriptor:
+				path = append(path, MessageNestedMessagesTag)
+			default:
+				return nil, false
+			}
+		case protoreflect.FieldDescriptor:
+			path = append(path, int32(d.Index()))
+			switch p.(type) {
+			case protoreflect.FileDescriptor:
+				if d.IsExtension() {
+					path = append(path, FileExtensionsTag)
+				} else {
+					return nil, false
+				}
+			case protoreflect.MessageDescriptor:
+				if d.IsExtension() {
+					path = append(path, MessageExtensionsTag)
+				} else {
+					path = append(path, MessageFieldsTag)
+				}
+			default:
+				return nil, false
+			}
+		case protoreflect.OneofDescriptor:
+			path = append(path, int32(d.Index()))
+			if _, ok := p.(protoreflect.MessageDescriptor); ok {
+				path = append(path, MessageOneofsTag)
+			} else {
+				return nil, false
+			}
+		case protoreflect.EnumDescriptor:
+			path = append(path, int32(d.Index()))
+			switch p.(type) {
+			case protoreflect.FileDescriptor:
+				path = append(path, FileEnumsTag)
+			case protoreflect.MessageDescriptor:
+				path = append(path, MessageEnumsTag)
+			default:
+				return nil, false
+			}
+		case protoreflect.EnumValueDescriptor:
+			path = append(path, int32(d.Index()))
+			if _, ok := p.(protoreflect.EnumDescriptor); ok {
+				path = append(path, EnumValuesTag)
+			} else {
+				return nil, false
+			}
+		case protoreflect.ServiceDescriptor:
+			path = append(path, int32(d.Index()))
+			if _, ok := p.(protoreflect.FileDescriptor); ok {
+				path = append(path, FileServicesTag)
+			} else {
+				return nil, false
+			}
+		case protoreflect.MethodDescriptor:
+			path = append(path, int32(d.Index()))
+			if _, ok := p.(protoreflect.ServiceDescriptor); ok {
+				path = append(path, ServiceMethodsTag)
+			} else {
+				return nil, false
+			}
+		}
+		d = p
+	}
+}
+
+// ForOptions computes the source location path for the options of the
+// given descriptor. The boolean value indicates whether the result is valid.
+// If the path cannot be computed for d, the function returns nil, false.
+func ForOptions(d protoreflect.Descriptor) (protoreflect.SourcePath, bool) {
+	path, ok := ForDescriptor(d)
+	if !ok {
+		return nil, false
+	}
+	var tag int32
+	switch d.(type) {
+	case protoreflect.FileDescriptor:
+		tag = FileOptionsTag
+	case protoreflect.MessageDescriptor:
+		tag = MessageOptionsTag
+	case protoreflect.FieldDescriptor:
+		tag = FieldOptionsTag
+	case protoreflect.OneofDescriptor:
+		tag = OneofOptionsTag
+	case protoreflect.EnumDescriptor:
+		tag = EnumOptionsTag
+	case protoreflect.EnumValueDescriptor:
+		tag = EnumValOptionsTag
+	case protoreflect.ServiceDescriptor:
+		tag = ServiceOptionsTag
+	case protoreflect.MethodDescriptor:
+		tag = MethodOptionsTag
+	default:
+		return nil, false
+	}
+	return append(path, tag), true
+}
+
+// Validate returns an error if path does not refer to an element of a file
+// descriptor proto. The error describes the first invalid element.
+//
+// A path may refer to a repeated field as a whole, by omitting the index. A
+// path may also continue into an options message. Since custom options are
+// not known, once such a path refers to a field number in one of the options
+// message's extension ranges, the rest of the path is not checked.
+func Validate(path protoreflect.SourcePath) error {
+	md := (*descriptorpb.FileDescriptorProto)(nil).ProtoReflect().Descriptor()
+	var fld protoreflect.FieldDescriptor
+	for i := 0; i < len(path); i++ {
+		if md == nil {
+			return fmt.Errorf("path element %d: field %s is not a message, so it has no elements", i, fld.FullName())
+		}
+		num := protoreflect.FieldNumber(path[i])
+		fld = md.Fields().ByNumber(num)
+		if fld == nil {
+			if md.ExtensionRanges().Has(num) {
+				return nil
+			}
+			return fmt.Errorf("path element %d: %s has no field with number %d", i, md.FullName(), num)
+		}
+		if fld.IsList() {
+			i++
+			if i == len(path) {
+				break
+			}
+			if path[i] < 0 {
+				return fmt.Errorf("path element %d: index %d into repeated field %s is negative", i, path[i], fld.FullName())
+			}
+		}
+		md = fld.Message()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descpath_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/descpath"
+)
+
+func TestForDescriptor(t *testing.T) {
+	t.Parallel()
+	file := descriptorpb.File_google_protobuf_descriptor_proto
+	fieldProto := file.Messages().ByName("FieldDescriptorProto")
+	typeEnum := fieldProto.Enums().ByName("Type")
+	ctype := file.Messages().ByName("FieldOptions").Fields().ByName("ctype")
+
+	testCases := []struct {
+		name string
+		d    protoreflect.Descriptor
+		path protoreflect.SourcePath
+	}{
+		{"file", file, nil},
+		{"message", fieldProto, protoreflect.SourcePath{descpath.FileMessagesTag, int32(fieldProto.Index())}},
+		{"enum value", typeEnum.Values().ByName("TYPE_INT32"), protoreflect.SourcePath{
+			descpath.FileMessagesTag, int32(fieldProto.Index()),
+			descpath.MessageEnumsTag, int32(typeEnum.Index()),
+			descpath.EnumValuesTag, int32(typeEnum.Values().ByName("TYPE_INT32").Index()),
+		}},
+		{"field", ctype, protoreflect.SourcePath{
+			descpath.FileMessagesTag, int32(ctype.Parent().Index()),
+			descpath.MessageFieldsTag, int32(ctype.Index()),
+		}},
+	}
+	for _, tc := range testCases {
+		path, ok := descpath.ForDescriptor(tc.d)
+		require.True(t, ok, tc.name)
+		assert.Equal(t, tc.path, path, tc.name)
+		assert.NoError(t, descpath.Validate(path), tc.name)
+	}
+
+	optsPath, ok := descpath.ForOptions(ctype)
+	require.True(t, ok)
+	assert.Equal(t, protoreflect.SourcePath{
+		descpath.FileMessagesTag, int32(ctype.Parent().Index()),
+		descpath.MessageFieldsTag, int32(ctype.Index()),
+		descpath.FieldOptionsTag,
+	}, optsPath)
+	assert.NoError(t, descpath.Validate(optsPath))
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name string
+		path protoreflect.SourcePath
+		err  string
+	}{
+		{name: "repeated field", path: protoreflect.SourcePath{descpath.FileMessagesTag}},
+		{name: "field name", path: protoreflect.SourcePath{descpath.FileMessagesTag, 0, descpath.MessageFieldsTag, 2, descpath.FieldNameTag}},
+		{name: "custom option", path: protoreflect.SourcePath{descpath.FileOptionsTag, 50000, 1, 2}},
+		{name: "standard option", path: protoreflect.SourcePath{descpath.FileMessagesTag, 0, descpath.MessageOptionsTag, 3}},
+		{
+			name: "unknown field",
+			path: protoreflect.SourcePath{descpath.FileMessagesTag, 0, 100},
+			err:  "path element 2: google.protobuf.DescriptorProto has no field with number 100",
+		},
+		{
+			name: "negative index",
+			path: protoreflect.SourcePath{descpath.FileEnumsTag, -1},
+			err:  "path element 1: index -1 into repeated field google.protobuf.FileDescriptorProto.enum_type is negative",
+		},
+		{
+			name: "element of scalar",
+			path: protoreflect.SourcePath{descpath.FilePackageTag, 1},
+			err:  "path element 1: field google.protobuf.FileDescriptorProto.package is not a message, so it has no elements",
+		},
+	}
+	for _, tc := range testCases {
+		err := descpath.Validate(tc.path)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.EqualError(t, err, tc.err, tc.name)
+		}
+	}
+}
@@ -38,6 +38,11 @@ const (
 
 	// MaxSupportedEdition is the most recent edition supported by this module.
 	MaxSupportedEdition = descriptorpb.Edition_EDITION_2023
+
+	// MaxExperimentalEdition is the most recent edition that can be compiled
+	// when experimental editions are enabled. Support for editions after
+	// MaxSupportedEdition is incomplete and may change in any release.
+	MaxExperimentalEdition = descriptorpb.Edition_EDITION_2024
 )
 
 var (
@@ -48,6 +53,11 @@ var (
 	// new logic in the compiler.
 	SupportedEditions = computeSupportedEditions(MinSupportedEdition, MaxSupportedEdition)
 
+	// ExperimentalEditions is the set of editions, newer than those in
+	// SupportedEditions, that can be compiled when experimental editions are
+	// enabled (see parser.WithExperimentalEditions).
+	ExperimentalEditions = computeSupportedEditions(MaxSupportedEdition+1, MaxExperimentalEdition)
+
 	// FeatureSetDescriptor is the message descriptor for the compiled-in
 	// version (in the descriptorpb package) of the google.protobuf.FeatureSet
 	// message type.
@@ -85,36 +95,11 @@ func ResolveFeature(
 	fields ...protoreflect.FieldDescriptor,
 ) (protoreflect.Value, error) {
 	for {
-		var features *descriptorpb.FeatureSet
-		if withFeatures, ok := element.Options().(HasFeatures); ok {
-			// It should not really be possible for 'ok' to ever be false...
-			features = withFeatures.GetFeatures()
-		}
-
-		// TODO: adaptFeatureSet is only looking at the first field. But if we needed to
-		//       support an extension field inside a custom feature, we'd really need
-		//       to check all fields. That gets particularly complicated if the traversal
-		//       path of fields includes list and map values. Luckily, features are not
-		//       supposed to be repeated and not supposed to themselves have extensions.
-		//       So this should be fine, at least for now.
-		msgRef, err := adaptFeatureSet(features, fields[0])
+		val, err := GetExplicitFeature(element, fields...)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
-		// Navigate the fields to find the value
-		var val protoreflect.Value
-		for i, field := range fields {
-			if i > 0 {
-				msgRef = val.Message()
-			}
-			if !msgRef.Has(field) {
-				val = protoreflect.Value{}
-				break
-			}
-			val = msgRef.Get(field)
-		}
 		if val.IsValid() {
-			// All fields were set!
 			return val, nil
 		}
 
@@ -127,6 +112,45 @@ func ResolveFeature(
 	}
 }
 
+// GetExplicitFeature returns the value of a feature that is explicitly set
+// in the options of the given descriptor. Unlike ResolveFeature, this does
+// not examine the element's ancestors. If the feature is not set on the
+// element, it returns a zero value.
+func GetExplicitFeature(
+	element protoreflect.Descriptor,
+	fields ...protoreflect.FieldDescriptor,
+) (protoreflect.Value, error) {
+	var features *descriptorpb.FeatureSet
+	if withFeatures, ok := element.Options().(HasFeatures); ok {
+		// It should not really be possible for 'ok' to ever be false...
+		features = withFeatures.GetFeatures()
+	}
+
+	// TODO: adaptFeatureSet is only looking at the first field. But if we needed to
+	//       support an extension field inside a custom feature, we'd really need
+	//       to check all fields. That gets particularly complicated if the traversal
+	//       path of fields includes list and map values. Luckily, features are not
+	//       supposed to be repeated and not supposed to themselves have extensions.
+	//       So this should be fine, at least for now.
+	msgRef, err := adaptFeatureSet(features, fields[0])
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	// Navigate the fields to find the value
+	var val protoreflect.Value
+	for i, field := range fields {
+		if i > 0 {
+			msgRef = val.Message()
+		}
+		if !msgRef.Has(field) {
+			return protoreflect.Value{}, nil
+		}
+		val = msgRef.Get(field)
+	}
+	// All fields were set!
+	return val, nil
+}
+
 // HasEdition should be implemented by values that implement
 // [protoreflect.FileDescriptor], to provide access to the file's
 // edition when its syntax is [protoreflect.Editions].
@@ -166,6 +190,70 @@ func GetEdition(d protoreflect.Descriptor) descriptorpb.Edition {
 	}
 }
 
+// FeatureSupportStatus describes whether an element with feature support
+// settings may be used in a particular edition. Fields of
+// [*descriptorpb.FeatureSet] (and custom features) and enum values used as
+// feature values can have such settings.
+type FeatureSupportStatus struct {
+	// If not EDITION_UNKNOWN, the element may not be used because it was
+	// not introduced until this later edition.
+	IntroducedIn descriptorpb.Edition
+	// If not EDITION_UNKNOWN, the element may not be used because it was
+	// removed in this edition.
+	RemovedIn descriptorpb.Edition
+	// If not EDITION_UNKNOWN, the element is deprecated as of this edition.
+	DeprecatedIn descriptorpb.Edition
+	// An optional message to show to users of a deprecated element.
+	DeprecationWarning string
+}
+
+// Allowed returns true if the element may be used. A deprecated element is
+// still allowed.
+func (s FeatureSupportStatus) Allowed() bool {
+	return s.IntroducedIn == descriptorpb.Edition_EDITION_UNKNOWN &&
+		s.RemovedIn == descriptorpb.Edition_EDITION_UNKNOWN
+}
+
+// Deprecated returns true if the element is deprecated.
+func (s FeatureSupportStatus) Deprecated() bool {
+	return s.DeprecatedIn != descriptorpb.Edition_EDITION_UNKNOWN
+}
+
+// GetFeatureSupport returns the feature support settings for the given
+// element, which should be a field or enum value descriptor. It returns nil
+// if the element has no such settings.
+func GetFeatureSupport(d protoreflect.Descriptor) *descriptorpb.FieldOptions_FeatureSupport {
+	switch opts := d.Options().(type) {
+	case *descriptorpb.FieldOptions:
+		return opts.GetFeatureSupport()
+	case *descriptorpb.EnumValueOptions:
+		return opts.GetFeatureSupport()
+	default:
+		return nil
+	}
+}
+
+// CheckFeatureSupport reports whether an element with the given feature
+// support settings may be used in the given edition. A nil support value
+// means the element may be used in any edition.
+func CheckFeatureSupport(support *descriptorpb.FieldOptions_FeatureSupport, edition descriptorpb.Edition) FeatureSupportStatus {
+	var status FeatureSupportStatus
+	if support == nil {
+		return status
+	}
+	if support.EditionIntroduced != nil && edition < support.GetEditionIntroduced() {
+		status.IntroducedIn = support.GetEditionIntroduced()
+	}
+	if support.EditionRemoved != nil && edition >= support.GetEditionRemoved() {
+		status.RemovedIn = support.GetEditionRemoved()
+	}
+	if support.EditionDeprecated != nil && edition >= support.GetEditionDeprecated() {
+		status.DeprecatedIn = support.GetEditionDeprecated()
+		status.DeprecationWarning = support.GetDeprecationWarning()
+	}
+	return status
+}
+
 // GetEditionDefaults returns the default feature values for the given edition.
 // It returns nil if the given edition is not known.
 //
@@ -314,10 +402,14 @@ func adaptFeatureSet(msg *descriptorpb.FeatureSet, field protoreflect.FieldDescr
 	}
 
 	other := dynamicpb.NewMessage(field.ContainingMessage())
-	// We don't need to use a resolver for this step because we know that
-	// field is not an extension. And features are not allowed to themselves
-	// have extensions.
-	if err := (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(data, other); err != nil {
+	unmarshaler := proto.UnmarshalOptions{AllowPartial: true}
+	if field.IsExtension() {
+		// The stored value used a different descriptor for the extension, so
+		// we need a resolver that recognizes the given one. Other than that,
+		// features are not allowed to themselves have extensions.
+		unmarshaler.Resolver = resolverForExtension{field}
+	}
+	if err := unmarshaler.Unmarshal(data, other); err != nil {
 		return nil, fmt.Errorf("failed to marshal FeatureSet field %s to bytes: %w", field.Name(), err)
 	}
 	return other, nil
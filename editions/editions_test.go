@@ -19,6 +19,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -65,3 +66,42 @@ func TestComputeSupportedEditions(t *testing.T) {
 		computeSupportedEditions(descriptorpb.Edition_EDITION_2023, descriptorpb.Edition_EDITION_2024),
 	)
 }
+
+func TestCheckFeatureSupport(t *testing.T) {
+	t.Parallel()
+	support := &descriptorpb.FieldOptions_FeatureSupport{
+		EditionIntroduced:  descriptorpb.Edition_EDITION_2023.Enum(),
+		EditionDeprecated:  descriptorpb.Edition_EDITION_2024.Enum(),
+		DeprecationWarning: proto.String("use something else"),
+		EditionRemoved:     descriptorpb.Edition_EDITION_99997_TEST_ONLY.Enum(),
+	}
+
+	status := CheckFeatureSupport(support, descriptorpb.Edition_EDITION_PROTO3)
+	assert.False(t, status.Allowed())
+	assert.Equal(t, descriptorpb.Edition_EDITION_2023, status.IntroducedIn)
+	assert.False(t, status.Deprecated())
+
+	status = CheckFeatureSupport(support, descriptorpb.Edition_EDITION_2023)
+	assert.True(t, status.Allowed())
+	assert.False(t, status.Deprecated())
+
+	status = CheckFeatureSupport(support, descriptorpb.Edition_EDITION_2024)
+	assert.True(t, status.Allowed())
+	assert.True(t, status.Deprecated())
+	assert.Equal(t, descriptorpb.Edition_EDITION_2024, status.DeprecatedIn)
+	assert.Equal(t, "use something else", status.DeprecationWarning)
+
+	status = CheckFeatureSupport(support, descriptorpb.Edition_EDITION_99997_TEST_ONLY)
+	assert.False(t, status.Allowed())
+	assert.Equal(t, descriptorpb.Edition_EDITION_99997_TEST_ONLY, status.RemovedIn)
+	assert.True(t, status.Deprecated())
+
+	assert.Equal(t, FeatureSupportStatus{}, CheckFeatureSupport(nil, descriptorpb.Edition_EDITION_PROTO2))
+
+	// descriptors for the standard features have support settings
+	fieldPresence := descriptorpb.File_google_protobuf_descriptor_proto.Messages().ByName("FeatureSet").Fields().ByName("field_presence")
+	support = GetFeatureSupport(fieldPresence)
+	require.NotNil(t, support)
+	assert.True(t, CheckFeatureSupport(support, descriptorpb.Edition_EDITION_2023).Allowed())
+	assert.Nil(t, GetFeatureSupport(descriptorpb.File_google_protobuf_descriptor_proto.Messages().ByName("FileOptions")))
+}
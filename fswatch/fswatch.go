@@ -0,0 +1,160 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fswatch provides a resolver that reports changes to the files it
+// resolves, by watching the file system with fsnotify. It can be used with
+// protocompile.Workspace.Watch to recompile files as they change on disk.
+package fswatch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/kralicky/protocompile"
+)
+
+// Resolver is a protocompile.SourceResolver that also implements
+// protocompile.WatchingResolver, reporting changes to .proto files under its
+// import paths. If it has no import paths, the current working directory is
+// watched. Its Accessor, if any, is used to read files, but changes are always
+// detected on the file system.
+type Resolver struct {
+	protocompile.SourceResolver
+	// If not nil, called with errors that occur while watching but do not
+	// stop the watch, such as an overflow of the operating system's event
+	// queue or a failure to watch a new directory. Changes may have been
+	// missed when such an error occurs. If nil, these errors are ignored.
+	OnError func(error)
+}
+
+var _ protocompile.WatchingResolver = (*Resolver)(nil)
+
+// NewResolver returns a resolver that finds files in the given import paths,
+// like a protocompile.SourceResolver, and watches them for changes.
+func NewResolver(importPaths ...string) *Resolver {
+	return &Resolver{SourceResolver: protocompile.SourceResolver{ImportPaths: importPaths}}
+}
+
+// Watch implements protocompile.WatchingResolver. The import paths, and all
+// directories in them, are watched, including directories created while
+// watching. When a directory is created, every .proto file in it is reported
+// as created. It returns an error if the import paths cannot be watched.
+// Errors that occur after that are passed to OnError instead.
+func (r *Resolver) Watch(ctx context.Context, fn func(protocompile.ChangeEvent)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	importPaths := r.ImportPaths
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+	for _, importPath := range importPaths {
+		if err := addDirs(watcher, importPath, nil); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.handleError(err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			var kind protocompile.ChangeKind
+			switch {
+			case event.Has(fsnotify.Create):
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// files created in the directory before it is watched
+					// would otherwise be missed, so report all of them
+					err := addDirs(watcher, event.Name, func(path string) {
+						r.report(importPaths, path, protocompile.ChangeCreated, fn)
+					})
+					if err != nil {
+						r.handleError(err)
+					}
+					continue
+				}
+				kind = protocompile.ChangeCreated
+			case event.Has(fsnotify.Write):
+				kind = protocompile.ChangeModified
+			case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+				// watches for removed directories are removed automatically,
+				// but their files are not reported individually
+				kind = protocompile.ChangeDeleted
+			default:
+				continue
+			}
+			r.report(importPaths, event.Name, kind, fn)
+		}
+	}
+}
+
+// report calls fn with the change to the given file, if it is a .proto file
+// in one of the import paths.
+func (r *Resolver) report(importPaths []string, name string, kind protocompile.ChangeKind, fn func(protocompile.ChangeEvent)) {
+	if filepath.Ext(name) != ".proto" {
+		return
+	}
+	for _, importPath := range importPaths {
+		rel, err := filepath.Rel(importPath, name)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		fn(protocompile.ChangeEvent{Path: protocompile.ResolvedPath(filepath.ToSlash(rel)), Kind: kind})
+		return
+	}
+}
+
+// handleError passes an error that does not stop the watch to OnError.
+func (r *Resolver) handleError(err error) {
+	if r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+// addDirs watches the given directory and all directories in it. If
+// onFile is not nil, it is called for every file found.
+func addDirs(watcher *fsnotify.Watcher, root string, onFile func(path string)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path != root && os.IsNotExist(err) {
+				// removed while walking
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			if onFile != nil {
+				onFile(path)
+			}
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.proto"), []byte(`syntax = "proto3";`), 0o666))
+
+	resolver := NewResolver(dir)
+	events := make(chan protocompile.ChangeEvent, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	ready := make(chan struct{})
+	go func() {
+		close(ready)
+		done <- resolver.Watch(ctx, func(event protocompile.ChangeEvent) {
+			events <- event
+		})
+	}()
+	<-ready
+
+	// wait for the watch to be established by writing a file until it is seen
+	next := func() protocompile.ChangeEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return protocompile.ChangeEvent{}
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.proto"), []byte(`syntax = "proto3";`), 0o666))
+		select {
+		case event := <-events:
+			assert.Equal(t, protocompile.ChangeEvent{Path: "a.proto", Kind: protocompile.ChangeModified}, event)
+		case <-time.After(50 * time.Millisecond):
+			require.True(t, time.Now().Before(deadline), "watch was never established")
+			continue
+		}
+		break
+	}
+	// drain duplicate write events
+	time.Sleep(50 * time.Millisecond)
+	for len(events) > 0 {
+		<-events
+	}
+
+	// files in new directories are reported and watched
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "foo"), 0o777))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo", "b.proto"), nil, 0o666))
+	assert.Equal(t, protocompile.ChangeEvent{Path: "foo/b.proto", Kind: protocompile.ChangeCreated}, next())
+
+	// files other than .proto files are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo", "README"), nil, 0o666))
+	require.NoError(t, os.Remove(filepath.Join(dir, "foo", "b.proto")))
+	for {
+		// the file may have been reported as created twice: once when its
+		// directory was found, and again if the directory was already watched
+		event := next()
+		assert.Equal(t, "foo/b.proto", string(event.Path))
+		if event.Kind != protocompile.ChangeCreated {
+			assert.Equal(t, protocompile.ChangeDeleted, event.Kind)
+			break
+		}
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,117 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+//go:embed seeds/*.proto
+var seeds embed.FS
+
+// DefaultSeeds returns a small seed corpus that covers proto2, proto3, and
+// editions syntax, including a file with syntax errors for the parser's error
+// recovery to start from.
+func DefaultSeeds() [][]byte {
+	corpus, err := readCorpus(seeds, "seeds")
+	if err != nil {
+		panic(err) // seeds are embedded, so this should not be possible
+	}
+	return corpus
+}
+
+// ReadCorpus returns the contents of all .proto files in the given directory
+// and its subdirectories, sorted by path. This is a convenient way to seed a
+// corpus with a project's own sources.
+func ReadCorpus(dir string) ([][]byte, error) {
+	return readCorpus(os.DirFS(dir), ".")
+}
+
+func readCorpus(fsys fs.FS, root string) ([][]byte, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".proto" {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	corpus := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		corpus = append(corpus, data)
+	}
+	return corpus, nil
+}
+
+// AddSeeds adds the given seeds to the corpus of a native Go fuzz test whose
+// fuzz function takes a single []byte argument.
+func AddSeeds(f *testing.F, corpus [][]byte) {
+	f.Helper()
+	for _, data := range corpus {
+		f.Add(data)
+	}
+}
+
+// WriteGoFuzzCorpus writes the given seeds to the given directory in the
+// format used by go-fuzz and libFuzzer: one file per seed containing its raw
+// contents. Files are named by the hash of their contents, so writing the
+// same seed twice produces a single file. The directory is created if
+// necessary.
+func WriteGoFuzzCorpus(dir string, corpus [][]byte) error {
+	return writeCorpus(dir, corpus, func(data []byte) []byte { return data })
+}
+
+// WriteNativeCorpus writes the given seeds to the given directory in the
+// format used by native Go fuzzing for a fuzz function that takes a single
+// []byte argument. To seed a fuzz test named FuzzX, dir should be
+// testdata/fuzz/FuzzX in the test's package directory. Files are named by the
+// hash of their contents, and the directory is created if necessary.
+func WriteNativeCorpus(dir string, corpus [][]byte) error {
+	return writeCorpus(dir, corpus, func(data []byte) []byte {
+		return []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", data))
+	})
+}
+
+func writeCorpus(dir string, corpus [][]byte, encode func([]byte) []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, data := range corpus {
+		sum := sha256.Sum256(data)
+		name := hex.EncodeToString(sum[:8])
+		if err := os.WriteFile(filepath.Join(dir, name), encode(data), 0o644); err != nil { //nolint:gosec // corpus files are not secret
+			return err
+		}
+	}
+	return nil
+}
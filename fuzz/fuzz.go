@@ -0,0 +1,152 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fuzz provides fuzz targets for the parser and compiler, along with
+// utilities for building seed corpora, so that projects using this module can
+// fuzz it with their own configurations.
+//
+// The targets have the signature expected by go-fuzz and OSS-Fuzz, and can
+// also be called from a native Go fuzz test:
+//
+//	func FuzzCompile(f *testing.F) {
+//		fuzz.AddSeeds(f, fuzz.DefaultSeeds())
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			fuzz.CompileFuzz(data)
+//		})
+//	}
+//
+// A target panics if it finds a bug, such as a panic in the parser or
+// compiler, or a violated invariant. Errors in the input are not bugs.
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/parser/parsertest"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Filename is the name of the file that holds the fuzzed input.
+const Filename = "fuzz.proto"
+
+// Target configures a fuzz target. The zero value is usable and fuzzes the
+// default configuration.
+type Target struct {
+	// Options to use when parsing. If nil, parser.DefaultLimits are applied
+	// so that the fuzzer does not report pathological inputs that would be
+	// rejected in practice.
+	ParserOptions []parser.ParserOption
+	// Additional files, by path, that the fuzzed input may import when
+	// compiling. The standard imports are always available.
+	Imports map[string]string
+	// If non-nil, this is called to configure the compiler before each
+	// compilation. The compiler's resolver is already configured; the
+	// function may wrap it but should not replace it.
+	Configure func(*protocompile.Compiler)
+}
+
+// ParseFuzz fuzzes the parser with the default configuration. See
+// Target.Parse.
+func ParseFuzz(data []byte) int {
+	var t Target
+	return t.Parse(data)
+}
+
+// CompileFuzz fuzzes the compiler with the default configuration. See
+// Target.Compile.
+func CompileFuzz(data []byte) int {
+	var t Target
+	return t.Compile(data)
+}
+
+// Parse parses the given data as a source file and then converts the AST to a
+// descriptor proto, reporting errors to a collector so that the parser's
+// error recovery is exercised. If the file parses without error, printing the
+// AST must reproduce the input exactly.
+//
+// Following the go-fuzz convention, it returns 1 if the input is valid, which
+// gives it priority in the corpus, and 0 otherwise.
+func (t *Target) Parse(data []byte) int {
+	opts := t.ParserOptions
+	if opts == nil {
+		opts = []parser.ParserOption{parser.WithLimits(parser.DefaultLimits)}
+	}
+	collector := reporter.NewCollector()
+	handler := reporter.NewHandler(collector)
+	file, err := parser.Parse(Filename, bytes.NewReader(data), handler, 0, opts...)
+	if file == nil {
+		return 0
+	}
+	if err == nil {
+		printed, _ := parsertest.Reprint(Filename, file)
+		if !bytes.Equal(printed, data) {
+			panic(fmt.Sprintf("printed AST does not match input:\ninput:\n%q\nprinted:\n%q", data, printed))
+		}
+	}
+	_, resErr := parser.ResultFromAST(file, true, handler, opts...)
+	if err != nil || resErr != nil {
+		return 0
+	}
+	if errCount, _ := collector.Counts(); errCount > 0 {
+		return 0
+	}
+	return 1
+}
+
+// Compile compiles the given data as a source file, with access to the
+// standard imports and to the target's Imports. Panics during compilation are
+// re-raised, with the stack trace of the original panic. If the file compiles
+// without error, its descriptor proto must be accepted by protodesc.
+//
+// Following the go-fuzz convention, it returns 1 if the input is valid, which
+// gives it priority in the corpus, and 0 otherwise.
+func (t *Target) Compile(data []byte) int {
+	sources := make(map[string]string, len(t.Imports)+1)
+	for path, src := range t.Imports {
+		sources[path] = src
+	}
+	sources[Filename] = string(data)
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		ParseLimits:   parser.DefaultLimits,
+		RecoverPanics: true,
+	}
+	if t.Configure != nil {
+		t.Configure(compiler)
+	}
+	res, err := compiler.Compile(context.Background(), Filename)
+	var panicErr protocompile.PanicError
+	if errors.As(err, &panicErr) {
+		panic(fmt.Sprintf("%v\n%s", panicErr.Value, panicErr.Stack))
+	}
+	if err != nil {
+		return 0
+	}
+	file := res.Files[0]
+	if _, err := protodesc.NewFile(protoutil.ProtoFromFileDescriptor(file), linker.ResolverFromFile(file)); err != nil {
+		panic(fmt.Sprintf("compiled descriptor is invalid: %v", err))
+	}
+	return 1
+}
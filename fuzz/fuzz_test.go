@@ -0,0 +1,94 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/fuzz"
+)
+
+func FuzzParse(f *testing.F) {
+	fuzz.AddSeeds(f, fuzz.DefaultSeeds())
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		fuzz.ParseFuzz(data)
+	})
+}
+
+func FuzzCompile(f *testing.F) {
+	fuzz.AddSeeds(f, fuzz.DefaultSeeds())
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		fuzz.CompileFuzz(data)
+	})
+}
+
+func TestTargets(t *testing.T) {
+	t.Parallel()
+	seeds := fuzz.DefaultSeeds()
+	require.Len(t, seeds, 4)
+	// seeds are sorted by name: editions, invalid, proto2, proto3
+	for i, valid := range []bool{true, false, true, true} {
+		expected := 0
+		if valid {
+			expected = 1
+		}
+		assert.Equal(t, expected, fuzz.ParseFuzz(seeds[i]), "seed %d", i)
+		assert.Equal(t, expected, fuzz.CompileFuzz(seeds[i]), "seed %d", i)
+	}
+
+	target := fuzz.Target{
+		Imports: map[string]string{
+			"dep.proto": `syntax = "proto3"; package dep; message Dep {}`,
+		},
+	}
+	source := []byte(`syntax = "proto3"; import "dep.proto"; message Foo { dep.Dep dep = 1; }`)
+	assert.Equal(t, 1, target.Compile(source))
+	assert.Equal(t, 0, fuzz.CompileFuzz(source))
+
+	var configured bool
+	target.Configure = func(c *protocompile.Compiler) {
+		configured = true
+		c.StrictUTF8 = true
+	}
+	assert.Equal(t, 0, target.Compile([]byte("syntax = \"proto3\"; // \xff\n")))
+	assert.True(t, configured)
+}
+
+func TestWriteCorpus(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	seeds := [][]byte{[]byte("syntax = \"proto3\";\n"), []byte("message Foo {}"), []byte("message Foo {}")}
+	require.NoError(t, fuzz.WriteNativeCorpus(filepath.Join(dir, "native"), seeds))
+	entries, err := os.ReadDir(filepath.Join(dir, "native"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	data, err := os.ReadFile(filepath.Join(dir, "native", entries[0].Name()))
+	require.NoError(t, err)
+	assert.Regexp(t, `^go test fuzz v1\n\[\]byte\(".*"\)\n$`, string(data))
+
+	require.NoError(t, fuzz.WriteGoFuzzCorpus(filepath.Join(dir, "gofuzz"), seeds))
+	corpus, err := fuzz.ReadCorpus(dir)
+	require.NoError(t, err)
+	assert.Empty(t, corpus) // go-fuzz corpus files have no .proto extension
+	entries, err = os.ReadDir(filepath.Join(dir, "gofuzz"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,274 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// hashVersion is incremented whenever the way hashes are computed changes, or
+// whenever the compiler's output changes in a way that is not reflected in
+// the module version, so that hashes from older versions are never equal to
+// new ones.
+const hashVersion = 1
+
+// FileHash contains content hashes for a single compiled file, which can be
+// compared to determine whether the file needs to be compiled again.
+type FileHash struct {
+	// A hash of the file's own inputs: its source code (or the descriptor
+	// provided by the resolver, if it was not compiled from source), the
+	// version of the compiler, and the compiler's configuration that affects
+	// its output. Zero if the resolver provided an AST, since ASTs cannot be
+	// hashed.
+	Content [sha256.Size]byte
+	// A hash of Content along with the Transitive hashes of all of the file's
+	// dependencies. This changes whenever the file or anything it imports,
+	// directly or indirectly, changes. Zero if Content or any dependency's
+	// Transitive hash is zero.
+	Transitive [sha256.Size]byte
+	// The file's direct dependencies, in import order.
+	Dependencies []ResolvedPath
+}
+
+// FileHashes are the hashes of a set of files, keyed by path.
+type FileHashes map[ResolvedPath]FileHash
+
+// FileStatus classifies a file by whether it is up to date. See
+// Compiler.CheckUpToDate.
+type FileStatus int
+
+const (
+	// FileUnchanged indicates that neither the file nor any of its
+	// dependencies have changed, so previous results for it can be reused.
+	FileUnchanged = FileStatus(0)
+	// FileDirty indicates that the file itself has changed or can no longer
+	// be resolved.
+	FileDirty = FileStatus(1)
+	// FileAffected indicates that the file is unchanged, but at least one of
+	// its dependencies, direct or indirect, is dirty.
+	FileAffected = FileStatus(2)
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case FileUnchanged:
+		return "unchanged"
+	case FileDirty:
+		return "dirty"
+	case FileAffected:
+		return "affected"
+	default:
+		return "unknown"
+	}
+}
+
+// CheckUpToDate compares the given hashes, typically from the Hashes of a
+// previous CompileResult, to the current inputs as provided by the compiler's
+// Resolver, and returns the status of each file in previous. A file is dirty
+// if its Content hash differs from the one it would have if compiled now with
+// this compiler, and affected if it is not dirty but one of its dependencies
+// is dirty or affected. Files whose Content hash is zero, or that import files
+// that are not in previous, are always dirty.
+//
+// Source code is read and hashed, but not parsed or compiled.
+func (c *Compiler) CheckUpToDate(previous FileHashes) map[ResolvedPath]FileStatus {
+	config := c.configHash()
+	statuses := make(map[ResolvedPath]FileStatus, len(previous))
+	for path, prev := range previous {
+		if prev.Content == ([sha256.Size]byte{}) {
+			statuses[path] = FileDirty
+			continue
+		}
+		sr, err := c.Resolver.FindFileByPath(UnresolvedPath(path), nil)
+		if err != nil || (sr.ResolvedPath != "" && sr.ResolvedPath != path) {
+			statuses[path] = FileDirty
+			continue
+		}
+		input, ok := hashSearchResult(&sr)
+		if !ok || contentHash(config, input) != prev.Content {
+			statuses[path] = FileDirty
+			continue
+		}
+		statuses[path] = FileUnchanged
+	}
+
+	// propagate to dependents
+	var visit func(path ResolvedPath, seen map[ResolvedPath]bool) FileStatus
+	visit = func(path ResolvedPath, seen map[ResolvedPath]bool) FileStatus {
+		status, ok := statuses[path]
+		if !ok {
+			// not in previous
+			return FileDirty
+		}
+		if status != FileUnchanged || seen[path] {
+			return status
+		}
+		seen[path] = true
+		for _, dep := range previous[path].Dependencies {
+			if visit(dep, seen) != FileUnchanged {
+				statuses[path] = FileAffected
+				return FileAffected
+			}
+		}
+		return FileUnchanged
+	}
+	for path := range previous {
+		visit(path, map[ResolvedPath]bool{})
+	}
+	return statuses
+}
+
+// hashSearchResult returns a hash of the input in the given search result,
+// using the same field that the compiler would use. If that is Source, it is
+// read to the end and closed. It returns false if the input cannot be hashed.
+func hashSearchResult(sr *SearchResult) ([sha256.Size]byte, bool) {
+	switch {
+	case sr.ParseResult != nil:
+		return hashProto(sr.ParseResult.FileDescriptorProto())
+	case sr.Proto != nil:
+		return hashProto(sr.Proto)
+	case sr.AST != nil:
+		return [sha256.Size]byte{}, false
+	case sr.Source != nil:
+		if closer, ok := sr.Source.(io.Closer); ok {
+			defer closer.Close()
+		}
+		var sum [sha256.Size]byte
+		hash := sha256.New()
+		if _, err := io.Copy(hash, sr.Source); err != nil {
+			return sum, false
+		}
+		hash.Sum(sum[:0])
+		return sum, true
+	default:
+		return [sha256.Size]byte{}, false
+	}
+}
+
+func hashProto(msg proto.Message) ([sha256.Size]byte, bool) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	// distinguish descriptors from source code with the same bytes
+	return sha256.Sum256(append(data, "descriptor"...)), true
+}
+
+// contentHash combines the hash of a file's input with the hash of the
+// compiler's configuration.
+func contentHash(config, input [sha256.Size]byte) [sha256.Size]byte {
+	return sha256.Sum256(append(config[:], input[:]...))
+}
+
+var moduleVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	const modulePath = "github.com/kralicky/protocompile"
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Path + "@" + dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+})
+
+// configHash returns a hash of the compiler's version and all configuration
+// that can affect the descriptors or diagnostics it produces.
+func (c *Compiler) configHash() [sha256.Size]byte {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "version %d %s\n", hashVersion, moduleVersion())
+	fmt.Fprintf(hash, "source info %d\n", c.SourceInfoMode)
+	fmt.Fprintf(hash, "options %t %t\n", c.InterpretOptionsLenient, c.SkipOptionInterpretation)
+	if c.OverrideDescriptorProto != nil {
+		// the override may have the same path as the standard file, or as
+		// another override, so its contents are hashed
+		contents, _ := hashProto(protoutil.ProtoFromFileDescriptor(c.OverrideDescriptorProto))
+		fmt.Fprintf(hash, "descriptor.proto %s %x\n", c.OverrideDescriptorProto.Path(), contents)
+	}
+	pseudoOptions := make([]string, 0, len(c.FieldPseudoOptions))
+	for name := range c.FieldPseudoOptions {
+		pseudoOptions = append(pseudoOptions, name)
+	}
+	slices.Sort(pseudoOptions)
+	fmt.Fprintf(hash, "pseudo-options %q\n", pseudoOptions)
+	validators := make([]string, 0, len(c.OptionValidators))
+	for name := range c.OptionValidators {
+		validators = append(validators, string(name))
+	}
+	slices.Sort(validators)
+	fmt.Fprintf(hash, "option validators %q %d\n", validators, c.ConstraintCheck)
+	fmt.Fprintf(hash, "checks %+v %+v %d %+v\n", c.FieldNumberChecks, c.PackageOptionChecks, c.EnumSemanticsCheck, c.SizeBudget)
+	fmt.Fprintf(hash, "limits %+v %+v\n", c.ParseLimits, c.ValidationLimits)
+	fmt.Fprintf(hash, "parsing %d %t %t %t\n", c.SourceEncoding, c.StrictUTF8, c.RawStrings, c.ExperimentalEditions)
+	fmt.Fprintf(hash, "imports %t %d %d %d\n", c.AllowMissingWeakImports, c.WeakImportPolicy, c.PublicImportPolicy, c.DirectDependencyCheck)
+	fmt.Fprintf(hash, "compatibility %+v\n", c.Compatibility)
+	var sum [sha256.Size]byte
+	hash.Sum(sum[:0])
+	return sum
+}
+
+// computeHashes returns the hashes for the given files, which must be in
+// topological order, as returned by linker.ComputeReflexiveTransitiveClosure.
+// The given function returns the hash of each file's input.
+func computeHashes(config [sha256.Size]byte, files linker.Files, inputHash func(ResolvedPath) ([sha256.Size]byte, bool)) FileHashes {
+	hashes := make(FileHashes, len(files))
+	for _, file := range files {
+		path := ResolvedPath(file.Path())
+		var fh FileHash
+		input, ok := inputHash(path)
+		if ok {
+			fh.Content = contentHash(config, input)
+		}
+		transitive := sha256.New()
+		transitive.Write(fh.Content[:])
+		complete := ok
+		imports := file.Imports()
+		fh.Dependencies = make([]ResolvedPath, imports.Len())
+		for i := range fh.Dependencies {
+			depPath := ResolvedPath(imports.Get(i).Path())
+			fh.Dependencies[i] = depPath
+			dep, ok := hashes[depPath]
+			if !ok || dep.Transitive == ([sha256.Size]byte{}) {
+				complete = false
+				continue
+			}
+			fmt.Fprintf(transitive, "%s\n", depPath)
+			transitive.Write(dep.Transitive[:])
+		}
+		if complete {
+			transitive.Sum(fh.Transitive[:0])
+		}
+		hashes[path] = fh
+	}
+	return hashes
+}
@@ -0,0 +1,109 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestCheckUpToDate(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+import "b.proto";
+message A { B b = 1; }
+`,
+		"b.proto": `syntax = "proto3";
+import "google/protobuf/empty.proto";
+message B { google.protobuf.Empty e = 1; }
+`,
+		"c.proto": `syntax = "proto3";
+message C {}
+`,
+	}
+	compiler := &Compiler{
+		Resolver: WithStandardImports(mkResolver(contents)),
+	}
+	res, err := compiler.Compile(context.Background(), "a.proto", "c.proto")
+	require.NoError(t, err)
+	hashes := res.Hashes
+	require.Len(t, hashes, 4)
+	assert.Equal(t, []ResolvedPath{"b.proto"}, hashes["a.proto"].Dependencies)
+	for path, fh := range hashes {
+		assert.NotZero(t, fh.Content, path)
+		assert.NotZero(t, fh.Transitive, path)
+	}
+
+	// hashes are stable
+	res, err = compiler.Compile(context.Background(), "a.proto", "c.proto")
+	require.NoError(t, err)
+	assert.Equal(t, hashes, res.Hashes)
+	assert.Equal(t, map[ResolvedPath]FileStatus{
+		"a.proto":                     FileUnchanged,
+		"b.proto":                     FileUnchanged,
+		"c.proto":                     FileUnchanged,
+		"google/protobuf/empty.proto": FileUnchanged,
+	}, compiler.CheckUpToDate(hashes))
+
+	contents["b.proto"] += "message B2 {}\n"
+	assert.Equal(t, map[ResolvedPath]FileStatus{
+		"a.proto":                     FileAffected,
+		"b.proto":                     FileDirty,
+		"c.proto":                     FileUnchanged,
+		"google/protobuf/empty.proto": FileUnchanged,
+	}, compiler.CheckUpToDate(hashes))
+
+	res, err = compiler.Compile(context.Background(), "a.proto", "c.proto")
+	require.NoError(t, err)
+	assert.Equal(t, hashes["a.proto"].Content, res.Hashes["a.proto"].Content)
+	assert.NotEqual(t, hashes["a.proto"].Transitive, res.Hashes["a.proto"].Transitive)
+	assert.NotEqual(t, hashes["b.proto"].Content, res.Hashes["b.proto"].Content)
+	assert.Equal(t, hashes["c.proto"], res.Hashes["c.proto"])
+	hashes = res.Hashes
+
+	// configuration that affects output invalidates everything
+	delete(contents, "c.proto")
+	compiler.SourceInfoMode = SourceInfoStandard
+	assert.Equal(t, map[ResolvedPath]FileStatus{
+		"a.proto":                     FileDirty,
+		"b.proto":                     FileDirty,
+		"c.proto":                     FileDirty,
+		"google/protobuf/empty.proto": FileDirty,
+	}, compiler.CheckUpToDate(hashes))
+
+	// as does allowing experimental editions, which changes what is accepted
+	assert.NotEqual(t, (&Compiler{}).configHash(), (&Compiler{ExperimentalEditions: true}).configHash())
+	assert.NotEqual(t, (&Compiler{}).configHash(), (&Compiler{Compatibility: ProtocCompatibility}).configHash())
+
+	// overrides of descriptor.proto are told apart by their contents
+	std, err := linker.NewFileRecursive(descriptorpb.File_google_protobuf_descriptor_proto)
+	require.NoError(t, err)
+	fdp := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	fdp.MessageType = append(fdp.MessageType, &descriptorpb.DescriptorProto{Name: proto.String("Extra")})
+	fd, err := protodesc.NewFile(fdp, nil)
+	require.NoError(t, err)
+	custom, err := linker.NewFileRecursive(fd)
+	require.NoError(t, err)
+	assert.NotEqual(t, (&Compiler{OverrideDescriptorProto: std}).configHash(), (&Compiler{OverrideDescriptorProto: custom}).configHash())
+}
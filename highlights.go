@@ -0,0 +1,99 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// HighlightKind describes how an occurrence of a symbol uses it.
+type HighlightKind int
+
+const (
+	// HighlightDefinition is the name in the symbol's declaration.
+	HighlightDefinition HighlightKind = iota + 1
+	// HighlightReference is a reference to the symbol, such as a field's type,
+	// a method's input or output type, or a part of an option name.
+	HighlightReference
+)
+
+// Highlight is an occurrence of a symbol in a file. See Workspace.HighlightsAt.
+type Highlight struct {
+	Kind HighlightKind
+	Span ast.SourceSpan
+}
+
+// HighlightsAt returns the occurrences, in the given file, of the symbol that
+// is referenced or declared at the given position, as determined by ResolveAt.
+// They are sorted by position. Only the given file's result is consulted, so
+// this is cheaper than finding the symbol's references in all files. It
+// returns nil if there is no symbol at the position or the file has no AST.
+func (w *Workspace) HighlightsAt(path ResolvedPath, pos ast.SourcePos) []Highlight {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil
+	}
+	target := resolveAt(res, pos)
+	if target == nil {
+		return nil
+	}
+	same := func(d protoreflect.Descriptor) bool {
+		return d.FullName() == target.FullName() && d.ParentFile().Path() == target.ParentFile().Path()
+	}
+
+	var highlights []Highlight
+	if target.ParentFile().Path() == res.Path() {
+		node := res.Node(protoutil.ProtoFromDescriptor(target))
+		if named, ok := node.(interface{ GetName() *ast.IdentNode }); ok && named.GetName() != nil {
+			highlights = append(highlights, Highlight{Kind: HighlightDefinition, Span: res.AST().NodeInfo(named.GetName())})
+		}
+	}
+	res.RangeReferences(func(ref ast.NodeReference, to protoreflect.Descriptor) bool {
+		if !same(to) {
+			return true
+		}
+		// narrow references to the name, excluding a message literal field's
+		// value or the parentheses and stream keyword of a method's type
+		var span ast.SourceSpan = ref.NodeInfo
+		switch node := ref.Node.(type) {
+		case *ast.MessageFieldNode:
+			if node.GetName() != nil {
+				span = res.AST().NodeInfo(node.GetName())
+			}
+		case *ast.RPCTypeNode:
+			if node.GetMessageType() != nil {
+				span = res.AST().NodeInfo(node.GetMessageType())
+			}
+		}
+		highlights = append(highlights, Highlight{Kind: HighlightReference, Span: span})
+		return true
+	})
+	sort.Slice(highlights, func(i, j int) bool {
+		a, b := highlights[i].Span.Start(), highlights[j].Span.Start()
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return highlights
+}
@@ -0,0 +1,90 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+func TestWorkspaceHighlightsAt(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(map[UnresolvedPath]string{
+			"a.proto": `syntax = "proto3";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Rule {
+  string name = 1;
+}
+extend google.protobuf.MessageOptions {
+  Rule rule = 50000;
+}
+message Msg {
+  option (rule) = { name: "x" };
+  Rule r = 1;
+}
+message Msg2 { option (rule).name = "y"; }
+service Svc {
+  rpc Get(Rule) returns (Msg);
+}
+`,
+			"b.proto": `syntax = "proto3";
+package foo;
+import "a.proto";
+message Other { Rule r = 1; }
+`,
+		})),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto"))
+
+	highlights := func(path ResolvedPath, pos ast.SourcePos) []string {
+		var actual []string
+		for _, h := range ws.HighlightsAt(path, pos) {
+			kind := "ref"
+			if h.Kind == HighlightDefinition {
+				kind = "def"
+			}
+			actual = append(actual, fmt.Sprintf("%s %s", h.Span.Start(), kind))
+		}
+		return actual
+	}
+
+	// message declaration
+	assert.Equal(t, []string{
+		"a.proto:4:9 def",
+		"a.proto:8:3 ref",
+		"a.proto:12:3 ref",
+		"a.proto:16:11 ref",
+	}, highlights("a.proto", ast.SourcePos{Line: 4, Col: 10}))
+	// field used in option names and message literals
+	assert.Equal(t, []string{
+		"a.proto:5:10 def",
+		"a.proto:11:21 ref",
+		"a.proto:14:30 ref",
+	}, highlights("a.proto", ast.SourcePos{Line: 14, Col: 31}))
+	// declared in another file
+	assert.Equal(t, []string{
+		"b.proto:4:17 ref",
+	}, highlights("b.proto", ast.SourcePos{Line: 4, Col: 18}))
+	assert.Nil(t, ws.HighlightsAt("a.proto", ast.SourcePos{Line: 1, Col: 1}))
+}
@@ -0,0 +1,187 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// HookError describes a call to one of the CompilerHooks that panicked or
+// that did not return within Compiler.HookTimeout. It is reported as a
+// diagnostic for the file that was being processed when the hook was called:
+// an error if the hook panicked, or a warning if it timed out.
+type HookError struct {
+	// The name of the hook, which is the name of its field in CompilerHooks.
+	Hook string
+	// The file for which the hook was called.
+	File ResolvedPath
+	// If the hook panicked, the details of the panic. Otherwise, nil.
+	Panic *PanicError
+	// If the hook did not return in time, the timeout that elapsed.
+	Timeout time.Duration
+}
+
+// Error implements the error interface.
+func (e HookError) Error() string {
+	if e.Panic != nil {
+		return fmt.Sprintf("internal error: panic in CompilerHooks.%s: %v", e.Hook, e.Panic.Value)
+	}
+	return fmt.Sprintf("CompilerHooks.%s did not return within %v; continuing without it", e.Hook, e.Timeout)
+}
+
+// Unwrap returns the PanicError if the hook panicked.
+func (e HookError) Unwrap() error {
+	if e.Panic != nil {
+		return *e.Panic
+	}
+	return nil
+}
+
+// callHook calls fn, which invokes the named hook for the given file, under
+// the contract described on CompilerHooks. Hook failures are reported to h.
+func (e *executor) callHook(ctx context.Context, h *reporter.Handler, hook string, path ResolvedPath, fn func()) {
+	e.invokeHook(ctx, h, hook, path, nil, func(func(context.Context)) { fn() })
+}
+
+// invokeHook calls the named hook for the given file, under the contract
+// described on CompilerHooks. The call function invokes the hook. If phase
+// is not nil, call passes the given run function to the hook, and phase is
+// run, at most once, on the calling goroutine when the hook calls it. Hook
+// failures are reported to h. It returns true if phase was run.
+func (e *executor) invokeHook(ctx context.Context, h *reporter.Handler, hook string, path ResolvedPath, phase func(context.Context), call func(run func(context.Context))) (ran bool) {
+	timeout := e.c.HookTimeout
+	if timeout <= 0 {
+		return e.invokeHookSync(ctx, h, hook, path, phase, call)
+	}
+
+	// The hook is called from another goroutine so that we can stop waiting
+	// for it. When it calls run, the phase is handed back to this goroutine,
+	// so that panics in the phase propagate as they normally would.
+	var claimed atomic.Bool
+	phases := make(chan context.Context)
+	finished := make(chan bool) // true if the phase panicked
+	abandoned := make(chan struct{})
+	done := make(chan *PanicError, 1)
+	run := func(ctx context.Context) {
+		if phase == nil || !claimed.CompareAndSwap(false, true) {
+			return
+		}
+		select {
+		case phases <- ctx:
+		case <-abandoned:
+			return
+		}
+		if <-finished {
+			// unwind the hook, running its deferred functions, as if the
+			// panic had propagated through it
+			runtime.Goexit()
+		}
+	}
+	go func() {
+		var panicErr *PanicError
+		defer func() {
+			if v := recover(); v != nil {
+				panicErr = &PanicError{File: string(path), Value: v, Stack: string(debug.Stack())}
+			}
+			done <- panicErr
+		}()
+		call(run)
+	}()
+
+	remaining := timeout
+	for {
+		timer := time.NewTimer(remaining)
+		start := time.Now()
+		select {
+		case panicErr := <-done:
+			timer.Stop()
+			if panicErr != nil {
+				e.reportHookError(ctx, h, HookError{Hook: hook, File: path, Panic: panicErr})
+			}
+			return ran
+		case phaseCtx := <-phases:
+			// time spent running the phase does not count against the hook
+			timer.Stop()
+			remaining -= time.Since(start)
+			ran = true
+			func() {
+				panicked := true
+				defer func() {
+					finished <- panicked
+				}()
+				pprof.SetGoroutineLabels(phaseCtx)
+				defer pprof.SetGoroutineLabels(ctx)
+				phase(phaseCtx)
+				panicked = false
+			}()
+		case <-timer.C:
+			close(abandoned)
+			e.reportHookError(ctx, h, HookError{Hook: hook, File: path, Timeout: timeout})
+			return ran
+		case <-ctx.Done():
+			timer.Stop()
+			close(abandoned)
+			return ran
+		}
+	}
+}
+
+// invokeHookSync is like invokeHook, but calls the hook on the calling
+// goroutine. It is used when hooks are not subject to a timeout.
+func (e *executor) invokeHookSync(ctx context.Context, h *reporter.Handler, hook string, path ResolvedPath, phase func(context.Context), call func(run func(context.Context))) (ran bool) {
+	var inPhase bool
+	defer func() {
+		if inPhase {
+			// the phase panicked, not the hook; let it propagate
+			return
+		}
+		if v := recover(); v != nil {
+			panicErr := &PanicError{File: string(path), Value: v, Stack: string(debug.Stack())}
+			e.reportHookError(ctx, h, HookError{Hook: hook, File: path, Panic: panicErr})
+		}
+	}()
+	call(func(ctx context.Context) {
+		if phase == nil || ran {
+			return
+		}
+		ran = true
+		inPhase = true
+		phase(ctx)
+		inPhase = false
+	})
+	return ran
+}
+
+// reportHookError logs the given hook failure and reports it to h.
+func (e *executor) reportHookError(ctx context.Context, h *reporter.Handler, err HookError) {
+	span := ast.UnknownSpan(string(err.File))
+	if err.Panic != nil {
+		e.log(ctx, slog.LevelError, "recovered from panic in hook", slog.String("hook", err.Hook), slog.String("path", string(err.File)), slog.Any("panic", err.Panic.Value), slog.String("stack", err.Panic.Stack))
+		_ = h.HandleErrorWithPos(span, err)
+		return
+	}
+	e.log(ctx, slog.LevelWarn, "abandoned hook that did not return in time", slog.String("hook", err.Hook), slog.String("path", string(err.File)), slog.Duration("timeout", err.Timeout))
+	h.HandleWarningWithPos(span, err)
+}
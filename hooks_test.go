@@ -0,0 +1,159 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestHookPanicIsolated(t *testing.T) {
+	t.Parallel()
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		var mu sync.Mutex
+		var errs []reporter.ErrorWithPos
+		compiler := Compiler{
+			Resolver: WithStandardImports(mkResolver(baseContents)),
+			Reporter: reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+				return nil
+			}, nil),
+			HookTimeout: timeout,
+			Hooks: CompilerHooks{
+				PreCompile: func(path ResolvedPath) {
+					if path == "a/b/b2.proto" {
+						panic("oops")
+					}
+				},
+			},
+		}
+		_, err := compiler.Compile(context.Background(), "a/b/b2.proto", "c/c.proto")
+		require.ErrorIs(t, err, reporter.ErrInvalidSource)
+		require.Len(t, errs, 1)
+		var hookErr HookError
+		require.ErrorAs(t, errs[0], &hookErr)
+		assert.Equal(t, "PreCompile", hookErr.Hook)
+		assert.Equal(t, ResolvedPath("a/b/b2.proto"), hookErr.File)
+		require.NotNil(t, hookErr.Panic)
+		assert.Equal(t, "oops", hookErr.Panic.Value)
+		var panicErr PanicError
+		assert.True(t, errors.As(errs[0], &panicErr))
+		assert.Equal(t, "a/b/b2.proto", errs[0].GetPosition().Start().Filename)
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	t.Parallel()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	var mu sync.Mutex
+	var warnings []reporter.ErrorWithPos
+	compiler := Compiler{
+		Resolver: WithStandardImports(mkResolver(baseContents)),
+		Reporter: reporter.NewReporter(nil, func(err reporter.ErrorWithPos) {
+			mu.Lock()
+			defer mu.Unlock()
+			warnings = append(warnings, err)
+		}),
+		HookTimeout: 20 * time.Millisecond,
+		Hooks: CompilerHooks{
+			PreCompile: func(path ResolvedPath) {
+				if path == "c/c.proto" {
+					<-unblock
+				}
+			},
+		},
+	}
+	res, err := compiler.Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	require.Len(t, res.Files, 1)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, warnings, 1)
+	var hookErr HookError
+	require.ErrorAs(t, warnings[0], &hookErr)
+	assert.Equal(t, "PreCompile", hookErr.Hook)
+	assert.Equal(t, ResolvedPath("c/c.proto"), hookErr.File)
+	assert.Nil(t, hookErr.Panic)
+	assert.Equal(t, 20*time.Millisecond, hookErr.Timeout)
+}
+
+func TestRunPhaseHookTimeout(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var phases []Phase
+	var warnings int
+	compiler := &Compiler{
+		Resolver: WithStandardImports(mkResolver(baseContents)),
+		Reporter: reporter.NewReporter(nil, func(reporter.ErrorWithPos) {
+			mu.Lock()
+			defer mu.Unlock()
+			warnings++
+		}),
+		HookTimeout: time.Minute,
+		Hooks: CompilerHooks{
+			RunPhase: func(ctx context.Context, path ResolvedPath, phase Phase, run func(context.Context)) {
+				mu.Lock()
+				phases = append(phases, phase)
+				mu.Unlock()
+				if phase == PhaseLink {
+					// not calling run is a bug in the hook, but the phase
+					// still runs
+					panic("oops")
+				}
+				PprofLabels(ctx, path, phase, run)
+			},
+		},
+	}
+	_, err := compiler.Compile(context.Background(), "c/c.proto")
+	var hookErr HookError
+	require.ErrorAs(t, err, &hookErr)
+	assert.Equal(t, "RunPhase", hookErr.Hook)
+	mu.Lock()
+	assert.Contains(t, phases, PhaseParse)
+	assert.Contains(t, phases, PhaseLink)
+	assert.Zero(t, warnings)
+	mu.Unlock()
+
+	// panics in the phase itself are not attributed to the hook
+	compiler = &Compiler{
+		Resolver: ResolverFunc(func(path UnresolvedPath, _ ImportContext) (SearchResult, error) {
+			return SearchResult{ResolvedPath: ResolvedPath(path), Source: panicReader{}}, nil
+		}),
+		RecoverPanics: true,
+		HookTimeout:   time.Minute,
+		Hooks: CompilerHooks{
+			RunPhase: func(ctx context.Context, path ResolvedPath, phase Phase, run func(context.Context)) {
+				run(ctx)
+			},
+		},
+	}
+	_, err = compiler.Compile(context.Background(), "c/c.proto")
+	var panicErr PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "oops", panicErr.Value)
+	assert.Contains(t, panicErr.Stack, "panicReader")
+	assert.False(t, errors.As(err, &hookErr))
+}
@@ -0,0 +1,179 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/protointernal"
+)
+
+// ImportStep is a single import statement in an ImportChain.
+type ImportStep struct {
+	// The file that contains the import statement.
+	Importer ResolvedPath
+	// The path as it appears in the import statement.
+	ImportedAs UnresolvedPath
+	// The file that the import statement resolved to.
+	Imported ResolvedPath
+	// The span of the import statement. If the AST of the importer
+	// was not retained, this is computed from its source code info, if any.
+	// Otherwise, or if the import was implicit (such as an overridden
+	// descriptor.proto), this is an unknown span in the importing file.
+	Span ast.SourceSpan
+}
+
+// ImportChain is a sequence of imports that leads from a file that was
+// requested to be compiled to one of its transitive dependencies. The first
+// step's Importer is the requested file, and each subsequent step's Importer
+// is the previous step's Imported file.
+type ImportChain []ImportStep
+
+// String returns the chain as a sequence of quoted paths separated by arrows,
+// such as "a.proto" -> "b.proto" -> "c.proto".
+func (c ImportChain) String() string {
+	if len(c) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`"` + string(c[0].Importer) + `"`)
+	for _, step := range c {
+		sb.WriteString(` -> "` + string(step.Imported) + `"`)
+	}
+	return sb.String()
+}
+
+// requestedFiles returns the files in descs that have the given paths, in
+// the order the paths were given. Paths that are repeated, or that did not
+// produce a linked file, are skipped.
+func requestedFiles(paths []ResolvedPath, descs linker.Files) linker.Files {
+	byPath := make(map[string]linker.File, len(descs))
+	for _, d := range descs {
+		byPath[d.Path()] = d
+	}
+	roots := make(linker.Files, 0, len(paths))
+	for _, path := range paths {
+		if d, ok := byPath[string(path)]; ok {
+			roots = append(roots, d)
+			delete(byPath, string(path))
+		}
+	}
+	return roots
+}
+
+// WhyCompiled explains why the file with the given path was compiled, by
+// returning chains of imports that lead to it from the files that were
+// requested to be compiled. For each requested file that transitively imports
+// the given file, the shortest such chain is returned, in the order the files
+// were requested. If the given file was itself requested, the result includes
+// an empty chain for it.
+//
+// This is mostly useful when IncludeDependenciesInResults is set, to explain
+// the presence of dependencies in the results. If the given file was not
+// compiled, or is only reachable from files that failed to link, nil is
+// returned.
+func (r CompileResult) WhyCompiled(path ResolvedPath) []ImportChain {
+	var chains []ImportChain
+	for _, root := range r.roots {
+		if root.Path() == string(path) {
+			chains = append(chains, ImportChain{})
+			continue
+		}
+		if chain := shortestImportChain(root, path); chain != nil {
+			chains = append(chains, chain)
+		}
+	}
+	return chains
+}
+
+// shortestImportChain does a breadth-first search of the imports of the given
+// root file for the given path.
+func shortestImportChain(root linker.File, path ResolvedPath) ImportChain {
+	type visit struct {
+		file linker.File
+		via  *visit
+		step ImportStep
+	}
+	seen := map[string]struct{}{root.Path(): {}}
+	queue := []*visit{{file: root}}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for i, dep := range v.file.Dependencies() {
+			if dep == nil || dep.IsPlaceholder() {
+				continue
+			}
+			if _, ok := seen[dep.Path()]; ok {
+				continue
+			}
+			seen[dep.Path()] = struct{}{}
+			next := &visit{file: dep, via: v, step: importStep(v.file, i, dep)}
+			if dep.Path() != string(path) {
+				queue = append(queue, next)
+				continue
+			}
+			var chain ImportChain
+			for ; next.via != nil; next = next.via {
+				chain = append(chain, next.step)
+			}
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+			return chain
+		}
+	}
+	return nil
+}
+
+// importStep describes the i-th dependency of the given file.
+func importStep(f linker.File, i int, dep linker.File) ImportStep {
+	step := ImportStep{
+		Importer:   ResolvedPath(f.Path()),
+		ImportedAs: UnresolvedPath(dep.Path()),
+		Imported:   ResolvedPath(dep.Path()),
+		Span:       ast.UnknownSpan(f.Path()),
+	}
+	// the dependencies of a file correspond to its imports, followed by any
+	// implicitly-added descriptor.proto
+	if i >= f.Imports().Len() {
+		return step
+	}
+	step.ImportedAs = UnresolvedPath(f.Imports().Get(i).Path())
+	if res, ok := f.(parser.Result); ok && res.AST() != nil {
+		root := res.AST()
+		for _, decl := range root.Decls {
+			if imp := decl.GetImport(); imp != nil && !imp.IsIncomplete() && imp.Name.AsString() == string(step.ImportedAs) {
+				step.Span = root.NodeInfo(imp)
+				break
+			}
+		}
+		return step
+	}
+	// no AST, so fall back to source code info, if present
+	loc := f.SourceLocations().ByPath(protoreflect.SourcePath{descpath.FileDependencyTag, int32(i)})
+	if !protointernal.IsZeroSourceLocation(loc) {
+		step.Span = ast.NewSourceSpan(
+			ast.SourcePos{Filename: f.Path(), Line: loc.StartLine + 1, Col: loc.StartColumn + 1},
+			ast.SourcePos{Filename: f.Path(), Line: loc.EndLine + 1, Col: loc.EndColumn + 1},
+		)
+	}
+	return step
+}
@@ -0,0 +1,195 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// InlayHintKind describes the information that an inlay hint shows.
+type InlayHintKind int
+
+const (
+	// InlayHintPresence shows the field presence of a singular field in a
+	// file that uses editions, where it is determined by features instead
+	// of by the field's label.
+	InlayHintPresence InlayHintKind = iota + 1
+	// InlayHintEncoding shows whether a repeated scalar field in a file that
+	// uses editions is packed or expanded.
+	InlayHintEncoding
+	// InlayHintTypeName shows the fully-qualified name of a type that is
+	// referred to by a relative name.
+	InlayHintTypeName
+	// InlayHintJSONName shows the JSON name of a field that has no json_name
+	// option, when it differs from the field's name.
+	InlayHintJSONName
+)
+
+func (k InlayHintKind) String() string {
+	switch k {
+	case InlayHintPresence:
+		return "presence"
+	case InlayHintEncoding:
+		return "encoding"
+	case InlayHintTypeName:
+		return "type name"
+	case InlayHintJSONName:
+		return "json name"
+	default:
+		return "unknown"
+	}
+}
+
+// InlayHint is information about a file that editors display inline in its
+// source, such as with LSP inlay hints. See Workspace.InlayHints.
+type InlayHint struct {
+	Kind InlayHintKind
+	// The position at which the hint is displayed, which is immediately after
+	// the element that it describes.
+	Pos  ast.SourcePos
+	Text string
+}
+
+// InlayHints returns the inlay hints for the given file, sorted by position.
+// It returns nil if the file has not been compiled or has no AST.
+func (w *Workspace) InlayHints(path ResolvedPath) []InlayHint {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil
+	}
+
+	var hints []InlayHint
+	addHint := func(kind InlayHintKind, after ast.Node, text string) {
+		hints = append(hints, InlayHint{Kind: kind, Pos: res.AST().NodeInfo(after).End(), Text: text})
+	}
+	addTypeName := func(node *ast.IdentValueNode, d protoreflect.Descriptor) {
+		if node == nil || d == nil || d.IsPlaceholder() {
+			return
+		}
+		name := string(node.AsIdentifier())
+		if !strings.HasPrefix(name, ".") && name != string(d.FullName()) {
+			addHint(InlayHintTypeName, node, string(d.FullName()))
+		}
+	}
+	editions := res.Syntax() == protoreflect.Editions
+	extendees := map[*ast.ExtendNode]struct{}{}
+	_ = walk.Descriptors(res, func(d protoreflect.Descriptor) error {
+		switch d := d.(type) {
+		case protoreflect.FieldDescriptor:
+			if msg, ok := d.Parent().(protoreflect.MessageDescriptor); ok && msg.IsMapEntry() {
+				// the map field itself gets the hints
+				return nil
+			}
+			fdp := protoutil.ProtoFromFieldDescriptor(d)
+			fieldDecl := res.FieldNode(fdp)
+			if fieldDecl == nil {
+				return nil
+			}
+			node := fieldDecl.Unwrap()
+			if node == nil || node.GetName() == nil || node.GetTag() == nil {
+				return nil
+			}
+			switch node := node.(type) {
+			case *ast.FieldNode:
+				addTypeName(node.GetFieldType(), fieldType(d))
+			case *ast.MapFieldNode:
+				addTypeName(node.GetMapType().GetValueType(), fieldType(d.MapValue()))
+			}
+			if d.IsExtension() {
+				if extend := res.FieldExtendeeNode(fdp); extend != nil {
+					if _, ok := extendees[extend]; !ok {
+						extendees[extend] = struct{}{}
+						addTypeName(extend.GetExtendee(), d.ContainingMessage())
+					}
+				}
+			}
+			if editions {
+				switch {
+				case d.IsList():
+					if protointernal.CanPack(d.Kind()) {
+						encoding := "expanded"
+						if d.IsPacked() {
+							encoding = "packed"
+						}
+						addHint(InlayHintEncoding, node.GetName(), encoding)
+					}
+				case !d.IsMap() && d.Message() == nil && d.ContainingOneof() == nil:
+					presence := "implicit presence"
+					if d.Cardinality() == protoreflect.Required {
+						presence = "required"
+					} else if d.HasPresence() {
+						presence = "explicit presence"
+					}
+					addHint(InlayHintPresence, node.GetName(), presence)
+				}
+			}
+			if !d.IsExtension() && d.JSONName() != string(d.Name()) && !hasOption(node.GetOptions(), "json_name") {
+				addHint(InlayHintJSONName, node.GetTag(), fmt.Sprintf("json_name: %q", d.JSONName()))
+			}
+		case protoreflect.MethodDescriptor:
+			node := res.MethodNode(protoutil.ProtoFromMethodDescriptor(d))
+			if node == nil {
+				return nil
+			}
+			addTypeName(node.GetInput().GetMessageType(), d.Input())
+			addTypeName(node.GetOutput().GetMessageType(), d.Output())
+		}
+		return nil
+	})
+	sort.SliceStable(hints, func(i, j int) bool {
+		a, b := hints[i].Pos, hints[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return hints
+}
+
+// fieldType returns the message or enum that is the type of the given field,
+// or nil if it has a scalar type.
+func fieldType(fd protoreflect.FieldDescriptor) protoreflect.Descriptor {
+	if md := fd.Message(); md != nil {
+		return md
+	}
+	if ed := fd.Enum(); ed != nil {
+		return ed
+	}
+	return nil
+}
+
+// hasOption reports whether the given compact options set the standard
+// option with the given name.
+func hasOption(opts *ast.CompactOptionsNode, name string) bool {
+	for _, opt := range opts.GetOptions() {
+		parts := opt.GetName().FilterFieldReferences()
+		if len(parts) == 1 && !parts[0].IsExtension() && string(parts[0].GetName().AsIdentifier()) == name {
+			return true
+		}
+	}
+	return false
+}
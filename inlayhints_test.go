@@ -0,0 +1,71 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceInlayHints(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(map[UnresolvedPath]string{
+			"a.proto": `edition = "2023";
+package foo.bar;
+option features.field_presence = IMPLICIT;
+message Thing {
+  int32 user_id = 1;
+  string first_name = 2 [json_name = "first"];
+  Other other = 3;
+  repeated int32 ids = 4 [features.repeated_field_encoding = EXPANDED];
+  repeated sint64 values = 5;
+  int32 count = 6 [features.field_presence = EXPLICIT];
+  .foo.bar.Other abs = 7;
+  map<string, Other> m_map = 8;
+}
+message Other {}
+service Svc {
+  rpc Get(Thing) returns (bar.Other);
+}
+`,
+		})),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto"))
+	assert.Nil(t, ws.InlayHints("b.proto"))
+
+	var actual []string
+	for _, hint := range ws.InlayHints("a.proto") {
+		actual = append(actual, fmt.Sprintf("%s %s: %s", hint.Pos, hint.Kind, hint.Text))
+	}
+	assert.Equal(t, []string{
+		"a.proto:5:16 presence: implicit presence",
+		`a.proto:5:20 json name: json_name: "userId"`,
+		"a.proto:6:20 presence: implicit presence",
+		"a.proto:7:8 type name: foo.bar.Other",
+		"a.proto:8:21 encoding: expanded",
+		"a.proto:9:25 encoding: packed",
+		"a.proto:10:14 presence: explicit presence",
+		"a.proto:12:20 type name: foo.bar.Other",
+		`a.proto:12:31 json name: json_name: "mMap"`,
+		"a.proto:16:16 type name: foo.bar.Thing",
+		"a.proto:16:36 type name: foo.bar.Other",
+	}, actual)
+}
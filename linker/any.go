@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+)
+
+// UnpackedAny is the unpacked value of a google.protobuf.Any message in an
+// interpreted option.
+type UnpackedAny struct {
+	// The unpacked message. It is a dynamic message whose type, and the types
+	// of any extensions in it, are resolved from the elements visible to the
+	// file that contains the option, the same way as when the option was
+	// interpreted.
+	Message protoreflect.Message
+	// The type reference, like "[type.googleapis.com/foo.Bar]", of the
+	// expanded Any syntax that produced the value. This is nil if the value
+	// was not written using the expanded syntax, or if the file has no AST.
+	TypeURL *ast.FieldReferenceNode
+	// The span of TypeURL in the file's source. This is nil if TypeURL is nil.
+	TypeURLSpan ast.SourceSpan
+}
+
+// UnpackAny unpacks anyMsg, a google.protobuf.Any message from an interpreted
+// option in res. It returns an error if anyMsg is not an Any message, or if its
+// type URL refers to a message that is not visible to res, or if its value
+// cannot be unmarshaled as that message.
+func UnpackAny(res Result, anyMsg protoreflect.Message) (*UnpackedAny, error) {
+	md := anyMsg.Descriptor()
+	if md.FullName() != "google.protobuf.Any" {
+		return nil, fmt.Errorf("%s is not google.protobuf.Any", md.FullName())
+	}
+	typeURLField := md.Fields().ByNumber(descpath.AnyTypeURLTag)
+	valueField := md.Fields().ByNumber(descpath.AnyValueTag)
+	if typeURLField == nil || valueField == nil {
+		return nil, fmt.Errorf("%s is missing type_url or value field", md.FullName())
+	}
+	typeURL := anyMsg.Get(typeURLField).String()
+	value := anyMsg.Get(valueField).Bytes()
+
+	resolver := ResolverFromFile(res)
+	mt, err := resolver.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve type URL %q: %w", typeURL, err)
+	}
+	msg := mt.New()
+	if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(value, msg.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value of type %s: %w", mt.Descriptor().FullName(), err)
+	}
+	unpacked := &UnpackedAny{Message: msg}
+
+	if file := res.AST(); file != nil {
+		// Identical values may appear in more than one place; the first one in
+		// the file is reported.
+		for node, val := range res.OptionDescriptorIndex().TypeReferenceURLsToAnyValues {
+			if val.GetTypeUrl() != typeURL || !bytes.Equal(val.GetValue(), value) {
+				continue
+			}
+			span := file.NodeInfo(node)
+			if unpacked.TypeURL == nil || span.Start().Offset < unpacked.TypeURLSpan.Start().Offset {
+				unpacked.TypeURL = node
+				unpacked.TypeURLSpan = span
+			}
+		}
+	}
+	return unpacked, nil
+}
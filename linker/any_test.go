@@ -0,0 +1,89 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestUnpackAny(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "b.proto";
+import "google/protobuf/any.proto";
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions { optional google.protobuf.Any any = 50000; }
+extend bar.Payload { optional string note = 100; }
+message Msg {
+  option (any) = {
+    [type.googleapis.com/bar.Payload] {
+      id: 123
+      [foo.note]: "abc"
+    }
+  };
+}`,
+		"b.proto": `syntax = "proto2";
+package bar;
+message Payload {
+  optional int32 id = 1;
+  extensions 100 to 200;
+}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		RetainASTs: true,
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	res, ok := files.Files[0].(linker.Result)
+	require.True(t, ok)
+
+	var anyMsg protoreflect.Message
+	res.Messages().ByName("Msg").Options().ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Number() == 50000 {
+			anyMsg = v.Message()
+		}
+		return true
+	})
+	require.NotNil(t, anyMsg)
+
+	unpacked, err := linker.UnpackAny(res, anyMsg)
+	require.NoError(t, err)
+	assert.Equal(t, "bar.Payload", string(unpacked.Message.Descriptor().FullName()))
+	var fields []string
+	unpacked.Message.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fields = append(fields, string(fd.FullName())+"="+v.String())
+		return true
+	})
+	assert.ElementsMatch(t, []string{"bar.Payload.id=123", "foo.note=abc"}, fields)
+	require.NotNil(t, unpacked.TypeURL)
+	assert.Equal(t, "a.proto:10:5-38", unpacked.TypeURLSpan.String())
+
+	_, err = linker.UnpackAny(res, (&anypb.Any{TypeUrl: "type.googleapis.com/bar.Missing"}).ProtoReflect())
+	assert.ErrorContains(t, err, `could not resolve type URL "type.googleapis.com/bar.Missing"`)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode/utf8"
 
 	"google.golang.org/protobuf/proto"
@@ -30,6 +31,7 @@ import (
 
 	art "github.com/kralicky/go-adaptive-radix-tree"
 	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
 	"github.com/kralicky/protocompile/editions"
 	"github.com/kralicky/protocompile/parser"
 	"github.com/kralicky/protocompile/protointernal"
@@ -69,6 +71,7 @@ var (
 	messageEncodingField       = editions.FeatureSetDescriptor.Fields().ByName("message_encoding")
 	enumTypeField              = editions.FeatureSetDescriptor.Fields().ByName("enum_type")
 	jsonFormatField            = editions.FeatureSetDescriptor.Fields().ByName("json_format")
+	utf8ValidationField        = editions.FeatureSetDescriptor.Fields().ByName("utf8_validation")
 )
 
 func init() {
@@ -155,13 +158,25 @@ type result struct {
 	prefix string
 	deps   Files
 
+	// Interner shared with the symbol table, used to de-duplicate the
+	// fully-qualified names of all descriptors.
+	names *protointernal.Interner
+
+	// If linked using a DescriptorPool, the pool and the memory that will be
+	// returned to it when this result is released.
+	descriptorPool *DescriptorPool
+	arena          *arena
+
 	// A map of all descriptors keyed by their fully-qualified name (without
 	// any leading dot).
 	descriptors art.Tree[protoreflect.Descriptor]
 
 	// A set of imports that have been used in the course of linking and
-	// interpreting options.
-	usedImports map[string]struct{}
+	// interpreting options. Resolving names via ResolverFromFile also marks
+	// imports as used, which can happen concurrently once the result is
+	// complete, so this is guarded by usedImportsMu.
+	usedImportsMu sync.Mutex
+	usedImports   map[string]struct{}
 
 	// A map of AST nodes that represent identifiers in ast.FieldReferenceNodes
 	// to their fully-qualified name. The identifiers are for field names in
@@ -184,6 +199,17 @@ type result struct {
 	srcLocations  srcLocs
 	optsIndex     sourceinfo.OptionIndex
 	optsDescIndex sourceinfo.OptionDescriptorIndex
+
+	// Loads the AST again after it is removed. May be nil.
+	astLoader ASTLoader
+
+	// The naming of synthetic oneofs reported by the parse result before its
+	// AST was removed.
+	oneofNaming parser.SyntheticOneofNaming
+
+	// For results created with Restore, option indexes that are attached
+	// once the AST is loaded. May be nil.
+	restoredIndexes *savedOptionIndexes
 }
 
 var (
@@ -198,7 +224,18 @@ func (r *result) Dependencies() Files {
 
 var _ editions.HasEdition = (*result)(nil)
 
+// SyntheticOneofNaming reports the naming of the parse result, so that
+// parser.SyntheticOneofNamingOf works for linked results too. The naming is
+// still reported after the AST is removed.
+func (r *result) SyntheticOneofNaming() parser.SyntheticOneofNaming {
+	if naming := parser.SyntheticOneofNamingOf(r.Result); naming != parser.SyntheticOneofNamingUnknown {
+		return naming
+	}
+	return r.oneofNaming
+}
+
 func (r *result) RemoveAST() {
+	r.oneofNaming = r.SyntheticOneofNaming()
 	r.Result = parser.ResultWithoutAST(r.FileDescriptorProto())
 	r.optionQualifiedNames = nil
 }
@@ -298,19 +335,142 @@ func (r *result) PopulateSourceCodeInfo(optsIndex sourceinfo.OptionIndex, optsDe
 	srcLocIndex := computeSourceLocIndex(srcLocProtos)
 	r.srcLocations = srcLocs{file: r, locs: srcLocProtos, index: srcLocIndex}
 	r.optsIndex = optsIndex
-	r.optsDescIndex = optsDescIndex
+	r.PopulateOptionDescriptorIndex(optsDescIndex)
+}
+
+func (r *result) RegenerateSourceInfo(opts ...sourceinfo.GenerateOption) error {
+	if r.AST() == nil {
+		return ErrNoAST
+	}
+	// The option index may have changed since the descriptor index was
+	// populated, so mappings for options that are no longer in it are dropped,
+	// along with the references found from them.
+	r.PopulateOptionDescriptorIndex(r.prunedOptionDescriptorIndex())
+	r.FileDescriptorProto().SourceCodeInfo = sourceinfo.GenerateSourceInfo(r.Result, r.optsIndex, opts...)
+	srcLocProtos := asSourceLocations(r.FileDescriptorProto().GetSourceCodeInfo().GetLocation())
+	r.srcLocations = srcLocs{file: r, locs: srcLocProtos, index: computeSourceLocIndex(srcLocProtos)}
+	return nil
+}
+
+// prunedOptionDescriptorIndex returns the option descriptor index without the
+// mappings for nodes inside options that are not in the option index. It
+// returns the index unchanged if there is no option index or if there are no
+// such options.
+func (r *result) prunedOptionDescriptorIndex() sourceinfo.OptionDescriptorIndex {
+	if r.optsIndex == nil {
+		return r.optsDescIndex
+	}
+	stale := map[ast.Node]struct{}{}
+	ast.Inspect(r.AST(), func(node ast.Node) bool {
+		opt, ok := node.(*ast.OptionNode)
+		if !ok {
+			return true
+		}
+		if _, ok := r.optsIndex[opt]; !ok {
+			ast.Inspect(opt, func(n ast.Node) bool {
+				stale[n] = struct{}{}
+				return true
+			})
+		}
+		return false
+	})
+	if len(stale) == 0 {
+		return r.optsDescIndex
+	}
+	keep := func(node ast.Node) ast.Node {
+		if _, ok := stale[node]; ok {
+			return nil
+		}
+		return node
+	}
+	idx := r.optsDescIndex
+	pruned := sourceinfo.OptionDescriptorIndex{
+		UninterpretedNameDescriptorsToFieldDescriptors: idx.UninterpretedNameDescriptorsToFieldDescriptors,
+		FieldReferenceNodesToFieldDescriptors:          remapKeys(idx.FieldReferenceNodesToFieldDescriptors, keep),
+		EnumValueIdentNodesToEnumValueDescriptors:      remapKeys(idx.EnumValueIdentNodesToEnumValueDescriptors, keep),
+		OptionsToFieldDescriptors:                      idx.OptionsToFieldDescriptors,
+		TypeReferenceURLsToMessageDescriptors:          remapKeys(idx.TypeReferenceURLsToMessageDescriptors, keep),
+		MessageLiteralsToMessageDescriptors:            remapKeys(idx.MessageLiteralsToMessageDescriptors, keep),
+		TypeReferenceURLsToAnyValues:                   remapKeys(idx.TypeReferenceURLsToAnyValues, keep),
+	}
+	if idx.FieldDefaults != nil {
+		pruned.FieldDefaults = make(map[protoreflect.FullName]sourceinfo.FieldDefault, len(idx.FieldDefaults))
+		for name, def := range idx.FieldDefaults {
+			if def.Option != nil {
+				if _, ok := stale[def.Option]; ok {
+					continue
+				}
+			}
+			pruned.FieldDefaults[name] = def
+		}
+	}
+	return pruned
+}
+
+func (r *result) OptionIndex() sourceinfo.OptionIndex {
+	return r.optsIndex
+}
+
+func (r *result) PopulateOptionDescriptorIndex(optsDescIndex sourceinfo.OptionDescriptorIndex) {
 	a := r.AST()
-	for node, desc := range optsDescIndex.FieldReferenceNodesToFieldDescriptors {
-		r.resolvedReferences[desc] = append(r.resolvedReferences[desc], ast.NewNodeReference(a, node))
+	if a != nil {
+		r.removeOptionReferences(r.optsDescIndex)
+	}
+	r.optsDescIndex = optsDescIndex
+	if a == nil {
+		return
+	}
+	rangeOptionReferences(optsDescIndex, func(to protoreflect.Descriptor, node ast.Node) {
+		r.resolvedReferences[to] = append(r.resolvedReferences[to], ast.NewNodeReference(a, node))
+	})
+}
 
+// removeOptionReferences removes the references that were recorded for the
+// given index by PopulateOptionDescriptorIndex, so that populating the index
+// again does not record them twice.
+func (r *result) removeOptionReferences(optsDescIndex sourceinfo.OptionDescriptorIndex) {
+	type optionRef struct {
+		to   protoreflect.Descriptor
+		node ast.Node
+	}
+	recorded := map[optionRef]int{}
+	rangeOptionReferences(optsDescIndex, func(to protoreflect.Descriptor, node ast.Node) {
+		recorded[optionRef{to, node}]++
+	})
+	if len(recorded) == 0 {
+		return
+	}
+	for to, refs := range r.resolvedReferences {
+		kept := refs[:0]
+		for _, ref := range refs {
+			key := optionRef{to, ref.Node}
+			if recorded[key] > 0 {
+				recorded[key]--
+				continue
+			}
+			kept = append(kept, ref)
+		}
+		if len(kept) == 0 {
+			delete(r.resolvedReferences, to)
+			continue
+		}
+		r.resolvedReferences[to] = kept
+	}
+}
+
+// rangeOptionReferences calls fn for each reference to a descriptor that is
+// recorded for the given index by PopulateOptionDescriptorIndex.
+func rangeOptionReferences(optsDescIndex sourceinfo.OptionDescriptorIndex, fn func(to protoreflect.Descriptor, node ast.Node)) {
+	for node, desc := range optsDescIndex.FieldReferenceNodesToFieldDescriptors {
+		fn(desc, node)
 		if ref, ok := node.(*ast.FieldReferenceNode); ok {
 			if name := ref.GetName().GetCompoundIdent(); name != nil {
-				r.indexCompoundIdentRefs(name, desc)
+				rangeCompoundIdentRefs(name, desc, fn)
 			}
 		}
 	}
 	for node, desc := range optsDescIndex.EnumValueIdentNodesToEnumValueDescriptors {
-		r.resolvedReferences[desc] = append(r.resolvedReferences[desc], ast.NewNodeReference(a, node))
+		fn(desc, node)
 	}
 }
 
@@ -370,6 +530,20 @@ func (r *result) FindReferences(to protoreflect.Descriptor) (results []ast.NodeR
 	return r.resolvedReferences[to]
 }
 
+func (r *result) RangeReferences(fn func(ref ast.NodeReference, to protoreflect.Descriptor) bool) {
+	for to, refs := range r.resolvedReferences {
+		for _, ref := range refs {
+			if !fn(ref, to) {
+				return
+			}
+		}
+	}
+}
+
+func (o *result) OptionDescriptorIndex() sourceinfo.OptionDescriptorIndex {
+	return o.optsDescIndex
+}
+
 func (o *result) FindOptionNameFieldDescriptor(name *descriptorpb.UninterpretedOption_NamePart) protoreflect.FieldDescriptor {
 	return o.optsDescIndex.UninterpretedNameDescriptorsToFieldDescriptors[name]
 }
@@ -398,6 +572,17 @@ func (o *result) FindMessageDescriptorByTypeReferenceURLNode(node *ast.FieldRefe
 	return o.optsDescIndex.TypeReferenceURLsToMessageDescriptors[node]
 }
 
+func (o *result) FindMessageDescriptorByMessageLiteralNode(node *ast.MessageLiteralNode) protoreflect.MessageDescriptor {
+	return o.optsDescIndex.MessageLiteralsToMessageDescriptors[node]
+}
+
+func (o *result) FindFieldDefault(fld protoreflect.FieldDescriptor) sourceinfo.FieldDefault {
+	if def, ok := o.optsDescIndex.FieldDefaults[fld.FullName()]; ok {
+		return def
+	}
+	return sourceinfo.FieldDefault{Value: fld.Default()}
+}
+
 func computeSourceLocIndex(locs []protoreflect.SourceLocation) map[interface{}]int {
 	index := map[interface{}]int{}
 	for i, loc := range locs {
@@ -513,7 +698,7 @@ func (s *srcLocs) ByDescriptor(d protoreflect.Descriptor) protoreflect.SourceLoc
 	if d.ParentFile() != s.file {
 		return protoreflect.SourceLocation{}
 	}
-	path, ok := protointernal.ComputeSourcePath(d)
+	path, ok := descpath.ForDescriptor(d)
 	if !ok {
 		return protoreflect.SourceLocation{}
 	}
@@ -528,7 +713,7 @@ type msgDescriptors struct {
 func (r *result) createMessages(prefix string, parent protoreflect.Descriptor, msgProtos []*descriptorpb.DescriptorProto, pool *allocPool) msgDescriptors {
 	msgs := pool.getMessages(len(msgProtos))
 	for i, msgProto := range msgProtos {
-		r.createMessageDescriptor(&msgs[i], msgProto, parent, i, prefix+msgProto.GetName(), pool)
+		r.createMessageDescriptor(&msgs[i], msgProto, parent, i, r.names.Concat(prefix, msgProto.GetName()), pool)
 	}
 	return msgDescriptors{msgs: msgs}
 }
@@ -801,7 +986,7 @@ type enumDescriptors struct {
 func (r *result) createEnums(prefix string, parent protoreflect.Descriptor, enumProtos []*descriptorpb.EnumDescriptorProto, pool *allocPool) enumDescriptors {
 	enums := pool.getEnums(len(enumProtos))
 	for i, enumProto := range enumProtos {
-		r.createEnumDescriptor(&enums[i], enumProto, parent, i, prefix+enumProto.GetName(), pool)
+		r.createEnumDescriptor(&enums[i], enumProto, parent, i, r.names.Concat(prefix, enumProto.GetName()), pool)
 	}
 	return enumDescriptors{enums: enums}
 }
@@ -961,7 +1146,7 @@ type enValDescriptors struct {
 func (r *result) createEnumValues(prefix string, parent *enumDescriptor, enValProtos []*descriptorpb.EnumValueDescriptorProto, pool *allocPool) enValDescriptors {
 	vals := pool.getEnumValues(len(enValProtos))
 	for i, enValProto := range enValProtos {
-		r.createEnumValueDescriptor(&vals[i], enValProto, parent, i, prefix+enValProto.GetName())
+		r.createEnumValueDescriptor(&vals[i], enValProto, parent, i, r.names.Concat(prefix, enValProto.GetName()))
 	}
 	return enValDescriptors{vals: vals}
 }
@@ -1070,7 +1255,7 @@ type extDescriptors struct {
 func (r *result) createExtensions(prefix string, parent protoreflect.Descriptor, extProtos []*descriptorpb.FieldDescriptorProto, pool *allocPool) extDescriptors {
 	exts := pool.getExtensions(len(extProtos))
 	for i, extProto := range extProtos {
-		r.createExtTypeDescriptor(&exts[i], extProto, parent, i, prefix+extProto.GetName())
+		r.createExtTypeDescriptor(&exts[i], extProto, parent, i, r.names.Concat(prefix, extProto.GetName()))
 	}
 	return extDescriptors{exts: exts}
 }
@@ -1129,7 +1314,7 @@ func (r *result) createFields(prefix string, parent *msgDescriptor, fldProtos []
 	fields := pool.getFields(len(fldProtos))
 	fieldPtrs := make([]*fldDescriptor, len(fldProtos))
 	for i, fldProto := range fldProtos {
-		r.createFieldDescriptor(&fields[i], fldProto, parent, i, prefix+fldProto.GetName())
+		r.createFieldDescriptor(&fields[i], fldProto, parent, i, r.names.Concat(prefix, fldProto.GetName()))
 		fieldPtrs[i] = &fields[i]
 	}
 	return fldDescriptors{fields: fieldPtrs}
@@ -1679,7 +1864,7 @@ type oneofDescriptors struct {
 func (r *result) createOneofs(prefix string, parent *msgDescriptor, ooProtos []*descriptorpb.OneofDescriptorProto, pool *allocPool) oneofDescriptors {
 	oos := pool.getOneofs(len(ooProtos))
 	for i, fldProto := range ooProtos {
-		r.createOneofDescriptor(&oos[i], fldProto, parent, i, prefix+fldProto.GetName())
+		r.createOneofDescriptor(&oos[i], fldProto, parent, i, r.names.Concat(prefix, fldProto.GetName()))
 	}
 	return oneofDescriptors{oneofs: oos}
 }
@@ -1797,7 +1982,7 @@ type svcDescriptors struct {
 func (r *result) createServices(prefix string, svcProtos []*descriptorpb.ServiceDescriptorProto, pool *allocPool) svcDescriptors {
 	svcs := pool.getServices(len(svcProtos))
 	for i, svcProto := range svcProtos {
-		r.createServiceDescriptor(&svcs[i], svcProto, i, prefix+svcProto.GetName(), pool)
+		r.createServiceDescriptor(&svcs[i], svcProto, i, r.names.Concat(prefix, svcProto.GetName()), pool)
 	}
 	return svcDescriptors{svcs: svcs}
 }
@@ -1899,7 +2084,7 @@ type mtdDescriptors struct {
 func (r *result) createMethods(prefix string, parent *svcDescriptor, mtdProtos []*descriptorpb.MethodDescriptorProto, pool *allocPool) mtdDescriptors {
 	mtds := pool.getMethods(len(mtdProtos))
 	for i, mtdProto := range mtdProtos {
-		r.createMethodDescriptor(&mtds[i], mtdProto, parent, i, prefix+mtdProto.GetName())
+		r.createMethodDescriptor(&mtds[i], mtdProto, parent, i, r.names.Concat(prefix, mtdProto.GetName()))
 	}
 	return mtdDescriptors{mtds: mtds}
 }
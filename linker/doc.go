@@ -45,4 +45,19 @@
 // The calling code simply uses the same Symbols instance across all compile
 // operations and if any files processed have such conflicts, they can be
 // reported.
+//
+// # Concurrency
+//
+// Once Link returns and the compile steps that follow it (interpreting options
+// and generating source code info) are complete, a Result is not modified
+// further, and it is safe for concurrent use by multiple goroutines. That
+// includes resolving names with ResolverFromFile and querying references and
+// option descriptor indexes. The exceptions are the methods that perform those
+// compile steps, such as PopulateSourceCodeInfo and RemoveAST, which must not
+// be called concurrently with any other use of the Result. Results allocated
+// from a DescriptorPool are also subject to the pool's rules for reuse.
+//
+// A Symbols table is safe for concurrent use, but readers may observe files
+// being imported or deleted. Callers that need a consistent view while files
+// are being linked should query a snapshot made with Clone.
 package linker
@@ -0,0 +1,83 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/walk"
+)
+
+// EnumValueIndex maps the simple names of enum values to the values with that
+// name, across a set of files and all of their transitive imports. This is
+// used to produce suggestions when a reference to an enum value uses a name
+// that the expected enum does not have, but that belongs to another enum.
+//
+// The index is built lazily, the first time it is queried, since it is only
+// needed when reporting errors. It is safe for concurrent use.
+type EnumValueIndex struct {
+	files  []protoreflect.FileDescriptor
+	once   sync.Once
+	byName map[protoreflect.Name][]protoreflect.EnumValueDescriptor
+}
+
+// NewEnumValueIndex returns an index of the enum values in the given files and
+// their transitive imports.
+func NewEnumValueIndex(files ...protoreflect.FileDescriptor) *EnumValueIndex {
+	return &EnumValueIndex{files: files}
+}
+
+func (ix *EnumValueIndex) init() {
+	ix.byName = map[protoreflect.Name][]protoreflect.EnumValueDescriptor{}
+	seen := map[string]struct{}{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if fd == nil || fd.IsPlaceholder() {
+			return
+		}
+		if _, ok := seen[fd.Path()]; ok {
+			return
+		}
+		seen[fd.Path()] = struct{}{}
+		_ = walk.Descriptors(fd, func(d protoreflect.Descriptor) error {
+			if ev, ok := d.(protoreflect.EnumValueDescriptor); ok {
+				ix.byName[ev.Name()] = append(ix.byName[ev.Name()], ev)
+			}
+			return nil
+		})
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+	}
+	for _, fd := range ix.files {
+		add(fd)
+	}
+	for _, vals := range ix.byName {
+		sort.Slice(vals, func(i, j int) bool {
+			return vals[i].FullName() < vals[j].FullName()
+		})
+	}
+}
+
+// Lookup returns all enum values with the given simple name, sorted by
+// full name.
+func (ix *EnumValueIndex) Lookup(name protoreflect.Name) []protoreflect.EnumValueDescriptor {
+	ix.once.Do(ix.init)
+	return ix.byName[name]
+}
@@ -0,0 +1,208 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/editions"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// EnumSemantics describes whether an enum is open or closed, along with
+// where that was determined. An open enum accepts any numeric value, while
+// a closed enum only accepts the numbers of its values; when unmarshalling,
+// unrecognized numbers for a closed enum are stored as unknown fields.
+type EnumSemantics struct {
+	Enum   protoreflect.EnumDescriptor
+	Closed bool
+	// The edition of the file that declares the enum. For files that use
+	// proto2 or proto3 syntax, this is EDITION_PROTO2 or EDITION_PROTO3.
+	Edition descriptorpb.Edition
+	// The element whose options explicitly set the enum_type feature that
+	// applies to the enum. This is the enum itself or one of its ancestors.
+	// If nil, the enum uses the default for Edition.
+	SetBy protoreflect.Descriptor
+}
+
+// ResolveEnumSemantics reports whether the given enum is open or closed,
+// based on its effective enum_type feature.
+func ResolveEnumSemantics(ed protoreflect.EnumDescriptor) EnumSemantics {
+	sem := EnumSemantics{
+		Enum:    ed,
+		Closed:  ed.IsClosed(),
+		Edition: editions.GetEdition(ed),
+	}
+	if ed.ParentFile().Syntax() != protoreflect.Editions {
+		// only files that use editions can override features
+		return sem
+	}
+	for d := protoreflect.Descriptor(ed); d != nil; d = d.Parent() {
+		withFeatures, ok := d.Options().(editions.HasFeatures)
+		if !ok {
+			continue
+		}
+		if features := withFeatures.GetFeatures(); features != nil && features.EnumType != nil {
+			sem.SetBy = d
+			break
+		}
+	}
+	return sem
+}
+
+func (s EnumSemantics) String() string {
+	kind := "open"
+	if s.Closed {
+		kind = "closed"
+	}
+	switch d := s.SetBy.(type) {
+	case nil:
+		return fmt.Sprintf("%s (default for %s)", kind, editionString(s.Edition))
+	case protoreflect.FileDescriptor:
+		return fmt.Sprintf("%s (features.enum_type set in file %q)", kind, d.Path())
+	default:
+		return fmt.Sprintf("%s (features.enum_type set on %s)", kind, d.FullName())
+	}
+}
+
+// editionString returns a description of the given edition, like "proto3"
+// or "edition 2023".
+func editionString(edition descriptorpb.Edition) string {
+	switch edition {
+	case descriptorpb.Edition_EDITION_PROTO2:
+		return "proto2"
+	case descriptorpb.Edition_EDITION_PROTO3:
+		return "proto3"
+	default:
+		return "edition " + strings.TrimPrefix(edition.String(), "EDITION_")
+	}
+}
+
+// CheckEnumSemantics reports fields in the given file whose type is a closed
+// enum, but where open semantics are expected:
+//   - Map fields whose value type is a closed enum. When a map entry has an
+//     unrecognized value, the whole entry is stored as an unknown field, so
+//     it is not visible in the map.
+//   - Fields in a file in which enums are open by default, such as proto3
+//     files, that refer to a closed enum. Unrecognized values are not kept
+//     in the field, which is likely surprising in such a file.
+//
+// Fields with implicit presence may not refer to closed enums at all, which
+// is always an error and so is not reported here. Findings are reported at
+// the field's type, with an error that implements ErrorClosedEnumUsage. If
+// any error is reported and the handler does not suppress it, this function
+// returns a non-nil error.
+func CheckEnumSemantics(res Result, handler *reporter.Handler, level CheckLevel) error {
+	if level == CheckLevelOff {
+		return nil
+	}
+	file := res.FileNode()
+	return walk.Descriptors(res, func(d protoreflect.Descriptor) error {
+		fld, ok := d.(protoreflect.FieldDescriptor)
+		if !ok {
+			return nil
+		}
+		if msg, ok := fld.Parent().(protoreflect.MessageDescriptor); ok && msg.IsMapEntry() {
+			// reported for the map field instead
+			return nil
+		}
+		var err *errClosedEnumUsage
+		switch {
+		case fld.IsMap():
+			if enum := fld.MapValue().Enum(); enum != nil && enum.IsClosed() {
+				err = &errClosedEnumUsage{field: fld, semantics: ResolveEnumSemantics(enum), isMapValue: true}
+			}
+		case fld.Enum() != nil && fld.Enum().IsClosed():
+			if !fld.IsList() && !fld.HasPresence() {
+				// already an error
+				return nil
+			}
+			defaults := editions.GetEditionDefaults(editions.GetEdition(fld))
+			if defaults.GetEnumType() == descriptorpb.FeatureSet_OPEN {
+				err = &errClosedEnumUsage{field: fld, semantics: ResolveEnumSemantics(fld.Enum())}
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		var span ast.SourceSpan = ast.UnknownSpan(res.Path())
+		if node := fieldTypeNode(res, fld); node != nil {
+			span = file.NodeInfo(node)
+		}
+		if level == CheckLevelWarn {
+			handler.HandleWarningWithPos(span, err)
+			return nil
+		}
+		return handler.HandleErrorWithPos(span, err)
+	})
+}
+
+// ErrorClosedEnumUsage may be passed to a reporter by CheckEnumSemantics
+// when a field refers to a closed enum where open semantics are expected.
+// The error the reporter receives will be wrapped with the source position
+// of the field's type.
+type ErrorClosedEnumUsage interface {
+	error
+	// Field returns the field that refers to the closed enum.
+	Field() protoreflect.FieldDescriptor
+	// Semantics describes the closed enum, including where its enum_type
+	// feature was set.
+	Semantics() EnumSemantics
+}
+
+type errClosedEnumUsage struct {
+	field      protoreflect.FieldDescriptor
+	semantics  EnumSemantics
+	isMapValue bool
+}
+
+func (e *errClosedEnumUsage) Error() string {
+	if e.isMapValue {
+		return fmt.Sprintf("map field %s has closed enum %s as its value type, so entries with unrecognized values are stored as unknown fields; enum is %v",
+			e.field.FullName(), e.semantics.Enum.FullName(), e.semantics)
+	}
+	return fmt.Sprintf("field %s refers to closed enum %s, but enums in %s are open by default, so unrecognized values are stored as unknown fields; enum is %v",
+		e.field.FullName(), e.semantics.Enum.FullName(), editionString(editions.GetEdition(e.field)), e.semantics)
+}
+
+func (e *errClosedEnumUsage) Field() protoreflect.FieldDescriptor {
+	return e.field
+}
+
+func (e *errClosedEnumUsage) Semantics() EnumSemantics {
+	return e.semantics
+}
+
+// fieldTypeNode returns the node for the type of the given field, or nil if
+// the file has no AST.
+func fieldTypeNode(res Result, fld protoreflect.FieldDescriptor) ast.Node {
+	var node *ast.FieldDeclNode
+	switch fld := fld.(type) {
+	case *fldDescriptor:
+		node = res.FieldNode(fld.proto)
+	case *extTypeDescriptor:
+		node = res.FieldNode(fld.field.proto)
+	}
+	if node == nil {
+		return nil
+	}
+	return node.GetFieldTypeNode()
+}
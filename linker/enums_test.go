@@ -0,0 +1,130 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestResolveEnumSemantics(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `edition = "2023";
+package foo;
+enum Open { OPEN_ZERO = 0; }
+enum Closed {
+  option features.enum_type = CLOSED;
+  CLOSED_ZERO = 0;
+}
+message Msg {
+  enum Nested { NESTED_ZERO = 0; }
+}`,
+		"b.proto": `edition = "2023";
+package foo;
+option features.enum_type = CLOSED;
+message Other {
+  enum Nested { NESTED_ZERO = 0; }
+}`,
+		"c.proto": `syntax = "proto2";
+package foo;
+enum Legacy { LEGACY_ZERO = 0; }`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto", "b.proto", "c.proto")
+	require.NoError(t, err)
+
+	testCases := map[string]string{
+		"foo.Open":         "open (default for edition 2023)",
+		"foo.Closed":       "closed (features.enum_type set on foo.Closed)",
+		"foo.Msg.Nested":   "open (default for edition 2023)",
+		"foo.Other.Nested": `closed (features.enum_type set in file "b.proto")`,
+		"foo.Legacy":       "closed (default for proto2)",
+	}
+	for name, expected := range testCases {
+		d, err := files.Files.AsResolver().FindDescriptorByName(protoreflect.FullName(name))
+		require.NoError(t, err)
+		ed, ok := d.(protoreflect.EnumDescriptor)
+		require.True(t, ok, name)
+		sem := linker.ResolveEnumSemantics(ed)
+		assert.Equal(t, expected, sem.String(), name)
+		assert.Equal(t, ed.IsClosed(), sem.Closed, name)
+	}
+}
+
+func TestCheckEnumSemantics(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto3";
+package foo;
+import "b.proto";
+message Msg {
+  repeated Legacy legacies = 1;
+  optional Legacy legacy = 2;
+  Open open = 3;
+}
+enum Open { OPEN_ZERO = 0; }`,
+		"b.proto": `syntax = "proto2";
+package foo;
+enum Legacy { LEGACY_ZERO = 0; }
+message Other {
+  optional Legacy legacy = 1;
+  map<string, Legacy> by_name = 2;
+}`,
+	}
+	var warnings []reporter.ErrorWithPos
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		Reporter: reporter.NewReporter(nil, func(err reporter.ErrorWithPos) {
+			warnings = append(warnings, err)
+		}),
+		EnumSemanticsCheck: linker.CheckLevelWarn,
+	}
+	_, err := compiler.Compile(context.Background(), "a.proto", "b.proto")
+	require.NoError(t, err)
+
+	// files are checked concurrently, so sort the findings
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Error() < warnings[j].Error()
+	})
+	var actual []string
+	for _, w := range warnings {
+		actual = append(actual, w.Error())
+	}
+	assert.Equal(t, []string{
+		`a.proto:5:12-18: field foo.Msg.legacies refers to closed enum foo.Legacy, but enums in proto3 are open by default, so unrecognized values are stored as unknown fields; enum is closed (default for proto2)`,
+		`a.proto:6:12-18: field foo.Msg.legacy refers to closed enum foo.Legacy, but enums in proto3 are open by default, so unrecognized values are stored as unknown fields; enum is closed (default for proto2)`,
+		`b.proto:6:3-22: map field foo.Other.by_name has closed enum foo.Legacy as its value type, so entries with unrecognized values are stored as unknown fields; enum is closed (default for proto2)`,
+	}, actual)
+	var usage linker.ErrorClosedEnumUsage
+	require.ErrorAs(t, warnings[0], &usage)
+	assert.Equal(t, "foo.Msg.legacies", string(usage.Field().FullName()))
+	assert.True(t, usage.Semantics().Closed)
+}
@@ -0,0 +1,504 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// extensionDeclaration identifies a declaration in the options of an
+// extension range.
+type extensionDeclaration struct {
+	extendee   protoreflect.MessageDescriptor
+	rangeIndex int
+	index      int
+	number     int32
+}
+
+// span returns the span of the declaration or, if a tag is given, of the
+// field of the declaration with that tag.
+func (d extensionDeclaration) span(tag ...int32) ast.SourceSpan {
+	path := append([]int32{descpath.ExtensionRangeOptionsDeclarationsTag, int32(d.index)}, tag...)
+	return extensionRangeOptionSpan(d.extendee, d.rangeIndex, path...)
+}
+
+// declaredExtensionNames tracks the full names that appear in extension
+// declarations, so that a name is not declared for more than one extension.
+// It is populated with the declarations in the file's imports the first time
+// it is used.
+type declaredExtensionNames struct {
+	file  *result
+	names map[string]extensionDeclaration
+}
+
+func (d *declaredExtensionNames) init() {
+	if d.names != nil {
+		return
+	}
+	d.names = map[string]extensionDeclaration{}
+	seen := map[string]struct{}{d.file.Path(): {}}
+	var addImports func(fd protoreflect.FileDescriptor)
+	addImports = func(fd protoreflect.FileDescriptor) {
+		imports := fd.Imports()
+		for i, l := 0, imports.Len(); i < l; i++ {
+			imp := imports.Get(i)
+			if imp.IsPlaceholder() {
+				continue
+			}
+			if _, ok := seen[imp.Path()]; ok {
+				continue
+			}
+			seen[imp.Path()] = struct{}{}
+			addImports(imp.FileDescriptor)
+			_ = walk.Descriptors(imp.FileDescriptor, func(desc protoreflect.Descriptor) error {
+				if md, ok := desc.(protoreflect.MessageDescriptor); ok {
+					d.addAll(md)
+				}
+				return nil
+			})
+		}
+	}
+	addImports(d.file)
+}
+
+func (d *declaredExtensionNames) addAll(md protoreflect.MessageDescriptor) {
+	ranges := md.ExtensionRanges()
+	for i, l := 0, ranges.Len(); i < l; i++ {
+		opts, _ := md.ExtensionRangeOptions(i).(*descriptorpb.ExtensionRangeOptions)
+		for j, decl := range opts.GetDeclaration() {
+			if decl.FullName == nil {
+				continue
+			}
+			if _, ok := d.names[decl.GetFullName()]; !ok {
+				d.names[decl.GetFullName()] = extensionDeclaration{extendee: md, rangeIndex: i, index: j, number: decl.GetNumber()}
+			}
+		}
+	}
+}
+
+// add records the given declaration. If the name was already declared for
+// a different extension, that other declaration is returned.
+func (d *declaredExtensionNames) add(name string, decl extensionDeclaration) (extensionDeclaration, bool) {
+	d.init()
+	if prev, ok := d.names[name]; ok {
+		// The same declaration may appear in multiple ranges when they are
+		// declared in a single extensions statement.
+		sameExtension := prev.extendee.FullName() == decl.extendee.FullName() && prev.number == decl.number
+		return prev, !sameExtension
+	}
+	d.names[name] = decl
+	return extensionDeclaration{}, false
+}
+
+// validateExtensionDeclarations checks the extension declarations in the
+// options of the given message's extension ranges.
+func (r *result) validateExtensionDeclarations(md *msgDescriptor, declaredNames *declaredExtensionNames, handler *reporter.Handler) error {
+	for i, er := range md.proto.GetExtensionRange() {
+		opts := er.GetOptions()
+		if len(opts.GetDeclaration()) == 0 {
+			continue
+		}
+		// A single extensions statement with multiple ranges produces a
+		// range for each, with identical options. The range of declaration
+		// numbers is checked for all of them, but everything else is only
+		// checked for the first, so errors aren't reported more than once.
+		siblings := r.extensionRangeSiblings(md.proto, er)
+		first := siblings[0] == er
+		if first && opts.Verification != nil && opts.GetVerification() != descriptorpb.ExtensionRangeOptions_DECLARATION {
+			span := extensionRangeOptionSpan(md, i, descpath.ExtensionRangeOptionsVerificationTag)
+			if err := handler.HandleErrorf(span, "extension range cannot have declarations and have verification of %s", opts.GetVerification()); err != nil {
+				return err
+			}
+		}
+		declaredNumbers := map[int32]extensionDeclaration{}
+		for j, decl := range opts.GetDeclaration() {
+			declaration := extensionDeclaration{extendee: md, rangeIndex: i, index: j, number: decl.GetNumber()}
+			switch number := decl.GetNumber(); {
+			case decl.Number == nil:
+				if first {
+					if err := handler.HandleErrorf(declaration.span(), "extension declaration is missing required field number"); err != nil {
+						return err
+					}
+				}
+			case number < er.GetStart() || number >= er.GetEnd():
+				var hint string
+				for _, sibling := range siblings {
+					if number >= sibling.GetStart() && number < sibling.GetEnd() {
+						hint = "; when using declarations, extends statements should indicate only a single span of field numbers"
+						break
+					}
+				}
+				span := declaration.span(descpath.ExtensionDeclarationNumberTag)
+				if err := handler.HandleErrorf(span, "extension declaration has number outside the range: %d not in [%d,%d]%s", number, er.GetStart(), er.GetEnd()-1, hint); err != nil {
+					return err
+				}
+			default:
+				if prev, ok := declaredNumbers[number]; ok {
+					span := declaration.span(descpath.ExtensionDeclarationNumberTag)
+					if err := handler.HandleErrorf(span, "extension for tag number %d already declared at %v", number, prev.span(descpath.ExtensionDeclarationNumberTag).Start()); err != nil {
+						return err
+					}
+				} else {
+					declaredNumbers[number] = declaration
+				}
+			}
+			if !first {
+				continue
+			}
+			if err := r.validateExtensionDeclarationName(decl, declaration, declaredNames, handler); err != nil {
+				return err
+			}
+			if err := r.validateExtensionDeclarationType(decl, declaration, handler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *result) validateExtensionDeclarationName(decl *descriptorpb.ExtensionRangeOptions_Declaration, declaration extensionDeclaration, declaredNames *declaredExtensionNames, handler *reporter.Handler) error {
+	switch {
+	case decl.FullName == nil:
+		if !decl.GetReserved() {
+			return handler.HandleErrorf(declaration.span(), "extension declaration that is not marked reserved must have a full_name")
+		}
+		return nil
+	case decl.GetReserved() && decl.Type == nil:
+		// Reserved declarations must have both a name and type or neither.
+		return handler.HandleErrorf(declaration.span(descpath.ExtensionDeclarationFullNameTag), "extension declaration is marked reserved so full_name should not be present")
+	}
+	name := decl.GetFullName()
+	span := declaration.span(descpath.ExtensionDeclarationFullNameTag)
+	if !strings.HasPrefix(name, ".") {
+		return handler.HandleErrorf(span, "extension declaration full name %q should start with a leading dot (.)", name)
+	}
+	if !protoreflect.FullName(name[1:]).IsValid() {
+		return handler.HandleErrorf(span, "extension declaration full name %q is not a valid qualified name", name)
+	}
+	if prev, ok := declaredNames.add(name, declaration); ok {
+		return handler.HandleErrorf(span, "extension %s already declared as extending %s with tag %d at %v",
+			name[1:], prev.extendee.FullName(), prev.number, prev.span(descpath.ExtensionDeclarationFullNameTag).Start())
+	}
+	return nil
+}
+
+func (r *result) validateExtensionDeclarationType(decl *descriptorpb.ExtensionRangeOptions_Declaration, declaration extensionDeclaration, handler *reporter.Handler) error {
+	switch {
+	case decl.Type == nil:
+		if !decl.GetReserved() {
+			return handler.HandleErrorf(declaration.span(), "extension declaration that is not marked reserved must have a type")
+		}
+		return nil
+	case decl.GetReserved() && decl.FullName == nil:
+		return handler.HandleErrorf(declaration.span(descpath.ExtensionDeclarationTypeTag), "extension declaration is marked reserved so type should not be present")
+	}
+	typeName := decl.GetType()
+	span := declaration.span(descpath.ExtensionDeclarationTypeTag)
+	if !strings.HasPrefix(typeName, ".") {
+		if _, ok := scalarTypeNames[typeName]; !ok {
+			return handler.HandleErrorf(span, "extension declaration type %q must be a builtin type or start with a leading dot (.)", typeName)
+		}
+		return nil
+	}
+	if !protoreflect.FullName(typeName[1:]).IsValid() {
+		return handler.HandleErrorf(span, "extension declaration type %q is not a valid qualified name", typeName)
+	}
+	return nil
+}
+
+// scalarTypeNames are the names of the scalar types that may be used as the
+// type in an extension declaration.
+var scalarTypeNames = map[string]struct{}{
+	"double": {}, "float": {}, "int32": {}, "int64": {}, "uint32": {}, "uint64": {},
+	"sint32": {}, "sint64": {}, "fixed32": {}, "fixed64": {}, "sfixed32": {}, "sfixed64": {},
+	"bool": {}, "string": {}, "bytes": {},
+}
+
+// verifyExtensionDeclaration checks the given extension against the
+// declarations in the extendee's extension range that contains it, if that
+// range is verified. Ranges are verified if their verification state is
+// DECLARATION, which is implied when they have any declarations.
+func (r *result) verifyExtensionDeclaration(fd *fldDescriptor, handler *reporter.Handler) error {
+	extendee := fd.ContainingMessage()
+	ranges := extendee.ExtensionRanges()
+	rangeIndex := -1
+	for i, l := 0, ranges.Len(); i < l; i++ {
+		if rng := ranges.Get(i); fd.Number() >= rng[0] && fd.Number() < rng[1] {
+			rangeIndex = i
+			break
+		}
+	}
+	if rangeIndex < 0 {
+		return nil
+	}
+	opts, _ := extendee.ExtensionRangeOptions(rangeIndex).(*descriptorpb.ExtensionRangeOptions)
+	if opts.GetVerification() != descriptorpb.ExtensionRangeOptions_DECLARATION &&
+		(len(opts.GetDeclaration()) == 0 || opts.Verification != nil) {
+		return nil
+	}
+
+	file := r.FileNode()
+	node := r.FieldNode(fd.proto)
+	var decl *descriptorpb.ExtensionRangeOptions_Declaration
+	declaration := extensionDeclaration{extendee: extendee, rangeIndex: rangeIndex, number: int32(fd.Number())}
+	for i, d := range opts.GetDeclaration() {
+		if d.GetNumber() == int32(fd.Number()) {
+			decl, declaration.index = d, i
+			break
+		}
+	}
+	if decl == nil {
+		return handler.HandleErrorf(file.NodeInfo(node.GetTag()), "expected extension with number %d to be declared in type %s, but no declaration found at %v",
+			fd.Number(), extendee.FullName(), extensionRangeOptionSpan(extendee, rangeIndex, descpath.ExtensionRangeOptionsVerificationTag).Start())
+	}
+	if decl.GetReserved() {
+		return handler.HandleErrorf(file.NodeInfo(node.GetTag()), "cannot use field number %d for an extension because it is reserved in declaration at %v",
+			fd.Number(), declaration.span(descpath.ExtensionDeclarationReservedTag).Start())
+	}
+	if decl.GetFullName() != "."+string(fd.FullName()) {
+		if err := handler.HandleErrorf(file.NodeInfo(node.GetName()), "expected extension with number %d to be named %s, not %s, per declaration at %v",
+			fd.Number(), decl.GetFullName(), fd.FullName(), declaration.span(descpath.ExtensionDeclarationFullNameTag).Start()); err != nil {
+			return err
+		}
+	}
+	if typeName := extensionTypeName(fd); decl.GetType() != typeName {
+		if err := handler.HandleErrorf(file.NodeInfo(node.GetFieldTypeNode()), "expected extension with number %d to have type %s, not %s, per declaration at %v",
+			fd.Number(), decl.GetType(), typeName, declaration.span(descpath.ExtensionDeclarationTypeTag).Start()); err != nil {
+			return err
+		}
+	}
+	if isRepeated := fd.Cardinality() == protoreflect.Repeated; decl.GetRepeated() != isRepeated {
+		expected, actual := "repeated", "optional"
+		if isRepeated {
+			expected, actual = actual, expected
+		}
+		var labelNode ast.Node = node
+		if label := node.GetLabel(); label != nil {
+			labelNode = label
+		}
+		if err := handler.HandleErrorf(file.NodeInfo(labelNode), "expected extension with number %d to be %s, not %s, per declaration at %v",
+			fd.Number(), expected, actual, declaration.span(descpath.ExtensionDeclarationRepeatedTag).Start()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extensionTypeName returns the type of the given extension as it would be
+// written in an extension declaration.
+func extensionTypeName(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "." + string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return "." + string(fd.Enum().FullName())
+	default:
+		return fd.Kind().String()
+	}
+}
+
+// extensionRangeSiblings returns all extension ranges of the given message
+// that are declared in the same extensions statement as the given range,
+// which includes the given range itself.
+func (r *result) extensionRangeSiblings(md *descriptorpb.DescriptorProto, er *descriptorpb.DescriptorProto_ExtensionRange) []*descriptorpb.DescriptorProto_ExtensionRange {
+	stmt := r.extensionRangeStatement(md, r.ExtensionRangeNode(er))
+	if stmt == nil {
+		return []*descriptorpb.DescriptorProto_ExtensionRange{er}
+	}
+	var siblings []*descriptorpb.DescriptorProto_ExtensionRange
+	for _, rng := range stmt.FilterRanges() {
+		if sibling := r.ExtensionRangeDescriptor(rng); sibling != nil {
+			siblings = append(siblings, sibling)
+		}
+	}
+	if len(siblings) == 0 {
+		return []*descriptorpb.DescriptorProto_ExtensionRange{er}
+	}
+	return siblings
+}
+
+// extensionRangeStatement returns the extensions statement in the given
+// message that contains the given range, or nil if there is none.
+func (r *result) extensionRangeStatement(md *descriptorpb.DescriptorProto, rng *ast.RangeNode) *ast.ExtensionRangeNode {
+	if rng == nil {
+		return nil
+	}
+	node := r.MessageNode(md)
+	decls := node.GetMessage().GetDecls()
+	if group := node.GetGroup(); group != nil {
+		decls = group.GetDecls()
+	}
+	for _, decl := range decls {
+		stmt := decl.GetExtensionRange()
+		if stmt == nil {
+			continue
+		}
+		for _, candidate := range stmt.FilterRanges() {
+			if candidate == rng {
+				return stmt
+			}
+		}
+	}
+	return nil
+}
+
+// extensionRangeOptionSpan returns the span of the element at the given path
+// in the options of the extension range at the given index of md. Like a
+// source code info path, the path is a sequence of field numbers, each
+// followed by an index if the field is repeated, starting from the
+// ExtensionRangeOptions message. If there is no element at the path, the
+// span of its closest enclosing element is returned instead, or else the
+// span of the extension range.
+func extensionRangeOptionSpan(md protoreflect.MessageDescriptor, rangeIndex int, path ...int32) ast.SourceSpan {
+	fd := md.ParentFile()
+	if fd == nil {
+		return ast.UnknownSpan(unknownFilePath)
+	}
+	if f, ok := fd.(*file); ok {
+		// unwrap any file instance
+		fd = f.FileDescriptor
+	}
+	if res, ok := fd.(*result); ok && res.hasSource() {
+		if md, ok := md.(*msgDescriptor); ok {
+			if node := res.extensionRangeOptionNode(md.proto, rangeIndex, path); node != nil {
+				return res.FileNode().NodeInfo(node)
+			}
+		}
+	}
+
+	msgPath, ok := descpath.ForDescriptor(md)
+	if !ok {
+		return ast.UnknownSpan(fd.Path())
+	}
+	rangePath := append(protoreflect.SourcePath{}, msgPath...)
+	rangePath = append(rangePath, descpath.MessageExtensionRangesTag, int32(rangeIndex))
+	for i := len(path); i >= 0; i-- {
+		elemPath := rangePath
+		if i > 0 {
+			elemPath = append(append(rangePath[:len(rangePath):len(rangePath)], descpath.ExtensionRangeOptionsTag), path[:i]...)
+		}
+		loc := fd.SourceLocations().ByPath(elemPath)
+		if protointernal.IsZeroSourceLocation(loc) {
+			continue
+		}
+		return ast.NewSourceSpan(
+			ast.SourcePos{Filename: fd.Path(), Line: loc.StartLine + 1, Col: loc.StartColumn + 1},
+			ast.SourcePos{Filename: fd.Path(), Line: loc.EndLine + 1, Col: loc.EndColumn + 1},
+		)
+	}
+	return ast.UnknownSpan(fd.Path())
+}
+
+// extensionRangeOptionNode returns the node for the element at the given
+// path in the options of the extension range at the given index of md. See
+// extensionRangeOptionSpan.
+func (r *result) extensionRangeOptionNode(md *descriptorpb.DescriptorProto, rangeIndex int, path []int32) ast.Node {
+	if rangeIndex >= len(md.GetExtensionRange()) {
+		return nil
+	}
+	rng := r.ExtensionRangeNode(md.GetExtensionRange()[rangeIndex])
+	stmt := r.extensionRangeStatement(md, rng)
+	if stmt == nil {
+		if rng == nil {
+			return nil
+		}
+		return rng
+	}
+	var values []namedValueNode
+	for _, opt := range stmt.GetOptions().GetOptions() {
+		parts := opt.GetName().GetParts()
+		if len(parts) != 1 {
+			continue
+		}
+		if ref := parts[0].GetFieldRef(); ref != nil && !ref.IsExtension() && ref.Name != nil {
+			values = append(values, namedValueNode{name: string(ref.Name.AsIdentifier()), node: opt, val: opt.GetVal()})
+		}
+	}
+	optsDesc := (*descriptorpb.ExtensionRangeOptions)(nil).ProtoReflect().Descriptor()
+	if node := findValueNode(values, optsDesc, path); node != nil {
+		return node
+	}
+	return stmt
+}
+
+// namedValueNode is an option or a field in a message literal.
+type namedValueNode struct {
+	name string
+	node ast.Node
+	val  *ast.ValueNode
+}
+
+// findValueNode returns the node for the element at the given path among
+// the given values, which are fields of a message of type md. If there is
+// no element at the path, the node for its closest enclosing element is
+// returned, or nil if there is none.
+func findValueNode(values []namedValueNode, md protoreflect.MessageDescriptor, path []int32) ast.Node {
+	if len(path) == 0 {
+		return nil
+	}
+	fld := md.Fields().ByNumber(protoreflect.FieldNumber(path[0]))
+	if fld == nil {
+		return nil
+	}
+	var elems []namedValueNode
+	for _, v := range values {
+		if v.name != string(fld.Name()) {
+			continue
+		}
+		if arr := v.val.GetArrayLiteral(); arr != nil && fld.IsList() {
+			for _, elem := range arr.GetElements() {
+				elems = append(elems, namedValueNode{name: v.name, node: elem.GetValue(), val: elem.GetValue()})
+			}
+			continue
+		}
+		elems = append(elems, v)
+	}
+	var target namedValueNode
+	rest := path[1:]
+	if fld.IsList() {
+		if len(rest) == 0 || int(rest[0]) >= len(elems) {
+			return nil
+		}
+		target, rest = elems[rest[0]], rest[1:]
+	} else {
+		if len(elems) == 0 {
+			return nil
+		}
+		target = elems[len(elems)-1]
+	}
+	lit := target.val.GetMessageLiteral()
+	if len(rest) == 0 || lit == nil || fld.Message() == nil {
+		return target.node
+	}
+	var fields []namedValueNode
+	for _, field := range lit.GetElements() {
+		if ref := field.GetName(); ref != nil && !ref.IsExtension() && ref.Name != nil {
+			fields = append(fields, namedValueNode{name: string(ref.Name.AsIdentifier()), node: field, val: field.GetVal()})
+		}
+	}
+	if node := findValueNode(fields, fld.Message(), rest); node != nil {
+		return node
+	}
+	return target.node
+}
@@ -0,0 +1,286 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// TypeReferenceKind indicates where in a file a TypeReference appears.
+type TypeReferenceKind int
+
+const (
+	// TypeReferenceFieldType is the message or enum type of a field or
+	// extension.
+	TypeReferenceFieldType TypeReferenceKind = iota + 1
+	// TypeReferenceExtendee is the message named in an extend block.
+	TypeReferenceExtendee
+	// TypeReferenceRequestType is the request type of an RPC.
+	TypeReferenceRequestType
+	// TypeReferenceResponseType is the response type of an RPC.
+	TypeReferenceResponseType
+)
+
+func (k TypeReferenceKind) String() string {
+	switch k {
+	case TypeReferenceFieldType:
+		return "field type"
+	case TypeReferenceExtendee:
+		return "extendee"
+	case TypeReferenceRequestType:
+		return "request type"
+	case TypeReferenceResponseType:
+		return "response type"
+	default:
+		return "unknown"
+	}
+}
+
+// TypeReference is a reference in a file to a message or enum type, along
+// with the import that made the type visible to the file. See
+// ImportResolutions.
+type TypeReference struct {
+	Kind TypeReferenceKind
+	// The field, extension, or method that contains the reference.
+	Element protoreflect.Descriptor
+	// The message or enum that the reference resolved to.
+	Target protoreflect.Descriptor
+	// The node for the type name in the source. This is nil if the file
+	// has no AST.
+	Node ast.Node
+	// The index, in the file's imports, of the direct import that satisfied
+	// the reference. This is -1 if the target is declared in the file itself.
+	ImportIndex int
+	// The path of the direct import that satisfied the reference, or empty
+	// if the target is declared in the file itself.
+	Import string
+	// True if the target is not declared in the imported file itself, but is
+	// only visible because that file (transitively) publicly imports the
+	// file in which the target is declared.
+	ViaPublicImport bool
+	// When ViaPublicImport is true, the chain of public imports that leads
+	// from the direct import to the file that declares the target, ending
+	// with that file.
+	PublicImportChain []string
+}
+
+// ImportResolutions returns every reference in the given file to a message
+// or enum type, including field types, extendees, and RPC request and
+// response types, mapped to the import that satisfied it. References are
+// returned in the order in which their elements are declared. References
+// to synthetic map entry messages are omitted, but the key and value types
+// of map fields are included.
+//
+// References that are satisfied only through public imports are flagged
+// with ViaPublicImport, which is useful for enforcing that files directly
+// import everything they use.
+func ImportResolutions(res Result) []TypeReference {
+	var refs []TypeReference
+	add := func(kind TypeReferenceKind, elem, target protoreflect.Descriptor, node ast.Node) {
+		if target == nil || target.IsPlaceholder() {
+			return
+		}
+		ref := TypeReference{
+			Kind:        kind,
+			Element:     elem,
+			Target:      target,
+			Node:        node,
+			ImportIndex: -1,
+		}
+		resolveImportFor(res, target.ParentFile(), &ref)
+		refs = append(refs, ref)
+	}
+	addField := func(fld protoreflect.FieldDescriptor, node *ast.FieldDeclNode, extendee *ast.ExtendNode) {
+		if fld.IsExtension() {
+			add(TypeReferenceExtendee, fld, fld.ContainingMessage(), astNodeOrNil(extendee.GetExtendee()))
+		}
+		var target protoreflect.Descriptor
+		switch {
+		case fld.Message() != nil:
+			if fld.Message().IsMapEntry() {
+				return
+			}
+			target = fld.Message()
+		case fld.Enum() != nil:
+			target = fld.Enum()
+		default:
+			return
+		}
+		var typeNode ast.Node
+		if node != nil {
+			typeNode = node.GetFieldTypeNode()
+		}
+		add(TypeReferenceFieldType, fld, target, typeNode)
+	}
+	_ = walk.Descriptors(res, func(d protoreflect.Descriptor) error {
+		switch d := d.(type) {
+		case *fldDescriptor:
+			addField(d, res.FieldNode(d.proto), nil)
+		case *extTypeDescriptor:
+			addField(d, res.FieldNode(d.field.proto), res.FieldExtendeeNode(d.field.proto))
+		case *mtdDescriptor:
+			node := res.MethodNode(d.proto)
+			add(TypeReferenceRequestType, d, d.Input(), astNodeOrNil(node.GetInput().GetMessageType()))
+			add(TypeReferenceResponseType, d, d.Output(), astNodeOrNil(node.GetOutput().GetMessageType()))
+		}
+		return nil
+	})
+	return refs
+}
+
+// CheckDirectDependencies reports every reference in the given file to a
+// message or enum type that is declared in a file that the given file does
+// not import directly, but that is only visible through public imports.
+// Many repositories enforce such layering rules so that a file's imports
+// list everything it depends on. Findings are reported at the type name,
+// with an error that implements ErrorIndirectDependency. If any error is
+// reported and the handler does not suppress it, this function returns a
+// non-nil error.
+func CheckDirectDependencies(res Result, handler *reporter.Handler, level CheckLevel) error {
+	if level == CheckLevelOff {
+		return nil
+	}
+	file := res.FileNode()
+	for _, ref := range ImportResolutions(res) {
+		if !ref.ViaPublicImport {
+			continue
+		}
+		err := &errIndirectDependency{ref: ref}
+		if res.AST() != nil {
+			// the insertion point is the position of the last character of
+			// the preceding statement, so the import goes just after it
+			fix := transform.Insert(file, res.ImportInsertionPoint().Offset+1, fmt.Sprintf("\nimport %q;", err.MissingImport()))
+			err.fix = &fix
+		}
+		var span ast.SourceSpan = ast.UnknownSpan(res.Path())
+		if ref.Node != nil {
+			span = file.NodeInfo(ref.Node)
+		}
+		if level == CheckLevelWarn {
+			handler.HandleWarningWithPos(span, err)
+		} else if err := handler.HandleErrorWithPos(span, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrorIndirectDependency may be passed to a reporter by
+// CheckDirectDependencies when a file references a type that is declared in
+// a file it does not directly import. The error the reporter receives will
+// be wrapped with the source position of the reference.
+type ErrorIndirectDependency interface {
+	error
+	// Reference returns the offending reference.
+	Reference() TypeReference
+	// MissingImport returns the path of the file that declares the
+	// referenced type, which should be imported directly.
+	MissingImport() string
+	// Fix returns an edit that adds an import of MissingImport after the
+	// file's existing imports. It returns false if the file has no AST.
+	Fix() (transform.TextEdit, bool)
+}
+
+type errIndirectDependency struct {
+	ref TypeReference
+	fix *transform.TextEdit
+}
+
+func (e *errIndirectDependency) Error() string {
+	return fmt.Sprintf("%s %s: %s is declared in %q, which is not imported directly (visible only through public import of %q)",
+		e.ref.Kind, e.ref.Element.FullName(), e.ref.Target.FullName(), e.MissingImport(), e.ref.Import)
+}
+
+func (e *errIndirectDependency) Reference() TypeReference {
+	return e.ref
+}
+
+func (e *errIndirectDependency) MissingImport() string {
+	return e.ref.Target.ParentFile().Path()
+}
+
+func (e *errIndirectDependency) Fix() (transform.TextEdit, bool) {
+	if e.fix == nil {
+		return transform.TextEdit{}, false
+	}
+	return *e.fix, true
+}
+
+// resolveImportFor populates the import-related fields of ref with the
+// direct import of f that declares target or, if there is none, with the
+// first direct import through whose public imports target is visible.
+func resolveImportFor(f File, target protoreflect.FileDescriptor, ref *TypeReference) {
+	if target == nil || target.Path() == f.Path() {
+		return
+	}
+	imports := f.Imports()
+	for i, l := 0, imports.Len(); i < l; i++ {
+		if imp := imports.Get(i); !imp.IsPlaceholder() && imp.Path() == target.Path() {
+			ref.ImportIndex, ref.Import = i, imp.Path()
+			return
+		}
+	}
+	for i, l := 0, imports.Len(); i < l; i++ {
+		imp := imports.Get(i)
+		if imp.IsPlaceholder() {
+			continue
+		}
+		if chain := publicImportChain(imp.FileDescriptor, target.Path(), map[string]struct{}{}); chain != nil {
+			ref.ImportIndex, ref.Import = i, imp.Path()
+			ref.ViaPublicImport = true
+			ref.PublicImportChain = chain
+			return
+		}
+	}
+}
+
+// publicImportChain returns the paths of the public imports that lead from
+// f to the file with the given path, or nil if there is no such chain.
+func publicImportChain(f protoreflect.FileDescriptor, path string, seen map[string]struct{}) []string {
+	if _, ok := seen[f.Path()]; ok {
+		return nil
+	}
+	seen[f.Path()] = struct{}{}
+	imports := f.Imports()
+	for i, l := 0, imports.Len(); i < l; i++ {
+		imp := imports.Get(i)
+		if !imp.IsPublic || imp.IsPlaceholder() {
+			continue
+		}
+		if imp.Path() == path {
+			return []string{path}
+		}
+		if chain := publicImportChain(imp.FileDescriptor, path, seen); chain != nil {
+			return append([]string{imp.Path()}, chain...)
+		}
+	}
+	return nil
+}
+
+// astNodeOrNil returns node as an ast.Node, or a nil interface if node is
+// a nil pointer.
+func astNodeOrNil(node *ast.IdentValueNode) ast.Node {
+	if node == nil {
+		return nil
+	}
+	return node
+}
@@ -0,0 +1,150 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestImportResolutions(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto3";
+			package foo;
+			import "b.proto";
+			import "google/protobuf/descriptor.proto";
+			message Local {}
+			message Msg {
+				Local local = 1;
+				B b = 2;
+				C c = 3;
+				map<string, C> cs = 4;
+			}
+			extend google.protobuf.FieldOptions { Local opt = 50000; }
+			service Svc {
+				rpc Do(B) returns (C);
+			}`,
+		"b.proto": `syntax = "proto3";
+			package foo;
+			import public "c.proto";
+			message B {}`,
+		"c.proto": `syntax = "proto3";
+			package foo;
+			message C {}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		RetainASTs: true,
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	res := files.Files[0].(linker.Result)
+
+	var actual []string
+	for _, ref := range linker.ImportResolutions(res) {
+		require.NotNil(t, ref.Node, "%s of %s", ref.Kind, ref.Element.FullName())
+		actual = append(actual, fmt.Sprintf("%s %s -> %s via %q (%d) public=%v %v",
+			ref.Element.FullName(), ref.Kind, ref.Target.FullName(), ref.Import, ref.ImportIndex, ref.ViaPublicImport, ref.PublicImportChain))
+	}
+	assert.Equal(t, []string{
+		`foo.Msg.local field type -> foo.Local via "" (-1) public=false []`,
+		`foo.Msg.b field type -> foo.B via "b.proto" (0) public=false []`,
+		`foo.Msg.c field type -> foo.C via "b.proto" (0) public=true [c.proto]`,
+		`foo.Msg.CsEntry.value field type -> foo.C via "b.proto" (0) public=true [c.proto]`,
+		`foo.opt extendee -> google.protobuf.FieldOptions via "google/protobuf/descriptor.proto" (1) public=false []`,
+		`foo.opt field type -> foo.Local via "" (-1) public=false []`,
+		`foo.Svc.Do request type -> foo.B via "b.proto" (0) public=false []`,
+		`foo.Svc.Do response type -> foo.C via "b.proto" (0) public=true [c.proto]`,
+	}, actual)
+
+	res.RemoveAST()
+	refs := linker.ImportResolutions(res)
+	require.Len(t, refs, len(actual))
+	for _, ref := range refs {
+		assert.Nil(t, ref.Node)
+	}
+}
+
+func TestCheckDirectDependencies(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto3";
+package foo;
+import "b.proto";
+message Msg {
+  B b = 1;
+  C c = 2;
+}`,
+		"b.proto": `syntax = "proto3";
+package foo;
+import public "c.proto";
+message B { C c = 1; }`,
+		"c.proto": `syntax = "proto3";
+package foo;
+message C {}`,
+	}
+	var errs []reporter.ErrorWithPos
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err)
+				return nil
+			},
+			nil,
+		),
+		DirectDependencyCheck: linker.CheckLevelError,
+	}
+	_, err := compiler.Compile(context.Background(), "a.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	require.Len(t, errs, 1)
+	assert.Equal(t, `a.proto:6:3-4: field type foo.Msg.c: foo.C is declared in "c.proto", which is not imported directly (visible only through public import of "b.proto")`, errs[0].Error())
+	var indirect linker.ErrorIndirectDependency
+	require.ErrorAs(t, errs[0], &indirect)
+	assert.Equal(t, "c.proto", indirect.MissingImport())
+	assert.Equal(t, "b.proto", indirect.Reference().Import)
+	fix, ok := indirect.Fix()
+	require.True(t, ok)
+
+	// applying the fix resolves the error
+	sources["a.proto"] = string(transform.ApplyEdits([]byte(sources["a.proto"]), []transform.TextEdit{fix}))
+	assert.Contains(t, sources["a.proto"], "import \"b.proto\";\nimport \"c.proto\";\n")
+	errs = nil
+	_, err = compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	// No check is performed by default.
+	sources["a.proto"] = strings.Replace(sources["a.proto"], "import \"c.proto\";\n", "", 1)
+	compiler.DirectDependencyCheck = linker.CheckLevelOff
+	_, err = compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
@@ -0,0 +1,99 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// benchmarkCorpus generates a set of files that all share a package and
+// declare deeply nested messages and enums, which is roughly the shape of
+// large API repositories (lots of elements with long common prefixes).
+func benchmarkCorpus(numFiles int) map[string]string {
+	files := make(map[string]string, numFiles)
+	for i := 0; i < numFiles; i++ {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "syntax = \"proto3\";\npackage acme.platform.services.v1;\n")
+		for m := 0; m < 10; m++ {
+			fmt.Fprintf(&sb, "message File%dMessage%d {\n", i, m)
+			for f := 0; f < 10; f++ {
+				fmt.Fprintf(&sb, "  string field_%d = %d;\n", f, f+1)
+			}
+			sb.WriteString("  message Nested {\n")
+			for f := 0; f < 10; f++ {
+				fmt.Fprintf(&sb, "    int64 nested_field_%d = %d;\n", f, f+1)
+			}
+			sb.WriteString("  }\n  enum Kind {\n")
+			for v := 0; v < 10; v++ {
+				fmt.Fprintf(&sb, "    FILE%d_MESSAGE%d_KIND_%d = %d;\n", i, m, v, v)
+			}
+			sb.WriteString("  }\n}\n")
+		}
+		files[fmt.Sprintf("file%d.proto", i)] = sb.String()
+	}
+	return files
+}
+
+func parseBenchmarkCorpus(b *testing.B, files map[string]string) []parser.Result {
+	results := make([]parser.Result, 0, len(files))
+	for name, contents := range files {
+		h := reporter.NewHandler(nil)
+		fileNode, err := parser.Parse(name, strings.NewReader(contents), h, 0)
+		require.NoError(b, err)
+		res, err := parser.ResultFromAST(fileNode, true, h)
+		require.NoError(b, err)
+		results = append(results, res)
+	}
+	return results
+}
+
+// BenchmarkLinkNames links the same corpus repeatedly into one symbol table,
+// which is what happens when a long-lived compiler re-links edited files.
+// The "plain" variant disables interning to serve as the baseline.
+func BenchmarkLinkNames(b *testing.B) {
+	corpus := benchmarkCorpus(20)
+	for _, interned := range []bool{false, true} {
+		name := "plain"
+		if interned {
+			name = "interned"
+		}
+		b.Run(name, func(b *testing.B) {
+			symbols := NewSymbolTable()
+			if !interned {
+				symbols.names = nil
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				parsed := parseBenchmarkCorpus(b, corpus)
+				b.StartTimer()
+				for _, res := range parsed {
+					linked, err := Link(res, nil, symbols, reporter.NewHandler(nil))
+					require.NoError(b, err)
+					b.StopTimer()
+					require.NoError(b, symbols.Delete(linked, reporter.NewHandler(nil)))
+					b.StartTimer()
+				}
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -25,10 +26,49 @@ import (
 	art "github.com/kralicky/go-adaptive-radix-tree"
 	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/reporter"
 	"github.com/kralicky/protocompile/sourceinfo"
 )
 
+// LinkOption is an option that can be passed to Link.
+type LinkOption func(*linkOptions)
+
+type linkOptions struct {
+	pool                    *DescriptorPool
+	allowMissingWeakImports bool
+	astLoader               ASTLoader
+}
+
+// WithDescriptorPool returns an option that causes Link to allocate the
+// result's descriptors and indexes from the given pool, reusing memory from
+// previously released results where possible. See DescriptorPool for the
+// contract that callers must follow.
+func WithDescriptorPool(pool *DescriptorPool) LinkOption {
+	return func(lo *linkOptions) {
+		lo.pool = pool
+	}
+}
+
+// WithAllowMissingWeakImports returns an option that causes Link to accept
+// placeholder files for weak imports without reporting an error. This is
+// intended for callers that have already reported the missing dependency
+// in some other way, such as with a warning.
+func WithAllowMissingWeakImports() LinkOption {
+	return func(lo *linkOptions) {
+		lo.allowMissingWeakImports = true
+	}
+}
+
+// WithASTLoader returns an option that records the given function in the
+// result, so that its AST can be loaded again with Result.ReloadAST after it
+// has been removed with Result.RemoveAST.
+func WithASTLoader(loader ASTLoader) LinkOption {
+	return func(lo *linkOptions) {
+		lo.astLoader = loader
+	}
+}
+
 // Link handles linking a parsed descriptor proto into a fully-linked descriptor.
 // If the given parser.Result has imports, they must all be present in the given
 // dependencies, in the exact order they are present in the parsed descriptor.
@@ -44,7 +84,11 @@ import (
 //
 // Note that linking does NOT interpret options. So options messages in the
 // returned value have all values stored in UninterpretedOptions fields.
-func Link(parsed parser.Result, dependencies Files, symbols *Symbols, handler *reporter.Handler) (Result, error) {
+func Link(parsed parser.Result, dependencies Files, symbols *Symbols, handler *reporter.Handler, opts ...LinkOption) (Result, error) {
+	var lo linkOptions
+	for _, opt := range opts {
+		opt(&lo)
+	}
 	if symbols == nil {
 		symbols = NewSymbolTable()
 	}
@@ -77,7 +121,9 @@ dependencies_ok:
 		dep := filteredDependencies[i]
 		fd.Dependency[i] = dep.Path()
 
-		if dep.IsPlaceholder() {
+		if dep.IsPlaceholder() && lo.allowMissingWeakImports && slices.Contains(fd.WeakDependency, int32(i)) {
+			continue
+		} else if dep.IsPlaceholder() {
 			// handle unresolvable import paths
 			// first, find the import node for this path
 			var importNode *ast.ImportNode
@@ -110,18 +156,28 @@ dependencies_ok:
 	}
 
 	r := &result{
-		FileDescriptor:       noOpFile,
-		Result:               parsed,
-		deps:                 dependencies, // the unfiltered dependencies
-		descriptors:          art.New[protoreflect.Descriptor](),
-		usedImports:          map[string]struct{}{},
-		prefix:               prefix,
-		optionQualifiedNames: map[*ast.IdentValueNode]string{},
-		resolvedReferences:   map[protoreflect.Descriptor][]ast.NodeReference{},
-		extensionsByMessage:  map[protoreflect.FullName][]protoreflect.ExtensionDescriptor{},
+		FileDescriptor: noOpFile,
+		Result:         parsed,
+		deps:           dependencies, // the unfiltered dependencies
+		descriptors:    art.New[protoreflect.Descriptor](),
+		usedImports:    map[string]struct{}{},
+		prefix:         prefix,
+		names:          symbols.names,
+		descriptorPool: lo.pool,
+		astLoader:      lo.astLoader,
+	}
+	reuse := lo.pool.get()
+	if reuse != nil && reuse.resolvedReferences != nil {
+		r.optionQualifiedNames = reuse.optionQualifiedNames
+		r.resolvedReferences = reuse.resolvedReferences
+		r.extensionsByMessage = reuse.extensionsByMessage
+	} else {
+		r.optionQualifiedNames = map[*ast.IdentValueNode]string{}
+		r.resolvedReferences = map[protoreflect.Descriptor][]ast.NodeReference{}
+		r.extensionsByMessage = map[protoreflect.FullName][]protoreflect.ExtensionDescriptor{}
 	}
 	// First, we create the hierarchy of descendant descriptors.
-	r.createDescendants()
+	r.createDescendants(reuse)
 
 	// Then we can put all symbols into a single pool, which lets us ensure there
 	// are no duplicate symbols and will also let us resolve and revise all type
@@ -199,11 +255,44 @@ type Result interface {
 	// step separate from linking, because computing source code info requires
 	// interpreting options (which is done after linking).
 	PopulateSourceCodeInfo(sourceinfo.OptionIndex, sourceinfo.OptionDescriptorIndex)
+	// RegenerateSourceInfo computes the source code info for the file again,
+	// from its AST and the index returned by OptionIndex, replacing the source
+	// code info in its descriptor proto and the locations returned by
+	// SourceLocations. This is used after the descriptor, or its options, were
+	// changed in ways that the index was updated to reflect, for example with
+	// sourceinfo.OptionIndex.RebasePaths. Mappings in the option descriptor
+	// index for options that were removed from the option index are dropped,
+	// along with the references found from them. Mappings for options that
+	// were added must be recorded with PopulateOptionDescriptorIndex. It
+	// returns ErrNoAST if the result has no AST.
+	RegenerateSourceInfo(opts ...sourceinfo.GenerateOption) error
+	// PopulateOptionDescriptorIndex records the mappings from option AST nodes
+	// to descriptors that were computed while interpreting options. This is
+	// also done by PopulateSourceCodeInfo, but this step does not require
+	// source code info, so it can be used when source code info is not needed.
+	// It must be called before the AST is removed in order for the mappings
+	// to be used to find references. Calling it again, directly or via
+	// PopulateSourceCodeInfo, replaces the previously recorded mappings and
+	// the references found from them.
+	PopulateOptionDescriptorIndex(sourceinfo.OptionDescriptorIndex)
+	// OptionDescriptorIndex returns the mappings from option AST nodes to
+	// descriptors that were recorded via PopulateOptionDescriptorIndex or
+	// PopulateSourceCodeInfo. The returned index's maps are nil if neither
+	// has been called.
+	OptionDescriptorIndex() sourceinfo.OptionDescriptorIndex
+	// OptionIndex returns the index of interpreted options that was recorded
+	// via PopulateSourceCodeInfo, or nil if it has not been called. The index
+	// may be updated in place before calling RegenerateSourceInfo.
+	OptionIndex() sourceinfo.OptionIndex
 
 	FindDescriptorsByPrefix(ctx context.Context, prefix string, filter ...func(protoreflect.Descriptor) bool) ([]protoreflect.Descriptor, error)
 	RangeDescriptors(ctx context.Context, fn func(protoreflect.Descriptor) bool) error
 
 	FindReferences(to protoreflect.Descriptor) []ast.NodeReference
+	// RangeReferences calls fn for each reference in this file to a resolved
+	// descriptor, until fn returns false. References are visited in no
+	// particular order.
+	RangeReferences(fn func(ref ast.NodeReference, to protoreflect.Descriptor) bool)
 
 	FindOptionSourceInfo(*ast.OptionNode) *sourceinfo.OptionSourceInfo
 	FindOptionNameFieldDescriptor(name *descriptorpb.UninterpretedOption_NamePart) protoreflect.FieldDescriptor
@@ -212,11 +301,37 @@ type Result interface {
 	FindFieldDescriptorByMessageFieldNode(node *ast.MessageFieldNode) protoreflect.FieldDescriptor
 	RangeFieldReferenceNodesWithDescriptors(func(node ast.Node, desc protoreflect.FieldDescriptor) bool)
 	FindMessageDescriptorByTypeReferenceURLNode(node *ast.FieldReferenceNode) protoreflect.MessageDescriptor
+	// FindMessageDescriptorByMessageLiteralNode returns the message that the
+	// given message literal, in an option value, populates. It returns nil if
+	// the literal's type could not be determined.
+	FindMessageDescriptorByMessageLiteralNode(node *ast.MessageLiteralNode) protoreflect.MessageDescriptor
 	FindExtendeeDescriptorByName(fqn protoreflect.FullName) protoreflect.MessageDescriptor
 	FindExtensionsByMessage(fqn protoreflect.FullName) []protoreflect.ExtensionDescriptor
+	// FindFieldDefault returns the default value of the given field, along
+	// with the "default" pseudo-option that specified it. If the field has no
+	// explicit default, or if its options were not interpreted from source,
+	// the returned option is nil and the value is the field's default
+	// as reported by its descriptor.
+	FindFieldDefault(fld protoreflect.FieldDescriptor) sourceinfo.FieldDefault
 
 	// RemoveAST drops the AST information from this result.
 	RemoveAST()
+	// ReloadAST loads the AST for this result again after it was removed,
+	// using the loader given to Link with WithASTLoader, and associates its
+	// nodes with this result's existing descriptors, references, and option
+	// indexes. It does nothing if the result already has an AST. It returns
+	// ErrNoASTLoader if the result has no loader, or an error if the loaded
+	// AST does not match the result's descriptors, for example because the
+	// source has changed since it was linked.
+	ReloadAST(ctx context.Context) error
+	// MemoryUsage estimates the memory retained by this result, by
+	// component, which can guide eviction policies in long-running
+	// processes: for example, dropping the ASTs of the least recently used
+	// files with RemoveAST. The estimate excludes memory that is shared
+	// with other results, such as dependencies and interned names. It is
+	// computed by traversing the result, so it takes time proportional to
+	// the size of the file.
+	MemoryUsage() MemoryUsage
 }
 
 // ErrorUnusedImport may be passed to a warning reporter when an unused
@@ -243,14 +358,19 @@ type ErrorUndeclaredName interface {
 	UndeclaredName() string
 	ParentFile() *ast.FileNode
 	Hint() string
+	// Suggestions returns names of visible elements that are plausibly what
+	// was meant, closest first. Each can replace the undeclared name in
+	// source as is. This is empty if there are no likely candidates.
+	Suggestions() []string
 }
 
 type errUndeclaredName struct {
-	scope      string
-	what       string
-	name       string
-	hint       string
-	parentFile *ast.FileNode
+	scope       string
+	what        string
+	name        string
+	hint        string
+	suggestions []string
+	parentFile  *ast.FileNode
 }
 
 func (e *errUndeclaredName) Error() string {
@@ -258,7 +378,7 @@ func (e *errUndeclaredName) Error() string {
 	if e.hint != "" {
 		hint = " (" + hint + ")"
 	}
-	return fmt.Sprintf("%s: unknown %s %s%s", e.scope, e.what, e.name, hint)
+	return fmt.Sprintf("%s: unknown %s %s%s%s", e.scope, e.what, e.name, hint, protointernal.DidYouMean(e.suggestions))
 }
 
 func (e *errUndeclaredName) UndeclaredName() string {
@@ -273,6 +393,10 @@ func (e *errUndeclaredName) Hint() string {
 	return e.hint
 }
 
+func (e *errUndeclaredName) Suggestions() []string {
+	return e.suggestions
+}
+
 func ComputeReflexiveTransitiveClosure(roots Files) Files {
 	seen := map[File]struct{}{}
 	var results Files
@@ -30,6 +30,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -40,6 +41,7 @@ import (
 	"github.com/kralicky/protocompile/internal/messageset"
 	"github.com/kralicky/protocompile/internal/protoc"
 	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/protointernal/prototest"
 	"github.com/kralicky/protocompile/protoutil"
 	"github.com/kralicky/protocompile/reporter"
@@ -2961,6 +2963,76 @@ func TestLinkerValidation(t *testing.T) {
 			},
 			expectedErr: `test.proto:9:31: expected extension with number 3 to be declared in type foo.A, but no declaration found at test.proto:5:17`,
 		},
+		"success_default_invalid_utf8_not_verified": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					message A {
+						string s = 1 [default = "\xff", features.utf8_validation = NONE];
+						bytes b = 2 [default = "\xff"];
+					}
+				`,
+			},
+		},
+		"failure_default_invalid_utf8": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					message A {
+						string s = 1 [default = "abc\xff"];
+					}
+				`,
+			},
+			expectedErr: `test.proto:4:33: field foo.A.s: default value is not valid UTF-8 (invalid byte at offset 3), but field's utf8_validation feature is VERIFY`,
+		},
+		"success_option_invalid_utf8_not_verified": {
+			input: map[string]string{
+				"test.proto": `
+					syntax = "proto2";
+					package foo;
+					import "google/protobuf/descriptor.proto";
+					extend google.protobuf.FileOptions {
+						optional string s = 10101;
+					}
+					option (s) = "\xff";
+				`,
+			},
+		},
+		"failure_option_invalid_utf8": {
+			input: map[string]string{
+				"test.proto": `
+					syntax = "proto3";
+					package foo;
+					import "google/protobuf/descriptor.proto";
+					extend google.protobuf.FileOptions {
+						string s = 10101;
+					}
+					option (s) = "\xff";
+				`,
+			},
+			expectedErr: `test.proto:7:14: value for string field s is not valid UTF-8 (invalid byte at offset 0), but field's utf8_validation feature is VERIFY`,
+		},
+		"failure_option_invalid_utf8_in_message_literal": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					import "google/protobuf/descriptor.proto";
+					message Msg {
+						string name = 1;
+					}
+					extend google.protobuf.MessageOptions {
+						Msg m = 10101;
+					}
+					message A {
+						option (m) = { name: "a\xc0" };
+					}
+				`,
+			},
+			expectedErr: `test.proto:11:30: value for string field name is not valid UTF-8 (invalid byte at offset 1), but field's utf8_validation feature is VERIFY`,
+		},
 		"success_field_presence": {
 			input: map[string]string{
 				"test.proto": `
@@ -3256,6 +3328,65 @@ func TestLinkerValidation(t *testing.T) {
 			},
 			expectedErr: `test.proto:7:26: ctype option cannot be CORD for extension fields`,
 		},
+		"success_editions_string_type": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					import "google/protobuf/cpp_features.proto";
+					message A {
+						string s = 1 [features.(pb.cpp).string_type=CORD];
+						bytes b = 2 [features.(pb.cpp).string_type=VIEW];
+						extensions 10 to 100;
+					}
+					extend A {
+						string ext = 10 [features.(pb.cpp).string_type=VIEW];
+					}
+				`,
+			},
+		},
+		"failure_editions_string_type_not_string_or_bytes": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					import "google/protobuf/cpp_features.proto";
+					message A {
+						int32 s = 1 [features.(pb.cpp).string_type=VIEW];
+					}
+				`,
+			},
+			expectedErr: `test.proto:5:9: features.(pb.cpp).string_type can only be used on string and bytes fields`,
+		},
+		"failure_editions_string_type_ext_cannot_be_cord": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					import "google/protobuf/cpp_features.proto";
+					message A {
+						extensions 10 to 100;
+					}
+					extend A {
+						bytes ext = 10 [features.(pb.cpp).string_type=CORD];
+					}
+				`,
+			},
+			expectedErr: `test.proto:8:9: features.(pb.cpp).string_type cannot be CORD for extension fields`,
+		},
+		"failure_editions_string_type_and_ctype": {
+			input: map[string]string{
+				"test.proto": `
+					edition = "2023";
+					package foo;
+					import "google/protobuf/cpp_features.proto";
+					message A {
+						string s = 1 [ctype=CORD, features.(pb.cpp).string_type=CORD];
+					}
+				`,
+			},
+			expectedErr: `test.proto:5:9: field foo.A.s specifies both ctype and features.(pb.cpp).string_type, which is not supported`,
+		},
 		"success_feature_within_lifetime": {
 			input: map[string]string{
 				"feature.proto": `
@@ -4050,6 +4181,124 @@ func TestSyntheticMapEntryUsageNoSource(t *testing.T) {
 	}
 }
 
+func TestPseudoOptionsNoSource(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		syntax      string
+		field       *descriptorpb.FieldDescriptorProto
+		extension   *descriptorpb.FieldDescriptorProto
+		expectedErr string
+	}{
+		"success_default": {
+			field: &descriptorpb.FieldDescriptorProto{
+				Name:         proto.String("bar"),
+				Number:       proto.Int32(1),
+				Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:         descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				DefaultValue: proto.String("123"),
+			},
+		},
+		"success_extension_default_json_name": {
+			extension: &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String("ext_bar"),
+				Number:   proto.Int32(100),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				Extendee: proto.String(".Foo"),
+				JsonName: proto.String("extBar"),
+			},
+		},
+		"failure_default_on_repeated": {
+			field: &descriptorpb.FieldDescriptorProto{
+				Name:         proto.String("bar"),
+				Number:       proto.Int32(1),
+				Label:        descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+				Type:         descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				DefaultValue: proto.String("123"),
+			},
+			expectedErr: "foo.proto: field Foo.bar: default value cannot be set because field is repeated",
+		},
+		"failure_default_on_message": {
+			field: &descriptorpb.FieldDescriptorProto{
+				Name:         proto.String("bar"),
+				Number:       proto.Int32(1),
+				Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:         descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName:     proto.String(".Foo"),
+				DefaultValue: proto.String("abc"),
+			},
+			expectedErr: "foo.proto: field Foo.bar: default value cannot be set because field is a message",
+		},
+		"failure_default_in_proto3": {
+			syntax: "proto3",
+			field: &descriptorpb.FieldDescriptorProto{
+				Name:         proto.String("bar"),
+				Number:       proto.Int32(1),
+				Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:         descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				DefaultValue: proto.String("123"),
+			},
+			expectedErr: "foo.proto: field Foo.bar: default values are not allowed in proto3",
+		},
+		"failure_json_name_on_extension": {
+			extension: &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String("ext_bar"),
+				Number:   proto.Int32(100),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				Extendee: proto.String(".Foo"),
+				JsonName: proto.String("customName"),
+			},
+			expectedErr: "foo.proto: field ext_bar: option json_name is not allowed on extensions",
+		},
+	}
+	for name, tc := range testCases {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fdProto := &descriptorpb.FileDescriptorProto{
+				Name: proto.String("foo.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{
+							{Start: proto.Int32(100), End: proto.Int32(200)},
+						},
+					},
+				},
+			}
+			if tc.syntax != "" {
+				fdProto.Syntax = proto.String(tc.syntax)
+				fdProto.MessageType[0].ExtensionRange = nil
+			}
+			if tc.field != nil {
+				fdProto.MessageType[0].Field = []*descriptorpb.FieldDescriptorProto{tc.field}
+			}
+			if tc.extension != nil {
+				fdProto.Extension = []*descriptorpb.FieldDescriptorProto{tc.extension}
+			}
+			resolver := protocompile.ResolverFunc(func(s protocompile.UnresolvedPath, _ protocompile.ImportContext) (protocompile.SearchResult, error) {
+				if s == "foo.proto" {
+					return protocompile.SearchResult{
+						ResolvedPath: protocompile.ResolvedPath(s),
+						Proto:        fdProto,
+					}, nil
+				}
+				return protocompile.SearchResult{}, protoregistry.NotFound
+			})
+			compiler := &protocompile.Compiler{
+				Resolver: resolver,
+			}
+			_, err := compiler.Compile(context.Background(), "foo.proto")
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestSyntheticOneofCollisions(t *testing.T) {
 	t.Parallel()
 	input := map[string]string{
@@ -4288,6 +4537,126 @@ func TestCustomJSONNameWarnings(t *testing.T) {
 	//  we are focusing on other test cases first before protoc is fixed.
 }
 
+func TestSymbolVisibility(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `edition = "2024";
+package a;
+message Top {
+  message Nested {}
+  export enum Exported {
+    EXPORTED_UNSPECIFIED = 0;
+  }
+}
+local message Hidden {}
+message Uses {
+  Hidden h = 1;
+  Top.Nested n = 2;
+}
+`,
+		"b.proto": `edition = "2024";
+package b;
+import "a.proto";
+message B {
+  a.Top t = 1;
+  a.Top.Exported e = 2;
+  a.Top.Nested n = 3;
+}
+service S {
+  rpc M(a.Hidden) returns (a.Top);
+}
+`,
+		// files before edition 2024 export all of their types
+		"c.proto": `syntax = "proto3";
+package c;
+message C {
+  message Nested {}
+}
+`,
+		"d.proto": `edition = "2024";
+package d;
+import "c.proto";
+message D {
+  c.C.Nested n = 1;
+}
+`,
+	}
+	var errs []error
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		ExperimentalEditions: true,
+		RetainASTs:           true,
+		Reporter: reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+			errs = append(errs, err)
+			return nil
+		}, nil),
+	}
+	_, err := compiler.Compile(context.Background(), "a.proto", "b.proto", "d.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	require.Len(t, errs, 2)
+
+	assert.EqualError(t, errs[0], `b.proto:7:3-15: field b.B.n: a.Top.Nested is local to "a.proto" and cannot be referenced from other files (see details)`)
+	var notVisible reporter.SymbolNotVisibleError
+	require.ErrorAs(t, errs[0], &notVisible)
+	assert.Equal(t, "a.proto:4:11-17", notVisible.Declaration.String())
+
+	assert.EqualError(t, errs[1], `b.proto:10:8-18: method b.S.M: a.Hidden is local to "a.proto" and cannot be referenced from other files (see details)`)
+	require.ErrorAs(t, errs[1], &notVisible)
+	assert.Equal(t, "a.proto:9:15-21", notVisible.Declaration.String())
+}
+
+func TestSymbolVisibilityStrictNoSource(t *testing.T) {
+	t.Parallel()
+	features := &descriptorpb.FeatureSet{}
+	features.ProtoReflect().SetUnknown(protowire.AppendVarint(
+		protowire.AppendTag(nil, protointernal.FeatureSetDefaultSymbolVisibilityTag, protowire.VarintType),
+		uint64(protointernal.DefaultVisibilityStrict),
+	))
+	nestedMsg := &descriptorpb.DescriptorProto{Name: proto.String("Nested")}
+	protointernal.SetVisibility(nestedMsg, protointernal.VisibilityExport)
+	nestedEnum := &descriptorpb.EnumDescriptorProto{
+		Name:  proto.String("Kind"),
+		Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+	}
+	protointernal.SetVisibility(nestedEnum, protointernal.VisibilityExport)
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Syntax:  proto.String("editions"),
+		Edition: descriptorpb.Edition_EDITION_2024.Enum(),
+		Options: &descriptorpb.FileOptions{Features: features},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:       proto.String("Foo"),
+				NestedType: []*descriptorpb.DescriptorProto{nestedMsg},
+			},
+			{
+				// enums may be exported from messages used only as namespaces
+				Name: proto.String("Namespace"),
+				ReservedRange: []*descriptorpb.DescriptorProto_ReservedRange{
+					{Start: proto.Int32(1), End: proto.Int32(protointernal.MaxNormalTag + 1)},
+				},
+				EnumType: []*descriptorpb.EnumDescriptorProto{nestedEnum},
+			},
+		},
+	}
+	resolver := protocompile.WithStandardImports(protocompile.ResolverFunc(func(s protocompile.UnresolvedPath, _ protocompile.ImportContext) (protocompile.SearchResult, error) {
+		if s == "foo.proto" {
+			return protocompile.SearchResult{
+				ResolvedPath: protocompile.ResolvedPath(s),
+				Proto:        fdProto,
+			}, nil
+		}
+		return protocompile.SearchResult{}, protoregistry.NotFound
+	}))
+	compiler := &protocompile.Compiler{
+		Resolver: resolver,
+	}
+	_, err := compiler.Compile(context.Background(), "foo.proto")
+	require.EqualError(t, err, "foo.proto: Foo.Nested: nested types cannot be exported when the default symbol visibility is STRICT")
+}
+
 func testByProtoc(t *testing.T, files map[string]string, fileNames []string) bool {
 	t.Helper()
 	stdout, err := protoc.Compile(files, fileNames)
@@ -0,0 +1,168 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// MessageLiteralFields describes the fields of a message literal in an option
+// value, for completing the names of fields inside the literal.
+type MessageLiteralFields struct {
+	// The innermost message literal that contains the queried position.
+	Literal *ast.MessageLiteralNode
+	// The message that the literal populates.
+	Message protoreflect.MessageDescriptor
+	// The fields that are set in the literal, in the order they appear. Each
+	// field appears once, even if it is set more than once. Elements whose
+	// names could not be resolved, and expanded Any values, are omitted.
+	Set []protoreflect.FieldDescriptor
+	// The fields that can still be set in the literal: repeated fields, and
+	// fields that are not set and that are not in a oneof with a field that
+	// is set. This includes extensions of the message that are visible to the
+	// file. Normal fields are listed first, in declaration order, followed by
+	// extensions sorted by name.
+	Remaining []LiteralField
+}
+
+// LiteralField is a field that can be set in a message literal.
+type LiteralField struct {
+	Field protoreflect.FieldDescriptor
+	// The name that refers to the field in the literal. This is the field's
+	// name, or the group's type name for fields that look like proto2 groups,
+	// or the extension's full name in brackets, like "[foo.bar.baz]".
+	Name string
+}
+
+// MessageLiteralAt returns the fields of the innermost message literal, in an
+// option value in res, that contains the given position. The position must be
+// between the literal's braces. It returns nil if there is no such literal,
+// if its type is not known (for example, because options have not been
+// interpreted), or if res has no AST.
+func MessageLiteralAt(res Result, pos ast.SourcePos) *MessageLiteralFields {
+	file := res.AST()
+	if file == nil {
+		return nil
+	}
+	var lit *ast.MessageLiteralNode
+	ast.Inspect(file, func(node ast.Node) bool {
+		if l, ok := node.(*ast.MessageLiteralNode); ok && literalContains(file, l, pos) {
+			// visited outermost first, so the last match is the innermost
+			lit = l
+		}
+		return true
+	})
+	if lit == nil {
+		return nil
+	}
+	md := res.FindMessageDescriptorByMessageLiteralNode(lit)
+	if md == nil {
+		return nil
+	}
+
+	fields := &MessageLiteralFields{Literal: lit, Message: md}
+	set := map[protoreflect.FullName]struct{}{}
+	setOneofs := map[protoreflect.FullName]struct{}{}
+	for _, elem := range lit.Elements {
+		fld := res.FindFieldDescriptorByMessageFieldNode(elem)
+		if fld == nil {
+			continue
+		}
+		if _, ok := set[fld.FullName()]; ok {
+			continue
+		}
+		set[fld.FullName()] = struct{}{}
+		if ood := fld.ContainingOneof(); ood != nil && !ood.IsSynthetic() {
+			setOneofs[ood.FullName()] = struct{}{}
+		}
+		fields.Set = append(fields.Set, fld)
+	}
+	settable := func(fld protoreflect.FieldDescriptor) bool {
+		if fld.IsList() || fld.IsMap() {
+			return true
+		}
+		if _, ok := set[fld.FullName()]; ok {
+			return false
+		}
+		if ood := fld.ContainingOneof(); ood != nil && !ood.IsSynthetic() {
+			if _, ok := setOneofs[ood.FullName()]; ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	fds := md.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fld := fds.Get(i)
+		if settable(fld) {
+			fields.Remaining = append(fields.Remaining, LiteralField{Field: fld, Name: literalFieldName(fld)})
+		}
+	}
+	var exts []LiteralField
+	protointernal.RangeVisibleFiles(res, func(fd protoreflect.FileDescriptor) {
+		_ = walk.Descriptors(fd, func(d protoreflect.Descriptor) error {
+			if xd, ok := d.(protoreflect.FieldDescriptor); ok && xd.IsExtension() &&
+				xd.ContainingMessage().FullName() == md.FullName() && settable(xd) {
+				exts = append(exts, LiteralField{Field: xd, Name: "[" + string(xd.FullName()) + "]"})
+			}
+			return nil
+		})
+	})
+	sort.Slice(exts, func(i, j int) bool {
+		return exts[i].Name < exts[j].Name
+	})
+	fields.Remaining = append(fields.Remaining, exts...)
+	return fields
+}
+
+// literalContains reports whether pos is between the braces of lit. A literal
+// without a closing brace, which can happen in sources with syntax errors,
+// extends to its end.
+func literalContains(file *ast.FileNode, lit *ast.MessageLiteralNode, pos ast.SourcePos) bool {
+	if lit.Open == nil {
+		return false
+	}
+	start := file.NodeInfo(lit.Open).End()
+	var end ast.SourcePos
+	if lit.Close != nil {
+		end = file.NodeInfo(lit.Close).Start()
+	} else {
+		end = file.NodeInfo(lit).End()
+	}
+	if pos.Line < start.Line || (pos.Line == start.Line && pos.Col < start.Col) {
+		return false
+	}
+	return pos.Line < end.Line || (pos.Line == end.Line && pos.Col <= end.Col)
+}
+
+// literalFieldName returns the name that refers to the given field in a
+// message literal. The text format refers to proto2 groups, and fields that
+// look like them, by the name of the group's type.
+func literalFieldName(fld protoreflect.FieldDescriptor) string {
+	if fld.Kind() == protoreflect.GroupKind &&
+		fld.Message().FullName().Parent() == fld.FullName().Parent() &&
+		protoreflect.Name(strings.ToLower(string(fld.Message().Name()))) == fld.Name() {
+		return string(fld.Message().Name())
+	}
+	return string(fld.Name())
+}
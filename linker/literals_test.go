@@ -0,0 +1,96 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestMessageLiteralAt(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Config {
+  optional string name = 1;
+  repeated int32 ids = 2;
+  oneof kind {
+    string a = 3;
+    string b = 4;
+  }
+  optional group Inner = 5 {
+    optional string val = 1;
+  }
+  extensions 100 to 200;
+}
+extend Config { optional bool enabled = 100; }
+extend google.protobuf.MessageOptions { optional Config config = 50000; }
+message Msg {
+  option (config) = {
+    name: "x"
+    ids: 1
+    a: "y"
+    Inner { }
+  };
+}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		RetainASTs: true,
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	res, ok := files.Files[0].(linker.Result)
+	require.True(t, ok)
+
+	type field struct {
+		name, fullName string
+	}
+	remaining := func(fields *linker.MessageLiteralFields) []field {
+		var actual []field
+		for _, f := range fields.Remaining {
+			actual = append(actual, field{f.Name, string(f.Field.FullName())})
+		}
+		return actual
+	}
+
+	fields := linker.MessageLiteralAt(res, ast.SourcePos{Line: 22, Col: 5})
+	require.NotNil(t, fields)
+	assert.Equal(t, "foo.Config", string(fields.Message.FullName()))
+	assert.Equal(t, []string{"foo.Config.name", "foo.Config.ids", "foo.Config.a", "foo.Config.inner"}, names(fields.Set))
+	assert.Equal(t, []field{
+		{"ids", "foo.Config.ids"},
+		{"[foo.enabled]", "foo.enabled"},
+	}, remaining(fields))
+
+	fields = linker.MessageLiteralAt(res, ast.SourcePos{Line: 23, Col: 13})
+	require.NotNil(t, fields)
+	assert.Equal(t, "foo.Config.Inner", string(fields.Message.FullName()))
+	assert.Empty(t, fields.Set)
+	assert.Equal(t, []field{{"val", "foo.Config.Inner.val"}}, remaining(fields))
+
+	assert.Nil(t, linker.MessageLiteralAt(res, ast.SourcePos{Line: 19, Col: 3}))
+}
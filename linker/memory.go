@@ -0,0 +1,187 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// MemoryUsage is an estimate of the memory, in bytes, that is retained by a
+// result, broken down by component. See Result.MemoryUsage.
+type MemoryUsage struct {
+	// The AST, including the file's source code and comments, and the
+	// mapping of AST nodes to descriptor protos. This is zero after
+	// Result.RemoveAST.
+	AST int64
+	// The descriptor protos, not including source code info, and the linked
+	// descriptors that wrap them.
+	Descriptors int64
+	// The source code info and the index used to look up locations by path.
+	SourceInfo int64
+	// Indexes used for queries, such as the descriptors by name, the
+	// references to each descriptor, and the index of interpreted options.
+	Indexes int64
+}
+
+// Total returns the sum of all components.
+func (u MemoryUsage) Total() int64 {
+	return u.AST + u.Descriptors + u.SourceInfo + u.Indexes
+}
+
+// Add returns the sum of u and other, by component.
+func (u MemoryUsage) Add(other MemoryUsage) MemoryUsage {
+	return MemoryUsage{
+		AST:         u.AST + other.AST,
+		Descriptors: u.Descriptors + other.Descriptors,
+		SourceInfo:  u.SourceInfo + other.SourceInfo,
+		Indexes:     u.Indexes + other.Indexes,
+	}
+}
+
+const (
+	// rough cost of a single entry in a Go map with pointer-sized keys and
+	// values, including the map's amortized bucket overhead
+	mapEntrySize = 48
+	// rough cost of a single entry in the radix tree of descriptors by name
+	artEntrySize = 64
+	wordSize     = 8
+)
+
+func (r *result) MemoryUsage() MemoryUsage {
+	var usage MemoryUsage
+	fd := r.FileDescriptorProto()
+	sourceInfo := fd.GetSourceCodeInfo()
+
+	var numDescriptors int64
+	_ = r.RangeDescriptors(context.Background(), func(d protoreflect.Descriptor) bool {
+		numDescriptors++
+		usage.Descriptors += typeSize(d)
+		return true
+	})
+	usage.Descriptors += typeSize(r) + messageSize(fd.ProtoReflect()) - messageSize(sourceInfo.ProtoReflect())
+
+	if sourceInfo != nil {
+		usage.SourceInfo = messageSize(sourceInfo.ProtoReflect())
+		usage.SourceInfo += int64(len(r.srcLocations.locs))*int64(reflect.TypeOf(protoreflect.SourceLocation{}).Size()) +
+			int64(len(r.srcLocations.index))*mapEntrySize
+	}
+
+	if file := r.AST(); file != nil {
+		usage.AST = messageSize(file.ProtoReflect())
+		// the parser result maps each descriptor proto to its node and back
+		usage.AST += 2 * numDescriptors * mapEntrySize
+	}
+
+	usage.Indexes = numDescriptors * artEntrySize
+	refSize := int64(reflect.TypeOf(ast.NodeReference{}).Size())
+	for _, refs := range r.resolvedReferences {
+		usage.Indexes += mapEntrySize + int64(cap(refs))*refSize
+	}
+	for _, exts := range r.extensionsByMessage {
+		usage.Indexes += mapEntrySize + int64(cap(exts))*2*wordSize
+	}
+	r.usedImportsMu.Lock()
+	numUsedImports := len(r.usedImports)
+	r.usedImportsMu.Unlock()
+	usage.Indexes += int64(len(r.optsIndex)+len(r.optionQualifiedNames)+numUsedImports) * mapEntrySize
+	return usage
+}
+
+// typeSize returns the size of the value that v points to, or of v itself if
+// it is not a pointer.
+func typeSize(v any) int64 {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return int64(t.Size())
+}
+
+// messageSize estimates the memory used by the given message, including all
+// of the messages, strings, and lists that it references. It returns zero for
+// an invalid message.
+func messageSize(msg protoreflect.Message) int64 {
+	if !msg.IsValid() {
+		return 0
+	}
+	size := typeSize(msg.Interface())
+	size += int64(len(msg.GetUnknown()))
+	msg.Range(func(fld protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		switch {
+		case fld.IsList():
+			list := val.List()
+			size += int64(list.Len()) * elementSize(fld)
+			for i := 0; i < list.Len(); i++ {
+				size += valueSize(fld, list.Get(i))
+			}
+		case fld.IsMap():
+			val.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				size += mapEntrySize + valueSize(fld.MapKey(), k.Value()) + valueSize(fld.MapValue(), v)
+				return true
+			})
+		default:
+			if oneof := fld.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+				// the value is boxed in a wrapper struct
+				size += elementSize(fld)
+			} else if fld.HasPresence() && fld.Message() == nil {
+				// scalars with presence are stored as pointers
+				size += elementSize(fld)
+			}
+			size += valueSize(fld, val)
+		}
+		return true
+	})
+	return size
+}
+
+// elementSize returns the size of a single element of the given field when
+// stored in a slice.
+func elementSize(fld protoreflect.FieldDescriptor) int64 {
+	switch fld.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return wordSize
+	case protoreflect.StringKind:
+		return 2 * wordSize
+	case protoreflect.BytesKind:
+		return 3 * wordSize
+	case protoreflect.BoolKind:
+		return 1
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind, protoreflect.DoubleKind:
+		return 8
+	default:
+		return 4
+	}
+}
+
+// valueSize returns the size of the memory referenced by the given value,
+// not including the value itself.
+func valueSize(fld protoreflect.FieldDescriptor, val protoreflect.Value) int64 {
+	switch fld.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSize(val.Message())
+	case protoreflect.StringKind:
+		return int64(len(val.String()))
+	case protoreflect.BytesKind:
+		return int64(len(val.Bytes()))
+	default:
+		return 0
+	}
+}
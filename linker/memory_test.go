@@ -0,0 +1,104 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	t.Parallel()
+	var source strings.Builder
+	source.WriteString("syntax = \"proto3\";\npackage foo;\nimport \"google/protobuf/descriptor.proto\";\n")
+	source.WriteString("extend google.protobuf.FieldOptions { string tag = 50000; }\n")
+	for i := 0; i < 50; i++ {
+		source.WriteString("// A message with a comment.\nmessage M")
+		source.WriteString(strings.Repeat("x", i))
+		source.WriteString(" {\n  string name = 1 [(tag) = \"a\"];\n  repeated int64 values = 2;\n  M child = 3;\n}\n")
+	}
+	compile := func(t *testing.T, mode protocompile.SourceInfoMode) linker.Result {
+		t.Helper()
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(map[string]string{"test.proto": source.String()}),
+			}),
+			SourceInfoMode: mode,
+			RetainASTs:     true,
+		}
+		files, err := compiler.Compile(context.Background(), "test.proto")
+		require.NoError(t, err)
+		return files.Files[0].(linker.Result)
+	}
+
+	res := compile(t, protocompile.SourceInfoStandard)
+	usage := res.MemoryUsage()
+	// the AST includes the source, so it must be at least as large
+	assert.Greater(t, usage.AST, int64(source.Len()))
+	assert.Positive(t, usage.Descriptors)
+	assert.Positive(t, usage.SourceInfo)
+	assert.Positive(t, usage.Indexes)
+	assert.Equal(t, usage.AST+usage.Descriptors+usage.SourceInfo+usage.Indexes, usage.Total())
+	assert.Equal(t, usage.Total()*2, usage.Add(usage).Total())
+
+	res.RemoveAST()
+	withoutAST := res.MemoryUsage()
+	assert.Zero(t, withoutAST.AST)
+	assert.Equal(t, usage.SourceInfo, withoutAST.SourceInfo)
+	assert.Equal(t, usage.Descriptors, withoutAST.Descriptors)
+
+	noSourceInfo := compile(t, protocompile.SourceInfoNone).MemoryUsage()
+	assert.Zero(t, noSourceInfo.SourceInfo)
+	assert.Equal(t, usage.Descriptors, noSourceInfo.Descriptors)
+}
+
+func TestMemoryUsageConcurrentResolve(t *testing.T) {
+	t.Parallel()
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{
+				"test.proto": `syntax = "proto3"; package foo; import "google/protobuf/descriptor.proto"; message M {}`,
+			}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	res := files.Files[0].(linker.Result)
+
+	// resolving names in imports records them as used, which must be safe
+	// while memory usage is computed
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			d, err := linker.ResolverFromFile(res).FindDescriptorByName("google.protobuf.FieldOptions")
+			assert.NoError(t, err)
+			assert.NotNil(t, d)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		assert.Positive(t, res.MemoryUsage().Indexes)
+	}
+	wg.Wait()
+}
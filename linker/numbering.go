@@ -0,0 +1,180 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// CheckLevel indicates whether an optional check is performed and, if so,
+// how its findings are reported.
+type CheckLevel int
+
+const (
+	// CheckLevelOff indicates that the check is not performed.
+	CheckLevelOff = CheckLevel(0)
+	// CheckLevelWarn indicates that findings are reported as warnings.
+	CheckLevelWarn = CheckLevel(1)
+	// CheckLevelError indicates that findings are reported as errors.
+	CheckLevelError = CheckLevel(2)
+)
+
+// DefaultMaxFieldNumberGap is the largest gap between field numbers that is
+// allowed by the FieldNumberChecks.LargeGaps check when MaxGap is zero.
+const DefaultMaxFieldNumberGap = 100
+
+// FieldNumberChecks configures hygiene checks for the field numbers of the
+// messages in a file. None of these are errors according to the language
+// spec; they are intended to catch likely mistakes. All checks are off by
+// default.
+type FieldNumberChecks struct {
+	// Reports fields whose number is lower than the number of a field
+	// declared before them in the same message.
+	NonMonotonicOrder CheckLevel
+	// Reports gaps between the field numbers of a message that are larger
+	// than MaxGap. Numbers that are reserved or in an extension range are not
+	// counted, since the gap is then explained.
+	LargeGaps CheckLevel
+	// The largest gap allowed by the LargeGaps check. If zero,
+	// DefaultMaxFieldNumberGap is used.
+	MaxGap int32
+	// Reports fields whose number is above one of the message's extension
+	// ranges. Extension ranges are conventionally at the end of a
+	// message's range of numbers, so such fields usually indicate that the
+	// extension range was declared too low or that the field was misnumbered.
+	AboveExtensionRange CheckLevel
+}
+
+// CheckFieldNumbers performs the given checks on the messages in the given
+// file, reporting findings via the given handler. Findings are reported at
+// the field number of the offending field. If any error is reported and the
+// handler does not suppress it, this function returns a non-nil error.
+func CheckFieldNumbers(res Result, handler *reporter.Handler, checks FieldNumberChecks) error {
+	if checks.NonMonotonicOrder == CheckLevelOff && checks.LargeGaps == CheckLevelOff && checks.AboveExtensionRange == CheckLevelOff {
+		return nil
+	}
+	if checks.MaxGap == 0 {
+		checks.MaxGap = DefaultMaxFieldNumberGap
+	}
+	return walk.DescriptorProtos(res.FileDescriptorProto(), func(name protoreflect.FullName, d proto.Message) error {
+		msg, ok := d.(*descriptorpb.DescriptorProto)
+		if !ok || msg.GetOptions().GetMapEntry() {
+			return nil
+		}
+		c := &numberChecker{res: res, handler: handler, checks: checks, msgName: name, msg: msg}
+		return c.check()
+	})
+}
+
+type numberChecker struct {
+	res     Result
+	handler *reporter.Handler
+	checks  FieldNumberChecks
+	msgName protoreflect.FullName
+	msg     *descriptorpb.DescriptorProto
+}
+
+func (c *numberChecker) check() error {
+	if c.checks.NonMonotonicOrder != CheckLevelOff {
+		var prev *descriptorpb.FieldDescriptorProto
+		for _, fld := range c.msg.Field {
+			if prev != nil && fld.GetNumber() < prev.GetNumber() {
+				if err := c.report(c.checks.NonMonotonicOrder, fld, "field %s: number %d is lower than number %d of preceding field %s",
+					c.fieldName(fld), fld.GetNumber(), prev.GetNumber(), prev.GetName()); err != nil {
+					return err
+				}
+			}
+			if prev == nil || fld.GetNumber() > prev.GetNumber() {
+				prev = fld
+			}
+		}
+	}
+	if c.checks.AboveExtensionRange != CheckLevelOff {
+		for _, fld := range c.msg.Field {
+			for _, rng := range c.msg.ExtensionRange {
+				if fld.GetNumber() >= rng.GetEnd() {
+					if err := c.report(c.checks.AboveExtensionRange, fld, "field %s: number %d is above extension range %d to %d",
+						c.fieldName(fld), fld.GetNumber(), rng.GetStart(), rng.GetEnd()-1); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+	if c.checks.LargeGaps != CheckLevelOff {
+		sorted := make([]*descriptorpb.FieldDescriptorProto, len(c.msg.Field))
+		copy(sorted, c.msg.Field)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].GetNumber() < sorted[j].GetNumber()
+		})
+		for i := 1; i < len(sorted); i++ {
+			lo, hi := sorted[i-1].GetNumber(), sorted[i].GetNumber()
+			if unused := c.unexplainedNumbers(lo+1, hi); unused > c.checks.MaxGap {
+				if err := c.report(c.checks.LargeGaps, sorted[i], "field %s: %d unused field numbers between %d and %d exceed the maximum gap of %d",
+					c.fieldName(sorted[i]), unused, lo, hi, c.checks.MaxGap); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// unexplainedNumbers returns how many of the numbers in [start, end) are
+// neither reserved nor in an extension range.
+func (c *numberChecker) unexplainedNumbers(start, end int32) int32 {
+	count := end - start
+	if count <= 0 {
+		return 0
+	}
+	covered := func(rngStart, rngEnd int32) {
+		lo, hi := max(rngStart, start), min(rngEnd, end)
+		if hi > lo {
+			count -= hi - lo
+		}
+	}
+	for _, rng := range c.msg.ReservedRange {
+		covered(rng.GetStart(), rng.GetEnd())
+	}
+	for _, rng := range c.msg.ExtensionRange {
+		covered(rng.GetStart(), rng.GetEnd())
+	}
+	return count
+}
+
+func (c *numberChecker) fieldName(fld *descriptorpb.FieldDescriptorProto) string {
+	return string(c.msgName) + "." + fld.GetName()
+}
+
+func (c *numberChecker) report(level CheckLevel, fld *descriptorpb.FieldDescriptorProto, format string, args ...any) error {
+	var span ast.SourceSpan = ast.UnknownSpan(c.res.Path())
+	if node := c.res.FieldNode(fld); node != nil && node.GetTag() != nil {
+		span = c.res.FileNode().NodeInfo(node.GetTag())
+	}
+	if level == CheckLevelWarn {
+		c.handler.HandleWarningf(span, format, args...)
+		return nil
+	}
+	return c.handler.HandleErrorf(span, format, args...)
+}
@@ -0,0 +1,84 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestCheckFieldNumbers(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto2";
+			message Foo {
+				optional string a = 1;
+				optional string b = 3;
+				optional string c = 2;
+				optional string d = 500;
+				optional string e = 2001;
+				reserved 4 to 200;
+				extensions 1000 to 1999;
+			}
+			`,
+	}
+	var warnings, errs []string
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			},
+			func(err reporter.ErrorWithPos) {
+				warnings = append(warnings, err.Error())
+			},
+		),
+		FieldNumberChecks: linker.FieldNumberChecks{
+			NonMonotonicOrder:   linker.CheckLevelWarn,
+			LargeGaps:           linker.CheckLevelWarn,
+			MaxGap:              200,
+			AboveExtensionRange: linker.CheckLevelError,
+		},
+	}
+	_, err := compiler.Compile(context.Background(), "test.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Equal(t, []string{
+		"test.proto:6:25-26: field Foo.c: number 2 is lower than number 3 of preceding field b",
+		"test.proto:7:25-28: field Foo.d: 299 unused field numbers between 3 and 500 exceed the maximum gap of 200",
+		"test.proto:8:25-29: field Foo.e: 500 unused field numbers between 500 and 2001 exceed the maximum gap of 200",
+	}, warnings)
+	assert.Equal(t, []string{
+		"test.proto:8:25-29: field Foo.e: number 2001 is above extension range 1000 to 1999",
+	}, errs)
+
+	// No checks are performed by default.
+	warnings, errs = nil, nil
+	compiler.FieldNumberChecks = linker.FieldNumberChecks{}
+	_, err = compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Empty(t, errs)
+}
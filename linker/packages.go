@@ -0,0 +1,249 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Package is a view of all files in a set that declare the same package.
+type Package struct {
+	// The name of the package. This is empty for files that do not declare
+	// a package.
+	Name protoreflect.FullName
+	// The files that declare the package, sorted by path.
+	Files Files
+	// The top-level elements of the package, from all of its files, sorted by
+	// name. These are messages, enums, extensions, and services.
+	Symbols []protoreflect.Descriptor
+	// The options in DefaultPackageScopedOptions, such as go_package and
+	// java_package, that are set to different values by different files in
+	// the package. See OptionConflicts.
+	Conflicts []PackageConflict
+}
+
+// PackageConflict describes a file option that is set to different values by
+// files in the same package.
+type PackageConflict struct {
+	// The name of the option, such as "go_package".
+	Option protoreflect.Name
+	// The distinct values of the option, sorted, and the files that use each.
+	Values []PackageOptionValue
+}
+
+// PackageOptionValue is one value of a conflicting file option, along with the
+// files that set the option to that value.
+type PackageOptionValue struct {
+	// The value, formatted as a string. Boolean values are "true" or "false".
+	Value string
+	// The files that use the value, sorted by path.
+	Files Files
+}
+
+// DefaultPackageScopedOptions are the file options whose values are expected
+// to be the same for all files in a package, since they determine where and
+// how the generated code for the package is organized.
+var DefaultPackageScopedOptions = []protoreflect.Name{
+	"csharp_namespace",
+	"go_package",
+	"java_multiple_files",
+	"java_package",
+	"objc_class_prefix",
+	"php_metadata_namespace",
+	"php_namespace",
+	"ruby_package",
+	"swift_prefix",
+}
+
+// Packages returns a view of the files in f grouped by package, sorted by
+// package name. Placeholder files are ignored.
+func (f Files) Packages() []Package {
+	byName := map[protoreflect.FullName]*Package{}
+	var pkgs []*Package
+	for _, file := range f {
+		if file.IsPlaceholder() {
+			continue
+		}
+		pkg := byName[file.Package()]
+		if pkg == nil {
+			pkg = &Package{Name: file.Package()}
+			byName[file.Package()] = pkg
+			pkgs = append(pkgs, pkg)
+		}
+		pkg.Files = append(pkg.Files, file)
+		pkg.Symbols = appendTopLevelSymbols(pkg.Symbols, file)
+	}
+	slices.SortFunc(pkgs, func(a, b *Package) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	result := make([]Package, len(pkgs))
+	for i, pkg := range pkgs {
+		slices.SortFunc(pkg.Files, compareFilePaths)
+		slices.SortStableFunc(pkg.Symbols, func(a, b protoreflect.Descriptor) int {
+			return cmp.Compare(a.FullName(), b.FullName())
+		})
+		result[i] = *pkg
+		result[i].Conflicts = result[i].OptionConflicts(DefaultPackageScopedOptions...)
+	}
+	return result
+}
+
+func appendTopLevelSymbols(symbols []protoreflect.Descriptor, file File) []protoreflect.Descriptor {
+	for i := 0; i < file.Messages().Len(); i++ {
+		symbols = append(symbols, file.Messages().Get(i))
+	}
+	for i := 0; i < file.Enums().Len(); i++ {
+		symbols = append(symbols, file.Enums().Get(i))
+	}
+	for i := 0; i < file.Extensions().Len(); i++ {
+		symbols = append(symbols, file.Extensions().Get(i))
+	}
+	for i := 0; i < file.Services().Len(); i++ {
+		symbols = append(symbols, file.Services().Get(i))
+	}
+	return symbols
+}
+
+// OptionConflicts returns the given file options that are set to different
+// values by files in the package, in the order given. Files that do not set an
+// option are not considered. Names that are not fields of FileOptions are
+// ignored.
+func (p Package) OptionConflicts(options ...protoreflect.Name) []PackageConflict {
+	fields := (*descriptorpb.FileOptions)(nil).ProtoReflect().Descriptor().Fields()
+	var conflicts []PackageConflict
+	for _, name := range options {
+		fld := fields.ByName(name)
+		if fld == nil {
+			continue
+		}
+		var values []PackageOptionValue
+		for _, file := range p.Files {
+			opts, _ := file.Options().(*descriptorpb.FileOptions)
+			if opts == nil || !opts.ProtoReflect().Has(fld) {
+				continue
+			}
+			val := fmt.Sprint(opts.ProtoReflect().Get(fld).Interface())
+			i, found := slices.BinarySearchFunc(values, val, func(v PackageOptionValue, s string) int {
+				return cmp.Compare(v.Value, s)
+			})
+			if !found {
+				values = slices.Insert(values, i, PackageOptionValue{Value: val})
+			}
+			values[i].Files = append(values[i].Files, file)
+		}
+		if len(values) > 1 {
+			conflicts = append(conflicts, PackageConflict{Option: name, Values: values})
+		}
+	}
+	return conflicts
+}
+
+// PackageOptionChecks configures checks that file options are consistent
+// across all files in each package.
+type PackageOptionChecks struct {
+	// How inconsistencies are reported. The checks are off by default.
+	Level CheckLevel
+	// The file options to check. If empty, DefaultPackageScopedOptions are
+	// checked.
+	Options []protoreflect.Name
+}
+
+// CheckPackageOptions reports file options that are set to different values
+// by files in the same package. For each such option, a diagnostic is reported
+// in every file that sets it, at the option's declaration. The message lists
+// all values of the option and the files that use each one. If any error is
+// reported and the handler does not suppress it, this function returns a
+// non-nil error.
+func CheckPackageOptions(files Files, handler *reporter.Handler, checks PackageOptionChecks) error {
+	if checks.Level == CheckLevelOff {
+		return nil
+	}
+	options := checks.Options
+	if len(options) == 0 {
+		options = DefaultPackageScopedOptions
+	}
+	fields := (*descriptorpb.FileOptions)(nil).ProtoReflect().Descriptor().Fields()
+	for _, pkg := range files.Packages() {
+		for _, conflict := range pkg.OptionConflicts(options...) {
+			isString := fields.ByName(conflict.Option).Kind() == protoreflect.StringKind
+			var sb strings.Builder
+			for i, val := range conflict.Values {
+				if i > 0 {
+					sb.WriteString("; ")
+				}
+				if isString {
+					sb.WriteString(strconv.Quote(val.Value))
+				} else {
+					sb.WriteString(val.Value)
+				}
+				sb.WriteString(" in ")
+				for j, file := range val.Files {
+					if j > 0 {
+						sb.WriteString(", ")
+					}
+					sb.WriteString(file.Path())
+				}
+			}
+			pkgName := string(pkg.Name)
+			if pkgName == "" {
+				pkgName = "<default>"
+			}
+			for _, val := range conflict.Values {
+				for _, file := range val.Files {
+					span := fileOptionSpan(file, conflict.Option)
+					if checks.Level == CheckLevelWarn {
+						handler.HandleWarningf(span, "package %s: inconsistent values for option %s: %s", pkgName, conflict.Option, sb.String())
+					} else if err := handler.HandleErrorf(span, "package %s: inconsistent values for option %s: %s", pkgName, conflict.Option, sb.String()); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fileOptionSpan returns the span of the declaration of the given file option
+// in the given file, or an unknown span if it cannot be found.
+func fileOptionSpan(file File, name protoreflect.Name) ast.SourceSpan {
+	if res, ok := file.(Result); ok && res.AST() != nil {
+		for _, decl := range res.AST().GetDecls() {
+			opt := decl.GetOption()
+			if opt == nil || opt.IsIncomplete() || len(opt.Name.Parts) != 1 {
+				continue
+			}
+			ref := opt.Name.Parts[0].GetFieldRef()
+			if ref != nil && !ref.IsExtension() && ref.Value() == string(name) {
+				return res.AST().NodeInfo(opt)
+			}
+		}
+	}
+	return ast.UnknownSpan(file.Path())
+}
+
+func compareFilePaths(a, b File) int {
+	return cmp.Compare(a.Path(), b.Path())
+}
@@ -0,0 +1,161 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestFilesPackages(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `
+			syntax = "proto3";
+			package foo;
+			option go_package = "example.com/foo";
+			option java_package = "com.example.foo";
+			message A {}
+			enum E { E_ZERO = 0; }
+			`,
+		"b.proto": `
+			syntax = "proto3";
+			package foo;
+			option go_package = "example.com/foo";
+			option java_package = "com.example.other";
+			service S {}
+			`,
+		"c.proto": `
+			syntax = "proto3";
+			package foo;
+			option java_package = "com.example.foo";
+			message C {}
+			`,
+		"d.proto": `
+			syntax = "proto3";
+			package bar;
+			option go_package = "example.com/bar";
+			message D {}
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+	}
+	files, err := compiler.Compile(context.Background(), "d.proto", "c.proto", "b.proto", "a.proto")
+	require.NoError(t, err)
+
+	pkgs := files.Packages()
+	require.Len(t, pkgs, 2)
+
+	assert.Equal(t, protoreflect.FullName("bar"), pkgs[0].Name)
+	assert.Equal(t, []string{"d.proto"}, filePaths(pkgs[0].Files))
+	assert.Equal(t, []protoreflect.FullName{"bar.D"}, symbolNames(pkgs[0].Symbols))
+	assert.Empty(t, pkgs[0].Conflicts)
+
+	foo := pkgs[1]
+	assert.Equal(t, protoreflect.FullName("foo"), foo.Name)
+	assert.Equal(t, []string{"a.proto", "b.proto", "c.proto"}, filePaths(foo.Files))
+	assert.Equal(t, []protoreflect.FullName{"foo.A", "foo.C", "foo.E", "foo.S"}, symbolNames(foo.Symbols))
+	// go_package is consistent among the files that set it
+	require.Len(t, foo.Conflicts, 1)
+	conflict := foo.Conflicts[0]
+	assert.Equal(t, protoreflect.Name("java_package"), conflict.Option)
+	require.Len(t, conflict.Values, 2)
+	assert.Equal(t, "com.example.foo", conflict.Values[0].Value)
+	assert.Equal(t, []string{"a.proto", "c.proto"}, filePaths(conflict.Values[0].Files))
+	assert.Equal(t, "com.example.other", conflict.Values[1].Value)
+	assert.Equal(t, []string{"b.proto"}, filePaths(conflict.Values[1].Files))
+}
+
+func TestCheckPackageOptions(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `
+			syntax = "proto3";
+			package foo;
+			option go_package = "example.com/foo";
+			option java_multiple_files = true;
+			`,
+		"b.proto": `
+			syntax = "proto3";
+			package foo;
+			option go_package = "example.com/foo/v2";
+			option java_multiple_files = false;
+			`,
+		"c.proto": `
+			syntax = "proto3";
+			package foo;
+			option go_package = "example.com/foo";
+			`,
+	}
+	var errs []string
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			},
+			nil,
+		),
+		PackageOptionChecks: linker.PackageOptionChecks{
+			Level:   linker.CheckLevelError,
+			Options: []protoreflect.Name{"java_multiple_files", "go_package"},
+		},
+	}
+	_, err := compiler.Compile(context.Background(), "a.proto", "b.proto", "c.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Equal(t, []string{
+		"b.proto:5:4-39: package foo: inconsistent values for option java_multiple_files: false in b.proto; true in a.proto",
+		"a.proto:5:4-38: package foo: inconsistent values for option java_multiple_files: false in b.proto; true in a.proto",
+		`a.proto:4:4-42: package foo: inconsistent values for option go_package: "example.com/foo" in a.proto, c.proto; "example.com/foo/v2" in b.proto`,
+		`c.proto:4:4-42: package foo: inconsistent values for option go_package: "example.com/foo" in a.proto, c.proto; "example.com/foo/v2" in b.proto`,
+		`b.proto:4:4-45: package foo: inconsistent values for option go_package: "example.com/foo" in a.proto, c.proto; "example.com/foo/v2" in b.proto`,
+	}, errs)
+
+	// Only the files being compiled are checked.
+	errs = nil
+	_, err = compiler.Compile(context.Background(), "a.proto", "c.proto")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
+
+func filePaths(files linker.Files) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path()
+	}
+	return paths
+}
+
+func symbolNames(symbols []protoreflect.Descriptor) []protoreflect.FullName {
+	names := make([]protoreflect.FullName, len(symbols))
+	for i, sym := range symbols {
+		names[i] = sym.FullName()
+	}
+	return names
+}
@@ -14,7 +14,14 @@
 
 package linker
 
-import "google.golang.org/protobuf/types/descriptorpb"
+import (
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+)
 
 // allocPool helps allocate descriptor instances. Instead of allocating
 // them one at a time, we allocate a pool -- a large, flat slice to hold
@@ -40,20 +47,47 @@ type allocPool struct {
 	methods    []mtdDescriptor
 }
 
-func newAllocPool(file *descriptorpb.FileDescriptorProto) *allocPool {
+func newAllocPool(file *descriptorpb.FileDescriptorProto, reuse *arena) *allocPool {
 	var pool allocPool
 	pool.countElements(file)
-	pool.messages = make([]msgDescriptor, pool.numMessages)
-	pool.fields = make([]fldDescriptor, pool.numFields)
-	pool.oneofs = make([]oneofDescriptor, pool.numOneofs)
-	pool.enums = make([]enumDescriptor, pool.numEnums)
-	pool.enumVals = make([]enValDescriptor, pool.numEnumValues)
-	pool.extensions = make([]extTypeDescriptor, pool.numExtensions)
-	pool.services = make([]svcDescriptor, pool.numServices)
-	pool.methods = make([]mtdDescriptor, pool.numMethods)
+	if reuse == nil {
+		reuse = &arena{}
+	}
+	pool.messages = reuseSlice(reuse.messages, pool.numMessages)
+	pool.fields = reuseSlice(reuse.fields, pool.numFields)
+	pool.oneofs = reuseSlice(reuse.oneofs, pool.numOneofs)
+	pool.enums = reuseSlice(reuse.enums, pool.numEnums)
+	pool.enumVals = reuseSlice(reuse.enumVals, pool.numEnumValues)
+	pool.extensions = reuseSlice(reuse.extensions, pool.numExtensions)
+	pool.services = reuseSlice(reuse.services, pool.numServices)
+	pool.methods = reuseSlice(reuse.methods, pool.numMethods)
 	return &pool
 }
 
+func reuseSlice[T any](s []T, n int) []T {
+	if cap(s) >= n {
+		return s[:n]
+	}
+	return make([]T, n)
+}
+
+// arena records the full backing slices handed out by an allocPool, so that
+// they can be returned to a DescriptorPool once the owning result is released.
+func (p *allocPool) arena() *arena {
+	// This must be called before any descriptors are allocated, since
+	// allocating re-slices the pool's fields.
+	return &arena{
+		messages:   p.messages,
+		fields:     p.fields,
+		oneofs:     p.oneofs,
+		enums:      p.enums,
+		enumVals:   p.enumVals,
+		extensions: p.extensions,
+		services:   p.services,
+		methods:    p.methods,
+	}
+}
+
 func (p *allocPool) getMessages(count int) []msgDescriptor {
 	allocated := p.messages[:count]
 	p.messages = p.messages[count:]
@@ -129,3 +163,100 @@ func (p *allocPool) countElementsInEnums(enums []*descriptorpb.EnumDescriptorPro
 		p.numEnumValues += len(enum.Value)
 	}
 }
+
+type arena struct {
+	messages   []msgDescriptor
+	fields     []fldDescriptor
+	oneofs     []oneofDescriptor
+	enums      []enumDescriptor
+	enumVals   []enValDescriptor
+	extensions []extTypeDescriptor
+	services   []svcDescriptor
+	methods    []mtdDescriptor
+
+	resolvedReferences   map[protoreflect.Descriptor][]ast.NodeReference
+	optionQualifiedNames map[*ast.IdentValueNode]string
+	extensionsByMessage  map[protoreflect.FullName][]protoreflect.ExtensionDescriptor
+}
+
+func (a *arena) reset() {
+	// Zero out everything (up to capacity) so that released descriptors don't
+	// keep the rest of their file, their protos, or their ASTs reachable.
+	clear(a.messages[:cap(a.messages)])
+	clear(a.fields[:cap(a.fields)])
+	clear(a.oneofs[:cap(a.oneofs)])
+	clear(a.enums[:cap(a.enums)])
+	clear(a.enumVals[:cap(a.enumVals)])
+	clear(a.extensions[:cap(a.extensions)])
+	clear(a.services[:cap(a.services)])
+	clear(a.methods[:cap(a.methods)])
+	clear(a.resolvedReferences)
+	clear(a.optionQualifiedNames)
+	clear(a.extensionsByMessage)
+}
+
+// DescriptorPool holds on to the memory backing descriptors and their
+// associated indexes after the results that own them are released, so that
+// it can be reused when linking other files. This reduces GC pressure in
+// long-lived processes, like language servers, that recompile continuously.
+//
+// A pool is opt-in: pass it to Link via WithDescriptorPool and call Release
+// when a result is no longer needed. Once a result has been released, it and
+// all of its descriptors must not be used again: their memory will be zeroed
+// and then handed out to other results. Using a released result will observe
+// empty or unrelated descriptors. A result must not be released more than once.
+//
+// The zero value is ready to use. This type is thread-safe.
+type DescriptorPool struct {
+	mu   sync.Mutex
+	free []*arena
+}
+
+// maxFreeArenas bounds the number of arenas retained by a DescriptorPool,
+// so a burst of invalidations does not pin memory indefinitely.
+const maxFreeArenas = 256
+
+// NewDescriptorPool returns a new, empty pool.
+func NewDescriptorPool() *DescriptorPool {
+	return &DescriptorPool{}
+}
+
+// Release returns the memory used by the given result to the pool. The result
+// must have been created by Link using this pool. Results created by other
+// means are ignored.
+func (p *DescriptorPool) Release(res Result) {
+	r, ok := res.(*result)
+	if !ok || r.arena == nil || r.descriptorPool != p {
+		return
+	}
+	a := r.arena
+	r.arena = nil
+	a.reset()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.free) < maxFreeArenas {
+		p.free = append(p.free, a)
+	}
+}
+
+// Len returns the number of released arenas currently held by the pool.
+func (p *DescriptorPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.free)
+}
+
+func (p *DescriptorPool) get() *arena {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.free) == 0 {
+		return nil
+	}
+	a := p.free[len(p.free)-1]
+	p.free[len(p.free)-1] = nil
+	p.free = p.free[:len(p.free)-1]
+	return a
+}
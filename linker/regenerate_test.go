@@ -0,0 +1,136 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/sourceinfo"
+)
+
+func TestRegenerateSourceInfo(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions { repeated string tags = 50000; }
+message Msg {
+  option (tags) = "a";
+  option (tags) = "b";
+}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+		RetainASTs:     true,
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	res, ok := files.Files[0].(linker.Result)
+	require.True(t, ok)
+
+	original := proto.Clone(res.FileDescriptorProto().GetSourceCodeInfo())
+	require.NoError(t, res.RegenerateSourceInfo())
+	assert.True(t, proto.Equal(original, res.FileDescriptorProto().GetSourceCodeInfo()))
+
+	optionsPath := protoreflect.SourcePath{descpath.FileMessagesTag, 0, descpath.MessageOptionsTag}
+	tagPath := func(i int32) protoreflect.SourcePath {
+		return append(append(protoreflect.SourcePath{}, optionsPath...), 50000, i)
+	}
+	locs := res.SourceLocations()
+	assert.Equal(t, 5, locs.ByPath(tagPath(0)).StartLine)
+	assert.Equal(t, 6, locs.ByPath(tagPath(1)).StartLine)
+
+	// as if a post-processing step had inserted a value at the front of the option
+	res.OptionIndex().RebasePaths(func(_ *ast.OptionNode, path []int32) []int32 {
+		return sourceinfo.ShiftIndex(path, []int32{50000}, 0, 1)
+	})
+	require.NoError(t, res.RegenerateSourceInfo())
+	locs = res.SourceLocations()
+	assert.Equal(t, 0, locs.ByPath(tagPath(0)).StartLine)
+	assert.Equal(t, 5, locs.ByPath(tagPath(1)).StartLine)
+	assert.Equal(t, 6, locs.ByPath(tagPath(2)).StartLine)
+
+	res.RemoveAST()
+	assert.ErrorIs(t, res.RegenerateSourceInfo(), linker.ErrNoAST)
+}
+
+func TestRegenerateSourceInfoAfterOptionsChange(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+enum Kind { FOO = 0; BAR = 1; }
+extend google.protobuf.MessageOptions {
+  optional string tag = 50000;
+  optional Kind kind = 50001;
+}
+message Msg {
+  option (tag) = "a";
+  option (kind) = BAR;
+}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+		RetainASTs:     true,
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	res, ok := files.Files[0].(linker.Result)
+	require.True(t, ok)
+
+	bar := res.Enums().ByName("Kind").Values().ByName("BAR")
+	kindPath := protoreflect.SourcePath{descpath.FileMessagesTag, 0, descpath.MessageOptionsTag, 50001}
+	var kindOpt *ast.OptionNode
+	for opt, info := range res.OptionIndex() {
+		if len(info.Path) > 0 && info.Path[0] == 50001 {
+			kindOpt = opt
+		}
+	}
+	require.NotNil(t, kindOpt)
+
+	require.NoError(t, res.RegenerateSourceInfo())
+	assert.Len(t, res.FindReferences(bar), 1)
+	assert.Len(t, res.OptionDescriptorIndex().EnumValueIdentNodesToEnumValueDescriptors, 1)
+	assert.Equal(t, 10, res.SourceLocations().ByPath(kindPath).StartLine)
+
+	// as if the option had been removed when options were interpreted again
+	delete(res.OptionIndex(), kindOpt)
+	require.NoError(t, res.RegenerateSourceInfo())
+	assert.Empty(t, res.FindReferences(bar))
+	assert.Empty(t, res.OptionDescriptorIndex().EnumValueIdentNodesToEnumValueDescriptors)
+	for node := range res.OptionDescriptorIndex().FieldReferenceNodesToFieldDescriptors {
+		assert.NotSame(t, kindOpt.GetName().GetParts()[0], node)
+	}
+	assert.Nil(t, res.SourceLocations().ByPath(kindPath).Path)
+	assert.Len(t, res.FindReferences(res.Extensions().ByName("tag")), 1)
+}
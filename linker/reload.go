@@ -0,0 +1,152 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/sourceinfo"
+)
+
+// ASTLoader loads the AST for a file, typically by parsing its source code
+// again. See WithASTLoader.
+type ASTLoader func(ctx context.Context) (*ast.FileNode, error)
+
+// ErrNoASTLoader is returned from Result.ReloadAST if the result was linked
+// without an ASTLoader.
+var ErrNoASTLoader = errors.New("result has no AST loader")
+
+// ErrNoAST is returned from operations that require a result's AST, such as
+// Result.RegenerateSourceInfo, if the result has no AST.
+var ErrNoAST = errors.New("result has no AST")
+
+func (r *result) ReloadAST(ctx context.Context) error {
+	if r.AST() != nil {
+		return nil
+	}
+	if r.astLoader == nil {
+		return ErrNoASTLoader
+	}
+	file, err := r.astLoader(ctx)
+	if err != nil {
+		return err
+	}
+	// The file was already accepted when it was first parsed, so experimental
+	// editions are allowed, and synthetic oneofs are named the same way.
+	protocOneofNames := r.SyntheticOneofNaming() == parser.SyntheticOneofNamingProtoc
+	parsed, err := parser.ResultFromAST(file, false, reporter.NewHandler(nil),
+		parser.WithExperimentalEditions(true), parser.WithProtocSyntheticOneofNames(protocOneofNames))
+	if err != nil {
+		return err
+	}
+	res, err := parser.Reassociate(parsed, r.FileDescriptorProto())
+	if err != nil {
+		return err
+	}
+
+	// References and option indexes still refer to nodes in the old AST,
+	// which are replaced with the nodes at the same positions in the new one.
+	nodes := indexNodes(file)
+	remap := func(node ast.Node) ast.Node {
+		if node == nil {
+			return nil
+		}
+		return nodes[keyForNode(node)]
+	}
+	for desc, refs := range r.resolvedReferences {
+		remapped := refs[:0]
+		for _, ref := range refs {
+			if node := remap(ref.Node); node != nil {
+				remapped = append(remapped, ast.NewNodeReference(file, node))
+			}
+		}
+		if len(remapped) == 0 {
+			delete(r.resolvedReferences, desc)
+			continue
+		}
+		r.resolvedReferences[desc] = remapped
+	}
+	r.optsIndex = remapKeys(r.optsIndex, remap)
+	r.optsDescIndex = sourceinfo.OptionDescriptorIndex{
+		UninterpretedNameDescriptorsToFieldDescriptors: r.optsDescIndex.UninterpretedNameDescriptorsToFieldDescriptors,
+		FieldReferenceNodesToFieldDescriptors:          remapKeys(r.optsDescIndex.FieldReferenceNodesToFieldDescriptors, remap),
+		EnumValueIdentNodesToEnumValueDescriptors:      remapKeys(r.optsDescIndex.EnumValueIdentNodesToEnumValueDescriptors, remap),
+		OptionsToFieldDescriptors:                      r.optsDescIndex.OptionsToFieldDescriptors,
+		TypeReferenceURLsToMessageDescriptors:          remapKeys(r.optsDescIndex.TypeReferenceURLsToMessageDescriptors, remap),
+		MessageLiteralsToMessageDescriptors:            remapKeys(r.optsDescIndex.MessageLiteralsToMessageDescriptors, remap),
+		TypeReferenceURLsToAnyValues:                   remapKeys(r.optsDescIndex.TypeReferenceURLsToAnyValues, remap),
+		FieldDefaults:                                  r.optsDescIndex.FieldDefaults,
+	}
+	r.Result = res
+	if r.restoredIndexes != nil {
+		optsIndex, optsDescIndex := r.restoredIndexes.restore(r, nodes)
+		r.restoredIndexes = nil
+		r.optsIndex = optsIndex
+		r.PopulateOptionDescriptorIndex(optsDescIndex)
+	}
+	return nil
+}
+
+// nodeKey identifies a node by its kind and the range of tokens it spans,
+// which are the same for two ASTs parsed from the same source.
+type nodeKey struct {
+	kind       protoreflect.FullName
+	start, end ast.Token
+}
+
+func keyForNode(node ast.Node) nodeKey {
+	return nodeKey{
+		kind:  node.ProtoReflect().Descriptor().FullName(),
+		start: node.Start(),
+		end:   node.End(),
+	}
+}
+
+// indexNodes returns all nodes in the given AST, keyed by nodeKey.
+func indexNodes(file *ast.FileNode) map[nodeKey]ast.Node {
+	nodes := map[nodeKey]ast.Node{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		key := keyForNode(node)
+		if _, ok := nodes[key]; !ok {
+			nodes[key] = node
+		}
+		return true
+	})
+	return nodes
+}
+
+// remapKeys returns a copy of m with each key replaced by the given function,
+// omitting entries for which it returns nil. It returns nil if m is nil.
+func remapKeys[K interface {
+	comparable
+	ast.Node
+}, V any](m map[K]V, remap func(ast.Node) ast.Node) map[K]V {
+	if m == nil {
+		return nil
+	}
+	remapped := make(map[K]V, len(m))
+	for key, val := range m {
+		if node, ok := remap(key).(K); ok {
+			remapped[node] = val
+		}
+	}
+	return remapped
+}
@@ -0,0 +1,166 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/editions"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// RequiredField describes a field whose effective presence is
+// LEGACY_REQUIRED, along with everything that transitively embeds it.
+// Removing such a field, or making it optional, changes whether encoded
+// messages that omit it are considered initialized, so all of these
+// elements are affected.
+type RequiredField struct {
+	Field protoreflect.FieldDescriptor
+	// The element whose options explicitly set the field_presence feature
+	// to LEGACY_REQUIRED. This is the field itself or one of its ancestors.
+	// If nil, the field is in a file that uses proto2 syntax and has the
+	// "required" label.
+	SetBy protoreflect.Descriptor
+	// The messages that contain the field, directly or transitively via
+	// message fields, map values, and extensions. The first element is the
+	// field's own message; the rest are sorted by full name.
+	Messages []protoreflect.MessageDescriptor
+	// The RPC methods whose request or response type is one of Messages,
+	// sorted by full name.
+	Methods []protoreflect.MethodDescriptor
+	// The services that declare any of Methods, sorted by full name.
+	Services []protoreflect.ServiceDescriptor
+}
+
+// RequiredFields returns all fields in f whose effective presence is
+// LEGACY_REQUIRED, ordered by file and then by declaration order. Only the
+// messages and services in f are considered when computing the elements that
+// embed each field. Placeholder files are ignored.
+func (f Files) RequiredFields() []RequiredField {
+	var required []protoreflect.FieldDescriptor
+	// embeddedBy maps a message to the messages that have a field of its type
+	embeddedBy := map[protoreflect.FullName]map[protoreflect.FullName]protoreflect.MessageDescriptor{}
+	// usedBy maps a message to the methods that use it as request or response
+	usedBy := map[protoreflect.FullName][]protoreflect.MethodDescriptor{}
+	addEdge := func(embedded protoreflect.MessageDescriptor, container protoreflect.MessageDescriptor) {
+		if embedded == nil || container == nil {
+			return
+		}
+		containers := embeddedBy[embedded.FullName()]
+		if containers == nil {
+			containers = map[protoreflect.FullName]protoreflect.MessageDescriptor{}
+			embeddedBy[embedded.FullName()] = containers
+		}
+		containers[container.FullName()] = container
+	}
+	for _, file := range f {
+		if file.IsPlaceholder() {
+			continue
+		}
+		_ = walk.Descriptors(file, func(d protoreflect.Descriptor) error {
+			switch d := d.(type) {
+			case protoreflect.FieldDescriptor:
+				if d.Cardinality() == protoreflect.Required {
+					required = append(required, d)
+				}
+				container := d.ContainingMessage()
+				if container.IsMapEntry() {
+					// attribute map values to the message with the map field
+					if parent, ok := container.Parent().(protoreflect.MessageDescriptor); ok {
+						container = parent
+					}
+				}
+				addEdge(d.Message(), container)
+			case protoreflect.MethodDescriptor:
+				usedBy[d.Input().FullName()] = append(usedBy[d.Input().FullName()], d)
+				if d.Output().FullName() != d.Input().FullName() {
+					usedBy[d.Output().FullName()] = append(usedBy[d.Output().FullName()], d)
+				}
+			}
+			return nil
+		})
+	}
+
+	results := make([]RequiredField, 0, len(required))
+	for _, fld := range required {
+		msg := fld.ContainingMessage()
+		result := RequiredField{
+			Field:    fld,
+			SetBy:    fieldPresenceSetBy(fld),
+			Messages: []protoreflect.MessageDescriptor{msg},
+		}
+		seen := map[protoreflect.FullName]struct{}{msg.FullName(): {}}
+		queue := []protoreflect.MessageDescriptor{msg}
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			for name, container := range embeddedBy[next.FullName()] {
+				if _, ok := seen[name]; ok {
+					continue
+				}
+				seen[name] = struct{}{}
+				result.Messages = append(result.Messages, container)
+				queue = append(queue, container)
+			}
+		}
+		sort.Slice(result.Messages[1:], func(i, j int) bool {
+			return result.Messages[i+1].FullName() < result.Messages[j+1].FullName()
+		})
+
+		methods := map[protoreflect.FullName]protoreflect.MethodDescriptor{}
+		services := map[protoreflect.FullName]protoreflect.ServiceDescriptor{}
+		for _, m := range result.Messages {
+			for _, mtd := range usedBy[m.FullName()] {
+				methods[mtd.FullName()] = mtd
+				services[mtd.Parent().FullName()] = mtd.Parent().(protoreflect.ServiceDescriptor)
+			}
+		}
+		for _, mtd := range methods {
+			result.Methods = append(result.Methods, mtd)
+		}
+		sort.Slice(result.Methods, func(i, j int) bool {
+			return result.Methods[i].FullName() < result.Methods[j].FullName()
+		})
+		for _, svc := range services {
+			result.Services = append(result.Services, svc)
+		}
+		sort.Slice(result.Services, func(i, j int) bool {
+			return result.Services[i].FullName() < result.Services[j].FullName()
+		})
+		results = append(results, result)
+	}
+	return results
+}
+
+// fieldPresenceSetBy returns the element whose options explicitly set the
+// field_presence feature that applies to the given field, or nil if the
+// field's file does not use editions.
+func fieldPresenceSetBy(fld protoreflect.FieldDescriptor) protoreflect.Descriptor {
+	if fld.ParentFile().Syntax() != protoreflect.Editions {
+		return nil
+	}
+	for d := protoreflect.Descriptor(fld); d != nil; d = d.Parent() {
+		withFeatures, ok := d.Options().(editions.HasFeatures)
+		if !ok {
+			continue
+		}
+		if features := withFeatures.GetFeatures(); features != nil && features.FieldPresence != nil {
+			return d
+		}
+	}
+	return nil
+}
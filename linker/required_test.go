@@ -0,0 +1,99 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+)
+
+func TestRequiredFields(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `edition = "2023";
+package foo;
+import "b.proto";
+message Inner {
+  string id = 1 [features.field_presence = LEGACY_REQUIRED];
+  string name = 2;
+}
+message Outer {
+  Inner inner = 1;
+  map<string, Inner> by_name = 2;
+}
+message Unrelated {
+  string s = 1;
+}
+service Svc {
+  rpc Get(Unrelated) returns (Outer);
+  rpc List(Unrelated) returns (Unrelated);
+}`,
+		"b.proto": `syntax = "proto2";
+package foo;
+message Legacy {
+  required int32 n = 1;
+  extensions 100 to 200;
+}`,
+		"c.proto": `syntax = "proto2";
+package foo;
+import "a.proto";
+import "b.proto";
+extend Legacy {
+  optional Legacy nested = 100;
+}
+message Wrapper {
+  optional Legacy legacy = 1;
+}
+service Other {
+  rpc Do(Wrapper) returns (Unrelated);
+}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto", "b.proto", "c.proto")
+	require.NoError(t, err)
+
+	var actual []string
+	for _, req := range files.Files.RequiredFields() {
+		setBy := "<label>"
+		if req.SetBy != nil {
+			setBy = string(req.SetBy.FullName())
+		}
+		actual = append(actual, fmt.Sprintf("%s (%s): messages=%v methods=%v services=%v",
+			req.Field.FullName(), setBy, names(req.Messages), names(req.Methods), names(req.Services)))
+	}
+	assert.Equal(t, []string{
+		"foo.Inner.id (foo.Inner.id): messages=[foo.Inner foo.Outer] methods=[foo.Svc.Get] services=[foo.Svc]",
+		"foo.Legacy.n (<label>): messages=[foo.Legacy foo.Wrapper] methods=[foo.Other.Do] services=[foo.Other]",
+	}, actual)
+}
+
+func names[T protoreflect.Descriptor](descs []T) []string {
+	names := make([]string, len(descs))
+	for i, d := range descs {
+		names[i] = string(d.FullName())
+	}
+	return names
+}
@@ -98,6 +98,8 @@ func resolveInFile[T any](f File, publicImportsOnly bool, checked []string, fn f
 }
 
 func (r *result) markUsed(importPath string) {
+	r.usedImportsMu.Lock()
+	defer r.usedImportsMu.Unlock()
 	r.usedImports[importPath] = struct{}{}
 }
 
@@ -130,6 +132,8 @@ func (r *result) CheckForUnusedImports(handler *reporter.Handler) {
 		}
 	}
 
+	r.usedImportsMu.Lock()
+	defer r.usedImportsMu.Unlock()
 	for i, impNode := range importNodes {
 		dep := deps[i]
 		if dep.IsPlaceholder() {
@@ -174,9 +178,15 @@ func descriptorTypeWithArticle(d protoreflect.Descriptor) string {
 	}
 }
 
-func (r *result) createDescendants() {
+func (r *result) createDescendants(reuse *arena) {
 	fd := r.FileDescriptorProto()
-	pool := newAllocPool(fd)
+	pool := newAllocPool(fd, reuse)
+	if r.descriptorPool != nil {
+		r.arena = pool.arena()
+		r.arena.resolvedReferences = r.resolvedReferences
+		r.arena.optionQualifiedNames = r.optionQualifiedNames
+		r.arena.extensionsByMessage = r.extensionsByMessage
+	}
 	prefix := ""
 	if fd.GetPackage() != "" {
 		prefix = fd.GetPackage() + "."
@@ -338,13 +348,20 @@ func resolveFieldTypes(f *fldDescriptor, handler *reporter.Handler, extendees ma
 	kind := "field"
 	if fld.GetExtendee() != "" {
 		kind = "extension"
-		dsc := r.resolve(ast.NewNodeReference(file, r.FieldExtendeeNode(fld)), fld.GetExtendee(), false, scopes, checkedCache)
+		// refer to the extendee's name, not the whole extend block, so that
+		// positions in the block's fields don't resolve to the extendee
+		var extendeeRef ast.Node = r.FieldExtendeeNode(fld)
+		if name := r.FieldExtendeeNode(fld).GetExtendee(); name != nil {
+			extendeeRef = name
+		}
+		dsc := r.resolve(ast.NewNodeReference(file, extendeeRef), fld.GetExtendee(), false, scopes, checkedCache)
 		if dsc == nil {
 			return handler.HandleErrorWithPos(file.NodeInfo(r.FieldExtendeeNode(fld)), &errUndeclaredName{
-				scope:      kind + " " + f.fqn,
-				what:       "extendee type",
-				name:       fld.GetExtendee(),
-				parentFile: file,
+				scope:       kind + " " + f.fqn,
+				what:        "extendee type",
+				name:        fld.GetExtendee(),
+				suggestions: r.suggestNames(fld.GetExtendee(), isSuggestedMessage),
+				parentFile:  file,
 			})
 		}
 		if isSentinelDescriptor(dsc) {
@@ -354,6 +371,9 @@ func resolveFieldTypes(f *fldDescriptor, handler *reporter.Handler, extendees ma
 		if !ok {
 			return handler.HandleErrorf(file.NodeInfo(r.FieldExtendeeNode(fld)), "extendee is invalid: %s is %s, not a message", dsc.FullName(), descriptorTypeWithArticle(dsc))
 		}
+		if err := r.checkVisibility(r.FieldExtendeeNode(fld), kind+" "+f.fqn, dsc, handler); err != nil {
+			return err
+		}
 
 		f.extendee = extd
 		extendeeName := "." + string(dsc.FullName())
@@ -394,15 +414,19 @@ func resolveFieldTypes(f *fldDescriptor, handler *reporter.Handler, extendees ma
 	dsc := r.resolve(ast.NewNodeReference(file, node.GetFieldTypeNode()), fld.GetTypeName(), true, scopes, checkedCache)
 	if dsc == nil {
 		return handler.HandleErrorWithPos(file.NodeInfo(node.GetFieldTypeNode()), &errUndeclaredName{
-			scope:      kind + " " + f.fqn,
-			what:       "type",
-			name:       fld.GetTypeName(),
-			parentFile: file,
+			scope:       kind + " " + f.fqn,
+			what:        "type",
+			name:        fld.GetTypeName(),
+			suggestions: r.suggestNames(fld.GetTypeName(), isSuggestedType),
+			parentFile:  file,
 		})
 	}
 	if isSentinelDescriptor(dsc) {
 		return handler.HandleErrorf(file.NodeInfo(node.GetFieldTypeNode()), "%s %s: unknown type %s; resolved to %s which is not defined; consider using a leading dot", kind, f.fqn, fld.GetTypeName(), dsc.FullName())
 	}
+	if err := r.checkVisibility(node.GetFieldTypeNode(), kind+" "+f.fqn, dsc, handler); err != nil {
+		return err
+	}
 	switch dsc := dsc.(type) {
 	case protoreflect.MessageDescriptor:
 		if dsc.IsMapEntry() {
@@ -484,10 +508,11 @@ func resolveMethodTypes(m *mtdDescriptor, handler *reporter.Handler, scopes []sc
 	dsc := r.resolve(ast.NewNodeReference(file, node.GetInput()), mtd.GetInputType(), false, scopes, checkedCache)
 	if dsc == nil {
 		if err := handler.HandleErrorWithPos(file.NodeInfo(node.GetInput()), &errUndeclaredName{
-			scope:      kind + " " + m.fqn,
-			what:       "request type",
-			name:       mtd.GetInputType(),
-			parentFile: file,
+			scope:       kind + " " + m.fqn,
+			what:        "request type",
+			name:        mtd.GetInputType(),
+			suggestions: r.suggestNames(mtd.GetInputType(), isSuggestedMessage),
+			parentFile:  file,
 		}); err != nil {
 			return err
 		}
@@ -500,6 +525,9 @@ func resolveMethodTypes(m *mtdDescriptor, handler *reporter.Handler, scopes []sc
 			return err
 		}
 	} else {
+		if err := r.checkVisibility(node.GetInput(), kind+" "+m.fqn, dsc, handler); err != nil {
+			return err
+		}
 		typeName := "." + string(dsc.FullName())
 		if mtd.GetInputType() != typeName {
 			mtd.InputType = proto.String(typeName)
@@ -511,10 +539,11 @@ func resolveMethodTypes(m *mtdDescriptor, handler *reporter.Handler, scopes []sc
 	dsc = r.resolve(ast.NewNodeReference(file, node.GetOutput()), mtd.GetOutputType(), false, scopes, checkedCache)
 	if dsc == nil {
 		if err := handler.HandleErrorWithPos(file.NodeInfo(node.GetOutput()), &errUndeclaredName{
-			scope:      kind + " " + m.fqn,
-			what:       "response type",
-			name:       mtd.GetOutputType(),
-			parentFile: file,
+			scope:       kind + " " + m.fqn,
+			what:        "response type",
+			name:        mtd.GetOutputType(),
+			suggestions: r.suggestNames(mtd.GetOutputType(), isSuggestedMessage),
+			parentFile:  file,
 		}); err != nil {
 			return err
 		}
@@ -527,6 +556,9 @@ func resolveMethodTypes(m *mtdDescriptor, handler *reporter.Handler, scopes []sc
 			return err
 		}
 	} else {
+		if err := r.checkVisibility(node.GetOutput(), kind+" "+m.fqn, dsc, handler); err != nil {
+			return err
+		}
 		typeName := "." + string(dsc.FullName())
 		if mtd.GetOutputType() != typeName {
 			mtd.OutputType = proto.String(typeName)
@@ -546,24 +578,31 @@ func (r *result) resolveOptions(handler *reporter.Handler, elemType string, elem
 	file := r.FileNode()
 opts:
 	for _, opt := range opts {
+		// the message that the next name part is a field or extension of,
+		// which is only used for suggestions and may be nil if not known yet
+		msg := optionsMessages[elemType]
 		// resolve any extension names found in option names
 		for _, nm := range opt.Name {
-			if nm.GetIsExtension() {
-				node := r.OptionNamePartNode(nm)
-				desc, err := r.resolveExtensionName(ast.NewNodeReference(file, node), nm.GetNamePart(), scopes, checkedCache)
-				if err != nil {
-					if err := handler.HandleErrorWithPos(file.NodeInfo(node), &errUndeclaredName{
-						scope:      mc.String(),
-						what:       "extension",
-						name:       nm.GetNamePart(),
-						parentFile: file,
-					}); err != nil {
-						return err
-					}
-					continue opts
+			if !nm.GetIsExtension() {
+				msg = fieldMessage(msg, nm.GetNamePart())
+				continue
+			}
+			node := r.OptionNamePartNode(nm)
+			desc, err := r.resolveExtensionName(ast.NewNodeReference(file, node), nm.GetNamePart(), scopes, checkedCache)
+			if err != nil {
+				if err := handler.HandleErrorWithPos(file.NodeInfo(node), &errUndeclaredName{
+					scope:       mc.String(),
+					what:        "extension",
+					name:        nm.GetNamePart(),
+					suggestions: r.suggestNames(nm.GetNamePart(), extensionOf(msg)),
+					parentFile:  file,
+				}); err != nil {
+					return err
 				}
-				nm.NamePart = proto.String("." + string(desc.FullName()))
+				continue opts
 			}
+			nm.NamePart = proto.String("." + string(desc.FullName()))
+			msg = desc.Message()
 		}
 		// also resolve any extension names found inside message literals in option values
 		mc.Option = opt
@@ -571,7 +610,7 @@ opts:
 		if optNode == nil || optNode.IsIncomplete() {
 			continue
 		}
-		if err := r.resolveOptionValue(handler, mc, optNode.Val, scopes, checkedCache); err != nil {
+		if err := r.resolveOptionValue(handler, mc, optNode.Val, msg, scopes, checkedCache); err != nil {
 			return err
 		}
 		mc.Option = nil
@@ -579,7 +618,10 @@ opts:
 	return nil
 }
 
-func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal.MessageContext, val *ast.ValueNode, scopes []scope, checkedCache []string) error {
+// resolveOptionValue resolves the extension names in message literals in the
+// given option value. The msg is the type of the value, if known, which is
+// used only for suggestions.
+func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal.MessageContext, val *ast.ValueNode, msg protoreflect.MessageDescriptor, scopes []scope, checkedCache []string) error {
 	optVal := val.Value()
 	switch optVal := optVal.(type) {
 	case []*ast.ValueNode:
@@ -589,7 +631,7 @@ func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal
 		}()
 		for i, v := range optVal {
 			mc.OptAggPath = fmt.Sprintf("%s[%d]", origPath, i)
-			if err := r.resolveOptionValue(handler, mc, v, scopes, checkedCache); err != nil {
+			if err := r.resolveOptionValue(handler, mc, v, msg, scopes, checkedCache); err != nil {
 				return err
 			}
 		}
@@ -602,6 +644,8 @@ func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal
 			if fld.IsIncomplete() {
 				continue
 			}
+			// the type of the field's value, which is only used for suggestions
+			var fldMsg protoreflect.MessageDescriptor
 			// check for extension name
 			if fld.Name.IsExtension() {
 				// Confusingly, an extension reference inside a message literal cannot refer to
@@ -614,17 +658,21 @@ func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal
 				desc, err := r.resolveExtensionName(ast.NewNodeReference(r.FileNode(), fld.Name.Name), string(fld.Name.Name.AsIdentifier()), scopes, checkedCache)
 				if err != nil {
 					if err := handler.HandleErrorWithPos(r.FileNode().NodeInfo(fld.Name.Name), &errUndeclaredName{
-						scope:      mc.String(),
-						what:       "extension",
-						name:       string(fld.Name.Name.AsIdentifier()),
-						parentFile: r.FileNode(),
+						scope:       mc.String(),
+						what:        "extension",
+						name:        string(fld.Name.Name.AsIdentifier()),
+						suggestions: r.suggestNames(string(fld.Name.Name.AsIdentifier()), extensionOf(msg)),
+						parentFile:  r.FileNode(),
 					}); err != nil {
 						return err
 					}
 					continue
 				} else {
 					r.optionQualifiedNames[fld.Name.Name] = "." + string(desc.FullName())
+					fldMsg = desc.Message()
 				}
+			} else {
+				fldMsg = fieldMessage(msg, string(fld.Name.Name.AsIdentifier()))
 			}
 
 			// recurse into value
@@ -638,7 +686,7 @@ func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal
 				mc.OptAggPath = fmt.Sprintf("%s%s", mc.OptAggPath, string(fld.Name.Name.AsIdentifier()))
 			}
 
-			if err := r.resolveOptionValue(handler, mc, fld.Val, scopes, checkedCache); err != nil {
+			if err := r.resolveOptionValue(handler, mc, fld.Val, fldMsg, scopes, checkedCache); err != nil {
 				return err
 			}
 		}
@@ -647,6 +695,14 @@ func (r *result) resolveOptionValue(handler *reporter.Handler, mc *protointernal
 }
 
 func (r *result) indexCompoundIdentRefs(fullIdent *ast.CompoundIdentNode, desc protoreflect.Descriptor) {
+	rangeCompoundIdentRefs(fullIdent, desc, func(to protoreflect.Descriptor, node ast.Node) {
+		r.resolvedReferences[to] = append(r.resolvedReferences[to], ast.NewNodeReference(r.AST(), node))
+	})
+}
+
+// rangeCompoundIdentRefs calls fn for each reference in the given compound
+// identifier, which refers to desc, to desc or one of its parents.
+func rangeCompoundIdentRefs(fullIdent *ast.CompoundIdentNode, desc protoreflect.Descriptor, fn func(to protoreflect.Descriptor, node ast.Node)) {
 	componentIdx := len(fullIdent.Components) - 1
 
 	for componentIdx >= 0 && desc != nil {
@@ -655,10 +711,9 @@ func (r *result) indexCompoundIdentRefs(fullIdent *ast.CompoundIdentNode, desc p
 			case protoreflect.FileDescriptor:
 				break
 			case protoreflect.MessageDescriptor:
-				r.resolvedReferences[desc] = append(r.resolvedReferences[desc], ast.NewNodeReference(r.AST(), ident))
+				fn(desc, ident)
 			case protoreflect.ExtensionTypeDescriptor:
-				fld := desc.Descriptor()
-				r.resolvedReferences[fld] = append(r.resolvedReferences[fld], ast.NewNodeReference(r.AST(), fullIdent))
+				fn(desc.Descriptor(), fullIdent)
 			}
 		}
 		desc = desc.Parent()
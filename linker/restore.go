@@ -0,0 +1,426 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	art "github.com/kralicky/go-adaptive-radix-tree"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/sourceinfo"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// Restore creates a result from a descriptor proto that was already linked,
+// such as one previously obtained from a Result and saved to disk. Unlike
+// Link, nothing is resolved or validated: every type reference in fd must be
+// fully-qualified and refer to an element defined in fd or in one of the
+// given dependencies, and options must already be interpreted. The given
+// dependencies must correspond to the descriptor's imports, in the exact
+// order they are present in fd. If a reference cannot be found, an error is
+// returned.
+//
+// If optionIndexes is not nil, it must have been returned by
+// MarshalOptionIndexes for a result for the same file. Its entries refer to
+// AST nodes by position, so they are only attached to the result, and
+// returned by its OptionIndex and OptionDescriptorIndex methods, once its AST
+// is loaded with Result.ReloadAST. Of the given options, only WithASTLoader
+// has any effect.
+func Restore(fd *descriptorpb.FileDescriptorProto, deps Files, optionIndexes []byte, opts ...LinkOption) (Result, error) {
+	var lo linkOptions
+	for _, opt := range opts {
+		opt(&lo)
+	}
+	if len(fd.Dependency) != len(deps) {
+		return nil, fmt.Errorf("%s: descriptor has %d imports but %d dependencies were given", fd.GetName(), len(fd.Dependency), len(deps))
+	}
+	for i, dep := range deps {
+		if dep.Path() != fd.Dependency[i] {
+			return nil, fmt.Errorf("%s: import %q does not match dependency %q", fd.GetName(), fd.Dependency[i], dep.Path())
+		}
+	}
+	prefix := fd.GetPackage()
+	if prefix != "" {
+		prefix += "."
+	}
+	r := &result{
+		FileDescriptor:       noOpFile,
+		Result:               parser.ResultWithoutAST(fd),
+		deps:                 deps,
+		descriptors:          art.New[protoreflect.Descriptor](),
+		usedImports:          map[string]struct{}{},
+		prefix:               prefix,
+		astLoader:            lo.astLoader,
+		optionQualifiedNames: map[*ast.IdentValueNode]string{},
+		resolvedReferences:   map[protoreflect.Descriptor][]ast.NodeReference{},
+		extensionsByMessage:  map[protoreflect.FullName][]protoreflect.ExtensionDescriptor{},
+	}
+	if optionIndexes != nil {
+		r.restoredIndexes = &savedOptionIndexes{}
+		if err := gob.NewDecoder(bytes.NewReader(optionIndexes)).Decode(r.restoredIndexes); err != nil {
+			return nil, fmt.Errorf("%s: failed to read option indexes: %w", fd.GetName(), err)
+		}
+	}
+	r.createDescendants(nil)
+	if err := r.bindReferences(); err != nil {
+		return nil, err
+	}
+	r.populateExtensionRefs()
+	srcLocProtos := asSourceLocations(fd.GetSourceCodeInfo().GetLocation())
+	r.srcLocations = srcLocs{file: r, locs: srcLocProtos, index: computeSourceLocIndex(srcLocProtos)}
+	return r, nil
+}
+
+// bindReferences sets the types, extendees, and oneofs of all fields and the
+// request and response types of all methods from their fully-qualified names
+// in the descriptor proto. It is the counterpart of resolveReferences for
+// descriptors that were already linked.
+func (r *result) bindReferences() error {
+	checkedCache := make([]string, 0, 16)
+	find := func(what string, fqn protoreflect.FullName, name string) (protoreflect.Descriptor, error) {
+		d := r.resolveElement(protoreflect.FullName(name), checkedCache)
+		if d == nil || isSentinelDescriptor(d) {
+			return nil, fmt.Errorf("%s: %s: unknown %s %s", r.Path(), fqn, what, name)
+		}
+		return d, nil
+	}
+	bindField := func(f *fldDescriptor) error {
+		fld := f.proto
+		if fld.GetExtendee() != "" {
+			d, err := find("extendee", f.FullName(), fld.GetExtendee())
+			if err != nil {
+				return err
+			}
+			extd, ok := d.(protoreflect.MessageDescriptor)
+			if !ok {
+				return fmt.Errorf("%s: %s: extendee %s is %s, not a message", r.Path(), f.FullName(), d.FullName(), descriptorTypeWithArticle(d))
+			}
+			f.extendee = extd
+		} else if fld.OneofIndex != nil {
+			f.oneof = f.parent.(protoreflect.MessageDescriptor).Oneofs().Get(int(fld.GetOneofIndex())) //nolint:errcheck
+		}
+		if fld.GetTypeName() == "" {
+			return nil
+		}
+		d, err := find("type", f.FullName(), fld.GetTypeName())
+		if err != nil {
+			return err
+		}
+		switch d := d.(type) {
+		case protoreflect.MessageDescriptor:
+			f.msgType = d
+		case protoreflect.EnumDescriptor:
+			f.enumType = d
+		default:
+			return fmt.Errorf("%s: %s: type %s is %s, not a message or enum", r.Path(), f.FullName(), d.FullName(), descriptorTypeWithArticle(d))
+		}
+		return nil
+	}
+	bindMethodType := func(m *mtdDescriptor, what string, name string) (protoreflect.MessageDescriptor, error) {
+		d, err := find(what, m.FullName(), name)
+		if err != nil {
+			return nil, err
+		}
+		msg, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s: %s %s is %s, not a message", r.Path(), m.FullName(), what, d.FullName(), descriptorTypeWithArticle(d))
+		}
+		return msg, nil
+	}
+	return walk.Descriptors(r, func(d protoreflect.Descriptor) error {
+		var err error
+		switch d := d.(type) {
+		case *extTypeDescriptor:
+			err = bindField(&d.field)
+		case *fldDescriptor:
+			err = bindField(d)
+		case *mtdDescriptor:
+			if d.inputType, err = bindMethodType(d, "request type", d.proto.GetInputType()); err != nil {
+				return err
+			}
+			d.outputType, err = bindMethodType(d, "response type", d.proto.GetOutputType())
+		}
+		return err
+	})
+}
+
+// MarshalOptionIndexes serializes the option indexes of the given result,
+// which are returned by its OptionIndex and OptionDescriptorIndex methods, so
+// that they can be given to Restore along with the result's descriptor proto.
+// AST nodes are identified by their position, so the indexes can only be used
+// with an AST parsed from the same source. Entries keyed by uninterpreted
+// options are not included, since options are already interpreted by the
+// time a result can be restored.
+//
+// This returns nil if the result has no option indexes.
+func MarshalOptionIndexes(res Result) ([]byte, error) {
+	r, ok := res.(*result)
+	if !ok {
+		return nil, nil
+	}
+	saved := r.restoredIndexes
+	if saved == nil {
+		if r.optsIndex == nil && r.optsDescIndex.FieldReferenceNodesToFieldDescriptors == nil {
+			return nil, nil
+		}
+		saved = saveOptionIndexes(r.optsIndex, r.optsDescIndex)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(saved); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// savedOptionIndexes is the serialized form of a result's option indexes. See
+// MarshalOptionIndexes.
+type savedOptionIndexes struct {
+	Options         []savedOption
+	FieldRefs       []savedReference
+	EnumValueRefs   []savedReference
+	TypeURLRefs     []savedReference
+	MessageLiterals []savedReference
+	AnyValues       []savedAnyValue
+	FieldDefaults   []savedFieldDefault
+}
+
+type savedNode struct {
+	Kind       string
+	Start, End ast.Token
+}
+
+// Kinds of option children in savedOptionInfo.
+const (
+	savedNoChildren = iota
+	savedArrayChildren
+	savedMessageChildren
+)
+
+type savedOptionInfo struct {
+	Path         []int32
+	ChildrenKind int
+	Elements     []savedOptionInfo
+	Fields       []savedOption
+}
+
+// savedOption is the info for an option node, or for a message field node in
+// the Fields of a savedOptionInfo.
+type savedOption struct {
+	Node savedNode
+	Info savedOptionInfo
+}
+
+type savedReference struct {
+	Node savedNode
+	Name protoreflect.FullName
+}
+
+type savedAnyValue struct {
+	Node    savedNode
+	TypeURL string
+	Value   []byte
+}
+
+type savedFieldDefault struct {
+	Field protoreflect.FullName
+	// The "default" pseudo-option, or nil if the value is implicit.
+	Option *savedNode
+}
+
+func saveNode(node ast.Node) savedNode {
+	key := keyForNode(node)
+	return savedNode{Kind: string(key.kind), Start: key.start, End: key.end}
+}
+
+func (n savedNode) compare(other savedNode) int {
+	if c := cmp.Compare(n.Start, other.Start); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(n.End, other.End); c != 0 {
+		return c
+	}
+	return cmp.Compare(n.Kind, other.Kind)
+}
+
+func (n savedNode) key() nodeKey {
+	return nodeKey{kind: protoreflect.FullName(n.Kind), start: n.Start, end: n.End}
+}
+
+func saveOptionIndexes(optsIndex sourceinfo.OptionIndex, descIndex sourceinfo.OptionDescriptorIndex) *savedOptionIndexes {
+	saved := &savedOptionIndexes{}
+	for node, info := range optsIndex {
+		saved.Options = append(saved.Options, savedOption{Node: saveNode(node), Info: saveOptionInfo(info)})
+	}
+	for node, fld := range descIndex.FieldReferenceNodesToFieldDescriptors {
+		saved.FieldRefs = append(saved.FieldRefs, savedReference{Node: saveNode(node), Name: fld.FullName()})
+	}
+	for node, val := range descIndex.EnumValueIdentNodesToEnumValueDescriptors {
+		saved.EnumValueRefs = append(saved.EnumValueRefs, savedReference{Node: saveNode(node), Name: val.FullName()})
+	}
+	for node, msg := range descIndex.TypeReferenceURLsToMessageDescriptors {
+		saved.TypeURLRefs = append(saved.TypeURLRefs, savedReference{Node: saveNode(node), Name: msg.FullName()})
+	}
+	for node, msg := range descIndex.MessageLiteralsToMessageDescriptors {
+		saved.MessageLiterals = append(saved.MessageLiterals, savedReference{Node: saveNode(node), Name: msg.FullName()})
+	}
+	for node, val := range descIndex.TypeReferenceURLsToAnyValues {
+		saved.AnyValues = append(saved.AnyValues, savedAnyValue{Node: saveNode(node), TypeURL: val.GetTypeUrl(), Value: val.GetValue()})
+	}
+	for name, def := range descIndex.FieldDefaults {
+		sd := savedFieldDefault{Field: name}
+		if def.Option != nil {
+			node := saveNode(def.Option)
+			sd.Option = &node
+		}
+		saved.FieldDefaults = append(saved.FieldDefaults, sd)
+	}
+
+	// sort everything, so that the same indexes are always saved the same way
+	slices.SortFunc(saved.Options, compareSavedOptions)
+	for _, refs := range [][]savedReference{saved.FieldRefs, saved.EnumValueRefs, saved.TypeURLRefs, saved.MessageLiterals} {
+		slices.SortFunc(refs, func(a, b savedReference) int {
+			return a.Node.compare(b.Node)
+		})
+	}
+	slices.SortFunc(saved.AnyValues, func(a, b savedAnyValue) int {
+		return a.Node.compare(b.Node)
+	})
+	slices.SortFunc(saved.FieldDefaults, func(a, b savedFieldDefault) int {
+		return cmp.Compare(a.Field, b.Field)
+	})
+	return saved
+}
+
+func compareSavedOptions(a, b savedOption) int {
+	return a.Node.compare(b.Node)
+}
+
+func saveOptionInfo(info *sourceinfo.OptionSourceInfo) savedOptionInfo {
+	saved := savedOptionInfo{Path: info.Path}
+	switch children := info.Children.(type) {
+	case *sourceinfo.ArrayLiteralSourceInfo:
+		saved.ChildrenKind = savedArrayChildren
+		for i := range children.Elements {
+			saved.Elements = append(saved.Elements, saveOptionInfo(&children.Elements[i]))
+		}
+	case *sourceinfo.MessageLiteralSourceInfo:
+		saved.ChildrenKind = savedMessageChildren
+		for node, fieldInfo := range children.Fields {
+			saved.Fields = append(saved.Fields, savedOption{Node: saveNode(node), Info: saveOptionInfo(fieldInfo)})
+		}
+		slices.SortFunc(saved.Fields, compareSavedOptions)
+	}
+	return saved
+}
+
+// restore returns the option indexes for the AST whose nodes are given,
+// looking up descriptors by name in the given file and its dependencies, or
+// in the global registry.
+// Entries whose nodes or descriptors cannot be found are omitted.
+func (s *savedOptionIndexes) restore(file File, nodes map[nodeKey]ast.Node) (sourceinfo.OptionIndex, sourceinfo.OptionDescriptorIndex) {
+	closure := ComputeReflexiveTransitiveClosure(Files{file})
+	findDescriptor := func(name protoreflect.FullName) protoreflect.Descriptor {
+		for _, f := range closure {
+			if d := f.FindDescriptorByName(name); d != nil {
+				return d
+			}
+		}
+		// options may refer to elements of descriptor.proto without
+		// importing it
+		d, _ := protoregistry.GlobalFiles.FindDescriptorByName(name)
+		return d
+	}
+
+	optsIndex := sourceinfo.OptionIndex{}
+	for _, opt := range s.Options {
+		if node, ok := nodes[opt.Node.key()].(*ast.OptionNode); ok {
+			optsIndex[node] = opt.Info.restore(nodes)
+		}
+	}
+	descIndex := sourceinfo.NewOptionDescriptorIndex()
+	for _, ref := range s.FieldRefs {
+		if fld, ok := findDescriptor(ref.Name).(protoreflect.FieldDescriptor); ok && nodes[ref.Node.key()] != nil {
+			descIndex.FieldReferenceNodesToFieldDescriptors[nodes[ref.Node.key()]] = fld
+		}
+	}
+	for _, ref := range s.EnumValueRefs {
+		node, nodeOk := nodes[ref.Node.key()].(*ast.IdentNode)
+		if val, ok := findDescriptor(ref.Name).(protoreflect.EnumValueDescriptor); ok && nodeOk {
+			descIndex.EnumValueIdentNodesToEnumValueDescriptors[node] = val
+		}
+	}
+	for _, ref := range s.TypeURLRefs {
+		node, nodeOk := nodes[ref.Node.key()].(*ast.FieldReferenceNode)
+		if msg, ok := findDescriptor(ref.Name).(protoreflect.MessageDescriptor); ok && nodeOk {
+			descIndex.TypeReferenceURLsToMessageDescriptors[node] = msg
+		}
+	}
+	for _, ref := range s.MessageLiterals {
+		node, nodeOk := nodes[ref.Node.key()].(*ast.MessageLiteralNode)
+		if msg, ok := findDescriptor(ref.Name).(protoreflect.MessageDescriptor); ok && nodeOk {
+			descIndex.MessageLiteralsToMessageDescriptors[node] = msg
+		}
+	}
+	for _, val := range s.AnyValues {
+		if node, ok := nodes[val.Node.key()].(*ast.FieldReferenceNode); ok {
+			descIndex.TypeReferenceURLsToAnyValues[node] = &anypb.Any{TypeUrl: val.TypeURL, Value: val.Value}
+		}
+	}
+	for _, def := range s.FieldDefaults {
+		fld, ok := findDescriptor(def.Field).(protoreflect.FieldDescriptor)
+		if !ok {
+			continue
+		}
+		// The value is the same as the one parsed from the field's
+		// default_value, which the interpreter computed it from.
+		fieldDefault := sourceinfo.FieldDefault{Value: fld.Default()}
+		if def.Option != nil {
+			fieldDefault.Option, _ = nodes[def.Option.key()].(*ast.OptionNode)
+		}
+		descIndex.FieldDefaults[def.Field] = fieldDefault
+	}
+	return optsIndex, descIndex
+}
+
+func (s savedOptionInfo) restore(nodes map[nodeKey]ast.Node) *sourceinfo.OptionSourceInfo {
+	info := &sourceinfo.OptionSourceInfo{Path: s.Path}
+	switch s.ChildrenKind {
+	case savedArrayChildren:
+		children := &sourceinfo.ArrayLiteralSourceInfo{Elements: make([]sourceinfo.OptionSourceInfo, len(s.Elements))}
+		for i, elem := range s.Elements {
+			children.Elements[i] = *elem.restore(nodes)
+		}
+		info.Children = children
+	case savedMessageChildren:
+		children := &sourceinfo.MessageLiteralSourceInfo{Fields: make(map[*ast.MessageFieldNode]*sourceinfo.OptionSourceInfo, len(s.Fields))}
+		for _, field := range s.Fields {
+			if node, ok := nodes[field.Node.key()].(*ast.MessageFieldNode); ok {
+				children.Fields[node] = field.Info.restore(nodes)
+			}
+		}
+		info.Children = children
+	}
+	return info
+}
@@ -0,0 +1,244 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"cmp"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// SchemaCounts counts the elements of a schema and the sizes of their
+// serialized descriptors. See Files.SchemaStats.
+type SchemaCounts struct {
+	// Messages include groups but not the synthetic entry messages of map
+	// fields. Fields include the fields of messages but not extensions.
+	Messages, Fields, Oneofs, Enums, EnumValues, Extensions, Services, Methods int
+	// The greatest depth of nested messages, where top-level messages have a
+	// depth of one.
+	MaxNestingDepth int
+	// The size of the serialized file descriptor protos, not including source
+	// code info.
+	DescriptorBytes int
+	// The part of DescriptorBytes used by the options of all elements.
+	OptionBytes int
+	// The size of the serialized source code info.
+	SourceInfoBytes int
+}
+
+// Add returns the sum of c and other. The nesting depth is the greater of
+// the two.
+func (c SchemaCounts) Add(other SchemaCounts) SchemaCounts {
+	return SchemaCounts{
+		Messages:        c.Messages + other.Messages,
+		Fields:          c.Fields + other.Fields,
+		Oneofs:          c.Oneofs + other.Oneofs,
+		Enums:           c.Enums + other.Enums,
+		EnumValues:      c.EnumValues + other.EnumValues,
+		Extensions:      c.Extensions + other.Extensions,
+		Services:        c.Services + other.Services,
+		Methods:         c.Methods + other.Methods,
+		MaxNestingDepth: max(c.MaxNestingDepth, other.MaxNestingDepth),
+		DescriptorBytes: c.DescriptorBytes + other.DescriptorBytes,
+		OptionBytes:     c.OptionBytes + other.OptionBytes,
+		SourceInfoBytes: c.SourceInfoBytes + other.SourceInfoBytes,
+	}
+}
+
+// MessageSize is the size of a message's serialized descriptor proto, which
+// includes its nested declarations.
+type MessageSize struct {
+	Message protoreflect.MessageDescriptor
+	Bytes   int
+}
+
+// FileSchemaStats are the statistics for a single file.
+type FileSchemaStats struct {
+	File File
+	SchemaCounts
+	// The file's largest messages, largest first.
+	LargestMessages []MessageSize
+}
+
+// PackageSchemaStats are the statistics for all files in a package.
+type PackageSchemaStats struct {
+	// The name of the package. This is empty for files that do not declare
+	// a package.
+	Name protoreflect.FullName
+	// The files that declare the package, sorted by path.
+	Files Files
+	SchemaCounts
+	// The package's largest messages, largest first.
+	LargestMessages []MessageSize
+}
+
+// SchemaStats are statistics about the elements and descriptor sizes of a
+// set of files, for capacity planning and for finding out what makes a
+// descriptor set large.
+type SchemaStats struct {
+	// The statistics for each file, sorted by path.
+	Files []FileSchemaStats
+	// The statistics for each package, sorted by name.
+	Packages []PackageSchemaStats
+	// The totals for all files.
+	Total SchemaCounts
+	// The largest messages in all files, largest first.
+	LargestMessages []MessageSize
+}
+
+// SchemaStats computes statistics for the files in f, grouped by file and by
+// package. At most n of the largest messages are listed for each file, each
+// package, and in total. Placeholder files are ignored.
+func (f Files) SchemaStats(n int) SchemaStats {
+	var stats SchemaStats
+	var all []MessageSize
+	byName := map[protoreflect.FullName]int{}
+	var pkgMessages [][]MessageSize
+	for _, file := range f {
+		if file.IsPlaceholder() {
+			continue
+		}
+		counts, messages := fileSchemaCounts(file)
+		stats.Files = append(stats.Files, FileSchemaStats{
+			File:            file,
+			SchemaCounts:    counts,
+			LargestMessages: largestMessages(messages, n),
+		})
+		stats.Total = stats.Total.Add(counts)
+		all = append(all, messages...)
+
+		i, ok := byName[file.Package()]
+		if !ok {
+			i = len(stats.Packages)
+			byName[file.Package()] = i
+			stats.Packages = append(stats.Packages, PackageSchemaStats{Name: file.Package()})
+			pkgMessages = append(pkgMessages, nil)
+		}
+		pkg := &stats.Packages[i]
+		pkg.Files = append(pkg.Files, file)
+		pkg.SchemaCounts = pkg.SchemaCounts.Add(counts)
+		pkgMessages[i] = append(pkgMessages[i], messages...)
+	}
+	for i := range stats.Packages {
+		slices.SortFunc(stats.Packages[i].Files, compareFilePaths)
+		stats.Packages[i].LargestMessages = largestMessages(pkgMessages[i], n)
+	}
+	slices.SortFunc(stats.Files, func(a, b FileSchemaStats) int {
+		return compareFilePaths(a.File, b.File)
+	})
+	slices.SortFunc(stats.Packages, func(a, b PackageSchemaStats) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	stats.LargestMessages = largestMessages(all, n)
+	return stats
+}
+
+// fileSchemaCounts returns the counts for the given file, along with the sizes
+// of all of its messages.
+func fileSchemaCounts(file protoreflect.FileDescriptor) (SchemaCounts, []MessageSize) {
+	var counts SchemaCounts
+	var messages []MessageSize
+	var countEnums func(enums protoreflect.EnumDescriptors)
+	countEnums = func(enums protoreflect.EnumDescriptors) {
+		counts.Enums += enums.Len()
+		for i, l := 0, enums.Len(); i < l; i++ {
+			counts.EnumValues += enums.Get(i).Values().Len()
+		}
+	}
+	var countMessages func(msgs protoreflect.MessageDescriptors, depth int)
+	countMessages = func(msgs protoreflect.MessageDescriptors, depth int) {
+		for i, l := 0, msgs.Len(); i < l; i++ {
+			md := msgs.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+			counts.Messages++
+			counts.MaxNestingDepth = max(counts.MaxNestingDepth, depth)
+			counts.Fields += md.Fields().Len()
+			counts.Oneofs += md.Oneofs().Len()
+			counts.Extensions += md.Extensions().Len()
+			messages = append(messages, MessageSize{
+				Message: md,
+				Bytes:   proto.Size(protoutil.ProtoFromMessageDescriptor(md)),
+			})
+			countEnums(md.Enums())
+			countMessages(md.Messages(), depth+1)
+		}
+	}
+	countMessages(file.Messages(), 1)
+	countEnums(file.Enums())
+	counts.Extensions += file.Extensions().Len()
+	counts.Services = file.Services().Len()
+	for i, l := 0, file.Services().Len(); i < l; i++ {
+		counts.Methods += file.Services().Get(i).Methods().Len()
+	}
+
+	fd := protoutil.ProtoFromFileDescriptor(file)
+	counts.SourceInfoBytes = proto.Size(fd.GetSourceCodeInfo())
+	counts.DescriptorBytes = proto.Size(fd) - counts.SourceInfoBytes
+	if fd.SourceCodeInfo != nil {
+		// the source code info field's tag and length prefix
+		counts.DescriptorBytes -= protowire.SizeTag(9) + protowire.SizeVarint(uint64(counts.SourceInfoBytes))
+	}
+	counts.OptionBytes = optionBytes(fd.ProtoReflect())
+	return counts, messages
+}
+
+// optionBytes returns the size of the serialized options messages in the
+// given descriptor proto and the descriptor protos nested in it, including the
+// tags and length prefixes of the options fields.
+func optionBytes(msg protoreflect.Message) int {
+	var size int
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind || fd.Name() == "source_code_info" {
+			return true
+		}
+		if fd.Name() == "options" {
+			size += protowire.SizeTag(fd.Number()) + protowire.SizeBytes(proto.Size(v.Message().Interface()))
+			return true
+		}
+		if fd.IsList() {
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				size += optionBytes(list.Get(i).Message())
+			}
+		} else if !fd.IsMap() {
+			size += optionBytes(v.Message())
+		}
+		return true
+	})
+	return size
+}
+
+// largestMessages returns the n largest of the given messages, largest first.
+// Messages of the same size are sorted by name.
+func largestMessages(messages []MessageSize, n int) []MessageSize {
+	if n <= 0 || len(messages) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(messages)
+	slices.SortFunc(sorted, func(a, b MessageSize) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Message.FullName(), b.Message.FullName())
+	})
+	return sorted[:min(n, len(sorted))]
+}
@@ -0,0 +1,121 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+func TestFilesSchemaStats(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `
+			syntax = "proto3";
+			package foo;
+			option go_package = "example.com/foo";
+			message A {
+				message B {
+					message C {
+						string s = 1 [deprecated = true];
+					}
+				}
+				map<string, B> m = 1;
+				oneof o {
+					string x = 2;
+					int32 y = 3;
+				}
+			}
+			enum E { E_ZERO = 0; E_ONE = 1; }
+			`,
+		"b.proto": `
+			syntax = "proto3";
+			package foo;
+			import "a.proto";
+			service S {
+				rpc M(A) returns (A);
+				rpc N(A) returns (A);
+			}
+			`,
+		"c.proto": `
+			syntax = "proto2";
+			package bar;
+			message D {
+				extensions 10 to 20;
+				extend D { optional string ext = 10; }
+			}
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), "c.proto", "b.proto", "a.proto")
+	require.NoError(t, err)
+
+	stats := files.SchemaStats(2)
+	require.Len(t, stats.Files, 3)
+	a := stats.Files[0]
+	assert.Equal(t, "a.proto", a.File.Path())
+	assert.Equal(t, 3, a.Messages)
+	assert.Equal(t, 4, a.Fields)
+	assert.Equal(t, 1, a.Oneofs)
+	assert.Equal(t, 1, a.Enums)
+	assert.Equal(t, 2, a.EnumValues)
+	assert.Equal(t, 3, a.MaxNestingDepth)
+	fd := protoutil.ProtoFromFileDescriptor(a.File)
+	assert.Equal(t, proto.Size(fd), a.DescriptorBytes+a.SourceInfoBytes+3)
+	assert.Greater(t, a.SourceInfoBytes, 0)
+	optionsSize := proto.Size(fd.GetOptions()) + 2 +
+		// deprecated = true, and map_entry = true
+		2*(2+2)
+	assert.Equal(t, optionsSize, a.OptionBytes)
+	require.Len(t, a.LargestMessages, 2)
+	assert.Equal(t, protoreflect.FullName("foo.A"), a.LargestMessages[0].Message.FullName())
+	assert.Equal(t, proto.Size(fd.GetMessageType()[0]), a.LargestMessages[0].Bytes)
+	assert.Equal(t, protoreflect.FullName("foo.A.B"), a.LargestMessages[1].Message.FullName())
+
+	b := stats.Files[1]
+	assert.Equal(t, 1, b.Services)
+	assert.Equal(t, 2, b.Methods)
+	assert.Empty(t, b.LargestMessages)
+
+	c := stats.Files[2]
+	assert.Equal(t, 1, c.Extensions)
+	assert.Equal(t, 1, c.MaxNestingDepth)
+
+	require.Len(t, stats.Packages, 2)
+	assert.Equal(t, protoreflect.FullName("bar"), stats.Packages[0].Name)
+	foo := stats.Packages[1]
+	assert.Equal(t, []string{"a.proto", "b.proto"}, filePaths(foo.Files))
+	assert.Equal(t, a.SchemaCounts.Add(b.SchemaCounts), foo.SchemaCounts)
+	assert.Equal(t, a.LargestMessages, foo.LargestMessages)
+
+	assert.Equal(t, 4, stats.Total.Messages)
+	assert.Equal(t, a.DescriptorBytes+b.DescriptorBytes+c.DescriptorBytes, stats.Total.DescriptorBytes)
+	assert.Len(t, stats.LargestMessages, 2)
+	assert.Empty(t, linker.Files(nil).SchemaStats(2).Files)
+}
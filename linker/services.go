@@ -0,0 +1,120 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// MethodInfo describes an RPC method, with its request and response types
+// resolved.
+type MethodInfo struct {
+	Method  protoreflect.MethodDescriptor
+	Service protoreflect.ServiceDescriptor
+	// The request and response message types.
+	Input, Output protoreflect.MessageDescriptor
+	// Whether the client and server send streams of messages.
+	ClientStreaming, ServerStreaming bool
+	// The method's options, with custom options interpreted. Never nil.
+	Options *descriptorpb.MethodOptions
+	// The span of the method's name in source. This is an unknown span if
+	// neither the file's AST nor its source code info is available.
+	Span ast.SourceSpan
+}
+
+// Option returns the value of the custom method option with the given
+// fully-qualified name, such as "google.api.http", and whether the option is
+// set. Options are looked up by name, so this works even if the caller does
+// not link in generated code for the option. Standard options can be read
+// directly from Options.
+func (m MethodInfo) Option(name protoreflect.FullName) (protoreflect.Value, bool) {
+	return findOptionByName(m.Options.ProtoReflect(), name)
+}
+
+// Methods returns all RPC methods of all services in f, ordered by file, then
+// by service and method declaration order. Placeholder files are ignored.
+func (f Files) Methods() []MethodInfo {
+	var methods []MethodInfo
+	for _, file := range f {
+		if file.IsPlaceholder() {
+			continue
+		}
+		for i := 0; i < file.Services().Len(); i++ {
+			svc := file.Services().Get(i)
+			for j := 0; j < svc.Methods().Len(); j++ {
+				mtd := svc.Methods().Get(j)
+				opts, _ := mtd.Options().(*descriptorpb.MethodOptions)
+				if opts == nil {
+					opts = &descriptorpb.MethodOptions{}
+				}
+				methods = append(methods, MethodInfo{
+					Method:          mtd,
+					Service:         svc,
+					Input:           mtd.Input(),
+					Output:          mtd.Output(),
+					ClientStreaming: mtd.IsStreamingClient(),
+					ServerStreaming: mtd.IsStreamingServer(),
+					Options:         opts,
+					Span:            methodSpan(file, mtd),
+				})
+			}
+		}
+	}
+	return methods
+}
+
+// methodSpan returns the span of the given method's name, using the file's AST
+// if available and its source code info otherwise.
+func methodSpan(file File, mtd protoreflect.MethodDescriptor) ast.SourceSpan {
+	if res, ok := file.(Result); ok && res.AST() != nil {
+		if mtdProto, ok := protoutil.ProtoFromDescriptor(mtd).(*descriptorpb.MethodDescriptorProto); ok {
+			if node := res.MethodNode(mtdProto); node != nil {
+				return res.FileNode().NodeInfo(node.Name)
+			}
+		}
+	}
+	path, ok := descpath.ForDescriptor(mtd)
+	if !ok {
+		return ast.UnknownSpan(file.Path())
+	}
+	loc := file.SourceLocations().ByPath(append(path, descpath.MethodNameTag))
+	if protointernal.IsZeroSourceLocation(loc) {
+		return ast.UnknownSpan(file.Path())
+	}
+	// source locations are zero-based
+	return ast.NewSourceSpan(
+		ast.SourcePos{Filename: file.Path(), Line: loc.StartLine + 1, Col: loc.StartColumn + 1},
+		ast.SourcePos{Filename: file.Path(), Line: loc.EndLine + 1, Col: loc.EndColumn + 1},
+	)
+}
+
+func findOptionByName(opts protoreflect.Message, name protoreflect.FullName) (protoreflect.Value, bool) {
+	var result protoreflect.Value
+	var found bool
+	opts.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.FullName() == name {
+			result, found = v, true
+			return false
+		}
+		return true
+	})
+	return result, found
+}
@@ -0,0 +1,101 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+)
+
+func TestFilesMethods(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"http.proto": `
+			syntax = "proto3";
+			package example.api;
+			import "google/protobuf/descriptor.proto";
+			message HttpRule { string get = 1; string post = 2; }
+			extend google.protobuf.MethodOptions { HttpRule http = 72295728; }
+			`,
+		"svc.proto": `
+			syntax = "proto3";
+			package foo;
+			import "http.proto";
+			message Req {}
+			message Resp {}
+			service Foo {
+				rpc Get(Req) returns (Resp) {
+					option (example.api.http).get = "/v1/foo";
+				}
+				rpc Watch(Req) returns (stream Resp);
+			}
+			service Bar {
+				rpc Upload(stream Req) returns (Resp) { option deprecated = true; }
+			}
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), "svc.proto")
+	require.NoError(t, err)
+	methods := files.Methods()
+	require.Len(t, methods, 3)
+	// with the AST, the span is the same
+	compiler.RetainASTs = true
+	compiler.SourceInfoMode = protocompile.SourceInfoNone
+	files, err = compiler.Compile(context.Background(), "svc.proto")
+	require.NoError(t, err)
+	assert.Equal(t, methods[0].Span.Start().String(), files.Methods()[0].Span.Start().String())
+
+	methods = files.Methods()
+	require.Len(t, methods, 3)
+
+	get := methods[0]
+	assert.Equal(t, protoreflect.FullName("foo.Foo.Get"), get.Method.FullName())
+	assert.Equal(t, protoreflect.FullName("foo.Foo"), get.Service.FullName())
+	assert.Equal(t, protoreflect.FullName("foo.Req"), get.Input.FullName())
+	assert.Equal(t, protoreflect.FullName("foo.Resp"), get.Output.FullName())
+	assert.False(t, get.ClientStreaming)
+	assert.False(t, get.ServerStreaming)
+	assert.Equal(t, "svc.proto:8:9", get.Span.Start().String())
+	assert.Equal(t, "svc.proto:8:12", get.Span.End().String())
+	http, ok := get.Option("example.api.http")
+	require.True(t, ok)
+	rule := http.Message()
+	assert.Equal(t, "/v1/foo", rule.Get(rule.Descriptor().Fields().ByName("get")).String())
+
+	watch := methods[1]
+	assert.Equal(t, protoreflect.FullName("foo.Foo.Watch"), watch.Method.FullName())
+	assert.False(t, watch.ClientStreaming)
+	assert.True(t, watch.ServerStreaming)
+	_, ok = watch.Option("example.api.http")
+	assert.False(t, ok)
+
+	upload := methods[2]
+	assert.Equal(t, protoreflect.FullName("foo.Bar.Upload"), upload.Method.FullName())
+	assert.True(t, upload.ClientStreaming)
+	assert.False(t, upload.ServerStreaming)
+	assert.True(t, upload.Options.GetDeprecated())
+}
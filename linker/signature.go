@@ -0,0 +1,159 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// OptionSignature describes the option field that is being assigned at a
+// position in an option name or value, for signature help in editors.
+type OptionSignature struct {
+	// The option that contains the queried position.
+	Option *ast.OptionNode
+	// The innermost node that refers to Field and contains the queried
+	// position. This is a part of the option's name, a field in a message
+	// literal in the option's value, or the option itself when the position
+	// is in its value but not in a message literal field.
+	Node ast.Node
+	// The field that is being assigned.
+	Field protoreflect.FieldDescriptor
+	// The field's type, as it would be written in a field declaration, such
+	// as "repeated string", "map<string, int32>", or "foo.bar.Baz".
+	Type string
+	// The message that contains the field. For a field in an option name, this
+	// is the options message, such as google.protobuf.FieldOptions, or the
+	// message that is the type of the preceding part of the name.
+	Options protoreflect.MessageDescriptor
+	// The kinds of elements on which the field may be used as an option. This
+	// is empty if the field does not restrict its targets.
+	Targets []descriptorpb.FieldOptions_OptionTargetType
+	// The field's leading comments, or its trailing comments if it has no
+	// leading comments. This is empty if the file that declares the field
+	// has no source code info.
+	Documentation string
+}
+
+// OptionSignatureAt returns the signature of the option field that is being
+// assigned at the given position in res, which may be in an option's name or
+// value. It returns nil if the position is not in an option, if the field is
+// not known (for example, because options have not been interpreted), if the
+// option is the json_name or default pseudo-option, or if res has no AST.
+func OptionSignatureAt(res Result, pos ast.SourcePos) *OptionSignature {
+	file := res.AST()
+	if file == nil {
+		return nil
+	}
+	var sig *OptionSignature
+	ast.Inspect(file, func(node ast.Node) bool {
+		if !nodeContains(file, node, pos) {
+			return true
+		}
+		// visited outermost first, so the last match is the innermost
+		switch node := node.(type) {
+		case *ast.OptionNode:
+			if isPseudoOption(node) {
+				return false
+			}
+			parts := node.GetName().FilterFieldReferences()
+			if len(parts) == 0 {
+				return true
+			}
+			if fld := res.FindFieldDescriptorByFieldReferenceNode(parts[len(parts)-1]); fld != nil {
+				sig = &OptionSignature{Option: node, Node: node, Field: fld}
+			}
+		case *ast.FieldReferenceNode:
+			if fld := res.FindFieldDescriptorByFieldReferenceNode(node); fld != nil && sig != nil {
+				sig.Node, sig.Field = node, fld
+			}
+		case *ast.MessageFieldNode:
+			if fld := res.FindFieldDescriptorByMessageFieldNode(node); fld != nil && sig != nil {
+				sig.Node, sig.Field = node, fld
+			}
+		}
+		return true
+	})
+	if sig == nil {
+		return nil
+	}
+
+	fld := sig.Field
+	sig.Type = fieldTypeString(fld)
+	sig.Options = fld.ContainingMessage()
+	if opts, ok := fld.Options().(*descriptorpb.FieldOptions); ok {
+		sig.Targets = opts.GetTargets()
+	}
+	loc := fld.ParentFile().SourceLocations().ByDescriptor(fld)
+	sig.Documentation = loc.LeadingComments
+	if sig.Documentation == "" {
+		sig.Documentation = loc.TrailingComments
+	}
+	return sig
+}
+
+// nodeContains reports whether pos is in the span of node, including the
+// position just after its end.
+func nodeContains(file *ast.FileNode, node ast.Node, pos ast.SourcePos) bool {
+	info := file.NodeInfo(node)
+	if !info.IsValid() {
+		return false
+	}
+	start, end := info.Start(), info.End()
+	if pos.Line < start.Line || (pos.Line == start.Line && pos.Col < start.Col) {
+		return false
+	}
+	return pos.Line < end.Line || (pos.Line == end.Line && pos.Col <= end.Col)
+}
+
+// isPseudoOption reports whether opt is the json_name or default option of a
+// field, which are stored in the field's descriptor instead of its options.
+func isPseudoOption(opt *ast.OptionNode) bool {
+	parts := opt.GetName().FilterFieldReferences()
+	if len(parts) != 1 || parts[0].IsExtension() {
+		return false
+	}
+	switch parts[0].GetName().AsIdentifier() {
+	case "json_name", "default":
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldTypeString returns the type of fld as it would be written in a field
+// declaration.
+func fieldTypeString(fld protoreflect.FieldDescriptor) string {
+	if fld.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldTypeString(fld.MapKey()), fieldTypeString(fld.MapValue()))
+	}
+	var typ string
+	switch {
+	case fld.Message() != nil:
+		typ = string(fld.Message().FullName())
+	case fld.Enum() != nil:
+		typ = string(fld.Enum().FullName())
+	default:
+		typ = fld.Kind().String()
+	}
+	if fld.IsList() {
+		return "repeated " + typ
+	}
+	return typ
+}
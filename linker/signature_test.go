@@ -0,0 +1,94 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestOptionSignatureAt(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto3";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Rule {
+  // The rule's name.
+  string name = 1;
+  map<string, int32> weights = 2;
+}
+extend google.protobuf.FieldOptions {
+  // Rules that apply to the field.
+  repeated Rule rules = 50000 [targets = TARGET_TYPE_FIELD];
+}
+message Msg {
+  string id = 1 [(rules) = { name: "x" weights { key: "a" value: 1 } }, json_name = "ID"];
+}`,
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+		RetainASTs:     true,
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	require.NoError(t, err)
+	res, ok := files.Files[0].(linker.Result)
+	require.True(t, ok)
+
+	// in the option name
+	sig := linker.OptionSignatureAt(res, ast.SourcePos{Line: 14, Col: 21})
+	require.NotNil(t, sig)
+	assert.Equal(t, "foo.rules", string(sig.Field.FullName()))
+	assert.Equal(t, "repeated foo.Rule", sig.Type)
+	assert.Equal(t, "google.protobuf.FieldOptions", string(sig.Options.FullName()))
+	assert.Equal(t, []descriptorpb.FieldOptions_OptionTargetType{descriptorpb.FieldOptions_TARGET_TYPE_FIELD}, sig.Targets)
+	assert.Equal(t, " Rules that apply to the field.\n", sig.Documentation)
+
+	// in a message literal field in the value
+	sig = linker.OptionSignatureAt(res, ast.SourcePos{Line: 14, Col: 37})
+	require.NotNil(t, sig)
+	assert.Equal(t, "foo.Rule.name", string(sig.Field.FullName()))
+	assert.Equal(t, "string", sig.Type)
+	assert.Equal(t, "foo.Rule", string(sig.Options.FullName()))
+	assert.Empty(t, sig.Targets)
+	assert.Equal(t, " The rule's name.\n", sig.Documentation)
+
+	// in the value of a map field
+	sig = linker.OptionSignatureAt(res, ast.SourcePos{Line: 14, Col: 42})
+	require.NotNil(t, sig)
+	assert.Equal(t, "foo.Rule.weights", string(sig.Field.FullName()))
+	assert.Equal(t, "map<string, int32>", sig.Type)
+
+	// in the value, between literal fields
+	sig = linker.OptionSignatureAt(res, ast.SourcePos{Line: 14, Col: 29})
+	require.NotNil(t, sig)
+	assert.Equal(t, "foo.rules", string(sig.Field.FullName()))
+	assert.Same(t, sig.Option, sig.Node)
+
+	// pseudo-option and outside of options
+	assert.Nil(t, linker.OptionSignatureAt(res, ast.SourcePos{Line: 14, Col: 75}))
+	assert.Nil(t, linker.OptionSignatureAt(res, ast.SourcePos{Line: 14, Col: 5}))
+}
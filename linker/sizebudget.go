@@ -0,0 +1,198 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// DefaultSizeContributors is the number of contributors listed in size budget
+// diagnostics when SizeBudget.Contributors is zero.
+const DefaultSizeContributors = 3
+
+// SizeBudget configures limits on the serialized size of file descriptors,
+// for example for descriptor sets that are embedded in mobile applications.
+// Budgets that are zero are not enforced.
+type SizeBudget struct {
+	// How budgets that are exceeded are reported. The checks are off by
+	// default.
+	Level CheckLevel
+	// The largest allowed size, in bytes, of each serialized file descriptor
+	// proto.
+	MaxFileBytes int
+	// The largest allowed size, in bytes, of a serialized descriptor set
+	// that contains all of the files.
+	MaxSetBytes int
+	// If true, sizes include source code info. Otherwise, they are the sizes
+	// of the descriptors with source code info stripped.
+	IncludeSourceInfo bool
+	// If true, the descriptor set also contains the dependencies of the
+	// files, like a descriptor set that is built with imports included.
+	// Dependencies are only counted towards MaxSetBytes.
+	IncludeDependencies bool
+	// The number of the largest contributors to list in diagnostics. If zero,
+	// DefaultSizeContributors is used.
+	Contributors int
+}
+
+// SizeContributor is an element that contributes to the size of a file
+// descriptor: a top-level message, enum, extension, or service, or the
+// options of any element, including the file.
+type SizeContributor struct {
+	Descriptor protoreflect.Descriptor
+	// True if the contributor is the descriptor's options rather than the
+	// descriptor itself.
+	Options bool
+	// The serialized size of the descriptor proto or options message.
+	Bytes int
+}
+
+func (c SizeContributor) String() string {
+	what := strings.SplitN(descriptorTypeWithArticle(c.Descriptor), " ", 2)[1]
+	name := string(c.Descriptor.FullName())
+	if fd, ok := c.Descriptor.(protoreflect.FileDescriptor); ok {
+		name = fd.Path()
+	}
+	if c.Options {
+		return fmt.Sprintf("options of %s %s (%d bytes)", what, name, c.Bytes)
+	}
+	return fmt.Sprintf("%s %s (%d bytes)", what, name, c.Bytes)
+}
+
+// SizeContributors returns the elements of the given file that contribute to
+// the size of its descriptor, largest first. Top-level elements include their
+// nested elements and options, so the options of an element may also be
+// listed separately, to attribute the size of large option values.
+func SizeContributors(file protoreflect.FileDescriptor) []SizeContributor {
+	var contributors []SizeContributor
+	add := func(d protoreflect.Descriptor) {
+		if _, ok := d.(protoreflect.FileDescriptor); !ok {
+			if _, ok := d.Parent().(protoreflect.FileDescriptor); ok {
+				contributors = append(contributors, SizeContributor{
+					Descriptor: d,
+					Bytes:      proto.Size(protoutil.ProtoFromDescriptor(d)),
+				})
+			}
+		}
+		if opts := d.Options(); opts != nil {
+			if size := proto.Size(opts); size > 0 {
+				contributors = append(contributors, SizeContributor{Descriptor: d, Options: true, Bytes: size})
+			}
+		}
+	}
+	add(file)
+	_ = walk.Descriptors(file, func(d protoreflect.Descriptor) error {
+		if md, ok := d.(protoreflect.MessageDescriptor); !ok || !md.IsMapEntry() {
+			add(d)
+		}
+		return nil
+	})
+	slices.SortStableFunc(contributors, func(a, b SizeContributor) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+	return contributors
+}
+
+// CheckSizeBudget reports files whose serialized descriptors exceed the
+// budget's MaxFileBytes, and reports if a descriptor set containing all of
+// the files, and optionally their dependencies, would exceed MaxSetBytes.
+// Diagnostics for a file list its largest contributors, and the diagnostic
+// for the set lists its largest files. If any error is reported and the
+// handler does not suppress it, this function returns a non-nil error.
+func CheckSizeBudget(files Files, handler *reporter.Handler, budget SizeBudget) error {
+	if budget.Level == CheckLevelOff || (budget.MaxFileBytes <= 0 && budget.MaxSetBytes <= 0) {
+		return nil
+	}
+	n := budget.Contributors
+	if n <= 0 {
+		n = DefaultSizeContributors
+	}
+	report := func(span ast.SourceSpan, format string, args ...interface{}) error {
+		if budget.Level == CheckLevelWarn {
+			handler.HandleWarningf(span, format, args...)
+			return nil
+		}
+		return handler.HandleErrorf(span, format, args...)
+	}
+
+	type fileSize struct {
+		file  File
+		bytes int
+	}
+	var sizes []fileSize
+	var setBytes int
+	set := files
+	if budget.IncludeDependencies {
+		set = ComputeReflexiveTransitiveClosure(files)
+	}
+	for _, file := range set {
+		if file.IsPlaceholder() {
+			continue
+		}
+		counts, _ := fileSchemaCounts(file)
+		size := counts.DescriptorBytes
+		if budget.IncludeSourceInfo && counts.SourceInfoBytes > 0 {
+			size += protowire.SizeTag(9) + protowire.SizeBytes(counts.SourceInfoBytes)
+		}
+		sizes = append(sizes, fileSize{file: file, bytes: size})
+		// the file field of FileDescriptorSet
+		setBytes += protowire.SizeTag(1) + protowire.SizeBytes(size)
+	}
+
+	if budget.MaxFileBytes > 0 {
+		for _, s := range sizes {
+			if s.bytes <= budget.MaxFileBytes || (budget.IncludeDependencies && files.FindFileByPath(s.file.Path()) == nil) {
+				continue
+			}
+			contributors := SizeContributors(s.file)
+			var names []string
+			for _, c := range contributors[:min(n, len(contributors))] {
+				names = append(names, c.String())
+			}
+			if err := report(ast.UnknownSpan(s.file.Path()), "descriptor is %d bytes, which exceeds the budget of %d bytes; largest contributors: %s",
+				s.bytes, budget.MaxFileBytes, strings.Join(names, ", ")); err != nil {
+				return err
+			}
+		}
+	}
+
+	if budget.MaxSetBytes <= 0 || setBytes <= budget.MaxSetBytes || len(sizes) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(sizes, func(a, b fileSize) int {
+		if c := cmp.Compare(b.bytes, a.bytes); c != 0 {
+			return c
+		}
+		return compareFilePaths(a.file, b.file)
+	})
+	var names []string
+	for _, s := range sizes[:min(n, len(sizes))] {
+		names = append(names, fmt.Sprintf("%s (%d bytes)", s.file.Path(), s.bytes))
+	}
+	return report(ast.UnknownSpan(sizes[0].file.Path()), "descriptor set is %d bytes, which exceeds the budget of %d bytes; largest files: %s",
+		setBytes, budget.MaxSetBytes, strings.Join(names, ", "))
+}
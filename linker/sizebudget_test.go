@@ -0,0 +1,101 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestCheckSizeBudget(t *testing.T) {
+	t.Parallel()
+	var values strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&values, "VALUE_%d = %d;\n", i, i)
+	}
+	sources := map[string]string{
+		"a.proto": `
+			syntax = "proto3";
+			package foo;
+			import "google/protobuf/descriptor.proto";
+			extend google.protobuf.MessageOptions {
+				string doc = 50000;
+			}
+			message A {
+				option (doc) = "` + strings.Repeat("x", 500) + `";
+				string s = 1;
+			}
+			enum Big {
+			` + values.String() + `
+			}
+			`,
+		"b.proto": `
+			syntax = "proto3";
+			package foo;
+			message B {}
+			`,
+	}
+	compile := func(budget linker.SizeBudget) ([]string, []string, error) {
+		var errs, warnings []string
+		compiler := &protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(sources),
+			}),
+			Reporter: reporter.NewReporter(
+				func(err reporter.ErrorWithPos) error {
+					errs = append(errs, err.Error())
+					return nil
+				},
+				func(err reporter.ErrorWithPos) {
+					warnings = append(warnings, err.Error())
+				},
+			),
+			SizeBudget: budget,
+		}
+		_, err := compiler.Compile(context.Background(), "a.proto", "b.proto")
+		return errs, warnings, err
+	}
+
+	errs, _, err := compile(linker.SizeBudget{Level: linker.CheckLevelError, MaxFileBytes: 1000})
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	require.Len(t, errs, 1)
+	assert.Regexp(t, `^a\.proto: descriptor is \d+ bytes, which exceeds the budget of 1000 bytes; largest contributors: `+
+		`enum foo\.Big \(\d+ bytes\), message foo\.A \(\d+ bytes\), options of message foo\.A \(50\d bytes\)$`, errs[0])
+
+	_, warnings, err := compile(linker.SizeBudget{Level: linker.CheckLevelWarn, MaxSetBytes: 1000, Contributors: 1})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Regexp(t, `^a\.proto: descriptor set is \d+ bytes, which exceeds the budget of 1000 bytes; largest files: a\.proto \(\d+ bytes\)$`, warnings[0])
+
+	// descriptor.proto is much larger than both files
+	_, warnings, err = compile(linker.SizeBudget{Level: linker.CheckLevelWarn, MaxFileBytes: 5000, MaxSetBytes: 5000, IncludeDependencies: true})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Regexp(t, `^google/protobuf/descriptor\.proto: descriptor set is \d+ bytes, .* largest files: google/protobuf/descriptor\.proto \(\d+ bytes\), a\.proto`, warnings[0])
+
+	errs, warnings, err = compile(linker.SizeBudget{Level: linker.CheckLevelError, MaxFileBytes: 5000, MaxSetBytes: 5000})
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,103 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/protointernal"
+)
+
+// suggestNames returns names of elements that are visible to r and that are
+// plausibly what was meant by the given name, which could not be resolved.
+// Only elements for which accept returns true are considered. Candidates are
+// matched by simple name, so the returned names may also fix an incorrect
+// qualifier. They are relative to r's package when possible.
+func (r *result) suggestNames(name string, accept func(protoreflect.Descriptor) bool) []string {
+	pkgPrefix := string(r.Package()) + "."
+	var results []string
+	for _, fullName := range protointernal.SuggestVisible(r, name, accept) {
+		name := string(fullName)
+		if r.Package() != "" && strings.HasPrefix(name, pkgPrefix) {
+			name = name[len(pkgPrefix):]
+		}
+		results = append(results, name)
+	}
+	return results
+}
+
+// isSuggestedMessage reports whether d is a message that can be suggested as
+// the type of a reference. Map entries cannot be referred to by name.
+func isSuggestedMessage(d protoreflect.Descriptor) bool {
+	md, ok := d.(protoreflect.MessageDescriptor)
+	return ok && !md.IsMapEntry()
+}
+
+// isSuggestedType reports whether d is a message or enum that can be
+// suggested as the type of a field.
+func isSuggestedType(d protoreflect.Descriptor) bool {
+	_, isEnum := d.(protoreflect.EnumDescriptor)
+	return isEnum || isSuggestedMessage(d)
+}
+
+func isExtension(d protoreflect.Descriptor) bool {
+	fd, ok := d.(protoreflect.FieldDescriptor)
+	return ok && fd.IsExtension()
+}
+
+// extensionOf returns a function that reports whether d is an extension of
+// the given message, so that it can be suggested as an extension name in an
+// option. If the message is not known, any extension is accepted.
+func extensionOf(extendee protoreflect.MessageDescriptor) func(d protoreflect.Descriptor) bool {
+	if extendee == nil {
+		return isExtension
+	}
+	return func(d protoreflect.Descriptor) bool {
+		fd, ok := d.(protoreflect.FieldDescriptor)
+		return ok && fd.IsExtension() && fd.ContainingMessage().FullName() == extendee.FullName()
+	}
+}
+
+// optionsMessages are the options messages for each of the element types
+// whose options are resolved, by the names used for them in error messages.
+var optionsMessages = map[string]protoreflect.MessageDescriptor{
+	"file":            (*descriptorpb.FileOptions)(nil).ProtoReflect().Descriptor(),
+	"message":         (*descriptorpb.MessageOptions)(nil).ProtoReflect().Descriptor(),
+	"extension range": (*descriptorpb.ExtensionRangeOptions)(nil).ProtoReflect().Descriptor(),
+	"extension":       (*descriptorpb.FieldOptions)(nil).ProtoReflect().Descriptor(),
+	"field":           (*descriptorpb.FieldOptions)(nil).ProtoReflect().Descriptor(),
+	"oneof":           (*descriptorpb.OneofOptions)(nil).ProtoReflect().Descriptor(),
+	"enum":            (*descriptorpb.EnumOptions)(nil).ProtoReflect().Descriptor(),
+	"enum value":      (*descriptorpb.EnumValueOptions)(nil).ProtoReflect().Descriptor(),
+	"service":         (*descriptorpb.ServiceOptions)(nil).ProtoReflect().Descriptor(),
+	"method":          (*descriptorpb.MethodOptions)(nil).ProtoReflect().Descriptor(),
+}
+
+// fieldMessage returns the message type of the field of md with the given
+// name, which may also be the name of a group's message type. It returns nil
+// if md is nil, if there is no such field, or if its type is not a message or
+// has not been resolved yet.
+func fieldMessage(md protoreflect.MessageDescriptor, name string) protoreflect.MessageDescriptor {
+	if md == nil {
+		return nil
+	}
+	if fld := md.Fields().ByTextName(name); fld != nil {
+		return fld.Message()
+	}
+	return nil
+}
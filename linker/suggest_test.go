@@ -0,0 +1,141 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestUndeclaredNameSuggestions(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto3";
+package foo;
+import "b.proto";
+import "google/protobuf/descriptor.proto";
+message Request {
+  Reqest nested = 1;
+  Color color = 2;
+  Size size = 3;
+}
+service Svc {
+  rpc Do(Requst) returns (bar.Respons);
+}
+extend google.protobuf.FieldOptions { string label = 50000; }
+message Labeled {
+  string s = 1 [(lable) = "x"];
+}`,
+		"b.proto": `syntax = "proto3";
+package bar;
+message Response {}
+enum Colour { COLOUR_UNSET = 0; }
+message Nested {
+  enum Size { SIZE_UNSET = 0; }
+}`,
+	}
+	var errs []reporter.ErrorWithPos
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err)
+				return nil
+			},
+			nil,
+		),
+	}
+	_, err := compiler.Compile(context.Background(), "a.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	var actual []string
+	for _, err := range errs {
+		actual = append(actual, err.Error())
+	}
+	assert.Equal(t, []string{
+		`a.proto:11:26-39: method foo.Svc.Do: unknown response type bar.Respons; did you mean bar.Response?`,
+		`a.proto:11:9-17: method foo.Svc.Do: unknown request type Requst; did you mean Request?`,
+		`a.proto:15:17-24: field foo.Labeled.s: : unknown extension lable; did you mean label?`,
+		`a.proto:15:17-24: unrecognized extension lable of google.protobuf.FieldOptions; did you mean foo.label?`,
+		`a.proto:6:3-9: field foo.Request.nested: unknown type Reqest; did you mean Request?`,
+		`a.proto:7:3-8: field foo.Request.color: unknown type Color; did you mean bar.Colour?`,
+		`a.proto:8:3-7: field foo.Request.size: unknown type Size; did you mean bar.Nested.Size?`,
+	}, actual)
+
+	var undeclared linker.ErrorUndeclaredName
+	require.ErrorAs(t, errs[1], &undeclared)
+	assert.Equal(t, "Requst", undeclared.UndeclaredName())
+	assert.Equal(t, []string{"Request"}, undeclared.Suggestions())
+}
+
+func TestExtensionSuggestionsMatchExtendee(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "b.proto";
+message M {
+  optional string a = 1 [(note) = "x"];
+  optional string b = 2 [(info).(note) = "x"];
+  optional string c = 3 [(info) = { [foo.note]: "x" }];
+}`,
+		"b.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Info {
+  optional string text = 1;
+  extensions 100 to 200;
+}
+extend Info { optional string notes = 100; }
+extend google.protobuf.FieldOptions {
+  optional Info info = 50000;
+  optional string nots = 50001;
+}`,
+	}
+	var errs []string
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			},
+			nil,
+		),
+	}
+	_, err := compiler.Compile(context.Background(), "a.proto")
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Equal(t, []string{
+		`a.proto:5:26-32: field foo.M.a: : unknown extension note; did you mean nots?`,
+		`a.proto:6:33-39: field foo.M.b: : unknown extension note; did you mean notes?`,
+		`a.proto:7:38-46: field foo.M.c: option (foo.info): : unknown extension foo.note; did you mean notes?`,
+		`a.proto:5:26-32: unrecognized extension note of google.protobuf.FieldOptions; did you mean foo.nots?`,
+	}, errs)
+}
@@ -15,6 +15,7 @@
 package linker
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"maps"
@@ -25,6 +26,7 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
 	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/protoutil"
 	"github.com/kralicky/protocompile/reporter"
@@ -43,15 +45,28 @@ type Symbols struct {
 	filesMu sync.RWMutex
 	files   map[string]fileEntry
 	pkgTrie packageSymbols
+	names   *protointernal.Interner
 }
 
 func NewSymbolTable() *Symbols {
 	return &Symbols{
 		files:   make(map[string]fileEntry),
 		pkgTrie: *newPackageSymbols("", nil),
+		names:   protointernal.NewInterner(),
 	}
 }
 
+// Names returns the interner used to de-duplicate fully-qualified names of
+// all elements linked using this symbol table. Clones of a symbol table share
+// the same interner. Names that are no longer used are released as files are
+// deleted from the table.
+func (s *Symbols) Names() *protointernal.Interner {
+	if s == nil {
+		return nil
+	}
+	return s.names
+}
+
 type packageSymbols struct {
 	fqn    protoreflect.FullName
 	parent *packageSymbols
@@ -109,8 +124,15 @@ func (ps *packageSymbols) MarshalText() string {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
 
+	// output is sorted so that it does not depend on map iteration order
+	symNames := make([]protoreflect.FullName, 0, len(ps.symbols))
+	for symName := range ps.symbols {
+		symNames = append(symNames, symName)
+	}
+	slices.Sort(symNames)
 	symbolCount := 0
-	for symName, sym := range ps.symbols {
+	for _, symName := range symNames {
+		sym := ps.symbols[symName]
 		if sym.isPackage {
 			fmt.Fprintf(&buf, "symbol (package): %s [@%s]\n", symName, sym.span)
 			continue
@@ -118,10 +140,26 @@ func (ps *packageSymbols) MarshalText() string {
 		symbolCount++
 	}
 	fmt.Fprintf(&buf, "symbols (regular): %d\n", symbolCount)
-	for ext, span := range ps.exts {
-		fmt.Fprintf(&buf, "extension: %s [@%s]\n", ext.extendee.Name(), span.Start())
+	exts := make([]extNumber, 0, len(ps.exts))
+	for ext := range ps.exts {
+		exts = append(exts, ext)
 	}
-	for pkgName, pkg := range ps.children {
+	slices.SortFunc(exts, func(a, b extNumber) int {
+		if c := cmp.Compare(a.extendee, b.extendee); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.tag, b.tag)
+	})
+	for _, ext := range exts {
+		fmt.Fprintf(&buf, "extension: %s [@%s]\n", ext.extendee.Name(), ps.exts[ext].Start())
+	}
+	pkgNames := make([]protoreflect.FullName, 0, len(ps.children))
+	for pkgName := range ps.children {
+		pkgNames = append(pkgNames, pkgName)
+	}
+	slices.Sort(pkgNames)
+	for _, pkgName := range pkgNames {
+		pkg := ps.children[pkgName]
 		pkg.mu.RLock()
 		fmt.Fprintf(&buf, " > package: %s\n", pkgName)
 		pkg.mu.RUnlock()
@@ -159,6 +197,7 @@ func (s *Symbols) Clone() *Symbols {
 	return &Symbols{
 		pkgTrie: *s.pkgTrie.clone(nil),
 		files:   maps.Clone(s.files),
+		names:   s.names,
 	}
 }
 
@@ -270,11 +309,18 @@ func (u *SymbolCollisionError) EntangledPackages() []string {
 }
 
 // Deletes all symbols associated with the given file descriptor.
+//
+// This also starts a new generation of the table's name interner (see Names),
+// so that names of deleted elements are eventually released.
 func (s *Symbols) Delete(fd protoreflect.FileDescriptor, handler *reporter.Handler) error {
 	if s == nil {
 		return nil
 	}
+	s.names.NextGeneration()
+	return s.delete(fd, handler)
+}
 
+func (s *Symbols) delete(fd protoreflect.FileDescriptor, handler *reporter.Handler) error {
 	if f, ok := fd.(*file); ok {
 		// unwrap any file instance
 		fd = f.FileDescriptor
@@ -285,7 +331,7 @@ func (s *Symbols) Delete(fd protoreflect.FileDescriptor, handler *reporter.Handl
 		if imp.IsPlaceholder() {
 			continue
 		}
-		if err := s.Delete(imp.FileDescriptor, handler); err != nil {
+		if err := s.delete(imp.FileDescriptor, handler); err != nil {
 			if !errors.Is(err, ErrFileStillInUse) {
 				return err
 			}
@@ -486,7 +532,7 @@ func (ps *packageSymbols) checkFileLocked(f protoreflect.FileDescriptor, handler
 }
 
 func sourceSpanForPackage(fd protoreflect.FileDescriptor) ast.SourceSpan {
-	loc := fd.SourceLocations().ByPath([]int32{protointernal.FilePackageTag})
+	loc := fd.SourceLocations().ByPath([]int32{descpath.FilePackageTag})
 	if protointernal.IsZeroSourceLocation(loc) {
 		return ast.UnknownSpan(fd.Path())
 	}
@@ -509,7 +555,7 @@ func sourceSpanFor(d protoreflect.Descriptor) ast.SourceSpan {
 	if file == nil {
 		return ast.UnknownSpan(unknownFilePath)
 	}
-	path, ok := protointernal.ComputeSourcePath(d)
+	path, ok := descpath.ForDescriptor(d)
 	if !ok {
 		return ast.UnknownSpan(file.Path())
 	}
@@ -520,19 +566,19 @@ func sourceSpanFor(d protoreflect.Descriptor) ast.SourceSpan {
 	namePath := path
 	switch d.(type) {
 	case protoreflect.FieldDescriptor:
-		namePath = append(namePath, protointernal.FieldNameTag)
+		namePath = append(namePath, descpath.FieldNameTag)
 	case protoreflect.MessageDescriptor:
-		namePath = append(namePath, protointernal.MessageNameTag)
+		namePath = append(namePath, descpath.MessageNameTag)
 	case protoreflect.OneofDescriptor:
-		namePath = append(namePath, protointernal.OneofNameTag)
+		namePath = append(namePath, descpath.OneofNameTag)
 	case protoreflect.EnumDescriptor:
-		namePath = append(namePath, protointernal.EnumNameTag)
+		namePath = append(namePath, descpath.EnumNameTag)
 	case protoreflect.EnumValueDescriptor:
-		namePath = append(namePath, protointernal.EnumValNameTag)
+		namePath = append(namePath, descpath.EnumValNameTag)
 	case protoreflect.ServiceDescriptor:
-		namePath = append(namePath, protointernal.ServiceNameTag)
+		namePath = append(namePath, descpath.ServiceNameTag)
 	case protoreflect.MethodDescriptor:
-		namePath = append(namePath, protointernal.MethodNameTag)
+		namePath = append(namePath, descpath.MethodNameTag)
 	default:
 		// NB: shouldn't really happen, but just in case fall back to path to
 		// descriptor, sans name field
@@ -563,12 +609,12 @@ func sourceSpanForNumber(fd protoreflect.FieldDescriptor) ast.SourceSpan {
 	if file == nil {
 		return ast.UnknownSpan(unknownFilePath)
 	}
-	path, ok := protointernal.ComputeSourcePath(fd)
+	path, ok := descpath.ForDescriptor(fd)
 	if !ok {
 		return ast.UnknownSpan(file.Path())
 	}
 	numberPath := path
-	numberPath = append(numberPath, protointernal.FieldNumberTag)
+	numberPath = append(numberPath, descpath.FieldNumberTag)
 	loc := file.SourceLocations().ByPath(numberPath)
 	if protointernal.IsZeroSourceLocation(loc) {
 		loc = file.SourceLocations().ByPath(path)
@@ -836,6 +882,8 @@ func (s *Symbols) AddExtension(pkg, extendee protoreflect.FullName, tag protoref
 // not been seen/registered, nil is returned.
 func (s *Symbols) Lookup(name protoreflect.FullName) ast.SourceSpan {
 	if pkgSyms := s.getPackage(name.Parent()); pkgSyms != nil {
+		pkgSyms.mu.RLock()
+		defer pkgSyms.mu.RUnlock()
 		if entry, ok := pkgSyms.symbols[name]; ok {
 			return entry.span
 		}
@@ -847,6 +895,8 @@ func (s *Symbols) Lookup(name protoreflect.FullName) ast.SourceSpan {
 // extension has not been seen/registered, nil is returned.
 func (s *Symbols) LookupExtension(messageName protoreflect.FullName, extensionNumber protoreflect.FieldNumber) ast.SourceSpan {
 	if pkgSyms := s.getPackage(messageName.Parent()); pkgSyms != nil {
+		pkgSyms.mu.RLock()
+		defer pkgSyms.mu.RUnlock()
 		if entry, ok := pkgSyms.exts[extNumber{messageName, extensionNumber}]; ok {
 			return entry
 		}
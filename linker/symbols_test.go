@@ -15,8 +15,10 @@
 package linker
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -281,6 +283,36 @@ func TestImportAndDelete(t *testing.T) {
 	ts.run()
 }
 
+func TestSymbolsDeleteReleasesNames(t *testing.T) {
+	t.Parallel()
+	symbols := NewSymbolTable()
+	link := func(pkg string) Result {
+		h := reporter.NewHandler(nil)
+		source := "syntax = \"proto3\";\npackage " + pkg + ";\nmessage Foo {\n  string bar = 1;\n}\n"
+		fileNode, err := parser.Parse(pkg+".proto", strings.NewReader(source), h, 0, parser.WithNameInterner(symbols.Names()))
+		require.NoError(t, err)
+		res, err := parser.ResultFromAST(fileNode, true, h)
+		require.NoError(t, err)
+		linked, err := Link(res, nil, symbols, h)
+		require.NoError(t, err)
+		return linked
+	}
+
+	linked := link("pkg0")
+	// parsed identifiers share storage with interned names
+	name := linked.FileDescriptorProto().GetMessageType()[0].GetName()
+	assert.Equal(t, unsafe.StringData(name), unsafe.StringData(symbols.Names().Intern("Foo")))
+	initial := symbols.Names().Len()
+	require.NoError(t, symbols.Delete(linked, reporter.NewHandler(nil)))
+
+	// names of deleted files do not accumulate
+	for i := 1; i <= 10; i++ {
+		linked := link(fmt.Sprintf("pkg%d", i))
+		require.NoError(t, symbols.Delete(linked, reporter.NewHandler(nil)))
+		assert.LessOrEqual(t, symbols.Names().Len(), 2*initial)
+	}
+}
+
 func TestSymbolPackageCollision(t *testing.T) {
 	files := []string{
 		0: `
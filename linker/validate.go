@@ -17,12 +17,17 @@ package linker
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/editions"
+	"github.com/kralicky/protocompile/internal/featuresext"
 	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/reporter"
 	"github.com/kralicky/protocompile/walk"
@@ -31,6 +36,7 @@ import (
 // ValidateOptions runs some validation checks on the result that can only
 // be done after options are interpreted.
 func (r *result) ValidateOptions(handler *reporter.Handler, lenient bool) error {
+	declaredNames := &declaredExtensionNames{file: r}
 	return walk.Descriptors(r, func(d protoreflect.Descriptor) error {
 		switch d := d.(type) {
 		case protoreflect.FieldDescriptor:
@@ -38,7 +44,7 @@ func (r *result) ValidateOptions(handler *reporter.Handler, lenient bool) error
 				return err
 			}
 		case protoreflect.MessageDescriptor:
-			if err := r.validateMessage(d, handler); err != nil {
+			if err := r.validateMessage(d, declaredNames, handler); err != nil {
 				return err
 			}
 		case protoreflect.EnumDescriptor:
@@ -67,6 +73,12 @@ func (r *result) validateField(fld protoreflect.FieldDescriptor, handler *report
 	if err := r.validatePacked(fd, handler); err != nil {
 		return err
 	}
+	if err := r.validatePseudoOptions(fd, handler); err != nil {
+		return err
+	}
+	if err := r.validateStringType(fd, handler); err != nil {
+		return err
+	}
 	if fd.Kind() == protoreflect.EnumKind {
 		requiresOpen := !fd.IsList() && !fd.HasPresence()
 		if requiresOpen && fd.Enum().IsClosed() {
@@ -118,6 +130,67 @@ func (r *result) validateExtension(fd *fldDescriptor, handler *reporter.Handler,
 		return handler.HandleErrorf(info, "tag number %d is higher than max allowed tag number (%d)", fd.Number(), protointernal.MaxNormalTag)
 	}
 
+	return r.verifyExtensionDeclaration(fd, handler)
+}
+
+// validatePseudoOptions checks the json_name and default pseudo-options of
+// the given field. These are checked when options are interpreted from source,
+// but a descriptor proto provided without source may already have them set.
+func (r *result) validatePseudoOptions(fd *fldDescriptor, handler *reporter.Handler) error {
+	scope := fmt.Sprintf("field %s", fd.FullName())
+	var info ast.SourceSpan = ast.UnknownSpan(r.Path())
+	if node := r.FieldNode(fd.proto); node != nil {
+		info = r.FileNode().NodeInfo(node)
+	}
+	if fd.IsExtension() && fd.proto.JsonName != nil && fd.proto.GetJsonName() != protointernal.JSONName(fd.proto.GetName()) {
+		if err := handler.HandleErrorf(info, "%s: option json_name is not allowed on extensions", scope); err != nil {
+			return err
+		}
+	}
+	if fd.proto.DefaultValue == nil {
+		return nil
+	}
+	switch {
+	case fd.proto.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+		return handler.HandleErrorf(info, "%s: default value cannot be set because field is repeated", scope)
+	case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+		return handler.HandleErrorf(info, "%s: default value cannot be set because field is a message", scope)
+	case r.Syntax() == protoreflect.Proto3:
+		return handler.HandleErrorf(info, "%s: default values are not allowed in proto3", scope)
+	case fd.Kind() == protoreflect.StringKind && requiresUTF8Validation(fd):
+		if offset := protointernal.InvalidUTF8Offset(fd.proto.GetDefaultValue()); offset >= 0 {
+			if node := r.fieldOptionValueNode(fd, "default"); node != nil {
+				info = r.FileNode().NodeInfo(node)
+			}
+			return handler.HandleErrorf(info, "%s: default value is not valid UTF-8 (invalid byte at offset %d), but field's utf8_validation feature is VERIFY", scope, offset)
+		}
+	}
+	return nil
+}
+
+// requiresUTF8Validation reports whether values of the given string field
+// must be valid UTF-8, per its effective utf8_validation feature.
+func requiresUTF8Validation(fd protoreflect.FieldDescriptor) bool {
+	utf8Validation := resolveFeature(fd, utf8ValidationField)
+	return descriptorpb.FeatureSet_Utf8Validation(utf8Validation.Enum()) == descriptorpb.FeatureSet_VERIFY
+}
+
+// fieldOptionValueNode returns the value node of the given field's option
+// with the given simple name, or nil if there is no such option in the AST.
+func (r *result) fieldOptionValueNode(fd *fldDescriptor, name string) *ast.ValueNode {
+	node := r.FieldNode(fd.proto)
+	if node == nil {
+		return nil
+	}
+	for _, opt := range node.GetOptions().GetOptions() {
+		parts := opt.GetName().GetParts()
+		if len(parts) != 1 {
+			continue
+		}
+		if ref := parts[0].GetFieldRef(); ref != nil && !ref.IsExtension() && ref.Name != nil && string(ref.Name.AsIdentifier()) == name {
+			return opt.GetVal()
+		}
+	}
 	return nil
 }
 
@@ -144,6 +217,92 @@ func (r *result) validatePacked(fd *fldDescriptor, handler *reporter.Handler) er
 	return nil
 }
 
+// validateStringType checks the ctype option and the (pb.cpp).string_type
+// feature of the given field, matching the rules enforced by protoc. The
+// ctype option is only validated in files that use editions, since files
+// that use proto2 or proto3 syntax have long been allowed to use it freely.
+func (r *result) validateStringType(fd *fldDescriptor, handler *reporter.Handler) error {
+	edition := editions.GetEdition(fd)
+	if edition < descriptorpb.Edition_EDITION_2023 {
+		return nil
+	}
+	isString := fd.Kind() == protoreflect.StringKind || fd.Kind() == protoreflect.BytesKind
+	var info ast.SourceSpan = ast.UnknownSpan(r.Path())
+	if node := r.FieldNode(fd.proto); node != nil {
+		info = r.FileNode().NodeInfo(node)
+	}
+	hasCType := fd.proto.GetOptions() != nil && fd.proto.GetOptions().Ctype != nil
+	if hasCType {
+		ctypeInfo := info
+		if node := r.fieldOptionValueNode(fd, "ctype"); node != nil {
+			ctypeInfo = r.FileNode().NodeInfo(node)
+		}
+		switch {
+		case edition >= descriptorpb.Edition_EDITION_2024:
+			if err := handler.HandleErrorf(ctypeInfo, "ctype option cannot be used as of edition 2024; use features.(pb.cpp).string_type instead"); err != nil {
+				return err
+			}
+		case !isString:
+			if err := handler.HandleErrorf(ctypeInfo, "ctype option can only be used on string and bytes fields"); err != nil {
+				return err
+			}
+		case fd.proto.GetOptions().GetCtype() == descriptorpb.FieldOptions_CORD && fd.IsExtension():
+			if err := handler.HandleErrorf(ctypeInfo, "ctype option cannot be CORD for extension fields"); err != nil {
+				return err
+			}
+		}
+	}
+	stringType, ok := explicitStringType(fd)
+	if !ok {
+		return nil
+	}
+	switch {
+	case hasCType:
+		return handler.HandleErrorf(info, "field %s specifies both ctype and features.(pb.cpp).string_type, which is not supported", fd.FullName())
+	case !isString:
+		return handler.HandleErrorf(info, "features.(pb.cpp).string_type can only be used on string and bytes fields")
+	case stringType == cppStringTypeCord && fd.IsExtension():
+		return handler.HandleErrorf(info, "features.(pb.cpp).string_type cannot be CORD for extension fields")
+	}
+	return nil
+}
+
+// cppStringTypeCord is the number of the CORD value of the
+// pb.CppFeatures.StringType enum.
+const cppStringTypeCord = 2
+
+var (
+	cppStringTypeOnce      sync.Once
+	cppFeaturesExt         protoreflect.ExtensionType
+	cppFeaturesStringField protoreflect.FieldDescriptor
+)
+
+// explicitStringType returns the value of the (pb.cpp).string_type feature
+// if it is set directly on the given field. Like protoc, this does not
+// consider values inherited from the field's ancestors.
+func explicitStringType(fd protoreflect.FieldDescriptor) (protoreflect.EnumNumber, bool) {
+	cppStringTypeOnce.Do(func() {
+		file, err := featuresext.CppFeaturesDescriptor()
+		if err != nil {
+			return
+		}
+		ext := file.Extensions().ByName("cpp")
+		if ext == nil || ext.Message() == nil {
+			return
+		}
+		cppFeaturesExt = dynamicpb.NewExtensionType(ext)
+		cppFeaturesStringField = ext.Message().Fields().ByName("string_type")
+	})
+	if cppFeaturesStringField == nil {
+		return 0, false
+	}
+	val, err := editions.GetExplicitFeature(fd, cppFeaturesExt.TypeDescriptor(), cppFeaturesStringField)
+	if err != nil || !val.IsValid() {
+		return 0, false
+	}
+	return val.Enum(), true
+}
+
 func (r *result) validateWeak(fld protoreflect.FieldDescriptor, handler *reporter.Handler) error {
 	fd := fld.(*fldDescriptor) //nolint:errcheck
 	if fd.proto.GetOptions().GetWeak() {
@@ -152,7 +311,7 @@ func (r *result) validateWeak(fld protoreflect.FieldDescriptor, handler *reporte
 	return nil
 }
 
-func (r *result) validateMessage(d protoreflect.MessageDescriptor, handler *reporter.Handler) error {
+func (r *result) validateMessage(d protoreflect.MessageDescriptor, declaredNames *declaredExtensionNames, handler *reporter.Handler) error {
 	md, ok := d.(*msgDescriptor)
 	if !ok {
 		// should not be possible
@@ -163,6 +322,14 @@ func (r *result) validateMessage(d protoreflect.MessageDescriptor, handler *repo
 		return err
 	}
 
+	if err := r.validateVisibility(md, md.proto, handler); err != nil {
+		return err
+	}
+
+	if err := r.validateExtensionDeclarations(md, declaredNames, handler); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -205,6 +372,10 @@ func (r *result) validateEnum(d protoreflect.EnumDescriptor, handler *reporter.H
 		return err
 	}
 
+	if err := r.validateVisibility(ed, ed.proto, handler); err != nil {
+		return err
+	}
+
 	return nil
 }
 
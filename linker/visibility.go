@@ -0,0 +1,123 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linker
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// checkVisibility reports an error if dsc, the message or enum that the given
+// node refers to, is local to a file other than r. The error includes the
+// location of the declaration of dsc.
+func (r *result) checkVisibility(node ast.Node, scope string, dsc protoreflect.Descriptor, handler *reporter.Handler) error {
+	if dsc.ParentFile() == nil || dsc.ParentFile().Path() == r.Path() || isExported(dsc) {
+		return nil
+	}
+	return handler.HandleErrorf(r.FileNode().NodeInfo(node), "%s: %w", scope, reporter.SymbolNotVisible(string(dsc.FullName()), sourceSpanFor(dsc)))
+}
+
+// isExported returns true if the given message or enum can be referenced from
+// files other than the one that declares it. Elements that were not linked by
+// this package are assumed to be exported, since their visibility is unknown.
+func isExported(d protoreflect.Descriptor) bool {
+	var file *result
+	var vis protointernal.SymbolVisibility
+	switch d := d.(type) {
+	case *msgDescriptor:
+		file, vis = d.file, protointernal.GetVisibility(d.proto)
+	case *enumDescriptor:
+		file, vis = d.file, protointernal.GetVisibility(d.proto)
+	default:
+		return true
+	}
+	switch vis {
+	case protointernal.VisibilityExport:
+		return true
+	case protointernal.VisibilityLocal:
+		return false
+	}
+	switch defaultSymbolVisibility(file) {
+	case protointernal.DefaultVisibilityExportTopLevel:
+		_, topLevel := d.Parent().(protoreflect.FileDescriptor)
+		return topLevel
+	case protointernal.DefaultVisibilityLocalAll, protointernal.DefaultVisibilityStrict:
+		return false
+	default:
+		return true
+	}
+}
+
+// defaultSymbolVisibility returns the value of the default_symbol_visibility
+// feature for the given file. The feature can only be set in file options.
+func defaultSymbolVisibility(r *result) protointernal.DefaultSymbolVisibility {
+	fd := r.FileDescriptorProto()
+	if vis := protointernal.GetDefaultSymbolVisibility(fd.GetOptions().GetFeatures()); vis != protointernal.DefaultVisibilityUnknown {
+		return vis
+	}
+	if fd.GetEdition() >= descriptorpb.Edition_EDITION_2024 {
+		return protointernal.DefaultVisibilityExportTopLevel
+	}
+	return protointernal.DefaultVisibilityExportAll
+}
+
+// validateVisibility checks that the given nested message or enum is not
+// explicitly exported when the file uses the STRICT default visibility. As a
+// special case, enums may be exported from messages that are only used as a
+// namespace, which declare no fields and reserve all field numbers.
+func (r *result) validateVisibility(d protoreflect.Descriptor, desc proto.Message, handler *reporter.Handler) error {
+	if protointernal.GetVisibility(desc) != protointernal.VisibilityExport ||
+		defaultSymbolVisibility(r) != protointernal.DefaultVisibilityStrict {
+		return nil
+	}
+	parent, ok := d.Parent().(*msgDescriptor)
+	if !ok {
+		return nil
+	}
+	if _, isEnum := d.(protoreflect.EnumDescriptor); isEnum && isNamespaceMessage(parent) {
+		return nil
+	}
+	var node ast.Node
+	switch desc := desc.(type) {
+	case *descriptorpb.DescriptorProto:
+		if msgNode, ok := r.MessageNode(desc).Unwrap().(*ast.MessageNode); ok {
+			node = msgNode.Visibility
+		}
+	case *descriptorpb.EnumDescriptorProto:
+		node = r.EnumNode(desc).GetVisibility()
+	}
+	var span ast.SourceSpan
+	if node != nil {
+		span = r.FileNode().NodeInfo(node)
+	} else {
+		span = sourceSpanFor(d)
+	}
+	return handler.HandleErrorf(span, "%s: nested types cannot be exported when the default symbol visibility is STRICT", d.FullName())
+}
+
+// isNamespaceMessage returns true if the given message declares no fields and
+// reserves all field numbers.
+func isNamespaceMessage(md *msgDescriptor) bool {
+	if md.Fields().Len() > 0 {
+		return false
+	}
+	rng := md.ReservedRanges()
+	return rng.Len() == 1 && rng.Get(0)[0] == 1 && rng.Get(0)[1] == protointernal.MaxNormalTag+1
+}
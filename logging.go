@@ -0,0 +1,54 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LevelTrace is a log level, more verbose than slog.LevelDebug, used for
+// high-volume events such as the start and end of every compilation phase.
+const LevelTrace = slog.LevelDebug - 4
+
+// log emits a log record if the compiler has a logger that is enabled at the
+// given level. Callers that need to do any work to compute the attributes
+// should check logEnabled first.
+func (e *executor) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !e.logEnabled(ctx, level) {
+		return
+	}
+	e.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (e *executor) logEnabled(ctx context.Context, level slog.Level) bool {
+	return e.logger != nil && e.logger.Enabled(ctx, level)
+}
+
+// searchResultKind describes what a resolver returned, for logging.
+func searchResultKind(sr *SearchResult) string {
+	switch {
+	case sr.ParseResult != nil:
+		return "parse result"
+	case sr.Proto != nil:
+		return "descriptor proto"
+	case sr.AST != nil:
+		return "ast"
+	case sr.Source != nil:
+		return "source"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// WorkspaceEdit is a set of changes to the files in a workspace, as computed
+// by a refactoring. Refactorings do not modify the workspace; callers apply
+// the changes, for example with transform.ApplyEdits and UpdateFile.
+type WorkspaceEdit struct {
+	// Edits to existing files, keyed by path.
+	Edits map[ResolvedPath][]transform.TextEdit
+	// The contents of new files to create, keyed by path.
+	Creates map[ResolvedPath]string
+}
+
+func (e *WorkspaceEdit) addEdit(path ResolvedPath, edit transform.TextEdit) {
+	if e.Edits == nil {
+		e.Edits = map[ResolvedPath][]transform.TextEdit{}
+	}
+	e.Edits[path] = append(e.Edits[path], edit)
+}
+
+// MoveOptions configures Workspace.MoveDeclaration.
+type MoveOptions struct {
+	// If true, the source file publicly imports the destination file, with a
+	// comment marking the import as deprecated, so that files that import the
+	// source file, including files outside of the workspace, continue to see
+	// the moved declaration until they are updated.
+	LeaveAlias bool
+}
+
+// MoveDeclaration returns the edits that move the top-level message, enum, or
+// service with the given name to the given destination file. The declaration
+// is moved along with its comments, and it keeps its fully-qualified name, so
+// the destination must declare the same package as the declaration's file.
+//
+// If the destination is not a file in the workspace, it is created, with the
+// same syntax or edition, package, and file options as the source file.
+// Imports are added to the destination for the types and custom options that
+// the declaration uses, to the source file if its remaining declarations use
+// the moved declaration, and to every other file in the workspace that uses
+// it. An error is returned if the move would create an import cycle.
+func (w *Workspace) MoveDeclaration(name protoreflect.FullName, dest ResolvedPath, opts MoveOptions) (*WorkspaceEdit, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var d protoreflect.Descriptor
+	for _, f := range w.files {
+		if d = f.FindDescriptorByName(name); d != nil {
+			break
+		}
+	}
+	if d == nil {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	switch d.(type) {
+	case protoreflect.MessageDescriptor, protoreflect.EnumDescriptor, protoreflect.ServiceDescriptor:
+	default:
+		return nil, fmt.Errorf("%s is not a message, enum, or service", name)
+	}
+	if _, ok := d.Parent().(protoreflect.FileDescriptor); !ok {
+		return nil, fmt.Errorf("%s is not a top-level declaration", name)
+	}
+	srcPath := ResolvedPath(d.ParentFile().Path())
+	src, ok := w.files[srcPath].(linker.Result)
+	if !ok || src.AST() == nil {
+		return nil, fmt.Errorf("%s: %w", srcPath, linker.ErrNoAST)
+	}
+	if dest == srcPath {
+		return nil, fmt.Errorf("%s is already declared in %s", name, dest)
+	}
+	var destRes linker.Result
+	if f, ok := w.files[dest]; ok {
+		if destRes, ok = f.(linker.Result); !ok || destRes.AST() == nil {
+			return nil, fmt.Errorf("%s: %w", dest, linker.ErrNoAST)
+		}
+		if destRes.Package() != src.Package() {
+			return nil, fmt.Errorf("%s declares package %q, but %s is in package %q", dest, destRes.Package(), name, src.Package())
+		}
+	}
+
+	file := src.AST()
+	data := proto.GetExtension(file, ast.E_FileInfo).(*ast.FileInfo).GetData()
+	node := src.Node(protoutil.ProtoFromDescriptor(d))
+	info := file.NodeInfo(node)
+	start, end := info.Start().Offset, info.Start().Offset+len(info.RawText())
+	if comments := info.LeadingComments(); comments.Len() > 0 {
+		start = comments.Index(0).Start().Offset
+	}
+	if comments := info.TrailingComments(); comments.Len() > 0 {
+		end = comments.Index(comments.Len()-1).End().Offset + 1
+	}
+	text := string(data[start:end])
+
+	moved := func(target protoreflect.Descriptor) bool {
+		return target.ParentFile().Path() == string(srcPath) &&
+			(target.FullName() == name || strings.HasPrefix(string(target.FullName()), string(name)+"."))
+	}
+	// a new destination gets a copy of the source file's options, so the
+	// files that declare the custom options among them must be imported too
+	var fileOpts []ast.NodeInfo
+	if destRes == nil {
+		for _, decl := range file.GetDecls() {
+			if opt := decl.GetOption(); opt != nil {
+				fileOpts = append(fileOpts, file.NodeInfo(opt))
+			}
+		}
+	}
+	inFileOpts := func(offset int) bool {
+		for _, opt := range fileOpts {
+			if offset >= opt.Start().Offset && offset < opt.Start().Offset+len(opt.RawText()) {
+				return true
+			}
+		}
+		return false
+	}
+	// the files that declare what the moved declaration uses, and whether
+	// the rest of the source file uses the moved declaration
+	needed := map[string]struct{}{}
+	srcUsesMoved := false
+	src.RangeReferences(func(ref ast.NodeReference, target protoreflect.Descriptor) bool {
+		if !requiresImport(target) {
+			return true
+		}
+		refStart := ref.NodeInfo.Start().Offset
+		inMoved := refStart >= start && refStart < end
+		switch {
+		case inFileOpts(refStart):
+			needed[target.ParentFile().Path()] = struct{}{}
+		case inMoved && !moved(target):
+			needed[target.ParentFile().Path()] = struct{}{}
+		case !inMoved && moved(target):
+			srcUsesMoved = true
+		}
+		return true
+	})
+	delete(needed, string(dest))
+
+	srcImportsDest := importsFile(src, string(dest))
+	srcNeedsDest := (srcUsesMoved || opts.LeaveAlias) && !srcImportsDest
+	if srcUsesMoved || opts.LeaveAlias || srcImportsDest {
+		if _, ok := needed[string(srcPath)]; ok {
+			return nil, fmt.Errorf("moving %s to %s would create an import cycle: it uses declarations that remain in %s", name, dest, srcPath)
+		}
+		if destRes != nil && dependsOn(destRes, string(srcPath)) {
+			return nil, fmt.Errorf("moving %s to %s would create an import cycle: %s already depends on %s", name, dest, dest, srcPath)
+		}
+	}
+	var destImports []string
+	for path := range needed {
+		if f, ok := w.files[ResolvedPath(path)]; ok && dependsOn(f, string(dest)) {
+			return nil, fmt.Errorf("moving %s to %s would create an import cycle: %s depends on %s", name, dest, path, dest)
+		}
+		if destRes == nil || !importsFile(destRes, path) {
+			destImports = append(destImports, path)
+		}
+	}
+	sort.Strings(destImports)
+
+	edit := &WorkspaceEdit{}
+
+	// remove the declaration from the source file, along with the rest of
+	// its lines if they are otherwise blank
+	delStart, delEnd := start, end
+	for delStart > 0 && (data[delStart-1] == ' ' || data[delStart-1] == '\t') {
+		delStart--
+	}
+	for delEnd < len(data) && (data[delEnd] == ' ' || data[delEnd] == '\t' || data[delEnd] == '\r') {
+		delEnd++
+	}
+	if (delStart == 0 || data[delStart-1] == '\n') && delEnd < len(data) && data[delEnd] == '\n' {
+		delEnd++
+		// also remove a preceding blank line, if another one follows
+		if delStart > 1 && data[delStart-2] == '\n' && (delEnd == len(data) || data[delEnd] == '\n') {
+			delStart--
+		}
+	} else {
+		delStart, delEnd = start, end
+	}
+	edit.addEdit(srcPath, transform.TextEdit{
+		Start: file.SourcePos(delStart),
+		End:   file.SourcePos(delEnd),
+	})
+	if srcNeedsDest {
+		var importText string
+		if opts.LeaveAlias {
+			importText = fmt.Sprintf("\n// Deprecated: %s was moved to %q; import it directly.\nimport public %q;", name, dest, dest)
+		} else {
+			importText = fmt.Sprintf("\nimport %q;", dest)
+		}
+		edit.addEdit(srcPath, transform.Insert(file, src.ImportInsertionPoint().Offset+1, importText))
+	}
+
+	// add the declaration to the destination
+	if destRes == nil {
+		var sb strings.Builder
+		switch {
+		case file.GetSyntax() != nil:
+			sb.WriteString(file.NodeInfo(file.GetSyntax()).RawText() + "\n")
+		case file.GetEdition() != nil:
+			sb.WriteString(file.NodeInfo(file.GetEdition()).RawText() + "\n")
+		}
+		for _, decl := range file.GetDecls() {
+			if pkg := decl.GetPackage(); pkg != nil {
+				sb.WriteString("\n" + file.NodeInfo(pkg).RawText() + "\n")
+			}
+		}
+		if len(destImports) > 0 {
+			sb.WriteString("\n")
+			for _, path := range destImports {
+				fmt.Fprintf(&sb, "import %q;\n", path)
+			}
+		}
+		if len(fileOpts) > 0 {
+			sb.WriteString("\n")
+			for _, opt := range fileOpts {
+				sb.WriteString(opt.RawText() + "\n")
+			}
+		}
+		sb.WriteString("\n" + text + "\n")
+		edit.Creates = map[ResolvedPath]string{dest: sb.String()}
+	} else {
+		destFile := destRes.AST()
+		insertAt := destRes.ImportInsertionPoint().Offset + 1
+		for _, path := range destImports {
+			edit.addEdit(dest, transform.Insert(destFile, insertAt, fmt.Sprintf("\nimport %q;", path)))
+		}
+		destData := proto.GetExtension(destFile, ast.E_FileInfo).(*ast.FileInfo).GetData()
+		sep := "\n"
+		if len(destData) > 0 && destData[len(destData)-1] != '\n' {
+			sep = "\n\n"
+		}
+		edit.addEdit(dest, transform.Insert(destFile, len(destData), sep+text+"\n"))
+	}
+
+	// import the destination in other files that use the declaration
+	for path, f := range w.files {
+		res, ok := f.(linker.Result)
+		if !ok || res.AST() == nil || path == srcPath || path == dest || importsFile(res, string(dest)) {
+			continue
+		}
+		usesMoved := false
+		res.RangeReferences(func(_ ast.NodeReference, target protoreflect.Descriptor) bool {
+			usesMoved = requiresImport(target) && moved(target)
+			return !usesMoved
+		})
+		if !usesMoved {
+			continue
+		}
+		if dependsOn(destRes, string(path)) {
+			return nil, fmt.Errorf("moving %s to %s would create an import cycle: %s depends on %s", name, dest, dest, path)
+		}
+		edit.addEdit(path, transform.Insert(res.AST(), res.ImportInsertionPoint().Offset+1, fmt.Sprintf("\nimport %q;", dest)))
+	}
+	return edit, nil
+}
+
+// requiresImport reports whether a file must import the file that declares
+// target in order to refer to it. This is true for messages, enums, and
+// extensions, but not, for example, for fields of options messages or for
+// enum values in option values.
+func requiresImport(target protoreflect.Descriptor) bool {
+	switch target := target.(type) {
+	case protoreflect.MessageDescriptor:
+		return !target.IsMapEntry()
+	case protoreflect.EnumDescriptor:
+		return true
+	case protoreflect.FieldDescriptor:
+		return target.IsExtension()
+	default:
+		return false
+	}
+}
+
+// importsFile reports whether f directly imports the file with the given path.
+func importsFile(f protoreflect.FileDescriptor, path string) bool {
+	imports := f.Imports()
+	for i, l := 0, imports.Len(); i < l; i++ {
+		if imports.Get(i).Path() == path {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOn reports whether f transitively imports the file with the given
+// path. It returns false if f is nil.
+func dependsOn(f protoreflect.FileDescriptor, path string) bool {
+	if f == nil {
+		return false
+	}
+	seen := map[string]struct{}{}
+	var visit func(protoreflect.FileDescriptor) bool
+	visit = func(f protoreflect.FileDescriptor) bool {
+		if _, ok := seen[f.Path()]; ok {
+			return false
+		}
+		seen[f.Path()] = struct{}{}
+		imports := f.Imports()
+		for i, l := 0, imports.Len(); i < l; i++ {
+			if imp := imports.Get(i); imp.Path() == path || visit(imp.FileDescriptor) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(f)
+}
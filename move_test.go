@@ -0,0 +1,199 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast/transform"
+)
+
+func TestWorkspaceMoveDeclaration(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+
+package foo;
+
+import "opts.proto";
+
+option go_package = "example.com/foo";
+
+// Thing is a thing.
+message Thing {
+  option (tag) = "t";
+  Kind kind = 1;
+}
+
+// Kind is a kind.
+enum Kind {
+  option (enum_tag) = "k";
+  KIND_UNSPECIFIED = 0;
+} // trailing
+
+message User {
+  Thing thing = 1;
+}
+`,
+		"opts.proto": `syntax = "proto3";
+package foo;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions { string tag = 50000; }
+extend google.protobuf.EnumOptions { string enum_tag = 50000; }
+`,
+		"b.proto": `syntax = "proto3";
+package foo;
+import "a.proto";
+message Wrapper { Kind kind = 1; }
+`,
+		"c.proto": `syntax = "proto3";
+package foo;
+message Other {}`,
+		"d.proto": `syntax = "proto3";
+package bar;
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(sources)),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto", "c.proto", "d.proto"))
+
+	apply := func(edit *WorkspaceEdit, path ResolvedPath) string {
+		return string(transform.ApplyEdits([]byte(sources[UnresolvedPath(path)]), edit.Edits[path]))
+	}
+
+	// to a new file
+	edit, err := ws.MoveDeclaration("foo.Kind", "kind.proto", MoveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[ResolvedPath]string{
+		"kind.proto": `syntax = "proto3";
+
+package foo;
+
+import "opts.proto";
+
+option go_package = "example.com/foo";
+
+// Kind is a kind.
+enum Kind {
+  option (enum_tag) = "k";
+  KIND_UNSPECIFIED = 0;
+} // trailing
+`,
+	}, edit.Creates)
+	assert.Len(t, edit.Edits, 2)
+	assert.Equal(t, `syntax = "proto3";
+
+package foo;
+
+import "opts.proto";
+import "kind.proto";
+
+option go_package = "example.com/foo";
+
+// Thing is a thing.
+message Thing {
+  option (tag) = "t";
+  Kind kind = 1;
+}
+
+message User {
+  Thing thing = 1;
+}
+`, apply(edit, "a.proto"))
+	assert.Equal(t, `syntax = "proto3";
+package foo;
+import "a.proto";
+import "kind.proto";
+message Wrapper { Kind kind = 1; }
+`, apply(edit, "b.proto"))
+
+	// to an existing file, leaving an alias
+	edit, err = ws.MoveDeclaration("foo.Kind", "c.proto", MoveOptions{LeaveAlias: true})
+	require.NoError(t, err)
+	assert.Empty(t, edit.Creates)
+	assert.Len(t, edit.Edits, 3)
+	assert.Equal(t, `syntax = "proto3";
+package foo;
+import "opts.proto";
+message Other {}
+
+// Kind is a kind.
+enum Kind {
+  option (enum_tag) = "k";
+  KIND_UNSPECIFIED = 0;
+} // trailing
+`, apply(edit, "c.proto"))
+	assert.Contains(t, apply(edit, "a.proto"), `import "opts.proto";
+// Deprecated: foo.Kind was moved to "c.proto"; import it directly.
+import public "c.proto";
+`)
+
+	// errors
+	_, err = ws.MoveDeclaration("foo.Thing", "thing.proto", MoveOptions{})
+	assert.ErrorContains(t, err, "import cycle")
+	_, err = ws.MoveDeclaration("foo.Kind", "d.proto", MoveOptions{})
+	assert.ErrorContains(t, err, `d.proto declares package "bar"`)
+	_, err = ws.MoveDeclaration("foo.Thing.kind", "thing.proto", MoveOptions{})
+	assert.ErrorContains(t, err, "not a message, enum, or service")
+	_, err = ws.MoveDeclaration("foo.Kind", "a.proto", MoveOptions{})
+	assert.ErrorContains(t, err, "already declared")
+}
+
+func TestWorkspaceMoveDeclarationCustomFileOptions(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+
+package foo;
+
+import "o.proto";
+
+option (o.flag) = true;
+
+message Thing {}
+`,
+		"o.proto": `syntax = "proto3";
+package o;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.FileOptions { bool flag = 50000; }
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(sources)),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto"))
+
+	edit, err := ws.MoveDeclaration("foo.Thing", "thing.proto", MoveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[ResolvedPath]string{
+		"thing.proto": `syntax = "proto3";
+
+package foo;
+
+import "o.proto";
+
+option (o.flag) = true;
+
+message Thing {}
+`,
+	}, edit.Creates)
+}
@@ -0,0 +1,129 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/linker"
+)
+
+// MoveFiles returns the edits that update import statements for files that
+// are moved or renamed, given a mapping from their old paths to their new
+// paths. Every import of an old path, in every file in the workspace that has
+// an AST, is rewritten to import the new path. The edits are keyed by the
+// paths of the files before they are moved; moving the files themselves is
+// left to the caller.
+//
+// The result is validated by compiling the edited files under their new paths,
+// without modifying the workspace. If they do not link cleanly, the edits are
+// returned along with the compilation error, so the caller can decide whether
+// to apply them anyway.
+func (w *Workspace) MoveFiles(ctx context.Context, moves map[ResolvedPath]ResolvedPath) (*WorkspaceEdit, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	// the old path of each new path, ignoring files that are not moved
+	from := map[ResolvedPath]ResolvedPath{}
+	for old, to := range moves {
+		if old == to {
+			continue
+		}
+		if other, ok := from[to]; ok {
+			return nil, fmt.Errorf("cannot move both %s and %s to %s", other, old, to)
+		}
+		from[to] = old
+	}
+	newPath := func(path ResolvedPath) ResolvedPath {
+		if to, ok := moves[path]; ok {
+			return to
+		}
+		return path
+	}
+	for to := range from {
+		if _, ok := w.files[to]; ok && newPath(to) == to {
+			return nil, fmt.Errorf("cannot move %s to %s: the file already exists", from[to], to)
+		}
+	}
+
+	edit := &WorkspaceEdit{}
+	// the edited contents of the files in the workspace, keyed by new path
+	contents := map[ResolvedPath]string{}
+	var paths []ResolvedPath
+	for path, f := range w.files {
+		res, ok := f.(linker.Result)
+		if !ok || res.AST() == nil {
+			continue
+		}
+		file := res.AST()
+		var edits []transform.TextEdit
+		for _, decl := range file.GetDecls() {
+			imp := decl.GetImport()
+			if imp == nil || imp.IsIncomplete() {
+				continue
+			}
+			to, ok := moves[ResolvedPath(imp.Name.AsString())]
+			if !ok || to == ResolvedPath(imp.Name.AsString()) {
+				continue
+			}
+			edits = append(edits, transform.Replace(file, imp.GetName(), strconv.Quote(string(to))))
+		}
+		for _, e := range edits {
+			edit.addEdit(path, e)
+		}
+		data := proto.GetExtension(file, ast.E_FileInfo).(*ast.FileInfo).GetData()
+		contents[newPath(path)] = string(transform.ApplyEdits(data, edits))
+		paths = append(paths, newPath(path))
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+
+	// moved files that are not in the workspace are still found by their old
+	// paths, and nothing is found at an old path once its file is moved
+	resolver := ResolverFunc(func(path UnresolvedPath, whence ImportContext) (SearchResult, error) {
+		if s, ok := contents[ResolvedPath(path)]; ok {
+			return SearchResult{ResolvedPath: ResolvedPath(path), Source: strings.NewReader(s)}, nil
+		}
+		if old, ok := from[ResolvedPath(path)]; ok {
+			res, err := w.compiler.Resolver.FindFileByPath(UnresolvedPath(old), whence)
+			res.ResolvedPath = ResolvedPath(path)
+			return res, err
+		}
+		if newPath(ResolvedPath(path)) != ResolvedPath(path) {
+			return SearchResult{}, os.ErrNotExist
+		}
+		return w.compiler.Resolver.FindFileByPath(path, whence)
+	})
+	// the files are checked with the workspace's configuration, but without
+	// its retained results, hooks, or reporter
+	c := *w.compiler
+	c.Resolver = resolver
+	c.RetainResults = false
+	c.Hooks = CompilerHooks{}
+	c.Reporter = nil
+	c.exec = nil
+	if _, err := c.Compile(ctx, paths...); err != nil {
+		return edit, fmt.Errorf("files do not link after moving: %w", err)
+	}
+	return edit, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast/transform"
+)
+
+func TestWorkspaceMoveFiles(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"foo/a.proto": `syntax = "proto3";
+package foo;
+message A {}
+`,
+		"foo/b.proto": `syntax = "proto3";
+package foo;
+import "foo/a.proto";
+import "google/protobuf/empty.proto";
+message B {
+  A a = 1;
+  google.protobuf.Empty e = 2;
+}
+`,
+		"c.proto": `syntax = "proto3";
+package foo;
+import public "foo/b.proto";
+import "foo/a.proto";
+message C {
+  A a = 1;
+  B b = 2;
+}
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(sources)),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "c.proto"))
+
+	apply := func(edit *WorkspaceEdit, path ResolvedPath) string {
+		return string(transform.ApplyEdits([]byte(sources[UnresolvedPath(path)]), edit.Edits[path]))
+	}
+	edit, err := ws.MoveFiles(ctx, map[ResolvedPath]ResolvedPath{
+		"foo/a.proto": "bar/a.proto",
+		"foo/b.proto": "bar/b.proto",
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, edit.Edits, ResolvedPath("foo/a.proto"))
+	assert.Equal(t, `syntax = "proto3";
+package foo;
+import "bar/a.proto";
+import "google/protobuf/empty.proto";
+message B {
+  A a = 1;
+  google.protobuf.Empty e = 2;
+}
+`, apply(edit, "foo/b.proto"))
+	assert.Equal(t, `syntax = "proto3";
+package foo;
+import public "bar/b.proto";
+import "bar/a.proto";
+message C {
+  A a = 1;
+  B b = 2;
+}
+`, apply(edit, "c.proto"))
+
+	edit, err = ws.MoveFiles(ctx, map[ResolvedPath]ResolvedPath{
+		"google/protobuf/empty.proto": "empty.proto",
+	})
+	require.NoError(t, err)
+	assert.Len(t, edit.Edits, 1)
+	assert.Len(t, edit.Edits["foo/b.proto"], 1)
+
+	// files without ASTs, such as the standard imports, are not edited, so
+	// they still import the old paths
+	require.NoError(t, ws.UpdateFile(ctx, "d.proto", `syntax = "proto3";
+import "google/protobuf/api.proto";
+`))
+	edit, err = ws.MoveFiles(ctx, map[ResolvedPath]ResolvedPath{
+		"google/protobuf/source_context.proto": "source_context.proto",
+	})
+	assert.ErrorContains(t, err, "files do not link after moving")
+	assert.Empty(t, edit.Edits)
+
+	_, err = ws.MoveFiles(ctx, map[ResolvedPath]ResolvedPath{"foo/a.proto": "c.proto"})
+	assert.ErrorContains(t, err, "cannot move foo/a.proto to c.proto: the file already exists")
+	_, err = ws.MoveFiles(ctx, map[ResolvedPath]ResolvedPath{"foo/a.proto": "c.proto", "c.proto": "foo/a.proto"})
+	require.NoError(t, err)
+}
+
+func TestWorkspaceMoveFilesUsesCompilerConfig(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"foo/a.proto": `edition = "2024";
+package foo;
+message A {}
+`,
+		"c.proto": `edition = "2024";
+package foo;
+import "foo/a.proto";
+message C {
+  A a = 1;
+}
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver:             WithStandardImports(mkResolver(sources)),
+		ExperimentalEditions: true,
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "c.proto"))
+
+	edit, err := ws.MoveFiles(ctx, map[ResolvedPath]ResolvedPath{"foo/a.proto": "bar/a.proto"})
+	require.NoError(t, err)
+	assert.Contains(t, edit.Edits, ResolvedPath("c.proto"))
+}
@@ -0,0 +1,109 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+)
+
+// TypeDefinitionAt returns the message or enum that is the type of the field
+// that is referenced or declared at the given position in the given file, as
+// determined by ResolveAt. For a map field, this is the type of the map's
+// values. It returns nil if there is no field at the position or if the field
+// has a scalar type.
+func (w *Workspace) TypeDefinitionAt(path ResolvedPath, pos ast.SourcePos) protoreflect.Descriptor {
+	fld, ok := w.ResolveAt(path, pos).(protoreflect.FieldDescriptor)
+	if !ok {
+		return nil
+	}
+	if fld.IsMap() {
+		fld = fld.MapValue()
+	}
+	if md := fld.Message(); md != nil {
+		return md
+	}
+	if ed := fld.Enum(); ed != nil {
+		return ed
+	}
+	return nil
+}
+
+// ExtendeeAt returns the message that is extended by the extension that is
+// referenced or declared at the given position in the given file, as
+// determined by ResolveAt. It returns nil if there is no extension at the
+// position.
+func (w *Workspace) ExtendeeAt(path ResolvedPath, pos ast.SourcePos) protoreflect.MessageDescriptor {
+	fld, ok := w.ResolveAt(path, pos).(protoreflect.FieldDescriptor)
+	if !ok || !fld.IsExtension() {
+		return nil
+	}
+	return fld.ContainingMessage()
+}
+
+// OptionExtensionAt returns the extension that declares the custom option
+// being set at the given position in the given file. The position may be
+// anywhere in an option's name: for a name with several parts, such as
+// "(foo.bar).baz", this is the extension named by the nearest part in
+// parentheses at or before the position. The position may also be in the
+// name of an extension that is set in a message literal, such as "[foo.bar]".
+// It returns nil if there is no such extension, if options have not been
+// interpreted, or if the file has no AST.
+func (w *Workspace) OptionExtensionAt(path ResolvedPath, pos ast.SourcePos) protoreflect.FieldDescriptor {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil
+	}
+
+	file := res.AST()
+	var found protoreflect.FieldDescriptor
+	ast.Inspect(file, func(node ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.OptionNameNode:
+			if !spanContains(file.NodeInfo(node), pos) {
+				return false
+			}
+			for _, part := range node.FilterFieldReferences() {
+				start := file.NodeInfo(part).Start()
+				if pos.Line < start.Line || (pos.Line == start.Line && pos.Col < start.Col) {
+					break
+				}
+				if part.IsExtension() {
+					if fld := res.FindFieldDescriptorByFieldReferenceNode(part); fld != nil {
+						found = fld
+					}
+				}
+			}
+			return false
+		case *ast.MessageFieldNode:
+			name := node.GetName()
+			if name != nil && name.IsExtension() && spanContains(file.NodeInfo(name), pos) {
+				if fld := res.FindFieldDescriptorByMessageFieldNode(node); fld != nil && fld.IsExtension() {
+					found = fld
+				}
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
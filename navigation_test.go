@@ -0,0 +1,81 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+func TestWorkspaceNavigation(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(map[UnresolvedPath]string{
+			"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+message Rule {
+  optional string name = 1;
+  optional Rule next = 2;
+  extensions 100 to 200;
+}
+enum Kind { KIND_UNSPECIFIED = 0; }
+extend google.protobuf.FieldOptions {
+  optional Rule rule = 50000;
+}
+message Msg {
+  optional string id = 1 [(rule).next.name = "x"];
+  optional Rule r = 2 [(rule) = { [foo.wrapped]: "y" }];
+  map<string, Kind> kinds = 3;
+}
+extend Rule { optional string wrapped = 100; }
+`,
+		})),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto"))
+
+	fullName := func(d protoreflect.Descriptor) protoreflect.FullName {
+		if d == nil {
+			return ""
+		}
+		return d.FullName()
+	}
+
+	// field declarations
+	assert.Equal(t, protoreflect.FullName("foo.Rule"), fullName(ws.TypeDefinitionAt("a.proto", ast.SourcePos{Line: 15, Col: 17})))
+	assert.Equal(t, protoreflect.FullName("foo.Kind"), fullName(ws.TypeDefinitionAt("a.proto", ast.SourcePos{Line: 16, Col: 22})))
+	assert.Nil(t, ws.TypeDefinitionAt("a.proto", ast.SourcePos{Line: 5, Col: 19}))
+	// field reference in an option name
+	assert.Equal(t, protoreflect.FullName("foo.Rule"), fullName(ws.TypeDefinitionAt("a.proto", ast.SourcePos{Line: 14, Col: 35})))
+
+	// extension declaration and reference
+	assert.Equal(t, protoreflect.FullName("google.protobuf.FieldOptions"), fullName(ws.ExtendeeAt("a.proto", ast.SourcePos{Line: 11, Col: 17})))
+	assert.Equal(t, protoreflect.FullName("google.protobuf.FieldOptions"), fullName(ws.ExtendeeAt("a.proto", ast.SourcePos{Line: 14, Col: 29})))
+	assert.Nil(t, ws.ExtendeeAt("a.proto", ast.SourcePos{Line: 5, Col: 19}))
+
+	// option names
+	assert.Equal(t, protoreflect.FullName("foo.rule"), fullName(ws.OptionExtensionAt("a.proto", ast.SourcePos{Line: 14, Col: 29})))
+	assert.Equal(t, protoreflect.FullName("foo.rule"), fullName(ws.OptionExtensionAt("a.proto", ast.SourcePos{Line: 14, Col: 40})))
+	assert.Equal(t, protoreflect.FullName("foo.wrapped"), fullName(ws.OptionExtensionAt("a.proto", ast.SourcePos{Line: 15, Col: 39})))
+	assert.Nil(t, ws.OptionExtensionAt("a.proto", ast.SourcePos{Line: 14, Col: 45}))
+	assert.Nil(t, ws.OptionExtensionAt("a.proto", ast.SourcePos{Line: 15, Col: 17}))
+}
@@ -0,0 +1,321 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+const (
+	// ProtovalidateFieldConstraints is the name of the field option of
+	// protovalidate, whose value is a buf.validate.FieldConstraints message.
+	ProtovalidateFieldConstraints protoreflect.FullName = "buf.validate.field"
+	// PGVFieldRules is the name of the field option of protoc-gen-validate,
+	// whose value is a validate.FieldRules message.
+	PGVFieldRules protoreflect.FullName = "validate.rules"
+)
+
+// lengthBounds are the names of the fields of type-specific rules that are
+// lower and upper bounds on lengths or counts.
+var lengthBounds = [][2]protoreflect.Name{
+	{"min_len", "max_len"},
+	{"min_bytes", "max_bytes"},
+	{"min_items", "max_items"},
+	{"min_pairs", "max_pairs"},
+}
+
+// exactLengths are the names of the fields of type-specific rules that are
+// exact lengths, along with the bounds they must be within.
+var exactLengths = [][3]protoreflect.Name{
+	{"len", "min_len", "max_len"},
+	{"len_bytes", "min_bytes", "max_bytes"},
+}
+
+// ConstraintValidators returns validators that check the internal
+// consistency of the field constraints of protovalidate and
+// protoc-gen-validate, which are otherwise only found to be wrong at runtime.
+// Problems are reported at the given level, at the location of the offending
+// rule, even when it is inside a message literal. The validators check that:
+//   - the rules for a type, such as string rules, match the type of the field
+//   - lower bounds on lengths and counts, such as min_len, are not greater
+//     than the corresponding upper bounds, and exact lengths are within them
+//   - integer bounds such as gt and lt admit at least one value
+//   - no value is in both the in and not_in rules
+//   - patterns are valid regular expressions
+//   - ignore_empty is only used on fields that track presence, and is not
+//     combined with required
+//
+// The validators use the rules' field names, so they work with any version
+// of the options. If the level is off, the result is nil. See
+// WithOptionValidator.
+func ConstraintValidators(level linker.CheckLevel) map[protoreflect.FullName]OptionValidator {
+	if level == linker.CheckLevelOff {
+		return nil
+	}
+	validator := func(opt *ValidatedOption, handler *reporter.Handler) error {
+		fld, ok := opt.Element.(protoreflect.FieldDescriptor)
+		if !ok || opt.Extension.Message() == nil || opt.Extension.IsList() {
+			return nil
+		}
+		c := &constraintChecker{opt: opt, handler: handler, level: level}
+		return c.checkRules(opt.Value.Message(), nil, fld, fld.IsList(), false)
+	}
+	return map[protoreflect.FullName]OptionValidator{
+		ProtovalidateFieldConstraints: validator,
+		PGVFieldRules:                 validator,
+	}
+}
+
+type constraintChecker struct {
+	opt     *ValidatedOption
+	handler *reporter.Handler
+	level   linker.CheckLevel
+}
+
+// report reports a problem with the rule at the given path.
+func (c *constraintChecker) report(path []protoreflect.FieldDescriptor, format string, args ...interface{}) error {
+	var name strings.Builder
+	fmt.Fprintf(&name, "(%s)", c.opt.Extension.FullName())
+	for _, fd := range path {
+		name.WriteByte('.')
+		name.WriteString(string(fd.Name()))
+	}
+	span := c.opt.Span(path...)
+	format = "%s: " + format
+	args = append([]interface{}{name.String()}, args...)
+	if c.level == linker.CheckLevelWarn {
+		c.handler.HandleWarningf(span, format, args...)
+		return nil
+	}
+	return c.handler.HandleErrorf(span, format, args...)
+}
+
+// checkRules checks the field rules at the given path, which constrain the
+// values of fld. If list is true, the rules apply to the list as a whole;
+// if item is true, they apply to the elements of a list or to the keys or
+// values of a map.
+func (c *constraintChecker) checkRules(rules protoreflect.Message, path []protoreflect.FieldDescriptor, fld protoreflect.FieldDescriptor, list, item bool) error {
+	if err := c.checkIgnoreEmpty(rules, path, fld, list || item); err != nil {
+		return err
+	}
+	oneof := rules.Descriptor().Oneofs().ByName("type")
+	if oneof == nil {
+		return nil
+	}
+	typeFld := rules.WhichOneof(oneof)
+	if typeFld == nil || typeFld.Message() == nil {
+		return nil
+	}
+	path = append(path[:len(path):len(path)], typeFld)
+	if want, got := ruleType(fld, list), typeFld.Name(); want != got {
+		return c.report(path, "%s rules cannot be used for field %s of type %s", got, fld.FullName(), fieldType(fld, list))
+	}
+	typeRules := rules.Get(typeFld).Message()
+	fields := typeRules.Descriptor().Fields()
+	if err := c.checkIgnoreEmpty(typeRules, path, fld, list || item); err != nil {
+		return err
+	}
+
+	for _, bounds := range lengthBounds {
+		minFld, maxFld := fields.ByName(bounds[0]), fields.ByName(bounds[1])
+		if !isSetUint(typeRules, minFld) || !isSetUint(typeRules, maxFld) {
+			continue
+		}
+		if lo, hi := typeRules.Get(minFld).Uint(), typeRules.Get(maxFld).Uint(); lo > hi {
+			if err := c.report(append(path, maxFld), "%s (%d) is less than %s (%d)", bounds[1], hi, bounds[0], lo); err != nil {
+				return err
+			}
+		}
+	}
+	for _, names := range exactLengths {
+		lenFld := fields.ByName(names[0])
+		if !isSetUint(typeRules, lenFld) {
+			continue
+		}
+		n := typeRules.Get(lenFld).Uint()
+		if minFld := fields.ByName(names[1]); isSetUint(typeRules, minFld) && n < typeRules.Get(minFld).Uint() {
+			if err := c.report(append(path, lenFld), "%s (%d) is less than %s (%d)", names[0], n, names[1], typeRules.Get(minFld).Uint()); err != nil {
+				return err
+			}
+		}
+		if maxFld := fields.ByName(names[2]); isSetUint(typeRules, maxFld) && n > typeRules.Get(maxFld).Uint() {
+			if err := c.report(append(path, lenFld), "%s (%d) is greater than %s (%d)", names[0], n, names[2], typeRules.Get(maxFld).Uint()); err != nil {
+				return err
+			}
+		}
+	}
+
+	// An integer range whose exclusive bounds are adjacent admits no value.
+	// Other combinations of bounds, including a lower bound that is greater
+	// than the upper bound, which is an exclusive range, are valid.
+	gtFld, ltFld := fields.ByName("gt"), fields.ByName("lt")
+	if gtFld != nil && ltFld != nil && gtFld.Kind() == ltFld.Kind() && typeRules.Has(gtFld) && typeRules.Has(ltFld) {
+		gt, lt := typeRules.Get(gtFld), typeRules.Get(ltFld)
+		var empty bool
+		switch gtFld.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+			protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			empty = lt.Int() > gt.Int() && lt.Int()-gt.Int() == 1
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			empty = lt.Uint() > gt.Uint() && lt.Uint()-gt.Uint() == 1
+		}
+		if empty {
+			if err := c.report(append(path, ltFld), "no value is greater than %v and less than %v", gt.Interface(), lt.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if inFld, notInFld := fields.ByName("in"), fields.ByName("not_in"); inFld != nil && notInFld != nil && inFld.IsList() && notInFld.IsList() {
+		in, notIn := typeRules.Get(inFld).List(), typeRules.Get(notInFld).List()
+		excluded := map[interface{}]struct{}{}
+		for i := 0; i < notIn.Len(); i++ {
+			excluded[comparableValue(notIn.Get(i))] = struct{}{}
+		}
+		for i := 0; i < in.Len(); i++ {
+			if _, ok := excluded[comparableValue(in.Get(i))]; ok {
+				if err := c.report(append(path, notInFld), "%v is in both in and not_in", in.Get(i).Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if patternFld := fields.ByName("pattern"); patternFld != nil && patternFld.Kind() == protoreflect.StringKind && typeRules.Has(patternFld) {
+		pattern := typeRules.Get(patternFld).String()
+		if _, err := regexp.Compile(pattern); err != nil {
+			if err := c.report(append(path, patternFld), "%q is not a valid regular expression: %v", pattern, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch typeFld.Name() {
+	case "repeated":
+		if items := fields.ByName("items"); items != nil && items.Message() != nil && typeRules.Has(items) {
+			return c.checkRules(typeRules.Get(items).Message(), append(path, items), fld, false, true)
+		}
+	case "map":
+		if keys := fields.ByName("keys"); keys != nil && keys.Message() != nil && typeRules.Has(keys) {
+			if err := c.checkRules(typeRules.Get(keys).Message(), append(path, keys), fld.MapKey(), false, true); err != nil {
+				return err
+			}
+		}
+		if values := fields.ByName("values"); values != nil && values.Message() != nil && typeRules.Has(values) {
+			return c.checkRules(typeRules.Get(values).Message(), append(path, values), fld.MapValue(), false, true)
+		}
+	}
+	return nil
+}
+
+// checkIgnoreEmpty checks the ignore_empty rule in the given rules, which
+// protovalidate declares in the field constraints and protoc-gen-validate in
+// the rules for each type. If elements is true, the rules apply to a list or
+// map, or to their elements, which do not track presence.
+func (c *constraintChecker) checkIgnoreEmpty(rules protoreflect.Message, path []protoreflect.FieldDescriptor, fld protoreflect.FieldDescriptor, elements bool) error {
+	fields := rules.Descriptor().Fields()
+	ignoreEmpty := fields.ByName("ignore_empty")
+	if ignoreEmpty == nil || ignoreEmpty.Kind() != protoreflect.BoolKind || !rules.Get(ignoreEmpty).Bool() {
+		return nil
+	}
+	path = append(path[:len(path):len(path)], ignoreEmpty)
+	if required := fields.ByName("required"); required != nil && required.Kind() == protoreflect.BoolKind && rules.Get(required).Bool() {
+		return c.report(path, "ignore_empty cannot be combined with required")
+	}
+	if !elements && !fld.IsMap() && !fld.HasPresence() {
+		return c.report(path, "ignore_empty is used on field %s, which is not optional, so an empty value cannot be distinguished from an unset one", fld.FullName())
+	}
+	return nil
+}
+
+// ruleType returns the name of the type-specific rules that apply to the
+// values of fld, which is the name of the corresponding field of the rules.
+func ruleType(fld protoreflect.FieldDescriptor, list bool) protoreflect.Name {
+	switch {
+	case fld.IsMap():
+		return "map"
+	case list:
+		return "repeated"
+	}
+	if md := fld.Message(); md != nil {
+		switch name := md.FullName(); name {
+		case "google.protobuf.Any":
+			return "any"
+		case "google.protobuf.Duration":
+			return "duration"
+		case "google.protobuf.Timestamp":
+			return "timestamp"
+		case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+			"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+			"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+			"google.protobuf.BoolValue", "google.protobuf.StringValue",
+			"google.protobuf.BytesValue":
+			// rules for the wrapped type apply to wrapper types
+			if value := md.Fields().ByName("value"); value != nil {
+				return protoreflect.Name(value.Kind().String())
+			}
+		}
+		return "message"
+	}
+	return protoreflect.Name(fld.Kind().String())
+}
+
+// fieldType describes the type of the values of fld for diagnostics.
+func fieldType(fld protoreflect.FieldDescriptor, list bool) string {
+	var typ string
+	switch {
+	case fld.IsMap():
+		return fmt.Sprintf("map<%s, %s>", fieldType(fld.MapKey(), false), fieldType(fld.MapValue(), false))
+	case fld.Message() != nil:
+		typ = string(fld.Message().FullName())
+	case fld.Enum() != nil:
+		typ = string(fld.Enum().FullName())
+	default:
+		typ = fld.Kind().String()
+	}
+	if list {
+		return "repeated " + typ
+	}
+	return typ
+}
+
+// isSetUint returns true if fld is a singular unsigned integer field that is
+// set in msg.
+func isSetUint(msg protoreflect.Message, fld protoreflect.FieldDescriptor) bool {
+	if fld == nil || fld.Cardinality() == protoreflect.Repeated {
+		return false
+	}
+	switch fld.Kind() {
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return msg.Has(fld)
+	}
+	return false
+}
+
+// comparableValue returns a value that can be used as a map key to compare
+// the given values of in and not_in rules.
+func comparableValue(v protoreflect.Value) interface{} {
+	if b, ok := v.Interface().([]byte); ok {
+		return string(b)
+	}
+	return v.Interface()
+}
@@ -31,6 +31,10 @@ type OptionNotFoundError interface {
 	error
 
 	Node() ast.Node
+	// Suggestions returns names that are plausibly what was meant, closest
+	// first. Each can replace the name at Node as is. This is empty if there
+	// are no likely candidates.
+	Suggestions() []string
 	isOptionNotFoundError()
 }
 
@@ -62,6 +66,11 @@ type OptionValueError interface {
 
 type optionNotFoundError struct {
 	interpreterError
+	suggestions []string
+}
+
+func (e *optionNotFoundError) Suggestions() []string {
+	return e.suggestions
 }
 
 func (e *optionNotFoundError) isOptionNotFoundError() {}
@@ -118,12 +127,19 @@ func (i *interpreter) HandleOptionForbiddenErrorf(mc *protointernal.MessageConte
 }
 
 func (i *interpreter) HandleOptionNotFoundErrorf(mc *protointernal.MessageContext, node ast.Node, formatStr string, args ...any) error {
+	return i.handleOptionNotFoundError(mc, node, nil, formatStr, args...)
+}
+
+// handleOptionNotFoundError is like HandleOptionNotFoundErrorf, but attaches
+// the given suggestions to the error and mentions them in its message.
+func (i *interpreter) handleOptionNotFoundError(mc *protointernal.MessageContext, node ast.Node, suggestions []string, formatStr string, args ...any) error {
 	if err := i.handler.HandleError(reporter.Error(i.nodeInfo(node), &optionNotFoundError{
 		interpreterError: interpreterError{
-			base: fmt.Errorf(formatStr, args...),
+			base: fmt.Errorf(formatStr+"%s", append(args, protointernal.DidYouMean(suggestions))...),
 			mc:   mc,
 			node: node,
 		},
+		suggestions: suggestions,
 	})); err != nil {
 		return err
 	}
@@ -30,6 +30,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 
 	"google.golang.org/protobuf/encoding/prototext"
@@ -38,12 +39,16 @@ import (
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/editions"
 	"github.com/kralicky/protocompile/internal/messageset"
 	"github.com/kralicky/protocompile/linker"
 	"github.com/kralicky/protocompile/parser"
 	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/protoutil"
 	"github.com/kralicky/protocompile/reporter"
 	"github.com/kralicky/protocompile/sourceinfo"
 )
@@ -57,6 +62,16 @@ type interpreter struct {
 	index                   sourceinfo.OptionIndex
 	pathBuffer              []int32
 	descriptorIndex         sourceinfo.OptionDescriptorIndex
+	names                   *protointernal.Interner
+	mapKeyNodes             map[mapEntryKey]ast.Node
+	pseudoOptions           []fieldPseudoOption
+	validators              map[protoreflect.FullName][]OptionValidator
+	enumValues              *linker.EnumValueIndex
+}
+
+type fieldPseudoOption struct {
+	name    string
+	handler FieldPseudoOptionHandler
 }
 
 type file interface {
@@ -87,6 +102,53 @@ func WithOverrideDescriptorProto(f linker.File) InterpreterOption {
 	}
 }
 
+// FieldPseudoOptionHandler consumes a custom field pseudo-option. It is given
+// the field on which the option appears, the uninterpreted option, and the
+// option's AST node, which is nil if the file has no AST. If it returns an
+// error, the error is reported at the position of the option.
+type FieldPseudoOptionHandler func(fld protoreflect.FieldDescriptor, opt *descriptorpb.UninterpretedOption, node *ast.OptionNode) error
+
+// WithFieldPseudoOption returns an option that registers an additional field
+// pseudo-option with the given name. Like the built-in "default" and
+// "json_name" pseudo-options, an option on a field whose name is exactly the
+// given name is not interpreted as a field of google.protobuf.FieldOptions.
+// Instead, it is passed to the given handler and then removed from the field's
+// uninterpreted options, so it does not appear in the resulting descriptor.
+//
+// This is intended for experimental annotations that are consumed by tools
+// and are not meant to be part of the descriptor. The name should not be the
+// name of a field of google.protobuf.FieldOptions, since such an option would
+// be intercepted instead of interpreted. The option may appear at most once
+// per field.
+func WithFieldPseudoOption(name string, handler FieldPseudoOptionHandler) InterpreterOption {
+	return func(interp *interpreter) {
+		interp.pseudoOptions = append(interp.pseudoOptions, fieldPseudoOption{name: name, handler: handler})
+	}
+}
+
+// WithNameInterner returns an option that causes the interpreter to use the
+// given interner when computing the fully-qualified names of elements. This is
+// typically the interner of the symbol table used to link the file (see
+// linker.Symbols.Names), so that names are shared with the linked descriptors.
+// If not specified, the interpreter uses its own interner.
+func WithNameInterner(names *protointernal.Interner) InterpreterOption {
+	return func(interp *interpreter) {
+		interp.names = names
+	}
+}
+
+// WithEnumValueIndex returns an option that causes the interpreter to use the
+// given index when an option value refers to an enum value name that does not
+// exist in the expected enum. If the name belongs to a different enum, the
+// error mentions it. This is useful to share a single index across all files
+// in a workspace. If not specified, the interpreter lazily builds an index of
+// the file being interpreted and its transitive imports.
+func WithEnumValueIndex(index *linker.EnumValueIndex) InterpreterOption {
+	return func(interp *interpreter) {
+		interp.enumValues = index
+	}
+}
+
 func WithInterpretLenient() InterpreterOption {
 	return func(interp *interpreter) {
 		interp.lenient = true
@@ -144,6 +206,9 @@ func interpretOptions(file file, res linker.Resolver, handler *reporter.Handler,
 	for _, opt := range interpOpts {
 		opt(&interp)
 	}
+	if interp.names == nil {
+		interp.names = protointernal.NewInterner()
+	}
 	// We have to do this in two phases. First we interpret non-custom options.
 	// This allows us to handle standard options and features that may needed to
 	// correctly reference the custom options in the second phase.
@@ -157,42 +222,95 @@ func interpretOptions(file file, res linker.Resolver, handler *reporter.Handler,
 	return interp.index, interp.descriptorIndex, nil
 }
 
+// EvaluateOptionValue computes the value of the given AST node val as if it
+// were assigned to the field fld in an option on an element of the given
+// target type. Unlike InterpretOptions, this does not interpret any of the
+// options in the given linked result, nor does it mutate it; the result is
+// only used to resolve extension names that appear in message literals and
+// to report source positions.
+//
+// The given handler is used to report errors and warnings. If any errors are
+// reported, this function returns a non-nil error and the returned value is
+// not valid.
+//
+// If fld is repeated and val is an array literal, the returned value is a list
+// (or map). Otherwise, it is a single value: for repeated fields, this is the
+// value of a single element.
+func EvaluateOptionValue(
+	linked linker.Result,
+	targetType descriptorpb.FieldOptions_OptionTargetType,
+	fld protoreflect.FieldDescriptor,
+	val *ast.ValueNode,
+	handler *reporter.Handler,
+	opts ...InterpreterOption,
+) (protoreflect.Value, error) {
+	interp := interpreter{
+		file:            linked,
+		resolver:        linker.ResolverFromFile(linked),
+		handler:         handler,
+		index:           sourceinfo.OptionIndex{},
+		descriptorIndex: sourceinfo.NewOptionDescriptorIndex(),
+		pathBuffer:      make([]int32, 0, 16),
+	}
+	for _, opt := range opts {
+		opt(&interp)
+	}
+	if interp.names == nil {
+		interp.names = protointernal.NewInterner()
+	}
+	if fld.IsExtension() {
+		if _, ok := fld.(protoreflect.ExtensionTypeDescriptor); !ok {
+			fld = dynamicpb.NewExtensionType(fld).TypeDescriptor()
+		}
+	}
+	mc := &protointernal.MessageContext{
+		File:        linked,
+		ElementType: "file",
+	}
+	msg := dynamicpb.NewMessage(fld.ContainingMessage())
+	if _, isList := val.Value().([]*ast.ValueNode); isList || !fld.IsList() && !fld.IsMap() {
+		if _, err := interp.setOptionField(targetType, mc, msg, fld, val, val, false, nil); err != nil || !msg.Has(fld) {
+			return protoreflect.Value{}, err
+		}
+		return msg.Get(fld), nil
+	}
+	v, _, err := interp.fieldValue(targetType, mc, msg, fld, val, false, nil)
+	return v, err
+}
+
 func (interp *interpreter) interpretFileOptions(file file, customOpts bool) error {
 	fd := file.FileDescriptorProto()
 	prefix := fd.GetPackage()
-	if prefix != "" {
-		prefix += "."
-	}
 	err := interpretElementOptions(interp, fd.GetName(), targetTypeFile, fd, customOpts)
 	if err != nil {
 		return err
 	}
 	for _, md := range fd.GetMessageType() {
-		fqn := prefix + md.GetName()
+		fqn := interp.names.Join(prefix, md.GetName())
 		if err := interp.interpretMessageOptions(fqn, md, customOpts); err != nil {
 			return err
 		}
 	}
 	for _, fld := range fd.GetExtension() {
-		fqn := prefix + fld.GetName()
+		fqn := interp.names.Join(prefix, fld.GetName())
 		if err := interp.interpretFieldOptions(fqn, fld, customOpts); err != nil {
 			return err
 		}
 	}
 	for _, ed := range fd.GetEnumType() {
-		fqn := prefix + ed.GetName()
+		fqn := interp.names.Join(prefix, ed.GetName())
 		if err := interp.interpretEnumOptions(fqn, ed, customOpts); err != nil {
 			return err
 		}
 	}
 	for _, sd := range fd.GetService() {
-		fqn := prefix + sd.GetName()
+		fqn := interp.names.Join(prefix, sd.GetName())
 		err := interpretElementOptions(interp, fqn, targetTypeService, sd, customOpts)
 		if err != nil {
 			return err
 		}
 		for _, mtd := range sd.GetMethod() {
-			mtdFqn := fqn + "." + mtd.GetName()
+			mtdFqn := interp.names.Join(fqn, mtd.GetName())
 			err := interpretElementOptions(interp, mtdFqn, targetTypeMethod, mtd, customOpts)
 			if err != nil {
 				return err
@@ -260,20 +378,20 @@ func (interp *interpreter) interpretMessageOptions(fqn string, md *descriptorpb.
 		return err
 	}
 	for _, fld := range md.GetField() {
-		fldFqn := fqn + "." + fld.GetName()
+		fldFqn := interp.names.Join(fqn, fld.GetName())
 		if err := interp.interpretFieldOptions(fldFqn, fld, customOpts); err != nil {
 			return err
 		}
 	}
 	for _, ood := range md.GetOneofDecl() {
-		oodFqn := fqn + "." + ood.GetName()
+		oodFqn := interp.names.Join(fqn, ood.GetName())
 		err := interpretElementOptions(interp, oodFqn, targetTypeOneof, ood, customOpts)
 		if err != nil {
 			return err
 		}
 	}
 	for _, fld := range md.GetExtension() {
-		fldFqn := fqn + "." + fld.GetName()
+		fldFqn := interp.names.Join(fqn, fld.GetName())
 		if err := interp.interpretFieldOptions(fldFqn, fld, customOpts); err != nil {
 			return err
 		}
@@ -286,13 +404,13 @@ func (interp *interpreter) interpretMessageOptions(fqn string, md *descriptorpb.
 		}
 	}
 	for _, nmd := range md.GetNestedType() {
-		nmdFqn := fqn + "." + nmd.GetName()
+		nmdFqn := interp.names.Join(fqn, nmd.GetName())
 		if err := interp.interpretMessageOptions(nmdFqn, nmd, customOpts); err != nil {
 			return err
 		}
 	}
 	for _, ed := range md.GetEnumType() {
-		edFqn := fqn + "." + ed.GetName()
+		edFqn := interp.names.Join(fqn, ed.GetName())
 		if err := interp.interpretEnumOptions(edFqn, ed, customOpts); err != nil {
 			return err
 		}
@@ -337,6 +455,8 @@ func (interp *interpreter) interpretMessageOptions(fqn string, md *descriptorpb.
 
 var emptyFieldOptions = &descriptorpb.FieldOptions{}
 
+var utf8ValidationField = editions.FeatureSetDescriptor.Fields().ByName("utf8_validation")
+
 func (interp *interpreter) interpretFieldOptions(fqn string, fld *descriptorpb.FieldDescriptorProto, customOpts bool) error {
 	opts := fld.GetOptions()
 	emptyOptionsAlreadyPresent := opts != nil && len(opts.GetUninterpretedOption()) == 0
@@ -385,7 +505,7 @@ func (interp *interpreter) interpretFieldPseudoOptions(fqn string, fld *descript
 			return interp.HandleOptionForbiddenErrorf(nil, optNode.GetName(), "%s: option json_name is not allowed on extensions", scope)
 		}
 		// attribute source code info
-		interp.index[optNode] = &sourceinfo.OptionSourceInfo{Path: []int32{-1, protointernal.FieldJSONNameTag}}
+		interp.index[optNode] = &sourceinfo.OptionSourceInfo{Path: []int32{-1, descpath.FieldJSONNameTag}}
 
 		uo = protointernal.RemoveOption(uo, index)
 		if strings.HasPrefix(jsonName, "[") && strings.HasSuffix(jsonName, "]") {
@@ -409,7 +529,7 @@ func (interp *interpreter) interpretFieldPseudoOptions(fqn string, fld *descript
 		interp.descriptorIndex.FieldReferenceNodesToFieldDescriptors[nm] = fldDesc
 		// attribute source code info
 		optNode := interp.file.OptionNode(uo[index])
-		interp.index[optNode] = &sourceinfo.OptionSourceInfo{Path: []int32{-1, protointernal.FieldDefaultTag}}
+		interp.index[optNode] = &sourceinfo.OptionSourceInfo{Path: []int32{-1, descpath.FieldDefaultTag}}
 
 		if optNode != nil && fldDesc != nil && fldDesc.Kind() == protoreflect.EnumKind {
 			interp.indexEnumValueRef(fldDesc, optNode.Val)
@@ -417,6 +537,27 @@ func (interp *interpreter) interpretFieldPseudoOptions(fqn string, fld *descript
 		uo = protointernal.RemoveOption(uo, index)
 	}
 
+	// and finally any registered custom pseudo-options
+	for _, pseudoOpt := range interp.pseudoOptions {
+		index, err := protointernal.FindOption(interp.file, interp.handler, scope, uo, pseudoOpt.name)
+		if err != nil && !interp.lenient {
+			return err
+		}
+		if index < 0 {
+			continue
+		}
+		optNode := interp.file.OptionNode(uo[index])
+		fldDesc := resolveDescriptor[protoreflect.FieldDescriptor](interp.resolver, protoreflect.FullName(fqn))
+		if err := pseudoOpt.handler(fldDesc, uo[index], optNode); err != nil {
+			if err := interp.HandleOptionValueErrorf(nil, optNode.GetVal(), "%s: option %s: %w", scope, pseudoOpt.name, err); err != nil && !interp.lenient {
+				return err
+			}
+		}
+		// there is no corresponding location in the descriptor
+		interp.index[optNode] = &sourceinfo.OptionSourceInfo{}
+		uo = protointernal.RemoveOption(uo, index)
+	}
+
 	opts.UninterpretedOption = uo
 	return nil
 }
@@ -453,6 +594,7 @@ func (interp *interpreter) processDefaultOption(scope string, fqn string, fld *d
 	if err != nil {
 		return -1, err
 	}
+	interp.recordFieldDefault(fqn, fld, v, optNode)
 
 	if str, ok := v.(string); ok {
 		fld.DefaultValue = proto.String(str)
@@ -485,6 +627,31 @@ func (interp *interpreter) processDefaultOption(scope string, fqn string, fld *d
 	return found, nil
 }
 
+// recordFieldDefault records the typed value v of the default pseudo-option
+// for the given field, before it is flattened into the descriptor's string
+// representation.
+func (interp *interpreter) recordFieldDefault(fqn string, fld *descriptorpb.FieldDescriptorProto, v interface{}, optNode *ast.OptionNode) {
+	var val protoreflect.Value
+	if fld.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+		ed := resolveDescriptor[protoreflect.EnumDescriptor](interp.resolver, protoreflect.FullName(fld.GetTypeName()))
+		name, _ := v.(string)
+		if ed == nil {
+			return
+		}
+		ev := ed.Values().ByName(protoreflect.Name(name))
+		if ev == nil {
+			return
+		}
+		val = protoreflect.ValueOfEnum(ev.Number())
+	} else {
+		val = protoreflect.ValueOf(v)
+	}
+	interp.descriptorIndex.FieldDefaults[protoreflect.FullName(fqn)] = sourceinfo.FieldDefault{
+		Value:  val,
+		Option: optNode,
+	}
+}
+
 func (interp *interpreter) defaultValue(mc *protointernal.MessageContext, fld *descriptorpb.FieldDescriptorProto, val *ast.ValueNode) (interface{}, error) {
 	if msgLit := val.GetMessageLiteral(); msgLit != nil {
 		return -1, interp.HandleOptionForbiddenErrorf(mc, val, "default value cannot be a message")
@@ -533,7 +700,7 @@ func (interp *interpreter) interpretEnumOptions(fqn string, ed *descriptorpb.Enu
 		return err
 	}
 	for _, evd := range ed.GetValue() {
-		evdFqn := fqn + "." + evd.GetName()
+		evdFqn := interp.names.Join(fqn, evd.GetName())
 		err := interpretElementOptions(interp, evdFqn, targetTypeEnumValue, evd, customOpts)
 		if err != nil {
 			return err
@@ -678,6 +845,9 @@ func (interp *interpreter) interpretOptions(
 		if err != nil {
 			return nil, err
 		}
+		if err := interp.validateOptions(fqn, targetType, element, msg, uninterpreted); err != nil {
+			return nil, err
+		}
 	}
 
 	if interp.lenient {
@@ -961,27 +1131,28 @@ func (interp *interpreter) validateFeatureSupport(
 	path []int32,
 	element proto.Message,
 ) error {
-	if featureSupport.EditionIntroduced != nil && edition < featureSupport.GetEditionIntroduced() {
+	status := editions.CheckFeatureSupport(featureSupport, edition)
+	if status.IntroducedIn != descriptorpb.Edition_EDITION_UNKNOWN {
 		node := interp.findOptionNode(path, element)
-		err := interp.HandleOptionForbiddenErrorf(mc, node, "%s %q was not introduced until edition %s", what, name, editionString(featureSupport.GetEditionIntroduced()))
+		err := interp.HandleOptionForbiddenErrorf(mc, node, "%s %q was not introduced until edition %s", what, name, editionString(status.IntroducedIn))
 		if err != nil {
 			return err
 		}
 	}
-	if featureSupport.EditionRemoved != nil && edition >= featureSupport.GetEditionRemoved() {
+	if status.RemovedIn != descriptorpb.Edition_EDITION_UNKNOWN {
 		node := interp.findOptionNode(path, element)
-		err := interp.HandleOptionForbiddenErrorf(mc, node, "%s %q was removed in edition %s", what, name, editionString(featureSupport.GetEditionRemoved()))
+		err := interp.HandleOptionForbiddenErrorf(mc, node, "%s %q was removed in edition %s", what, name, editionString(status.RemovedIn))
 		if err != nil {
 			return err
 		}
 	}
-	if featureSupport.EditionDeprecated != nil && edition >= featureSupport.GetEditionDeprecated() {
+	if status.Deprecated() {
 		node := interp.findOptionNode(path, element)
 		var suffix string
-		if featureSupport.GetDeprecationWarning() != "" {
-			suffix = ": " + featureSupport.GetDeprecationWarning()
+		if status.DeprecationWarning != "" {
+			suffix = ": " + status.DeprecationWarning
 		}
-		interp.handler.HandleWarningf(interp.nodeInfo(node), "%s %q is deprecated as of edition %s%s", what, name, editionString(featureSupport.GetEditionDeprecated()), suffix)
+		interp.handler.HandleWarningf(interp.nodeInfo(node), "%s %q is deprecated as of edition %s%s", what, name, editionString(status.DeprecatedIn), suffix)
 	}
 	return nil
 }
@@ -1164,7 +1335,8 @@ func (interp *interpreter) interpretField(
 		var err error
 		fld, err = interp.resolveExtensionType(extName)
 		if err != nil {
-			return nil, interp.HandleOptionNotFoundErrorf(mc, node, "unrecognized extension %s of %s", extName, msg.Descriptor().FullName())
+			suggestions := interp.extensionSuggestions(msg.Descriptor().FullName(), extName)
+			return nil, interp.handleOptionNotFoundError(mc, node, suggestions, "unrecognized extension %s of %s", extName, msg.Descriptor().FullName())
 		}
 		if fld.ContainingMessage().FullName() != msg.Descriptor().FullName() {
 			return nil, interp.HandleOptionForbiddenErrorf(mc, node, "extension %s should extend %s but instead extends %s", extName, msg.Descriptor().FullName(), fld.ContainingMessage().FullName())
@@ -1172,7 +1344,8 @@ func (interp *interpreter) interpretField(
 	} else {
 		fld = msg.Descriptor().Fields().ByName(protoreflect.Name(nm.GetNamePart()))
 		if fld == nil {
-			return nil, interp.HandleOptionNotFoundErrorf(mc, node, "field %s of %s does not exist", nm.GetNamePart(), msg.Descriptor().FullName())
+			suggestions := fieldSuggestions(msg.Descriptor(), nm.GetNamePart(), false)
+			return nil, interp.handleOptionNotFoundError(mc, node, suggestions, "field %s of %s does not exist", nm.GetNamePart(), msg.Descriptor().FullName())
 		}
 	}
 	interp.descriptorIndex.UninterpretedNameDescriptorsToFieldDescriptors[nm] = fld
@@ -1270,7 +1443,9 @@ func (interp *interpreter) setOptionField(
 			}
 			if fld.IsMap() {
 				mv := msg.Mutable(fld).Map()
-				setMapEntry(fld, msg, mv, value.Message())
+				if err := interp.setMapEntry(mc, fld, msg, mv, value.Message(), item); err != nil {
+					return nil, err
+				}
 			} else {
 				lv := msg.Mutable(fld).List()
 				lv.Append(value)
@@ -1305,7 +1480,9 @@ func (interp *interpreter) setOptionField(
 	switch {
 	case fld.IsMap():
 		mv := msg.Mutable(fld).Map()
-		setMapEntry(fld, msg, mv, value.Message())
+		if err := interp.setMapEntry(mc, fld, msg, mv, value.Message(), val); err != nil {
+			return nil, err
+		}
 	case fld.IsList():
 		lv := msg.Mutable(fld).List()
 		lv.Append(value)
@@ -1371,6 +1548,11 @@ func (interp *interpreter) setOptionFieldFromProto(
 		if err != nil {
 			return interp.HandleOptionValueErrorf(mc, node, "%w", err)
 		}
+		if str, ok := v.(string); ok {
+			if err := interp.checkUTF8(mc, fld, str, node); err != nil {
+				return err
+			}
+		}
 		value = protoreflect.ValueOf(v)
 	}
 
@@ -1384,7 +1566,9 @@ func (interp *interpreter) setOptionFieldFromProto(
 	switch {
 	case fld.IsMap():
 		mv := msg.Mutable(fld).Map()
-		setMapEntry(fld, msg, mv, value.Message())
+		if err := interp.setMapEntry(mc, fld, msg, mv, value.Message(), node); err != nil {
+			return err
+		}
 	case fld.IsList():
 		msg.Mutable(fld).List().Append(value)
 	default:
@@ -1440,15 +1624,43 @@ func (interp *interpreter) checkFieldUsagesInMessage(
 	return err
 }
 
-func setMapEntry(
+// mapEntryKey identifies an entry in a map field of an options message. It is
+// used to remember where each map key was first set, for reporting duplicates.
+type mapEntryKey struct {
+	msg protoreflect.Message
+	fld protoreflect.FieldNumber
+	key any
+}
+
+// setMapEntry adds the given entry to the map field fld of msg. The given node
+// is the source of the entry and is used to report an error if the entry's key
+// is already present in the map.
+func (interp *interpreter) setMapEntry(
+	mc *protointernal.MessageContext,
 	fld protoreflect.FieldDescriptor,
 	msg protoreflect.Message,
 	mapVal protoreflect.Map,
 	entry protoreflect.Message,
-) {
+	node ast.Node,
+) error {
 	keyFld, valFld := fld.MapKey(), fld.MapValue()
 	key := entry.Get(keyFld)
 	val := entry.Get(valFld)
+	entryKey := mapEntryKey{msg: msg, fld: fld.Number(), key: key.Interface()}
+	if mapVal.Has(key.MapKey()) {
+		keyStr := fmt.Sprintf("%v", key.Interface())
+		if keyFld.Kind() == protoreflect.StringKind {
+			keyStr = strconv.Quote(key.String())
+		}
+		if first, ok := interp.mapKeyNodes[entryKey]; ok && first != nil {
+			return interp.HandleOptionValueErrorf(mc, node, "duplicate key %s for map field %s; first set at %v", keyStr, fieldName(fld), interp.nodeInfo(first).Start())
+		}
+		return interp.HandleOptionValueErrorf(mc, node, "duplicate key %s for map field %s", keyStr, fieldName(fld))
+	}
+	if interp.mapKeyNodes == nil {
+		interp.mapKeyNodes = map[mapEntryKey]ast.Node{}
+	}
+	interp.mapKeyNodes[entryKey] = node
 	if fld.MapValue().Kind() == protoreflect.MessageKind {
 		// Replace any nil/invalid values with an empty message
 		dm, valIsDynamic := val.Interface().(*dynamicpb.Message)
@@ -1471,8 +1683,8 @@ func setMapEntry(
 			}
 		}
 	}
-	// TODO: error if key is already present
 	mapVal.Set(key.MapKey(), val)
+	return nil
 }
 
 type msgLiteralResolver struct {
@@ -1625,6 +1837,9 @@ func (interp *interpreter) fieldValue(
 				// Normal message field
 				childMsg = msg.NewField(fld).Message()
 			}
+			if lit := val.GetMessageLiteral(); lit != nil {
+				interp.descriptorIndex.MessageLiteralsToMessageDescriptors[lit] = childMsg.Descriptor()
+			}
 			return interp.messageLiteralValue(targetType, mc, aggs, childMsg, pathPrefix)
 		}
 		return protoreflect.Value{}, sourceinfo.OptionSourceInfo{},
@@ -1635,10 +1850,29 @@ func (interp *interpreter) fieldValue(
 		if err != nil {
 			return protoreflect.Value{}, sourceinfo.OptionSourceInfo{}, interp.handler.HandleError(err)
 		}
+		if str, ok := v.(string); ok {
+			if err := interp.checkUTF8(mc, fld, str, val); err != nil {
+				return protoreflect.Value{}, sourceinfo.OptionSourceInfo{}, err
+			}
+		}
 		return protoreflect.ValueOf(v), newSrcInfo(pathPrefix, nil), nil
 	}
 }
 
+// checkUTF8 reports an error if the given value of the given string field is
+// not valid UTF-8 and the field's effective utf8_validation feature is VERIFY.
+func (interp *interpreter) checkUTF8(mc *protointernal.MessageContext, fld protoreflect.FieldDescriptor, str string, node ast.Node) error {
+	offset := protointernal.InvalidUTF8Offset(str)
+	if offset < 0 || fld.Kind() != protoreflect.StringKind {
+		return nil
+	}
+	utf8Validation, err := protoutil.ResolveFeature(fld, utf8ValidationField)
+	if err != nil || descriptorpb.FeatureSet_Utf8Validation(utf8Validation.Enum()) != descriptorpb.FeatureSet_VERIFY {
+		return nil
+	}
+	return interp.HandleOptionValueErrorf(mc, node, "value for string field %s is not valid UTF-8 (invalid byte at offset %d), but field's utf8_validation feature is VERIFY", fld.Name(), offset)
+}
+
 // enumFieldValue resolves the given AST node val as an enum value descriptor. If the given
 // value is not a valid identifier (or number if allowed), an error is returned instead.
 func (interp *interpreter) enumFieldValue(
@@ -1654,7 +1888,7 @@ func (interp *interpreter) enumFieldValue(
 		name := protoreflect.Name(v)
 		ev := ed.Values().ByName(name)
 		if ev == nil {
-			return 0, "", interp.HandleOptionValueErrorf(mc, val, "enum %s has no value named %s", ed.FullName(), v)
+			return 0, "", interp.HandleOptionValueErrorf(mc, val, "enum %s has no value named %s%s", ed.FullName(), v, interp.unknownEnumValueHint(ed, name))
 		}
 		return ev.Number(), name, nil
 	case int64:
@@ -1703,7 +1937,7 @@ func (interp *interpreter) enumFieldValueFromProto(
 		name := protoreflect.Name(opt.GetIdentifierValue())
 		ev := ed.Values().ByName(name)
 		if ev == nil {
-			return 0, "", interp.HandleOptionValueErrorf(mc, node, "enum %s has no value named %s", ed.FullName(), name)
+			return 0, "", interp.HandleOptionValueErrorf(mc, node, "enum %s has no value named %s%s", ed.FullName(), name, interp.unknownEnumValueHint(ed, name))
 		}
 		return ev.Number(), name, nil
 	default:
@@ -1711,6 +1945,40 @@ func (interp *interpreter) enumFieldValueFromProto(
 	}
 }
 
+// unknownEnumValueHint returns a clause to append to an error about the given
+// name not being a value of the given enum. It suggests values of the enum with
+// similar names or, failing that, names the other enums that have a value with
+// the given name. It returns the empty string if there is nothing to suggest.
+func (interp *interpreter) unknownEnumValueHint(ed protoreflect.EnumDescriptor, name protoreflect.Name) string {
+	vals := ed.Values()
+	candidates := make([]string, vals.Len())
+	for i := 0; i < vals.Len(); i++ {
+		candidates[i] = string(vals.Get(i).Name())
+	}
+	if suggestions := protointernal.Suggestions(string(name), candidates, 3); len(suggestions) > 0 {
+		return protointernal.DidYouMean(suggestions)
+	}
+	if interp.enumValues == nil {
+		fd, ok := interp.file.(protoreflect.FileDescriptor)
+		if !ok {
+			return ""
+		}
+		interp.enumValues = linker.NewEnumValueIndex(fd)
+	}
+	others := interp.enumValues.Lookup(name)
+	if len(others) == 0 {
+		return ""
+	}
+	enums := make([]string, 0, len(others))
+	for _, ev := range others {
+		enums = append(enums, string(ev.Parent().FullName()))
+	}
+	if len(enums) == 1 {
+		return fmt.Sprintf("; %s is a value of enum %s", name, enums[0])
+	}
+	return fmt.Sprintf("; %s is a value of enums %s", name, strings.Join(enums, ", "))
+}
+
 func (interp *interpreter) indexEnumValueRef(fld protoreflect.FieldDescriptor, optValNode *ast.ValueNode) {
 	enumDesc := fld.Enum()
 	switch v := optValNode.Unwrap().(type) {
@@ -2055,15 +2323,15 @@ func (interp *interpreter) messageLiteralValue(
 				hadError = true
 				continue
 			}
-			typeURLDescriptor := fmd.Fields().ByNumber(protointernal.AnyTypeURLTag)
+			typeURLDescriptor := fmd.Fields().ByNumber(descpath.AnyTypeURLTag)
 			var err error
 			switch {
 			case typeURLDescriptor == nil:
-				err = fmt.Errorf("message schema is missing type_url field (number %d)", protointernal.AnyTypeURLTag)
+				err = fmt.Errorf("message schema is missing type_url field (number %d)", descpath.AnyTypeURLTag)
 			case typeURLDescriptor.IsList():
-				err = fmt.Errorf("message schema has type_url field (number %d) that is a list but should be singular", protointernal.AnyTypeURLTag)
+				err = fmt.Errorf("message schema has type_url field (number %d) that is a list but should be singular", descpath.AnyTypeURLTag)
 			case typeURLDescriptor.Kind() != protoreflect.StringKind:
-				err = fmt.Errorf("message schema has type_url field (number %d) that is %s but should be string", protointernal.AnyTypeURLTag, typeURLDescriptor.Kind())
+				err = fmt.Errorf("message schema has type_url field (number %d) that is %s but should be string", descpath.AnyTypeURLTag, typeURLDescriptor.Kind())
 			}
 			if err != nil {
 				err := interp.HandleOptionValueErrorf(mc, fieldNode.Name, "%w", err)
@@ -2073,14 +2341,14 @@ func (interp *interpreter) messageLiteralValue(
 				hadError = true
 				continue
 			}
-			valueDescriptor := fmd.Fields().ByNumber(protointernal.AnyValueTag)
+			valueDescriptor := fmd.Fields().ByNumber(descpath.AnyValueTag)
 			switch {
 			case valueDescriptor == nil:
-				err = fmt.Errorf("message schema is missing value field (number %d)", protointernal.AnyValueTag)
+				err = fmt.Errorf("message schema is missing value field (number %d)", descpath.AnyValueTag)
 			case valueDescriptor.IsList():
-				err = fmt.Errorf("message schema has value field (number %d) that is a list but should be singular", protointernal.AnyValueTag)
+				err = fmt.Errorf("message schema has value field (number %d) that is a list but should be singular", descpath.AnyValueTag)
 			case valueDescriptor.Kind() != protoreflect.BytesKind:
-				err = fmt.Errorf("message schema has value field (number %d) that is %s but should be bytes", protointernal.AnyValueTag, valueDescriptor.Kind())
+				err = fmt.Errorf("message schema has value field (number %d) that is %s but should be bytes", descpath.AnyValueTag, valueDescriptor.Kind())
 			}
 			if err != nil {
 				err := interp.HandleOptionValueErrorf(mc, fieldNode.Name, "%w", err)
@@ -2128,8 +2396,11 @@ func (interp *interpreter) messageLiteralValue(
 				hadError = true
 				continue
 			}
+			if lit := fieldNode.Val.GetMessageLiteral(); lit != nil {
+				interp.descriptorIndex.MessageLiteralsToMessageDescriptors[lit] = anyMd
+			}
 			// parse the message value
-			msgVal, valueSrcInfo, err := interp.messageLiteralValue(targetType, mc, anyFields, dynamicpb.NewMessage(anyMd), append(pathPrefix, protointernal.AnyValueTag))
+			msgVal, valueSrcInfo, err := interp.messageLiteralValue(targetType, mc, anyFields, dynamicpb.NewMessage(anyMd), append(pathPrefix, descpath.AnyValueTag))
 			if err != nil {
 				return protoreflect.Value{}, sourceinfo.OptionSourceInfo{}, err
 			} else if !msgVal.IsValid() {
@@ -2150,6 +2421,7 @@ func (interp *interpreter) messageLiteralValue(
 			// Success!
 			if !hadError {
 				interp.descriptorIndex.TypeReferenceURLsToMessageDescriptors[fieldNode.Name] = anyMd
+				interp.descriptorIndex.TypeReferenceURLsToAnyValues[fieldNode.Name] = &anypb.Any{TypeUrl: fullURL, Value: b}
 				msg.Set(typeURLDescriptor, protoreflect.ValueOfString(fullURL))
 				msg.Set(valueDescriptor, protoreflect.ValueOfBytes(b))
 				flds[fieldNode] = &valueSrcInfo
@@ -2198,7 +2470,11 @@ func (interp *interpreter) messageLiteralValue(
 			}
 		}
 		if errors.Is(err, protoregistry.NotFound) {
-			err := interp.HandleOptionNotFoundErrorf(mc, fieldNode.Name, "field %s not found", string(fieldNode.Name.Name.AsIdentifier()))
+			var suggestions []string
+			if !fieldNode.Name.IsExtension() {
+				suggestions = fieldSuggestions(fmd, fieldNode.Name.Value(), true)
+			}
+			err := interp.handleOptionNotFoundError(mc, fieldNode.Name, suggestions, "field %s not found", string(fieldNode.Name.Name.AsIdentifier()))
 			if err != nil {
 				return protoreflect.Value{}, sourceinfo.OptionSourceInfo{}, err
 			}
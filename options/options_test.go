@@ -19,10 +19,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,12 +36,14 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 
 	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/linker"
 	"github.com/kralicky/protocompile/options"
 	"github.com/kralicky/protocompile/parser"
 	"github.com/kralicky/protocompile/protointernal/prototest"
 	"github.com/kralicky/protocompile/protoutil"
 	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/protocompile/sourceinfo"
 )
 
 type (
@@ -612,3 +616,559 @@ func TestInterpretOptionsFeatureLifetimeWarnings(t *testing.T) {
 	sort.Strings(warnings)
 	assert.Equal(t, expectedWarnings, warnings)
 }
+
+func TestEvaluateOptionValue(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto3";
+			import "google/protobuf/descriptor.proto";
+			package foo;
+			enum E { E_ZERO = 0; E_ONE = 1; }
+			message Opts {
+				int32 n = 1;
+				repeated string s = 2;
+				E e = 3;
+				map<string, int32> m = 4;
+			}
+			extend google.protobuf.FileOptions { Opts opts = 10101; }
+			option (opts) = { n: 1 s: ["a", "b"] e: E_ONE m: [{key: "x" value: 1}] };
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		RetainASTs: true,
+	}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	res := files.Files[0].(linker.Result)
+
+	var optVal *ast.ValueNode
+	for _, decl := range res.AST().Decls {
+		if opt := decl.GetOption(); opt != nil {
+			optVal = opt.Val
+		}
+	}
+	require.NotNil(t, optVal)
+	fieldVals := map[string]*ast.ValueNode{}
+	for _, fieldNode := range optVal.GetMessageLiteral().GetElements() {
+		fieldVals[fieldNode.Name.Value()] = fieldNode.Val
+	}
+	optsExt := res.Extensions().ByName("opts")
+	optsMsg := res.Messages().ByName("Opts")
+	fileTarget := descriptorpb.FieldOptions_TARGET_TYPE_FILE
+
+	val, err := options.EvaluateOptionValue(res, fileTarget, optsExt, optVal, reporter.NewHandler(nil))
+	require.NoError(t, err)
+	msg := val.Message()
+	assert.Equal(t, int32(1), int32(msg.Get(optsMsg.Fields().ByName("n")).Int()))
+	assert.Equal(t, 2, msg.Get(optsMsg.Fields().ByName("s")).List().Len())
+	assert.Equal(t, protoreflect.EnumNumber(1), msg.Get(optsMsg.Fields().ByName("e")).Enum())
+	assert.Equal(t, 1, msg.Get(optsMsg.Fields().ByName("m")).Map().Len())
+
+	val, err = options.EvaluateOptionValue(res, fileTarget, optsMsg.Fields().ByName("s"), fieldVals["s"], reporter.NewHandler(nil))
+	require.NoError(t, err)
+	require.Equal(t, 2, val.List().Len())
+	assert.Equal(t, "b", val.List().Get(1).String())
+
+	val, err = options.EvaluateOptionValue(res, fileTarget, optsMsg.Fields().ByName("e"), fieldVals["e"], reporter.NewHandler(nil))
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.EnumNumber(1), val.Enum())
+
+	// A value of the wrong type is reported through the handler.
+	var reported []reporter.ErrorWithPos
+	handler := reporter.NewHandler(reporter.NewReporter(
+		func(err reporter.ErrorWithPos) error {
+			reported = append(reported, err)
+			return nil
+		},
+		nil,
+	))
+	val, err = options.EvaluateOptionValue(res, fileTarget, optsMsg.Fields().ByName("n"), fieldVals["e"], handler)
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.False(t, val.IsValid())
+	require.Len(t, reported, 1)
+	assert.Contains(t, reported[0].Error(), "expecting int32")
+}
+
+func TestFieldDefaults(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto2";
+			package foo;
+			enum E { E_ZERO = 0; E_ONE = 1; }
+			message M {
+				optional bytes b = 1 [default = "\x00ab"];
+				optional double d = 2 [default = -inf];
+				optional E e = 3 [default = E_ONE];
+				optional int32 i = 4;
+				repeated string s = 5;
+			}
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+		RetainASTs:     true,
+	}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	res := files.Files[0].(linker.Result)
+	fields := res.Messages().ByName("M").Fields()
+
+	def := res.FindFieldDefault(fields.ByName("b"))
+	require.NotNil(t, def.Option)
+	assert.Equal(t, `default = "\x00ab"`, res.AST().NodeInfo(def.Option).RawText())
+	assert.Equal(t, []byte("\x00ab"), def.Value.Bytes())
+
+	def = res.FindFieldDefault(fields.ByName("d"))
+	require.NotNil(t, def.Option)
+	assert.True(t, math.IsInf(def.Value.Float(), -1))
+
+	def = res.FindFieldDefault(fields.ByName("e"))
+	require.NotNil(t, def.Option)
+	assert.Equal(t, protoreflect.EnumNumber(1), def.Value.Enum())
+
+	// Implicit defaults have no option node.
+	def = res.FindFieldDefault(fields.ByName("i"))
+	assert.Nil(t, def.Option)
+	assert.Equal(t, int64(0), def.Value.Int())
+
+	def = res.FindFieldDefault(fields.ByName("s"))
+	assert.Nil(t, def.Option)
+	assert.False(t, def.Value.IsValid())
+}
+
+func TestOptionDescriptorIndexWithoutSourceInfo(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto3";
+			import "google/protobuf/descriptor.proto";
+			package foo;
+			enum E { E_ZERO = 0; E_ONE = 1; }
+			extend google.protobuf.FileOptions { E enum_option = 10101; }
+			option (enum_option) = E_ONE;
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		RetainASTs: true,
+	}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	res := files.Files[0].(linker.Result)
+	require.Nil(t, res.FileDescriptorProto().GetSourceCodeInfo())
+
+	index := res.OptionDescriptorIndex()
+	ext := res.Extensions().ByName("enum_option")
+	var extRefs int
+	for _, desc := range index.FieldReferenceNodesToFieldDescriptors {
+		if desc.FullName() == ext.FullName() {
+			extRefs++
+		}
+	}
+	assert.Equal(t, 1, extRefs)
+	require.Len(t, index.EnumValueIdentNodesToEnumValueDescriptors, 1)
+	for _, desc := range index.EnumValueIdentNodesToEnumValueDescriptors {
+		assert.Equal(t, protoreflect.FullName("foo.E_ONE"), desc.FullName())
+	}
+	assert.Len(t, res.FindReferences(res.Enums().ByName("E").Values().ByName("E_ONE")), 1)
+}
+
+func TestPopulateOptionDescriptorIndexIsIdempotent(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto3";
+			import "google/protobuf/descriptor.proto";
+			package foo;
+			enum E { E_ZERO = 0; E_ONE = 1; }
+			extend google.protobuf.FileOptions { E enum_option = 10101; }
+			option (enum_option) = E_ONE;
+			`,
+	}
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+		RetainASTs:     true,
+	}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	res := files.Files[0].(linker.Result)
+	eOne := res.Enums().ByName("E").Values().ByName("E_ONE")
+	ext := res.Extensions().ByName("enum_option")
+	require.Len(t, res.FindReferences(eOne), 1)
+	extRefs := len(res.FindReferences(ext))
+	require.Positive(t, extRefs)
+
+	// populating the same index again does not duplicate references
+	index := res.OptionDescriptorIndex()
+	res.PopulateOptionDescriptorIndex(index)
+	res.PopulateSourceCodeInfo(res.OptionIndex(), index)
+	assert.Len(t, res.FindReferences(eOne), 1)
+	assert.Len(t, res.FindReferences(ext), extRefs)
+
+	// a new index replaces the old one, along with its references
+	res.PopulateSourceCodeInfo(res.OptionIndex(), sourceinfo.NewOptionDescriptorIndex())
+	assert.Empty(t, res.OptionDescriptorIndex().EnumValueIdentNodesToEnumValueDescriptors)
+	assert.Empty(t, res.FindReferences(eOne))
+	res.PopulateOptionDescriptorIndex(index)
+	assert.Len(t, res.FindReferences(eOne), 1)
+	assert.Len(t, res.FindReferences(ext), extRefs)
+}
+
+func TestDuplicateMapKeys(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"foo.proto": `
+			syntax = "proto3";
+			import "google/protobuf/descriptor.proto";
+			message Foo { map<string, int32> m = 1; }
+			extend google.protobuf.FileOptions { Foo f = 10101; }
+			`,
+		"literal.proto": `
+			syntax = "proto3";
+			import "foo.proto";
+			option (f) = { m: [{key: "a" value: 1}, {key: "b" value: 2}, {key: "a" value: 3}] };
+			`,
+		"statements.proto": `
+			syntax = "proto3";
+			import "foo.proto";
+			option (f).m = {key: "x" value: 1};
+			option (f).m = {key: "y" value: 2};
+			option (f).m = {key: "x" value: 3};
+			`,
+	}
+	var errs []string
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			},
+			nil,
+		),
+	}
+	for _, name := range []protocompile.ResolvedPath{"literal.proto", "statements.proto"} {
+		_, err := compiler.Compile(context.Background(), name)
+		require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	}
+	assert.Equal(t, []string{
+		`literal.proto:4:65-84: duplicate key "a" for map field m; first set at literal.proto:4:23`,
+		`statements.proto:6:19-38: duplicate key "x" for map field m; first set at statements.proto:4:19`,
+	}, errs)
+}
+
+func TestUnknownEnumValueHints(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"enums.proto": `
+			syntax = "proto2";
+			package foo;
+			enum Color { RED = 0; GREEN = 1; BLUE = 2; }
+			enum Size { SMALL = 0; LARGE = 1; }
+			`,
+		"misspelled.proto": `
+			syntax = "proto2";
+			package foo;
+			import "enums.proto";
+			message Foo { optional Color c = 1 [default = GREN]; }
+			`,
+		"other_enum.proto": `
+			syntax = "proto2";
+			package foo;
+			import "enums.proto";
+			message Foo { optional Color c = 1 [default = SMALL]; }
+			`,
+		"option.proto": `
+			syntax = "proto2";
+			package foo;
+			import "enums.proto";
+			import "google/protobuf/descriptor.proto";
+			extend google.protobuf.FileOptions { optional Size size = 10101; }
+			option (size) = LARGR;
+			`,
+	}
+	var errs []string
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			},
+			nil,
+		),
+	}
+	for _, name := range []protocompile.ResolvedPath{"misspelled.proto", "other_enum.proto", "option.proto"} {
+		_, err := compiler.Compile(context.Background(), name)
+		require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	}
+	assert.Equal(t, []string{
+		`misspelled.proto:5:50-54: enum foo.Color has no value named GREN; did you mean GREEN?`,
+		`other_enum.proto:5:50-55: enum foo.Color has no value named SMALL; SMALL is a value of enum foo.Size`,
+		`option.proto:7:20-25: enum foo.Size has no value named LARGR; did you mean LARGE?`,
+	}, errs)
+}
+
+func TestOptionNotFoundSuggestions(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"foo.proto": `
+			syntax = "proto3";
+			package foo;
+			import "google/protobuf/descriptor.proto";
+			message Foo { string name = 1; int32 count = 2; }
+			extend google.protobuf.FileOptions { Foo foo = 10101; }
+			`,
+		"literal.proto": `
+			syntax = "proto3";
+			import "foo.proto";
+			option (foo.foo) = { nmae: "x" };
+			`,
+		"path.proto": `
+			syntax = "proto3";
+			import "foo.proto";
+			option (foo.foo).cuont = 1;
+			`,
+	}
+	var errs []reporter.ErrorWithPos
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(
+			func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err)
+				return nil
+			},
+			nil,
+		),
+	}
+	for _, name := range []protocompile.ResolvedPath{"literal.proto", "path.proto"} {
+		_, err := compiler.Compile(context.Background(), name)
+		require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	}
+	require.Len(t, errs, 2)
+	assert.Equal(t, `literal.proto:4:25-29: field nmae not found; did you mean name?`, errs[0].Error())
+	assert.Equal(t, `path.proto:4:21-26: field cuont of foo.Foo does not exist; did you mean count?`, errs[1].Error())
+	var notFound options.OptionNotFoundError
+	require.ErrorAs(t, errs[1], &notFound)
+	assert.Equal(t, []string{"count"}, notFound.Suggestions())
+}
+
+func TestFieldPseudoOptions(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto3";
+			package foo;
+			message M {
+				string a = 1 [experimental = "x", json_name = "A"];
+				int32 b = 2 [experimental = 42, deprecated = true];
+			}
+			`,
+	}
+	seen := map[protoreflect.FullName]string{}
+	var mu sync.Mutex
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+		FieldPseudoOptions: map[string]options.FieldPseudoOptionHandler{
+			"experimental": func(fld protoreflect.FieldDescriptor, opt *descriptorpb.UninterpretedOption, node *ast.OptionNode) error {
+				mu.Lock()
+				defer mu.Unlock()
+				require.NotNil(t, node)
+				seen[fld.FullName()] = fmt.Sprint(node.Val.Value())
+				return nil
+			},
+		},
+	}
+	files, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	assert.Equal(t, map[protoreflect.FullName]string{"foo.M.a": "x", "foo.M.b": "42"}, seen)
+
+	fields := files.Files[0].Messages().ByName("M").Fields()
+	optsA := fields.ByName("a").Options().(*descriptorpb.FieldOptions)
+	assert.Empty(t, optsA.GetUninterpretedOption())
+	assert.Equal(t, "A", fields.ByName("a").JSONName())
+	optsB := fields.ByName("b").Options().(*descriptorpb.FieldOptions)
+	assert.Empty(t, optsB.GetUninterpretedOption())
+	assert.True(t, optsB.GetDeprecated())
+
+	// Without registration, the option is interpreted as a normal option.
+	compiler.FieldPseudoOptions = nil
+	_, err = compiler.Compile(context.Background(), "test.proto")
+	require.ErrorContains(t, err, "field experimental of google.protobuf.FieldOptions does not exist")
+
+	// Errors returned by the handler are reported at the option.
+	compiler.FieldPseudoOptions = map[string]options.FieldPseudoOptionHandler{
+		"experimental": func(protoreflect.FieldDescriptor, *descriptorpb.UninterpretedOption, *ast.OptionNode) error {
+			return errors.New("not allowed here")
+		},
+	}
+	_, err = compiler.Compile(context.Background(), "test.proto")
+	require.ErrorContains(t, err, "test.proto:5:34-37: field foo.M.a: option experimental: not allowed here")
+}
+
+func TestOptionValidators(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"test.proto": `
+			syntax = "proto3";
+			package foo;
+			import "google/protobuf/descriptor.proto";
+			message Range {
+				int32 min = 1;
+				int32 max = 2;
+			}
+			extend google.protobuf.FieldOptions {
+				Range range = 50000;
+			}
+			message M {
+				int32 a = 1 [(range) = { min: 1 max: 10 }];
+				int32 b = 2 [(range) = { min: 5 max: 1 }];
+				int32 c = 3 [(range).max = 1, (range).min = 5];
+			}
+			`,
+	}
+	var mu sync.Mutex
+	var elements []string
+	validator := func(opt *options.ValidatedOption, handler *reporter.Handler) error {
+		mu.Lock()
+		elements = append(elements, opt.ElementName)
+		mu.Unlock()
+		assert.Equal(t, protoreflect.Int32Kind, opt.Element.(protoreflect.FieldDescriptor).Kind())
+		rng := opt.Value.Message()
+		fields := rng.Descriptor().Fields()
+		minFld, maxFld := fields.ByName("min"), fields.ByName("max")
+		if rng.Get(minFld).Int() > rng.Get(maxFld).Int() {
+			return handler.HandleErrorf(opt.Span(maxFld), "max must not be less than min")
+		}
+		return nil
+	}
+	var errs []error
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+			errs = append(errs, err)
+			return nil
+		}, nil),
+		OptionValidators: map[protoreflect.FullName]options.OptionValidator{
+			"foo.range": validator,
+		},
+	}
+	_, err := compiler.Compile(context.Background(), "test.proto")
+	require.Error(t, err)
+	assert.ElementsMatch(t, []string{"foo.M.a", "foo.M.b", "foo.M.c"}, elements)
+	require.Len(t, errs, 2)
+	assert.Equal(t, "test.proto:14:37-43: max must not be less than min", errs[0].Error())
+	assert.Equal(t, "test.proto:15:26-29: max must not be less than min", errs[1].Error())
+}
+
+func TestConstraintValidators(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		// a subset of protovalidate's schema
+		"buf/validate/validate.proto": `
+			syntax = "proto2";
+			package buf.validate;
+			import "google/protobuf/descriptor.proto";
+			extend google.protobuf.FieldOptions {
+				optional FieldConstraints field = 1159;
+			}
+			message FieldConstraints {
+				optional bool required = 25;
+				optional bool ignore_empty = 26;
+				oneof type {
+					Int32Rules int32 = 3;
+					StringRules string = 14;
+					RepeatedRules repeated = 18;
+				}
+			}
+			message Int32Rules {
+				optional int32 lt = 2;
+				optional int32 lte = 3;
+				optional int32 gt = 4;
+				optional int32 gte = 5;
+				repeated int32 in = 6;
+				repeated int32 not_in = 7;
+			}
+			message StringRules {
+				optional uint64 len = 19;
+				optional uint64 min_len = 2;
+				optional uint64 max_len = 3;
+				optional string pattern = 6;
+			}
+			message RepeatedRules {
+				optional uint64 min_items = 1;
+				optional uint64 max_items = 2;
+				optional FieldConstraints items = 4;
+			}
+			`,
+		"test.proto": `
+			syntax = "proto3";
+			package foo;
+			import "buf/validate/validate.proto";
+			message M {
+				string a = 1 [(buf.validate.field).string = { min_len: 1 max_len: 10 pattern: "^[a-z]+$" }];
+				string b = 2 [(buf.validate.field).string = { min_len: 5 max_len: 1 }];
+				string c = 3 [(buf.validate.field).string.pattern = "[a-z"];
+				int32 d = 4 [(buf.validate.field).string.min_len = 1];
+				int32 e = 5 [(buf.validate.field).int32 = { gt: 4 lt: 5 }];
+				int32 f = 6 [(buf.validate.field).int32 = { gt: 10 lt: 5 in: [1, 2] not_in: [2] }];
+				string g = 7 [(buf.validate.field).ignore_empty = true];
+				optional string h = 8 [(buf.validate.field) = { ignore_empty: true, string: { len: 3 } }];
+				repeated string i = 9 [(buf.validate.field).repeated = { min_items: 1 items: { string: { len: 3 max_len: 2 } } }];
+			}
+			`,
+	}
+	var errs []string
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+		Reporter: reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+			errs = append(errs, err.Error())
+			return nil
+		}, nil),
+		ConstraintCheck: linker.CheckLevelError,
+	}
+	_, err := compiler.Compile(context.Background(), "test.proto")
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"test.proto:7:62-72: (buf.validate.field).string.max_len: max_len (1) is less than min_len (5)",
+		`test.proto:8:47-54: (buf.validate.field).string.pattern: "[a-z" is not a valid regular expression: error parsing regexp: missing closing ]: ` + "`[a-z`",
+		"test.proto:9:39-45: (buf.validate.field).string: string rules cannot be used for field foo.M.d of type int32",
+		"test.proto:10:55-60: (buf.validate.field).int32.lt: no value is greater than 4 and less than 5",
+		"test.proto:11:73-84: (buf.validate.field).int32.not_in: 2 is in both in and not_in",
+		"test.proto:12:40-52: (buf.validate.field).ignore_empty: ignore_empty is used on field foo.M.g, which is not optional, so an empty value cannot be distinguished from an unset one",
+		"test.proto:14:94-100: (buf.validate.field).repeated.items.string.len: len (3) is greater than max_len (2)",
+	}, errs)
+
+	// With the checks off, nothing is reported.
+	errs = nil
+	compiler.ConstraintCheck = linker.CheckLevelOff
+	_, err = compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
@@ -21,7 +21,7 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 
-	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/descpath"
 )
 
 // StripSourceRetentionOptionsFromFile returns a file descriptor proto that omits any
@@ -40,7 +40,7 @@ func StripSourceRetentionOptionsFromFile(file *descriptorpb.FileDescriptorProto)
 		removedPaths = &sourcePathTrie{}
 	}
 	var dirty bool
-	optionsPath := path.push(protointernal.FileOptionsTag)
+	optionsPath := path.push(descpath.FileOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(file.GetOptions(), optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -48,7 +48,7 @@ func StripSourceRetentionOptionsFromFile(file *descriptorpb.FileDescriptorProto)
 	if newOpts != file.GetOptions() {
 		dirty = true
 	}
-	msgsPath := path.push(protointernal.FileMessagesTag)
+	msgsPath := path.push(descpath.FileMessagesTag)
 	newMsgs, changed, err := stripOptionsFromAll(file.GetMessageType(), stripSourceRetentionOptionsFromMessage, msgsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -56,7 +56,7 @@ func StripSourceRetentionOptionsFromFile(file *descriptorpb.FileDescriptorProto)
 	if changed {
 		dirty = true
 	}
-	enumsPath := path.push(protointernal.FileEnumsTag)
+	enumsPath := path.push(descpath.FileEnumsTag)
 	newEnums, changed, err := stripOptionsFromAll(file.GetEnumType(), stripSourceRetentionOptionsFromEnum, enumsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -64,7 +64,7 @@ func StripSourceRetentionOptionsFromFile(file *descriptorpb.FileDescriptorProto)
 	if changed {
 		dirty = true
 	}
-	extsPath := path.push(protointernal.FileExtensionsTag)
+	extsPath := path.push(descpath.FileExtensionsTag)
 	newExts, changed, err := stripOptionsFromAll(file.GetExtension(), stripSourceRetentionOptionsFromField, extsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -72,7 +72,7 @@ func StripSourceRetentionOptionsFromFile(file *descriptorpb.FileDescriptorProto)
 	if changed {
 		dirty = true
 	}
-	svcsPath := path.push(protointernal.FileServicesTag)
+	svcsPath := path.push(descpath.FileServicesTag)
 	newSvcs, changed, err := stripOptionsFromAll(file.GetService(), stripSourceRetentionOptionsFromService, svcsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -217,7 +217,7 @@ func stripSourceRetentionOptionsFromMessage(
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.DescriptorProto, error) {
 	var dirty bool
-	optionsPath := path.push(protointernal.MessageOptionsTag)
+	optionsPath := path.push(descpath.MessageOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(msg.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -225,7 +225,7 @@ func stripSourceRetentionOptionsFromMessage(
 	if newOpts != msg.Options {
 		dirty = true
 	}
-	fieldsPath := path.push(protointernal.MessageFieldsTag)
+	fieldsPath := path.push(descpath.MessageFieldsTag)
 	newFields, changed, err := stripOptionsFromAll(msg.Field, stripSourceRetentionOptionsFromField, fieldsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -233,7 +233,7 @@ func stripSourceRetentionOptionsFromMessage(
 	if changed {
 		dirty = true
 	}
-	oneofsPath := path.push(protointernal.MessageOneofsTag)
+	oneofsPath := path.push(descpath.MessageOneofsTag)
 	newOneofs, changed, err := stripOptionsFromAll(msg.OneofDecl, stripSourceRetentionOptionsFromOneof, oneofsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -241,7 +241,7 @@ func stripSourceRetentionOptionsFromMessage(
 	if changed {
 		dirty = true
 	}
-	extRangesPath := path.push(protointernal.MessageExtensionRangesTag)
+	extRangesPath := path.push(descpath.MessageExtensionRangesTag)
 	newExtRanges, changed, err := stripOptionsFromAll(msg.ExtensionRange, stripSourceRetentionOptionsFromExtensionRange, extRangesPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -249,7 +249,7 @@ func stripSourceRetentionOptionsFromMessage(
 	if changed {
 		dirty = true
 	}
-	msgsPath := path.push(protointernal.MessageNestedMessagesTag)
+	msgsPath := path.push(descpath.MessageNestedMessagesTag)
 	newMsgs, changed, err := stripOptionsFromAll(msg.NestedType, stripSourceRetentionOptionsFromMessage, msgsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -257,7 +257,7 @@ func stripSourceRetentionOptionsFromMessage(
 	if changed {
 		dirty = true
 	}
-	enumsPath := path.push(protointernal.MessageEnumsTag)
+	enumsPath := path.push(descpath.MessageEnumsTag)
 	newEnums, changed, err := stripOptionsFromAll(msg.EnumType, stripSourceRetentionOptionsFromEnum, enumsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -265,7 +265,7 @@ func stripSourceRetentionOptionsFromMessage(
 	if changed {
 		dirty = true
 	}
-	extsPath := path.push(protointernal.MessageExtensionsTag)
+	extsPath := path.push(descpath.MessageExtensionsTag)
 	newExts, changed, err := stripOptionsFromAll(msg.Extension, stripSourceRetentionOptionsFromField, extsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -297,7 +297,7 @@ func stripSourceRetentionOptionsFromField(
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.FieldDescriptorProto, error) {
-	optionsPath := path.push(protointernal.FieldOptionsTag)
+	optionsPath := path.push(descpath.FieldOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(field.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -318,7 +318,7 @@ func stripSourceRetentionOptionsFromOneof(
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.OneofDescriptorProto, error) {
-	optionsPath := path.push(protointernal.OneofOptionsTag)
+	optionsPath := path.push(descpath.OneofOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(oneof.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -339,7 +339,7 @@ func stripSourceRetentionOptionsFromExtensionRange(
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.DescriptorProto_ExtensionRange, error) {
-	optionsPath := path.push(protointernal.ExtensionRangeOptionsTag)
+	optionsPath := path.push(descpath.ExtensionRangeOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(extRange.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -361,7 +361,7 @@ func stripSourceRetentionOptionsFromEnum(
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.EnumDescriptorProto, error) {
 	var dirty bool
-	optionsPath := path.push(protointernal.EnumOptionsTag)
+	optionsPath := path.push(descpath.EnumOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(enum.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -369,7 +369,7 @@ func stripSourceRetentionOptionsFromEnum(
 	if newOpts != enum.Options {
 		dirty = true
 	}
-	valsPath := path.push(protointernal.EnumValuesTag)
+	valsPath := path.push(descpath.EnumValuesTag)
 	newVals, changed, err := stripOptionsFromAll(enum.Value, stripSourceRetentionOptionsFromEnumValue, valsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -396,7 +396,7 @@ func stripSourceRetentionOptionsFromEnumValue(
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.EnumValueDescriptorProto, error) {
-	optionsPath := path.push(protointernal.EnumValOptionsTag)
+	optionsPath := path.push(descpath.EnumValOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(enumVal.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -418,7 +418,7 @@ func stripSourceRetentionOptionsFromService(
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.ServiceDescriptorProto, error) {
 	var dirty bool
-	optionsPath := path.push(protointernal.ServiceOptionsTag)
+	optionsPath := path.push(descpath.ServiceOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(svc.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -426,7 +426,7 @@ func stripSourceRetentionOptionsFromService(
 	if newOpts != svc.Options {
 		dirty = true
 	}
-	methodsPath := path.push(protointernal.ServiceMethodsTag)
+	methodsPath := path.push(descpath.ServiceMethodsTag)
 	newMethods, changed, err := stripOptionsFromAll(svc.Method, stripSourceRetentionOptionsFromMethod, methodsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -453,7 +453,7 @@ func stripSourceRetentionOptionsFromMethod(
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.MethodDescriptorProto, error) {
-	optionsPath := path.push(protointernal.MethodOptionsTag)
+	optionsPath := path.push(descpath.MethodOptionsTag)
 	newOpts, err := stripSourceRetentionOptions(method.Options, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
@@ -28,7 +28,7 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 
-	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/descpath"
 )
 
 func TestStripSourceOnlyOptions(t *testing.T) {
@@ -427,24 +427,24 @@ func TestStripSourceOnlyOptionsFromFile(t *testing.T) {
 		},
 		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
 			Location: combineAll(
-				allLocations(protointernal.FileOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageFieldsTag, 0, protointernal.FieldOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageFieldsTag, 1, protointernal.FieldOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageOneofsTag, 0, protointernal.OneofOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageExtensionRangesTag, 0, protointernal.ExtensionRangeOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageNestedMessagesTag, 0, protointernal.MessageOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageNestedMessagesTag, 0, protointernal.MessageFieldsTag, 0, protointernal.FieldOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageEnumsTag, 0, protointernal.EnumOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageEnumsTag, 0, protointernal.EnumValuesTag, 0, protointernal.EnumValOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageEnumsTag, 0, protointernal.EnumValuesTag, 1, protointernal.EnumValOptionsTag),
-				allLocations(protointernal.FileMessagesTag, 0, protointernal.MessageExtensionsTag, 0, protointernal.FieldOptionsTag),
-				allLocations(protointernal.FileEnumsTag, 0, protointernal.EnumOptionsTag),
-				allLocations(protointernal.FileEnumsTag, 0, protointernal.EnumValuesTag, 0, protointernal.EnumValOptionsTag),
-				allLocations(protointernal.FileEnumsTag, 0, protointernal.EnumValuesTag, 1, protointernal.EnumValOptionsTag),
-				allLocations(protointernal.FileExtensionsTag, 0, protointernal.FieldOptionsTag),
-				allLocations(protointernal.FileServicesTag, 0, protointernal.ServiceOptionsTag),
-				allLocations(protointernal.FileServicesTag, 0, protointernal.ServiceMethodsTag, 0, protointernal.MethodOptionsTag),
+				allLocations(descpath.FileOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageFieldsTag, 0, descpath.FieldOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageFieldsTag, 1, descpath.FieldOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageOneofsTag, 0, descpath.OneofOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageExtensionRangesTag, 0, descpath.ExtensionRangeOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageNestedMessagesTag, 0, descpath.MessageOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageNestedMessagesTag, 0, descpath.MessageFieldsTag, 0, descpath.FieldOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageEnumsTag, 0, descpath.EnumOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageEnumsTag, 0, descpath.EnumValuesTag, 0, descpath.EnumValOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageEnumsTag, 0, descpath.EnumValuesTag, 1, descpath.EnumValOptionsTag),
+				allLocations(descpath.FileMessagesTag, 0, descpath.MessageExtensionsTag, 0, descpath.FieldOptionsTag),
+				allLocations(descpath.FileEnumsTag, 0, descpath.EnumOptionsTag),
+				allLocations(descpath.FileEnumsTag, 0, descpath.EnumValuesTag, 0, descpath.EnumValOptionsTag),
+				allLocations(descpath.FileEnumsTag, 0, descpath.EnumValuesTag, 1, descpath.EnumValOptionsTag),
+				allLocations(descpath.FileExtensionsTag, 0, descpath.FieldOptionsTag),
+				allLocations(descpath.FileServicesTag, 0, descpath.ServiceOptionsTag),
+				allLocations(descpath.FileServicesTag, 0, descpath.ServiceMethodsTag, 0, descpath.MethodOptionsTag),
 			),
 		},
 	}
@@ -580,24 +580,24 @@ func TestStripSourceOnlyOptionsFromFile(t *testing.T) {
 		},
 		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
 			Location: combineAll(
-				strippedLocations(protointernal.FileOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageFieldsTag, 0, protointernal.FieldOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageFieldsTag, 1, protointernal.FieldOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageOneofsTag, 0, protointernal.OneofOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageExtensionRangesTag, 0, protointernal.ExtensionRangeOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageNestedMessagesTag, 0, protointernal.MessageOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageNestedMessagesTag, 0, protointernal.MessageFieldsTag, 0, protointernal.FieldOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageEnumsTag, 0, protointernal.EnumOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageEnumsTag, 0, protointernal.EnumValuesTag, 0, protointernal.EnumValOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageEnumsTag, 0, protointernal.EnumValuesTag, 1, protointernal.EnumValOptionsTag),
-				strippedLocations(protointernal.FileMessagesTag, 0, protointernal.MessageExtensionsTag, 0, protointernal.FieldOptionsTag),
-				strippedLocations(protointernal.FileEnumsTag, 0, protointernal.EnumOptionsTag),
-				strippedLocations(protointernal.FileEnumsTag, 0, protointernal.EnumValuesTag, 0, protointernal.EnumValOptionsTag),
-				strippedLocations(protointernal.FileEnumsTag, 0, protointernal.EnumValuesTag, 1, protointernal.EnumValOptionsTag),
-				strippedLocations(protointernal.FileExtensionsTag, 0, protointernal.FieldOptionsTag),
-				strippedLocations(protointernal.FileServicesTag, 0, protointernal.ServiceOptionsTag),
-				strippedLocations(protointernal.FileServicesTag, 0, protointernal.ServiceMethodsTag, 0, protointernal.MethodOptionsTag),
+				strippedLocations(descpath.FileOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageFieldsTag, 0, descpath.FieldOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageFieldsTag, 1, descpath.FieldOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageOneofsTag, 0, descpath.OneofOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageExtensionRangesTag, 0, descpath.ExtensionRangeOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageNestedMessagesTag, 0, descpath.MessageOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageNestedMessagesTag, 0, descpath.MessageFieldsTag, 0, descpath.FieldOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageEnumsTag, 0, descpath.EnumOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageEnumsTag, 0, descpath.EnumValuesTag, 0, descpath.EnumValOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageEnumsTag, 0, descpath.EnumValuesTag, 1, descpath.EnumValOptionsTag),
+				strippedLocations(descpath.FileMessagesTag, 0, descpath.MessageExtensionsTag, 0, descpath.FieldOptionsTag),
+				strippedLocations(descpath.FileEnumsTag, 0, descpath.EnumOptionsTag),
+				strippedLocations(descpath.FileEnumsTag, 0, descpath.EnumValuesTag, 0, descpath.EnumValOptionsTag),
+				strippedLocations(descpath.FileEnumsTag, 0, descpath.EnumValuesTag, 1, descpath.EnumValOptionsTag),
+				strippedLocations(descpath.FileExtensionsTag, 0, descpath.FieldOptionsTag),
+				strippedLocations(descpath.FileServicesTag, 0, descpath.ServiceOptionsTag),
+				strippedLocations(descpath.FileServicesTag, 0, descpath.ServiceMethodsTag, 0, descpath.MethodOptionsTag),
 			),
 		},
 	}
@@ -0,0 +1,58 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/protointernal"
+)
+
+// fieldSuggestions returns the names of fields of md that are plausibly what
+// was meant by the given name. If groupNames is true, the names of the message
+// types of group fields are also candidates, since message literals may refer
+// to groups that way.
+func fieldSuggestions(md protoreflect.MessageDescriptor, name string, groupNames bool) []string {
+	fields := md.Fields()
+	candidates := make([]string, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fld := fields.Get(i)
+		if string(fld.Name()) != name {
+			candidates = append(candidates, string(fld.Name()))
+		}
+		if groupNames && fld.Kind() == protoreflect.GroupKind && fld.Message() != nil && string(fld.Message().Name()) != name {
+			candidates = append(candidates, string(fld.Message().Name()))
+		}
+	}
+	return protointernal.Suggestions(name, candidates, protointernal.MaxSuggestions)
+}
+
+// extensionSuggestions returns the full names of extensions of the given
+// message that are visible to the file being interpreted and that are
+// plausibly what was meant by the given name.
+func (interp *interpreter) extensionSuggestions(extendee protoreflect.FullName, name string) []string {
+	fd, ok := interp.file.(protoreflect.FileDescriptor)
+	if !ok {
+		return nil
+	}
+	var results []string
+	for _, fullName := range protointernal.SuggestVisible(fd, name, func(d protoreflect.Descriptor) bool {
+		ext, ok := d.(protoreflect.FieldDescriptor)
+		return ok && ext.IsExtension() && ext.ContainingMessage().FullName() == extendee
+	}) {
+		results = append(results, string(fullName))
+	}
+	return results
+}
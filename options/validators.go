@@ -0,0 +1,237 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// OptionValidator checks the interpreted value of a custom option. It reports
+// problems to the given handler, typically at a span returned by
+// ValidatedOption.Span. If it returns a non-nil error, such as one returned by
+// the handler, interpretation stops and the error is returned.
+type OptionValidator func(opt *ValidatedOption, handler *reporter.Handler) error
+
+// ValidatedOption is the value of a custom option that is passed to an
+// OptionValidator.
+type ValidatedOption struct {
+	// The element whose options include the extension, or nil if the file
+	// being interpreted is not linked. For extension ranges, this is the
+	// message that declares the range.
+	Element protoreflect.Descriptor
+	// The fully-qualified name of the element. For files, this is the path
+	// of the file.
+	ElementName string
+	// The custom option.
+	Extension protoreflect.FieldDescriptor
+	// The interpreted value of the option. If the extension is repeated, this
+	// is a list that includes the values of all options that set it.
+	Value protoreflect.Value
+	// The options that set the extension, in the order they are declared.
+	// Entries are nil if the file has no AST.
+	Nodes []*ast.OptionNode
+
+	interp      *interpreter
+	uninterp    []*descriptorpb.UninterpretedOption
+	elementNode ast.Node
+}
+
+// Span returns the location of the most specific part of the options that
+// sets the field at the given path, where the first field is a field of the
+// option's message type, the second a field of that field's message type, and
+// so on. The path is followed through the names of the options, as in
+// "option (ext).a.b = 1", and through message literals, as in
+// "option (ext) = { a: { b: 1 } }". If the path is empty or not found, the
+// location of the whole option, or of the part of it that sets the longest
+// prefix of the path, is returned. If the file has no AST, the location of
+// the element is returned.
+func (o *ValidatedOption) Span(path ...protoreflect.FieldDescriptor) ast.SourceSpan {
+	var best ast.Node
+	bestDepth := -1
+	for i, node := range o.Nodes {
+		if node == nil {
+			continue
+		}
+		found, depth := o.findInOption(o.uninterp[i], node, path)
+		if depth > bestDepth {
+			best, bestDepth = found, depth
+		}
+		if depth == len(path) {
+			break
+		}
+	}
+	if best == nil {
+		best = o.elementNode
+	}
+	return o.interp.nodeInfo(best)
+}
+
+// findInOption returns the node of the given option that sets the longest
+// prefix of path, along with the length of that prefix.
+func (o *ValidatedOption) findInOption(uo *descriptorpb.UninterpretedOption, node *ast.OptionNode, path []protoreflect.FieldDescriptor) (ast.Node, int) {
+	var found ast.Node = node
+	depth := 0
+	for _, nm := range uo.Name[1:] {
+		if depth == len(path) {
+			return found, depth
+		}
+		if fld := o.interp.descriptorIndex.UninterpretedNameDescriptorsToFieldDescriptors[nm]; fld == nil || fld.FullName() != path[depth].FullName() {
+			return found, depth
+		}
+		if part := o.interp.file.OptionNamePartNode(nm); part != nil {
+			found = part
+		}
+		depth++
+	}
+	if depth == len(path) {
+		return found, depth
+	}
+	if n, d := o.findInValue(node.GetVal(), path[depth:]); n != nil {
+		return n, depth + d
+	}
+	return found, depth
+}
+
+// findInValue returns the field of the given message literal, or of the
+// message literals in the given array literal, that sets the longest prefix of
+// path, along with the length of that prefix. It returns nil if val sets no
+// field in path.
+func (o *ValidatedOption) findInValue(val *ast.ValueNode, path []protoreflect.FieldDescriptor) (ast.Node, int) {
+	if len(path) == 0 {
+		return nil, 0
+	}
+	if arr := val.GetArrayLiteral(); arr != nil {
+		var best ast.Node
+		bestDepth := 0
+		for _, elem := range arr.GetElements() {
+			if n, d := o.findInValue(elem.GetValue(), path); d > bestDepth {
+				best, bestDepth = n, d
+			}
+		}
+		return best, bestDepth
+	}
+	var best ast.Node
+	bestDepth := 0
+	for _, field := range val.GetMessageLiteral().GetElements() {
+		fld := o.interp.descriptorIndex.FieldReferenceNodesToFieldDescriptors[field]
+		if fld == nil || fld.FullName() != path[0].FullName() {
+			continue
+		}
+		n, d := o.findInValue(field.GetVal(), path[1:])
+		if n == nil {
+			n = field
+		}
+		if d+1 > bestDepth {
+			best, bestDepth = n, d+1
+		}
+		if bestDepth == len(path) {
+			break
+		}
+	}
+	return best, bestDepth
+}
+
+// WithOptionValidator returns an option that registers a validator for the
+// custom option with the given fully-qualified name. After the options of an
+// element are interpreted, the validator is called if the element sets the
+// option. This allows tools to check that the values of options that encode
+// contracts, such as validation rules or internal policies, are well-formed.
+// Multiple validators may be registered for the same option; they are called
+// in the order they are registered.
+func WithOptionValidator(name protoreflect.FullName, validator OptionValidator) InterpreterOption {
+	return func(interp *interpreter) {
+		if interp.validators == nil {
+			interp.validators = map[protoreflect.FullName][]OptionValidator{}
+		}
+		interp.validators[name] = append(interp.validators[name], validator)
+	}
+}
+
+// validateOptions calls the registered validators for the custom options of
+// the given element that are set in msg.
+func (interp *interpreter) validateOptions(
+	fqn string,
+	targetType descriptorpb.FieldOptions_OptionTargetType,
+	element proto.Message,
+	msg protoreflect.Message,
+	uninterpreted []*descriptorpb.UninterpretedOption,
+) error {
+	if len(interp.validators) == 0 {
+		return nil
+	}
+	var validated []*ValidatedOption
+	for _, uo := range uninterpreted {
+		if len(uo.Name) == 0 || !uo.Name[0].GetIsExtension() {
+			continue
+		}
+		ext := interp.descriptorIndex.UninterpretedNameDescriptorsToFieldDescriptors[uo.Name[0]]
+		if ext == nil || len(interp.validators[ext.FullName()]) == 0 || !msg.Has(ext) {
+			continue
+		}
+		var opt *ValidatedOption
+		for _, v := range validated {
+			if v.Extension.FullName() == ext.FullName() {
+				opt = v
+				break
+			}
+		}
+		if opt == nil {
+			opt = &ValidatedOption{
+				Element:     interp.elementDescriptor(fqn, targetType),
+				ElementName: fqn,
+				Extension:   ext,
+				Value:       msg.Get(ext),
+				interp:      interp,
+				elementNode: interp.file.Node(element),
+			}
+			validated = append(validated, opt)
+		}
+		opt.Nodes = append(opt.Nodes, interp.file.OptionNode(uo))
+		opt.uninterp = append(opt.uninterp, uo)
+	}
+	for _, opt := range validated {
+		for _, validator := range interp.validators[opt.Extension.FullName()] {
+			if err := validator(opt, interp.handler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// elementDescriptor returns the descriptor of the element with the given name
+// and target type, or nil if the file is not linked.
+func (interp *interpreter) elementDescriptor(fqn string, targetType descriptorpb.FieldOptions_OptionTargetType) protoreflect.Descriptor {
+	res, ok := interp.file.(linker.Result)
+	if !ok {
+		return nil
+	}
+	switch targetType {
+	case descriptorpb.FieldOptions_TARGET_TYPE_FILE:
+		return res
+	case descriptorpb.FieldOptions_TARGET_TYPE_EXTENSION_RANGE:
+		// the name has the form "Message.start-end"
+		fqn = fqn[:strings.LastIndexByte(fqn, '.')]
+	}
+	return res.FindDescriptorByName(protoreflect.FullName(fqn))
+}
@@ -0,0 +1,181 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ParseMetrics describes how many parsed-but-not-yet-linked files were held
+// in memory during a call to Compile.
+type ParseMetrics struct {
+	// The maximum number of files that had been parsed but whose linking had
+	// not yet completed, at any one time.
+	PeakUnlinkedASTs int
+	// The maximum total size, in bytes, of the source code of those files at
+	// any one time. Sizes are only known for sources that report them (such as
+	// *os.File, *bytes.Reader, and *strings.Reader) or when the compiler has a
+	// ParseMemoryBudget (in which case sources are read into memory up front).
+	PeakUnlinkedBytes int64
+	// The number of files whose parsing was delayed in order to stay within
+	// the configured limits.
+	ThrottledFiles int
+}
+
+// parseLimiter applies back-pressure to the parse phase, so that files are not
+// parsed much faster than they can be linked. A permit is acquired before a
+// file is parsed and released once it has been linked.
+//
+// To avoid deadlock, a file that is needed as a dependency of a file already
+// being processed is never throttled: otherwise files holding permits could
+// wait forever on imports that are waiting for permits.
+type parseLimiter struct {
+	count    *semaphore.Weighted // nil if unlimited
+	bytes    *semaphore.Weighted // nil if unlimited
+	maxBytes int64
+
+	mu      sync.Mutex
+	metrics ParseMetrics
+	cur     int
+	curSize int64
+}
+
+func newParseLimiter(maxASTs int, maxBytes int64) *parseLimiter {
+	l := &parseLimiter{maxBytes: maxBytes}
+	if maxASTs > 0 {
+		l.count = semaphore.NewWeighted(int64(maxASTs))
+	}
+	if maxBytes > 0 {
+		l.bytes = semaphore.NewWeighted(maxBytes)
+	}
+	return l
+}
+
+// reset clears the metrics at the start of a call to Compile.
+func (l *parseLimiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics = ParseMetrics{PeakUnlinkedASTs: l.cur, PeakUnlinkedBytes: l.curSize}
+}
+
+func (l *parseLimiter) snapshot() ParseMetrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.metrics
+}
+
+// sourceSize returns the size of the given search result's source, if it can
+// be determined. If the limiter has a memory budget and the size cannot be
+// otherwise determined, the source is read into memory.
+func (l *parseLimiter) sourceSize(sr *SearchResult) (int64, error) {
+	switch src := sr.Source.(type) {
+	case interface{ Len() int }:
+		return int64(src.Len()), nil
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := src.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size(), nil
+		}
+	}
+	if l.bytes == nil {
+		return 0, nil
+	}
+	data, err := io.ReadAll(sr.Source)
+	if c, ok := sr.Source.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	sr.Source = bytes.NewReader(data)
+	return int64(len(data)), nil
+}
+
+// acquire blocks until the file of the given size may be parsed, or until
+// needed is closed. The returned function must be called once the file has
+// been linked (or has failed).
+func (l *parseLimiter) acquire(ctx context.Context, needed <-chan struct{}, size int64) (func(), error) {
+	var throttled bool
+	var release []func()
+	if l.count != nil || l.bytes != nil {
+		waitCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-needed:
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+		weight := min(size, l.maxBytes)
+		for _, sem := range []struct {
+			s *semaphore.Weighted
+			n int64
+		}{{l.count, 1}, {l.bytes, weight}} {
+			if sem.s == nil || sem.n <= 0 {
+				continue
+			}
+			if sem.s.TryAcquire(sem.n) {
+				release = append(release, releaseFunc(sem.s, sem.n))
+				continue
+			}
+			throttled = true
+			if err := sem.s.Acquire(waitCtx, sem.n); err != nil {
+				if ctx.Err() != nil {
+					for _, fn := range release {
+						fn()
+					}
+					return nil, ctx.Err()
+				}
+				// The file is needed by another file that is being linked,
+				// so proceed without a permit.
+				break
+			}
+			release = append(release, releaseFunc(sem.s, sem.n))
+		}
+	}
+
+	l.mu.Lock()
+	l.cur++
+	l.curSize += size
+	if throttled {
+		l.metrics.ThrottledFiles++
+	}
+	l.metrics.PeakUnlinkedASTs = max(l.metrics.PeakUnlinkedASTs, l.cur)
+	l.metrics.PeakUnlinkedBytes = max(l.metrics.PeakUnlinkedBytes, l.curSize)
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.cur--
+			l.curSize -= size
+			l.mu.Unlock()
+			for _, fn := range release {
+				fn()
+			}
+		})
+	}, nil
+}
+
+func releaseFunc(s *semaphore.Weighted, n int64) func() {
+	return func() { s.Release(n) }
+}
@@ -15,6 +15,9 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 
@@ -47,10 +50,13 @@ func Clone(r Result) Result {
 		newNodes := make(map[proto.Message]ast.Node, len(res.nodes))
 		newNodesInverse := make(map[ast.Node]proto.Message, len(res.nodesInverse))
 		newResult := &result{
-			file:         res.file,
-			proto:        newProto,
-			nodes:        newNodes,
-			nodesInverse: newNodesInverse,
+			file:                 res.file,
+			proto:                newProto,
+			nodes:                newNodes,
+			nodesInverse:         newNodesInverse,
+			limits:               res.limits,
+			experimentalEditions: res.experimentalEditions,
+			oneofNaming:          res.oneofNaming,
 		}
 		recreateNodeIndexForFile(res, newResult, res.proto, newProto)
 		return newResult
@@ -64,8 +70,11 @@ func Clone(r Result) Result {
 		return ResultWithoutAST(fileProto)
 	}
 	// Otherwise, we have an AST, but no way to clone the result's
-	// internals. So just re-create them from scratch.
-	res, err := ResultFromAST(r.AST(), false, reporter.NewHandler(nil))
+	// internals. So just re-create them from scratch, naming synthetic
+	// oneofs the same way as the original. The original already accepted
+	// the file's edition, so experimental editions are allowed.
+	protocOneofNames := SyntheticOneofNamingOf(r) == SyntheticOneofNamingProtoc
+	res, err := ResultFromAST(r.AST(), false, reporter.NewHandler(nil), WithProtocSyntheticOneofNames(protocOneofNames), WithExperimentalEditions(true))
 	if err != nil {
 		panic(err)
 	}
@@ -141,6 +150,11 @@ func recreateNodeIndexForEnum(orig, clone *result, origProto, cloneProto *descri
 }
 
 func recreateNodeIndexForOptions(orig, clone *result, origProtos, cloneProtos []*descriptorpb.UninterpretedOption) {
+	if len(origProtos) != len(cloneProtos) {
+		// some or all options in the clone have been interpreted (see
+		// Reassociate), so which of them remain is not known
+		return
+	}
 	for i, origOpt := range origProtos {
 		cloneOpt := cloneProtos[i]
 		updateNodeIndex(orig, clone, origOpt, cloneOpt)
@@ -156,9 +170,8 @@ func updateNodeIndex[M proto.Message](orig, clone *result, origProto, cloneProto
 	if node != nil {
 		clone.nodes[cloneProto] = node
 	}
-	desc := orig.nodesInverse[node]
-	if desc != nil {
-		clone.nodesInverse[node] = desc
+	if orig.nodesInverse[node] != nil {
+		clone.nodesInverse[node] = cloneProto
 	}
 }
 
@@ -186,3 +199,162 @@ func updateNodeIndexWithOptions[O options[T], M withOptions[O, T], T any](orig,
 		recreateNodeIndexForOptions(orig, clone, origOpts.GetUninterpretedOption(), cloneOpts.GetUninterpretedOption())
 	}
 }
+
+// Reassociate returns a result that has the AST of the given parse result but
+// uses the given descriptor proto, which must have been created from the same
+// source, such as a proto that was produced by an earlier parse and then
+// linked, after which its original AST was discarded. Nodes in the AST are
+// associated with the corresponding elements of the given proto, so the
+// result can be used to look up nodes for the same proto messages. Options
+// that have since been interpreted, and so are no longer present as
+// uninterpreted options, are not associated with nodes.
+//
+// An error is returned if the structure of the given proto does not match
+// the AST, which indicates that they were not created from the same source.
+// The given parse result must be one returned by this package, such as from
+// ResultFromAST.
+func Reassociate(parsed Result, fd *descriptorpb.FileDescriptorProto) (Result, error) {
+	res, ok := parsed.(*result)
+	if !ok || res.file == nil {
+		return nil, errors.New("parse result has no AST")
+	}
+	if err := matchFile(res.proto, fd); err != nil {
+		return nil, fmt.Errorf("%s: AST does not match descriptor: %w", fd.GetName(), err)
+	}
+	newResult := &result{
+		file:                 res.file,
+		proto:                fd,
+		nodes:                make(map[proto.Message]ast.Node, len(res.nodes)),
+		nodesInverse:         make(map[ast.Node]proto.Message, len(res.nodesInverse)),
+		fieldExtendeeNodes:   res.fieldExtendeeNodes,
+		limits:               res.limits,
+		experimentalEditions: res.experimentalEditions,
+		oneofNaming:          res.oneofNaming,
+		importInsertionPoint: res.importInsertionPoint,
+	}
+	recreateNodeIndexForFile(res, newResult, res.proto, fd)
+	return newResult, nil
+}
+
+// matchFile returns an error if the structure of the given file protos, in
+// terms of the elements that have AST nodes, is not the same.
+func matchFile(orig, clone *descriptorpb.FileDescriptorProto) error {
+	if err := matchCounts("message", len(orig.MessageType), len(clone.MessageType)); err != nil {
+		return err
+	}
+	if err := matchCounts("enum", len(orig.EnumType), len(clone.EnumType)); err != nil {
+		return err
+	}
+	if err := matchCounts("extension", len(orig.Extension), len(clone.Extension)); err != nil {
+		return err
+	}
+	if err := matchCounts("service", len(orig.Service), len(clone.Service)); err != nil {
+		return err
+	}
+	for i, md := range orig.MessageType {
+		if err := matchMessage(md, clone.MessageType[i]); err != nil {
+			return err
+		}
+	}
+	for i, ed := range orig.EnumType {
+		if err := matchEnum(ed, clone.EnumType[i]); err != nil {
+			return err
+		}
+	}
+	for i, fld := range orig.Extension {
+		if err := matchNames("extension", fld.GetName(), clone.Extension[i].GetName()); err != nil {
+			return err
+		}
+	}
+	for i, sd := range orig.Service {
+		cloneSd := clone.Service[i]
+		if err := matchNames("service", sd.GetName(), cloneSd.GetName()); err != nil {
+			return err
+		}
+		if err := matchCounts("method", len(sd.Method), len(cloneSd.Method)); err != nil {
+			return err
+		}
+		for j, mtd := range sd.Method {
+			if err := matchNames("method", mtd.GetName(), cloneSd.Method[j].GetName()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchMessage(orig, clone *descriptorpb.DescriptorProto) error {
+	if err := matchNames("message", orig.GetName(), clone.GetName()); err != nil {
+		return err
+	}
+	counts := []struct {
+		what        string
+		orig, clone int
+	}{
+		{"field", len(orig.Field), len(clone.Field)},
+		{"oneof", len(orig.OneofDecl), len(clone.OneofDecl)},
+		{"extension range", len(orig.ExtensionRange), len(clone.ExtensionRange)},
+		{"reserved range", len(orig.ReservedRange), len(clone.ReservedRange)},
+		{"nested message", len(orig.NestedType), len(clone.NestedType)},
+		{"nested enum", len(orig.EnumType), len(clone.EnumType)},
+		{"extension", len(orig.Extension), len(clone.Extension)},
+	}
+	for _, c := range counts {
+		if err := matchCounts(c.what, c.orig, c.clone); err != nil {
+			return fmt.Errorf("message %s: %w", orig.GetName(), err)
+		}
+	}
+	for i, fld := range orig.Field {
+		if err := matchNames("field", fld.GetName(), clone.Field[i].GetName()); err != nil {
+			return err
+		}
+	}
+	for i, fld := range orig.Extension {
+		if err := matchNames("extension", fld.GetName(), clone.Extension[i].GetName()); err != nil {
+			return err
+		}
+	}
+	for i, md := range orig.NestedType {
+		if err := matchMessage(md, clone.NestedType[i]); err != nil {
+			return err
+		}
+	}
+	for i, ed := range orig.EnumType {
+		if err := matchEnum(ed, clone.EnumType[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchEnum(orig, clone *descriptorpb.EnumDescriptorProto) error {
+	if err := matchNames("enum", orig.GetName(), clone.GetName()); err != nil {
+		return err
+	}
+	if err := matchCounts("value", len(orig.Value), len(clone.Value)); err != nil {
+		return fmt.Errorf("enum %s: %w", orig.GetName(), err)
+	}
+	if err := matchCounts("reserved range", len(orig.ReservedRange), len(clone.ReservedRange)); err != nil {
+		return fmt.Errorf("enum %s: %w", orig.GetName(), err)
+	}
+	for i, evd := range orig.Value {
+		if err := matchNames("enum value", evd.GetName(), clone.Value[i].GetName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchCounts(what string, orig, clone int) error {
+	if orig != clone {
+		return fmt.Errorf("AST has %d %s declarations, descriptor has %d", orig, what, clone)
+	}
+	return nil
+}
+
+func matchNames(what, orig, clone string) error {
+	if orig != clone {
+		return fmt.Errorf("AST declares %s %s where descriptor has %s", what, orig, clone)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
 
 	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/reporter"
@@ -199,3 +200,83 @@ func (c *customCloneResultImpl) Clone() Result {
 	}
 	return c.clone
 }
+
+func TestReassociate(t *testing.T) {
+	t.Parallel()
+	parse := func(source string) Result {
+		handler := reporter.NewHandler(nil)
+		fileNode, err := Parse("test.proto", bytes.NewReader([]byte(source)), handler, 0)
+		require.NoError(t, err)
+		result, err := ResultFromAST(fileNode, true, handler)
+		require.NoError(t, err)
+		return result
+	}
+	const source = `syntax = "proto3"; message A { option deprecated = true; string s = 1; } enum E { X = 0; }`
+	orig := parse(source)
+	fd := proto.Clone(orig.FileDescriptorProto()).(*descriptorpb.FileDescriptorProto)
+	// as if options were interpreted
+	fd.MessageType[0].Options.UninterpretedOption = nil
+
+	result, err := Reassociate(parse(source), fd)
+	require.NoError(t, err)
+	assert.Same(t, fd, result.FileDescriptorProto())
+	msgNode := result.MessageNode(fd.MessageType[0])
+	require.NotNil(t, msgNode)
+	assert.Same(t, fd.MessageType[0], result.MessageDescriptor(msgNode))
+	assert.Same(t, fd.MessageType[0].Field[0], result.FieldDescriptor(result.FieldNode(fd.MessageType[0].Field[0])))
+	assert.NotNil(t, result.EnumValueNode(fd.EnumType[0].Value[0]))
+
+	_, err = Reassociate(parse(`syntax = "proto3"; message A { string s = 1; int32 i = 2; } enum E { X = 0; }`), fd)
+	assert.EqualError(t, err, "test.proto: AST does not match descriptor: message A: AST has 2 field declarations, descriptor has 1")
+	_, err = Reassociate(parse(`syntax = "proto3"; message A { string t = 1; } enum E { X = 0; }`), fd)
+	assert.EqualError(t, err, "test.proto: AST does not match descriptor: AST declares field t where descriptor has s")
+}
+
+func TestSyntheticOneofNaming(t *testing.T) {
+	t.Parallel()
+
+	// protoc only avoids conflicts with field and oneof names, so it
+	// picks "_foo" even though a nested message has that name.
+	data := `syntax = "proto3";
+message Foo {
+  optional string foo = 1;
+  message _foo {}
+}`
+	testCases := []struct {
+		name      string
+		protoc    bool
+		naming    SyntheticOneofNaming
+		oneofName string
+	}{
+		{name: "default", naming: SyntheticOneofNamingDefault, oneofName: "X_foo"},
+		{name: "protoc", protoc: true, naming: SyntheticOneofNamingProtoc, oneofName: "_foo"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			handler := reporter.NewHandler(nil)
+			fileNode, err := Parse("test.proto", bytes.NewReader([]byte(data)), handler, 0)
+			require.NoError(t, err)
+			result, err := ResultFromAST(fileNode, true, handler, WithProtocSyntheticOneofNames(tc.protoc))
+			require.NoError(t, err)
+			assert.Equal(t, tc.naming, SyntheticOneofNamingOf(result))
+			assert.Equal(t, tc.oneofName, result.FileDescriptorProto().MessageType[0].OneofDecl[0].GetName())
+
+			assert.Equal(t, tc.naming, SyntheticOneofNamingOf(Clone(result)))
+			assert.Equal(t, SyntheticOneofNamingUnknown, SyntheticOneofNamingOf(otherResultImpl{Result: result}))
+			cloned := Clone(oneofNamingResultImpl{Result: result})
+			assert.Equal(t, tc.naming, SyntheticOneofNamingOf(cloned))
+			assert.Equal(t, tc.oneofName, cloned.FileDescriptorProto().MessageType[0].OneofDecl[0].GetName())
+		})
+	}
+	assert.Equal(t, SyntheticOneofNamingUnknown, SyntheticOneofNamingOf(ResultWithoutAST(nil)))
+}
+
+type oneofNamingResultImpl struct {
+	Result
+}
+
+func (r oneofNamingResultImpl) SyntheticOneofNaming() SyntheticOneofNaming {
+	return SyntheticOneofNamingOf(r.Result)
+}
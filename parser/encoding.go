@@ -0,0 +1,147 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Encoding identifies the character encoding of source files that are not
+// valid UTF-8. See WithSourceEncoding.
+type Encoding int
+
+const (
+	// EncodingUTF8 is the default. Source files are expected to be UTF-8 and
+	// invalid bytes are not transcoded.
+	EncodingUTF8 Encoding = iota
+	// EncodingLatin1 is ISO-8859-1, in which each byte is the code point of
+	// the same value.
+	EncodingLatin1
+	// EncodingWindows1252 is the Windows-1252 code page, a superset of the
+	// printable characters of ISO-8859-1 that assigns characters such as
+	// curly quotes and the euro sign to bytes 0x80-0x9F.
+	EncodingWindows1252
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "UTF-8"
+	case EncodingLatin1:
+		return "Latin-1"
+	case EncodingWindows1252:
+		return "Windows-1252"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// windows1252 maps bytes 0x80-0x9F to their code points. Bytes that are
+// not assigned a character map to the C1 control of the same value.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+func (e Encoding) decodeByte(b byte) rune {
+	if e == EncodingWindows1252 && b >= 0x80 && b <= 0x9F {
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// transcode converts any bytes in data that are not part of a valid UTF-8
+// sequence from the given encoding to UTF-8. Valid UTF-8 sequences are left
+// as is, so files that mix the two are converted correctly. It returns the
+// offset in the result of the first transcoded character, or -1 if data was
+// already valid UTF-8 (in which case data is returned unchanged).
+func transcode(data []byte, enc Encoding) ([]byte, int) {
+	if enc == EncodingUTF8 || utf8.Valid(data) {
+		return data, -1
+	}
+	first := -1
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for len(data) > 0 {
+		r, sz := utf8.DecodeRune(data)
+		if r == utf8.RuneError && sz <= 1 {
+			if first < 0 {
+				first = len(out)
+			}
+			r = enc.decodeByte(data[0])
+		}
+		out = utf8.AppendRune(out, r)
+		data = data[sz:]
+	}
+	return out, first
+}
+
+// spanAtOffset returns a span for the character at the given offset in data.
+// The lexer has not yet recorded any line information when the source is
+// checked and transcoded, so the position is computed here.
+func spanAtOffset(filename string, data []byte, offset int) ast.SourceSpan {
+	line, lineStart := 1, 0
+	for i := 0; i < offset; i++ {
+		if data[i] == '\n' {
+			line++
+			lineStart = i + 1
+		}
+	}
+	return charSpan(filename, data, line, lineStart, offset)
+}
+
+func charSpan(filename string, data []byte, line, lineStart, offset int) ast.SourceSpan {
+	// columns are byte offsets, like the default position encoding used
+	// by the lexer
+	start := ast.SourcePos{
+		Filename: filename,
+		Line:     line,
+		Col:      offset - lineStart + 1,
+		Offset:   offset,
+	}
+	_, sz := utf8.DecodeRune(data[offset:])
+	end := start
+	end.Col += sz
+	end.Offset += sz
+	return ast.NewSourceSpan(start, end)
+}
+
+// checkUTF8 reports an error for each byte in data that is not part of a
+// valid UTF-8 sequence. It stops early if the handler returns an error.
+func checkUTF8(filename string, data []byte, handler *reporter.Handler) {
+	if utf8.Valid(data) {
+		return
+	}
+	line, lineStart := 1, 0
+	for i := 0; i < len(data); {
+		r, sz := utf8.DecodeRune(data[i:])
+		switch {
+		case r == '\n':
+			line++
+			lineStart = i + 1
+		case r == utf8.RuneError && sz <= 1:
+			span := charSpan(filename, data, line, lineStart, i)
+			if handler.HandleErrorf(span, "invalid UTF-8 byte 0x%02x", data[i]) != nil {
+				return
+			}
+		}
+		i += sz
+	}
+}
@@ -56,6 +56,10 @@ const (
 	CategoryIncorrectToken = "wrong_token"
 	CategoryMissingToken   = "missing_token"
 	CategoryDeclNotAllowed = "decl_not_allowed"
+	// CategoryNonStandard marks opt-in syntax that is accepted but is not
+	// part of the protobuf language, such as raw string literals. A
+	// formatter can rewrite it into standard syntax.
+	CategoryNonStandard = "non_standard"
 )
 
 func NewExtendedSyntaxError(base error, category string) ExtendedSyntaxError {
@@ -95,7 +99,7 @@ func (e *extendedSyntaxError) Category() string {
 
 func (e *extendedSyntaxError) CanFormat() bool {
 	switch e.category {
-	case CategoryEmptyDecl, CategoryIncorrectToken, CategoryMissingToken, CategoryExtraTokens:
+	case CategoryEmptyDecl, CategoryIncorrectToken, CategoryMissingToken, CategoryExtraTokens, CategoryNonStandard:
 		return true
 	case CategoryIncompleteDecl:
 		return false
@@ -0,0 +1,77 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// ParseOption parses a single option assignment, such as
+//
+//	(my.opt).foo = {bar: 1}
+//
+// from the given source, without the leading "option" keyword, so the returned
+// node's Keyword is always nil. A trailing semicolon is allowed. The filename
+// is only used in the positions of reported errors.
+//
+// Errors are handled the same way as by Parse, including error recovery and
+// the reporting of incomplete assignments as extended syntax warnings, so a
+// partial node may be returned along with an error. The returned FileInfo
+// can be used to look up the positions of nodes within the source.
+func ParseOption(filename, source string, handler *reporter.Handler, opts ...ParserOption) (*ast.OptionNode, *ast.FileInfo, error) {
+	res, info, err := parseExpr(filename, strings.NewReader(source), handler, _START_OPTION, opts)
+	opt, _ := res.(*ast.OptionNode)
+	if opt == nil {
+		// a syntax error prevented any parsing; synthesize an empty node
+		opt = &ast.OptionNode{}
+	}
+	return opt, info, err
+}
+
+// ParseValue parses a single option value from the given source. This can
+// be a scalar value, such as a string literal or identifier, or a message
+// literal, such as {bar: 1, baz: [1, 2]}. A trailing semicolon is allowed.
+// The filename is only used in the positions of reported errors.
+//
+// Errors are handled the same way as by ParseOption. If the source could not
+// be parsed at all, the returned value is nil.
+func ParseValue(filename, source string, handler *reporter.Handler, opts ...ParserOption) (*ast.ValueNode, *ast.FileInfo, error) {
+	res, info, err := parseExpr(filename, strings.NewReader(source), handler, _START_VALUE, opts)
+	val, _ := res.(*ast.ValueNode)
+	return val, info, err
+}
+
+func parseExpr(filename string, r io.Reader, handler *reporter.Handler, startToken int, opts []ParserOption) (ast.Node, *ast.FileInfo, error) {
+	po := newParseOptions(opts)
+	lx, err := newLexer(r, filename, handler, 0, po)
+	if err != nil {
+		if errors.Is(err, errFileTooLarge) {
+			if err := handler.HandleErrorf(ast.UnknownSpan(filename), "source exceeds maximum size of %d bytes", po.limits.MaxFileSize); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, handler.Error()
+		}
+		return nil, nil, err
+	}
+	lx.startToken = startToken
+	lx.textFormat = startToken == _START_TEXT_FORMAT
+	protoParse(lx)
+	return lx.exprRes, lx.info, handler.Error()
+}
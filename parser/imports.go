@@ -0,0 +1,154 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"slices"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// ImportBlock describes the import statements of a file, so that tools that
+// add, remove, or reorder imports can produce edits that respect the
+// existing layout.
+type ImportBlock struct {
+	// The groups of imports in the file, in source order. A group is a run of
+	// import statements that is not interrupted by a blank line or by any
+	// other declaration.
+	Groups []ImportGroup
+	// The position just after the last import statement, where new imports
+	// can be inserted. This is the same as Result.ImportInsertionPoint.
+	InsertionPoint ast.SourcePos
+}
+
+// Imports returns all of the imports in the block, in source order.
+func (b ImportBlock) Imports() []ImportSpec {
+	var imports []ImportSpec
+	for _, g := range b.Groups {
+		imports = append(imports, g.Imports...)
+	}
+	return imports
+}
+
+// IsSorted returns true if the imports in every group are sorted by path.
+// Imports are not expected to be sorted across groups.
+func (b ImportBlock) IsSorted() bool {
+	for _, g := range b.Groups {
+		if !g.IsSorted() {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportGroup is a run of import statements that is not interrupted by a
+// blank line or by any other declaration. It is never empty.
+type ImportGroup struct {
+	Imports []ImportSpec
+}
+
+// Span returns the span from the start of the first import in the group to
+// the end of the last one.
+func (g ImportGroup) Span() ast.SourceSpan {
+	return ast.NewSourceSpan(g.Imports[0].Info.Start(), g.Imports[len(g.Imports)-1].Info.End())
+}
+
+// IsSorted returns true if the imports in the group are sorted by path.
+func (g ImportGroup) IsSorted() bool {
+	return slices.IsSortedFunc(g.Imports, func(a, b ImportSpec) int {
+		switch {
+		case a.Path < b.Path:
+			return -1
+		case a.Path > b.Path:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// ImportSpec describes a single import statement.
+type ImportSpec struct {
+	Node *ast.ImportNode
+	// The imported path.
+	Path string
+	// Source info for the whole statement, which can also be used to query
+	// its comments.
+	Info ast.NodeInfo
+	// True if the import has a "public" or "weak" modifier, respectively.
+	Public, Weak bool
+}
+
+// ImportBlockOf returns the structure of the import statements of the given
+// result's file, including how they are grouped. If the result has no AST,
+// this returns an empty block.
+func ImportBlockOf(r Result) ImportBlock {
+	file := r.AST()
+	if file == nil {
+		return ImportBlock{}
+	}
+	block := ImportBlock{InsertionPoint: r.ImportInsertionPoint()}
+	var group []ImportSpec
+	endGroup := func() {
+		if len(group) > 0 {
+			block.Groups = append(block.Groups, ImportGroup{Imports: group})
+			group = nil
+		}
+	}
+	for _, decl := range file.Decls {
+		imp, ok := decl.Unwrap().(*ast.ImportNode)
+		if !ok {
+			endGroup()
+			continue
+		}
+		if imp.IsIncomplete() {
+			continue
+		}
+		spec := ImportSpec{
+			Node:   imp,
+			Path:   imp.Name.AsString(),
+			Info:   file.NodeInfo(imp),
+			Public: imp.Public != nil,
+			Weak:   imp.Weak != nil,
+		}
+		if len(group) > 0 && hasBlankLineBetween(group[len(group)-1].Info, spec.Info) {
+			endGroup()
+		}
+		group = append(group, spec)
+	}
+	endGroup()
+	return block
+}
+
+// hasBlankLineBetween returns true if there is an empty line between the end
+// of prev and the start of next. Lines with only comments are not empty.
+func hasBlankLineBetween(prev, next ast.NodeInfo) bool {
+	first, last := prev.End().Line+1, next.Start().Line-1
+	if first > last {
+		return false
+	}
+	covered := make([]bool, last-first+1)
+	cover := func(comments ast.Comments) {
+		for i := 0; i < comments.Len(); i++ {
+			c := comments.Index(i)
+			for line := max(c.Start().Line, first); line <= min(c.End().Line, last); line++ {
+				covered[line-first] = true
+			}
+		}
+	}
+	cover(prev.TrailingComments())
+	cover(next.LeadingComments())
+	return slices.Contains(covered, false)
+}
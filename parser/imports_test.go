@@ -0,0 +1,129 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestImportBlock(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		contents string
+		groups   [][]string
+		sorted   bool
+	}{
+		{
+			name:     "no imports",
+			contents: `syntax = "proto3";`,
+			sorted:   true,
+		},
+		{
+			name: "one group",
+			contents: `syntax = "proto3";
+import "a.proto";
+// comment lines don't split groups
+import public "b.proto";
+/* nor do
+   block comments */
+import weak "c.proto";`,
+			groups: [][]string{{"a.proto", "b.proto", "c.proto"}},
+			sorted: true,
+		},
+		{
+			name: "blank lines",
+			contents: `syntax = "proto3";
+import "z.proto";
+import "y.proto";
+
+// next group
+import "a.proto"; // trailing
+
+import "b.proto";`,
+			groups: [][]string{{"z.proto", "y.proto"}, {"a.proto"}, {"b.proto"}},
+		},
+		{
+			name: "other declarations",
+			contents: `syntax = "proto3";
+import "a.proto";
+option java_package = "foo";
+import "b.proto";
+package foo;
+import "c.proto";`,
+			groups: [][]string{{"a.proto"}, {"b.proto"}, {"c.proto"}},
+			sorted: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			handler := reporter.NewHandler(nil)
+			file, err := Parse("test.proto", strings.NewReader(tc.contents), handler, 0)
+			require.NoError(t, err)
+			res, err := ResultFromAST(file, false, handler)
+			require.NoError(t, err)
+
+			block := ImportBlockOf(res)
+			var groups [][]string
+			for _, g := range block.Groups {
+				var paths []string
+				for _, imp := range g.Imports {
+					paths = append(paths, imp.Path)
+					assert.Equal(t, `import`, strings.Fields(imp.Info.RawText())[0])
+				}
+				groups = append(groups, paths)
+			}
+			assert.Equal(t, tc.groups, groups)
+			assert.Equal(t, tc.sorted, block.IsSorted())
+			assert.Equal(t, res.ImportInsertionPoint(), block.InsertionPoint)
+		})
+	}
+
+	t.Run("modifiers and spans", func(t *testing.T) {
+		t.Parallel()
+		handler := reporter.NewHandler(nil)
+		file, err := Parse("test.proto", strings.NewReader(`syntax = "proto3";
+import public "a.proto";
+import weak "b.proto";`), handler, 0)
+		require.NoError(t, err)
+		res, err := ResultFromAST(file, false, handler)
+		require.NoError(t, err)
+		imports := ImportBlockOf(res).Imports()
+		require.Len(t, imports, 2)
+		assert.True(t, imports[0].Public)
+		assert.False(t, imports[0].Weak)
+		assert.False(t, imports[1].Public)
+		assert.True(t, imports[1].Weak)
+		span := ImportBlockOf(res).Groups[0].Span()
+		assert.Equal(t, 2, span.Start().Line)
+		assert.Equal(t, 1, span.Start().Col)
+		assert.Equal(t, 3, span.End().Line)
+	})
+
+	t.Run("no AST", func(t *testing.T) {
+		t.Parallel()
+		res := ResultWithoutAST(&descriptorpb.FileDescriptorProto{Dependency: []string{"a.proto"}})
+		assert.Empty(t, ImportBlockOf(res).Groups)
+	})
+}
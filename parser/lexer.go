@@ -26,6 +26,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/reporter"
 )
 
@@ -34,9 +35,6 @@ type runeReader struct {
 	pos  int
 	err  error
 	mark int
-	// Enable this check to make input required to be valid UTF-8.
-	// For now, since protoc allows invalid UTF-8, default to false.
-	utf8Strict bool
 
 	savedPos int
 	savedErr error
@@ -61,10 +59,6 @@ func (rr *runeReader) readRune() (r rune, size int, err error) {
 		return 0, 0, rr.err
 	}
 	r, sz := utf8.DecodeRune(rr.data[rr.pos:])
-	if rr.utf8Strict && r == utf8.RuneError {
-		rr.err = fmt.Errorf("invalid UTF8 at offset %d: %x", rr.pos, rr.data[rr.pos])
-		return 0, 0, rr.err
-	}
 	rr.pos += sz
 	return r, sz, nil
 }
@@ -113,6 +107,11 @@ type protoLex struct {
 	res          *ast.FileNode
 	parsedSyntax string
 
+	// when parsing a standalone option or value, startToken is returned
+	// before any input is read, and exprRes holds the parsed node
+	startToken int
+	exprRes    ast.Node
+
 	prevSym    ast.TerminalNode
 	prevOffset int
 	eof        ast.Token
@@ -126,11 +125,35 @@ type protoLex struct {
 	inMethodTypeDecl        bool
 
 	comments []ast.Token
+
+	// if true, triple-quoted raw string literals are recognized
+	rawStrings bool
+	// if true, the input is in the protobuf text format, in which '#'
+	// starts a line comment
+	textFormat bool
+
+	// if non-nil, used to de-duplicate the values of identifiers
+	names *protointernal.Interner
+
+	limits        Limits
+	numTokens     int
+	numComments   int
+	depth         int
+	limitExceeded bool
+	// the error returned by the handler when a limit was exceeded
+	limitErr error
 }
 
 var utf8Bom = []byte{0xEF, 0xBB, 0xBF}
 
-func newLexer(in io.Reader, filename string, handler *reporter.Handler, version int32) (*protoLex, error) {
+var errFileTooLarge = errors.New("file too large")
+
+func newLexer(in io.Reader, filename string, handler *reporter.Handler, version int32, opts parseOptions) (*protoLex, error) {
+	if opts.limits.MaxFileSize > 0 {
+		// read at most one byte past the limit, so we can tell it was exceeded
+		// without buffering an arbitrarily large input
+		in = io.LimitReader(in, int64(opts.limits.MaxFileSize)+int64(len(utf8Bom))+1)
+	}
 	br := bufio.NewReader(in)
 
 	// if file has UTF8 byte order marker preface, consume it
@@ -143,10 +166,26 @@ func newLexer(in io.Reader, filename string, handler *reporter.Handler, version
 	if err != nil {
 		return nil, err
 	}
+	if opts.limits.MaxFileSize > 0 && len(contents) > opts.limits.MaxFileSize {
+		return nil, errFileTooLarge
+	}
+	if transcoded, first := transcode(contents, opts.encoding); first >= 0 {
+		contents = transcoded
+		handler.HandleWarningf(spanAtOffset(filename, contents, first),
+			"file is not valid UTF-8; transcoded from %v", opts.encoding)
+	}
+	if opts.strictUTF8 {
+		checkUTF8(filename, contents, handler)
+	}
+	info := ast.NewFileInfo(filename, contents, version)
+	info.PositionEncoding = opts.positionEncoding
 	return &protoLex{
-		input:   &runeReader{data: contents},
-		info:    ast.NewFileInfo(filename, contents, version),
-		handler: handler,
+		input:      &runeReader{data: contents},
+		info:       info,
+		handler:    handler,
+		rawStrings: opts.rawStrings,
+		limits:     opts.limits,
+		names:      opts.names,
 	}, nil
 }
 
@@ -214,12 +253,18 @@ func (l *protoLex) writeVirtualRune(lval *protoSymType, rn rune) int {
 }
 
 func (l *protoLex) Lex(lval *protoSymType) int {
-	if l.handler.ReporterError() != nil {
-		// if error reporter already returned non-nil error,
-		// we can skip the rest of the input
+	if l.handler.ReporterError() != nil || l.limitExceeded {
+		// if error reporter already returned non-nil error, or if the
+		// input exceeded a limit, we can skip the rest of the input
 		return 0
 	}
 
+	if l.startToken != 0 {
+		tok := l.startToken
+		l.startToken = 0
+		return tok
+	}
+
 	l.comments = nil
 
 	for {
@@ -551,6 +596,13 @@ func (l *protoLex) Lex(lval *protoSymType) int {
 				}
 			}
 
+			if l.isVisibilityModifier(str) {
+				l.setIdent(lval, str)
+				if str == "export" {
+					return _EXPORT
+				}
+				return _LOCAL
+			}
 			if keyword, ok := keywords[str]; ok {
 				switch keyword {
 				case _RPC:
@@ -690,12 +742,23 @@ func (l *protoLex) Lex(lval *protoSymType) int {
 
 		if c == '\'' || c == '"' {
 			// string literal
-			str, raw, err := l.readStringLiteral(c)
+			isRaw := l.rawStrings && ast.ExtendedSyntaxEnabled && l.skipRawStringDelimiter(c)
+			var str string
+			var raw []byte
+			var err error
+			if isRaw {
+				str, raw, err = l.readRawStringLiteral(c)
+			} else {
+				str, raw, err = l.readStringLiteral(c)
+			}
 			if err != nil {
 				l.setError(lval, err)
 				return _ERROR
 			}
-			l.setString(lval, str, raw)
+			node := l.setString(lval, str, raw)
+			if isRaw {
+				l.ErrExtendedSyntaxAt("raw string literals are not standard protobuf syntax", node, CategoryNonStandard)
+			}
 			// check if this is a compound string literal
 			if _, ok := l.matchNextRune('"', '\''); ok {
 				l.inCompoundStringLiteral = true
@@ -709,6 +772,15 @@ func (l *protoLex) Lex(lval *protoSymType) int {
 			return _STRING_LIT
 		}
 
+		if c == '#' && l.textFormat {
+			// text format comment
+			if hasErr := l.skipToEndOfLineComment(lval); hasErr {
+				return _ERROR
+			}
+			l.comments = append(l.comments, l.newCommentToken())
+			continue
+		}
+
 		if c == '/' {
 			// comment
 			cn, szn, err := l.input.readRune()
@@ -720,7 +792,7 @@ func (l *protoLex) Lex(lval *protoSymType) int {
 				if hasErr := l.skipToEndOfLineComment(lval); hasErr {
 					return _ERROR
 				}
-				l.comments = append(l.comments, l.newToken())
+				l.comments = append(l.comments, l.newCommentToken())
 				continue
 			}
 			if cn == '*' {
@@ -732,7 +804,7 @@ func (l *protoLex) Lex(lval *protoSymType) int {
 					l.setError(lval, errors.New("block comment never terminates, unexpected EOF"))
 					return _ERROR
 				}
-				l.comments = append(l.comments, l.newToken())
+				l.comments = append(l.comments, l.newCommentToken())
 				continue
 			}
 			l.input.unreadRune(szn)
@@ -783,7 +855,34 @@ func parseFloat(token string) (float64, error) {
 func (l *protoLex) newToken() ast.Token {
 	offset := l.input.mark
 	length := l.input.pos - l.input.mark
-	return l.info.AddToken(offset, length)
+	tok := l.info.AddToken(offset, length)
+	l.numTokens++
+	if l.limits.MaxTokens > 0 && l.numTokens > l.limits.MaxTokens {
+		l.exceedLimit(tok, "file exceeds maximum of %d tokens", l.limits.MaxTokens)
+	}
+	return tok
+}
+
+func (l *protoLex) newCommentToken() ast.Token {
+	offset := l.input.mark
+	length := l.input.pos - l.input.mark
+	tok := l.info.AddToken(offset, length)
+	l.numComments++
+	if l.limits.MaxComments > 0 && l.numComments > l.limits.MaxComments {
+		l.exceedLimit(tok, "file exceeds maximum of %d comments", l.limits.MaxComments)
+	}
+	return tok
+}
+
+// exceedLimit reports that the given token caused the input to exceed one
+// of the configured limits. The rest of the input will be ignored. The error
+// returned by the handler, if any, is returned from Parse.
+func (l *protoLex) exceedLimit(tok ast.Token, format string, args ...any) {
+	if l.limitExceeded {
+		return
+	}
+	l.limitExceeded = true
+	l.limitErr = l.handler.HandleErrorf(l.info.TokenInfo(tok), format, args...)
 }
 
 func (l *protoLex) setPrevAndAddComments(n ast.TerminalNode) {
@@ -813,6 +912,7 @@ func (l *protoLex) setPrevAndAddComments(n ast.TerminalNode) {
 			// if there are multiple comments or if the block comment ends
 			// on a line before n.
 			canDonate := strings.HasPrefix(commentInfo.RawText(), "//") ||
+				strings.HasPrefix(commentInfo.RawText(), "#") ||
 				len(comments) > 1 || commentInfo.End().Line < nStart
 
 			if canDonate {
@@ -840,7 +940,7 @@ func (l *protoLex) setPrevAndAddComments(n ast.TerminalNode) {
 	l.prevSym = n
 }
 
-func (l *protoLex) setString(lval *protoSymType, val string, raw []byte) {
+func (l *protoLex) setString(lval *protoSymType, val string, raw []byte) *ast.StringLiteralNode {
 	node := &ast.StringLiteralNode{
 		Token: l.newToken(),
 		Val:   val,
@@ -857,10 +957,11 @@ func (l *protoLex) setString(lval *protoSymType, val string, raw []byte) {
 		lval.sv = node.AsStringValueNode()
 	}
 	l.setPrevAndAddComments(node)
+	return node
 }
 
 func (l *protoLex) setIdent(lval *protoSymType, val string) {
-	lval.id = &ast.IdentNode{Token: l.newToken(), Val: val}
+	lval.id = &ast.IdentNode{Token: l.newToken(), Val: l.names.Intern(val)}
 	l.setPrevAndAddComments(lval.id)
 }
 
@@ -877,6 +978,17 @@ func (l *protoLex) setFloat(lval *protoSymType, val float64, raw string) {
 func (l *protoLex) setRune(lval *protoSymType, val rune) {
 	lval.b = &ast.RuneNode{Token: l.newToken(), Rune: val}
 	l.setPrevAndAddComments(lval.b)
+	switch val {
+	case '{', '[', '(', '<':
+		l.depth++
+		if l.limits.MaxNestingDepth > 0 && l.depth > l.limits.MaxNestingDepth {
+			l.exceedLimit(lval.b.GetToken(), "exceeded maximum nesting depth of %d", l.limits.MaxNestingDepth)
+		}
+	case '}', ']', ')', '>':
+		if l.depth > 0 {
+			l.depth--
+		}
+	}
 }
 
 func (l *protoLex) setVirtualRune(lval *protoSymType, val rune) {
@@ -1312,6 +1424,56 @@ func (l *protoLex) readStringLiteral(quote rune) (string, []byte, error) {
 	return buf.String(), l.input.data[start:l.input.pos], nil
 }
 
+// skipRawStringDelimiter is called after an opening quote has been read. If
+// the next two runes are the same quote, they are consumed and true is
+// returned, indicating the start of a triple-quoted raw string literal.
+func (l *protoLex) skipRawStringDelimiter(quote rune) bool {
+	l.input.save()
+	for i := 0; i < 2; i++ {
+		if c, _, err := l.input.readRune(); err != nil || c != quote {
+			l.input.restore()
+			return false
+		}
+	}
+	return true
+}
+
+// readRawStringLiteral reads the rest of a triple-quoted raw string literal,
+// after the opening delimiter. The contents are taken verbatim, without any
+// escape processing, and may span multiple lines. The literal ends at the
+// first occurrence of the closing delimiter. The returned raw bytes include
+// both delimiters.
+func (l *protoLex) readRawStringLiteral(quote rune) (string, []byte, error) {
+	start := l.input.offset() - 3
+	var buf bytes.Buffer
+	var quotes int
+	for {
+		c, _, err := l.input.readRune()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", nil, err
+		}
+		if c == quote {
+			quotes++
+			if quotes == 3 {
+				break
+			}
+			continue
+		}
+		for ; quotes > 0; quotes-- {
+			buf.WriteRune(quote)
+		}
+		if c == 0 {
+			return "", nil, errors.New("null character ('\\0') not allowed in string literal")
+		}
+		l.maybeNewLine(c)
+		buf.WriteRune(c)
+	}
+	return buf.String(), l.input.data[start:l.input.pos], nil
+}
+
 func (l *protoLex) skipToEndOfLineComment(lval *protoSymType) (hasErr bool) {
 	for {
 		c, sz, err := l.input.readRune()
@@ -1595,6 +1757,24 @@ func (l *protoLex) canStartFileElement() bool {
 	return false
 }
 
+// isVisibilityModifier returns true if the given identifier, which was just
+// read, is an "export" or "local" modifier for a message or enum declaration.
+// These are not reserved words, so they are only treated as keywords in that
+// position and can still be used as names elsewhere.
+func (l *protoLex) isVisibilityModifier(str string) bool {
+	if str != "export" && str != "local" {
+		return false
+	}
+	if l.prevSym != nil {
+		prev, ok := l.prevSym.(*ast.RuneNode)
+		if !ok || (prev.Rune != '{' && prev.Rune != '}' && prev.Rune != ';') {
+			return false
+		}
+	}
+	next, _ := l.peekNextIdentsFast(2)
+	return len(next) == 2 && (next[0] == "message" || next[0] == "enum") && !strings.Contains(next[1], ".")
+}
+
 func canDirectlyPrecedeVirtualSemi(c rune) bool {
 	switch c {
 	case ';', '{', '<', '=':
@@ -1093,7 +1093,7 @@ func TestStringLiteralMultipleErrors(t *testing.T) {
 }
 
 func newTestLexer(t *testing.T, in io.Reader, h *reporter.Handler) *protoLex {
-	lexer, err := newLexer(in, "test.proto", h, 0)
+	lexer, err := newLexer(in, "test.proto", h, 0, parseOptions{})
 	require.NoError(t, err)
 	return lexer
 }
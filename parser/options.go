@@ -0,0 +1,218 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/protointernal"
+)
+
+// ParserOption is an option that can be passed to Parse.
+type ParserOption func(*parseOptions)
+
+type parseOptions struct {
+	limits           Limits
+	validationLimits ValidationLimits
+	encoding         Encoding
+	strictUTF8       bool
+	rawStrings       bool
+	experimental     bool
+	positionEncoding ast.FileInfo_PositionEncoding
+	protocOneofNames bool
+	names            *protointernal.Interner
+}
+
+func newParseOptions(opts []ParserOption) parseOptions {
+	var po parseOptions
+	for _, opt := range opts {
+		opt(&po)
+	}
+	po.validationLimits = po.validationLimits.withDefaults()
+	return po
+}
+
+// WithLimits returns an option that applies the given limits to the input
+// while parsing. See Limits for more details.
+func WithLimits(limits Limits) ParserOption {
+	return func(opts *parseOptions) {
+		opts.limits = limits
+	}
+}
+
+// Limits bounds the resources used to parse a single file, to protect
+// against pathological or hostile inputs that would otherwise cause the
+// parser to allocate without bound. A zero value for any limit means that
+// the limit is not enforced.
+//
+// When a limit is exceeded, an error is reported with the position at which
+// it was exceeded and the rest of the file is ignored.
+type Limits struct {
+	// The maximum size of a source file, in bytes. Files larger than this
+	// are not parsed at all.
+	MaxFileSize int
+	// The maximum number of tokens in a file, not counting comments.
+	MaxTokens int
+	// The maximum number of comments in a file.
+	MaxComments int
+	// The maximum depth of nested braces, brackets, parentheses, and angle
+	// brackets. This bounds the nesting of message literals and arrays in
+	// option values, in addition to the nesting of declarations.
+	MaxNestingDepth int
+}
+
+// DefaultLimits are generous limits that no reasonable source file should
+// reach, suitable for processes (such as language servers) that must not be
+// taken down by a single malicious or corrupt file.
+var DefaultLimits = Limits{
+	MaxFileSize:     64 << 20, // 64 MiB
+	MaxTokens:       8 << 20,
+	MaxComments:     4 << 20,
+	MaxNestingDepth: 1024,
+}
+
+// WithSourceEncoding returns an option that transcodes source files that are
+// not valid UTF-8 from the given legacy encoding, instead of treating invalid
+// bytes as replacement characters. When a file is transcoded, a warning is
+// reported at the first character that was converted. Sequences that are
+// already valid UTF-8 are left alone.
+func WithSourceEncoding(enc Encoding) ParserOption {
+	return func(opts *parseOptions) {
+		opts.encoding = enc
+	}
+}
+
+// WithStrictUTF8 returns an option that controls whether source files must
+// be valid UTF-8. By default, like protoc, invalid bytes are accepted and
+// read as replacement characters. In strict mode, an error is reported at
+// each invalid byte, including those in comments and string literals.
+//
+// Strict mode is checked after any transcoding done for WithSourceEncoding,
+// so files in the configured legacy encoding are still accepted.
+func WithStrictUTF8(strict bool) ParserOption {
+	return func(opts *parseOptions) {
+		opts.strictUTF8 = strict
+	}
+}
+
+// WithRawStrings returns an option that controls whether raw string literals,
+// delimited by three double quotes or three single quotes, are accepted.
+// Their contents are taken verbatim, without escape processing, and may span
+// lines, which is useful for long regular expressions and descriptions in
+// option values.
+//
+// Raw strings are not standard protobuf syntax, so they are only recognized
+// when extended syntax is enabled (see ast.ExtendedSyntaxEnabled), and each
+// one is reported as an ExtendedSyntaxError warning with the category
+// CategoryNonStandard. In the descriptor, their values are indistinguishable
+// from standard string literals.
+func WithRawStrings(enabled bool) ParserOption {
+	return func(opts *parseOptions) {
+		opts.rawStrings = enabled
+	}
+}
+
+// WithExperimentalEditions returns an option that controls whether files may
+// use editions that are newer than editions.MaxSupportedEdition, up to
+// editions.MaxExperimentalEdition. It is accepted by ResultFromAST.
+//
+// This allows trying out new language features, such as the "export" and
+// "local" modifiers of edition 2024, before they are fully supported. The
+// descriptors produced for such files may change in future releases.
+func WithExperimentalEditions(enabled bool) ParserOption {
+	return func(opts *parseOptions) {
+		opts.experimental = enabled
+	}
+}
+
+// WithPositionEncoding returns an option that sets how column numbers are
+// computed for positions in the parsed file, including the positions of any
+// errors reported while parsing. By default, columns count bytes. With
+// ast.FileInfo_PositionEncodingProtocCompatible, they are computed like
+// protoc: tabs advance to the next multiple of 8 columns and multi-byte
+// characters count as one column.
+func WithPositionEncoding(enc ast.FileInfo_PositionEncoding) ParserOption {
+	return func(opts *parseOptions) {
+		opts.positionEncoding = enc
+	}
+}
+
+// WithProtocSyntheticOneofNames returns an option that controls how names are
+// chosen for the synthetic oneofs of proto3 optional fields. It is accepted by
+// ResultFromAST. The name is the field name with a "_" prefix, with further
+// "X" prefixes added until it does not conflict with another name. By
+// default, the name avoids conflicts with the names of all elements declared
+// in the message. When enabled, like protoc, it only avoids the names of
+// fields and oneofs, so it may conflict with a nested message, enum, enum
+// value, or extension, which makes the resulting descriptor invalid.
+func WithProtocSyntheticOneofNames(enabled bool) ParserOption {
+	return func(opts *parseOptions) {
+		opts.protocOneofNames = enabled
+	}
+}
+
+// WithNameInterner returns an option that causes the values of identifiers
+// in the parsed file to be de-duplicated using the given interner. This is
+// typically the interner of the symbol table that the file will be linked
+// with (see linker.Symbols.Names), so that element names share storage with
+// the fully-qualified names computed when linking.
+func WithNameInterner(names *protointernal.Interner) ParserOption {
+	return func(opts *parseOptions) {
+		opts.names = names
+	}
+}
+
+// WithValidationLimits returns an option that overrides the limits enforced
+// when validating the descriptor produced from a parsed file. It is accepted
+// by ResultFromAST. See ValidationLimits for more details.
+func WithValidationLimits(limits ValidationLimits) ParserOption {
+	return func(opts *parseOptions) {
+		opts.validationLimits = limits
+	}
+}
+
+// ValidationLimits configures limits that protoc enforces on the contents of
+// a file, which are checked when a descriptor is created from an AST. Unlike
+// Limits, a zero value for any field means the default (protoc's) value is
+// used, so the limits can be raised but never turned off entirely.
+type ValidationLimits struct {
+	// The length that a package name (with whitespace removed) must be
+	// less than. Defaults to 512.
+	MaxPackageNameLength int
+	// The maximum number of periods in a package name. Defaults to 100.
+	MaxPackageNamePeriods int
+	// The depth that nested messages (including groups and map entries)
+	// must be less than. Defaults to 32.
+	MaxMessageNestingDepth int
+}
+
+// DefaultValidationLimits are the limits enforced by protoc.
+var DefaultValidationLimits = ValidationLimits{
+	MaxPackageNameLength:   512,
+	MaxPackageNamePeriods:  100,
+	MaxMessageNestingDepth: 32,
+}
+
+func (l ValidationLimits) withDefaults() ValidationLimits {
+	if l.MaxPackageNameLength <= 0 {
+		l.MaxPackageNameLength = DefaultValidationLimits.MaxPackageNameLength
+	}
+	if l.MaxPackageNamePeriods <= 0 {
+		l.MaxPackageNamePeriods = DefaultValidationLimits.MaxPackageNamePeriods
+	}
+	if l.MaxMessageNestingDepth <= 0 {
+		l.MaxMessageNestingDepth = DefaultValidationLimits.MaxMessageNestingDepth
+	}
+	return l
+}
@@ -15,6 +15,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -94,9 +95,19 @@ func setTokenName(token int, text string) {
 // depends on the nature of the syntax error and if there are any tokens after the
 // syntax error that can help the parser recover. This error recovery and partial
 // AST production is best effort.
-func Parse(filename string, r io.Reader, handler *reporter.Handler, version int32) (*ast.FileNode, error) {
-	lx, err := newLexer(r, filename, handler, version)
+//
+// Options may be given to customize parsing, such as to limit resource usage
+// with WithLimits.
+func Parse(filename string, r io.Reader, handler *reporter.Handler, version int32, opts ...ParserOption) (*ast.FileNode, error) {
+	po := newParseOptions(opts)
+	lx, err := newLexer(r, filename, handler, version, po)
 	if err != nil {
+		if errors.Is(err, errFileTooLarge) {
+			if err := handler.HandleErrorf(ast.UnknownSpan(filename), "file exceeds maximum size of %d bytes", po.limits.MaxFileSize); err != nil {
+				return ast.NewEmptyFileNode(filename, version), err
+			}
+			return ast.NewEmptyFileNode(filename, version), handler.Error()
+		}
 		return nil, err
 	}
 	protoParse(lx)
@@ -105,6 +116,9 @@ func Parse(filename string, r io.Reader, handler *reporter.Handler, version int3
 		// or the file was empty; synthesize empty non-nil AST
 		lx.res = ast.NewEmptyFileNode(filename, version)
 	}
+	if lx.limitErr != nil {
+		return lx.res, lx.limitErr
+	}
 	return lx.res, handler.Error()
 }
 
@@ -214,3 +228,44 @@ type Result interface {
 
 	ImportInsertionPoint() ast.SourcePos
 }
+
+// SyntheticOneofNamingOf returns the algorithm that was used to name the
+// synthetic oneofs of proto3 optional fields in the given result's descriptor
+// proto. This is useful when comparing descriptors against those produced by
+// protoc. It returns SyntheticOneofNamingUnknown if the result was created
+// without an AST or if it does not have a SyntheticOneofNaming method, like
+// the results returned by this package.
+func SyntheticOneofNamingOf(r Result) SyntheticOneofNaming {
+	if n, ok := r.(interface{ SyntheticOneofNaming() SyntheticOneofNaming }); ok {
+		return n.SyntheticOneofNaming()
+	}
+	return SyntheticOneofNamingUnknown
+}
+
+// SyntheticOneofNaming identifies an algorithm for naming the synthetic oneofs
+// of proto3 optional fields. See WithProtocSyntheticOneofNames.
+type SyntheticOneofNaming int
+
+const (
+	// SyntheticOneofNamingUnknown indicates that the descriptor proto was not
+	// created from an AST, so it is not known how its synthetic oneofs were
+	// named.
+	SyntheticOneofNamingUnknown SyntheticOneofNaming = iota
+	// SyntheticOneofNamingDefault indicates that the names avoid conflicts
+	// with the names of all elements declared in the message.
+	SyntheticOneofNamingDefault
+	// SyntheticOneofNamingProtoc indicates that the names were chosen exactly
+	// like protoc, only avoiding conflicts with the names of fields and oneofs.
+	SyntheticOneofNamingProtoc
+)
+
+func (n SyntheticOneofNaming) String() string {
+	switch n {
+	case SyntheticOneofNamingDefault:
+		return "default"
+	case SyntheticOneofNamingProtoc:
+		return "protoc"
+	default:
+		return "unknown"
+	}
+}
@@ -17,8 +17,10 @@ package parser
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,7 +31,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/descriptorpb"
 
+	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/internal"
+	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/reporter"
 )
 
@@ -364,6 +368,439 @@ func TestPathological(t *testing.T) {
 	}
 }
 
+func TestLimits(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		limits      Limits
+		source      string
+		expectedErr string
+	}{
+		{
+			name:        "file size",
+			limits:      Limits{MaxFileSize: 16},
+			source:      `syntax = "proto3"; message Foo {}`,
+			expectedErr: "test.proto: file exceeds maximum size of 16 bytes",
+		},
+		{
+			name:        "tokens",
+			limits:      Limits{MaxTokens: 6},
+			source:      `syntax = "proto3"; message Foo {}`,
+			expectedErr: "test.proto:1:32-33: file exceeds maximum of 6 tokens",
+		},
+		{
+			name:        "comments",
+			limits:      Limits{MaxComments: 1},
+			source:      "// one\n// two\nsyntax = \"proto3\";",
+			expectedErr: "test.proto:2:1-7: file exceeds maximum of 1 comments",
+		},
+		{
+			name:   "nesting",
+			limits: Limits{MaxNestingDepth: 3},
+			source: `syntax = "proto3";
+option (foo) = { a { b [ { c: 1 } ] } };`,
+			expectedErr: "test.proto:2:26-27: exceeded maximum nesting depth of 3",
+		},
+		{
+			name:   "within limits",
+			limits: DefaultLimits,
+			source: `syntax = "proto3"; // comment
+option (foo) = { a { b [ { c: 1 } ] } };`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			handler := reporter.NewHandler(nil)
+			fileNode, err := Parse("test.proto", strings.NewReader(tc.source), handler, 0, WithLimits(tc.limits))
+			require.NotNil(t, fileNode)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestLimitsReporterError(t *testing.T) {
+	t.Parallel()
+	errStop := errors.New("stop")
+	handler := reporter.NewHandler(reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+		return fmt.Errorf("%w: %w", errStop, err)
+	}, nil))
+	fileNode, err := Parse("test.proto", strings.NewReader(`syntax = "proto3"; message Foo {}`), handler, 0, WithLimits(Limits{MaxTokens: 6}))
+	require.NotNil(t, fileNode)
+	require.ErrorIs(t, err, errStop)
+	require.ErrorContains(t, err, "file exceeds maximum of 6 tokens")
+}
+
+func TestSourceEncoding(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name            string
+		source          string
+		encoding        Encoding
+		expectedWarning string
+		expectedDefault string
+	}{
+		{
+			name:            "latin1",
+			source:          "syntax = \"proto2\";\n// caf\xe9\nmessage Foo { optional string s = 1 [default = \"na\xefve\"]; }",
+			encoding:        EncodingLatin1,
+			expectedWarning: "test.proto:2:7-9: file is not valid UTF-8; transcoded from Latin-1",
+			expectedDefault: "naïve",
+		},
+		{
+			name:            "windows1252",
+			source:          "syntax = \"proto2\";\nmessage Foo { optional string s = 1 [default = \"\x93quoted\x94 \x80\"]; }",
+			encoding:        EncodingWindows1252,
+			expectedWarning: "test.proto:2:49-52: file is not valid UTF-8; transcoded from Windows-1252",
+			expectedDefault: "\u201cquoted\u201d \u20ac",
+		},
+		{
+			name:            "mixed",
+			source:          "syntax = \"proto2\";\nmessage Foo { optional string s = 1 [default = \"caf\u00e9 \xe9\"]; }",
+			encoding:        EncodingLatin1,
+			expectedWarning: "test.proto:2:55-57: file is not valid UTF-8; transcoded from Latin-1",
+			expectedDefault: "café é",
+		},
+		{
+			name:            "valid utf8",
+			source:          "syntax = \"proto2\";\nmessage Foo { optional string s = 1 [default = \"caf\u00e9\"]; }",
+			encoding:        EncodingLatin1,
+			expectedDefault: "café",
+		},
+		{
+			name:            "not enabled",
+			source:          "syntax = \"proto2\";\nmessage Foo { optional string s = 1 [default = \"caf\xe9\"]; }",
+			expectedDefault: "caf\ufffd",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var warnings []string
+			handler := reporter.NewHandler(reporter.NewReporter(nil, func(err reporter.ErrorWithPos) {
+				warnings = append(warnings, err.Error())
+			}))
+			fileNode, err := Parse("test.proto", strings.NewReader(tc.source), handler, 0, WithSourceEncoding(tc.encoding))
+			require.NoError(t, err)
+			if tc.expectedWarning == "" {
+				assert.Empty(t, warnings)
+			} else {
+				require.Len(t, warnings, 1)
+				assert.Equal(t, tc.expectedWarning, warnings[0])
+			}
+			res, err := ResultFromAST(fileNode, true, handler)
+			require.NoError(t, err)
+			// default is not interpreted until options are processed
+			opt := res.FileDescriptorProto().GetMessageType()[0].GetField()[0].GetOptions().GetUninterpretedOption()[0]
+			assert.Equal(t, tc.expectedDefault, string(opt.GetStringValue()))
+		})
+	}
+}
+
+func TestStrictUTF8(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name           string
+		source         string
+		encoding       Encoding
+		expectedErrors []string
+	}{
+		{
+			name:           "string literal",
+			source:         "syntax = \"proto3\";\noption go_package = \"caf\xe9\";",
+			expectedErrors: []string{"test.proto:2:25-26: invalid UTF-8 byte 0xe9"},
+		},
+		{
+			name:           "line comment",
+			source:         "syntax = \"proto3\";\n\t// na\xefve\nmessage Foo {}",
+			expectedErrors: []string{"test.proto:2:7-8: invalid UTF-8 byte 0xef"},
+		},
+		{
+			name:   "block comments",
+			source: "syntax = \"proto3\";\n/* \xff\n * \u00e9\xc3 */\nmessage Foo {}",
+			expectedErrors: []string{
+				"test.proto:2:4-5: invalid UTF-8 byte 0xff",
+				"test.proto:3:6-7: invalid UTF-8 byte 0xc3",
+			},
+		},
+		{
+			name:   "valid",
+			source: "syntax = \"proto3\";\n// caf\u00e9 \U0001F600\noption go_package = \"\u00e9\";",
+		},
+		{
+			name:     "transcoded",
+			source:   "syntax = \"proto3\";\noption go_package = \"caf\xe9\";",
+			encoding: EncodingLatin1,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var errs []string
+			handler := reporter.NewHandler(reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+				errs = append(errs, err.Error())
+				return nil
+			}, nil))
+			fileNode, err := Parse("test.proto", strings.NewReader(tc.source), handler, 0,
+				WithStrictUTF8(true), WithSourceEncoding(tc.encoding))
+			require.NotNil(t, fileNode)
+			assert.Equal(t, tc.expectedErrors, errs)
+			if len(tc.expectedErrors) > 0 {
+				require.ErrorIs(t, err, reporter.ErrInvalidSource)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// without strict mode, the same source is accepted
+			fileNode, err = Parse("test.proto", strings.NewReader(tc.source), reporter.NewHandler(nil), 0)
+			require.NotNil(t, fileNode)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	t.Parallel()
+	source := `syntax = "proto3";
+option (re) = """^\d+"[a-z]""?$""";
+option (desc) = '''first line
+  second \n line''';
+message Foo {}
+`
+	parse := func(t *testing.T, rawStrings bool) (*ast.FileNode, []reporter.ErrorWithPos, Result) {
+		t.Helper()
+		var warnings []reporter.ErrorWithPos
+		handler := reporter.NewHandler(reporter.NewReporter(nil, func(err reporter.ErrorWithPos) {
+			warnings = append(warnings, err)
+		}))
+		fileNode, err := Parse("test.proto", strings.NewReader(source), handler, 0, WithRawStrings(rawStrings))
+		require.NoError(t, err)
+		res, err := ResultFromAST(fileNode, true, handler)
+		require.NoError(t, err)
+		return fileNode, warnings, res
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		fileNode, warnings, res := parse(t, true)
+		require.Len(t, warnings, 2)
+		for _, w := range warnings {
+			var extErr ExtendedSyntaxError
+			require.ErrorAs(t, w, &extErr)
+			assert.Equal(t, CategoryNonStandard, extErr.Category())
+			assert.True(t, extErr.CanFormat())
+		}
+		assert.Equal(t, "test.proto:2:15-35: error: raw string literals are not standard protobuf syntax", warnings[0].Error())
+		assert.Equal(t, "test.proto:3:17-20: error: raw string literals are not standard protobuf syntax", warnings[1].Error())
+		assert.Equal(t, 4, warnings[1].GetPosition().End().Line)
+
+		opts := res.FileDescriptorProto().GetOptions().GetUninterpretedOption()
+		require.Len(t, opts, 2)
+		assert.Equal(t, `^\d+"[a-z]""?$`, string(opts[0].GetStringValue()))
+		assert.Equal(t, "first line\n  second \\n line", string(opts[1].GetStringValue()))
+
+		var lits []*ast.StringLiteralNode
+		ast.Inspect(fileNode, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.StringLiteralNode); ok {
+				lits = append(lits, lit)
+			}
+			return true
+		})
+		require.Len(t, lits, 3)
+		assert.False(t, lits[0].IsRaw())
+		assert.True(t, lits[1].IsRaw())
+		assert.True(t, lits[2].IsRaw())
+		assert.Equal(t, `"^\\d+\"[a-z]\"\"?$"`, lits[1].StandardLiteral())
+		assert.Equal(t, `"first line\n  second \\n line"`, lits[2].StandardLiteral())
+
+		// positions after a multi-line raw string are still correct
+		msg := res.FileDescriptorProto().GetMessageType()[0]
+		assert.Equal(t, 5, fileNode.NodeInfo(res.MessageNode(msg)).Start().Line)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		handler := reporter.NewHandler(nil)
+		_, err := Parse("test.proto", strings.NewReader(source), handler, 0)
+		require.Error(t, err)
+	})
+
+	t.Run("unterminated", func(t *testing.T) {
+		t.Parallel()
+		handler := reporter.NewHandler(nil)
+		_, err := Parse("test.proto", strings.NewReader(`syntax = "proto3"; option (foo) = """abc""`), handler, 0, WithRawStrings(true))
+		require.ErrorContains(t, err, "unexpected EOF")
+	})
+}
+
+func TestParseOption(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name             string
+		source           string
+		expectedName     string
+		expectedVal      string
+		expectedWarnings []string
+		expectedErr      string
+	}{
+		{
+			name:         "extension with message literal",
+			source:       "(my.opt).foo = {bar: 1}",
+			expectedName: "(my.opt).foo",
+			expectedVal:  "1:16-24",
+		},
+		{
+			name:         "trailing semicolon",
+			source:       "java_package = \"foo\";",
+			expectedName: "java_package",
+			expectedVal:  "1:16-21",
+		},
+		{
+			name:         "multi-line message literal",
+			source:       "(a) = {\n  bar: 1\n  // comment\n  baz: [1, 2]\n}\n",
+			expectedName: "(a)",
+			expectedVal:  "1:7-2",
+		},
+		{
+			name:             "missing value",
+			source:           "(a) =",
+			expectedName:     "(a)",
+			expectedWarnings: []string{"test.proto:1:6: error: expected value"},
+		},
+		{
+			name:             "missing equals",
+			source:           "foo.bar",
+			expectedName:     "foo.bar",
+			expectedWarnings: []string{"test.proto:1:8: error: expected '='"},
+		},
+		{
+			name:        "syntax error",
+			source:      "(a) = = 1",
+			expectedErr: "test.proto:1:7: syntax error: unexpected '='",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var warnings []string
+			handler := reporter.NewHandler(reporter.NewReporter(nil, func(err reporter.ErrorWithPos) {
+				warnings = append(warnings, err.Error())
+			}))
+			opt, info, err := ParseOption("test.proto", tc.source, handler)
+			require.NotNil(t, opt)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedWarnings, warnings)
+			assert.Nil(t, opt.Keyword)
+			assert.Equal(t, tc.expectedName, stringForOptionName(opt.GetName()))
+			if tc.expectedVal == "" {
+				assert.Nil(t, opt.Val)
+			} else {
+				require.NotNil(t, opt.Val)
+				assert.Equal(t, "test.proto:"+tc.expectedVal, info.NodeInfo(opt.Val).String())
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	t.Parallel()
+	handler := reporter.NewHandler(nil)
+	val, info, err := ParseValue("test.proto", "{a: {b: 1} c: [\"x\", \"y\"]};", handler)
+	require.NoError(t, err)
+	msgLit := val.GetMessageLiteral()
+	require.NotNil(t, msgLit)
+	require.Len(t, msgLit.GetElements(), 2)
+	assert.Equal(t, ast.Identifier("b"), msgLit.GetElements()[0].GetVal().GetMessageLiteral().GetElements()[0].GetName().GetName().AsIdentifier())
+	assert.Equal(t, "test.proto:1:12-25", info.NodeInfo(msgLit.GetElements()[1]).String())
+
+	val, _, err = ParseValue("test.proto", `"abc" 'def'`, reporter.NewHandler(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "abcdef", val.Value())
+
+	val, _, err = ParseValue("test.proto", "-inf", reporter.NewHandler(nil))
+	require.NoError(t, err)
+	assert.Equal(t, math.Inf(-1), val.Value())
+
+	val, _, err = ParseValue("test.proto", "{", reporter.NewHandler(nil))
+	require.ErrorContains(t, err, "syntax error")
+	assert.Nil(t, val)
+}
+
+func TestSymbolVisibility(t *testing.T) {
+	t.Parallel()
+	source := `edition = "2024";
+package foo;
+export message Foo {
+  local enum Kind {
+    KIND_UNSPECIFIED = 0;
+  }
+  message Bar {}
+  string export = 1;
+  local local = 2;
+}
+local message local {}
+enum export {
+  EXPORT_UNSPECIFIED = 0;
+}
+`
+	parse := func(source string, opts ...ParserOption) (Result, []string) {
+		var errs []string
+		handler := reporter.NewHandler(reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+			errs = append(errs, err.Error())
+			return nil
+		}, nil))
+		fileNode, err := Parse("test.proto", strings.NewReader(source), handler, 0)
+		require.NoError(t, err)
+		res, _ := ResultFromAST(fileNode, true, handler, opts...)
+		return res, errs
+	}
+
+	res, errs := parse(source, WithExperimentalEditions(true))
+	require.Empty(t, errs)
+	fd := res.FileDescriptorProto()
+	assert.Equal(t, descriptorpb.Edition_EDITION_2024, fd.GetEdition())
+	foo := fd.MessageType[0]
+	assert.Equal(t, protointernal.VisibilityExport, protointernal.GetVisibility(foo))
+	assert.Equal(t, protointernal.VisibilityLocal, protointernal.GetVisibility(foo.EnumType[0]))
+	assert.Equal(t, protointernal.VisibilityUnset, protointernal.GetVisibility(foo.NestedType[0]))
+	assert.Equal(t, []string{"export", "local"}, []string{foo.Field[0].GetName(), foo.Field[1].GetName()})
+	assert.Equal(t, "local", fd.MessageType[1].GetName())
+	assert.Equal(t, protointernal.VisibilityLocal, protointernal.GetVisibility(fd.MessageType[1]))
+	assert.Equal(t, "export", fd.EnumType[0].GetName())
+	assert.Equal(t, protointernal.VisibilityUnset, protointernal.GetVisibility(fd.EnumType[0]))
+	// the modifier is part of the declaration
+	msgNode := res.MessageNode(foo).Unwrap()
+	assert.Equal(t, "test.proto:3:1-7", res.AST().TokenInfo(msgNode.Start()).String())
+	barNode := res.MessageNode(foo.NestedType[0]).Unwrap()
+	assert.Equal(t, "test.proto:7:3-10", res.AST().TokenInfo(barNode.Start()).String())
+	// clones still allow the experimental edition
+	clone := Clone(res)
+	assert.True(t, clone.(*result).experimentalEditions)
+	clone = Clone(otherResultImpl{Result: res})
+	assert.Equal(t, descriptorpb.Edition_EDITION_2024, clone.FileDescriptorProto().GetEdition())
+
+	_, errs = parse(source)
+	assert.Equal(t, []string{`test.proto:1:11-17: edition value "2024" not recognized; should be one of ["2023"]`}, errs)
+
+	_, errs = parse("syntax = \"proto3\";\nexport message Foo {}\nmessage Bar {\n  local enum Baz { BAZ_UNSPECIFIED = 0; }\n}\n")
+	assert.Equal(t, []string{
+		`test.proto:2:1-7: message Foo: "export" modifier is only allowed in edition 2024 or later`,
+		`test.proto:4:3-8: enum Bar.Baz: "local" modifier is only allowed in edition 2024 or later`,
+	}, errs)
+}
+
 func TestExtendedSyntax(t *testing.T) {
 	t.Parallel()
 	// inputs that have been found in the past to cause panics by oss-fuzz
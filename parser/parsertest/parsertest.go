@@ -0,0 +1,263 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parsertest provides a golden-test harness that verifies that
+// formatting a proto source file preserves it. Each file is parsed,
+// formatted, and parsed again, and the harness checks that both parses
+// produce the same AST, ignoring positions and whitespace, and the same
+// file descriptor proto.
+//
+// The formatter is pluggable, so forks and rule authors can test their own
+// formatters and AST rewrites. The default formatter, Reprint, reproduces the
+// original source text exactly.
+package parsertest
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// Formatter formats the given parsed file and returns the formatted source.
+type Formatter func(filename string, file *ast.FileNode) ([]byte, error)
+
+// Option configures a round-trip check.
+type Option func(*options)
+
+type options struct {
+	formatter    Formatter
+	updateGolden bool
+}
+
+// WithFormatter configures the formatter under test. If not specified,
+// Reprint is used.
+func WithFormatter(formatter Formatter) Option {
+	return func(o *options) {
+		o.formatter = formatter
+	}
+}
+
+// WithUpdateGolden configures whether golden files are written instead of
+// checked. When true, the formatted output of each file is written to the
+// file's golden file, creating it if necessary. This is typically wired to a
+// test flag, so golden files can be regenerated after an intended change.
+func WithUpdateGolden(update bool) Option {
+	return func(o *options) {
+		o.updateGolden = update
+	}
+}
+
+// GoldenSuffix is appended to the name of a .proto file to get the name of
+// its golden file. If a golden file exists, the formatted output must match
+// its contents.
+const GoldenSuffix = ".golden"
+
+// CheckDir checks round trips for all .proto files in the given directory and
+// its subdirectories. Each file is checked in its own subtest, named after the
+// file's path relative to dir.
+func CheckDir(t *testing.T, dir string, opts ...Option) {
+	t.Helper()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".proto" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		t.Run(filepath.ToSlash(rel), func(t *testing.T) {
+			t.Helper()
+			CheckFile(t, path, opts...)
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk %s: %v", dir, err)
+	}
+}
+
+// CheckFile checks the round trip of the given .proto file, including its
+// golden file, if present.
+func CheckFile(t testing.TB, path string, opts ...Option) {
+	t.Helper()
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	formatted, ok := check(t, filepath.Base(path), data, o)
+	if !ok {
+		return
+	}
+	goldenPath := path + GoldenSuffix
+	if o.updateGolden {
+		if err := os.WriteFile(goldenPath, formatted, 0o644); err != nil { //nolint:gosec // golden files are not secret
+			t.Fatalf("failed to write %s: %v", goldenPath, err)
+		}
+		return
+	}
+	golden, err := os.ReadFile(goldenPath)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		t.Fatalf("failed to read %s: %v", goldenPath, err)
+	}
+	if diff := cmp.Diff(string(golden), string(formatted)); diff != "" {
+		t.Errorf("%s: formatted output does not match golden file (-want +got):\n%s", path, diff)
+	}
+}
+
+// CheckSource checks the round trip of the given source. The filename is only
+// used in error messages.
+func CheckSource(t testing.TB, filename string, data []byte, opts ...Option) {
+	t.Helper()
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	check(t, filename, data, o)
+}
+
+func check(t testing.TB, filename string, data []byte, o options) ([]byte, bool) {
+	t.Helper()
+	formatter := o.formatter
+	if formatter == nil {
+		formatter = Reprint
+	}
+
+	original, err := parser.Parse(filename, bytes.NewReader(data), reporter.NewHandler(nil), 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", filename, err)
+	}
+	formatted, err := formatter(filename, original)
+	if err != nil {
+		t.Fatalf("failed to format %s: %v", filename, err)
+	}
+	reparsed, err := parser.Parse(filename, bytes.NewReader(formatted), reporter.NewHandler(nil), 0)
+	if err != nil {
+		t.Errorf("failed to parse formatted %s: %v\nformatted source:\n%s", filename, err, formatted)
+		return formatted, false
+	}
+
+	ok := true
+	if diff := cmp.Diff(stripPositions(original), stripPositions(reparsed), protocmp.Transform(), cmpopts.EquateNaNs()); diff != "" {
+		t.Errorf("%s: AST changed after formatting (-original +formatted):\n%s", filename, diff)
+		ok = false
+	}
+	originalFd, err := descriptorProto(original)
+	if err != nil {
+		t.Fatalf("failed to produce descriptor for %s: %v", filename, err)
+	}
+	reparsedFd, err := descriptorProto(reparsed)
+	if err != nil {
+		t.Errorf("failed to produce descriptor for formatted %s: %v", filename, err)
+		return formatted, false
+	}
+	if diff := cmp.Diff(originalFd, reparsedFd, protocmp.Transform()); diff != "" {
+		t.Errorf("%s: descriptor changed after formatting (-original +formatted):\n%s", filename, diff)
+		ok = false
+	}
+	return formatted, ok
+}
+
+func descriptorProto(file *ast.FileNode) (proto.Message, error) {
+	res, err := parser.ResultFromAST(file, true, reporter.NewHandler(nil))
+	if err != nil {
+		return nil, err
+	}
+	return res.FileDescriptorProto(), nil
+}
+
+// stripPositions returns a copy of the given AST without tokens and file
+// info, which are positional and so are expected to change when formatting.
+func stripPositions(file *ast.FileNode) *ast.FileNode {
+	file = proto.Clone(file).(*ast.FileNode)
+	proto.ClearExtension(file, ast.E_FileInfo)
+	clearTokens(file.ProtoReflect())
+	return file
+}
+
+var tokenEnum = ast.Token(0).Descriptor().FullName()
+
+func clearTokens(msg protoreflect.Message) {
+	msg.Range(func(fd protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		switch {
+		case fd.Kind() == protoreflect.EnumKind && fd.Enum().FullName() == tokenEnum:
+			msg.Clear(fd)
+		case fd.IsList() && fd.Message() != nil:
+			list := val.List()
+			for i := 0; i < list.Len(); i++ {
+				clearTokens(list.Get(i).Message())
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				val.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					clearTokens(v.Message())
+					return true
+				})
+			}
+		case fd.Message() != nil:
+			clearTokens(val.Message())
+		}
+		return true
+	})
+}
+
+// Reprint is a Formatter that prints each token of the given file with its
+// comments and whitespace, which reproduces the original source exactly. It
+// is useful for verifying that the parser retains everything in a file, and
+// as a baseline for formatters that only rewrite the AST.
+func Reprint(_ string, file *ast.FileNode) ([]byte, error) {
+	var buf bytes.Buffer
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ast.IsTerminalNode(n) {
+			info := file.NodeInfo(n)
+			writeComments(&buf, info.LeadingComments())
+			buf.WriteString(info.LeadingWhitespace())
+			buf.WriteString(info.RawText())
+			writeComments(&buf, info.TrailingComments())
+		}
+		return true
+	})
+	return buf.Bytes(), nil
+}
+
+func writeComments(buf *bytes.Buffer, comments ast.Comments) {
+	for i := 0; i < comments.Len(); i++ {
+		comment := comments.Index(i)
+		if comment.IsVirtual() {
+			continue
+		}
+		buf.WriteString(comment.LeadingWhitespace())
+		buf.WriteString(comment.RawText())
+	}
+}
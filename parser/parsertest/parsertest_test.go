@@ -0,0 +1,72 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsertest_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser/parsertest"
+)
+
+func TestCheckDir(t *testing.T) {
+	t.Parallel()
+	parsertest.CheckDir(t, "testdata")
+}
+
+func TestCheckSource(t *testing.T) {
+	t.Parallel()
+	source := []byte(`syntax = "proto3";
+message Foo {
+	string name = 1;
+	Foo child = 2;
+}
+`)
+	collapse := func(filename string, file *ast.FileNode) ([]byte, error) {
+		data, err := parsertest.Reprint(filename, file)
+		return []byte(strings.Join(strings.Fields(string(data)), " ")), err
+	}
+	rec := &recorder{TB: t}
+	parsertest.CheckSource(rec, "test.proto", source, parsertest.WithFormatter(collapse))
+	assert.Empty(t, rec.errors)
+
+	rename := func(filename string, file *ast.FileNode) ([]byte, error) {
+		data, err := parsertest.Reprint(filename, file)
+		return bytes.ReplaceAll(data, []byte("name"), []byte("title")), err
+	}
+	rec = &recorder{TB: t}
+	parsertest.CheckSource(rec, "test.proto", source, parsertest.WithFormatter(rename))
+	require.Len(t, rec.errors, 2)
+	assert.Contains(t, rec.errors[0], "test.proto: AST changed after formatting")
+	assert.Contains(t, rec.errors[0], `"title"`)
+	assert.Contains(t, rec.errors[1], "test.proto: descriptor changed after formatting")
+}
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
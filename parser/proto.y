@@ -81,14 +81,14 @@ import (
 %type <fileElements> fileElements
 %type <imprt>        importDecl
 %type <pkg>          packageDecl
-%type <opt>          optionDecl compactOption
+%type <opt>          optionDecl compactOption standaloneOption
 %type <opts>         compactOptionDecls
 %type <ref>          messageLiteralFieldName
 %type <optName>      optionName
 %type <cmpctOpts>    compactOptions
 %type <v>            fieldValue optionValue compactOptionValue scalarValue fieldScalarValue messageLiteral numLit specialFloatLit listLiteral listElement listOfMessagesLiteral messageValue
 %type <il>           enumValueNumber
-%type <id>           singularIdent identKeywordName mapKeyType fieldCardinality msgElementKeywordName oneofElementKeywordName notGroupElementKeywordName mtdElementKeywordName enumValueName enumValueKeywordName
+%type <id>           symbolVisibility singularIdent identKeywordName mapKeyType fieldCardinality msgElementKeywordName oneofElementKeywordName notGroupElementKeywordName mtdElementKeywordName enumValueName enumValueKeywordName
 %type <idv>          anyIdentifier msgElementTypeIdent oneofElementTypeIdent notGroupElementTypeIdent mtdElementTypeIdent
 %type <sl>           listElements messageLiterals
 %type <msgLitFlds>   messageLiteralFieldEntry messageLiteralFields messageTextFormat
@@ -132,10 +132,13 @@ import (
 %token <id>      _SYNTAX _EDITION _IMPORT _WEAK _PUBLIC _PACKAGE _OPTION _TRUE _FALSE _INF _NAN _REPEATED _OPTIONAL _REQUIRED
 %token <id>      _DOUBLE _FLOAT _INT32 _INT64 _UINT32 _UINT64 _SINT32 _SINT64 _FIXED32 _FIXED64 _SFIXED32 _SFIXED64
 %token <id>      _BOOL _STRING _BYTES _GROUP _ONEOF _MAP _EXTENSIONS _TO _MAX _RESERVED _ENUM _MESSAGE _EXTEND
-%token <id>      _SERVICE _RPC _STREAM _RETURNS
+%token <id>      _SERVICE _RPC _STREAM _RETURNS _EXPORT _LOCAL
 %token <id>      _SINGULAR_IDENT
 %token <idv>     _QUALIFIED_IDENT _FULLY_QUALIFIED_IDENT
 %token <err>     _ERROR
+// pseudo-tokens returned first by the lexer to select what to parse, when
+// parsing a standalone option or value instead of a file
+%token           _START_OPTION _START_VALUE _START_TEXT_FORMAT
 // we define all of these, even ones that aren't used, to improve error messages
 // so it shows the unexpected symbol instead of showing "$unk"
 %token <b>   '=' ';' ':' '{' '}' '\\' '/' '?' ',' '>' '<' '+' '-' '(' ')' '[' ']' '*' '&' '^' '%' '$' '#' '@' '!' '~' '`'
@@ -177,6 +180,21 @@ file
 		$$ = ast.NewFileNode(lex.info, nil, nil, lex.eof)
 		lex.res = $$
 	}
+	| _START_OPTION standaloneOption {
+		protolex.(*protoLex).exprRes = $2
+	}
+	| _START_VALUE optionValue {
+		protolex.(*protoLex).exprRes = $2
+	}
+	| _START_VALUE optionValue ';' {
+		protolex.(*protoLex).exprRes = $2
+	}
+	| _START_TEXT_FORMAT messageTextFormat {
+		protolex.(*protoLex).exprRes = &ast.MessageLiteralNode{Elements: $2}
+	}
+	| _START_TEXT_FORMAT {
+		protolex.(*protoLex).exprRes = &ast.MessageLiteralNode{}
+	}
 
 fileElements
 	: fileElements fileElement {
@@ -290,6 +308,26 @@ optionDecl
 		$$ = &ast.OptionNode{Keyword: $1.ToKeyword()}
 	}
 
+standaloneOption
+	: optionName '=' optionValue {
+		$$ = &ast.OptionNode{Name: $1, Equals: $2, Val: $3}
+	}
+	| optionName '=' optionValue ';' {
+		$$ = &ast.OptionNode{Name: $1, Equals: $2, Val: $3, Semicolon: $4}
+	}
+	| optionName '=' {
+		protolex.(*protoLex).ErrExtendedSyntax("expected value", CategoryIncompleteDecl)
+		$$ = &ast.OptionNode{Name: $1, Equals: $2}
+	}
+	| optionName {
+		protolex.(*protoLex).ErrExtendedSyntax("expected '='", CategoryIncompleteDecl)
+		$$ = &ast.OptionNode{Name: $1}
+	}
+	| {
+		protolex.(*protoLex).ErrExtendedSyntax("expected option name", CategoryIncompleteDecl)
+		$$ = &ast.OptionNode{}
+	}
+
 optionName
 	: anyIdentifier {
 		$$ = ast.OptionNameNodeFromIdentValue($1)
@@ -834,6 +872,9 @@ enumDecl
 	: _ENUM singularIdent '{' enumBody '}' {
 		$$ = &ast.EnumNode{Keyword: $1.ToKeyword(), Name: $2, OpenBrace: $3, Decls: $4, CloseBrace: $5}
 	}
+	| symbolVisibility _ENUM singularIdent '{' enumBody '}' {
+		$$ = &ast.EnumNode{Visibility: $1.ToKeyword(), Keyword: $2.ToKeyword(), Name: $3, OpenBrace: $4, Decls: $5, CloseBrace: $6}
+	}
 
 enumBody
 	: {
@@ -886,6 +927,15 @@ messageDecl
 	: _MESSAGE singularIdent '{' messageBody '}' {
 		$$ = &ast.MessageNode{Keyword: $1.ToKeyword(), Name: $2, OpenBrace: $3, Decls: $4, CloseBrace: $5}
 	}
+	| symbolVisibility _MESSAGE singularIdent '{' messageBody '}' {
+		$$ = &ast.MessageNode{Visibility: $1.ToKeyword(), Keyword: $2.ToKeyword(), Name: $3, OpenBrace: $4, Decls: $5, CloseBrace: $6}
+	}
+
+// the lexer only produces these tokens when they are followed by a message
+// or enum declaration, so they can still be used as identifiers elsewhere
+symbolVisibility
+	: _EXPORT
+	| _LOCAL
 
 messageBody
 	: {
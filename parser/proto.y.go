@@ -118,10 +118,15 @@ const _SERVICE = 57389
 const _RPC = 57390
 const _STREAM = 57391
 const _RETURNS = 57392
-const _SINGULAR_IDENT = 57393
-const _QUALIFIED_IDENT = 57394
-const _FULLY_QUALIFIED_IDENT = 57395
-const _ERROR = 57396
+const _EXPORT = 57393
+const _LOCAL = 57394
+const _SINGULAR_IDENT = 57395
+const _QUALIFIED_IDENT = 57396
+const _FULLY_QUALIFIED_IDENT = 57397
+const _ERROR = 57398
+const _START_OPTION = 57399
+const _START_VALUE = 57400
+const _START_TEXT_FORMAT = 57401
 
 var protoToknames = [...]string{
 	"$end",
@@ -174,10 +179,15 @@ var protoToknames = [...]string{
 	"_RPC",
 	"_STREAM",
 	"_RETURNS",
+	"_EXPORT",
+	"_LOCAL",
 	"_SINGULAR_IDENT",
 	"_QUALIFIED_IDENT",
 	"_FULLY_QUALIFIED_IDENT",
 	"_ERROR",
+	"_START_OPTION",
+	"_START_VALUE",
+	"_START_TEXT_FORMAT",
 	"'='",
 	"';'",
 	"':'",
@@ -223,367 +233,385 @@ var protoExca = [...]int16{
 	-1, 4,
 	1, 3,
 	-2, 0,
-	-1, 24,
+	-1, 30,
 	1, 1,
 	-2, 0,
-	-1, 26,
+	-1, 32,
 	1, 2,
 	-2, 0,
-	-1, 98,
+	-1, 128,
 	1, 4,
 	-2, 0,
-	-1, 99,
+	-1, 129,
 	1, 5,
 	-2, 0,
-	-1, 106,
-	59, 178,
+	-1, 158,
+	64, 189,
 	-2, 0,
-	-1, 107,
-	59, 218,
+	-1, 159,
+	64, 232,
 	-2, 0,
-	-1, 108,
-	59, 232,
+	-1, 160,
+	64, 246,
 	-2, 0,
-	-1, 183,
-	59, 179,
-	-2, 0,
-	-1, 236,
-	59, 219,
+	-1, 245,
+	64, 189,
 	-2, 0,
 	-1, 248,
-	59, 233,
+	64, 190,
+	-2, 0,
+	-1, 301,
+	64, 233,
 	-2, 0,
-	-1, 387,
-	59, 126,
+	-1, 313,
+	64, 247,
 	-2, 0,
-	-1, 443,
-	59, 127,
+	-1, 458,
+	64, 136,
 	-2, 0,
-	-1, 610,
-	59, 244,
+	-1, 499,
+	64, 137,
 	-2, 0,
-	-1, 625,
-	59, 245,
+	-1, 639,
+	64, 258,
+	-2, 0,
+	-1, 650,
+	64, 259,
 	-2, 0,
 }
 
 const protoPrivate = 57344
 
-const protoLast = 1699
+const protoLast = 1781
 
 var protoAct = [...]int16{
-	33, 437, 10, 626, 110, 419, 12, 574, 558, 426,
-	11, 13, 602, 35, 36, 37, 135, 122, 93, 24,
-	26, 503, 444, 411, 351, 30, 31, 32, 350, 331,
-	559, 90, 260, 384, 560, 256, 249, 42, 42, 129,
-	237, 42, 142, 184, 124, 134, 133, 421, 330, 130,
-	41, 132, 125, 615, 96, 634, 7, 374, 120, 612,
-	597, 27, 593, 591, 590, 121, 566, 438, 92, 95,
-	378, 97, 438, 340, 564, 563, 433, 438, 611, 587,
-	412, 352, 352, 120, 609, 604, 258, 432, 373, 430,
-	121, 259, 603, 25, 430, 372, 589, 562, 496, 407,
-	346, 392, 428, 391, 385, 646, 643, 642, 131, 185,
-	113, 250, 126, 636, 245, 630, 127, 128, 244, 500,
-	576, 364, 354, 341, 269, 644, 131, 272, 273, 274,
-	126, 276, 631, 278, 127, 128, 610, 387, 108, 111,
-	107, 334, 271, 353, 353, 106, 275, 243, 277, 191,
-	279, 280, 115, 242, 240, 27, 27, 239, 120, 241,
-	238, 105, 34, 430, 263, 121, 16, 25, 270, 568,
-	368, 427, 5, 6, 17, 363, 362, 18, 19, 361,
-	16, 360, 359, 358, 607, 329, 185, 333, 17, 339,
-	335, 18, 19, 606, 605, 493, 435, 434, 383, 348,
-	104, 29, 343, 345, 347, 28, 380, 501, 21, 20,
-	22, 23, 440, 409, 386, 15, 253, 4, 266, 265,
-	266, 265, 21, 20, 22, 23, 191, 342, 19, 15,
-	552, 553, 267, 268, 575, 623, 628, 622, 339, 494,
-	357, 583, 98, 245, 99, 38, 103, 244, 19, 441,
-	570, 250, 39, 40, 89, 356, 379, 569, 332, 436,
-	410, 102, 254, 101, 100, 116, 344, 366, 367, 624,
-	625, 252, 251, 247, 248, 14, 243, 355, 235, 236,
-	186, 182, 242, 240, 183, 365, 239, 349, 241, 238,
-	371, 370, 187, 337, 336, 442, 443, 376, 123, 140,
-	447, 446, 369, 261, 255, 263, 257, 425, 554, 282,
-	449, 448, 137, 192, 189, 504, 283, 455, 456, 457,
-	458, 459, 460, 19, 461, 462, 463, 464, 451, 381,
-	382, 465, 466, 467, 468, 469, 470, 471, 472, 473,
-	474, 475, 476, 477, 478, 479, 450, 480, 481, 482,
-	483, 484, 485, 486, 487, 488, 489, 490, 491, 492,
-	452, 453, 454, 146, 136, 393, 375, 377, 417, 389,
-	390, 114, 619, 406, 109, 414, 413, 423, 424, 262,
-	572, 9, 388, 408, 8, 3, 2, 1, 0, 431,
-	445, 394, 395, 396, 397, 398, 399, 400, 401, 402,
-	403, 404, 405, 0, 0, 415, 0, 0, 497, 416,
-	0, 0, 0, 429, 0, 439, 0, 550, 551, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	565, 0, 555, 0, 499, 561, 498, 0, 571, 0,
-	495, 0, 0, 0, 0, 0, 445, 0, 580, 0,
-	0, 42, 0, 0, 0, 0, 0, 42, 0, 0,
-	0, 0, 578, 579, 567, 0, 577, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 581, 582, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 42, 0, 0, 0, 0,
-	0, 0, 0, 0, 586, 585, 0, 0, 584, 0,
+	112, 493, 185, 651, 86, 631, 96, 612, 87, 559,
+	198, 484, 38, 30, 32, 500, 114, 115, 116, 36,
+	424, 423, 109, 110, 111, 194, 13, 89, 38, 38,
+	13, 402, 329, 38, 314, 302, 176, 455, 249, 247,
+	401, 120, 205, 187, 330, 133, 126, 191, 16, 102,
+	192, 85, 16, 178, 197, 100, 13, 489, 13, 196,
+	486, 193, 195, 188, 143, 98, 448, 658, 189, 15,
+	626, 446, 99, 15, 445, 337, 335, 147, 16, 494,
+	16, 334, 494, 640, 183, 494, 625, 190, 14, 485,
+	425, 425, 14, 411, 638, 10, 98, 633, 31, 15,
+	33, 15, 134, 99, 632, 419, 182, 135, 180, 142,
+	552, 480, 463, 145, 98, 462, 141, 456, 14, 180,
+	14, 99, 180, 444, 333, 670, 667, 177, 556, 666,
+	660, 654, 614, 478, 477, 437, 427, 414, 340, 668,
+	655, 639, 458, 246, 164, 245, 173, 174, 132, 113,
+	160, 140, 166, 159, 13, 13, 179, 426, 426, 158,
+	155, 31, 441, 436, 435, 434, 433, 321, 322, 432,
+	431, 169, 339, 320, 131, 636, 16, 16, 162, 635,
+	336, 331, 161, 634, 250, 181, 315, 91, 165, 38,
+	343, 344, 345, 549, 347, 105, 349, 15, 15, 342,
+	169, 256, 338, 346, 405, 348, 184, 350, 351, 326,
+	308, 122, 332, 125, 307, 127, 14, 14, 491, 305,
+	490, 304, 306, 303, 33, 33, 454, 421, 310, 154,
+	341, 130, 108, 107, 149, 557, 318, 123, 124, 482,
+	496, 457, 4, 653, 613, 406, 117, 309, 22, 413,
+	35, 648, 647, 118, 119, 22, 621, 19, 608, 607,
+	550, 416, 418, 420, 403, 20, 492, 121, 21, 22,
+	483, 250, 153, 128, 250, 129, 152, 497, 151, 150,
+	105, 92, 319, 137, 136, 412, 148, 415, 256, 105,
+	417, 256, 649, 650, 317, 323, 324, 137, 136, 25,
+	23, 26, 27, 316, 312, 430, 28, 29, 18, 138,
+	139, 156, 157, 313, 429, 17, 300, 301, 251, 248,
+	422, 252, 408, 407, 498, 499, 439, 440, 186, 203,
+	503, 171, 447, 502, 103, 101, 175, 428, 450, 315,
+	325, 353, 449, 505, 200, 257, 254, 560, 438, 354,
+	507, 209, 308, 199, 464, 24, 307, 144, 146, 167,
+	171, 305, 443, 304, 306, 303, 90, 644, 163, 104,
+	310, 610, 34, 12, 11, 451, 465, 466, 467, 468,
+	469, 470, 471, 472, 473, 474, 475, 476, 400, 309,
+	404, 3, 410, 37, 42, 43, 44, 45, 46, 47,
+	48, 49, 50, 51, 52, 53, 54, 55, 56, 57,
+	58, 59, 60, 61, 62, 63, 64, 65, 66, 67,
+	68, 69, 70, 71, 72, 73, 74, 75, 76, 77,
+	78, 79, 80, 81, 82, 83, 84, 2, 1, 39,
+	40, 41, 479, 410, 460, 461, 0, 0, 0, 0,
+	488, 0, 0, 331, 0, 0, 0, 0, 0, 0,
+	481, 0, 629, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 169, 0, 0, 0, 0, 487, 0, 0,
+	0, 553, 0, 0, 501, 0, 0, 0, 495, 0,
+	606, 0, 0, 0, 609, 0, 0, 0, 0, 0,
+	0, 38, 554, 555, 618, 0, 0, 442, 0, 0,
+	0, 0, 616, 617, 0, 615, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 501, 0, 0, 0, 0,
+	0, 19, 0, 0, 0, 0, 0, 8, 9, 20,
+	452, 453, 21, 22, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 38,
+	0, 0, 0, 0, 0, 0, 0, 0, 624, 623,
+	0, 0, 622, 25, 23, 26, 27, 0, 0, 0,
+	28, 29, 18, 0, 0, 0, 5, 6, 7, 0,
+	0, 0, 0, 459, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 627,
+	628, 0, 0, 0, 0, 0, 0, 38, 630, 0,
+	0, 0, 0, 637, 0, 0, 0, 0, 642, 641,
+	0, 0, 171, 0, 0, 0, 643, 0, 0, 645,
+	0, 0, 0, 646, 0, 0, 0, 656, 0, 657,
+	659, 551, 0, 0, 661, 0, 0, 0, 663, 0,
+	665, 664, 0, 662, 0, 652, 0, 0, 0, 0,
+	0, 669, 0, 0, 0, 0, 652, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 592, 0, 0, 588, 0, 0, 595, 0, 0,
-	0, 598, 599, 0, 0, 0, 0, 596, 0, 0,
-	601, 0, 0, 0, 0, 608, 0, 0, 0, 0,
-	0, 42, 0, 0, 0, 614, 0, 594, 613, 617,
-	131, 0, 0, 0, 126, 0, 0, 0, 127, 128,
-	620, 113, 0, 627, 618, 616, 629, 0, 0, 0,
-	0, 0, 632, 0, 633, 635, 0, 0, 627, 637,
-	0, 0, 0, 0, 131, 0, 641, 131, 126, 0,
-	621, 126, 127, 128, 638, 127, 128, 131, 0, 639,
-	0, 126, 640, 115, 0, 127, 128, 557, 0, 418,
-	119, 117, 645, 46, 47, 48, 49, 50, 51, 52,
+	0, 0, 0, 619, 620, 328, 0, 168, 95, 93,
+	0, 42, 43, 44, 45, 46, 47, 48, 49, 50,
+	51, 52, 53, 54, 55, 56, 57, 58, 59, 60,
+	61, 62, 63, 64, 65, 66, 67, 68, 69, 70,
+	71, 72, 73, 74, 75, 76, 77, 78, 79, 80,
+	81, 82, 83, 84, 0, 0, 97, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 98, 0, 0, 0,
+	0, 180, 0, 99, 0, 170, 168, 95, 93, 327,
+	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
+	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	62, 63, 64, 65, 66, 67, 68, 69, 70, 71,
+	72, 73, 74, 75, 76, 77, 78, 79, 80, 81,
+	82, 83, 84, 0, 0, 97, 0, 0, 0, 0,
+	0, 0, 0, 113, 0, 98, 0, 0, 0, 0,
+	0, 0, 99, 0, 170, 0, 0, 172, 37, 42,
+	43, 44, 45, 46, 47, 48, 49, 50, 51, 52,
 	53, 54, 55, 56, 57, 58, 59, 60, 61, 62,
 	63, 64, 65, 66, 67, 68, 69, 70, 71, 72,
 	73, 74, 75, 76, 77, 78, 79, 80, 81, 82,
-	83, 84, 85, 86, 87, 88, 94, 0, 0, 0,
-	0, 0, 0, 120, 0, 0, 0, 0, 430, 0,
-	121, 0, 420, 418, 119, 117, 556, 46, 47, 48,
-	49, 50, 51, 52, 53, 54, 55, 56, 57, 58,
-	59, 60, 61, 62, 63, 64, 65, 66, 67, 68,
-	69, 70, 71, 72, 73, 74, 75, 76, 77, 78,
-	79, 80, 81, 82, 83, 84, 85, 86, 87, 88,
-	94, 0, 0, 0, 0, 34, 0, 120, 0, 0,
-	0, 0, 0, 0, 121, 0, 420, 0, 0, 422,
-	91, 46, 47, 48, 49, 50, 51, 52, 53, 54,
+	83, 84, 0, 0, 39, 40, 41, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 88, 95, 93, 611, 42, 43,
+	44, 45, 46, 47, 48, 49, 50, 51, 52, 53,
+	54, 55, 56, 57, 58, 59, 60, 61, 62, 63,
+	64, 65, 66, 67, 68, 69, 70, 71, 72, 73,
+	74, 75, 76, 77, 78, 79, 80, 81, 82, 83,
+	84, 0, 0, 97, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 98, 0, 0, 0, 0, 0, 0,
+	99, 0, 94, 168, 95, 93, 0, 42, 43, 44,
+	45, 46, 47, 48, 49, 50, 51, 52, 53, 54,
 	55, 56, 57, 58, 59, 60, 61, 62, 63, 64,
 	65, 66, 67, 68, 69, 70, 71, 72, 73, 74,
 	75, 76, 77, 78, 79, 80, 81, 82, 83, 84,
-	85, 86, 87, 88, 43, 44, 45, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 600, 91, 46, 47, 48, 49,
+	0, 0, 97, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 98, 0, 0, 0, 0, 0, 0, 99,
+	0, 170, 42, 43, 44, 45, 46, 47, 48, 49,
 	50, 51, 52, 53, 54, 55, 56, 57, 58, 59,
 	60, 61, 62, 63, 64, 65, 66, 67, 68, 69,
 	70, 71, 72, 73, 74, 75, 76, 77, 78, 79,
-	80, 81, 82, 83, 84, 85, 86, 87, 88, 43,
-	44, 45, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 112, 119, 117, 573,
-	46, 47, 48, 49, 50, 51, 52, 53, 54, 55,
-	56, 57, 58, 59, 60, 61, 62, 63, 64, 65,
-	66, 67, 68, 69, 70, 71, 72, 73, 74, 75,
-	76, 77, 78, 79, 80, 81, 82, 83, 84, 85,
-	86, 87, 88, 94, 0, 0, 0, 0, 0, 0,
-	120, 0, 0, 0, 0, 0, 0, 121, 0, 118,
-	418, 119, 117, 0, 46, 47, 48, 49, 50, 51,
-	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
-	62, 63, 64, 65, 66, 67, 68, 69, 70, 71,
-	72, 73, 74, 75, 76, 77, 78, 79, 80, 81,
-	82, 83, 84, 85, 86, 87, 88, 94, 0, 0,
-	0, 0, 0, 0, 120, 0, 0, 0, 0, 0,
-	0, 121, 0, 420, 46, 47, 48, 49, 50, 51,
+	80, 81, 82, 83, 84, 0, 0, 97, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 134, 0,
+	0, 0, 0, 135, 0, 0, 0, 0, 0, 106,
+	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
 	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
 	62, 63, 64, 65, 66, 67, 68, 69, 70, 71,
 	72, 73, 74, 75, 76, 77, 78, 79, 80, 81,
-	82, 83, 84, 85, 86, 87, 88, 94, 0, 0,
-	0, 0, 0, 0, 0, 258, 0, 0, 0, 0,
-	259, 0, 0, 0, 0, 0, 264, 46, 47, 48,
-	49, 50, 51, 52, 53, 54, 55, 56, 57, 58,
-	59, 60, 61, 62, 63, 64, 65, 66, 67, 68,
-	69, 70, 71, 72, 73, 74, 75, 76, 77, 78,
-	79, 80, 81, 82, 83, 84, 85, 86, 87, 88,
-	94, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 338, 352, 0, 264,
-	46, 47, 48, 49, 50, 51, 52, 53, 54, 55,
-	56, 57, 58, 59, 60, 61, 62, 63, 64, 65,
-	66, 67, 68, 69, 70, 71, 72, 73, 74, 75,
-	76, 77, 78, 79, 80, 81, 82, 83, 84, 85,
-	86, 87, 88, 94, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 353,
-	151, 152, 153, 154, 155, 156, 19, 157, 158, 159,
-	160, 145, 144, 143, 161, 162, 163, 164, 165, 166,
-	167, 168, 169, 170, 171, 172, 173, 174, 175, 0,
-	139, 150, 138, 176, 177, 141, 21, 20, 22, 178,
-	179, 180, 181, 147, 148, 149, 0, 0, 25, 91,
+	82, 83, 84, 0, 0, 97, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 409, 425, 0, 106, 42, 43,
+	44, 45, 46, 47, 48, 49, 50, 51, 52, 53,
+	54, 55, 56, 57, 58, 59, 60, 61, 62, 63,
+	64, 65, 66, 67, 68, 69, 70, 71, 72, 73,
+	74, 75, 76, 77, 78, 79, 80, 81, 82, 83,
+	84, 0, 0, 97, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 426, 214, 215, 216, 217, 218, 219, 22,
+	220, 221, 222, 223, 208, 207, 206, 224, 225, 226,
+	227, 228, 229, 230, 231, 232, 233, 234, 235, 236,
+	237, 238, 0, 202, 213, 201, 239, 240, 204, 25,
+	23, 26, 241, 242, 243, 244, 28, 29, 210, 211,
+	212, 0, 0, 0, 0, 0, 31, 37, 42, 43,
+	44, 45, 46, 47, 48, 49, 50, 51, 52, 53,
+	54, 55, 56, 57, 58, 59, 60, 61, 62, 63,
+	64, 65, 66, 67, 68, 69, 70, 71, 72, 73,
+	74, 75, 76, 77, 78, 79, 80, 81, 82, 83,
+	84, 0, 0, 39, 40, 41, 42, 43, 44, 45,
 	46, 47, 48, 49, 50, 51, 52, 53, 54, 55,
 	56, 57, 58, 59, 60, 61, 62, 63, 64, 65,
 	66, 67, 68, 69, 70, 71, 72, 73, 74, 75,
-	76, 77, 78, 79, 80, 81, 82, 83, 84, 85,
-	86, 87, 88, 43, 44, 45, 46, 47, 48, 49,
-	50, 51, 52, 53, 54, 55, 56, 57, 58, 59,
-	60, 61, 62, 63, 64, 65, 66, 67, 68, 69,
-	70, 71, 72, 73, 74, 75, 76, 77, 78, 79,
-	80, 81, 82, 83, 84, 85, 86, 87, 88, 43,
-	44, 45, 508, 509, 510, 511, 512, 513, 514, 515,
-	516, 517, 518, 519, 520, 521, 522, 523, 524, 525,
-	526, 527, 528, 529, 530, 531, 532, 533, 534, 535,
-	536, 537, 538, 539, 540, 541, 542, 543, 544, 545,
-	546, 547, 548, 502, 549, 505, 506, 507, 287, 288,
-	289, 290, 291, 292, 293, 294, 295, 296, 297, 298,
-	299, 300, 301, 302, 303, 304, 305, 306, 307, 308,
-	309, 310, 311, 312, 313, 314, 315, 281, 316, 317,
-	318, 319, 320, 321, 322, 323, 324, 325, 326, 327,
-	328, 284, 285, 286, 508, 509, 510, 511, 512, 513,
-	514, 515, 516, 517, 518, 519, 520, 521, 522, 523,
-	524, 525, 526, 527, 528, 529, 530, 531, 532, 533,
-	534, 535, 536, 537, 538, 539, 540, 541, 542, 543,
-	544, 545, 546, 547, 548, 246, 549, 505, 506, 507,
-	0, 151, 152, 153, 154, 155, 156, 0, 157, 158,
-	159, 160, 145, 144, 143, 161, 162, 163, 164, 165,
-	166, 167, 168, 169, 170, 171, 172, 173, 174, 175,
-	0, 139, 150, 138, 176, 177, 141, 21, 20, 0,
-	178, 179, 180, 181, 147, 148, 149, 338, 332, 0,
-	0, 46, 47, 48, 49, 50, 51, 52, 53, 54,
-	55, 56, 57, 58, 59, 60, 61, 62, 63, 64,
-	65, 66, 67, 68, 69, 70, 71, 72, 73, 74,
-	75, 76, 77, 78, 79, 80, 81, 82, 83, 84,
-	85, 86, 87, 88, 94, 188, 0, 0, 0, 0,
-	0, 194, 195, 196, 197, 198, 199, 19, 200, 201,
-	202, 203, 204, 205, 206, 207, 208, 209, 210, 211,
-	212, 213, 214, 215, 216, 217, 218, 219, 220, 221,
-	222, 223, 224, 225, 226, 227, 190, 228, 229, 230,
-	231, 232, 233, 234, 193, 46, 47, 48, 49, 50,
-	51, 52, 53, 54, 55, 56, 57, 58, 59, 60,
-	61, 62, 63, 64, 65, 66, 67, 68, 69, 70,
-	71, 72, 73, 74, 75, 76, 77, 78, 79, 80,
-	81, 82, 83, 84, 85, 86, 87, 88, 94,
+	76, 77, 78, 79, 80, 81, 82, 83, 84, 0,
+	0, 39, 40, 41, 564, 565, 566, 567, 568, 569,
+	570, 571, 572, 573, 574, 575, 576, 577, 578, 579,
+	580, 581, 582, 583, 584, 585, 586, 587, 588, 589,
+	590, 591, 592, 593, 594, 595, 596, 597, 598, 599,
+	600, 601, 602, 603, 604, 558, 605, 311, 0, 561,
+	562, 563, 0, 214, 215, 216, 217, 218, 219, 0,
+	220, 221, 222, 223, 208, 207, 206, 224, 225, 226,
+	227, 228, 229, 230, 231, 232, 233, 234, 235, 236,
+	237, 238, 0, 202, 213, 201, 239, 240, 204, 25,
+	23, 0, 241, 242, 243, 244, 28, 29, 210, 211,
+	212, 358, 359, 360, 361, 362, 363, 364, 365, 366,
+	367, 368, 369, 370, 371, 372, 373, 374, 375, 376,
+	377, 378, 379, 380, 381, 382, 383, 384, 385, 386,
+	352, 387, 388, 389, 390, 391, 392, 393, 394, 395,
+	396, 397, 398, 399, 0, 0, 355, 356, 357, 564,
+	565, 566, 567, 568, 569, 570, 571, 572, 573, 574,
+	575, 576, 577, 578, 579, 580, 581, 582, 583, 584,
+	585, 586, 587, 588, 589, 590, 591, 592, 593, 594,
+	595, 596, 597, 598, 599, 600, 601, 602, 603, 604,
+	0, 605, 504, 0, 561, 562, 563, 0, 511, 512,
+	513, 514, 515, 516, 22, 517, 518, 519, 520, 0,
+	0, 0, 521, 522, 523, 524, 525, 526, 527, 528,
+	529, 530, 531, 532, 533, 534, 535, 506, 536, 537,
+	538, 539, 540, 541, 542, 543, 544, 545, 546, 547,
+	548, 0, 0, 508, 509, 510, 409, 403, 0, 0,
+	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
+	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	62, 63, 64, 65, 66, 67, 68, 69, 70, 71,
+	72, 73, 74, 75, 76, 77, 78, 79, 80, 81,
+	82, 83, 84, 253, 0, 97, 0, 0, 0, 259,
+	260, 261, 262, 263, 264, 22, 265, 266, 267, 268,
+	269, 270, 271, 272, 273, 274, 275, 276, 277, 278,
+	279, 280, 281, 282, 283, 284, 285, 286, 287, 288,
+	289, 290, 291, 292, 255, 293, 294, 295, 296, 297,
+	298, 299, 0, 0, 258, 42, 43, 44, 45, 46,
+	47, 48, 49, 50, 51, 52, 53, 54, 55, 56,
+	57, 58, 59, 60, 61, 62, 63, 64, 65, 66,
+	67, 68, 69, 70, 71, 72, 73, 74, 75, 76,
+	77, 78, 79, 80, 81, 82, 83, 84, 0, 0,
+	97,
 }
 
 var protoPact = [...]int16{
-	164, -1000, 111, 111, 178, 150, 146, -1000, 111, 111,
-	111, 106, 106, 106, 106, -1000, -1000, 241, 1318, 1272,
-	1647, 1647, 1318, 1647, 178, -1000, 178, -1000, 260, 259,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 257,
-	242, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	529, -1000, 100, 100, 255, 1290, 900, 1102, 173, 172,
+	-1000, 100, 100, 100, 88, 88, 88, 88, -1000, -1000,
+	242, 1338, 1290, 1727, 193, 1727, 1338, 1727, -1000, -1000,
+	255, -1000, 255, -1000, -1000, 171, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, 113, -1000, -1000, -1000, -1000,
+	-1000, -1000, 1034, -1000, 292, -1000, -1000, -1000, -1000, -1000,
+	-1000, 1102, -1000, 48, 2, -1000, 232, 275, 274, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 272, 268,
+	-1000, 169, 97, 1727, 1727, 96, 90, 87, 255, 255,
+	900, -1000, 38, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, 762, -1000, 88, 88, 51, 40, 8,
+	-1000, -1000, -1000, -1000, 900, 1235, 82, 80, 1681, 1435,
+	234, 112, -1000, -1000, -1000, -1000, 88, 88, -1000, -1000,
+	278, -1000, 693, -1000, -1000, 56, 5, -1000, 0, 88,
+	-1000, -1, 1338, 111, -1000, 74, 1235, -1000, 100, 88,
+	88, 88, 100, 88, 100, 88, 100, 100, -1000, 1483,
+	1727, 259, 1727, 88, 1632, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, 23, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 145,
-	-1000, -1000, 103, -1000, -1000, 87, 82, 80, 178, 178,
-	-1000, -1000, -1000, -1000, 912, 1222, 1603, 1503, 214, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, 1036, -1000, 215, -1000,
-	-1000, -1000, 65, 1222, -1000, 111, 106, 106, 106, 111,
-	106, 111, 106, 111, 111, -1000, 1410, 1647, 253, 1647,
-	106, 1553, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	8, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, 1681, 1235, 73, 1681, -1000,
+	100, 37, 100, -1000, 167, 1170, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, 64, 1603, -1000, 111, 37, 111, -1000, 144,
-	1162, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	72, 1435, -1000, 100, 88, 109, 108, 105, 104, 103,
+	102, -1000, 71, 234, -1000, 100, 100, 101, -1000, 1727,
+	-1000, -1000, -1000, -1000, -1000, 55, -2, -1000, -5, -1000,
+	-1000, 88, -10, 33, -1000, -1000, -1000, 88, 54, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, 63, 1503, -1000, 111, 106,
-	127, 126, 125, 123, 120, 119, -1000, 62, 214, -1000,
-	111, 111, 114, -1000, 1647, 27, -1000, 1099, -1000, -1000,
-	-1000, 32, 0, -1000, 204, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, 1727, 1727, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, 1647, 1647, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 143,
-	41, -1000, 173, 79, 1647, 41, 40, 38, -1000, -1000,
-	367, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 76, 36,
-	-1000, 172, -1000, 255, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 12,
-	-1000, -1000, -1000, -1000, 719, -1000, 106, 106, 100, 26,
-	5, 142, 141, 254, 7, 253, 207, 309, 140, -1000,
-	-1000, 235, 1647, 35, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, 7, 76, -1000, 77,
-	-1000, 157, 1364, -1000, -1000, -1000, 106, 106, -1000, -1000,
-	213, -1000, 655, -1000, -1000, 34, 4, -1000, 3, 106,
-	-1000, -5, 1318, 113, 252, 245, 7, -1000, 848, -1000,
-	-1000, -1000, 61, 309, -1000, 111, 111, 106, -1000, 1647,
-	1647, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	166, 49, -1000, 200, 79, 1727, 49, 47, 44, -1000,
+	-1000, 352, 70, 69, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, 85, 43, -1000, 198, -1000, 265, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, 16, -16, 969, -1000, -1000, -1000, -1000, 88,
+	-1000, -19, 160, 158, 261, 10, 259, 235, 1580, 133,
+	-1000, -1000, 256, 1727, 42, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 10,
+	85, -1000, 86, -1000, 185, 1386, -1000, -1000, -1000, 88,
+	254, 253, 10, -1000, 831, -1000, -1000, -1000, 68, 1580,
+	-1000, 100, 100, 88, -1000, 1727, 1727, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, 236, -1000, -1000, 1318, -1000, -1000, -1000,
-	-1000, 12, 1456, 10, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 251,
+	-1000, -1000, 1338, -1000, -1000, -1000, -1000, 16, 1531, 12,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
 	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, 33, -7, -1000, -8, -1000, -1000,
-	106, -9, 25, -1000, -1000, -1000, 106, 31, -1000, 2,
-	7, -1000, 783, -1000, 29, 139, -1000, -1000, -1000, -1000,
-	-1000, 138, 129, 7, 20, 78, 9, -1000, -12, 976,
-	-1000, -1000, -1000, -1000, 106, -1000, -18, 1222, 106, -1000,
-	-1000, 29, -1000, -1000, -1000, 912, 232, 230, -1000, -1000,
-	234, -1000, -1000, -1000, -1000, 106, 56, 74, -1000, -1000,
-	-1000, 106, 7, -3, 54, 234, -1000, 111, -1000, -1000,
-	-1000, 1222, -1000, -1000, 1222, 106, -1000, -1000, -1000, 48,
-	47, 67, -1000, -1000, 1222, 46, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, 7, 10, -1000,
+	386, -1000, 36, 123, -1000, -1000, -1000, -1000, -1000, 119,
+	115, 10, 25, 78, 9, -1000, 1235, 88, -1000, -1000,
+	36, -1000, -1000, -1000, 900, 247, 246, -1000, -1000, 241,
+	-1000, 67, 77, -1000, -1000, -1000, 88, 10, 4, 66,
+	241, -1000, 100, -1000, -1000, 1235, -1000, -1000, 1235, 88,
+	-1000, -1000, -1000, 65, 62, 76, -1000, -1000, 1235, 61,
+	-1000,
 }
 
 var protoPgo = [...]int16{
-	0, 387, 386, 385, 56, 217, 384, 381, 2, 7,
-	380, 379, 234, 1, 376, 374, 372, 4, 30, 34,
-	5, 371, 368, 8, 367, 366, 24, 47, 18, 365,
-	364, 363, 328, 316, 315, 314, 313, 31, 312, 310,
-	309, 21, 308, 307, 32, 306, 304, 303, 52, 301,
-	51, 49, 300, 46, 299, 10, 44, 298, 17, 22,
-	296, 295, 294, 293, 45, 292, 42, 29, 28, 48,
-	287, 39, 6, 43, 284, 281, 280, 11, 40, 279,
-	278, 275, 36, 274, 273, 272, 271, 3, 270, 269,
-	23, 266, 33, 9, 16, 0, 12, 265, 35,
+	0, 438, 437, 391, 95, 242, 374, 373, 25, 7,
+	372, 371, 369, 244, 1, 368, 51, 367, 4, 44,
+	8, 27, 366, 359, 32, 358, 357, 20, 355, 187,
+	6, 354, 353, 351, 350, 349, 347, 346, 345, 19,
+	344, 343, 341, 9, 340, 336, 49, 335, 55, 334,
+	63, 333, 62, 61, 330, 59, 329, 87, 43, 328,
+	2, 15, 325, 324, 323, 322, 54, 321, 42, 31,
+	21, 40, 320, 50, 68, 38, 319, 39, 318, 47,
+	35, 317, 316, 315, 34, 313, 304, 303, 294, 3,
+	293, 292, 11, 290, 37, 36, 10, 0, 5, 281,
+	45,
 }
 
 var protoR1 = [...]int8{
-	0, 1, 1, 1, 1, 1, 1, 5, 5, 4,
-	4, 4, 4, 4, 4, 4, 4, 4, 2, 3,
-	6, 6, 6, 6, 6, 6, 7, 7, 8, 8,
-	8, 8, 12, 12, 15, 15, 16, 16, 17, 17,
-	17, 17, 20, 20, 20, 20, 21, 21, 19, 19,
-	46, 45, 45, 44, 44, 44, 47, 47, 47, 27,
-	27, 37, 37, 37, 37, 11, 11, 11, 11, 14,
-	14, 14, 18, 18, 18, 18, 18, 25, 25, 22,
-	22, 22, 22, 42, 42, 23, 23, 24, 24, 24,
-	24, 43, 43, 38, 38, 38, 38, 39, 39, 39,
-	39, 40, 40, 40, 40, 41, 41, 41, 41, 41,
-	35, 35, 30, 30, 30, 13, 13, 10, 10, 9,
-	9, 9, 9, 51, 51, 50, 61, 61, 60, 60,
-	59, 59, 59, 59, 49, 49, 52, 52, 53, 53,
-	54, 29, 29, 29, 29, 29, 29, 29, 29, 29,
-	29, 29, 29, 71, 71, 69, 69, 67, 67, 67,
-	70, 70, 68, 68, 68, 26, 26, 64, 64, 65,
-	65, 66, 66, 62, 62, 63, 63, 72, 75, 75,
-	74, 74, 73, 73, 73, 73, 76, 76, 55, 58,
-	58, 57, 57, 56, 56, 56, 56, 56, 56, 56,
-	56, 56, 56, 56, 48, 48, 48, 48, 48, 48,
-	48, 48, 48, 48, 48, 77, 77, 77, 80, 80,
-	79, 79, 78, 78, 78, 78, 78, 78, 78, 78,
-	78, 81, 84, 84, 83, 83, 82, 82, 82, 82,
-	85, 86, 90, 90, 89, 89, 88, 88, 87, 87,
-	31, 31, 31, 31, 31, 31, 31, 31, 31, 31,
-	31, 31, 31, 31, 31, 31, 31, 31, 31, 31,
-	31, 31, 31, 31, 31, 31, 31, 31, 31, 31,
-	31, 31, 36, 36, 36, 36, 36, 36, 36, 36,
-	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
-	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
-	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
-	36, 36, 36, 32, 32, 32, 32, 32, 32, 32,
-	32, 32, 32, 32, 32, 32, 32, 32, 32, 32,
-	32, 32, 32, 32, 32, 32, 32, 32, 32, 32,
-	32, 32, 32, 32, 32, 32, 32, 32, 32, 32,
-	32, 33, 33, 33, 33, 33, 33, 33, 33, 33,
-	33, 33, 33, 33, 33, 33, 33, 33, 33, 33,
+	0, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 5, 5, 4, 4, 4, 4, 4, 4,
+	4, 4, 4, 2, 3, 6, 6, 6, 6, 6,
+	6, 7, 7, 8, 8, 8, 8, 10, 10, 10,
+	10, 10, 13, 13, 16, 16, 17, 17, 18, 18,
+	18, 18, 21, 21, 21, 21, 22, 22, 20, 20,
+	48, 47, 47, 46, 46, 46, 49, 49, 49, 29,
+	29, 39, 39, 39, 39, 12, 12, 12, 12, 15,
+	15, 15, 19, 19, 19, 19, 19, 26, 26, 23,
+	23, 23, 23, 44, 44, 24, 24, 25, 25, 25,
+	25, 45, 45, 40, 40, 40, 40, 41, 41, 41,
+	41, 42, 42, 42, 42, 43, 43, 43, 43, 43,
+	37, 37, 32, 32, 32, 14, 14, 11, 11, 9,
+	9, 9, 9, 53, 53, 52, 63, 63, 62, 62,
+	61, 61, 61, 61, 51, 51, 54, 54, 55, 55,
+	56, 31, 31, 31, 31, 31, 31, 31, 31, 31,
+	31, 31, 31, 73, 73, 71, 71, 69, 69, 69,
+	72, 72, 70, 70, 70, 27, 27, 66, 66, 67,
+	67, 68, 68, 64, 64, 65, 65, 74, 74, 77,
+	77, 76, 76, 75, 75, 75, 75, 78, 78, 57,
+	57, 28, 28, 60, 60, 59, 59, 58, 58, 58,
+	58, 58, 58, 58, 58, 58, 58, 58, 50, 50,
+	50, 50, 50, 50, 50, 50, 50, 50, 50, 79,
+	79, 79, 82, 82, 81, 81, 80, 80, 80, 80,
+	80, 80, 80, 80, 80, 83, 86, 86, 85, 85,
+	84, 84, 84, 84, 87, 88, 92, 92, 91, 91,
+	90, 90, 89, 89, 33, 33, 33, 33, 33, 33,
 	33, 33, 33, 33, 33, 33, 33, 33, 33, 33,
 	33, 33, 33, 33, 33, 33, 33, 33, 33, 33,
-	33, 33, 33, 34, 34, 34, 34, 34, 34, 34,
+	33, 33, 33, 33, 33, 33, 38, 38, 38, 38,
+	38, 38, 38, 38, 38, 38, 38, 38, 38, 38,
+	38, 38, 38, 38, 38, 38, 38, 38, 38, 38,
+	38, 38, 38, 38, 38, 38, 38, 38, 38, 38,
+	38, 38, 38, 38, 38, 38, 38, 34, 34, 34,
 	34, 34, 34, 34, 34, 34, 34, 34, 34, 34,
 	34, 34, 34, 34, 34, 34, 34, 34, 34, 34,
 	34, 34, 34, 34, 34, 34, 34, 34, 34, 34,
-	34, 34, 34, 34, 34, 28, 28, 28, 28, 28,
-	28, 28, 28, 28, 28, 28, 28, 28, 28, 28,
-	28, 28, 28, 28, 28, 28, 28, 28, 28, 28,
-	28, 28, 28, 28, 28, 28, 28, 28, 28, 28,
-	28, 28, 28, 28, 28, 28, 28, 28, 92, 92,
-	91, 91, 94, 95, 93, 96, 96, 97, 97, 98,
-	98,
+	34, 34, 34, 34, 34, 35, 35, 35, 35, 35,
+	35, 35, 35, 35, 35, 35, 35, 35, 35, 35,
+	35, 35, 35, 35, 35, 35, 35, 35, 35, 35,
+	35, 35, 35, 35, 35, 35, 35, 35, 35, 35,
+	35, 35, 35, 35, 35, 35, 35, 36, 36, 36,
+	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
+	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
+	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
+	36, 36, 36, 36, 36, 36, 36, 36, 36, 30,
+	30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+	30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+	30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+	30, 30, 30, 30, 30, 30, 30, 30, 30, 30,
+	30, 30, 94, 94, 93, 93, 96, 97, 95, 98,
+	98, 99, 99, 100, 100,
 }
 
 var protoR2 = [...]int8{
-	0, 2, 2, 1, 3, 3, 0, 2, 1, 2,
-	2, 2, 2, 2, 2, 2, 1, 1, 3, 3,
-	2, 3, 3, 1, 2, 2, 2, 1, 4, 3,
-	2, 1, 1, 1, 1, 1, 1, 2, 1, 1,
+	0, 2, 2, 1, 3, 3, 0, 2, 2, 3,
+	2, 1, 2, 1, 2, 2, 2, 2, 2, 2,
+	2, 1, 1, 3, 3, 2, 3, 3, 1, 2,
+	2, 2, 1, 4, 3, 2, 1, 3, 4, 2,
+	1, 0, 1, 1, 1, 1, 1, 2, 1, 1,
 	1, 1, 1, 2, 1, 2, 2, 2, 3, 2,
 	1, 1, 2, 1, 2, 2, 3, 3, 2, 1,
 	1, 1, 1, 1, 1, 1, 5, 7, 4, 1,
@@ -597,15 +625,15 @@ var protoR2 = [...]int8{
 	6, 1, 1, 1, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 3, 4, 1, 3, 1, 3, 3,
 	1, 3, 1, 3, 3, 1, 2, 3, 1, 3,
-	1, 3, 2, 1, 3, 1, 3, 5, 0, 1,
-	2, 1, 2, 2, 2, 1, 3, 4, 5, 0,
-	1, 2, 1, 2, 2, 2, 2, 2, 2, 2,
-	2, 2, 2, 1, 5, 6, 4, 5, 4, 3,
-	2, 3, 2, 1, 1, 5, 2, 1, 0, 1,
-	2, 1, 2, 2, 2, 2, 2, 2, 2, 2,
-	1, 5, 0, 1, 2, 1, 2, 2, 2, 1,
-	5, 8, 4, 3, 0, 1, 2, 1, 2, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 3, 2, 1, 3, 1, 3, 5, 6, 0,
+	1, 2, 1, 2, 2, 2, 1, 3, 4, 5,
+	6, 1, 1, 0, 1, 2, 1, 2, 2, 2,
+	2, 2, 2, 2, 2, 2, 2, 1, 5, 6,
+	4, 5, 4, 3, 2, 3, 2, 1, 1, 5,
+	2, 1, 0, 1, 2, 1, 2, 2, 2, 2,
+	2, 2, 2, 2, 1, 5, 0, 1, 2, 1,
+	2, 2, 2, 1, 5, 8, 4, 3, 0, 1,
+	2, 1, 2, 1, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
@@ -628,161 +656,167 @@ var protoR2 = [...]int8{
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 0,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1,
+	1, 1, 1, 0, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1,
 }
 
 var protoChk = [...]int16{
-	-1000, -1, -2, -3, -5, 8, 9, -4, -6, -7,
-	-8, -55, -72, -77, -81, 51, 2, 10, 13, 14,
-	45, 44, 46, 47, -94, 56, -94, -4, 55, 55,
-	-94, -94, -94, -95, 56, -95, -95, -95, 4, 11,
-	12, -37, -28, 51, 52, 53, 8, 9, 10, 11,
-	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
-	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
-	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
-	42, 43, 44, 45, 46, 47, 48, 49, 50, -12,
-	-37, 7, -27, -28, 51, -27, -37, -27, -5, -5,
-	4, 4, 4, 4, 55, 58, 58, 58, 58, -15,
-	-17, -19, 4, -20, -21, -27, -97, 6, 67, 5,
-	58, 65, -58, -57, -56, -48, -72, -55, -77, -71,
-	-51, -8, -50, -53, -64, -94, -30, -38, 40, 38,
-	-54, 43, -66, 21, 20, 19, -31, 51, 52, 53,
-	39, 8, 9, 10, 11, 12, 13, 15, 16, 17,
-	18, 22, 23, 24, 25, 26, 27, 28, 29, 30,
-	31, 32, 33, 34, 35, 36, 41, 42, 47, 48,
-	49, 50, -75, -74, -73, -8, -76, -65, 2, -35,
-	43, -66, -36, 51, 8, 9, 10, 11, 12, 13,
-	15, 16, 17, 18, 19, 20, 21, 22, 23, 24,
-	25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
-	35, 36, 37, 38, 39, 40, 41, 42, 44, 45,
-	46, 47, 48, 49, 50, -80, -79, -78, -48, -51,
-	-53, -50, -64, -71, -55, -72, 2, -84, -83, -82,
-	-8, -85, -86, 2, 48, -46, -98, -45, 59, 64,
-	-44, -47, -11, -27, 70, 6, 5, 17, 18, 59,
-	-56, -94, -95, -95, -95, -94, -95, -94, -95, -94,
-	-94, 37, -40, -33, 51, 52, 53, 8, 9, 10,
-	11, 12, 13, 14, 15, 16, 17, 18, 19, 20,
-	21, 22, 23, 24, 25, 26, 27, 28, 29, 30,
-	31, 32, 33, 34, 35, 36, 38, 39, 40, 41,
-	42, 43, 44, 45, 46, 47, 48, 49, 50, -27,
-	-69, -67, 5, -27, -95, -69, -62, -63, 4, -27,
-	65, 59, -73, -94, -91, -94, 63, -94, 55, -70,
-	-68, -26, 5, 67, 59, -78, -94, -95, 56, 56,
-	56, 56, 56, 56, 59, -82, -94, -94, 56, -27,
-	-98, -44, 63, 56, 57, -25, -19, -24, 70, 52,
-	2, -27, -27, 55, -92, 63, 41, 58, -27, -92,
-	-92, 63, 63, -29, 24, 25, 26, 27, 28, 29,
-	30, 31, 32, 33, 34, 35, -26, 63, -92, 41,
-	5, -90, 68, -14, -95, -18, -19, -22, 4, -20,
-	67, -27, 70, -95, -95, -43, -93, 71, 2, -19,
-	63, -93, 61, 71, 55, 55, 5, -13, 70, -67,
-	5, 42, -61, -60, -59, -8, -49, -52, 2, -39,
-	37, -32, 51, 52, 53, 8, 9, 10, 11, 12,
-	13, 15, 16, 17, 18, 22, 23, 24, 25, 26,
-	27, 28, 29, 30, 31, 32, 33, 34, 35, 36,
-	38, 39, 40, 41, 42, 43, 44, 45, 46, 47,
-	48, 49, 50, 55, 4, -27, 63, -13, -68, -26,
-	42, 50, 49, -41, -34, 51, 52, 53, 8, 9,
-	10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+	-1000, -1, -2, -3, -5, 57, 58, 59, 8, 9,
+	-4, -6, -7, -8, -57, -74, -79, -83, 53, 2,
+	10, 13, 14, 45, -28, 44, 46, 47, 51, 52,
+	-96, 61, -96, -4, -10, -13, -39, 7, -30, 53,
+	54, 55, 8, 9, 10, 11, 12, 13, 14, 15,
+	16, 17, 18, 19, 20, 21, 22, 23, 24, 25,
+	26, 27, 28, 29, 30, 31, 32, 33, 34, 35,
+	36, 37, 38, 39, 40, 41, 42, 43, 44, 45,
+	46, 47, 48, 49, 50, -16, -18, -20, 4, -21,
+	-22, -29, -99, 6, 72, 5, -30, 53, 63, 70,
+	-48, -47, -46, -49, -12, -29, 75, 60, 60, -96,
+	-96, -96, -97, 61, -97, -97, -97, 4, 11, 12,
+	-39, -13, -29, 44, 45, -29, -39, -29, -5, -5,
+	60, 61, -48, -100, 64, 69, 6, 5, 17, 18,
+	-46, 68, 61, 62, -26, -20, -25, 75, 54, 2,
+	4, 4, 4, 4, 60, 63, -29, -29, 63, 63,
+	63, -16, -100, -15, -97, -19, -20, -23, 4, -21,
+	72, -29, 75, -97, -97, -45, -95, 76, 2, -20,
+	68, -95, 66, 76, -16, -60, -59, -58, -50, -74,
+	-57, -79, -73, -53, -8, -52, -55, -66, -96, -32,
+	-40, 40, 38, -56, 43, -68, 21, 20, 19, -33,
+	53, 54, 55, 39, 8, 9, 10, 11, 12, 13,
+	15, 16, 17, 18, 22, 23, 24, 25, 26, 27,
+	28, 29, 30, 31, 32, 33, 34, 35, 36, 41,
+	42, 47, 48, 49, 50, 63, 63, -77, -76, -75,
+	-8, -78, -67, 2, -37, 43, -68, -38, 53, 8,
+	9, 10, 11, 12, 13, 15, 16, 17, 18, 19,
 	20, 21, 22, 23, 24, 25, 26, 27, 28, 29,
 	30, 31, 32, 33, 34, 35, 36, 37, 38, 39,
-	40, 41, 42, 43, 44, 45, 46, 47, 48, 50,
-	-95, -95, 17, 18, -42, -93, 71, 2, -23, -18,
-	-19, -93, 63, 71, 71, -95, 71, -37, 56, 5,
-	5, -13, -10, 71, -9, -12, 59, -59, -94, -94,
-	-95, -27, -27, 5, -37, -90, -41, 69, -93, 63,
-	71, 71, -95, 71, -19, -95, -93, 58, -13, -13,
-	71, -9, -96, 63, 56, 55, 55, 55, -13, 64,
-	58, 69, 71, -23, -95, 71, -58, -95, -96, -16,
-	-17, -19, 5, 5, -89, -88, -87, -8, 2, -95,
-	59, 58, -95, -13, 58, -13, 59, -87, -94, -58,
-	-58, -95, 59, 59, 58, -58, 59,
+	40, 41, 42, 44, 45, 46, 47, 48, 49, 50,
+	-82, -81, -80, -50, -53, -55, -52, -66, -73, -57,
+	-74, 2, -86, -85, -84, -8, -87, -88, 2, 48,
+	61, -97, -97, 17, 18, -44, -95, 76, 2, -24,
+	-19, -20, -95, 68, 76, 76, -97, 76, -39, 61,
+	64, -58, -96, -97, -97, -97, -96, -97, -96, -97,
+	-96, -96, 37, -42, -35, 53, 54, 55, 8, 9,
+	10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+	20, 21, 22, 23, 24, 25, 26, 27, 28, 29,
+	30, 31, 32, 33, 34, 35, 36, 38, 39, 40,
+	41, 42, 43, 44, 45, 46, 47, 48, 49, 50,
+	-29, -71, -69, 5, -29, -97, -71, -64, -65, 4,
+	-29, 70, -77, -60, 64, -75, -96, -93, -96, 68,
+	-96, 60, -72, -70, -27, 5, 72, 64, -80, -96,
+	-97, 61, 61, 61, 61, 61, 61, 64, -84, -96,
+	-96, 61, -29, -95, 68, 76, 76, -97, 76, -20,
+	-97, -95, -29, -29, 60, -94, 68, 41, 63, -29,
+	-94, -94, 68, 68, -31, 24, 25, 26, 27, 28,
+	29, 30, 31, 32, 33, 34, 35, 64, 64, -27,
+	68, -94, 41, 5, -92, 73, 76, -24, -97, 76,
+	60, 60, 5, -14, 75, -69, 5, 42, -63, -62,
+	-61, -8, -51, -54, 2, -41, 37, -34, 53, 54,
+	55, 8, 9, 10, 11, 12, 13, 15, 16, 17,
+	18, 22, 23, 24, 25, 26, 27, 28, 29, 30,
+	31, 32, 33, 34, 35, 36, 38, 39, 40, 41,
+	42, 43, 44, 45, 46, 47, 48, 49, 50, 60,
+	4, -29, 68, -14, -70, -27, 42, 50, 49, -43,
+	-36, 53, 54, 55, 8, 9, 10, 11, 12, 13,
+	14, 15, 16, 17, 18, 19, 20, 21, 22, 23,
+	24, 25, 26, 27, 28, 29, 30, 31, 32, 33,
+	34, 35, 36, 37, 38, 39, 40, 41, 42, 43,
+	44, 45, 46, 47, 48, 50, -97, 5, 5, -14,
+	-11, 76, -9, -13, 64, -61, -96, -96, -97, -29,
+	-29, 5, -39, -92, -43, 74, 63, -14, -14, 76,
+	-9, -98, 68, 61, 60, 60, 60, -14, 69, 63,
+	74, -60, -97, -98, -17, -18, -20, 5, 5, -91,
+	-90, -89, -8, 2, 64, 63, -97, -14, 63, -14,
+	64, -89, -96, -60, -60, -97, 64, 64, 63, -60,
+	64,
 }
 
 var protoDef = [...]int16{
-	-2, -2, 0, 0, -2, 0, 0, 8, 0, 0,
-	0, 0, 0, 0, 0, 16, 17, 23, 27, 31,
-	0, 0, 217, 0, -2, 492, -2, 7, 0, 0,
-	9, 10, 11, 12, 493, 13, 14, 15, 20, 24,
-	25, 26, 61, 62, 63, 64, 445, 446, 447, 448,
-	449, 450, 451, 452, 453, 454, 455, 456, 457, 458,
-	459, 460, 461, 462, 463, 464, 465, 466, 467, 468,
-	469, 470, 471, 472, 473, 474, 475, 476, 477, 478,
-	479, 480, 481, 482, 483, 484, 485, 486, 487, 30,
-	32, 33, 0, 59, 60, 0, 216, 0, -2, -2,
-	18, 19, 21, 22, 29, 189, -2, -2, -2, 28,
-	34, 35, 38, 39, 40, 41, 0, 42, 0, 44,
-	497, 498, 0, 190, 192, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 203, 214, 213, 0, 0,
-	0, 0, 168, 112, 113, 114, 93, 94, 95, 96,
-	275, 250, 251, 252, 253, 254, 255, 256, 257, 258,
-	259, 260, 261, 262, 263, 264, 265, 266, 267, 268,
-	269, 270, 271, 272, 273, 274, 276, 277, 278, 279,
-	280, 281, 0, -2, 181, 0, 0, 0, 185, 0,
-	0, 170, 110, 111, 282, 283, 284, 285, 286, 287,
-	288, 289, 290, 291, 292, 293, 294, 295, 296, 297,
-	298, 299, 300, 301, 302, 303, 304, 305, 306, 307,
-	308, 309, 310, 311, 312, 313, 314, 315, 316, 317,
-	318, 319, 320, 321, 322, 0, -2, 221, 0, 0,
-	0, 0, 0, 0, 0, 0, 230, 0, -2, 235,
-	0, 0, 0, 239, 0, 0, 49, 50, 499, 500,
-	51, 53, 0, 65, 0, 43, 45, 46, 47, 188,
-	191, 193, 194, 195, 196, 197, 198, 199, 200, 201,
-	202, 0, 210, 101, 102, 103, 104, 361, 362, 363,
-	364, 365, 366, 367, 368, 369, 370, 371, 372, 373,
-	374, 375, 376, 377, 378, 379, 380, 381, 382, 383,
-	384, 385, 386, 387, 388, 389, 390, 391, 392, 393,
-	394, 395, 396, 397, 398, 399, 400, 401, 402, 212,
-	489, 155, 157, 0, 0, 489, 489, 172, 173, 175,
-	0, 177, 180, 182, 183, 490, 491, 184, 0, 489,
-	160, 162, 165, 0, 215, 220, 222, 223, 224, 225,
-	226, 227, 228, 229, 231, 234, 236, 237, 238, 0,
-	48, 52, 54, 55, 0, 58, 0, 0, 0, 0,
-	0, 0, 209, 211, 153, 488, 0, -2, 0, 167,
-	171, 488, 0, 0, 141, 142, 143, 144, 145, 146,
-	147, 148, 149, 150, 151, 152, 186, 488, 169, 0,
-	166, 0, 109, 56, 57, 69, 0, 0, 72, 73,
-	0, 76, 0, 77, 78, 0, 0, 89, 0, 0,
-	494, 0, 0, 0, 0, 208, 206, 154, 122, 156,
-	158, 159, 0, -2, 129, 0, 0, 0, 133, 0,
-	0, 97, 98, 99, 100, 323, 324, 325, 326, 327,
-	328, 329, 330, 331, 332, 333, 334, 335, 336, 337,
-	338, 339, 340, 341, 342, 343, 344, 345, 346, 347,
-	348, 349, 350, 351, 352, 353, 354, 355, 356, 357,
-	358, 359, 360, 0, 174, 176, 0, 187, 161, 163,
-	164, 0, 109, 0, 105, 106, 107, 108, 403, 404,
-	405, 406, 407, 408, 409, 410, 411, 412, 413, 414,
-	415, 416, 417, 418, 419, 420, 421, 422, 423, 424,
-	425, 426, 427, 428, 429, 430, 431, 432, 433, 434,
-	435, 436, 437, 438, 439, 440, 441, 442, 443, 444,
-	70, 71, 74, 75, 0, 0, 81, 0, 83, 85,
-	0, 0, 494, 88, 90, 91, 0, 0, 68, 0,
-	204, 207, 122, 116, 0, 121, 125, 128, 130, 131,
-	132, 0, 0, 138, 0, 240, 0, 243, 0, 494,
-	80, 82, 86, 87, 0, 66, 0, 189, 0, 205,
-	115, 0, 117, 495, 496, 120, 0, 0, 139, 140,
-	-2, 242, 79, 84, 92, 0, 0, 0, 118, 119,
-	36, 0, 134, 0, 0, -2, 247, 0, 249, 67,
-	123, 189, 37, 135, 189, 0, 241, 246, 248, 0,
-	0, 0, 124, 136, 189, 0, 137,
+	-2, -2, 0, 0, -2, 41, 0, 11, 0, 0,
+	13, 0, 0, 0, 0, 0, 0, 0, 21, 22,
+	28, 32, 36, 0, 0, 0, 231, 0, 201, 202,
+	-2, 506, -2, 12, 7, 40, 42, 43, 71, 72,
+	73, 74, 459, 460, 461, 462, 463, 464, 465, 466,
+	467, 468, 469, 470, 471, 472, 473, 474, 475, 476,
+	477, 478, 479, 480, 481, 482, 483, 484, 485, 486,
+	487, 488, 489, 490, 491, 492, 493, 494, 495, 496,
+	497, 498, 499, 500, 501, 8, 44, 45, 48, 49,
+	50, 51, 0, 52, 0, 54, 69, 70, 511, 512,
+	10, 60, 61, 63, 0, 75, 0, 0, 0, 14,
+	15, 16, 17, 507, 18, 19, 20, 25, 29, 30,
+	31, 35, 0, 0, 0, 0, 230, 0, -2, -2,
+	39, 9, 0, 59, 513, 514, 53, 55, 56, 57,
+	62, 64, 65, 0, 68, 0, 0, 0, 0, 0,
+	23, 24, 26, 27, 34, 203, 0, 0, -2, -2,
+	-2, 37, 58, 66, 67, 79, 0, 0, 82, 83,
+	0, 86, 0, 87, 88, 0, 0, 99, 0, 0,
+	508, 0, 0, 0, 33, 0, 204, 206, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 217, 228,
+	227, 0, 0, 0, 0, 178, 122, 123, 124, 103,
+	104, 105, 106, 289, 264, 265, 266, 267, 268, 269,
+	270, 271, 272, 273, 274, 275, 276, 277, 278, 279,
+	280, 281, 282, 283, 284, 285, 286, 287, 288, 290,
+	291, 292, 293, 294, 295, -2, 203, 0, -2, 192,
+	0, 0, 0, 196, 0, 0, 180, 120, 121, 296,
+	297, 298, 299, 300, 301, 302, 303, 304, 305, 306,
+	307, 308, 309, 310, 311, 312, 313, 314, 315, 316,
+	317, 318, 319, 320, 321, 322, 323, 324, 325, 326,
+	327, 328, 329, 330, 331, 332, 333, 334, 335, 336,
+	0, -2, 235, 0, 0, 0, 0, 0, 0, 0,
+	0, 244, 0, -2, 249, 0, 0, 0, 253, 0,
+	38, 80, 81, 84, 85, 0, 0, 91, 0, 93,
+	95, 0, 0, 508, 98, 100, 101, 0, 0, 78,
+	199, 205, 207, 208, 209, 210, 211, 212, 213, 214,
+	215, 216, 0, 224, 111, 112, 113, 114, 375, 376,
+	377, 378, 379, 380, 381, 382, 383, 384, 385, 386,
+	387, 388, 389, 390, 391, 392, 393, 394, 395, 396,
+	397, 398, 399, 400, 401, 402, 403, 404, 405, 406,
+	407, 408, 409, 410, 411, 412, 413, 414, 415, 416,
+	226, 503, 165, 167, 0, 0, 503, 503, 182, 183,
+	185, 0, 0, 0, 187, 191, 193, 194, 504, 505,
+	195, 0, 503, 170, 172, 175, 0, 229, 234, 236,
+	237, 238, 239, 240, 241, 242, 243, 245, 248, 250,
+	251, 252, 0, 0, 508, 90, 92, 96, 97, 0,
+	76, 0, 0, 223, 225, 163, 502, 0, -2, 0,
+	177, 181, 502, 0, 0, 151, 152, 153, 154, 155,
+	156, 157, 158, 159, 160, 161, 162, 188, 200, 197,
+	502, 179, 0, 176, 0, 119, 89, 94, 102, 0,
+	0, 222, 220, 164, 132, 166, 168, 169, 0, -2,
+	139, 0, 0, 0, 143, 0, 0, 107, 108, 109,
+	110, 337, 338, 339, 340, 341, 342, 343, 344, 345,
+	346, 347, 348, 349, 350, 351, 352, 353, 354, 355,
+	356, 357, 358, 359, 360, 361, 362, 363, 364, 365,
+	366, 367, 368, 369, 370, 371, 372, 373, 374, 0,
+	184, 186, 0, 198, 171, 173, 174, 0, 119, 0,
+	115, 116, 117, 118, 417, 418, 419, 420, 421, 422,
+	423, 424, 425, 426, 427, 428, 429, 430, 431, 432,
+	433, 434, 435, 436, 437, 438, 439, 440, 441, 442,
+	443, 444, 445, 446, 447, 448, 449, 450, 451, 452,
+	453, 454, 455, 456, 457, 458, 77, 0, 218, 221,
+	132, 126, 0, 131, 135, 138, 140, 141, 142, 0,
+	0, 148, 0, 254, 0, 257, 203, 0, 219, 125,
+	0, 127, 509, 510, 130, 0, 0, 149, 150, -2,
+	256, 0, 0, 128, 129, 46, 0, 144, 0, 0,
+	-2, 261, 0, 263, 133, 203, 47, 145, 203, 0,
+	255, 260, 262, 0, 0, 0, 134, 146, 203, 0,
+	147,
 }
 
 var protoTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 79, 3, 77, 76, 75, 73, 3,
-	68, 69, 72, 66, 63, 67, 3, 61, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 57, 56,
-	65, 55, 64, 62, 78, 3, 3, 3, 3, 3,
+	3, 3, 3, 84, 3, 82, 81, 80, 78, 3,
+	73, 74, 77, 71, 68, 72, 3, 66, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 62, 61,
+	70, 60, 69, 67, 83, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 70, 60, 71, 74, 3, 81, 3, 3, 3,
+	3, 75, 65, 76, 79, 3, 86, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 58, 3, 59, 80,
+	3, 3, 3, 63, 3, 64, 85,
 }
 
 var protoTok2 = [...]int8{
@@ -791,7 +825,7 @@ var protoTok2 = [...]int8{
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
 	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
-	52, 53, 54,
+	52, 53, 54, 55, 56, 57, 58, 59,
 }
 
 var protoTok3 = [...]int8{
@@ -1180,6 +1214,31 @@ protodefault:
 			lex.res = protoVAL.file
 		}
 	case 7:
+		protoDollar = protoS[protopt-2 : protopt+1]
+		{
+			protolex.(*protoLex).exprRes = protoDollar[2].opt
+		}
+	case 8:
+		protoDollar = protoS[protopt-2 : protopt+1]
+		{
+			protolex.(*protoLex).exprRes = protoDollar[2].v
+		}
+	case 9:
+		protoDollar = protoS[protopt-3 : protopt+1]
+		{
+			protolex.(*protoLex).exprRes = protoDollar[2].v
+		}
+	case 10:
+		protoDollar = protoS[protopt-2 : protopt+1]
+		{
+			protolex.(*protoLex).exprRes = &ast.MessageLiteralNode{Elements: protoDollar[2].msgLitFlds}
+		}
+	case 11:
+		protoDollar = protoS[protopt-1 : protopt+1]
+		{
+			protolex.(*protoLex).exprRes = &ast.MessageLiteralNode{}
+		}
+	case 12:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].fileElement != nil {
@@ -1188,7 +1247,7 @@ protodefault:
 				protoVAL.fileElements = protoDollar[1].fileElements
 			}
 		}
-	case 8:
+	case 13:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].fileElement != nil {
@@ -1197,178 +1256,206 @@ protodefault:
 				protoVAL.fileElements = nil
 			}
 		}
-	case 9:
+	case 14:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].imprt.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].imprt.AsFileElement()
 		}
-	case 10:
+	case 15:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].pkg.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].pkg.AsFileElement()
 		}
-	case 11:
+	case 16:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].opt.AsFileElement()
 		}
-	case 12:
+	case 17:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].msg.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].msg.AsFileElement()
 		}
-	case 13:
+	case 18:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].en.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].en.AsFileElement()
 		}
-	case 14:
+	case 19:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].extend.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].extend.AsFileElement()
 		}
-	case 15:
+	case 20:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].svc.Semicolon = protoDollar[2].b
 			protoVAL.fileElement = protoDollar[1].svc.AsFileElement()
 		}
-	case 16:
+	case 21:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntaxAt("unexpected identifier", protoDollar[1].id, CategoryIncompleteDecl)
 			protoVAL.fileElement = (&ast.ErrorNode{Err: protoDollar[1].id}).AsFileElement()
 		}
-	case 17:
+	case 22:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.fileElement = nil
 		}
-	case 18:
+	case 23:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.syn = &ast.SyntaxNode{Keyword: protoDollar[1].id.ToKeyword(), Equals: protoDollar[2].b, Syntax: protoDollar[3].sv}
 		}
-	case 19:
+	case 24:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.ed = &ast.EditionNode{Keyword: protoDollar[1].id.ToKeyword(), Equals: protoDollar[2].b, Edition: protoDollar[3].sv}
 		}
-	case 20:
+	case 25:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.imprt = &ast.ImportNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].sv}
 		}
-	case 21:
+	case 26:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.imprt = &ast.ImportNode{Keyword: protoDollar[1].id.ToKeyword(), Weak: protoDollar[2].id.ToKeyword(), Name: protoDollar[3].sv}
 		}
-	case 22:
+	case 27:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.imprt = &ast.ImportNode{Keyword: protoDollar[1].id.ToKeyword(), Public: protoDollar[2].id.ToKeyword(), Name: protoDollar[3].sv}
 		}
-	case 23:
+	case 28:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expecting string literal or \"weak\" or \"public\"", CategoryIncompleteDecl)
 			protoVAL.imprt = &ast.ImportNode{Keyword: protoDollar[1].id.ToKeyword()}
 		}
-	case 24:
+	case 29:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expecting string literal", CategoryIncompleteDecl)
 			protoVAL.imprt = &ast.ImportNode{Keyword: protoDollar[1].id.ToKeyword(), Weak: protoDollar[2].id.ToKeyword()}
 		}
-	case 25:
+	case 30:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expecting string literal", CategoryIncompleteDecl)
 			protoVAL.imprt = &ast.ImportNode{Keyword: protoDollar[1].id.ToKeyword(), Public: protoDollar[2].id.ToKeyword()}
 		}
-	case 26:
+	case 31:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.pkg = &ast.PackageNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].idv}
 		}
-	case 27:
+	case 32:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected package name", CategoryIncompleteDecl)
 			protoVAL.pkg = &ast.PackageNode{Keyword: protoDollar[1].id.ToKeyword()}
 		}
-	case 28:
+	case 33:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoVAL.opt = &ast.OptionNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].optName, Equals: protoDollar[3].b, Val: protoDollar[4].v}
 		}
-	case 29:
+	case 34:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected value", CategoryIncompleteDecl)
 			protoVAL.opt = &ast.OptionNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].optName, Equals: protoDollar[3].b}
 		}
-	case 30:
+	case 35:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected '='", CategoryIncompleteDecl)
 			protoVAL.opt = &ast.OptionNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].optName}
 		}
-	case 31:
+	case 36:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected option name", CategoryIncompleteDecl)
 			protoVAL.opt = &ast.OptionNode{Keyword: protoDollar[1].id.ToKeyword()}
 		}
-	case 32:
+	case 37:
+		protoDollar = protoS[protopt-3 : protopt+1]
+		{
+			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName, Equals: protoDollar[2].b, Val: protoDollar[3].v}
+		}
+	case 38:
+		protoDollar = protoS[protopt-4 : protopt+1]
+		{
+			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName, Equals: protoDollar[2].b, Val: protoDollar[3].v, Semicolon: protoDollar[4].b}
+		}
+	case 39:
+		protoDollar = protoS[protopt-2 : protopt+1]
+		{
+			protolex.(*protoLex).ErrExtendedSyntax("expected value", CategoryIncompleteDecl)
+			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName, Equals: protoDollar[2].b}
+		}
+	case 40:
+		protoDollar = protoS[protopt-1 : protopt+1]
+		{
+			protolex.(*protoLex).ErrExtendedSyntax("expected '='", CategoryIncompleteDecl)
+			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName}
+		}
+	case 41:
+		protoDollar = protoS[protopt-0 : protopt+1]
+		{
+			protolex.(*protoLex).ErrExtendedSyntax("expected option name", CategoryIncompleteDecl)
+			protoVAL.opt = &ast.OptionNode{}
+		}
+	case 42:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.optName = ast.OptionNameNodeFromIdentValue(protoDollar[1].idv)
 		}
-	case 33:
+	case 43:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.optName = protoDollar[1].optName
 		}
-	case 37:
+	case 47:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetMessageLiteral().Semicolon = protoDollar[2].b
 			protoVAL.v = protoDollar[1].v
 		}
-	case 38:
+	case 48:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.v = protoDollar[1].sv.AsValueNode()
 		}
-	case 41:
+	case 51:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.v = protoDollar[1].id.AsValueNode()
 		}
-	case 42:
+	case 52:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.v = protoDollar[1].f.AsValueNode()
 		}
-	case 43:
+	case 53:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.v = (&ast.SignedFloatLiteralNode{Sign: protoDollar[1].b, Float: protoDollar[2].f.AsFloatValueNode()}).AsValueNode()
 		}
-	case 44:
+	case 54:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.v = protoDollar[1].i.AsValueNode()
 		}
-	case 45:
+	case 55:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].i.Val > math.MaxInt64+1 {
@@ -1378,56 +1465,56 @@ protodefault:
 				protoVAL.v = (&ast.NegativeIntLiteralNode{Minus: protoDollar[1].b, Uint: protoDollar[2].i}).AsValueNode()
 			}
 		}
-	case 46:
+	case 56:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			f := ast.NewSpecialFloatLiteralNode(protoDollar[2].id.ToKeyword())
 			protoVAL.v = (&ast.SignedFloatLiteralNode{Sign: protoDollar[1].b, Float: f.AsFloatValueNode()}).AsValueNode()
 		}
-	case 47:
+	case 57:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			f := ast.NewSpecialFloatLiteralNode(protoDollar[2].id.ToKeyword())
 			protoVAL.v = (&ast.SignedFloatLiteralNode{Sign: protoDollar[1].b, Float: f.AsFloatValueNode()}).AsValueNode()
 		}
-	case 48:
+	case 58:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.v = (&ast.MessageLiteralNode{Open: protoDollar[1].b, Elements: protoDollar[2].msgLitFlds, Close: protoDollar[3].b}).AsValueNode()
 		}
-	case 49:
+	case 59:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.v = (&ast.MessageLiteralNode{Open: protoDollar[1].b, Close: protoDollar[2].b}).AsValueNode()
 		}
-	case 51:
+	case 61:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.msgLitFlds = protoDollar[1].msgLitFlds
 		}
-	case 52:
+	case 62:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.msgLitFlds = append(protoDollar[1].msgLitFlds, protoDollar[2].msgLitFlds...)
 		}
-	case 53:
+	case 63:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.msgLitFlds = []*ast.MessageFieldNode{protoDollar[1].msgLitFld}
 		}
-	case 54:
+	case 64:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].msgLitFld.Semicolon = protoDollar[2].b
 			protoVAL.msgLitFlds = []*ast.MessageFieldNode{protoDollar[1].msgLitFld}
 		}
-	case 55:
+	case 65:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].msgLitFld.Semicolon = protoDollar[2].b
 			protoVAL.msgLitFlds = []*ast.MessageFieldNode{protoDollar[1].msgLitFld}
 		}
-	case 56:
+	case 66:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			if protoDollar[1].ref != nil && protoDollar[2].b != nil {
@@ -1436,14 +1523,14 @@ protodefault:
 				protoVAL.msgLitFld = nil
 			}
 		}
-	case 57:
+	case 67:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected value", CategoryIncompleteDecl)
 			n := &ast.MessageFieldNode{Name: protoDollar[1].ref, Sep: protoDollar[2].b, Semicolon: protoDollar[3].b}
 			protoVAL.msgLitFld = n
 		}
-	case 58:
+	case 68:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[1].ref != nil && protoDollar[2].v != nil {
@@ -1452,90 +1539,90 @@ protodefault:
 				protoVAL.msgLitFld = nil
 			}
 		}
-	case 59:
+	case 69:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.id = protoDollar[1].id
 		}
-	case 61:
+	case 71:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 62:
+	case 72:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 65:
+	case 75:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.ref = &ast.FieldReferenceNode{Name: protoDollar[1].id.AsIdentValueNode()}
 		}
-	case 66:
+	case 76:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.ref = &ast.FieldReferenceNode{Open: protoDollar[1].b, Name: protoDollar[2].idv, Comma: protoDollar[3].b, Close: protoDollar[4].b, Semicolon: protoDollar[5].b}
 		}
-	case 67:
+	case 77:
 		protoDollar = protoS[protopt-7 : protopt+1]
 		{
 			protoVAL.ref = &ast.FieldReferenceNode{Open: protoDollar[1].b, UrlPrefix: protoDollar[2].idv, Slash: protoDollar[3].b, Name: protoDollar[4].idv, Comma: protoDollar[5].b, Close: protoDollar[6].b, Semicolon: protoDollar[7].b}
 		}
-	case 68:
+	case 78:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoVAL.ref = nil
 		}
-	case 70:
+	case 80:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetMessageLiteral().Semicolon = protoDollar[2].b
 			protoVAL.v = protoDollar[1].v
 		}
-	case 71:
+	case 81:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetArrayLiteral().Semicolon = protoDollar[2].b
 			protoVAL.v = protoDollar[1].v
 		}
-	case 72:
+	case 82:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.v = protoDollar[1].sv.AsValueNode()
 		}
-	case 74:
+	case 84:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			kw := protoDollar[2].id.ToKeyword()
 			f := ast.NewSpecialFloatLiteralNode(kw)
 			protoVAL.v = (&ast.SignedFloatLiteralNode{Sign: protoDollar[1].b, Float: f.AsFloatValueNode()}).AsValueNode()
 		}
-	case 75:
+	case 85:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			kw := protoDollar[2].id.ToKeyword()
 			f := ast.NewSpecialFloatLiteralNode(kw)
 			protoVAL.v = (&ast.SignedFloatLiteralNode{Sign: protoDollar[1].b, Float: f.AsFloatValueNode()}).AsValueNode()
 		}
-	case 76:
+	case 86:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.v = protoDollar[1].id.AsValueNode()
 		}
-	case 77:
+	case 87:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetMessageLiteral().Semicolon = protoDollar[2].b
 			protoVAL.v = protoDollar[1].v
 		}
-	case 78:
+	case 88:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetArrayLiteral().Semicolon = protoDollar[2].b
 			protoVAL.v = protoDollar[1].v
 		}
-	case 79:
+	case 89:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1543,38 +1630,38 @@ protodefault:
 			}
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, Elements: protoDollar[2].sl, CloseBracket: protoDollar[4].b}).AsValueNode()
 		}
-	case 80:
+	case 90:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, Elements: []*ast.ArrayLiteralElement{protoDollar[2].b.AsArrayLiteralElement()}, CloseBracket: protoDollar[3].b}).AsValueNode()
 		}
-	case 81:
+	case 91:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, CloseBracket: protoDollar[2].b}).AsValueNode()
 		}
-	case 82:
+	case 92:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, CloseBracket: protoDollar[3].b}).AsValueNode()
 		}
-	case 83:
+	case 93:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.sl = []*ast.ArrayLiteralElement{protoDollar[1].v.AsArrayLiteralElement()}
 		}
-	case 84:
+	case 94:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.sl = append(protoDollar[1].sl, protoDollar[2].b.AsArrayLiteralElement(), protoDollar[3].v.AsArrayLiteralElement())
 		}
-	case 86:
+	case 96:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetMessageLiteral().Semicolon = protoDollar[2].b
 			protoVAL.v = protoDollar[1].v
 		}
-	case 87:
+	case 97:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1582,85 +1669,85 @@ protodefault:
 			}
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, Elements: protoDollar[2].sl, CloseBracket: protoDollar[4].b}).AsValueNode()
 		}
-	case 88:
+	case 98:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, Elements: []*ast.ArrayLiteralElement{protoDollar[2].b.AsArrayLiteralElement()}, CloseBracket: protoDollar[3].b}).AsValueNode()
 		}
-	case 89:
+	case 99:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, CloseBracket: protoDollar[2].b}).AsValueNode()
 		}
-	case 90:
+	case 100:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.v = (&ast.ArrayLiteralNode{OpenBracket: protoDollar[1].b, CloseBracket: protoDollar[3].b}).AsValueNode()
 		}
-	case 91:
+	case 101:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].v.GetMessageLiteral().Semicolon = protoDollar[2].b
 			protoVAL.sl = []*ast.ArrayLiteralElement{protoDollar[1].v.AsArrayLiteralElement()}
 		}
-	case 92:
+	case 102:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoDollar[3].v.GetMessageLiteral().Semicolon = protoDollar[4].b
 			protoVAL.sl = append(protoDollar[1].sl, protoDollar[2].b.AsArrayLiteralElement(), protoDollar[3].v.AsArrayLiteralElement())
 		}
-	case 93:
+	case 103:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 94:
+	case 104:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 97:
+	case 107:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 98:
+	case 108:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 101:
+	case 111:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 102:
+	case 112:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 105:
+	case 115:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 106:
+	case 116:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.idv = protoDollar[1].id.AsIdentValueNode()
 		}
-	case 109:
+	case 119:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected message type", CategoryIncompleteDecl)
 			protoVAL.idv = nil
 		}
-	case 111:
+	case 121:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.id = protoDollar[1].id
 		}
-	case 115:
+	case 125:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			if r := protoDollar[2].opts[len(protoDollar[2].opts)-1].Semicolon; r != nil && !r.Virtual {
@@ -1668,69 +1755,69 @@ protodefault:
 			}
 			protoVAL.cmpctOpts = &ast.CompactOptionsNode{OpenBracket: protoDollar[1].b, Options: protoDollar[2].opts, CloseBracket: protoDollar[3].b}
 		}
-	case 116:
+	case 126:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("compact options list cannot be empty", CategoryEmptyDecl)
 			protoVAL.cmpctOpts = &ast.CompactOptionsNode{OpenBracket: protoDollar[1].b, CloseBracket: protoDollar[2].b}
 		}
-	case 117:
+	case 127:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.opts = []*ast.OptionNode{protoDollar[1].opt}
 		}
-	case 118:
+	case 128:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoDollar[2].opt.Semicolon = protoDollar[3].b
 			protoVAL.opts = append(protoDollar[1].opts, protoDollar[2].opt)
 		}
-	case 119:
+	case 129:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName, Equals: protoDollar[2].b, Val: protoDollar[3].v}
 		}
-	case 120:
+	case 130:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected value", CategoryIncompleteDecl)
 			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName, Equals: protoDollar[2].b}
 		}
-	case 121:
+	case 131:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected '='", CategoryIncompleteDecl)
 			protoVAL.opt = &ast.OptionNode{Name: protoDollar[1].optName}
 		}
-	case 122:
+	case 132:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected option name", CategoryIncompleteDecl)
 			protoVAL.opt = &ast.OptionNode{}
 		}
-	case 123:
+	case 133:
 		protoDollar = protoS[protopt-8 : protopt+1]
 		{
 			protoVAL.grp = &ast.GroupNode{Label: protoDollar[1].id.ToKeyword(), Keyword: protoDollar[2].id.ToKeyword(), Name: protoDollar[3].id, Equals: protoDollar[4].b, Tag: protoDollar[5].i, OpenBrace: protoDollar[6].b, Decls: protoDollar[7].msgElements, CloseBrace: protoDollar[8].b}
 		}
-	case 124:
+	case 134:
 		protoDollar = protoS[protopt-10 : protopt+1]
 		{
 			protoDollar[6].cmpctOpts.Semicolon = protoDollar[7].b
 			protoVAL.grp = &ast.GroupNode{Label: protoDollar[1].id.ToKeyword(), Keyword: protoDollar[2].id.ToKeyword(), Name: protoDollar[3].id, Equals: protoDollar[4].b, Tag: protoDollar[5].i, Options: protoDollar[6].cmpctOpts, OpenBrace: protoDollar[8].b, Decls: protoDollar[9].msgElements, CloseBrace: protoDollar[10].b}
 		}
-	case 125:
+	case 135:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.oo = &ast.OneofNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, OpenBrace: protoDollar[3].b, Decls: protoDollar[4].ooElements, CloseBrace: protoDollar[5].b}
 		}
-	case 126:
+	case 136:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.ooElements = nil
 		}
-	case 128:
+	case 138:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].ooElement != nil {
@@ -1739,7 +1826,7 @@ protodefault:
 				protoVAL.ooElements = protoDollar[1].ooElements
 			}
 		}
-	case 129:
+	case 139:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].ooElement != nil {
@@ -1748,68 +1835,68 @@ protodefault:
 				protoVAL.ooElements = nil
 			}
 		}
-	case 130:
+	case 140:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.ooElement = protoDollar[1].opt.AsOneofElement()
 		}
-	case 131:
+	case 141:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].fld.Semicolon = protoDollar[2].b
 			protoVAL.ooElement = protoDollar[1].fld.AsOneofElement()
 		}
-	case 132:
+	case 142:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].grp.Semicolon = protoDollar[2].b
 			protoVAL.ooElement = protoDollar[1].grp.AsOneofElement()
 		}
-	case 133:
+	case 143:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.ooElement = nil
 		}
-	case 134:
+	case 144:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv, Name: protoDollar[2].id, Equals: protoDollar[3].b, Tag: protoDollar[4].i}
 		}
-	case 135:
+	case 145:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv, Name: protoDollar[2].id, Equals: protoDollar[3].b, Tag: protoDollar[4].i, Options: protoDollar[5].cmpctOpts}
 		}
-	case 136:
+	case 146:
 		protoDollar = protoS[protopt-7 : protopt+1]
 		{
 			protoVAL.grp = &ast.GroupNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, Equals: protoDollar[3].b, Tag: protoDollar[4].i, OpenBrace: protoDollar[5].b, Decls: protoDollar[6].msgElements, CloseBrace: protoDollar[7].b}
 		}
-	case 137:
+	case 147:
 		protoDollar = protoS[protopt-9 : protopt+1]
 		{
 			protoDollar[5].cmpctOpts.Semicolon = protoDollar[6].b
 			protoVAL.grp = &ast.GroupNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, Equals: protoDollar[3].b, Tag: protoDollar[4].i, Options: protoDollar[5].cmpctOpts, OpenBrace: protoDollar[7].b, Decls: protoDollar[8].msgElements, CloseBrace: protoDollar[9].b}
 		}
-	case 138:
+	case 148:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoDollar[1].mapType.Semicolon = protoDollar[2].b
 			protoVAL.mapFld = &ast.MapFieldNode{MapType: protoDollar[1].mapType, Name: protoDollar[3].id, Equals: protoDollar[4].b, Tag: protoDollar[5].i}
 		}
-	case 139:
+	case 149:
 		protoDollar = protoS[protopt-6 : protopt+1]
 		{
 			protoDollar[1].mapType.Semicolon = protoDollar[2].b
 			protoVAL.mapFld = &ast.MapFieldNode{MapType: protoDollar[1].mapType, Name: protoDollar[3].id, Equals: protoDollar[4].b, Tag: protoDollar[5].i, Options: protoDollar[6].cmpctOpts}
 		}
-	case 140:
+	case 150:
 		protoDollar = protoS[protopt-6 : protopt+1]
 		{
 			protoVAL.mapType = &ast.MapTypeNode{Keyword: protoDollar[1].id.ToKeyword(), OpenAngle: protoDollar[2].b, KeyType: protoDollar[3].id, Comma: protoDollar[4].b, ValueType: protoDollar[5].idv, CloseAngle: protoDollar[6].b}
 		}
-	case 153:
+	case 163:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1817,7 +1904,7 @@ protodefault:
 			}
 			protoVAL.ext = &ast.ExtensionRangeNode{Keyword: protoDollar[1].id.ToKeyword(), Elements: protoDollar[2].rngs}
 		}
-	case 154:
+	case 164:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1825,67 +1912,67 @@ protodefault:
 			}
 			protoVAL.ext = &ast.ExtensionRangeNode{Keyword: protoDollar[1].id.ToKeyword(), Elements: protoDollar[2].rngs, Options: protoDollar[4].cmpctOpts}
 		}
-	case 155:
+	case 165:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.rngs = []*ast.RangeElement{protoDollar[1].rng.AsRangeElement()}
 		}
-	case 156:
+	case 166:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.rngs = append(protoDollar[1].rngs, protoDollar[2].b.AsRangeElement(), protoDollar[3].rng.AsRangeElement())
 		}
-	case 157:
+	case 167:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.rng = &ast.RangeNode{StartVal: protoDollar[1].i.AsIntValueNode()}
 		}
-	case 158:
+	case 168:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.rng = &ast.RangeNode{StartVal: protoDollar[1].i.AsIntValueNode(), To: protoDollar[2].id.ToKeyword(), EndVal: protoDollar[3].i.AsIntValueNode()}
 		}
-	case 159:
+	case 169:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.rng = &ast.RangeNode{StartVal: protoDollar[1].i.AsIntValueNode(), To: protoDollar[2].id.ToKeyword(), Max: protoDollar[3].id.ToKeyword()}
 		}
-	case 160:
+	case 170:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.rngs = []*ast.RangeElement{protoDollar[1].rng.AsRangeElement()}
 		}
-	case 161:
+	case 171:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.rngs = append(protoDollar[1].rngs, protoDollar[2].b.AsRangeElement(), protoDollar[3].rng.AsRangeElement())
 		}
-	case 162:
+	case 172:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.rng = &ast.RangeNode{StartVal: protoDollar[1].il}
 		}
-	case 163:
+	case 173:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.rng = &ast.RangeNode{StartVal: protoDollar[1].il, To: protoDollar[2].id.ToKeyword(), EndVal: protoDollar[3].il}
 		}
-	case 164:
+	case 174:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.rng = &ast.RangeNode{StartVal: protoDollar[1].il, To: protoDollar[2].id.ToKeyword(), Max: protoDollar[3].id.ToKeyword()}
 		}
-	case 165:
+	case 175:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.il = protoDollar[1].i.AsIntValueNode()
 		}
-	case 166:
+	case 176:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.il = (&ast.NegativeIntLiteralNode{Minus: protoDollar[1].b, Uint: protoDollar[2].i}).AsIntValueNode()
 		}
-	case 167:
+	case 177:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1893,7 +1980,7 @@ protodefault:
 			}
 			protoVAL.resvd = &ast.ReservedNode{Keyword: protoDollar[1].id.ToKeyword(), Elements: ast.RangeElementsToReservedElements(protoDollar[2].rngs)}
 		}
-	case 169:
+	case 179:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1901,7 +1988,7 @@ protodefault:
 			}
 			protoVAL.resvd = &ast.ReservedNode{Keyword: protoDollar[1].id.ToKeyword(), Elements: ast.RangeElementsToReservedElements(protoDollar[2].rngs)}
 		}
-	case 171:
+	case 181:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			if protoDollar[3].b != nil {
@@ -1909,42 +1996,47 @@ protodefault:
 			}
 			protoVAL.resvd = &ast.ReservedNode{Keyword: protoDollar[1].id.ToKeyword(), Elements: protoDollar[2].names}
 		}
-	case 172:
+	case 182:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoVAL.resvd = &ast.ReservedNode{Keyword: protoDollar[1].id.ToKeyword(), Elements: protoDollar[2].names}
 		}
-	case 173:
+	case 183:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.names = []*ast.ReservedElement{protoDollar[1].sv.AsReservedElement()}
 		}
-	case 174:
+	case 184:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.names = append(protoDollar[1].names, protoDollar[2].b.AsReservedElement(), protoDollar[3].sv.AsReservedElement())
 		}
-	case 175:
+	case 185:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.names = []*ast.ReservedElement{protoDollar[1].id.AsReservedElement()}
 		}
-	case 176:
+	case 186:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.names = append(protoDollar[1].names, protoDollar[2].b.AsReservedElement(), protoDollar[3].id.AsReservedElement())
 		}
-	case 177:
+	case 187:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.en = &ast.EnumNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, OpenBrace: protoDollar[3].b, Decls: protoDollar[4].enElements, CloseBrace: protoDollar[5].b}
 		}
-	case 178:
+	case 188:
+		protoDollar = protoS[protopt-6 : protopt+1]
+		{
+			protoVAL.en = &ast.EnumNode{Visibility: protoDollar[1].id.ToKeyword(), Keyword: protoDollar[2].id.ToKeyword(), Name: protoDollar[3].id, OpenBrace: protoDollar[4].b, Decls: protoDollar[5].enElements, CloseBrace: protoDollar[6].b}
+		}
+	case 189:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.enElements = nil
 		}
-	case 180:
+	case 191:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].enElement != nil {
@@ -1953,7 +2045,7 @@ protodefault:
 				protoVAL.enElements = protoDollar[1].enElements
 			}
 		}
-	case 181:
+	case 192:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].enElement != nil {
@@ -1962,50 +2054,55 @@ protodefault:
 				protoVAL.enElements = nil
 			}
 		}
-	case 182:
+	case 193:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.enElement = protoDollar[1].opt.AsEnumElement()
 		}
-	case 183:
+	case 194:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].env.Semicolon = protoDollar[2].b
 			protoVAL.enElement = protoDollar[1].env.AsEnumElement()
 		}
-	case 184:
+	case 195:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].resvd.Semicolon = protoDollar[2].b
 			protoVAL.enElement = protoDollar[1].resvd.AsEnumElement()
 		}
-	case 185:
+	case 196:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.enElement = nil
 		}
-	case 186:
+	case 197:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.env = &ast.EnumValueNode{Name: protoDollar[1].id, Equals: protoDollar[2].b, Number: protoDollar[3].il}
 		}
-	case 187:
+	case 198:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoVAL.env = &ast.EnumValueNode{Name: protoDollar[1].id, Equals: protoDollar[2].b, Number: protoDollar[3].il, Options: protoDollar[4].cmpctOpts}
 		}
-	case 188:
+	case 199:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.msg = &ast.MessageNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, OpenBrace: protoDollar[3].b, Decls: protoDollar[4].msgElements, CloseBrace: protoDollar[5].b}
 		}
-	case 189:
+	case 200:
+		protoDollar = protoS[protopt-6 : protopt+1]
+		{
+			protoVAL.msg = &ast.MessageNode{Visibility: protoDollar[1].id.ToKeyword(), Keyword: protoDollar[2].id.ToKeyword(), Name: protoDollar[3].id, OpenBrace: protoDollar[4].b, Decls: protoDollar[5].msgElements, CloseBrace: protoDollar[6].b}
+		}
+	case 203:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.msgElements = nil
 		}
-	case 191:
+	case 205:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].msgElement != nil {
@@ -2014,7 +2111,7 @@ protodefault:
 				protoVAL.msgElements = protoDollar[1].msgElements
 			}
 		}
-	case 192:
+	case 206:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].msgElement != nil {
@@ -2023,156 +2120,156 @@ protodefault:
 				protoVAL.msgElements = nil
 			}
 		}
-	case 193:
+	case 207:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].fld.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].fld.AsMessageElement()
 		}
-	case 194:
+	case 208:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].en.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].en.AsMessageElement()
 		}
-	case 195:
+	case 209:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].msg.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].msg.AsMessageElement()
 		}
-	case 196:
+	case 210:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].extend.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].extend.AsMessageElement()
 		}
-	case 197:
+	case 211:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].ext.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].ext.AsMessageElement()
 		}
-	case 198:
+	case 212:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].grp.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].grp.AsMessageElement()
 		}
-	case 199:
+	case 213:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].opt.AsMessageElement()
 		}
-	case 200:
+	case 214:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].oo.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].oo.AsMessageElement()
 		}
-	case 201:
+	case 215:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].mapFld.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].mapFld.AsMessageElement()
 		}
-	case 202:
+	case 216:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].resvd.Semicolon = protoDollar[2].b
 			protoVAL.msgElement = protoDollar[1].resvd.AsMessageElement()
 		}
-	case 203:
+	case 217:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.msgElement = (&ast.EmptyDeclNode{Semicolon: protoDollar[1].b}).AsMessageElement()
 		}
-	case 204:
+	case 218:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.fld = &ast.FieldNode{Label: protoDollar[1].id.ToKeyword(), FieldType: protoDollar[2].idv, Name: protoDollar[3].id, Equals: protoDollar[4].b, Tag: protoDollar[5].i}
 		}
-	case 205:
+	case 219:
 		protoDollar = protoS[protopt-6 : protopt+1]
 		{
 			protoVAL.fld = &ast.FieldNode{Label: protoDollar[1].id.ToKeyword(), FieldType: protoDollar[2].idv, Name: protoDollar[3].id, Equals: protoDollar[4].b, Tag: protoDollar[5].i, Options: protoDollar[6].cmpctOpts}
 		}
-	case 206:
+	case 220:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv, Name: protoDollar[2].id, Equals: protoDollar[3].b, Tag: protoDollar[4].i}
 		}
-	case 207:
+	case 221:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv, Name: protoDollar[2].id, Equals: protoDollar[3].b, Tag: protoDollar[4].i, Options: protoDollar[5].cmpctOpts}
 		}
-	case 208:
+	case 222:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing field number after '='", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{Label: protoDollar[1].id.ToKeyword(), FieldType: protoDollar[2].idv, Name: protoDollar[3].id}
 		}
-	case 209:
+	case 223:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing '=' after field name", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{Label: protoDollar[1].id.ToKeyword(), FieldType: protoDollar[2].idv, Name: protoDollar[3].id}
 		}
-	case 210:
+	case 224:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing field name", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{Label: protoDollar[1].id.ToKeyword(), FieldType: protoDollar[2].idv}
 		}
-	case 211:
+	case 225:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing field number after '='", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv, Name: protoDollar[2].id}
 		}
-	case 212:
+	case 226:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing '=' after field name", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv, Name: protoDollar[2].id}
 		}
-	case 213:
+	case 227:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing field name", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{FieldType: protoDollar[1].idv}
 		}
-	case 214:
+	case 228:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("missing field type", CategoryIncompleteDecl)
 			protoVAL.fld = &ast.FieldNode{Label: protoDollar[1].id.ToKeyword()}
 		}
-	case 215:
+	case 229:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.extend = &ast.ExtendNode{Keyword: protoDollar[1].id.ToKeyword(), Extendee: protoDollar[2].idv, OpenBrace: protoDollar[3].b, Decls: protoDollar[4].extElements, CloseBrace: protoDollar[5].b}
 		}
-	case 216:
+	case 230:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected '{'", CategoryIncompleteDecl)
 			protoVAL.extend = &ast.ExtendNode{Keyword: protoDollar[1].id.ToKeyword(), Extendee: protoDollar[2].idv}
 		}
-	case 217:
+	case 231:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntax("expected message name", CategoryIncompleteDecl)
 			protoVAL.extend = &ast.ExtendNode{Keyword: protoDollar[1].id.ToKeyword()}
 		}
-	case 218:
+	case 232:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.extElements = nil
 		}
-	case 220:
+	case 234:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].extElement != nil {
@@ -2181,7 +2278,7 @@ protodefault:
 				protoVAL.extElements = protoDollar[1].extElements
 			}
 		}
-	case 221:
+	case 235:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].extElement != nil {
@@ -2190,76 +2287,76 @@ protodefault:
 				protoVAL.extElements = nil
 			}
 		}
-	case 222:
+	case 236:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].fld.Semicolon = protoDollar[2].b
 			protoVAL.extElement = protoDollar[1].fld.AsExtendElement()
 		}
-	case 223:
+	case 237:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].grp.Semicolon = protoDollar[2].b
 			protoVAL.extElement = protoDollar[1].grp.AsExtendElement()
 		}
-	case 224:
+	case 238:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].mapFld.Semicolon = protoDollar[2].b
 			protolex.(*protoLex).ErrExtendedSyntaxAt("map fields not allowed in extend declarations", protoDollar[1].mapFld, CategoryDeclNotAllowed)
 			protoVAL.extElement = nil
 		}
-	case 225:
+	case 239:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].oo.Semicolon = protoDollar[2].b
 			protolex.(*protoLex).ErrExtendedSyntaxAt("\"oneof\" not allowed in extend declarations", protoDollar[1].oo, CategoryDeclNotAllowed)
 			protoVAL.extElement = nil
 		}
-	case 226:
+	case 240:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].resvd.Semicolon = protoDollar[2].b
 			protolex.(*protoLex).ErrExtendedSyntaxAt("\"reserved\" not allowed in extend declarations", protoDollar[1].resvd, CategoryDeclNotAllowed)
 			protoVAL.extElement = nil
 		}
-	case 227:
+	case 241:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].ext.Semicolon = protoDollar[2].b
 			protolex.(*protoLex).ErrExtendedSyntaxAt("extension ranges not allowed in extend declarations", protoDollar[1].ext, CategoryDeclNotAllowed)
 			protoVAL.extElement = nil
 		}
-	case 228:
+	case 242:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].msg.Semicolon = protoDollar[2].b
 			protolex.(*protoLex).ErrExtendedSyntaxAt("nested messages not allowed in extend declarations", protoDollar[1].msg, CategoryDeclNotAllowed)
 			protoVAL.extElement = nil
 		}
-	case 229:
+	case 243:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].en.Semicolon = protoDollar[2].b
 			protolex.(*protoLex).ErrExtendedSyntaxAt("nested enums not allowed in extend declarations", protoDollar[1].en, CategoryDeclNotAllowed)
 			protoVAL.extElement = nil
 		}
-	case 230:
+	case 244:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.extElement = nil
 		}
-	case 231:
+	case 245:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.svc = &ast.ServiceNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, OpenBrace: protoDollar[3].b, Decls: protoDollar[4].svcElements, CloseBrace: protoDollar[5].b}
 		}
-	case 232:
+	case 246:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.svcElements = nil
 		}
-	case 234:
+	case 248:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].svcElement != nil {
@@ -2268,7 +2365,7 @@ protodefault:
 				protoVAL.svcElements = protoDollar[1].svcElements
 			}
 		}
-	case 235:
+	case 249:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].svcElement != nil {
@@ -2277,55 +2374,55 @@ protodefault:
 				protoVAL.svcElements = nil
 			}
 		}
-	case 236:
+	case 250:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.svcElement = protoDollar[1].opt.AsServiceElement()
 		}
-	case 237:
+	case 251:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].mtd.Semicolon = protoDollar[2].b
 			protoVAL.svcElement = protoDollar[1].mtd.AsServiceElement()
 		}
-	case 238:
+	case 252:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].mtd.Semicolon = protoDollar[2].b
 			protoVAL.svcElement = protoDollar[1].mtd.AsServiceElement()
 		}
-	case 239:
+	case 253:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.svcElement = nil
 		}
-	case 240:
+	case 254:
 		protoDollar = protoS[protopt-5 : protopt+1]
 		{
 			protoVAL.mtd = &ast.RPCNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, Input: protoDollar[3].mtdMsgType, Returns: protoDollar[4].id.ToKeyword(), Output: protoDollar[5].mtdMsgType}
 		}
-	case 241:
+	case 255:
 		protoDollar = protoS[protopt-8 : protopt+1]
 		{
 			protoVAL.mtd = &ast.RPCNode{Keyword: protoDollar[1].id.ToKeyword(), Name: protoDollar[2].id, Input: protoDollar[3].mtdMsgType, Returns: protoDollar[4].id.ToKeyword(), Output: protoDollar[5].mtdMsgType, OpenBrace: protoDollar[6].b, Decls: protoDollar[7].mtdElements, CloseBrace: protoDollar[8].b}
 		}
-	case 242:
+	case 256:
 		protoDollar = protoS[protopt-4 : protopt+1]
 		{
 			protoVAL.mtdMsgType = &ast.RPCTypeNode{OpenParen: protoDollar[1].b, Stream: protoDollar[2].id.ToKeyword(), MessageType: protoDollar[3].idv, CloseParen: protoDollar[4].b}
 		}
-	case 243:
+	case 257:
 		protoDollar = protoS[protopt-3 : protopt+1]
 		{
 			protoVAL.mtdMsgType = &ast.RPCTypeNode{OpenParen: protoDollar[1].b, MessageType: protoDollar[2].idv, CloseParen: protoDollar[3].b}
 		}
-	case 244:
+	case 258:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.mtdElements = nil
 		}
-	case 246:
+	case 260:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			if protoDollar[2].mtdElement != nil {
@@ -2334,7 +2431,7 @@ protodefault:
 				protoVAL.mtdElements = protoDollar[1].mtdElements
 			}
 		}
-	case 247:
+	case 261:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].mtdElement != nil {
@@ -2343,40 +2440,40 @@ protodefault:
 				protoVAL.mtdElements = nil
 			}
 		}
-	case 248:
+	case 262:
 		protoDollar = protoS[protopt-2 : protopt+1]
 		{
 			protoDollar[1].opt.Semicolon = protoDollar[2].b
 			protoVAL.mtdElement = protoDollar[1].opt.AsRPCElement()
 		}
-	case 249:
+	case 263:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.mtdElement = nil
 		}
-	case 488:
+	case 502:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntaxAt("unexpected trailing comma", protoDollar[1].b, CategoryExtraTokens)
 			protoVAL.b = protoDollar[1].b
 		}
-	case 489:
+	case 503:
 		protoDollar = protoS[protopt-0 : protopt+1]
 		{
 			protoVAL.b = nil
 		}
-	case 490:
+	case 504:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.b = protoDollar[1].b
 		}
-	case 491:
+	case 505:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protolex.(*protoLex).ErrExtendedSyntaxAt("expected ';', found ','", protoDollar[1].b, CategoryIncorrectToken)
 			protoVAL.b = protoDollar[1].b
 		}
-	case 492:
+	case 506:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].b.Virtual {
@@ -2384,7 +2481,7 @@ protodefault:
 			}
 			protoVAL.b = protoDollar[1].b
 		}
-	case 493:
+	case 507:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if !protoDollar[1].b.Virtual {
@@ -2392,7 +2489,7 @@ protodefault:
 			}
 			protoVAL.b = protoDollar[1].b
 		}
-	case 494:
+	case 508:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if !protoDollar[1].b.Virtual {
@@ -2400,7 +2497,7 @@ protodefault:
 			}
 			protoVAL.b = protoDollar[1].b
 		}
-	case 496:
+	case 510:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			if protoDollar[1].b.Virtual {
@@ -2409,22 +2506,22 @@ protodefault:
 				protolex.(*protoLex).ErrExtendedSyntaxAt("expected ',', found ';'", protoDollar[1].b, CategoryIncorrectToken)
 			}
 		}
-	case 497:
+	case 511:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.b = protoDollar[1].b
 		}
-	case 498:
+	case 512:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.b = protoDollar[1].b
 		}
-	case 499:
+	case 513:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.b = protoDollar[1].b
 		}
-	case 500:
+	case 514:
 		protoDollar = protoS[protopt-1 : protopt+1]
 		{
 			protoVAL.b = protoDollar[1].b
@@ -41,6 +41,10 @@ type result struct {
 	nodesInverse       map[ast.Node]proto.Message
 	fieldExtendeeNodes map[ast.Node]*ast.ExtendNode
 
+	limits               ValidationLimits
+	experimentalEditions bool
+	oneofNaming          SyntheticOneofNaming
+
 	// A position in the source file corresponding to the end of the last import
 	// statement (the point just after the semicolon). This can be used as an
 	// insertion point for new import statements.
@@ -67,13 +71,25 @@ func ResultWithoutAST(proto *descriptorpb.FileDescriptorProto) Result {
 //
 // The given handler is used to report any errors or warnings encountered. If any
 // errors are reported, this function returns a non-nil error.
-func ResultFromAST(file *ast.FileNode, validate bool, handler *reporter.Handler) (Result, error) {
+//
+// The limits checked during validation can be raised with WithValidationLimits.
+// WithExperimentalEditions allows editions that are not yet fully supported,
+// and WithProtocSyntheticOneofNames changes how synthetic oneofs are named;
+// other options are ignored.
+func ResultFromAST(file *ast.FileNode, validate bool, handler *reporter.Handler, opts ...ParserOption) (Result, error) {
 	filename := file.Name()
+	po := newParseOptions(opts)
 	r := &result{
-		file:               file,
-		nodes:              map[proto.Message]ast.Node{},
-		nodesInverse:       map[ast.Node]proto.Message{},
-		fieldExtendeeNodes: map[ast.Node]*ast.ExtendNode{},
+		file:                 file,
+		nodes:                map[proto.Message]ast.Node{},
+		nodesInverse:         map[ast.Node]proto.Message{},
+		fieldExtendeeNodes:   map[ast.Node]*ast.ExtendNode{},
+		limits:               po.validationLimits,
+		experimentalEditions: po.experimental,
+		oneofNaming:          SyntheticOneofNamingDefault,
+	}
+	if po.protocOneofNames {
+		r.oneofNaming = SyntheticOneofNamingProtoc
 	}
 	r.createFileDescriptor(filename, file, handler)
 	if validate {
@@ -109,6 +125,10 @@ DECLS:
 	return r, handler.Error()
 }
 
+func (r *result) SyntheticOneofNaming() SyntheticOneofNaming {
+	return r.oneofNaming
+}
+
 func (r *result) ImportInsertionPoint() ast.SourcePos {
 	if r.file == nil {
 		return ast.SourcePos{}
@@ -159,6 +179,9 @@ func (r *result) createFileDescriptor(filename string, file *ast.FileNode, handl
 
 		fd.Syntax = proto.String("editions")
 		editionEnum, ok := editions.SupportedEditions[edition]
+		if !ok && r.experimentalEditions {
+			editionEnum, ok = editions.ExperimentalEditions[edition]
+		}
 		if !ok {
 			nodeInfo := file.NodeInfo(file.Edition.Edition)
 			editionStrs := make([]string, 0, len(editions.SupportedEditions))
@@ -225,15 +248,15 @@ func (r *result) createFileDescriptor(filename string, file *ast.FileNode, handl
 				}
 			}
 			pkgName := string(decl.Name.AsIdentifier())
-			if len(pkgName) >= 512 {
+			if len(pkgName) >= r.limits.MaxPackageNameLength {
 				nodeInfo := file.NodeInfo(decl.Name)
-				if handler.HandleErrorf(nodeInfo, "package name (with whitespace removed) must be less than 512 characters long") != nil {
+				if handler.HandleErrorf(nodeInfo, "package name (with whitespace removed) must be less than %d characters long", r.limits.MaxPackageNameLength) != nil {
 					return
 				}
 			}
-			if strings.Count(pkgName, ".") > 100 {
+			if strings.Count(pkgName, ".") > r.limits.MaxPackageNamePeriods {
 				nodeInfo := file.NodeInfo(decl.Name)
-				if handler.HandleErrorf(nodeInfo, "package name may not contain more than 100 periods") != nil {
+				if handler.HandleErrorf(nodeInfo, "package name may not contain more than %d periods", r.limits.MaxPackageNamePeriods) != nil {
 					return
 				}
 			}
@@ -387,7 +410,7 @@ func asLabel(lbl *ast.IdentNode) *descriptorpb.FieldDescriptorProto_Label {
 
 func (r *result) asFieldDescriptor(node *ast.FieldNode, maxTag int32, syntax protoreflect.Syntax, handler *reporter.Handler) *descriptorpb.FieldDescriptorProto {
 	tag := node.Tag.Val
-	if err := r.checkTag(node.Tag, tag, maxTag); err != nil {
+	if err := checkTag(r.file.NodeInfo(node.Tag), tag, maxTag); err != nil {
 		_ = handler.HandleError(err)
 	}
 	fd := newFieldDescriptor(node.Name.Val, string(node.GetFieldType().AsIdentifier()), int32(tag), asLabel(node.Label))
@@ -440,7 +463,7 @@ func newFieldDescriptor(name string, fieldType string, tag int32, lbl *descripto
 
 func (r *result) asGroupDescriptors(group *ast.GroupNode, syntax protoreflect.Syntax, maxTag int32, handler *reporter.Handler, depth int) (*descriptorpb.FieldDescriptorProto, *descriptorpb.DescriptorProto) {
 	tag := group.Tag.Val
-	if err := r.checkTag(group.Tag, tag, maxTag); err != nil {
+	if err := checkTag(r.file.NodeInfo(group.Tag), tag, maxTag); err != nil {
 		_ = handler.HandleError(err)
 	}
 	if !unicode.IsUpper(rune(group.Name.Val[0])) {
@@ -470,7 +493,7 @@ func (r *result) asGroupDescriptors(group *ast.GroupNode, syntax protoreflect.Sy
 
 func (r *result) asMapDescriptors(mapField *ast.MapFieldNode, syntax protoreflect.Syntax, maxTag int32, handler *reporter.Handler, depth int) (*descriptorpb.FieldDescriptorProto, *descriptorpb.DescriptorProto) {
 	tag := mapField.Tag.Val
-	if err := r.checkTag(mapField.Tag, tag, maxTag); err != nil {
+	if err := checkTag(r.file.NodeInfo(mapField.Tag), tag, maxTag); err != nil {
 		_ = handler.HandleError(err)
 	}
 	r.checkDepth(depth, mapField, handler)
@@ -570,6 +593,7 @@ func (r *result) asMethodDescriptor(node *ast.RPCNode) *descriptorpb.MethodDescr
 func (r *result) asEnumDescriptor(en *ast.EnumNode, syntax protoreflect.Syntax, handler *reporter.Handler) *descriptorpb.EnumDescriptorProto {
 	ed := &descriptorpb.EnumDescriptorProto{Name: proto.String(en.Name.Val)}
 	r.putEnumNode(ed, en)
+	protointernal.SetVisibility(ed, asVisibility(en.Visibility))
 	rsvdNames := map[string]ast.SourcePos{}
 	for _, decl := range en.Decls {
 		switch decl := decl.Unwrap().(type) {
@@ -608,6 +632,7 @@ func (r *result) asEnumReservedRange(rng *ast.RangeNode, handler *reporter.Handl
 func (r *result) asMessageDescriptor(node *ast.MessageNode, syntax protoreflect.Syntax, handler *reporter.Handler, depth int) *descriptorpb.DescriptorProto {
 	msgd := &descriptorpb.DescriptorProto{Name: proto.String(node.Name.Val)}
 	r.putMessageNode(msgd, node)
+	protointernal.SetVisibility(msgd, asVisibility(node.Visibility))
 	// don't bother processing body if we've exceeded depth
 	if r.checkDepth(depth, node, handler) {
 		r.addMessageBody(msgd, node.Decls, syntax, handler, depth)
@@ -615,6 +640,19 @@ func (r *result) asMessageDescriptor(node *ast.MessageNode, syntax protoreflect.
 	return msgd
 }
 
+// asVisibility returns the visibility indicated by the given "export" or
+// "local" modifier, which may be nil.
+func asVisibility(node *ast.IdentNode) protointernal.SymbolVisibility {
+	switch node.GetVal() {
+	case "export":
+		return protointernal.VisibilityExport
+	case "local":
+		return protointernal.VisibilityLocal
+	default:
+		return protointernal.VisibilityUnset
+	}
+}
+
 func (r *result) addReservedNames(names *[]string, node *ast.ReservedNode, syntax protoreflect.Syntax, handler *reporter.Handler, alreadyReserved map[string]ast.SourcePos) {
 	if syntax == protoreflect.Editions {
 		if len(node.FilterNames()) > 0 {
@@ -651,14 +689,14 @@ func (r *result) addReservedNames(names *[]string, node *ast.ReservedNode, synta
 }
 
 func (r *result) checkDepth(depth int, node ast.Node, handler *reporter.Handler) bool {
-	if depth < 32 {
+	if depth < r.limits.MaxMessageNestingDepth {
 		return true
 	}
 	if grp, ok := node.(*ast.GroupNode); ok {
 		// pinpoint the group keyword if the source is a group
 		node = grp.Keyword
 	}
-	_ = handler.HandleErrorf(r.file.NodeInfo(node), "message nesting depth must be less than 32")
+	_ = handler.HandleErrorf(r.file.NodeInfo(node), "message nesting depth must be less than %d", r.limits.MaxMessageNestingDepth)
 	return false
 }
 
@@ -874,14 +912,14 @@ func (r *result) asServiceDescriptor(svc *ast.ServiceNode) *descriptorpb.Service
 	return sd
 }
 
-func (r *result) checkTag(n ast.Node, v uint64, maxTag int32) error {
+func checkTag[T int32 | uint64](span ast.SourceSpan, v T, maxTag int32) error {
 	switch {
 	case v < 1:
-		return reporter.Errorf(r.file.NodeInfo(n), "tag number %d must be greater than zero", v)
-	case v > uint64(maxTag):
-		return reporter.Errorf(r.file.NodeInfo(n), "tag number %d is higher than max allowed tag number (%d)", v, maxTag)
+		return reporter.Errorf(span, "tag number %d must be greater than zero", v)
+	case v > T(maxTag):
+		return reporter.Errorf(span, "tag number %d is higher than max allowed tag number (%d)", v, maxTag)
 	case v >= protointernal.SpecialReservedStart && v <= protointernal.SpecialReservedEnd:
-		return reporter.Errorf(r.file.NodeInfo(n), "tag number %d is in disallowed reserved range %d-%d", v, protointernal.SpecialReservedStart, protointernal.SpecialReservedEnd)
+		return reporter.Errorf(span, "tag number %d is in disallowed reserved range %d-%d", v, protointernal.SpecialReservedStart, protointernal.SpecialReservedEnd)
 	default:
 		return nil
 	}
@@ -908,20 +946,22 @@ func (r *result) processProto3OptionalFields(msgd *descriptorpb.DescriptorProto)
 				// NB: protoc only considers names of other fields and oneofs
 				// when computing the synthetic oneof name. But that feels like
 				// a bug, since it means it could generate a name that conflicts
-				// with some other symbol defined in the message. If it's decided
-				// that's NOT a bug and is desirable, then we should remove the
-				// following four loops to mimic protoc's behavior.
-				for _, fd := range msgd.Extension {
-					allNames[fd.GetName()] = struct{}{}
-				}
-				for _, ed := range msgd.EnumType {
-					allNames[ed.GetName()] = struct{}{}
-					for _, evd := range ed.Value {
-						allNames[evd.GetName()] = struct{}{}
+				// with some other symbol defined in the message. So, unless
+				// protoc's behavior was requested, the following loops also
+				// consider the other elements of the message.
+				if r.oneofNaming != SyntheticOneofNamingProtoc {
+					for _, fd := range msgd.Extension {
+						allNames[fd.GetName()] = struct{}{}
+					}
+					for _, ed := range msgd.EnumType {
+						allNames[ed.GetName()] = struct{}{}
+						for _, evd := range ed.Value {
+							allNames[evd.GetName()] = struct{}{}
+						}
+					}
+					for _, fd := range msgd.NestedType {
+						allNames[fd.GetName()] = struct{}{}
 					}
-				}
-				for _, fd := range msgd.NestedType {
-					allNames[fd.GetName()] = struct{}{}
 				}
 			}
 
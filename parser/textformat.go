@@ -0,0 +1,553 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// ParseTextFormat parses a document in the protobuf text format, such as the
+// contents of a .textpb file. The result is a message literal with no braces,
+// whose elements are the top-level fields of the document. It uses the same
+// AST nodes as message literals in option values, so the positions of fields
+// and values (and their comments, which start with '#') are available from
+// the returned FileInfo.
+//
+// Errors are handled the same way as by Parse, including error recovery, so
+// a partial node may be returned along with an error. If the document could
+// not be parsed at all, an empty node is returned.
+//
+// Use TextFormatToMessage to convert the result to a message.
+func ParseTextFormat(filename string, r io.Reader, handler *reporter.Handler, opts ...ParserOption) (*ast.MessageLiteralNode, *ast.FileInfo, error) {
+	res, info, err := parseExpr(filename, r, handler, _START_TEXT_FORMAT, opts)
+	doc, _ := res.(*ast.MessageLiteralNode)
+	if doc == nil {
+		doc = &ast.MessageLiteralNode{}
+	}
+	return doc, info, err
+}
+
+// MessageToTextFormat formats the given message in the protobuf text format
+// and parses the result, returning the same kind of document as
+// ParseTextFormat. The filename is used for the returned FileInfo.
+func MessageToTextFormat(filename string, msg proto.Message) (*ast.MessageLiteralNode, *ast.FileInfo, error) {
+	data, err := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ParseTextFormat(filename, bytes.NewReader(data), reporter.NewHandler(nil))
+}
+
+// TextFormatResolver resolves the extensions and the message types of
+// expanded Any values that are referenced in a text format document.
+type TextFormatResolver interface {
+	protoregistry.ExtensionTypeResolver
+	protoregistry.MessageTypeResolver
+}
+
+// TextFormatToMessage sets the fields of msg from the given text format
+// document, as returned by ParseTextFormat. Unlike prototext, problems such as
+// unknown fields or values of the wrong type are reported to the handler with
+// the position of the offending node, and conversion continues if the handler
+// does not return an error. Fields that are already set in msg are merged
+// with the document, like proto.Merge.
+//
+// Extensions and expanded Any values are resolved with the given resolver. If
+// it is nil, protoregistry.GlobalTypes is used.
+func TextFormatToMessage(doc *ast.MessageLiteralNode, info *ast.FileInfo, msg proto.Message, resolver TextFormatResolver, handler *reporter.Handler) error {
+	if resolver == nil {
+		resolver = protoregistry.GlobalTypes
+	}
+	c := &textFormatConverter{info: info, resolver: resolver, handler: handler}
+	if err := c.message(doc.GetElements(), msg.ProtoReflect(), nil); err != nil {
+		return err
+	}
+	return handler.Error()
+}
+
+type textFormatConverter struct {
+	info     *ast.FileInfo
+	resolver TextFormatResolver
+	handler  *reporter.Handler
+}
+
+func (c *textFormatConverter) errorf(node ast.Node, format string, args ...any) error {
+	var span ast.SourceSpan
+	if node == nil {
+		span = ast.UnknownSpan(c.info.GetName())
+	} else {
+		span = c.info.NodeInfo(node)
+	}
+	return c.handler.HandleErrorf(span, format, args...)
+}
+
+// message sets the given fields in msg. The literal is the node to which
+// errors about the message as a whole are attributed; it is nil for the
+// top-level document.
+func (c *textFormatConverter) message(fields []*ast.MessageFieldNode, msg protoreflect.Message, literal ast.Node) error {
+	md := msg.Descriptor()
+	if md.FullName() == "google.protobuf.Any" {
+		if handled, err := c.expandedAny(fields, msg); handled {
+			return err
+		}
+	}
+	set := map[protoreflect.FieldNumber]bool{}
+	oneofs := map[protoreflect.FullName]protoreflect.FieldDescriptor{}
+	for _, fld := range fields {
+		if fld == nil || fld.Name == nil || fld.Val == nil {
+			// incomplete; already reported by the parser
+			continue
+		}
+		fd, err := c.findField(md, fld.Name)
+		if err != nil {
+			return err
+		}
+		if fd == nil {
+			continue
+		}
+		if !fd.IsList() && !fd.IsMap() {
+			if set[fd.Number()] {
+				if err := c.errorf(fld.Name, "non-repeated field %s is already set", fd.Name()); err != nil {
+					return err
+				}
+				continue
+			}
+			set[fd.Number()] = true
+			if oo := fd.ContainingOneof(); oo != nil && !oo.IsSynthetic() {
+				if other, ok := oneofs[oo.FullName()]; ok {
+					if err := c.errorf(fld.Name, "oneof %s is already set by field %s", oo.Name(), other.Name()); err != nil {
+						return err
+					}
+					continue
+				}
+				oneofs[oo.FullName()] = fd
+			}
+		}
+		if err := c.field(msg, fd, fld.Val); err != nil {
+			return err
+		}
+	}
+	for i, flds := 0, md.Fields(); i < flds.Len(); i++ {
+		if fd := flds.Get(i); fd.Cardinality() == protoreflect.Required && !msg.Has(fd) {
+			if err := c.errorf(literal, "message %s is missing required field %s", md.FullName(), fd.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *textFormatConverter) findField(md protoreflect.MessageDescriptor, name *ast.FieldReferenceNode) (protoreflect.FieldDescriptor, error) {
+	switch {
+	case name.IsAnyTypeReference():
+		return nil, c.errorf(name, "type URL %s may only be used in a google.protobuf.Any message, not %s", name.Value(), md.FullName())
+	case name.IsExtension():
+		extName := protoreflect.FullName(name.Name.AsIdentifier())
+		xt, err := c.resolver.FindExtensionByName(extName)
+		if err != nil {
+			return nil, c.errorf(name, "unknown extension %s", extName)
+		}
+		xtd := xt.TypeDescriptor()
+		if xtd.ContainingMessage().FullName() != md.FullName() {
+			return nil, c.errorf(name, "extension %s extends %s, not %s", extName, xtd.ContainingMessage().FullName(), md.FullName())
+		}
+		return xtd, nil
+	}
+	fieldName := string(name.Name.AsIdentifier())
+	fd := md.Fields().ByTextName(fieldName)
+	if fd == nil {
+		fd = md.Fields().ByName(protoreflect.Name(fieldName))
+	}
+	if fd == nil {
+		return nil, c.errorf(name, "message %s has no field named %q", md.FullName(), fieldName)
+	}
+	return fd, nil
+}
+
+func (c *textFormatConverter) field(msg protoreflect.Message, fd protoreflect.FieldDescriptor, val *ast.ValueNode) error {
+	switch {
+	case fd.IsMap():
+		entries := msg.Mutable(fd).Map()
+		for _, elem := range elements(val) {
+			if err := c.mapEntry(entries, fd, elem); err != nil {
+				return err
+			}
+		}
+	case fd.IsList():
+		list := msg.Mutable(fd).List()
+		for _, elem := range elements(val) {
+			if fd.Message() != nil {
+				v := list.NewElement()
+				if ok, err := c.messageValue(v.Message(), fd, elem); !ok {
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				list.Append(v)
+				continue
+			}
+			v, ok, err := c.scalarValue(fd, elem)
+			if err != nil {
+				return err
+			}
+			if ok {
+				list.Append(v)
+			}
+		}
+	default:
+		if arr := val.GetArrayLiteral(); arr != nil {
+			return c.errorf(val, "non-repeated field %s does not accept a list", fd.Name())
+		}
+		if fd.Message() != nil {
+			_, err := c.messageValue(msg.Mutable(fd).Message(), fd, val)
+			return err
+		}
+		v, ok, err := c.scalarValue(fd, val)
+		if err != nil {
+			return err
+		}
+		if ok {
+			msg.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+// elements returns the values in val if it is a list, or else val itself.
+func elements(val *ast.ValueNode) []*ast.ValueNode {
+	if arr := val.GetArrayLiteral(); arr != nil {
+		return arr.FilterValues()
+	}
+	return []*ast.ValueNode{val}
+}
+
+func (c *textFormatConverter) messageValue(msg protoreflect.Message, fd protoreflect.FieldDescriptor, val *ast.ValueNode) (bool, error) {
+	lit := val.GetMessageLiteral()
+	if lit == nil {
+		return false, c.errorf(val, "field %s expects a message literal, got %s", fd.Name(), textFormatValueKind(val))
+	}
+	if err := c.message(lit.GetElements(), msg, lit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *textFormatConverter) mapEntry(entries protoreflect.Map, fd protoreflect.FieldDescriptor, val *ast.ValueNode) error {
+	lit := val.GetMessageLiteral()
+	if lit == nil {
+		return c.errorf(val, "map field %s expects message literals with a key and value, got %s", fd.Name(), textFormatValueKind(val))
+	}
+	key := fd.MapKey().Default().MapKey()
+	var value protoreflect.Value
+	var hasKey, hasValue bool
+	for _, fld := range lit.GetElements() {
+		if fld == nil || fld.Name == nil || fld.Val == nil {
+			continue
+		}
+		name := string(fld.Name.Name.AsIdentifier())
+		switch {
+		case fld.Name.IsExtension() || fld.Name.IsAnyTypeReference() || (name != "key" && name != "value"):
+			if err := c.errorf(fld.Name, "map entry for field %s has no field named %q", fd.Name(), fld.Name.Value()); err != nil {
+				return err
+			}
+		case (name == "key" && hasKey) || (name == "value" && hasValue):
+			if err := c.errorf(fld.Name, "map entry for field %s has more than one %s", fd.Name(), name); err != nil {
+				return err
+			}
+		case name == "key":
+			hasKey = true
+			k, ok, err := c.scalarValue(fd.MapKey(), fld.Val)
+			if err != nil {
+				return err
+			}
+			if ok {
+				key = k.MapKey()
+			}
+		default:
+			hasValue = true
+			if fd.MapValue().Message() != nil {
+				value = entries.NewValue()
+				if ok, err := c.messageValue(value.Message(), fd.MapValue(), fld.Val); !ok {
+					if err != nil {
+						return err
+					}
+					value = protoreflect.Value{}
+				}
+				continue
+			}
+			v, ok, err := c.scalarValue(fd.MapValue(), fld.Val)
+			if err != nil {
+				return err
+			}
+			if ok {
+				value = v
+			}
+		}
+	}
+	if !value.IsValid() {
+		if fd.MapValue().Message() != nil {
+			value = entries.NewValue()
+		} else {
+			value = fd.MapValue().Default()
+		}
+	}
+	entries.Set(key, value)
+	return nil
+}
+
+func (c *textFormatConverter) expandedAny(fields []*ast.MessageFieldNode, msg protoreflect.Message) (bool, error) {
+	var expanded *ast.MessageFieldNode
+	for _, fld := range fields {
+		if fld != nil && fld.Name != nil && fld.Name.IsAnyTypeReference() {
+			expanded = fld
+			break
+		}
+	}
+	if expanded == nil {
+		return false, nil
+	}
+	if len(fields) > 1 {
+		return true, c.errorf(expanded.Name, "expanded Any value cannot be combined with other fields")
+	}
+	url := string(expanded.Name.UrlPrefix.AsIdentifier()) + "/" + string(expanded.Name.Name.AsIdentifier())
+	mt, err := c.resolver.FindMessageByURL(url)
+	if err != nil {
+		return true, c.errorf(expanded.Name, "unknown message type %s", url)
+	}
+	if expanded.Val == nil {
+		return true, nil
+	}
+	lit := expanded.Val.GetMessageLiteral()
+	if lit == nil {
+		return true, c.errorf(expanded.Val, "expanded Any value must be a message literal, got %s", textFormatValueKind(expanded.Val))
+	}
+	inner := mt.New()
+	if err := c.message(lit.GetElements(), inner, lit); err != nil {
+		return true, err
+	}
+	data, err := proto.MarshalOptions{AllowPartial: true, Deterministic: true}.Marshal(inner.Interface())
+	if err != nil {
+		return true, c.errorf(expanded.Val, "failed to serialize %s: %v", url, err)
+	}
+	md := msg.Descriptor()
+	msg.Set(md.Fields().ByName("type_url"), protoreflect.ValueOfString(url))
+	msg.Set(md.Fields().ByName("value"), protoreflect.ValueOfBytes(data))
+	return true, nil
+}
+
+// scalarValue converts val to a value for the given non-message field. If the
+// value is invalid, an error is reported and false is returned, along with
+// any error returned by the handler.
+func (c *textFormatConverter) scalarValue(fd protoreflect.FieldDescriptor, val *ast.ValueNode) (protoreflect.Value, bool, error) {
+	mismatch := func() (protoreflect.Value, bool, error) {
+		return protoreflect.Value{}, false, c.errorf(val, "field %s expects %s, got %s", fd.Name(), textFormatKind(fd), textFormatValueKind(val))
+	}
+	outOfRange := func() (protoreflect.Value, bool, error) {
+		return protoreflect.Value{}, false, c.errorf(val, "value %v is out of range for field %s of type %s", val.Value(), fd.Name(), fd.Kind())
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		switch v := val.Unwrap().(type) {
+		case *ast.IdentNode:
+			switch v.Val {
+			case "true", "True", "t":
+				return protoreflect.ValueOfBool(true), true, nil
+			case "false", "False", "f":
+				return protoreflect.ValueOfBool(false), true, nil
+			}
+		case *ast.UintLiteralNode:
+			if v.Val <= 1 {
+				return protoreflect.ValueOfBool(v.Val == 1), true, nil
+			}
+		}
+		return mismatch()
+	case protoreflect.EnumKind:
+		ed := fd.Enum()
+		if ident, ok := val.Unwrap().(*ast.IdentNode); ok {
+			ev := ed.Values().ByName(protoreflect.Name(ident.Val))
+			if ev == nil {
+				return protoreflect.Value{}, false, c.errorf(val, "enum %s has no value named %q", ed.FullName(), ident.Val)
+			}
+			return protoreflect.ValueOfEnum(ev.Number()), true, nil
+		}
+		num, ok := intValue(val)
+		if !ok {
+			return mismatch()
+		}
+		if num < math.MinInt32 || num > math.MaxInt32 {
+			return outOfRange()
+		}
+		if ed.IsClosed() && ed.Values().ByNumber(protoreflect.EnumNumber(num)) == nil {
+			return protoreflect.Value{}, false, c.errorf(val, "closed enum %s has no value with number %d", ed.FullName(), num)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(num)), true, nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		num, ok := intValue(val)
+		if !ok {
+			if _, isUint := val.Unwrap().(*ast.UintLiteralNode); isUint {
+				return outOfRange()
+			}
+			return mismatch()
+		}
+		if num < math.MinInt32 || num > math.MaxInt32 {
+			return outOfRange()
+		}
+		return protoreflect.ValueOfInt32(int32(num)), true, nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		num, ok := intValue(val)
+		if !ok {
+			if _, isUint := val.Unwrap().(*ast.UintLiteralNode); isUint {
+				return outOfRange()
+			}
+			return mismatch()
+		}
+		return protoreflect.ValueOfInt64(num), true, nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		switch v := val.Unwrap().(type) {
+		case *ast.UintLiteralNode:
+			if v.Val > math.MaxUint32 {
+				return outOfRange()
+			}
+			return protoreflect.ValueOfUint32(uint32(v.Val)), true, nil
+		case *ast.NegativeIntLiteralNode:
+			return outOfRange()
+		}
+		return mismatch()
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		switch v := val.Unwrap().(type) {
+		case *ast.UintLiteralNode:
+			return protoreflect.ValueOfUint64(v.Val), true, nil
+		case *ast.NegativeIntLiteralNode:
+			return outOfRange()
+		}
+		return mismatch()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, ok := floatValue(val)
+		if !ok {
+			return mismatch()
+		}
+		if fd.Kind() == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(f)), true, nil
+		}
+		return protoreflect.ValueOfFloat64(f), true, nil
+	case protoreflect.StringKind:
+		s, ok := stringValue(val)
+		if !ok {
+			return mismatch()
+		}
+		if !utf8.ValidString(s) {
+			return protoreflect.Value{}, false, c.errorf(val, "value for string field %s is not valid UTF-8", fd.Name())
+		}
+		return protoreflect.ValueOfString(s), true, nil
+	case protoreflect.BytesKind:
+		s, ok := stringValue(val)
+		if !ok {
+			return mismatch()
+		}
+		return protoreflect.ValueOfBytes([]byte(s)), true, nil
+	}
+	return mismatch()
+}
+
+func intValue(val *ast.ValueNode) (int64, bool) {
+	switch v := val.Unwrap().(type) {
+	case *ast.UintLiteralNode:
+		return v.AsInt64()
+	case *ast.NegativeIntLiteralNode:
+		return v.AsInt64()
+	}
+	return 0, false
+}
+
+func floatValue(val *ast.ValueNode) (float64, bool) {
+	switch v := val.Unwrap().(type) {
+	case *ast.UintLiteralNode:
+		return v.AsFloat(), true
+	case *ast.NegativeIntLiteralNode:
+		return -v.Uint.AsFloat(), true
+	case *ast.FloatLiteralNode:
+		return v.AsFloat(), true
+	case *ast.SpecialFloatLiteralNode:
+		return v.AsFloat(), true
+	case *ast.SignedFloatLiteralNode:
+		return v.AsFloat(), true
+	case *ast.IdentNode:
+		switch strings.ToLower(v.Val) {
+		case "inf", "infinity":
+			return math.Inf(1), true
+		case "nan":
+			return math.NaN(), true
+		}
+	}
+	return 0, false
+}
+
+func stringValue(val *ast.ValueNode) (string, bool) {
+	switch v := val.Unwrap().(type) {
+	case *ast.StringLiteralNode:
+		return v.AsString(), true
+	case *ast.CompoundStringLiteralNode:
+		return v.AsString(), true
+	}
+	return "", false
+}
+
+func textFormatKind(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "a bool"
+	case protoreflect.EnumKind:
+		return "an enum value name or number"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "a string"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "a number"
+	default:
+		return "an integer"
+	}
+}
+
+func textFormatValueKind(val *ast.ValueNode) string {
+	switch v := val.Unwrap().(type) {
+	case *ast.IdentNode:
+		return fmt.Sprintf("identifier %s", v.Val)
+	case *ast.StringLiteralNode, *ast.CompoundStringLiteralNode:
+		return "a string"
+	case *ast.UintLiteralNode, *ast.NegativeIntLiteralNode:
+		return "an integer"
+	case *ast.FloatLiteralNode, *ast.SpecialFloatLiteralNode, *ast.SignedFloatLiteralNode:
+		return "a float"
+	case *ast.ArrayLiteralNode:
+		return "a list"
+	case *ast.MessageLiteralNode:
+		return "a message literal"
+	default:
+		return "a value"
+	}
+}
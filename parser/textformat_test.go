@@ -0,0 +1,205 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestParseTextFormat(t *testing.T) {
+	t.Parallel()
+	source := `# leading comment
+name: "foo.proto"  # trailing comment
+package: 'foo'
+dependency: ["a.proto", "b.proto"]
+message_type {
+  name: "Foo"
+  field { name: "a" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 }
+  field < name: "b", number: 2; label: 3 >
+  options { deprecated: true }
+}
+message_type: [{name: "Bar"}, {name: "Baz"}]
+options { java_package: "x" optimize_for: SPEED double_field_value: -inf }
+`
+	handler := reporter.NewHandler(nil)
+	doc, info, err := ParseTextFormat("test.textpb", strings.NewReader(source), handler)
+	require.NoError(t, err)
+	require.Len(t, doc.GetElements(), 6)
+
+	first := info.NodeInfo(doc.GetElements()[0])
+	assert.Equal(t, "test.textpb:2:1-18", first.String())
+	require.Equal(t, 1, first.LeadingComments().Len())
+	assert.Equal(t, "# leading comment", first.LeadingComments().Index(0).RawText())
+	require.Equal(t, 1, first.TrailingComments().Len())
+	assert.Equal(t, "# trailing comment", first.TrailingComments().Index(0).RawText())
+
+	// double_field_value is not a real field
+	var fd descriptorpb.FileDescriptorProto
+	err = TextFormatToMessage(doc, info, &fd, nil, handler)
+	require.EqualError(t, err, `test.textpb:12:49-67: message google.protobuf.FileOptions has no field named "double_field_value"`)
+
+	source = strings.Replace(source, " double_field_value: -inf", "", 1)
+	doc, info, err = ParseTextFormat("test.textpb", strings.NewReader(source), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	var actual, expected descriptorpb.FileDescriptorProto
+	require.NoError(t, TextFormatToMessage(doc, info, &actual, nil, reporter.NewHandler(nil)))
+	require.NoError(t, prototext.Unmarshal([]byte(source), &expected))
+	assert.True(t, proto.Equal(&expected, &actual), "%v != %v", &expected, &actual)
+}
+
+func TestTextFormatToMessageErrors(t *testing.T) {
+	t.Parallel()
+	source := `name: 1
+foo: 2
+message_type { name: "a" field: { label: LABEL_X number: 3000000000 } }
+name: "z"
+options { [foo.bar]: 1 }
+message_type { options { uninterpreted_option: { name { name_part: "x" } positive_int_value: -1 } } }
+[type.googleapis.com/foo.Bar] {}
+`
+	var errs []string
+	handler := reporter.NewHandler(reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+		errs = append(errs, err.Error())
+		return nil
+	}, nil))
+	doc, info, err := ParseTextFormat("test.textpb", strings.NewReader(source), handler)
+	require.NoError(t, err)
+	var fd descriptorpb.FileDescriptorProto
+	err = TextFormatToMessage(doc, info, &fd, nil, handler)
+	require.ErrorIs(t, err, reporter.ErrInvalidSource)
+	assert.Equal(t, []string{
+		"test.textpb:1:7-8: field name expects a string, got an integer",
+		`test.textpb:2:1-4: message google.protobuf.FileDescriptorProto has no field named "foo"`,
+		`test.textpb:3:42-49: enum google.protobuf.FieldDescriptorProto.Label has no value named "LABEL_X"`,
+		"test.textpb:3:58-68: value 3000000000 is out of range for field number of type int32",
+		"test.textpb:4:1-5: non-repeated field name is already set",
+		"test.textpb:5:11-20: unknown extension foo.bar",
+		"test.textpb:6:55-73: message google.protobuf.UninterpretedOption.NamePart is missing required field is_extension",
+		"test.textpb:6:94-96: value -1 is out of range for field positive_int_value of type uint64",
+		"test.textpb:7:1-30: type URL [type.googleapis.com/foo.Bar] may only be used in a google.protobuf.Any message, not google.protobuf.FileDescriptorProto",
+	}, errs)
+	// valid fields are still set
+	require.Len(t, fd.GetMessageType(), 2)
+	assert.Equal(t, "a", fd.GetMessageType()[0].GetName())
+}
+
+func TestTextFormatMapsAndAny(t *testing.T) {
+	t.Parallel()
+	source := `fields { key: "a" value { string_value: "x" } }
+fields [{ key: "b" value { list_value { values { number_value: 1 } values { bool_value: true } } } }]
+fields { key: "c" }
+fields { key: "d" value { struct_value { fields { key: "e" value { null_value: NULL_VALUE } } } } }
+`
+	doc, info, err := ParseTextFormat("test.textpb", strings.NewReader(source), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	var actual, expected structpb.Struct
+	require.NoError(t, TextFormatToMessage(doc, info, &actual, nil, reporter.NewHandler(nil)))
+	require.NoError(t, prototext.Unmarshal([]byte(source), &expected))
+	assert.True(t, proto.Equal(&expected, &actual), "%v != %v", &expected, &actual)
+
+	source = `[type.googleapis.com/google.protobuf.FieldDescriptorProto] { name: "x" number: 3 }`
+	doc, info, err = ParseTextFormat("test.textpb", strings.NewReader(source), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	var anyMsg anypb.Any
+	require.NoError(t, TextFormatToMessage(doc, info, &anyMsg, nil, reporter.NewHandler(nil)))
+	fld, err := anyMsg.UnmarshalNew()
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&descriptorpb.FieldDescriptorProto{Name: proto.String("x"), Number: proto.Int32(3)}, fld))
+
+	source = `[type.googleapis.com/google.protobuf.FieldDescriptorProto] { name: "x" } type_url: "foo"`
+	doc, info, err = ParseTextFormat("test.textpb", strings.NewReader(source), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	err = TextFormatToMessage(doc, info, &anyMsg, nil, reporter.NewHandler(nil))
+	require.EqualError(t, err, "test.textpb:1:1-59: expanded Any value cannot be combined with other fields")
+}
+
+func TestTextFormatExtensions(t *testing.T) {
+	t.Parallel()
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test.proto"),
+		Package:    proto.String("test"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("label"),
+				Number:   proto.Int32(50000),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Extendee: proto.String(".google.protobuf.FileOptions"),
+			},
+		},
+	}
+	file, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	files := new(protoregistry.Files)
+	require.NoError(t, files.RegisterFile(file))
+	types := dynamicpb.NewTypes(files)
+
+	source := `options { [test.label]: "hello" }`
+	doc, info, err := ParseTextFormat("test.textpb", strings.NewReader(source), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	var fd descriptorpb.FileDescriptorProto
+	require.NoError(t, TextFormatToMessage(doc, info, &fd, types, reporter.NewHandler(nil)))
+	xtd := file.Extensions().ByName("label")
+	var found protoreflect.Value
+	fd.GetOptions().ProtoReflect().Range(func(fld protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		if fld.FullName() == xtd.FullName() {
+			found = val
+		}
+		return true
+	})
+	assert.Equal(t, "hello", found.String())
+
+	source = `[test.label]: "hello"`
+	doc, info, err = ParseTextFormat("test.textpb", strings.NewReader(source), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	err = TextFormatToMessage(doc, info, &fd, types, reporter.NewHandler(nil))
+	require.EqualError(t, err, "test.textpb:1:1-13: extension test.label extends google.protobuf.FileOptions, not google.protobuf.FileDescriptorProto")
+}
+
+func TestMessageToTextFormat(t *testing.T) {
+	t.Parallel()
+	msg := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("foo.proto"),
+		Dependency: []string{"a.proto", "b.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Foo"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("a"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum(), DefaultValue: proto.String("-inf")},
+			}},
+		},
+		Options: &descriptorpb.FileOptions{JavaPackage: proto.String("x\n\"y\"")},
+	}
+	doc, info, err := MessageToTextFormat("test.textpb", msg)
+	require.NoError(t, err)
+	require.Len(t, doc.GetElements(), 5)
+	var roundTripped descriptorpb.FileDescriptorProto
+	require.NoError(t, TextFormatToMessage(doc, info, &roundTripped, nil, reporter.NewHandler(nil)))
+	assert.True(t, proto.Equal(msg, &roundTripped), "%v != %v", msg, &roundTripped)
+}
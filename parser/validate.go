@@ -17,18 +17,193 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sort"
+	"strings"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 
 	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
 	"github.com/kralicky/protocompile/protointernal"
 	"github.com/kralicky/protocompile/reporter"
 	"github.com/kralicky/protocompile/walk"
 )
 
+// Validate performs the same basic validation that ResultFromAST does, but on
+// a file descriptor proto that was not produced by this package, such as one
+// synthesized by a protoc plugin. Since there is no AST, diagnostics are
+// reported with an ast.PathSpan, which identifies the offending element by its
+// path in fd instead of by its position in a source file.
+//
+// Options that have already been interpreted are checked in addition to
+// uninterpreted ones. Like ResultFromAST, this does not check anything that
+// requires resolving symbols. For that, the file can be wrapped with
+// ResultWithoutAST and linked with linker.Link.
+//
+// The given handler is used to report any errors or warnings encountered. If
+// any errors are reported, this function returns a non-nil error. The limits
+// checked can be raised with WithValidationLimits. The given fd is not
+// modified.
+func Validate(fd *descriptorpb.FileDescriptorProto, handler *reporter.Handler, opts ...ParserOption) error {
+	po := newParseOptions(opts)
+	res := &result{proto: fd, limits: po.validationLimits}
+	if validateSyntax(res, handler) != nil {
+		return handler.Error()
+	}
+	validateLimits(res, handler)
+	validateNumbers(res, handler)
+	validateBasic(res, handler)
+	return handler.Error()
+}
+
+// span returns the span of the given node. If res has no AST, it instead
+// returns the span for the element at the given path, extended with elems.
+func (r *result) span(node ast.Node, path protoreflect.SourcePath, elems ...int32) ast.SourceSpan {
+	if r.file == nil {
+		// NB: The three-index slice makes sure that appending always copies,
+		// since the walker may reuse the backing array of path.
+		return ast.PathSpan{Filename: r.proto.GetName(), Path: append(path[:len(path):len(path)], elems...)}
+	}
+	return r.file.NodeInfo(node)
+}
+
+// validateLimits checks the limits that ResultFromAST checks while it creates
+// the descriptor proto. It is only needed for results without an AST.
+func validateLimits(res *result, handler *reporter.Handler) {
+	fd := res.proto
+	if fd.Package != nil {
+		pkgName := fd.GetPackage()
+		if len(pkgName) >= res.limits.MaxPackageNameLength {
+			if handler.HandleErrorf(res.span(nil, nil, descpath.FilePackageTag), "package name (with whitespace removed) must be less than %d characters long", res.limits.MaxPackageNameLength) != nil {
+				return
+			}
+		}
+		if strings.Count(pkgName, ".") > res.limits.MaxPackageNamePeriods {
+			if handler.HandleErrorf(res.span(nil, nil, descpath.FilePackageTag), "package name may not contain more than %d periods", res.limits.MaxPackageNamePeriods) != nil {
+				return
+			}
+		}
+	}
+	_ = walk.DescriptorProtosWithPath(fd, func(_ protoreflect.FullName, path protoreflect.SourcePath, d proto.Message) error {
+		if _, ok := d.(*descriptorpb.DescriptorProto); !ok {
+			return nil
+		}
+		// Each level of nesting adds two elements to the path. Only the
+		// outermost message that is too deep is reported.
+		if len(path)/2 == res.limits.MaxMessageNestingDepth {
+			return handler.HandleErrorf(res.span(nil, path), "message nesting depth must be less than %d", res.limits.MaxMessageNestingDepth)
+		}
+		return nil
+	})
+}
+
+// validateSyntax checks the syntax of a result without an AST. ResultFromAST
+// checks it while it creates the descriptor proto. Since nothing else can be
+// validated without knowing the syntax, the caller should stop if this
+// returns an error, even if the handler did not.
+func validateSyntax(res *result, handler *reporter.Handler) error {
+	switch syntax := res.proto.GetSyntax(); syntax {
+	case "", "proto2", "proto3", "editions":
+		return nil
+	default:
+		span := res.span(nil, nil, descpath.FileSyntaxTag)
+		_ = handler.HandleErrorf(span, `syntax value %q must be "proto2", "proto3", or "editions"`, syntax)
+		return reporter.ErrInvalidSource
+	}
+}
+
+// validateNumbers checks the field numbers and the extension and reserved
+// ranges that ResultFromAST checks while it creates the descriptor proto. It
+// is only needed for results without an AST.
+func validateNumbers(res *result, handler *reporter.Handler) {
+	_ = walk.DescriptorProtosWithPath(res.proto, func(name protoreflect.FullName, path protoreflect.SourcePath, d proto.Message) error {
+		switch d := d.(type) {
+		case *descriptorpb.DescriptorProto:
+			maxTag := int32(protointernal.MaxNormalTag)
+			if isMessageSetWireFormat(res, handler, name, d) {
+				maxTag = protointernal.MaxTag
+			}
+			for i, fld := range d.Field {
+				span := res.span(nil, path, descpath.MessageFieldsTag, int32(i), descpath.FieldNumberTag)
+				if err := checkTag(span, fld.GetNumber(), maxTag); err != nil {
+					if err := handler.HandleError(err); err != nil {
+						return err
+					}
+				}
+			}
+			for i, r := range d.ExtensionRange {
+				rangePath := append(path[:len(path):len(path)], descpath.MessageExtensionRangesTag, int32(i))
+				if err := validateRange(res, handler, rangePath, r.GetStart(), r.GetEnd()-1, 1, maxTag); err != nil {
+					return err
+				}
+			}
+			for i, r := range d.ReservedRange {
+				rangePath := append(path[:len(path):len(path)], descpath.MessageReservedRangesTag, int32(i))
+				if err := validateRange(res, handler, rangePath, r.GetStart(), r.GetEnd()-1, 1, maxTag); err != nil {
+					return err
+				}
+			}
+		case *descriptorpb.FieldDescriptorProto:
+			// fields of messages were checked above, with the message
+			if d.Extendee == nil {
+				return nil
+			}
+			span := res.span(nil, path, descpath.FieldNumberTag)
+			if err := checkTag(span, d.GetNumber(), protointernal.MaxTag); err != nil {
+				return handler.HandleError(err)
+			}
+		case *descriptorpb.EnumDescriptorProto:
+			for i, r := range d.ReservedRange {
+				rangePath := append(path[:len(path):len(path)], descpath.EnumReservedRangesTag, int32(i))
+				if err := validateRange(res, handler, rangePath, r.GetStart(), r.GetEnd(), math.MinInt32, math.MaxInt32); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// isMessageSetWireFormat reports whether the given message of a result without
+// an AST uses the message-set wire format, whether or not its options have been
+// interpreted.
+func isMessageSetWireFormat(res *result, handler *reporter.Handler, name protoreflect.FullName, md *descriptorpb.DescriptorProto) bool {
+	if md.GetOptions().GetMessageSetWireFormat() {
+		return true
+	}
+	uo := md.GetOptions().GetUninterpretedOption()
+	index, err := protointernal.FindOption(res, handler, fmt.Sprintf("message %s", name), uo, "message_set_wire_format")
+	return err == nil && index >= 0 && uo[index].GetIdentifierValue() == "true"
+}
+
+// validateRange checks the bounds of a range, whose end is inclusive, in a
+// result without an AST. The path identifies the range in the file.
+func validateRange(res *result, handler *reporter.Handler, path protoreflect.SourcePath, start, end, minVal, maxVal int32) error {
+	checkOrder := true
+	if start < minVal || start > maxVal {
+		checkOrder = false
+		span := res.span(nil, path, descpath.ReservedRangeStartTag)
+		if err := handler.HandleErrorf(span, "range start %d is out of range: should be between %d and %d", start, minVal, maxVal); err != nil {
+			return err
+		}
+	}
+	if end < minVal || end > maxVal {
+		checkOrder = false
+		span := res.span(nil, path, descpath.ReservedRangeEndTag)
+		if err := handler.HandleErrorf(span, "range end %d is out of range: should be between %d and %d", end, minVal, maxVal); err != nil {
+			return err
+		}
+	}
+	if checkOrder && start > end {
+		span := res.span(nil, path, descpath.ReservedRangeStartTag)
+		return handler.HandleErrorf(span, "range, %d to %d, is invalid: start must be <= end", start, end)
+	}
+	return nil
+}
+
 func validateBasic(res *result, handler *reporter.Handler) {
 	fd := res.proto
 	var syntax protoreflect.Syntax
@@ -39,47 +214,51 @@ func validateBasic(res *result, handler *reporter.Handler) {
 		syntax = protoreflect.Proto3
 	case "editions":
 		syntax = protoreflect.Editions
-		// TODO: default: error?
 	}
 
 	if err := validateImports(res, handler); err != nil {
 		return
 	}
 
-	if err := validateNoFeatures(res, syntax, "file options", fd.Options.GetUninterpretedOption(), handler); err != nil {
+	fileOptsPath := protoreflect.SourcePath{descpath.FileOptionsTag}
+	if err := validateNoFeatures(res, syntax, "file options", fd.Options, fileOptsPath, handler); err != nil {
 		return
 	}
 
-	_ = walk.DescriptorProtos(fd,
-		func(name protoreflect.FullName, d proto.Message) error {
+	_ = walk.DescriptorProtosWithPath(fd,
+		func(name protoreflect.FullName, path protoreflect.SourcePath, d proto.Message) error {
 			switch d := d.(type) {
 			case *descriptorpb.DescriptorProto:
-				if err := validateMessage(res, syntax, name, d, handler); err != nil {
+				if err := validateMessage(res, syntax, name, path, d, handler); err != nil {
 					// exit func is not called when enter returns error
 					return err
 				}
 			case *descriptorpb.FieldDescriptorProto:
-				if err := validateField(res, syntax, name, d, handler); err != nil {
+				if err := validateField(res, syntax, name, path, d, handler); err != nil {
 					return err
 				}
 			case *descriptorpb.OneofDescriptorProto:
-				if err := validateNoFeatures(res, syntax, fmt.Sprintf("oneof %s", name), d.Options.GetUninterpretedOption(), handler); err != nil {
+				optsPath := append(path[:len(path):len(path)], descpath.OneofOptionsTag)
+				if err := validateNoFeatures(res, syntax, fmt.Sprintf("oneof %s", name), d.Options, optsPath, handler); err != nil {
 					return err
 				}
 			case *descriptorpb.EnumDescriptorProto:
-				if err := validateEnum(res, syntax, name, d, handler); err != nil {
+				if err := validateEnum(res, syntax, name, path, d, handler); err != nil {
 					return err
 				}
 			case *descriptorpb.EnumValueDescriptorProto:
-				if err := validateNoFeatures(res, syntax, fmt.Sprintf("enum value %s", name), d.Options.GetUninterpretedOption(), handler); err != nil {
+				optsPath := append(path[:len(path):len(path)], descpath.EnumValOptionsTag)
+				if err := validateNoFeatures(res, syntax, fmt.Sprintf("enum value %s", name), d.Options, optsPath, handler); err != nil {
 					return err
 				}
 			case *descriptorpb.ServiceDescriptorProto:
-				if err := validateNoFeatures(res, syntax, fmt.Sprintf("service %s", name), d.Options.GetUninterpretedOption(), handler); err != nil {
+				optsPath := append(path[:len(path):len(path)], descpath.ServiceOptionsTag)
+				if err := validateNoFeatures(res, syntax, fmt.Sprintf("service %s", name), d.Options, optsPath, handler); err != nil {
 					return err
 				}
 			case *descriptorpb.MethodDescriptorProto:
-				if err := validateNoFeatures(res, syntax, fmt.Sprintf("method %s", name), d.Options.GetUninterpretedOption(), handler); err != nil {
+				optsPath := append(path[:len(path):len(path)], descpath.MethodOptionsTag)
+				if err := validateNoFeatures(res, syntax, fmt.Sprintf("method %s", name), d.Options, optsPath, handler); err != nil {
 					return err
 				}
 			}
@@ -90,6 +269,13 @@ func validateBasic(res *result, handler *reporter.Handler) {
 func validateImports(res *result, handler *reporter.Handler) error {
 	fileNode := res.file
 	if fileNode == nil {
+		imports := make(map[string]struct{}, len(res.proto.Dependency))
+		for i, name := range res.proto.Dependency {
+			if _, ok := imports[name]; ok {
+				return handler.HandleErrorf(res.span(nil, nil, descpath.FileDependencyTag, int32(i)), "%q was already imported", name)
+			}
+			imports[name] = struct{}{}
+		}
 		return nil
 	}
 	imports := make(map[string]ast.SourcePos)
@@ -108,45 +294,107 @@ func validateImports(res *result, handler *reporter.Handler) error {
 	return nil
 }
 
-func validateNoFeatures(res *result, syntax protoreflect.Syntax, scope string, opts []*descriptorpb.UninterpretedOption, handler *reporter.Handler) error {
+// optionsMessage is implemented by all of the options messages in
+// descriptor.proto.
+type optionsMessage interface {
+	proto.Message
+	GetUninterpretedOption() []*descriptorpb.UninterpretedOption
+}
+
+func validateNoFeatures(res *result, syntax protoreflect.Syntax, scope string, opts optionsMessage, optsPath protoreflect.SourcePath, handler *reporter.Handler) error {
 	if syntax == protoreflect.Editions {
 		// Editions is allowed to use features
 		return nil
 	}
-	if index, err := protointernal.FindFirstOption(res, handler, scope, opts, "features"); err != nil {
+	uninterpreted := opts.GetUninterpretedOption()
+	if index, err := protointernal.FindFirstOption(res, handler, scope, uninterpreted, "features"); err != nil {
 		return err
 	} else if index >= 0 {
-		optNode := res.OptionNode(opts[index])
-		optNameNodeInfo := res.file.NodeInfo(optNode.GetName())
-		if err := handler.HandleErrorf(optNameNodeInfo, "%s: option 'features' may only be used with editions but file uses %s syntax", scope, syntax); err != nil {
+		optNode := res.OptionNode(uninterpreted[index])
+		optNameSpan := res.span(optNode.GetName(), optsPath, descpath.UninterpretedOptionsTag, int32(index), descpath.UninterpretedNameTag)
+		if err := handler.HandleErrorf(optNameSpan, "%s: option 'features' may only be used with editions but file uses %s syntax", scope, syntax); err != nil {
 			return err
 		}
+	} else if msg := opts.ProtoReflect(); msg.IsValid() {
+		// features may also have already been interpreted
+		if fld := msg.Descriptor().Fields().ByName("features"); fld != nil && msg.Has(fld) {
+			span := res.span(nil, optsPath, int32(fld.Number()))
+			if err := handler.HandleErrorf(span, "%s: option 'features' may only be used with editions but file uses %s syntax", scope, syntax); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
-func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.FullName, md *descriptorpb.DescriptorProto, handler *reporter.Handler) error {
+// validateVisibility checks that the "export" or "local" modifier of the given
+// message or enum, if any, is only used in files that use edition 2024 or later.
+func validateVisibility(res *result, scope string, path protoreflect.SourcePath, d proto.Message, handler *reporter.Handler) error {
+	vis := protointernal.GetVisibility(d)
+	if vis == protointernal.VisibilityUnset ||
+		res.proto.GetEdition() >= descriptorpb.Edition_EDITION_2024 {
+		return nil
+	}
+	if res.proto.GetSyntax() == "editions" && res.proto.GetEdition() == descriptorpb.Edition_EDITION_UNKNOWN {
+		// the edition was not recognized, which has already been reported
+		return nil
+	}
+	modifier := "export"
+	if vis == protointernal.VisibilityLocal {
+		modifier = "local"
+	}
+	var node ast.Node
+	var tag int32
+	switch d := d.(type) {
+	case *descriptorpb.DescriptorProto:
+		tag = descpath.MessageVisibilityTag
+		if res.file != nil {
+			msgNode, ok := res.MessageNode(d).Unwrap().(*ast.MessageNode)
+			if !ok || msgNode.Visibility == nil {
+				return nil
+			}
+			node = msgNode.Visibility
+		}
+	case *descriptorpb.EnumDescriptorProto:
+		tag = descpath.EnumVisibilityTag
+		if res.file != nil {
+			visNode := res.EnumNode(d).GetVisibility()
+			if visNode == nil {
+				return nil
+			}
+			node = visNode
+		}
+	}
+	return handler.HandleErrorf(res.span(node, path, tag), "%s: %q modifier is only allowed in edition 2024 or later", scope, modifier)
+}
+
+func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.FullName, path protoreflect.SourcePath, md *descriptorpb.DescriptorProto, handler *reporter.Handler) error {
 	scope := fmt.Sprintf("message %s", name)
 
 	if syntax == protoreflect.Proto3 && len(md.ExtensionRange) > 0 {
 		n := res.ExtensionRangeNode(md.ExtensionRange[0])
-		nInfo := res.file.NodeInfo(n)
+		nInfo := res.span(n, path, descpath.MessageExtensionRangesTag, 0)
 		if err := handler.HandleErrorf(nInfo, "%s: extension ranges are not allowed in proto3", scope); err != nil {
 			return err
 		}
 	}
 
+	optsPath := append(path[:len(path):len(path)], descpath.MessageOptionsTag)
 	if index, err := protointernal.FindOption(res, handler, scope, md.Options.GetUninterpretedOption(), "map_entry"); err != nil {
 		return err
 	} else if index >= 0 {
 		optNode := res.OptionNode(md.Options.GetUninterpretedOption()[index])
-		optNameNodeInfo := res.file.NodeInfo(optNode.GetName())
+		optNameNodeInfo := res.span(optNode.GetName(), optsPath, descpath.UninterpretedOptionsTag, int32(index), descpath.UninterpretedNameTag)
 		if err := handler.HandleErrorf(optNameNodeInfo, "%s: map_entry option should not be set explicitly; use map type instead", scope); err != nil {
 			return err
 		}
 	}
 
-	if err := validateNoFeatures(res, syntax, scope, md.Options.GetUninterpretedOption(), handler); err != nil {
+	if err := validateNoFeatures(res, syntax, scope, md.Options, optsPath, handler); err != nil {
+		return err
+	}
+
+	if err := validateVisibility(res, scope, path, md, handler); err != nil {
 		return err
 	}
 
@@ -154,12 +402,12 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 	rsvd := make(tagRanges, len(md.ReservedRange))
 	for i, r := range md.ReservedRange {
 		n := res.MessageReservedRangeNode(r)
-		rsvd[i] = tagRange{start: r.GetStart(), end: r.GetEnd(), node: n}
+		rsvd[i] = tagRange{start: r.GetStart(), end: r.GetEnd(), node: n, index: int32(i)}
 	}
 	sort.Sort(rsvd)
 	for i := 1; i < len(rsvd); i++ {
 		if rsvd[i].start < rsvd[i-1].end {
-			rangeNodeInfo := res.file.NodeInfo(rsvd[i].node)
+			rangeNodeInfo := res.span(rsvd[i].node, path, descpath.MessageReservedRangesTag, rsvd[i].index)
 			if err := handler.HandleErrorf(rangeNodeInfo, "%s: reserved ranges overlap: %d to %d and %d to %d", scope, rsvd[i-1].start, rsvd[i-1].end-1, rsvd[i].start, rsvd[i].end-1); err != nil {
 				return err
 			}
@@ -169,16 +417,17 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 	// extensions ranges should not overlap
 	exts := make(tagRanges, len(md.ExtensionRange))
 	for i, r := range md.ExtensionRange {
-		if err := validateNoFeatures(res, syntax, scope, r.Options.GetUninterpretedOption(), handler); err != nil {
+		rangeOptsPath := append(path[:len(path):len(path)], descpath.MessageExtensionRangesTag, int32(i), descpath.ExtensionRangeOptionsTag)
+		if err := validateNoFeatures(res, syntax, scope, r.Options, rangeOptsPath, handler); err != nil {
 			return err
 		}
 		n := res.ExtensionRangeNode(r)
-		exts[i] = tagRange{start: r.GetStart(), end: r.GetEnd(), node: n}
+		exts[i] = tagRange{start: r.GetStart(), end: r.GetEnd(), node: n, index: int32(i)}
 	}
 	sort.Sort(exts)
 	for i := 1; i < len(exts); i++ {
 		if exts[i].start < exts[i-1].end {
-			rangeNodeInfo := res.file.NodeInfo(exts[i].node)
+			rangeNodeInfo := res.span(exts[i].node, path, descpath.MessageExtensionRangesTag, exts[i].index)
 			if err := handler.HandleErrorf(rangeNodeInfo, "%s: extension ranges overlap: %d to %d and %d to %d", scope, exts[i-1].start, exts[i-1].end-1, exts[i].start, exts[i].end-1); err != nil {
 				return err
 			}
@@ -192,11 +441,9 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 			exts[j].start >= rsvd[i].start && exts[j].start < rsvd[i].end {
 			var span ast.SourceSpan
 			if rsvd[i].start >= exts[j].start && rsvd[i].start < exts[j].end {
-				rangeNodeInfo := res.file.NodeInfo(rsvd[i].node)
-				span = rangeNodeInfo
+				span = res.span(rsvd[i].node, path, descpath.MessageReservedRangesTag, rsvd[i].index)
 			} else {
-				rangeNodeInfo := res.file.NodeInfo(exts[j].node)
-				span = rangeNodeInfo
+				span = res.span(exts[j].node, path, descpath.MessageExtensionRangesTag, exts[j].index)
 			}
 			// ranges overlap
 			if err := handler.HandleErrorf(span, "%s: extension range %d to %d overlaps reserved range %d to %d", scope, exts[j].start, exts[j].end-1, rsvd[i].start, rsvd[i].end-1); err != nil {
@@ -213,11 +460,11 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 	// now, check that fields don't re-use tags and don't try to use extension
 	// or reserved ranges or reserved names
 	rsvdNames := map[string]struct{}{}
-	for _, n := range md.ReservedName {
+	for i, n := range md.ReservedName {
 		// validate reserved name while we're here
 		if !isIdentifier(n) {
 			node := findMessageReservedNameNode(res.MessageNode(md), n)
-			nodeInfo := res.file.NodeInfo(node)
+			nodeInfo := res.span(node, path, descpath.MessageReservedNamesTag, int32(i))
 			if err := handler.HandleErrorf(nodeInfo, "%s: reserved name %q is not a valid identifier", scope, n); err != nil {
 				return err
 			}
@@ -225,16 +472,16 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 		rsvdNames[n] = struct{}{}
 	}
 	fieldTags := map[int32]string{}
-	for _, fld := range md.Field {
+	for i, fld := range md.Field {
 		fn := res.FieldNode(fld)
 		if _, ok := rsvdNames[fld.GetName()]; ok {
-			fieldNameNodeInfo := res.file.NodeInfo(fn.GetName())
+			fieldNameNodeInfo := res.span(fn.GetName(), path, descpath.MessageFieldsTag, int32(i), descpath.FieldNameTag)
 			if err := handler.HandleErrorf(fieldNameNodeInfo, "%s: field %s is using a reserved name", scope, fld.GetName()); err != nil {
 				return err
 			}
 		}
 		if existing := fieldTags[fld.GetNumber()]; existing != "" {
-			fieldTagNodeInfo := res.file.NodeInfo(fn.GetTag())
+			fieldTagNodeInfo := res.span(fn.GetTag(), path, descpath.MessageFieldsTag, int32(i), descpath.FieldNumberTag)
 			if err := handler.HandleErrorf(fieldTagNodeInfo, "%s: fields %s and %s both have the same tag %d", scope, existing, fld.GetName(), fld.GetNumber()); err != nil {
 				return err
 			}
@@ -243,7 +490,7 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 		// check reserved ranges
 		r := sort.Search(len(rsvd), func(index int) bool { return rsvd[index].end > fld.GetNumber() })
 		if r < len(rsvd) && rsvd[r].start <= fld.GetNumber() {
-			fieldTagNodeInfo := res.file.NodeInfo(fn.GetTag())
+			fieldTagNodeInfo := res.span(fn.GetTag(), path, descpath.MessageFieldsTag, int32(i), descpath.FieldNumberTag)
 			if err := handler.HandleErrorf(fieldTagNodeInfo, "%s: field %s is using tag %d which is in reserved range %d to %d", scope, fld.GetName(), fld.GetNumber(), rsvd[r].start, rsvd[r].end-1); err != nil {
 				return err
 			}
@@ -251,7 +498,7 @@ func validateMessage(res *result, syntax protoreflect.Syntax, name protoreflect.
 		// and check extension ranges
 		e := sort.Search(len(exts), func(index int) bool { return exts[index].end > fld.GetNumber() })
 		if e < len(exts) && exts[e].start <= fld.GetNumber() {
-			fieldTagNodeInfo := res.file.NodeInfo(fn.GetTag())
+			fieldTagNodeInfo := res.span(fn.GetTag(), path, descpath.MessageFieldsTag, int32(i), descpath.FieldNumberTag)
 			if err := handler.HandleErrorf(fieldTagNodeInfo, "%s: field %s is using tag %d which is in extension range %d to %d", scope, fld.GetName(), fld.GetNumber(), exts[e].start, exts[e].end-1); err != nil {
 				return err
 			}
@@ -318,12 +565,16 @@ func findReservedNameNode[T interface {
 	return parent
 }
 
-func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.FullName, ed *descriptorpb.EnumDescriptorProto, handler *reporter.Handler) error {
+func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.FullName, path protoreflect.SourcePath, ed *descriptorpb.EnumDescriptorProto, handler *reporter.Handler) error {
 	scope := fmt.Sprintf("enum %s", name)
 
+	if err := validateVisibility(res, scope, path, ed, handler); err != nil {
+		return err
+	}
+
 	if len(ed.Value) == 0 {
 		enNode := res.EnumNode(ed)
-		enNodeInfo := res.file.NodeInfo(enNode.GetName())
+		enNodeInfo := res.span(enNode.GetName(), path, descpath.EnumNameTag)
 
 		if ast.ExtendedSyntaxEnabled {
 			handler.HandleWarningWithPos(enNodeInfo,
@@ -333,16 +584,19 @@ func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.Ful
 		}
 	}
 
-	if err := validateNoFeatures(res, syntax, scope, ed.Options.GetUninterpretedOption(), handler); err != nil {
+	optsPath := append(path[:len(path):len(path)], descpath.EnumOptionsTag)
+	if err := validateNoFeatures(res, syntax, scope, ed.Options, optsPath, handler); err != nil {
 		return err
 	}
 
 	allowAlias := false
-	var allowAliasOpt *descriptorpb.UninterpretedOption
+	var allowAliasSpan ast.SourceSpan
 	if index, err := protointernal.FindOption(res, handler, scope, ed.Options.GetUninterpretedOption(), "allow_alias"); err != nil {
 		return err
 	} else if index >= 0 {
-		allowAliasOpt = ed.Options.UninterpretedOption[index]
+		allowAliasOpt := ed.Options.UninterpretedOption[index]
+		optNode := res.OptionNode(allowAliasOpt)
+		allowAliasSpan = res.span(optNode.GetVal(), optsPath, descpath.UninterpretedOptionsTag, int32(index))
 		valid := false
 		if allowAliasOpt.IdentifierValue != nil {
 			if allowAliasOpt.GetIdentifierValue() == "true" {
@@ -353,25 +607,27 @@ func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.Ful
 			}
 		}
 		if !valid {
-			optNode := res.OptionNode(allowAliasOpt)
-			optNodeInfo := res.file.NodeInfo(optNode.GetVal())
-			if err := handler.HandleErrorf(optNodeInfo, "%s: expecting bool value for allow_alias option", scope); err != nil {
+			if err := handler.HandleErrorf(allowAliasSpan, "%s: expecting bool value for allow_alias option", scope); err != nil {
 				return err
 			}
 		}
+	} else if ed.Options != nil && ed.Options.AllowAlias != nil {
+		// already interpreted
+		allowAlias = ed.Options.GetAllowAlias()
+		allowAliasSpan = res.span(nil, optsPath, int32(ed.Options.ProtoReflect().Descriptor().Fields().ByName("allow_alias").Number()))
 	}
 
 	// check for aliases
 	vals := map[int32]string{}
 	hasAlias := false
-	for _, evd := range ed.Value {
+	for i, evd := range ed.Value {
 		existing := vals[evd.GetNumber()]
 		if existing != "" {
 			if allowAlias {
 				hasAlias = true
 			} else {
 				evNode := res.EnumValueNode(evd)
-				evNodeInfo := res.file.NodeInfo(evNode.GetNumber())
+				evNodeInfo := res.span(evNode.GetNumber(), path, descpath.EnumValuesTag, int32(i), descpath.EnumValNumberTag)
 				if err := handler.HandleErrorf(evNodeInfo, "%s: values %s and %s both have the same numeric value %d; use allow_alias option if intentional", scope, existing, evd.GetName(), evd.GetNumber()); err != nil {
 					return err
 				}
@@ -380,21 +636,19 @@ func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.Ful
 		vals[evd.GetNumber()] = evd.GetName()
 	}
 	if allowAlias && !hasAlias {
-		optNode := res.OptionNode(allowAliasOpt)
-		optNodeInfo := res.file.NodeInfo(optNode.GetVal())
-		handler.HandleWarningf(optNodeInfo, "%s: allow_alias is true but no values are aliases", scope)
+		handler.HandleWarningf(allowAliasSpan, "%s: allow_alias is true but no values are aliases", scope)
 	}
 
 	// reserved ranges should not overlap
 	rsvd := make(tagRanges, len(ed.ReservedRange))
 	for i, r := range ed.ReservedRange {
 		n := res.EnumReservedRangeNode(r)
-		rsvd[i] = tagRange{start: r.GetStart(), end: r.GetEnd(), node: n}
+		rsvd[i] = tagRange{start: r.GetStart(), end: r.GetEnd(), node: n, index: int32(i)}
 	}
 	sort.Sort(rsvd)
 	for i := 1; i < len(rsvd); i++ {
 		if rsvd[i].start <= rsvd[i-1].end {
-			rangeNodeInfo := res.file.NodeInfo(rsvd[i].node)
+			rangeNodeInfo := res.span(rsvd[i].node, path, descpath.EnumReservedRangesTag, rsvd[i].index)
 			if err := handler.HandleErrorf(rangeNodeInfo, "%s: reserved ranges overlap: %d to %d and %d to %d", scope, rsvd[i-1].start, rsvd[i-1].end, rsvd[i].start, rsvd[i].end); err != nil {
 				return err
 			}
@@ -404,21 +658,21 @@ func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.Ful
 	// now, check that fields don't re-use tags and don't try to use extension
 	// or reserved ranges or reserved names
 	rsvdNames := map[string]struct{}{}
-	for _, n := range ed.ReservedName {
+	for i, n := range ed.ReservedName {
 		// validate reserved name while we're here
 		if !isIdentifier(n) {
 			node := findEnumReservedNameNode(res.EnumNode(ed), n)
-			nodeInfo := res.file.NodeInfo(node)
+			nodeInfo := res.span(node, path, descpath.EnumReservedNamesTag, int32(i))
 			if err := handler.HandleErrorf(nodeInfo, "%s: reserved name %q is not a valid identifier", scope, n); err != nil {
 				return err
 			}
 		}
 		rsvdNames[n] = struct{}{}
 	}
-	for _, ev := range ed.Value {
+	for i, ev := range ed.Value {
 		evn := res.EnumValueNode(ev)
 		if _, ok := rsvdNames[ev.GetName()]; ok {
-			enumValNodeInfo := res.file.NodeInfo(evn.GetName())
+			enumValNodeInfo := res.span(evn.GetName(), path, descpath.EnumValuesTag, int32(i), descpath.EnumValNameTag)
 			if err := handler.HandleErrorf(enumValNodeInfo, "%s: value %s is using a reserved name", scope, ev.GetName()); err != nil {
 				return err
 			}
@@ -426,7 +680,7 @@ func validateEnum(res *result, syntax protoreflect.Syntax, name protoreflect.Ful
 		// check reserved ranges
 		r := sort.Search(len(rsvd), func(index int) bool { return rsvd[index].end >= ev.GetNumber() })
 		if r < len(rsvd) && rsvd[r].start <= ev.GetNumber() {
-			enumValNodeInfo := res.file.NodeInfo(evn.GetNumber())
+			enumValNodeInfo := res.span(evn.GetNumber(), path, descpath.EnumValuesTag, int32(i), descpath.EnumValNumberTag)
 			if err := handler.HandleErrorf(enumValNodeInfo, "%s: value %s is using number %d which is in reserved range %d to %d", scope, ev.GetName(), ev.GetNumber(), rsvd[r].start, rsvd[r].end); err != nil {
 				return err
 			}
@@ -445,7 +699,7 @@ func findEnumReservedNameNode(enumNode ast.Node, name string) ast.Node {
 	return findReservedNameNode(enumNode, decls, name)
 }
 
-func validateField(res *result, syntax protoreflect.Syntax, name protoreflect.FullName, fld *descriptorpb.FieldDescriptorProto, handler *reporter.Handler) error {
+func validateField(res *result, syntax protoreflect.Syntax, name protoreflect.FullName, path protoreflect.SourcePath, fld *descriptorpb.FieldDescriptorProto, handler *reporter.Handler) error {
 	var scope string
 	if fld.Extendee != nil {
 		scope = fmt.Sprintf("extension %s", name)
@@ -454,21 +708,24 @@ func validateField(res *result, syntax protoreflect.Syntax, name protoreflect.Fu
 	}
 
 	node := res.FieldNode(fld)
+	optsPath := append(path[:len(path):len(path)], descpath.FieldOptionsTag)
 	if syntax != protoreflect.Proto2 {
 		if fld.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP {
-			groupNodeInfo := res.file.NodeInfo(node.GetGroup().GetKeyword())
+			groupNodeInfo := res.span(node.GetGroup().GetKeyword(), path, descpath.FieldTypeTag)
 			if err := handler.HandleErrorf(groupNodeInfo, "%s: groups are not allowed in proto3 or editions", scope); err != nil {
 				return err
 			}
 		} else if fld.Label != nil && fld.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
-			fieldLabelNodeInfo := res.file.NodeInfo(node.GetLabel())
+			fieldLabelNodeInfo := res.span(node.GetLabel(), path, descpath.FieldLabelTag)
 			if err := handler.HandleErrorf(fieldLabelNodeInfo, "%s: label 'required' is not allowed in proto3 or editions", scope); err != nil {
 				return err
 			}
 		}
 		if syntax == protoreflect.Editions {
-			if fld.Label != nil && fld.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL {
-				fieldLabelNodeInfo := res.file.NodeInfo(node.GetLabel())
+			// Without an AST, the label is always present, so an optional
+			// label can't be told apart from an omitted one.
+			if res.file != nil && fld.Label != nil && fld.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL {
+				fieldLabelNodeInfo := res.span(node.GetLabel(), path, descpath.FieldLabelTag)
 				if err := handler.HandleErrorf(fieldLabelNodeInfo, "%s: label 'optional' is not allowed in editions; use option features.field_presence instead", scope); err != nil {
 					return err
 				}
@@ -477,44 +734,55 @@ func validateField(res *result, syntax protoreflect.Syntax, name protoreflect.Fu
 				return err
 			} else if index >= 0 {
 				optNode := res.OptionNode(fld.Options.GetUninterpretedOption()[index])
-				optNameNodeInfo := res.file.NodeInfo(optNode.GetName())
+				optNameNodeInfo := res.span(optNode.GetName(), optsPath, descpath.UninterpretedOptionsTag, int32(index), descpath.UninterpretedNameTag)
 				if err := handler.HandleErrorf(optNameNodeInfo, "%s: packed option is not allowed in editions; use option features.repeated_field_encoding instead", scope); err != nil {
 					return err
 				}
+			} else if fld.Options != nil && fld.Options.Packed != nil {
+				optSpan := res.span(nil, optsPath, int32(fld.Options.ProtoReflect().Descriptor().Fields().ByName("packed").Number()))
+				if err := handler.HandleErrorf(optSpan, "%s: packed option is not allowed in editions; use option features.repeated_field_encoding instead", scope); err != nil {
+					return err
+				}
 			}
 		} else if syntax == protoreflect.Proto3 {
 			if index, err := protointernal.FindOption(res, handler, scope, fld.Options.GetUninterpretedOption(), "default"); err != nil {
 				return err
 			} else if index >= 0 {
 				optNode := res.OptionNode(fld.Options.GetUninterpretedOption()[index])
-				optNameNodeInfo := res.file.NodeInfo(optNode.GetName())
+				optNameNodeInfo := res.span(optNode.GetName(), optsPath, descpath.UninterpretedOptionsTag, int32(index), descpath.UninterpretedNameTag)
 				if err := handler.HandleErrorf(optNameNodeInfo, "%s: default values are not allowed in proto3", scope); err != nil {
 					return err
 				}
+			} else if fld.DefaultValue != nil {
+				if err := handler.HandleErrorf(res.span(nil, path, descpath.FieldDefaultTag), "%s: default values are not allowed in proto3", scope); err != nil {
+					return err
+				}
 			}
 		}
 	} else {
 		if fld.Label == nil && fld.OneofIndex == nil {
-			fieldNameNodeInfo := res.file.NodeInfo(node.GetLabel())
+			fieldNameNodeInfo := res.span(node.GetLabel(), path, descpath.FieldLabelTag)
 			if err := handler.HandleErrorf(fieldNameNodeInfo, "%s: field has no label; proto2 requires explicit 'optional' label", scope); err != nil {
 				return err
 			}
 		}
 		if fld.GetExtendee() != "" && fld.Label != nil && fld.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
-			fieldLabelNodeInfo := res.file.NodeInfo(node.GetLabel())
+			fieldLabelNodeInfo := res.span(node.GetLabel(), path, descpath.FieldLabelTag)
 			if err := handler.HandleErrorf(fieldLabelNodeInfo, "%s: extension fields cannot be 'required'", scope); err != nil {
 				return err
 			}
 		}
 	}
 
-	return validateNoFeatures(res, syntax, scope, fld.Options.GetUninterpretedOption(), handler)
+	return validateNoFeatures(res, syntax, scope, fld.Options, optsPath, handler)
 }
 
 type tagRange struct {
 	start int32
 	end   int32
 	node  *ast.RangeNode
+	// index of the range in the descriptor proto
+	index int32
 }
 
 type tagRanges []tagRange
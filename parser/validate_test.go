@@ -23,7 +23,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 
+	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/internal/protoc"
 	"github.com/kralicky/protocompile/reporter"
 )
@@ -1056,6 +1060,404 @@ func TestBasicValidation(t *testing.T) {
 	}
 }
 
+func TestValidationLimits(t *testing.T) {
+	t.Parallel()
+	longPkg := "package " + strings.Repeat("a.", 300) + "b;"
+	manyPeriods := "package " + strings.Repeat("a.", 101) + "b;"
+	nested := strings.Repeat("message M { ", 33) + strings.Repeat("} ", 33)
+	testCases := []struct {
+		name        string
+		contents    string
+		limits      ValidationLimits
+		expectedErr string
+	}{
+		{
+			name:        "default package length",
+			contents:    longPkg,
+			expectedErr: "test.proto:1:28-629: package name (with whitespace removed) must be less than 512 characters long",
+		},
+		{
+			name:     "raised package length",
+			contents: longPkg,
+			limits:   ValidationLimits{MaxPackageNameLength: 1024, MaxPackageNamePeriods: 400},
+		},
+		{
+			name:        "raised package length still validates",
+			contents:    longPkg,
+			limits:      ValidationLimits{MaxPackageNameLength: 600, MaxPackageNamePeriods: 400},
+			expectedErr: "test.proto:1:28-629: package name (with whitespace removed) must be less than 600 characters long",
+		},
+		{
+			name:        "default package periods",
+			contents:    manyPeriods,
+			expectedErr: "test.proto:1:28-231: package name may not contain more than 100 periods",
+		},
+		{
+			name:     "raised package periods",
+			contents: manyPeriods,
+			limits:   ValidationLimits{MaxPackageNamePeriods: 101},
+		},
+		{
+			name:        "default nesting depth",
+			contents:    nested,
+			expectedErr: "test.proto:1:392-419: message nesting depth must be less than 32",
+		},
+		{
+			name:     "raised nesting depth",
+			contents: nested,
+			limits:   ValidationLimits{MaxMessageNestingDepth: 34},
+		},
+		{
+			name:        "lowered nesting depth",
+			contents:    nested,
+			limits:      ValidationLimits{MaxMessageNestingDepth: 2},
+			expectedErr: "test.proto:1:32-479: message nesting depth must be less than 2",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			errs := reporter.NewHandler(nil)
+			file, err := Parse("test.proto", strings.NewReader(`syntax = "proto3"; `+tc.contents), errs, 0)
+			require.NoError(t, err)
+			_, err = ResultFromAST(file, true, errs, WithValidationLimits(tc.limits))
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	// These cases check that Validate reports the same errors for a descriptor
+	// as ResultFromAST does for the source it was created from.
+	parityCases := []struct {
+		name     string
+		contents string
+		path     protoreflect.SourcePath
+	}{
+		{
+			name:     "extension ranges in proto3",
+			contents: `syntax = "proto3"; message Foo { extensions 1 to 10; }`,
+			path:     protoreflect.SourcePath{4, 0, 5, 0},
+		},
+		{
+			name:     "overlapping reserved ranges",
+			contents: `message Foo { reserved 1 to 10; reserved 5 to 20; }`,
+			path:     protoreflect.SourcePath{4, 0, 9, 1},
+		},
+		{
+			name:     "extension range overlaps reserved range",
+			contents: `message Foo { reserved 1 to 10; extensions 10 to 20; }`,
+			path:     protoreflect.SourcePath{4, 0, 5, 0},
+		},
+		{
+			name:     "duplicate field numbers",
+			contents: `message Foo { optional int32 a = 1; message Bar { optional int32 b = 1; optional int32 c = 1; } }`,
+			path:     protoreflect.SourcePath{4, 0, 3, 0, 2, 1, 3},
+		},
+		{
+			name:     "field uses reserved name",
+			contents: `message Foo { reserved "a"; optional int32 a = 1; }`,
+			path:     protoreflect.SourcePath{4, 0, 2, 0, 1},
+		},
+		{
+			name:     "enum values alias",
+			contents: `enum Foo { A = 0; B = 0; }`,
+			path:     protoreflect.SourcePath{5, 0, 2, 1, 2},
+		},
+		{
+			name:     "enum value in reserved range",
+			contents: `enum Foo { reserved 1 to 3; A = 0; B = 2; }`,
+			path:     protoreflect.SourcePath{5, 0, 2, 1, 2},
+		},
+		{
+			name:     "required label in proto3",
+			contents: `syntax = "proto3"; message Foo { required int32 a = 1; }`,
+			path:     protoreflect.SourcePath{4, 0, 2, 0, 4},
+		},
+		{
+			name:     "default value in proto3",
+			contents: `syntax = "proto3"; message Foo { int32 a = 1 [default = 1]; }`,
+			path:     protoreflect.SourcePath{4, 0, 2, 0, 8, 999, 0, 2},
+		},
+		{
+			name:     "packed option in editions",
+			contents: `edition = "2023"; message Foo { repeated int32 a = 1 [packed = true]; }`,
+			path:     protoreflect.SourcePath{4, 0, 2, 0, 8, 999, 0, 2},
+		},
+		{
+			name:     "features in proto3",
+			contents: `syntax = "proto3"; service Foo { option features.foo = 1; }`,
+			path:     protoreflect.SourcePath{6, 0, 3, 999, 0, 2},
+		},
+		{
+			name:     "map fields are allowed",
+			contents: `syntax = "proto3"; message Foo { map<string, Foo> foos = 1; }`,
+		},
+	}
+	for _, tc := range parityCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			file, err := Parse("test.proto", strings.NewReader(tc.contents), reporter.NewHandler(nil), 0)
+			require.NoError(t, err)
+			_, expectedErr := ResultFromAST(file, true, reporter.NewHandler(nil))
+			res, err := ResultFromAST(file, false, reporter.NewHandler(nil))
+			require.NoError(t, err)
+
+			err = Validate(res.FileDescriptorProto(), reporter.NewHandler(nil))
+			if tc.path == nil {
+				require.NoError(t, expectedErr)
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, expectedErr)
+			require.Error(t, err)
+			var expectedErrWithPos, errWithPos reporter.ErrorWithPos
+			require.ErrorAs(t, expectedErr, &expectedErrWithPos)
+			require.ErrorAs(t, err, &errWithPos)
+			assert.Equal(t, expectedErrWithPos.Unwrap().Error(), errWithPos.Unwrap().Error())
+			assert.Equal(t, ast.PathSpan{Filename: "test.proto", Path: tc.path}, errWithPos.GetPosition())
+		})
+	}
+
+	// Descriptors that don't come from source may have options that are
+	// already interpreted.
+	interpretedCases := []struct {
+		name        string
+		file        *descriptorpb.FileDescriptorProto
+		expectedErr string
+	}{
+		{
+			name: "features in proto3",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:   proto.String("test.proto"),
+				Syntax: proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name:    proto.String("Foo"),
+					Options: &descriptorpb.MessageOptions{Features: &descriptorpb.FeatureSet{}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].options.features: message Foo: option 'features' may only be used with editions but file uses proto3 syntax",
+		},
+		{
+			name: "default value in proto3",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:   proto.String("test.proto"),
+				Syntax: proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:         proto.String("a"),
+						Number:       proto.Int32(1),
+						Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:         descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+						DefaultValue: proto.String("1"),
+					}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].field[0].default_value: field Foo.a: default values are not allowed in proto3",
+		},
+		{
+			name: "packed option in editions",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:    proto.String("test.proto"),
+				Syntax:  proto.String("editions"),
+				Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:    proto.String("a"),
+						Number:  proto.Int32(1),
+						Label:   descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+						Type:    descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+						Options: &descriptorpb.FieldOptions{Packed: proto.Bool(true)},
+					}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].field[0].options.packed: field Foo.a: packed option is not allowed in editions; use option features.repeated_field_encoding instead",
+		},
+		{
+			name: "optional label in editions",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:    proto.String("test.proto"),
+				Syntax:  proto.String("editions"),
+				Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:   proto.String("a"),
+						Number: proto.Int32(1),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					}},
+				}},
+			},
+		},
+		{
+			name: "duplicate imports",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:       proto.String("test.proto"),
+				Dependency: []string{"a.proto", "b.proto", "a.proto"},
+			},
+			expectedErr: `test.proto:.dependency[2]: "a.proto" was already imported`,
+		},
+		{
+			name: "package name too long",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:    proto.String("test.proto"),
+				Package: proto.String(strings.Repeat("a", 512)),
+			},
+			expectedErr: "test.proto:.package: package name (with whitespace removed) must be less than 512 characters long",
+		},
+		{
+			name: "field number zero",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:   proto.String("a"),
+						Number: proto.Int32(0),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].field[0].number: tag number 0 must be greater than zero",
+		},
+		{
+			name: "field number out of range",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:   proto.String("a"),
+						Number: proto.Int32(536870912),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].field[0].number: tag number 536870912 is higher than max allowed tag number (536870911)",
+		},
+		{
+			name: "field number in message set",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name:           proto.String("Foo"),
+					Options:        &descriptorpb.MessageOptions{MessageSetWireFormat: proto.Bool(true)},
+					ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{{Start: proto.Int32(1), End: proto.Int32(2147483647)}},
+				}},
+				Extension: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("a"),
+					Number:   proto.Int32(536870912),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".Foo"),
+					Extendee: proto.String(".Foo"),
+				}},
+			},
+		},
+		{
+			name: "extension number in special reserved range",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				Extension: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("a"),
+					Number:   proto.Int32(19000),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					Extendee: proto.String(".Foo"),
+				}},
+			},
+			expectedErr: "test.proto:.extension[0].number: tag number 19000 is in disallowed reserved range 19000-19999",
+		},
+		{
+			name: "inverted reserved range",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name:          proto.String("Foo"),
+					ReservedRange: []*descriptorpb.DescriptorProto_ReservedRange{{Start: proto.Int32(10), End: proto.Int32(6)}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].reserved_range[0].start: range, 10 to 5, is invalid: start must be <= end",
+		},
+		{
+			name: "inverted extension range",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name:           proto.String("Foo"),
+					ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{{Start: proto.Int32(10), End: proto.Int32(5)}},
+				}},
+			},
+			expectedErr: "test.proto:.message_type[0].extension_range[0].start: range, 10 to 4, is invalid: start must be <= end",
+		},
+		{
+			name: "inverted enum reserved range",
+			file: &descriptorpb.FileDescriptorProto{
+				Name: proto.String("test.proto"),
+				EnumType: []*descriptorpb.EnumDescriptorProto{{
+					Name:          proto.String("Foo"),
+					Value:         []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("A"), Number: proto.Int32(0)}},
+					ReservedRange: []*descriptorpb.EnumDescriptorProto_EnumReservedRange{{Start: proto.Int32(5), End: proto.Int32(1)}},
+				}},
+			},
+			expectedErr: "test.proto:.enum_type[0].reserved_range[0].start: range, 5 to 1, is invalid: start must be <= end",
+		},
+		{
+			name: "unknown syntax",
+			file: &descriptorpb.FileDescriptorProto{
+				Name:   proto.String("test.proto"),
+				Syntax: proto.String("bogus"),
+			},
+			expectedErr: `test.proto:.syntax: syntax value "bogus" must be "proto2", "proto3", or "editions"`,
+		},
+	}
+	for _, tc := range interpretedCases {
+		tc := tc
+		t.Run("interpreted "+tc.name, func(t *testing.T) {
+			t.Parallel()
+			orig := proto.Clone(tc.file)
+			err := Validate(tc.file, reporter.NewHandler(nil))
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+			assert.True(t, proto.Equal(orig, tc.file))
+		})
+	}
+}
+
+func TestValidateStopsOnUnknownSyntax(t *testing.T) {
+	t.Parallel()
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("test.proto"),
+		Syntax: proto.String("bogus"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:    proto.String("Foo"),
+			Options: &descriptorpb.MessageOptions{Features: &descriptorpb.FeatureSet{}},
+		}},
+	}
+	var errs []string
+	handler := reporter.NewHandler(reporter.NewReporter(func(err reporter.ErrorWithPos) error {
+		errs = append(errs, err.Error())
+		return nil
+	}, nil))
+	require.ErrorIs(t, Validate(fd, handler), reporter.ErrInvalidSource)
+	assert.Equal(t, []string{`test.proto:.syntax: syntax value "bogus" must be "proto2", "proto3", or "editions"`}, errs)
+}
+
 var errRegex = regexp.MustCompile(`test\.proto:(\d+):[^:]+:`)
 
 func testByProtoc(t *testing.T, fileContents string, expectSuccess bool) {
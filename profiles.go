@@ -0,0 +1,180 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"sync"
+
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// ProfileResult is the outcome of compiling files under a single profile
+// with CompileProfiles.
+type ProfileResult struct {
+	CompileResult
+	// The error returned from compiling the files under this profile, if any.
+	Err error
+}
+
+// CompileProfiles compiles the given paths once for each of the given
+// profiles and returns the results keyed by profile name. Each profile is a
+// compiler whose configuration may differ from the others (for example,
+// lenient vs. strict option interpretation, or a resolver that supplies an
+// override of descriptor.proto), which makes it cheap to compare outputs
+// across configurations.
+//
+// Profiles are compiled concurrently. Work to parse a source file is shared
+// between all profiles that resolve the file to identical contents and that
+// use the same parser settings. Files with syntax errors or warnings are
+// parsed separately for each profile, so that each profile's reporter sees
+// every diagnostic.
+//
+// The given compilers are not modified, and no results are retained in them,
+// even if RetainResults is set.
+func CompileProfiles(ctx context.Context, profiles map[string]*Compiler, paths ...ResolvedPath) map[string]ProfileResult {
+	cache := &parseCache{entries: map[parseCacheKey]*parseCacheEntry{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]ProfileResult, len(profiles))
+	for name, c := range profiles {
+		cc := *c
+		if c.Resolver != nil {
+			cc.Resolver = &sharedParseResolver{resolver: c.Resolver, cache: cache, c: &cc}
+		}
+		cc.RetainResults = false
+		cc.exec = nil
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			res, err := cc.Compile(ctx, paths...)
+			mu.Lock()
+			results[name] = ProfileResult{CompileResult: res, Err: err}
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return results
+}
+
+// parseSettings are the compiler settings that affect the results of parsing.
+type parseSettings struct {
+	limits           parser.Limits
+	validationLimits parser.ValidationLimits
+	encoding         parser.Encoding
+	strictUTF8       bool
+	rawStrings       bool
+}
+
+type parseCacheKey struct {
+	settings parseSettings
+	path     ResolvedPath
+	version  int32
+	sum      [sha256.Size]byte
+}
+
+type parseCacheEntry struct {
+	once sync.Once
+	// nil if the file could not be parsed without diagnostics
+	res parser.Result
+}
+
+type parseCache struct {
+	mu      sync.Mutex
+	entries map[parseCacheKey]*parseCacheEntry
+}
+
+func (pc *parseCache) get(key parseCacheKey) *parseCacheEntry {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	entry := pc.entries[key]
+	if entry == nil {
+		entry = &parseCacheEntry{}
+		pc.entries[key] = entry
+	}
+	return entry
+}
+
+// sharedParseResolver wraps the resolver of a profile, replacing source
+// code in its results with parse results that are shared with other
+// profiles.
+type sharedParseResolver struct {
+	resolver Resolver
+	cache    *parseCache
+	c        *Compiler
+}
+
+func (r *sharedParseResolver) FindFileByPath(path UnresolvedPath, whence ImportContext) (SearchResult, error) {
+	sr, err := r.resolver.FindFileByPath(path, whence)
+	if err != nil || sr.Source == nil || sr.AST != nil || sr.Proto != nil || sr.ParseResult != nil {
+		return sr, err
+	}
+	data, err := io.ReadAll(sr.Source)
+	if c, ok := sr.Source.(io.Closer); ok {
+		_ = c.Close()
+	}
+	if err != nil {
+		return SearchResult{}, err
+	}
+	if sr.ResolvedPath == "" {
+		sr.ResolvedPath = ResolvedPath(path)
+	}
+	key := parseCacheKey{
+		settings: parseSettings{
+			limits:           r.c.ParseLimits,
+			validationLimits: r.c.ValidationLimits,
+			encoding:         r.c.SourceEncoding,
+			strictUTF8:       r.c.StrictUTF8,
+			rawStrings:       r.c.RawStrings,
+		},
+		path:    sr.ResolvedPath,
+		version: sr.Version,
+		sum:     sha256.Sum256(data),
+	}
+	entry := r.cache.get(key)
+	entry.once.Do(func() {
+		var reported bool
+		h := reporter.NewHandler(reporter.NewReporter(
+			func(reporter.ErrorWithPos) error {
+				reported = true
+				return nil
+			},
+			func(reporter.ErrorWithPos) {
+				reported = true
+			},
+		))
+		file, err := parser.Parse(string(sr.ResolvedPath), bytes.NewReader(data), h, sr.Version, r.c.parserOptions()...)
+		if err != nil || reported {
+			return
+		}
+		res, err := parser.ResultFromAST(file, true, h, r.c.parserOptions()...)
+		if err != nil || reported {
+			return
+		}
+		entry.res = res
+	})
+	if entry.res == nil {
+		// let the compiler parse the file, so diagnostics are reported
+		sr.Source = bytes.NewReader(data)
+		return sr, nil
+	}
+	sr.Source = nil
+	sr.ParseResult = entry.res
+	return sr, nil
+}
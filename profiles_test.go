@@ -0,0 +1,75 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+func TestCompileProfiles(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+import public "b.proto";
+message A { B b = 1; }
+`,
+		"b.proto": `syntax = "proto3";
+message B { string s = 1; }
+`,
+	}
+	resolver := WithStandardImports(mkResolver(contents))
+	profiles := map[string]*Compiler{
+		"strict":  {Resolver: resolver, RetainASTs: true, PublicImportPolicy: ImportPolicyError},
+		"lenient": {Resolver: resolver, RetainASTs: true},
+	}
+	results := CompileProfiles(context.Background(), profiles, "a.proto", "b.proto")
+	require.Len(t, results, 2)
+	require.Error(t, results["strict"].Err)
+	require.NoError(t, results["lenient"].Err)
+	require.Len(t, results["lenient"].Files, 2)
+
+	// parse work for b.proto is shared, but each profile links its own copy
+	strictB := results["strict"].Files.FindFileByPath("b.proto")
+	lenientB := results["lenient"].Files.FindFileByPath("b.proto")
+	require.NotNil(t, strictB)
+	require.NotNil(t, lenientB)
+	assert.NotSame(t, strictB, lenientB)
+	assert.Same(t, strictB.(linker.Result).AST(), lenientB.(linker.Result).AST())
+
+	// files with diagnostics are parsed separately for each profile
+	contents["b.proto"] = `syntax = "proto3"; message B { string s = 1; };`
+	var mu sync.Mutex
+	warnings := map[string]int{}
+	for name, c := range profiles {
+		name := name
+		c.Reporter = reporter.NewReporter(nil, func(reporter.ErrorWithPos) {
+			mu.Lock()
+			warnings[name]++
+			mu.Unlock()
+		})
+	}
+	results = CompileProfiles(context.Background(), profiles, "b.proto")
+	require.NoError(t, results["strict"].Err)
+	require.NoError(t, results["lenient"].Err)
+	assert.Equal(t, map[string]int{"strict": 1, "lenient": 1}, warnings)
+}
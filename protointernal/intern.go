@@ -0,0 +1,142 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protointernal
+
+import "sync"
+
+// Interner de-duplicates fully-qualified names. Compiling a large set of files
+// produces the same names over and over (every element's name is computed by
+// the linker, by the options interpreter, and again for every file that is
+// re-linked after an edit), so sharing a single copy of each string cuts both
+// allocations and retained memory.
+//
+// Names are held for at most two generations, so that an interner shared by a
+// long-lived symbol table does not grow without bound as files are edited and
+// deleted. A name that is not used during an entire generation is dropped;
+// using it again afterwards simply interns a new copy. See NextGeneration.
+//
+// A nil *Interner is valid and simply performs plain concatenation.
+//
+// This type is thread-safe.
+type Interner struct {
+	mu sync.RWMutex
+	// names used during the current generation
+	names map[string]string
+	// names used during the previous generation but not yet in this one
+	prev map[string]string
+}
+
+// NewInterner returns a new, empty interner.
+func NewInterner() *Interner {
+	return &Interner{names: map[string]string{}}
+}
+
+// NextGeneration starts a new generation. Names that were not used since the
+// previous call are dropped. If no names were interned since the previous
+// call, this does nothing, so calling it repeatedly does not discard names
+// that are still in use.
+func (in *Interner) NextGeneration() {
+	if in == nil {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.names) == 0 {
+		return
+	}
+	in.prev = in.names
+	in.names = make(map[string]string, len(in.prev))
+}
+
+// Intern returns the canonical copy of s.
+func (in *Interner) Intern(s string) string {
+	if in == nil {
+		return s
+	}
+	in.mu.RLock()
+	v, ok := in.names[s]
+	in.mu.RUnlock()
+	if ok {
+		return v
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if v, ok := in.names[s]; ok {
+		return v
+	}
+	if v, ok := in.prev[s]; ok {
+		// still in use, so carry it over to the current generation
+		delete(in.prev, s)
+		s = v
+	}
+	in.names[s] = s
+	return s
+}
+
+// Concat returns the canonical copy of prefix+name. Unlike computing the
+// concatenation and then calling Intern, this does not allocate a new
+// string when the name has already been interned.
+func (in *Interner) Concat(prefix, name string) string {
+	if in == nil {
+		return prefix + name
+	}
+	if prefix == "" {
+		return in.Intern(name)
+	}
+	var arr [128]byte
+	buf := arr[:0]
+	buf = append(append(buf, prefix...), name...)
+	return in.internBytes(buf)
+}
+
+// Join returns the canonical copy of the name formed by joining parent and
+// name with a dot. If parent is empty, the result is just name.
+func (in *Interner) Join(parent, name string) string {
+	if in == nil {
+		if parent == "" {
+			return name
+		}
+		return parent + "." + name
+	}
+	if parent == "" {
+		return in.Intern(name)
+	}
+	var arr [128]byte
+	buf := arr[:0]
+	buf = append(append(append(buf, parent...), '.'), name...)
+	return in.internBytes(buf)
+}
+
+func (in *Interner) internBytes(buf []byte) string {
+	in.mu.RLock()
+	// The compiler elides the allocation for string(buf) in a map index
+	// expression, so a hit costs nothing beyond the lookup.
+	v, ok := in.names[string(buf)]
+	in.mu.RUnlock()
+	if ok {
+		return v
+	}
+	return in.Intern(string(buf))
+}
+
+// Len returns the number of distinct names held by the interner.
+func (in *Interner) Len() int {
+	if in == nil {
+		return 0
+	}
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.names) + len(in.prev)
+}
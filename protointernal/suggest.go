@@ -0,0 +1,175 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protointernal
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/walk"
+)
+
+// MaxSuggestions is the maximum number of suggestions included in an error
+// about a name that could not be resolved.
+const MaxSuggestions = 3
+
+// EditDistance returns the edit distance between a and b, computed over
+// bytes. Names in proto sources are ASCII, so this is the number of
+// single-character insertions, deletions, substitutions, and transpositions
+// of adjacent characters needed to turn a into b.
+func EditDistance(a, b string) int {
+	// rows i-2, i-1, and i of the distance matrix
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// Suggestions returns the candidates that are plausibly what was meant by the
+// given name, closest first, with ties broken by name. A candidate that is the
+// same as the name or differs only by case is always included. Otherwise, a
+// candidate must be within an edit distance of a third of the name's length
+// (and at least 1). At most limit candidates are returned.
+func Suggestions(name string, candidates []string, limit int) []string {
+	maxDist := max(len(name)/3, 1)
+	type scored struct {
+		name string
+		dist int
+	}
+	var matches []scored
+	seen := map[string]struct{}{}
+	for _, c := range candidates {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		dist := EditDistance(name, c)
+		if strings.EqualFold(name, c) {
+			dist = 0
+		} else if dist > maxDist {
+			continue
+		}
+		matches = append(matches, scored{name: c, dist: dist})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	results := make([]string, len(matches))
+	for i, m := range matches {
+		results[i] = m.name
+	}
+	return results
+}
+
+// SuggestVisible returns the full names of elements that are visible to file
+// and that are plausibly what was meant by the given name, which could not be
+// resolved. Only elements for which accept returns true are considered.
+// Candidates are matched by simple name, so the returned names may also fix an
+// incorrect qualifier. At most MaxSuggestions names are returned.
+func SuggestVisible(file protoreflect.FileDescriptor, name string, accept func(protoreflect.Descriptor) bool) []protoreflect.FullName {
+	name = strings.TrimPrefix(name, ".")
+	simpleName := name[strings.LastIndexByte(name, '.')+1:]
+	candidates := map[string][]protoreflect.FullName{}
+	RangeVisibleFiles(file, func(fd protoreflect.FileDescriptor) {
+		_ = walk.Descriptors(fd, func(d protoreflect.Descriptor) error {
+			if accept(d) {
+				candidates[string(d.Name())] = append(candidates[string(d.Name())], d.FullName())
+			}
+			return nil
+		})
+	})
+	simpleNames := make([]string, 0, len(candidates))
+	for n := range candidates {
+		simpleNames = append(simpleNames, n)
+	}
+	var results []protoreflect.FullName
+	for _, match := range Suggestions(simpleName, simpleNames, MaxSuggestions) {
+		for _, fullName := range candidates[match] {
+			if string(fullName) == name {
+				continue
+			}
+			results = append(results, fullName)
+			if len(results) == MaxSuggestions {
+				return results
+			}
+		}
+	}
+	return results
+}
+
+// RangeVisibleFiles calls fn for file and every file whose elements it can
+// refer to: its direct imports and, transitively, their public imports.
+func RangeVisibleFiles(file protoreflect.FileDescriptor, fn func(protoreflect.FileDescriptor)) {
+	seen := map[string]struct{}{}
+	var visit func(fd protoreflect.FileDescriptor, publicOnly bool)
+	visit = func(fd protoreflect.FileDescriptor, publicOnly bool) {
+		if fd == nil || fd.IsPlaceholder() {
+			return
+		}
+		if _, ok := seen[fd.Path()]; ok {
+			return
+		}
+		seen[fd.Path()] = struct{}{}
+		fn(fd)
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imp := imports.Get(i)
+			if publicOnly && !imp.IsPublic {
+				continue
+			}
+			visit(imp.FileDescriptor, true)
+		}
+	}
+	visit(file, false)
+}
+
+// DidYouMean formats the given suggestions as a clause to append to an error
+// message, like "; did you mean foo or bar?". It returns the empty string if
+// there are no suggestions.
+func DidYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return "; did you mean " + suggestions[0] + "?"
+	default:
+		return "; did you mean " + strings.Join(suggestions[:len(suggestions)-1], ", ") + " or " + suggestions[len(suggestions)-1] + "?"
+	}
+}
@@ -14,7 +14,11 @@
 
 package protointernal
 
-import "math"
+import (
+	"math"
+
+	"github.com/kralicky/protocompile/descpath"
+)
 
 const (
 	// MaxNormalTag is the maximum allowed tag number for a field in a normal message.
@@ -34,218 +38,164 @@ const (
 	// SpecialReservedEnd is the last tag in a range that is reserved and not
 	// allowed for use in message definitions.
 	SpecialReservedEnd = 19999
+)
 
-	// NB: It would be nice to use constants from generated code instead of
-	// hard-coding these here. But code-gen does not emit these as constants
-	// anywhere. The only places they appear in generated code are struct tags
-	// on fields of the generated descriptor protos.
-
-	// FilePackageTag is the tag number of the package element in a file
-	// descriptor proto.
-	FilePackageTag = 2
-	// FileDependencyTag is the tag number of the dependencies element in a
-	// file descriptor proto.
-	FileDependencyTag = 3
-	// FileMessagesTag is the tag number of the messages element in a file
-	// descriptor proto.
-	FileMessagesTag = 4
-	// FileEnumsTag is the tag number of the enums element in a file descriptor
-	// proto.
-	FileEnumsTag = 5
-	// FileServicesTag is the tag number of the services element in a file
-	// descriptor proto.
-	FileServicesTag = 6
-	// FileExtensionsTag is the tag number of the extensions element in a file
-	// descriptor proto.
-	FileExtensionsTag = 7
-	// FileOptionsTag is the tag number of the options element in a file
-	// descriptor proto.
-	FileOptionsTag = 8
-	// FileSourceCodeInfoTag is the tag number of the source code info element
-	// in a file descriptor proto.
-	FileSourceCodeInfoTag = 9
-	// FilePublicDependencyTag is the tag number of the public dependency element
-	// in a file descriptor proto.
-	FilePublicDependencyTag = 10
-	// FileWeakDependencyTag is the tag number of the weak dependency element
-	// in a file descriptor proto.
-	FileWeakDependencyTag = 11
-	// FileSyntaxTag is the tag number of the syntax element in a file
-	// descriptor proto.
-	FileSyntaxTag = 12
-	// FileEditionTag is the tag number of the edition element in a file
-	// descriptor proto.
-	FileEditionTag = 14
-	// MessageNameTag is the tag number of the name element in a message
-	// descriptor proto.
-	MessageNameTag = 1
-	// MessageFieldsTag is the tag number of the fields element in a message
-	// descriptor proto.
-	MessageFieldsTag = 2
-	// MessageNestedMessagesTag is the tag number of the nested messages
-	// element in a message descriptor proto.
-	MessageNestedMessagesTag = 3
-	// MessageEnumsTag is the tag number of the enums element in a message
-	// descriptor proto.
-	MessageEnumsTag = 4
-	// MessageExtensionRangesTag is the tag number of the extension ranges
-	// element in a message descriptor proto.
-	MessageExtensionRangesTag = 5
-	// MessageExtensionsTag is the tag number of the extensions element in a
-	// message descriptor proto.
-	MessageExtensionsTag = 6
-	// MessageOptionsTag is the tag number of the options element in a message
-	// descriptor proto.
-	MessageOptionsTag = 7
-	// MessageOneofsTag is the tag number of the one-ofs element in a message
-	// descriptor proto.
-	MessageOneofsTag = 8
-	// MessageReservedRangesTag is the tag number of the reserved ranges element
-	// in a message descriptor proto.
-	MessageReservedRangesTag = 9
-	// MessageReservedNamesTag is the tag number of the reserved names element
-	// in a message descriptor proto.
-	MessageReservedNamesTag = 10
-	// ExtensionRangeStartTag is the tag number of the start index in an
-	// extension range proto.
-	ExtensionRangeStartTag = 1
-	// ExtensionRangeEndTag is the tag number of the end index in an
-	// extension range proto.
-	ExtensionRangeEndTag = 2
-	// ExtensionRangeOptionsTag is the tag number of the options element in an
-	// extension range proto.
-	ExtensionRangeOptionsTag = 3
-	// ReservedRangeStartTag is the tag number of the start index in a reserved
-	// range proto. This field number is the same for both "flavors" of reserved
-	// ranges: DescriptorProto.ReservedRange and EnumDescriptorProto.EnumReservedRange.
-	ReservedRangeStartTag = 1
-	// ReservedRangeEndTag is the tag number of the end index in a reserved
-	// range proto. This field number is the same for both "flavors" of reserved
-	// ranges: DescriptorProto.ReservedRange and EnumDescriptorProto.EnumReservedRange.
-	ReservedRangeEndTag = 2
-	// FieldNameTag is the tag number of the name element in a field descriptor
-	// proto.
-	FieldNameTag = 1
-	// FieldExtendeeTag is the tag number of the extendee element in a field
-	// descriptor proto.
-	FieldExtendeeTag = 2
-	// FieldNumberTag is the tag number of the number element in a field
-	// descriptor proto.
-	FieldNumberTag = 3
-	// FieldLabelTag is the tag number of the label element in a field
-	// descriptor proto.
-	FieldLabelTag = 4
-	// FieldTypeTag is the tag number of the type element in a field descriptor
-	// proto.
-	FieldTypeTag = 5
-	// FieldTypeNameTag is the tag number of the type name element in a field
-	// descriptor proto.
-	FieldTypeNameTag = 6
-	// FieldDefaultTag is the tag number of the default value element in a
-	// field descriptor proto.
-	FieldDefaultTag = 7
-	// FieldOptionsTag is the tag number of the options element in a field
-	// descriptor proto.
-	FieldOptionsTag = 8
-	// FieldOneofIndexTag is the tag number of the oneof index element in a
-	// field descriptor proto.
-	FieldOneofIndexTag = 9
-	// FieldJSONNameTag is the tag number of the JSON name element in a field
-	// descriptor proto.
-	FieldJSONNameTag = 10
-	// FieldProto3OptionalTag is the tag number of the proto3_optional element
-	// in a descriptor proto.
-	FieldProto3OptionalTag = 17
-	// OneofNameTag is the tag number of the name element in a one-of
-	// descriptor proto.
-	OneofNameTag = 1
-	// OneofOptionsTag is the tag number of the options element in a one-of
-	// descriptor proto.
-	OneofOptionsTag = 2
-	// EnumNameTag is the tag number of the name element in an enum descriptor
-	// proto.
-	EnumNameTag = 1
-	// EnumValuesTag is the tag number of the values element in an enum
-	// descriptor proto.
-	EnumValuesTag = 2
-	// EnumOptionsTag is the tag number of the options element in an enum
-	// descriptor proto.
-	EnumOptionsTag = 3
-	// EnumReservedRangesTag is the tag number of the reserved ranges element in
-	// an enum descriptor proto.
-	EnumReservedRangesTag = 4
-	// EnumReservedNamesTag is the tag number of the reserved names element in
-	// an enum descriptor proto.
-	EnumReservedNamesTag = 5
-	// EnumValNameTag is the tag number of the name element in an enum value
-	// descriptor proto.
-	EnumValNameTag = 1
-	// EnumValNumberTag is the tag number of the number element in an enum
-	// value descriptor proto.
-	EnumValNumberTag = 2
-	// EnumValOptionsTag is the tag number of the options element in an enum
-	// value descriptor proto.
-	EnumValOptionsTag = 3
-	// ServiceNameTag is the tag number of the name element in a service
-	// descriptor proto.
-	ServiceNameTag = 1
-	// ServiceMethodsTag is the tag number of the methods element in a service
-	// descriptor proto.
-	ServiceMethodsTag = 2
-	// ServiceOptionsTag is the tag number of the options element in a service
-	// descriptor proto.
-	ServiceOptionsTag = 3
-	// MethodNameTag is the tag number of the name element in a method
-	// descriptor proto.
-	MethodNameTag = 1
-	// MethodInputTag is the tag number of the input type element in a method
-	// descriptor proto.
-	MethodInputTag = 2
-	// MethodOutputTag is the tag number of the output type element in a method
-	// descriptor proto.
-	MethodOutputTag = 3
-	// MethodOptionsTag is the tag number of the options element in a method
-	// descriptor proto.
-	MethodOptionsTag = 4
-	// MethodInputStreamTag is the tag number of the input stream flag in a
-	// method descriptor proto.
-	MethodInputStreamTag = 5
-	// MethodOutputStreamTag is the tag number of the output stream flag in a
-	// method descriptor proto.
-	MethodOutputStreamTag = 6
-
-	// UninterpretedOptionsTag is the tag number of the uninterpreted options
-	// element. All *Options messages use the same tag for the field that stores
-	// uninterpreted options.
-	UninterpretedOptionsTag = 999
-
-	// UninterpretedNameTag is the tag number of the name element in an
-	// uninterpreted options proto.
-	UninterpretedNameTag = 2
-	// UninterpretedIdentTag is the tag number of the identifier value in an
-	// uninterpreted options proto.
-	UninterpretedIdentTag = 3
-	// UninterpretedPosIntTag is the tag number of the positive int value in an
-	// uninterpreted options proto.
-	UninterpretedPosIntTag = 4
-	// UninterpretedNegIntTag is the tag number of the negative int value in an
-	// uninterpreted options proto.
-	UninterpretedNegIntTag = 5
-	// UninterpretedDoubleTag is the tag number of the double value in an
-	// uninterpreted options proto.
-	UninterpretedDoubleTag = 6
-	// UninterpretedStringTag is the tag number of the string value in an
-	// uninterpreted options proto.
-	UninterpretedStringTag = 7
-	// UninterpretedAggregateTag is the tag number of the aggregate value in an
-	// uninterpreted options proto.
-	UninterpretedAggregateTag = 8
-	// UninterpretedNameNameTag is the tag number of the name element in an
-	// uninterpreted option name proto.
-	UninterpretedNameNameTag = 1
-
-	// AnyTypeURLTag is the tag number of the type_url field of the Any proto.
-	AnyTypeURLTag = 1
-	// AnyValueTag is the tag number of the value field of the Any proto.
-	AnyValueTag = 2
+// Tag numbers of elements in descriptor protos. These are aliases for the
+// constants in the descpath package, which is where new code should refer
+// to them.
+const (
+	// Deprecated: Use descpath.FilePackageTag instead.
+	FilePackageTag = descpath.FilePackageTag
+	// Deprecated: Use descpath.FileDependencyTag instead.
+	FileDependencyTag = descpath.FileDependencyTag
+	// Deprecated: Use descpath.FileMessagesTag instead.
+	FileMessagesTag = descpath.FileMessagesTag
+	// Deprecated: Use descpath.FileEnumsTag instead.
+	FileEnumsTag = descpath.FileEnumsTag
+	// Deprecated: Use descpath.FileServicesTag instead.
+	FileServicesTag = descpath.FileServicesTag
+	// Deprecated: Use descpath.FileExtensionsTag instead.
+	FileExtensionsTag = descpath.FileExtensionsTag
+	// Deprecated: Use descpath.FileOptionsTag instead.
+	FileOptionsTag = descpath.FileOptionsTag
+	// Deprecated: Use descpath.FileSourceCodeInfoTag instead.
+	FileSourceCodeInfoTag = descpath.FileSourceCodeInfoTag
+	// Deprecated: Use descpath.FilePublicDependencyTag instead.
+	FilePublicDependencyTag = descpath.FilePublicDependencyTag
+	// Deprecated: Use descpath.FileWeakDependencyTag instead.
+	FileWeakDependencyTag = descpath.FileWeakDependencyTag
+	// Deprecated: Use descpath.FileSyntaxTag instead.
+	FileSyntaxTag = descpath.FileSyntaxTag
+	// Deprecated: Use descpath.FileEditionTag instead.
+	FileEditionTag = descpath.FileEditionTag
+	// Deprecated: Use descpath.MessageNameTag instead.
+	MessageNameTag = descpath.MessageNameTag
+	// Deprecated: Use descpath.MessageFieldsTag instead.
+	MessageFieldsTag = descpath.MessageFieldsTag
+	// Deprecated: Use descpath.MessageNestedMessagesTag instead.
+	MessageNestedMessagesTag = descpath.MessageNestedMessagesTag
+	// Deprecated: Use descpath.MessageEnumsTag instead.
+	MessageEnumsTag = descpath.MessageEnumsTag
+	// Deprecated: Use descpath.MessageExtensionRangesTag instead.
+	MessageExtensionRangesTag = descpath.MessageExtensionRangesTag
+	// Deprecated: Use descpath.MessageExtensionsTag instead.
+	MessageExtensionsTag = descpath.MessageExtensionsTag
+	// Deprecated: Use descpath.MessageOptionsTag instead.
+	MessageOptionsTag = descpath.MessageOptionsTag
+	// Deprecated: Use descpath.MessageOneofsTag instead.
+	MessageOneofsTag = descpath.MessageOneofsTag
+	// Deprecated: Use descpath.MessageReservedRangesTag instead.
+	MessageReservedRangesTag = descpath.MessageReservedRangesTag
+	// Deprecated: Use descpath.MessageReservedNamesTag instead.
+	MessageReservedNamesTag = descpath.MessageReservedNamesTag
+	// Deprecated: Use descpath.MessageVisibilityTag instead.
+	MessageVisibilityTag = descpath.MessageVisibilityTag
+	// Deprecated: Use descpath.ExtensionRangeStartTag instead.
+	ExtensionRangeStartTag = descpath.ExtensionRangeStartTag
+	// Deprecated: Use descpath.ExtensionRangeEndTag instead.
+	ExtensionRangeEndTag = descpath.ExtensionRangeEndTag
+	// Deprecated: Use descpath.ExtensionRangeOptionsTag instead.
+	ExtensionRangeOptionsTag = descpath.ExtensionRangeOptionsTag
+	// Deprecated: Use descpath.ExtensionRangeOptionsDeclarationsTag instead.
+	ExtensionRangeOptionsDeclarationsTag = descpath.ExtensionRangeOptionsDeclarationsTag
+	// Deprecated: Use descpath.ExtensionRangeOptionsVerificationTag instead.
+	ExtensionRangeOptionsVerificationTag = descpath.ExtensionRangeOptionsVerificationTag
+	// Deprecated: Use descpath.ExtensionDeclarationNumberTag instead.
+	ExtensionDeclarationNumberTag = descpath.ExtensionDeclarationNumberTag
+	// Deprecated: Use descpath.ExtensionDeclarationFullNameTag instead.
+	ExtensionDeclarationFullNameTag = descpath.ExtensionDeclarationFullNameTag
+	// Deprecated: Use descpath.ExtensionDeclarationTypeTag instead.
+	ExtensionDeclarationTypeTag = descpath.ExtensionDeclarationTypeTag
+	// Deprecated: Use descpath.ExtensionDeclarationReservedTag instead.
+	ExtensionDeclarationReservedTag = descpath.ExtensionDeclarationReservedTag
+	// Deprecated: Use descpath.ExtensionDeclarationRepeatedTag instead.
+	ExtensionDeclarationRepeatedTag = descpath.ExtensionDeclarationRepeatedTag
+	// Deprecated: Use descpath.ReservedRangeStartTag instead.
+	ReservedRangeStartTag = descpath.ReservedRangeStartTag
+	// Deprecated: Use descpath.ReservedRangeEndTag instead.
+	ReservedRangeEndTag = descpath.ReservedRangeEndTag
+	// Deprecated: Use descpath.FieldNameTag instead.
+	FieldNameTag = descpath.FieldNameTag
+	// Deprecated: Use descpath.FieldExtendeeTag instead.
+	FieldExtendeeTag = descpath.FieldExtendeeTag
+	// Deprecated: Use descpath.FieldNumberTag instead.
+	FieldNumberTag = descpath.FieldNumberTag
+	// Deprecated: Use descpath.FieldLabelTag instead.
+	FieldLabelTag = descpath.FieldLabelTag
+	// Deprecated: Use descpath.FieldTypeTag instead.
+	FieldTypeTag = descpath.FieldTypeTag
+	// Deprecated: Use descpath.FieldTypeNameTag instead.
+	FieldTypeNameTag = descpath.FieldTypeNameTag
+	// Deprecated: Use descpath.FieldDefaultTag instead.
+	FieldDefaultTag = descpath.FieldDefaultTag
+	// Deprecated: Use descpath.FieldOptionsTag instead.
+	FieldOptionsTag = descpath.FieldOptionsTag
+	// Deprecated: Use descpath.FieldOneofIndexTag instead.
+	FieldOneofIndexTag = descpath.FieldOneofIndexTag
+	// Deprecated: Use descpath.FieldJSONNameTag instead.
+	FieldJSONNameTag = descpath.FieldJSONNameTag
+	// Deprecated: Use descpath.FieldProto3OptionalTag instead.
+	FieldProto3OptionalTag = descpath.FieldProto3OptionalTag
+	// Deprecated: Use descpath.OneofNameTag instead.
+	OneofNameTag = descpath.OneofNameTag
+	// Deprecated: Use descpath.OneofOptionsTag instead.
+	OneofOptionsTag = descpath.OneofOptionsTag
+	// Deprecated: Use descpath.EnumNameTag instead.
+	EnumNameTag = descpath.EnumNameTag
+	// Deprecated: Use descpath.EnumValuesTag instead.
+	EnumValuesTag = descpath.EnumValuesTag
+	// Deprecated: Use descpath.EnumOptionsTag instead.
+	EnumOptionsTag = descpath.EnumOptionsTag
+	// Deprecated: Use descpath.EnumReservedRangesTag instead.
+	EnumReservedRangesTag = descpath.EnumReservedRangesTag
+	// Deprecated: Use descpath.EnumReservedNamesTag instead.
+	EnumReservedNamesTag = descpath.EnumReservedNamesTag
+	// Deprecated: Use descpath.EnumVisibilityTag instead.
+	EnumVisibilityTag = descpath.EnumVisibilityTag
+	// Deprecated: Use descpath.EnumValNameTag instead.
+	EnumValNameTag = descpath.EnumValNameTag
+	// Deprecated: Use descpath.EnumValNumberTag instead.
+	EnumValNumberTag = descpath.EnumValNumberTag
+	// Deprecated: Use descpath.EnumValOptionsTag instead.
+	EnumValOptionsTag = descpath.EnumValOptionsTag
+	// Deprecated: Use descpath.ServiceNameTag instead.
+	ServiceNameTag = descpath.ServiceNameTag
+	// Deprecated: Use descpath.ServiceMethodsTag instead.
+	ServiceMethodsTag = descpath.ServiceMethodsTag
+	// Deprecated: Use descpath.ServiceOptionsTag instead.
+	ServiceOptionsTag = descpath.ServiceOptionsTag
+	// Deprecated: Use descpath.MethodNameTag instead.
+	MethodNameTag = descpath.MethodNameTag
+	// Deprecated: Use descpath.MethodInputTag instead.
+	MethodInputTag = descpath.MethodInputTag
+	// Deprecated: Use descpath.MethodOutputTag instead.
+	MethodOutputTag = descpath.MethodOutputTag
+	// Deprecated: Use descpath.MethodOptionsTag instead.
+	MethodOptionsTag = descpath.MethodOptionsTag
+	// Deprecated: Use descpath.MethodInputStreamTag instead.
+	MethodInputStreamTag = descpath.MethodInputStreamTag
+	// Deprecated: Use descpath.MethodOutputStreamTag instead.
+	MethodOutputStreamTag = descpath.MethodOutputStreamTag
+	// Deprecated: Use descpath.UninterpretedOptionsTag instead.
+	UninterpretedOptionsTag = descpath.UninterpretedOptionsTag
+	// Deprecated: Use descpath.UninterpretedNameTag instead.
+	UninterpretedNameTag = descpath.UninterpretedNameTag
+	// Deprecated: Use descpath.UninterpretedIdentTag instead.
+	UninterpretedIdentTag = descpath.UninterpretedIdentTag
+	// Deprecated: Use descpath.UninterpretedPosIntTag instead.
+	UninterpretedPosIntTag = descpath.UninterpretedPosIntTag
+	// Deprecated: Use descpath.UninterpretedNegIntTag instead.
+	UninterpretedNegIntTag = descpath.UninterpretedNegIntTag
+	// Deprecated: Use descpath.UninterpretedDoubleTag instead.
+	UninterpretedDoubleTag = descpath.UninterpretedDoubleTag
+	// Deprecated: Use descpath.UninterpretedStringTag instead.
+	UninterpretedStringTag = descpath.UninterpretedStringTag
+	// Deprecated: Use descpath.UninterpretedAggregateTag instead.
+	UninterpretedAggregateTag = descpath.UninterpretedAggregateTag
+	// Deprecated: Use descpath.UninterpretedNameNameTag instead.
+	UninterpretedNameNameTag = descpath.UninterpretedNameNameTag
+	// Deprecated: Use descpath.AnyTypeURLTag instead.
+	AnyTypeURLTag = descpath.AnyTypeURLTag
+	// Deprecated: Use descpath.AnyValueTag instead.
+	AnyValueTag = descpath.AnyValueTag
 )
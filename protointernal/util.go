@@ -16,11 +16,12 @@ package protointernal
 
 import (
 	"bytes"
-	"slices"
 	"unicode"
 	"unicode/utf8"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/descpath"
 )
 
 // JSONName returns the default JSON name for a field with the given name.
@@ -121,6 +122,19 @@ func WriteEscapedBytes(buf *bytes.Buffer, b []byte) {
 	}
 }
 
+// InvalidUTF8Offset returns the offset of the first byte in s that is not
+// part of a valid UTF-8 encoded rune, or -1 if s is valid UTF-8.
+func InvalidUTF8Offset(s string) int {
+	for i := 0; i < len(s); {
+		r, sz := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && sz <= 1 {
+			return i
+		}
+		i += sz
+	}
+	return -1
+}
+
 // IsZeroSourceLocation returns true if the given loc is a zero value
 // (which is returned from queries that have no result).
 func IsZeroSourceLocation(loc protoreflect.SourceLocation) bool {
@@ -138,87 +152,10 @@ func IsZeroSourceLocation(loc protoreflect.SourceLocation) bool {
 // ComputeSourcePath computes the source location path for the given descriptor.
 // The boolean value indicates whether the result is valid. If the path
 // cannot be computed for d, the function returns nil, false.
+//
+// Deprecated: Use descpath.ForDescriptor instead.
 func ComputeSourcePath(d protoreflect.Descriptor) (protoreflect.SourcePath, bool) {
-	_, ok := d.(protoreflect.FileDescriptor)
-	if ok {
-		return nil, true
-	}
-	var path protoreflect.SourcePath
-	for {
-		p := d.Parent()
-		switch d := d.(type) {
-		case protoreflect.FileDescriptor:
-			slices.Reverse(path)
-			return path, true
-		case protoreflect.MessageDescriptor:
-			path = append(path, int32(d.Index()))
-			switch p.(type) {
-			case protoreflect.FileDescriptor:
-				path = append(path, FileMessagesTag)
-			case protoreflect.MessageDescriptor:
-				path = append(path, MessageNestedMessagesTag)
-			default:
-				return nil, false
-			}
-		case protoreflect.FieldDescriptor:
-			path = append(path, int32(d.Index()))
-			switch p.(type) {
-			case protoreflect.FileDescriptor:
-				if d.IsExtension() {
-					path = append(path, FileExtensionsTag)
-				} else {
-					return nil, false
-				}
-			case protoreflect.MessageDescriptor:
-				if d.IsExtension() {
-					path = append(path, MessageExtensionsTag)
-				} else {
-					path = append(path, MessageFieldsTag)
-				}
-			default:
-				return nil, false
-			}
-		case protoreflect.OneofDescriptor:
-			path = append(path, int32(d.Index()))
-			if _, ok := p.(protoreflect.MessageDescriptor); ok {
-				path = append(path, MessageOneofsTag)
-			} else {
-				return nil, false
-			}
-		case protoreflect.EnumDescriptor:
-			path = append(path, int32(d.Index()))
-			switch p.(type) {
-			case protoreflect.FileDescriptor:
-				path = append(path, FileEnumsTag)
-			case protoreflect.MessageDescriptor:
-				path = append(path, MessageEnumsTag)
-			default:
-				return nil, false
-			}
-		case protoreflect.EnumValueDescriptor:
-			path = append(path, int32(d.Index()))
-			if _, ok := p.(protoreflect.EnumDescriptor); ok {
-				path = append(path, EnumValuesTag)
-			} else {
-				return nil, false
-			}
-		case protoreflect.ServiceDescriptor:
-			path = append(path, int32(d.Index()))
-			if _, ok := p.(protoreflect.FileDescriptor); ok {
-				path = append(path, FileServicesTag)
-			} else {
-				return nil, false
-			}
-		case protoreflect.MethodDescriptor:
-			path = append(path, int32(d.Index()))
-			if _, ok := p.(protoreflect.ServiceDescriptor); ok {
-				path = append(path, ServiceMethodsTag)
-			} else {
-				return nil, false
-			}
-		}
-		d = p
-	}
+	return descpath.ForDescriptor(d)
 }
 
 // CanPack returns true if a repeated field of the given kind
@@ -0,0 +1,142 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protointernal
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/descpath"
+)
+
+// Symbol visibility was introduced in edition 2024, which is newer than the
+// version of descriptor.proto compiled into the descriptorpb package. So the
+// visibility of messages and enums, and the default_symbol_visibility
+// feature, are stored in and read from unknown fields.
+
+// SymbolVisibility is a value of the google.protobuf.SymbolVisibility enum.
+type SymbolVisibility int32
+
+const (
+	VisibilityUnset  SymbolVisibility = 0
+	VisibilityLocal  SymbolVisibility = 1
+	VisibilityExport SymbolVisibility = 2
+)
+
+// DefaultSymbolVisibility is a value of the
+// google.protobuf.FeatureSet.VisibilityFeature.DefaultSymbolVisibility enum.
+type DefaultSymbolVisibility int32
+
+const (
+	DefaultVisibilityUnknown DefaultSymbolVisibility = 0
+	// All messages and enums are exported by default.
+	DefaultVisibilityExportAll DefaultSymbolVisibility = 1
+	// Top-level messages and enums are exported by default; nested ones are
+	// local.
+	DefaultVisibilityExportTopLevel DefaultSymbolVisibility = 2
+	// All messages and enums are local by default.
+	DefaultVisibilityLocalAll DefaultSymbolVisibility = 3
+	// Like DefaultVisibilityLocalAll, but nested messages and enums may not
+	// be exported.
+	DefaultVisibilityStrict DefaultSymbolVisibility = 4
+)
+
+// FeatureSetDefaultSymbolVisibilityTag is the tag number of the
+// default_symbol_visibility element in a feature set.
+const FeatureSetDefaultSymbolVisibilityTag = 8
+
+// GetVisibility returns the visibility of the given message or enum, which
+// must be a *descriptorpb.DescriptorProto or *descriptorpb.EnumDescriptorProto.
+func GetVisibility(msg proto.Message) SymbolVisibility {
+	return SymbolVisibility(lastVarint(msg.ProtoReflect().GetUnknown(), visibilityTag(msg)))
+}
+
+// SetVisibility sets the visibility of the given message or enum, which must
+// be a *descriptorpb.DescriptorProto or *descriptorpb.EnumDescriptorProto.
+func SetVisibility(msg proto.Message, vis SymbolVisibility) {
+	tag := visibilityTag(msg)
+	ref := msg.ProtoReflect()
+	unknown := removeField(ref.GetUnknown(), tag)
+	if vis != VisibilityUnset {
+		unknown = protowire.AppendTag(unknown, tag, protowire.VarintType)
+		unknown = protowire.AppendVarint(unknown, uint64(vis))
+	}
+	ref.SetUnknown(unknown)
+}
+
+// GetDefaultSymbolVisibility returns the value of the default_symbol_visibility
+// feature in the given feature set, or DefaultVisibilityUnknown if it is not
+// set.
+func GetDefaultSymbolVisibility(features *descriptorpb.FeatureSet) DefaultSymbolVisibility {
+	if features == nil {
+		return DefaultVisibilityUnknown
+	}
+	return DefaultSymbolVisibility(lastVarint(features.ProtoReflect().GetUnknown(), FeatureSetDefaultSymbolVisibilityTag))
+}
+
+func visibilityTag(msg proto.Message) protowire.Number {
+	if _, ok := msg.(*descriptorpb.EnumDescriptorProto); ok {
+		return descpath.EnumVisibilityTag
+	}
+	return descpath.MessageVisibilityTag
+}
+
+// lastVarint returns the last value of the varint field with the given tag in
+// the given encoded fields, or zero if there is none.
+func lastVarint(b []byte, tag protowire.Number) uint64 {
+	var val uint64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return val
+		}
+		b = b[n:]
+		if num == tag && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return val
+			}
+			val = v
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return val
+		}
+		b = b[n:]
+	}
+	return val
+}
+
+// removeField returns the given encoded fields without any occurrences of the
+// field with the given tag.
+func removeField(b []byte, tag protowire.Number) []byte {
+	var out []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return append(out, b...)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return append(out, b...)
+		}
+		if num != tag {
+			out = append(out, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoutil
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DocSource describes where the documentation returned by DocFor came from.
+type DocSource int
+
+const (
+	// DocSourceNone indicates that no documentation was found.
+	DocSourceNone DocSource = iota
+	// DocSourceLeading indicates the element's own leading comments.
+	DocSourceLeading
+	// DocSourceTrailing indicates the element's own trailing comments, which
+	// are used when it has no leading comments.
+	DocSourceTrailing
+	// DocSourceContext indicates the documentation of an element that
+	// encloses or stands in for the element: the oneof that contains a field,
+	// the enum that contains a value, the map field of a map entry, or the
+	// proto3 optional field of a synthetic oneof.
+	DocSourceContext
+	// DocSourceType indicates the documentation of the message or enum type
+	// of a field.
+	DocSourceType
+)
+
+func (s DocSource) String() string {
+	switch s {
+	case DocSourceNone:
+		return "none"
+	case DocSourceLeading:
+		return "leading"
+	case DocSourceTrailing:
+		return "trailing"
+	case DocSourceContext:
+		return "context"
+	case DocSourceType:
+		return "type"
+	default:
+		return "unknown"
+	}
+}
+
+// Doc is the documentation for an element, as computed by DocFor.
+type Doc struct {
+	// The text of the comments, in the same form as the comments of a
+	// protoreflect.SourceLocation: without comment markers, and with each
+	// line ending in a newline.
+	Text string
+	// Where the text came from.
+	Source DocSource
+	// The element whose comments were used. This is the element itself
+	// when Source is DocSourceLeading or DocSourceTrailing, and nil when
+	// Source is DocSourceNone.
+	From protoreflect.Descriptor
+}
+
+// DocFor returns the documentation for the given element. The comments come
+// from the source locations of its file, so they are only available if the
+// file includes source code info.
+//
+// The following policy is used, stopping at the first step that finds
+// non-blank comments:
+//  1. The element's own leading comments.
+//  2. The element's own trailing comments.
+//  3. The own comments (leading, then trailing) of a related element:
+//     - for a field in a oneof that is not synthetic, the oneof;
+//     - for a key or value field of a map entry, the map field;
+//     - for a map entry message, the map field;
+//     - for a synthetic oneof, its proto3 optional field;
+//     - for an enum value, the enum.
+//  4. For a field whose type is a message or enum (other than a map
+//     entry), the own comments of that type.
+//
+// Detached comments are never used. Only one level of fallback is applied,
+// so, for example, a value field of a map entry does not get the
+// documentation of the map field's oneof.
+func DocFor(d protoreflect.Descriptor) Doc {
+	if doc := ownDoc(d); doc.Source != DocSourceNone {
+		return doc
+	}
+	if ctx := docContext(d); ctx != nil {
+		if doc := ownDoc(ctx); doc.Source != DocSourceNone {
+			doc.Source = DocSourceContext
+			return doc
+		}
+	}
+	if fld, ok := d.(protoreflect.FieldDescriptor); ok {
+		var typ protoreflect.Descriptor
+		switch {
+		case fld.IsMap():
+			// the map entry is synthetic, so it has no comments of its own
+		case fld.Message() != nil:
+			typ = fld.Message()
+		case fld.Enum() != nil:
+			typ = fld.Enum()
+		}
+		if typ != nil {
+			if doc := ownDoc(typ); doc.Source != DocSourceNone {
+				doc.Source = DocSourceType
+				return doc
+			}
+		}
+	}
+	return Doc{}
+}
+
+func ownDoc(d protoreflect.Descriptor) Doc {
+	file := d.ParentFile()
+	if file == nil {
+		return Doc{}
+	}
+	loc := file.SourceLocations().ByDescriptor(d)
+	switch {
+	case !isBlank(loc.LeadingComments):
+		return Doc{Text: loc.LeadingComments, Source: DocSourceLeading, From: d}
+	case !isBlank(loc.TrailingComments):
+		return Doc{Text: loc.TrailingComments, Source: DocSourceTrailing, From: d}
+	default:
+		return Doc{}
+	}
+}
+
+// docContext returns the element whose documentation is used for d in step
+// 3 of the policy described by DocFor, or nil if there is none.
+func docContext(d protoreflect.Descriptor) protoreflect.Descriptor {
+	switch d := d.(type) {
+	case protoreflect.FieldDescriptor:
+		if oo := d.ContainingOneof(); oo != nil && !oo.IsSynthetic() {
+			return oo
+		}
+		if msg, ok := d.Parent().(protoreflect.MessageDescriptor); ok && msg.IsMapEntry() {
+			return mapFieldFor(msg)
+		}
+	case protoreflect.MessageDescriptor:
+		if d.IsMapEntry() {
+			return mapFieldFor(d)
+		}
+	case protoreflect.OneofDescriptor:
+		if d.IsSynthetic() && d.Fields().Len() > 0 {
+			return d.Fields().Get(0)
+		}
+	case protoreflect.EnumValueDescriptor:
+		return d.Parent()
+	}
+	return nil
+}
+
+// mapFieldFor returns the map field whose type is the given map entry.
+func mapFieldFor(entry protoreflect.MessageDescriptor) protoreflect.Descriptor {
+	parent, ok := entry.Parent().(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil
+	}
+	fields := parent.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fld := fields.Get(i); fld.IsMap() && fld.Message().FullName() == entry.FullName() {
+			return fld
+		}
+	}
+	return nil
+}
+
+func isBlank(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r', '\f', '\v':
+		default:
+			return false
+		}
+	}
+	return true
+}
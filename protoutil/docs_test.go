@@ -0,0 +1,96 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+func TestDocFor(t *testing.T) {
+	t.Parallel()
+	compiler := protocompile.Compiler{SourceInfoMode: protocompile.SourceInfoStandard}
+	file, err := compiler.CompileSource(context.Background(), "test.proto", `
+syntax = "proto3";
+
+// Foo is a message.
+message Foo {
+  // The choice.
+  oneof choice {
+    string a = 1;
+    // B has its own doc.
+    string b = 2;
+  }
+  // Some labels.
+  map<string, string> labels = 3;
+  optional int32 count = 4; // How many.
+  Bar bar = 5;
+  Foo foo = 6;
+  Baz baz = 7;
+}
+
+// Bar is an enum.
+enum Bar {
+  BAR_UNSPECIFIED = 0;
+  BAR_ONE = 1; // The first.
+}
+
+message Baz {}
+`)
+	require.NoError(t, err)
+
+	foo := file.Messages().ByName("Foo")
+	fields := foo.Fields()
+	labels := fields.ByName("labels")
+	bar := file.Enums().ByName("Bar")
+	testCases := []struct {
+		name   string
+		desc   protoreflect.Descriptor
+		text   string
+		source protoutil.DocSource
+		from   protoreflect.Descriptor
+	}{
+		{"message", foo, " Foo is a message.\n", protoutil.DocSourceLeading, foo},
+		{"field in oneof", fields.ByName("a"), " The choice.\n", protoutil.DocSourceContext, foo.Oneofs().ByName("choice")},
+		{"field in oneof with doc", fields.ByName("b"), " B has its own doc.\n", protoutil.DocSourceLeading, fields.ByName("b")},
+		{"map field", labels, " Some labels.\n", protoutil.DocSourceLeading, labels},
+		{"map entry", labels.Message(), " Some labels.\n", protoutil.DocSourceContext, labels},
+		{"map value", labels.MapValue(), " Some labels.\n", protoutil.DocSourceContext, labels},
+		{"trailing", fields.ByName("count"), " How many.\n", protoutil.DocSourceTrailing, fields.ByName("count")},
+		{"synthetic oneof", fields.ByName("count").ContainingOneof(), " How many.\n", protoutil.DocSourceContext, fields.ByName("count")},
+		{"enum type", fields.ByName("bar"), " Bar is an enum.\n", protoutil.DocSourceType, bar},
+		{"message type", fields.ByName("foo"), " Foo is a message.\n", protoutil.DocSourceType, foo},
+		{"enum value", bar.Values().ByName("BAR_UNSPECIFIED"), " Bar is an enum.\n", protoutil.DocSourceContext, bar},
+		{"enum value with doc", bar.Values().ByName("BAR_ONE"), " The first.\n", protoutil.DocSourceTrailing, bar.Values().ByName("BAR_ONE")},
+		{"no doc", fields.ByName("baz"), "", protoutil.DocSourceNone, nil},
+	}
+	for _, tc := range testCases {
+		doc := protoutil.DocFor(tc.desc)
+		assert.Equal(t, tc.text, doc.Text, tc.name)
+		assert.Equal(t, tc.source, doc.Source, tc.name)
+		if tc.from == nil {
+			assert.Nil(t, doc.From, tc.name)
+		} else if assert.NotNil(t, doc.From, tc.name) {
+			assert.Equal(t, tc.from.FullName(), doc.From.FullName(), tc.name)
+		}
+	}
+}
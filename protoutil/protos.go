@@ -13,9 +13,10 @@
 // limitations under the License.
 
 // Package protoutil contains useful functions for interacting with descriptors.
-// For now these include only functions for efficiently converting descriptors
-// produced by the compiler to descriptor protos and functions for resolving
-// "features" (a core concept of Protobuf Editions).
+// These include functions for efficiently converting descriptors produced by
+// the compiler to descriptor protos, functions for resolving "features" (a
+// core concept of Protobuf Editions), and a function for finding the
+// documentation of an element.
 //
 // Despite the fact that descriptor protos are mutable, calling code should NOT
 // mutate any of the protos returned from this package. For efficiency, some
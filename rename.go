@@ -0,0 +1,305 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/transform"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// RenameEnumValue returns the edits that rename the enum value with the given
+// name. Besides the value's declaration, this rewrites every reference to the
+// value in the workspace, which are the interpreted option values that use it,
+// including values in message literals and the default pseudo-options of
+// fields. Option values refer to enum values by their simple names, so they
+// are not found by renaming symbols by their fully-qualified names.
+//
+// Enum values are scoped to the element that encloses their enum, so an error
+// is returned if that scope already has an element with the new name.
+func (w *Workspace) RenameEnumValue(name protoreflect.FullName, newName string) (*WorkspaceEdit, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !protoreflect.Name(newName).IsValid() {
+		return nil, fmt.Errorf("%q is not a valid identifier", newName)
+	}
+	var evd protoreflect.EnumValueDescriptor
+	for _, f := range w.files {
+		if d, ok := f.FindDescriptorByName(name).(protoreflect.EnumValueDescriptor); ok {
+			evd = d
+			break
+		}
+	}
+	if evd == nil {
+		return nil, fmt.Errorf("enum value %s not found", name)
+	}
+	newFullName := name.Parent().Append(protoreflect.Name(newName))
+	for _, f := range w.files {
+		if d := f.FindDescriptorByName(newFullName); d != nil {
+			return nil, fmt.Errorf("%s is already declared in %s", newFullName, d.ParentFile().Path())
+		}
+	}
+	path := ResolvedPath(evd.ParentFile().Path())
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil, fmt.Errorf("%s: %w", path, linker.ErrNoAST)
+	}
+
+	edit := &WorkspaceEdit{}
+	node, ok := res.Node(protoutil.ProtoFromEnumValueDescriptor(evd)).(*ast.EnumValueNode)
+	if !ok || node.GetName() == nil {
+		return nil, fmt.Errorf("%s has no declaration in %s", name, path)
+	}
+	edit.addEdit(path, transform.Replace(res.AST(), node.GetName(), newName))
+
+	for refPath, f := range w.files {
+		res, ok := f.(linker.Result)
+		if !ok || res.AST() == nil {
+			continue
+		}
+		res.RangeReferences(func(ref ast.NodeReference, to protoreflect.Descriptor) bool {
+			if to == nil || to.FullName() != name || to.ParentFile().Path() != string(path) {
+				return true
+			}
+			// keep any qualifier
+			text := res.AST().NodeInfo(ref.Node).RawText()
+			prefix := text[:strings.LastIndexByte(text, '.')+1]
+			edit.addEdit(refPath, transform.Replace(res.AST(), ref.Node, prefix+newName))
+			return true
+		})
+	}
+	return edit, nil
+}
+
+// UnsafeReference is a possible reference to a renamed element that cannot be
+// rewritten safely, such as a type URL or a fully-qualified name in a string
+// option value. See Workspace.RenamePackage.
+type UnsafeReference struct {
+	Path ResolvedPath
+	// The location of the reference, or nil if the file has no AST.
+	Span   ast.SourceSpan
+	Reason string
+}
+
+// RenamePackage returns the edits that rename the package with the given name.
+// The package statements of the files in the package are updated, and so are
+// the references to the package's elements in every file in the workspace,
+// including extension names in options and type URLs in message literals. A
+// reference that includes a qualifier refers to the new package with the same
+// form of qualifier, or else with a fully-qualified name; one without a
+// qualifier is only rewritten if it is no longer in the package's scope.
+// References in the package's files to elements of other packages are made
+// fully-qualified if they depended on the old package's scope.
+//
+// Sub-packages, which merely share a prefix with the package, are not
+// renamed. Strings that contain fully-qualified names in the package, such as
+// string option values, and files in the package that have no AST cannot be
+// updated safely, so they are returned for the caller to review.
+func (w *Workspace) RenamePackage(oldPkg, newPkg protoreflect.FullName) (*WorkspaceEdit, []UnsafeReference, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !newPkg.IsValid() {
+		return nil, nil, fmt.Errorf("%q is not a valid package name", newPkg)
+	}
+	if oldPkg == newPkg {
+		return nil, nil, fmt.Errorf("package is already named %s", newPkg)
+	}
+	var found bool
+	for _, f := range w.files {
+		if f.Package() != oldPkg {
+			continue
+		}
+		found = true
+		decls := f.Messages()
+		for i, l := 0, decls.Len(); i < l; i++ {
+			if err := w.checkPackageConflict(newPkg, decls.Get(i).Name()); err != nil {
+				return nil, nil, err
+			}
+		}
+		enums := f.Enums()
+		for i, l := 0, enums.Len(); i < l; i++ {
+			if err := w.checkPackageConflict(newPkg, enums.Get(i).Name()); err != nil {
+				return nil, nil, err
+			}
+		}
+		exts := f.Extensions()
+		for i, l := 0, exts.Len(); i < l; i++ {
+			if err := w.checkPackageConflict(newPkg, exts.Get(i).Name()); err != nil {
+				return nil, nil, err
+			}
+		}
+		svcs := f.Services()
+		for i, l := 0, svcs.Len(); i < l; i++ {
+			if err := w.checkPackageConflict(newPkg, svcs.Get(i).Name()); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("package %s not found", oldPkg)
+	}
+
+	edit := &WorkspaceEdit{}
+	var unsafe []UnsafeReference
+	qualified := regexp.MustCompile(`(?:^|[^\w.])\.?` + regexp.QuoteMeta(string(oldPkg)) + `\.[A-Za-z_]`)
+	for path, f := range w.files {
+		res, ok := f.(linker.Result)
+		if !ok || res.AST() == nil {
+			if f.Package() == oldPkg {
+				unsafe = append(unsafe, UnsafeReference{Path: path, Reason: "file has no AST"})
+			}
+			continue
+		}
+		file := res.AST()
+		inPkg := res.Package() == oldPkg
+		if inPkg {
+			for _, decl := range file.GetDecls() {
+				if pkg := decl.GetPackage(); pkg != nil && pkg.GetName() != nil {
+					edit.addEdit(path, transform.Replace(file, pkg.GetName(), string(newPkg)))
+				}
+			}
+		}
+
+		type rename struct {
+			node       ast.Node
+			start, end int
+			text       string
+		}
+		var renames []rename
+		visit := func(ref ast.Node, to protoreflect.Descriptor) {
+			if to == nil || !requiresImport(to) {
+				return
+			}
+			node, written := referenceName(ref)
+			if node == nil {
+				return
+			}
+			name := strings.TrimPrefix(written, ".")
+			var text string
+			switch toPkg := to.ParentFile().Package(); {
+			case toPkg == oldPkg:
+				rel := strings.TrimPrefix(string(to.FullName()), string(oldPkg)+".")
+				switch {
+				case name == string(oldPkg)+"."+rel:
+					text = strings.TrimSuffix(written, name) + string(newPkg) + "." + rel
+				case strings.Count(name, ".") > strings.Count(rel, ".") || !inPkg:
+					// qualified with part of the package, or resolved
+					// relative to a scope that is not renamed
+					text = "." + string(newPkg) + "." + rel
+				default:
+					return
+				}
+			case inPkg && !strings.HasPrefix(written, ".") && name != string(to.FullName()):
+				// resolved relative to the old package's scope
+				text = "." + string(to.FullName())
+			default:
+				return
+			}
+			info := file.NodeInfo(node)
+			start := info.Start().Offset
+			renames = append(renames, rename{node: node, start: start, end: start + len(info.RawText()), text: text})
+		}
+		res.RangeReferences(func(ref ast.NodeReference, to protoreflect.Descriptor) bool {
+			visit(ref.Node, to)
+			return true
+		})
+		// type URLs in message literals are not included in references
+		for node, md := range res.OptionDescriptorIndex().TypeReferenceURLsToMessageDescriptors {
+			visit(node, md)
+		}
+		// the components of compound names are also references, to enclosing
+		// messages; only the whole name is rewritten
+		sort.Slice(renames, func(i, j int) bool {
+			if renames[i].start != renames[j].start {
+				return renames[i].start < renames[j].start
+			}
+			return renames[i].end > renames[j].end
+		})
+		end := -1
+		for _, r := range renames {
+			if r.start < end {
+				continue
+			}
+			end = r.end
+			edit.addEdit(path, transform.Replace(file, r.node, r.text))
+		}
+
+		for _, decl := range file.GetDecls() {
+			if decl.GetImport() != nil || decl.GetPackage() != nil {
+				continue
+			}
+			ast.Inspect(decl, func(node ast.Node) bool {
+				if lit, ok := node.(*ast.StringLiteralNode); ok && qualified.MatchString(lit.AsString()) {
+					unsafe = append(unsafe, UnsafeReference{
+						Path:   path,
+						Span:   file.NodeInfo(lit),
+						Reason: fmt.Sprintf("string %q may refer to an element of package %s", lit.AsString(), oldPkg),
+					})
+				}
+				return true
+			})
+		}
+	}
+	sort.SliceStable(unsafe, func(i, j int) bool { return unsafe[i].Path < unsafe[j].Path })
+	return edit, unsafe, nil
+}
+
+// checkPackageConflict returns an error if the given package already has an
+// element with the given name. w.mu must be held.
+func (w *Workspace) checkPackageConflict(pkg protoreflect.FullName, name protoreflect.Name) error {
+	fullName := pkg.Append(name)
+	for _, f := range w.files {
+		if d := f.FindDescriptorByName(fullName); d != nil {
+			return fmt.Errorf("%s is already declared in %s", fullName, d.ParentFile().Path())
+		}
+	}
+	return nil
+}
+
+// referenceName returns the name node of a reference to a message, enum, or
+// extension, along with the name as it is written, or nil if the reference
+// does not include a name.
+func referenceName(node ast.Node) (ast.Node, string) {
+	switch n := node.(type) {
+	case *ast.MessageFieldNode:
+		return referenceName(n.GetName())
+	case *ast.FieldReferenceNode:
+		if n.GetName() == nil {
+			return nil, ""
+		}
+		return n.GetName(), string(n.GetName().AsIdentifier())
+	case *ast.RPCTypeNode:
+		if n.GetMessageType() == nil {
+			return nil, ""
+		}
+		return n.GetMessageType(), string(n.GetMessageType().AsIdentifier())
+	case *ast.IdentValueNode:
+		return n, string(n.AsIdentifier())
+	case ast.AnyIdentValueNode:
+		return n, string(n.AsIdentifier())
+	default:
+		return nil, ""
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast/transform"
+)
+
+func TestWorkspaceRenameEnumValue(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+enum Level {
+  LOW = 0;
+  HIGH = 1;
+}
+message Rule {
+  optional Level level = 1 [default = HIGH];
+  repeated Level levels = 2;
+}
+extend google.protobuf.MessageOptions {
+  optional Level level = 50000;
+  optional Rule rule = 50001;
+}
+`,
+		"b.proto": `syntax = "proto2";
+package foo;
+import "a.proto";
+message Msg {
+  option (level) = HIGH;
+  option (rule) = { level: HIGH levels: [LOW, HIGH] };
+}
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(sources)),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto"))
+
+	apply := func(edit *WorkspaceEdit, path ResolvedPath) string {
+		return string(transform.ApplyEdits([]byte(sources[UnresolvedPath(path)]), edit.Edits[path]))
+	}
+	edit, err := ws.RenameEnumValue("foo.HIGH", "LEVEL_HIGH")
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto2";
+package foo;
+import "google/protobuf/descriptor.proto";
+enum Level {
+  LOW = 0;
+  LEVEL_HIGH = 1;
+}
+message Rule {
+  optional Level level = 1 [default = LEVEL_HIGH];
+  repeated Level levels = 2;
+}
+extend google.protobuf.MessageOptions {
+  optional Level level = 50000;
+  optional Rule rule = 50001;
+}
+`, apply(edit, "a.proto"))
+	assert.Equal(t, `syntax = "proto2";
+package foo;
+import "a.proto";
+message Msg {
+  option (level) = LEVEL_HIGH;
+  option (rule) = { level: LEVEL_HIGH levels: [LOW, LEVEL_HIGH] };
+}
+`, apply(edit, "b.proto"))
+
+	_, err = ws.RenameEnumValue("foo.HIGH", "Rule")
+	assert.ErrorContains(t, err, "foo.Rule is already declared in a.proto")
+	_, err = ws.RenameEnumValue("foo.HIGH", "not valid")
+	assert.ErrorContains(t, err, "not a valid identifier")
+	_, err = ws.RenameEnumValue("foo.Level", "X")
+	assert.ErrorContains(t, err, "enum value foo.Level not found")
+}
+
+func TestWorkspaceRenamePackage(t *testing.T) {
+	t.Parallel()
+	sources := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+import "google/protobuf/any.proto";
+import "google/protobuf/descriptor.proto";
+import "common.proto";
+message Outer {
+  message Inner {}
+  optional Inner inner = 1;
+  optional foo.Outer.Inner qualified = 2;
+  optional common.C c = 3;
+  optional google.protobuf.Any any = 4;
+}
+extend google.protobuf.MessageOptions {
+  optional string type = 50000;
+  optional Outer.Inner inner = 50001;
+  optional google.protobuf.Any any = 50002;
+}
+`,
+		"common.proto": `syntax = "proto2";
+package foo.common;
+message C {}
+message Outer {}
+`,
+		"sub.proto": `syntax = "proto2";
+package foo.sub;
+import "a.proto";
+message S {
+  optional Outer o = 1;
+}
+`,
+		"b.proto": `syntax = "proto2";
+package other;
+import "a.proto";
+message Msg {
+  option (foo.type) = "type.googleapis.com/foo.Outer";
+  option (.foo.inner) = {};
+  option (foo.any) = { [type.googleapis.com/foo.Outer]: { inner: {} } };
+  optional foo.Outer.Inner inner = 1;
+  optional .foo.Outer outer = 2;
+}
+`,
+	}
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(sources)),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto", "sub.proto"))
+
+	apply := func(edit *WorkspaceEdit, path ResolvedPath) string {
+		return string(transform.ApplyEdits([]byte(sources[UnresolvedPath(path)]), edit.Edits[path]))
+	}
+	edit, unsafe, err := ws.RenamePackage("foo", "bar.v1")
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto2";
+package bar.v1;
+import "google/protobuf/any.proto";
+import "google/protobuf/descriptor.proto";
+import "common.proto";
+message Outer {
+  message Inner {}
+  optional Inner inner = 1;
+  optional bar.v1.Outer.Inner qualified = 2;
+  optional .foo.common.C c = 3;
+  optional google.protobuf.Any any = 4;
+}
+extend google.protobuf.MessageOptions {
+  optional string type = 50000;
+  optional Outer.Inner inner = 50001;
+  optional google.protobuf.Any any = 50002;
+}
+`, apply(edit, "a.proto"))
+	assert.Equal(t, `syntax = "proto2";
+package other;
+import "a.proto";
+message Msg {
+  option (bar.v1.type) = "type.googleapis.com/foo.Outer";
+  option (.bar.v1.inner) = {};
+  option (bar.v1.any) = { [type.googleapis.com/bar.v1.Outer]: { inner: {} } };
+  optional bar.v1.Outer.Inner inner = 1;
+  optional .bar.v1.Outer outer = 2;
+}
+`, apply(edit, "b.proto"))
+	assert.Equal(t, `syntax = "proto2";
+package foo.sub;
+import "a.proto";
+message S {
+  optional .bar.v1.Outer o = 1;
+}
+`, apply(edit, "sub.proto"))
+	assert.NotContains(t, edit.Edits, ResolvedPath("common.proto"))
+	require.Len(t, unsafe, 1)
+	assert.Equal(t, ResolvedPath("b.proto"), unsafe[0].Path)
+	assert.Equal(t, 5, unsafe[0].Span.Start().Line)
+
+	_, _, err = ws.RenamePackage("foo", "foo.common")
+	assert.ErrorContains(t, err, "foo.common.Outer is already declared in common.proto")
+	_, _, err = ws.RenamePackage("foo", "not valid")
+	assert.ErrorContains(t, err, "not a valid package name")
+	_, _, err = ws.RenamePackage("nope", "x")
+	assert.ErrorContains(t, err, "package nope not found")
+}
@@ -0,0 +1,171 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Severity indicates whether a Diagnostic is an error or a warning.
+type Severity int
+
+const (
+	// SeverityError indicates an error, which causes an operation to fail.
+	SeverityError Severity = iota
+	// SeverityWarning indicates a warning, which does not cause an operation
+	// to fail.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is an error or warning reported to a Collector.
+type Diagnostic struct {
+	ErrorWithPos
+	Severity Severity
+}
+
+// FileDiagnostics is the set of diagnostics for a single file.
+type FileDiagnostics struct {
+	Filename string
+	// Diagnostics for the file, sorted by position.
+	Diagnostics []Diagnostic
+	// The number of diagnostics of each severity.
+	Errors, Warnings int
+}
+
+// Collector is a Reporter that records all errors and warnings, so they can be
+// examined in a stable order after an operation finishes. Since it never
+// returns an error from Error, operations that use it report as many errors as
+// they can find instead of failing fast.
+//
+// A Collector is safe for concurrent use.
+type Collector struct {
+	mu    sync.Mutex
+	diags []Diagnostic
+}
+
+var _ Reporter = (*Collector)(nil)
+
+// NewCollector returns a new, empty Collector.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Error implements Reporter. It records the error and returns nil.
+func (c *Collector) Error(err ErrorWithPos) error {
+	c.add(err, SeverityError)
+	return nil
+}
+
+// Warning implements Reporter. It records the warning.
+func (c *Collector) Warning(err ErrorWithPos) {
+	c.add(err, SeverityWarning)
+}
+
+func (c *Collector) add(err ErrorWithPos, severity Severity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diags = append(c.diags, Diagnostic{ErrorWithPos: err, Severity: severity})
+}
+
+// Reset discards all recorded diagnostics.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diags = nil
+}
+
+// Counts returns the number of errors and warnings recorded.
+func (c *Collector) Counts() (errors, warnings int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.diags {
+		if d.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// Diagnostics returns all recorded diagnostics, sorted by file name and then
+// by position. Diagnostics at the same position are ordered with errors
+// before warnings, then by message, and otherwise in the order reported. So
+// the order does not depend on the order in which concurrent operations
+// happened to report them.
+func (c *Collector) Diagnostics() []Diagnostic {
+	c.mu.Lock()
+	diags := slices.Clone(c.diags)
+	c.mu.Unlock()
+	slices.SortStableFunc(diags, compareDiagnostics)
+	return diags
+}
+
+// ByFile returns all recorded diagnostics grouped by file, sorted by file
+// name. Diagnostics within each group are sorted as by Diagnostics.
+func (c *Collector) ByFile() []FileDiagnostics {
+	var files []FileDiagnostics
+	for _, d := range c.Diagnostics() {
+		filename := d.GetPosition().Start().Filename
+		if len(files) == 0 || files[len(files)-1].Filename != filename {
+			files = append(files, FileDiagnostics{Filename: filename})
+		}
+		group := &files[len(files)-1]
+		group.Diagnostics = append(group.Diagnostics, d)
+		if d.Severity == SeverityError {
+			group.Errors++
+		} else {
+			group.Warnings++
+		}
+	}
+	return files
+}
+
+func compareDiagnostics(a, b Diagnostic) int {
+	aStart, bStart := a.GetPosition().Start(), b.GetPosition().Start()
+	if c := cmp.Compare(aStart.Filename, bStart.Filename); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(aStart.Line, bStart.Line); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(aStart.Col, bStart.Col); c != 0 {
+		return c
+	}
+	aEnd, bEnd := a.GetPosition().End(), b.GetPosition().End()
+	if c := cmp.Compare(aEnd.Line, bEnd.Line); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(aEnd.Col, bEnd.Col); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Severity, b.Severity); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Error(), b.Error())
+}
@@ -84,3 +84,21 @@ func SymbolRedeclared(name string, otherDeclaration ast.SourceSpan) SymbolRedecl
 func (e SymbolRedeclaredError) Error() string {
 	return fmt.Sprintf("%s redeclared in this block (see details)", e.name)
 }
+
+// SymbolNotVisibleError is an error that is returned when a message or enum
+// that is local to one file is referenced from another file.
+type SymbolNotVisibleError struct {
+	name        string
+	Declaration ast.SourceSpan
+}
+
+func SymbolNotVisible(name string, declaration ast.SourceSpan) SymbolNotVisibleError {
+	return SymbolNotVisibleError{
+		name:        name,
+		Declaration: declaration,
+	}
+}
+
+func (e SymbolNotVisibleError) Error() string {
+	return fmt.Sprintf("%s is local to %q and cannot be referenced from other files (see details)", e.name, e.Declaration.Start().Filename)
+}
@@ -18,6 +18,7 @@
 package reporter
 
 import (
+	"slices"
 	"sync"
 
 	"github.com/kralicky/protocompile/ast"
@@ -88,6 +89,10 @@ type Handler struct {
 	reporter     Reporter
 	errsReported bool
 	err          error
+
+	// if true, diagnostics are recorded in diags
+	recording bool
+	diags     []Diagnostic
 }
 
 // NewHandler creates a new Handler that reports errors and warnings using the
@@ -110,6 +115,35 @@ func (h *Handler) SubHandler() *Handler {
 	return &Handler{parent: h}
 }
 
+// RecordingSubHandler returns a child of h, like SubHandler, that also records
+// every error and warning reported using it, so they can be retrieved with
+// Diagnostics. When a concurrent operation uses a separate recording child
+// for each file, this partitions diagnostics by file without requiring the
+// parent's reporter to sort through an interleaved stream.
+func (h *Handler) RecordingSubHandler() *Handler {
+	return &Handler{parent: h, recording: true}
+}
+
+// Diagnostics returns the errors and warnings recorded by a handler returned
+// from RecordingSubHandler, sorted by position as by Collector.Diagnostics.
+// It returns nil for other handlers.
+func (h *Handler) Diagnostics() []Diagnostic {
+	h.mu.Lock()
+	diags := slices.Clone(h.diags)
+	h.mu.Unlock()
+	slices.SortStableFunc(diags, compareDiagnostics)
+	return diags
+}
+
+func (h *Handler) record(err ErrorWithPos, severity Severity) {
+	if !h.recording {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.diags = append(h.diags, Diagnostic{ErrorWithPos: err, Severity: severity})
+}
+
 // HandleError handles the given error. If the given err is an ErrorWithPos, it
 // is reported, and this function returns the error returned by the reporter. If
 // the given err is NOT an ErrorWithPos, the current operation will abort
@@ -120,7 +154,10 @@ func (h *Handler) SubHandler() *Handler {
 // given error is not reported.
 func (h *Handler) HandleError(err error) error {
 	if h.parent != nil {
-		_, isErrWithPos := err.(ErrorWithPos)
+		ewp, isErrWithPos := err.(ErrorWithPos)
+		if isErrWithPos && h.ReporterError() == nil {
+			h.record(ewp, SeverityError)
+		}
 		err = h.parent.HandleError(err)
 
 		// update child state
@@ -176,6 +213,7 @@ func (h *Handler) HandleErrorf(span ast.SourceSpan, format string, args ...inter
 // configured reporter.
 func (h *Handler) HandleWarning(err ErrorWithPos) {
 	if h.parent != nil {
+		h.record(err, SeverityWarning)
 		h.parent.HandleWarning(err)
 		return
 	}
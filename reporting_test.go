@@ -378,6 +378,16 @@ func TestWarningReporting(t *testing.T) {
 				"foo.proto":  `syntax = "proto3"; message Bar { string name = 1; }`,
 			},
 		},
+		{
+			name: "unused weak import",
+			sources: map[string]string{
+				"test.proto": `syntax = "proto3"; import weak "foo.proto"; message Foo { }`,
+				"foo.proto":  `syntax = "proto3"; message Bar { string name = 1; }`,
+			},
+			expectedNotices: []string{
+				`test.proto:1:20: import "foo.proto" not used`,
+			},
+		},
 		{
 			name: "unused descriptor.proto import",
 			sources: map[string]string{
@@ -438,3 +448,120 @@ func TestWarningReporting(t *testing.T) {
 		})
 	}
 }
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+import "b.proto";
+message A {
+  Unknown u = 1;
+  string s = 1;
+}
+`,
+		"b.proto": `syntax = "proto3";
+message B {
+  Unknown u = 1;
+}
+`,
+		"c.proto": `syntax = "proto3";
+import "b.proto";
+message C {}
+`,
+	}
+	collector := reporter.NewCollector()
+	comp := Compiler{
+		Resolver: WithStandardImports(mkResolver(contents)),
+		Reporter: collector,
+	}
+	_, err := comp.Compile(context.Background(), "c.proto", "a.proto")
+	require.Error(t, err)
+
+	errs, warnings := collector.Counts()
+	assert.Equal(t, 3, errs)
+	assert.Equal(t, 2, warnings)
+
+	var lines []string
+	for _, d := range collector.Diagnostics() {
+		lines = append(lines, d.Severity.String()+": "+d.Error())
+	}
+	assert.Equal(t, []string{
+		`warning: a.proto:2:1-18: import "b.proto" not used`,
+		`error: a.proto:4:3-10: field A.u: unknown type Unknown`,
+		`error: a.proto:5:14-15: message A: fields u and s both have the same tag 1`,
+		`error: b.proto:3:3-10: field B.u: unknown type Unknown`,
+		`warning: c.proto:2:1-18: import "b.proto" not used`,
+	}, lines)
+
+	files := collector.ByFile()
+	require.Len(t, files, 3)
+	assert.Equal(t, "a.proto", files[0].Filename)
+	assert.Equal(t, 2, files[0].Errors)
+	assert.Equal(t, 1, files[0].Warnings)
+	assert.Len(t, files[0].Diagnostics, 3)
+	assert.Equal(t, "c.proto", files[2].Filename)
+	assert.Equal(t, 1, files[2].Warnings)
+
+	collector.Reset()
+	assert.Empty(t, collector.Diagnostics())
+}
+
+func TestDeterministicReporting(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{
+		"a.proto": `syntax = "proto3";
+message A {
+  Unknown u = 1;
+}
+`,
+		"b.proto": `syntax = "proto3";
+import "c.proto";
+message B {
+  Unknown u = 1;
+}
+`,
+		"c.proto": `syntax = "proto3";
+message C {}
+`,
+		"d.proto": `syntax = "proto3";
+message D {}
+`,
+	}
+	for i := 0; i < 10; i++ {
+		var lines []string
+		comp := Compiler{
+			Resolver: WithStandardImports(mkResolver(contents)),
+			Reporter: reporter.NewReporter(
+				func(err reporter.ErrorWithPos) error {
+					lines = append(lines, "error: "+err.Error())
+					return nil
+				},
+				func(err reporter.ErrorWithPos) {
+					lines = append(lines, "warning: "+err.Error())
+				},
+			),
+			Deterministic: true,
+		}
+		_, err := comp.Compile(context.Background(), "b.proto", "a.proto", "c.proto")
+		require.ErrorIs(t, err, reporter.ErrInvalidSource)
+		assert.Equal(t, []string{
+			`error: a.proto:3:3-10: field A.u: unknown type Unknown`,
+			`warning: b.proto:2:1-18: import "c.proto" not used`,
+			`error: b.proto:4:3-10: field B.u: unknown type Unknown`,
+		}, lines)
+
+		// the first error, by position, is returned from the default reporter
+		comp.Reporter = nil
+		_, err = comp.Compile(context.Background(), "b.proto", "a.proto", "c.proto")
+		require.EqualError(t, err, `a.proto:3:3-10: field A.u: unknown type Unknown`)
+
+		comp.IncludeDependenciesInResults = true
+		res, err := comp.Compile(context.Background(), "d.proto", "c.proto")
+		require.NoError(t, err)
+		var paths []string
+		for _, f := range res.Files {
+			paths = append(paths, f.Path())
+		}
+		assert.Equal(t, []string{"d.proto", "c.proto"}, paths)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// ReserveDeletedFields returns the edits that reserve the numbers and names of
+// fields that were deleted from the messages of a file, so that they cannot be
+// reused by mistake. The given descriptor is a previous version of the file,
+// such as one from version control or from a snapshot, and it is compared with
+// the version of the file with the same path in the workspace.
+//
+// A field is deleted if no field in the current version of its message has its
+// number. Its number and name are reserved, unless they are already reserved
+// or, for the name, used by another field. The reserved statements are added
+// after the message's last element. Messages that were themselves deleted are
+// ignored.
+func (w *Workspace) ReserveDeletedFields(previous protoreflect.FileDescriptor) (*WorkspaceEdit, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	path := ResolvedPath(previous.Path())
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil, fmt.Errorf("%s: %w", path, linker.ErrNoAST)
+	}
+	file := res.AST()
+	edit := &WorkspaceEdit{}
+	var visit func(msgs protoreflect.MessageDescriptors) error
+	visit = func(msgs protoreflect.MessageDescriptors) error {
+		for i, l := 0, msgs.Len(); i < l; i++ {
+			old := msgs.Get(i)
+			if old.IsMapEntry() {
+				continue
+			}
+			if err := visit(old.Messages()); err != nil {
+				return err
+			}
+			md, ok := res.FindDescriptorByName(old.FullName()).(protoreflect.MessageDescriptor)
+			if !ok {
+				continue
+			}
+			numbers, names := deletedFields(old, md)
+			if len(numbers) == 0 && len(names) == 0 {
+				continue
+			}
+			msgNode, ok := res.Node(protoutil.ProtoFromMessageDescriptor(md)).(*ast.MessageNode)
+			if !ok || msgNode.GetOpenBrace() == nil || msgNode.GetCloseBrace() == nil {
+				return fmt.Errorf("%s is not declared by a complete message declaration", md.FullName())
+			}
+			var decls []string
+			if len(numbers) > 0 {
+				decls = append(decls, "reserved "+numberRanges(numbers)+";")
+			}
+			if len(names) > 0 {
+				for i, name := range names {
+					if res.Syntax() != protoreflect.Editions {
+						names[i] = strconv.Quote(name)
+					}
+				}
+				decls = append(decls, "reserved "+strings.Join(names, ", ")+";")
+			}
+			edit.addEdit(path, appendToMessage(file, msgNode, decls...))
+		}
+		return nil
+	}
+	if err := visit(previous.Messages()); err != nil {
+		return nil, err
+	}
+	return edit, nil
+}
+
+// deletedFields returns the sorted numbers and the names of the fields of old
+// that are not in md and that md does not already reserve.
+func deletedFields(old, md protoreflect.MessageDescriptor) ([]protoreflect.FieldNumber, []string) {
+	var numbers []protoreflect.FieldNumber
+	var names []string
+	fields := old.Fields()
+	for i, l := 0, fields.Len(); i < l; i++ {
+		fld := fields.Get(i)
+		if md.Fields().ByNumber(fld.Number()) != nil {
+			continue
+		}
+		if !md.ReservedRanges().Has(fld.Number()) && !md.ExtensionRanges().Has(fld.Number()) {
+			numbers = append(numbers, fld.Number())
+		}
+		if md.Fields().ByName(fld.Name()) == nil && !md.ReservedNames().Has(fld.Name()) {
+			names = append(names, string(fld.Name()))
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers, names
+}
+
+// numberRanges formats the given sorted numbers as the ranges of a reserved
+// statement, combining consecutive numbers.
+func numberRanges(numbers []protoreflect.FieldNumber) string {
+	var ranges []string
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(int(numbers[i])))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d to %d", numbers[i], numbers[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
@@ -0,0 +1,100 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/ast/transform"
+)
+
+func TestWorkspaceReserveDeletedFields(t *testing.T) {
+	t.Parallel()
+	previous := `syntax = "proto3";
+package foo;
+message Msg {
+  string a = 1;
+  string b = 2;
+  string c = 3;
+  string d = 4;
+  string e = 5;
+  string f = 6;
+  message Nested {
+    int32 x = 1;
+  }
+  Nested nested = 7;
+  map<string, string> m = 8;
+}
+message Empty {
+  int32 x = 1;
+}
+message Gone {
+  int32 x = 1;
+}
+`
+	current := `syntax = "proto3";
+package foo;
+message Msg {
+  string a = 1;
+  // renamed
+  string e2 = 5;
+  string b = 6;
+  reserved 4;
+  message Nested {}
+  Nested nested = 7;
+}
+message Empty {}
+`
+	ctx := context.Background()
+	old, err := (&Compiler{Resolver: mkResolver(map[UnresolvedPath]string{"a.proto": previous})}).Compile(ctx, "a.proto")
+	require.NoError(t, err)
+	ws := NewWorkspace(&Compiler{
+		Resolver: mkResolver(map[UnresolvedPath]string{"a.proto": current}),
+	})
+	require.NoError(t, ws.Open(ctx, "a.proto"))
+
+	edit, err := ws.ReserveDeletedFields(old.Files[0])
+	require.NoError(t, err)
+	assert.Equal(t, `syntax = "proto3";
+package foo;
+message Msg {
+  string a = 1;
+  // renamed
+  string e2 = 5;
+  string b = 6;
+  reserved 4;
+  message Nested {
+    reserved 1;
+    reserved "x";
+  }
+  Nested nested = 7;
+  reserved 2 to 3, 8;
+  reserved "c", "d", "m";
+}
+message Empty {
+  reserved 1;
+  reserved "x";
+}
+`, string(transform.ApplyEdits([]byte(current), edit.Edits["a.proto"])))
+
+	// nothing to reserve when comparing a file with itself
+	res, err := ws.ReserveDeletedFields(ws.files["a.proto"])
+	require.NoError(t, err)
+	assert.Empty(t, res.Edits)
+}
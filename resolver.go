@@ -204,9 +204,10 @@ func SourceAccessorFromMap(srcs map[string]string) func(ResolvedPath) (io.ReadCl
 }
 
 // WithStandardImports returns a new resolver that knows about the same standard
-// imports that are included with protoc.
+// imports that are included with protoc. If r implements WatchingResolver, so
+// does the returned resolver.
 func WithStandardImports(r Resolver) Resolver {
-	return ResolverFunc(func(name UnresolvedPath, whence ImportContext) (SearchResult, error) {
+	res := ResolverFunc(func(name UnresolvedPath, whence ImportContext) (SearchResult, error) {
 		res, err := r.FindFileByPath(name, whence)
 		if err != nil {
 			// error from given resolver? see if it's a known standard file
@@ -219,4 +220,8 @@ func WithStandardImports(r Resolver) Resolver {
 		}
 		return res, err
 	})
+	if w, ok := r.(WatchingResolver); ok {
+		return watchingResolver{Resolver: res, watcher: w}
+	}
+	return res
 }
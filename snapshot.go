@@ -0,0 +1,261 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/symindex"
+)
+
+// snapshotVersion is incremented whenever the snapshot format changes in an
+// incompatible way.
+const snapshotVersion = 2
+
+// snapshot is the serialized form of a compile session.
+type snapshot struct {
+	Version int
+	// The paths of the files that were requested to be compiled.
+	Roots []string
+	// Every file in the transitive closure of the results, in topological
+	// order, as serialized FileDescriptorProtos.
+	Files []snapshotFile
+	// A serialized symbol index (see package symindex) for all of the files.
+	SymbolIndex []byte
+}
+
+type snapshotFile struct {
+	Path  string
+	Proto []byte
+	// The SHA-256 hash of the file's source code, or nil if the file was
+	// not compiled from source.
+	SourceHash []byte
+	// The file's option indexes, as serialized by
+	// linker.MarshalOptionIndexes, or nil if it has none.
+	OptionIndexes []byte
+}
+
+// WriteSnapshot serializes the given results to w, so that they can later be
+// restored with Compiler.RestoreSnapshot. The snapshot includes descriptors,
+// including source code info, for the requested files and all of their
+// dependencies, along with their option indexes, a symbol index for all of
+// them, and hashes of their source code, which are used to detect files that
+// have changed since the snapshot was written.
+//
+// ASTs are not included. Files whose results were reused from a previous
+// call to Compile have no recorded source hash (see CompileResult.Stats) and
+// so are always recompiled when restored, unless the resolver does not
+// provide source code for them.
+func WriteSnapshot(w io.Writer, res CompileResult) error {
+	snap := snapshot{Version: snapshotVersion}
+	for _, root := range res.roots {
+		snap.Roots = append(snap.Roots, root.Path())
+	}
+	closure := linker.ComputeReflexiveTransitiveClosure(res.roots)
+	for _, f := range closure {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(protoutil.ProtoFromFileDescriptor(f))
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path(), err)
+		}
+		sf := snapshotFile{Path: f.Path(), Proto: data}
+		if stats, ok := res.Stats[ResolvedPath(f.Path())]; ok && stats.SourceHash != ([sha256.Size]byte{}) {
+			sf.SourceHash = stats.SourceHash[:]
+		}
+		if lr, ok := f.(linker.Result); ok {
+			if sf.OptionIndexes, err = linker.MarshalOptionIndexes(lr); err != nil {
+				return fmt.Errorf("%s: %w", f.Path(), err)
+			}
+		}
+		snap.Files = append(snap.Files, sf)
+	}
+	b := symindex.NewBuilder(nil)
+	b.AddFiles(closure)
+	snap.SymbolIndex = b.Bytes()
+	return gob.NewEncoder(w).Encode(&snap)
+}
+
+// RestoredSnapshot is the result of Compiler.RestoreSnapshot. Its Stats
+// include the source hash of every restored file in the transitive closure of
+// the results, even those that were not requested, so that the results can be
+// written to another snapshot.
+type RestoredSnapshot struct {
+	CompileResult
+	// The files that were compiled from source, because they changed since
+	// the snapshot was written, can no longer be resolved, or depend on such
+	// files.
+	Stale []ResolvedPath
+	// An index of the symbols in every file in the snapshot, updated for the
+	// files that were compiled from source.
+	SymbolIndex *symindex.Index
+}
+
+// RestoreSnapshot restores results from a snapshot written by WriteSnapshot,
+// as if the files that were originally requested were compiled again with
+// this compiler. Results are restored from the snapshot, without linking
+// them again, for all files whose source code, as provided by the compiler's
+// Resolver, is unchanged. Files that have changed, that can no longer be
+// resolved, or that depend on such files, are compiled from source instead.
+//
+// Results restored from a snapshot have no ASTs, like the results of
+// compiling the same files from source without RetainASTs: their ASTs, along
+// with the option indexes that were saved in the snapshot, are loaded again
+// by Result.ReloadAST. The compiler's configuration is
+// otherwise used as is. If RetainResults is set, the restored results
+// replace any results previously retained by the compiler.
+func (c *Compiler) RestoreSnapshot(ctx context.Context, r io.Reader) (RestoredSnapshot, error) {
+	var snap snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return RestoredSnapshot{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return RestoredSnapshot{}, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	// Files are in topological order, so a file's dependencies have been
+	// restored by the time the file itself is restored.
+	restored := make(map[string]linker.Result, len(snap.Files))
+	hashes := make(map[ResolvedPath][]byte, len(snap.Files))
+	var stale []ResolvedPath
+	for _, sf := range snap.Files {
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(sf.Proto, fd); err != nil {
+			return RestoredSnapshot{}, fmt.Errorf("failed to read snapshot: %s: %w", sf.Path, err)
+		}
+		ok, err := c.isFresh(sf)
+		if err != nil {
+			return RestoredSnapshot{}, err
+		}
+		deps := make(linker.Files, 0, len(fd.Dependency))
+		for _, dep := range fd.Dependency {
+			depRes, depOk := restored[dep]
+			if !depOk {
+				ok = false
+				break
+			}
+			deps = append(deps, depRes)
+		}
+		if !ok {
+			stale = append(stale, ResolvedPath(sf.Path))
+			continue
+		}
+		var linkOpts []linker.LinkOption
+		if sf.SourceHash != nil {
+			linkOpts = append(linkOpts, linker.WithASTLoader(loadAST(c.Resolver, ResolvedPath(sf.Path), [sha256.Size]byte(sf.SourceHash), c.parserOptions())))
+			hashes[ResolvedPath(sf.Path)] = sf.SourceHash
+		}
+		res, err := linker.Restore(fd, deps, sf.OptionIndexes, linkOpts...)
+		if err != nil {
+			return RestoredSnapshot{}, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		restored[sf.Path] = res
+	}
+
+	cc := *c
+	cc.exec = nil
+	cc.Resolver = ResolverFunc(func(path UnresolvedPath, whence ImportContext) (SearchResult, error) {
+		if res, ok := restored[string(path)]; ok {
+			return SearchResult{ResolvedPath: ResolvedPath(path), ParseResult: res}, nil
+		}
+		return c.Resolver.FindFileByPath(path, whence)
+	})
+	roots := make([]ResolvedPath, len(snap.Roots))
+	for i, root := range snap.Roots {
+		roots[i] = ResolvedPath(root)
+	}
+	res, err := cc.Compile(ctx, roots...)
+	if cc.exec != nil {
+		// retain results in the original compiler, so that later compilations
+		// use its resolver for files that need to be recompiled
+		c.exec = cc.exec
+		c.exec.c = c
+	}
+	for _, f := range linker.ComputeReflexiveTransitiveClosure(res.Files) {
+		path := ResolvedPath(f.Path())
+		if hash, ok := hashes[path]; ok {
+			// record the hash, so the file is not considered stale if the
+			// results are written to another snapshot
+			if res.Stats == nil {
+				res.Stats = map[ResolvedPath]FileStats{}
+			}
+			stats := res.Stats[path]
+			stats.SourceHash = [sha256.Size]byte(hash)
+			res.Stats[path] = stats
+		}
+	}
+	restoredSnap := RestoredSnapshot{CompileResult: res, Stale: stale}
+	if err != nil {
+		return restoredSnap, err
+	}
+
+	index, err := symindex.Open(snap.SymbolIndex)
+	if err != nil {
+		return restoredSnap, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if len(stale) > 0 {
+		b := symindex.NewBuilder(index)
+		for _, path := range stale {
+			b.RemoveFile(string(path))
+		}
+		for _, f := range linker.ComputeReflexiveTransitiveClosure(res.Files) {
+			if _, ok := restored[f.Path()]; !ok {
+				b.AddFile(f)
+			}
+		}
+		if index, err = symindex.Open(b.Bytes()); err != nil {
+			return restoredSnap, err
+		}
+	}
+	restoredSnap.SymbolIndex = index
+	return restoredSnap, nil
+}
+
+// isFresh reports whether the source of the given file, as provided by the
+// compiler's resolver, is unchanged since the file was written to a snapshot.
+func (c *Compiler) isFresh(sf snapshotFile) (bool, error) {
+	if c.Resolver == nil {
+		return false, errors.New("compiler has no resolver")
+	}
+	sr, err := c.Resolver.FindFileByPath(UnresolvedPath(sf.Path), nil)
+	if err != nil {
+		return false, nil
+	}
+	if sr.Source == nil {
+		// the file wasn't compiled from source before either
+		return sf.SourceHash == nil && (sr.ResolvedPath == "" || string(sr.ResolvedPath) == sf.Path), nil
+	}
+	defer func() {
+		if closer, ok := sr.Source.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}()
+	if len(sf.SourceHash) != sha256.Size || (sr.ResolvedPath != "" && string(sr.ResolvedPath) != sf.Path) {
+		return false, nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, sr.Source); err != nil {
+		return false, nil
+	}
+	return string(hash.Sum(nil)) == string(sf.SourceHash), nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{}
+	for k, v := range baseContents {
+		contents[k] = v
+	}
+	contents["c/c.proto"] = strings.Replace(contents["c/c.proto"], "a.b.BeeTwo bee_two = 2;", "a.b.BeeTwo bee_two = 2 [deprecated = true];", 1)
+	comp := Compiler{
+		Resolver:       WithStandardImports(mkResolver(contents)),
+		SourceInfoMode: SourceInfoStandard,
+	}
+	res, err := comp.Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buf, res))
+	data := buf.Bytes()
+
+	restored, err := comp.RestoreSnapshot(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Empty(t, restored.Stale)
+	require.Len(t, restored.Files, 1)
+	assert.Equal(t, "c/c.proto", restored.Files[0].Path())
+	assert.True(t, proto.Equal(protoutil.ProtoFromFileDescriptor(res.Files[0]), protoutil.ProtoFromFileDescriptor(restored.Files[0])))
+	assert.Equal(t, 0, restored.Stats["c/c.proto"].SourceBytes)
+	assert.Equal(t, res.Stats["c/c.proto"].SourceHash, restored.Stats["c/c.proto"].SourceHash)
+	assert.Len(t, restored.SymbolIndex.Lookup("a.b.BeeTwo"), 1)
+
+	// the restored result is not linked again, but its references are bound
+	restoredRes, ok := restored.Files[0].(linker.Result)
+	require.True(t, ok)
+	assert.Nil(t, restoredRes.AST())
+	beeTwo := restoredRes.Messages().ByName("See").Fields().ByName("bee_two")
+	assert.Equal(t, "a.b.BeeTwo", string(beeTwo.Message().FullName()))
+	assert.Equal(t, "a/b/b2.proto", beeTwo.Message().ParentFile().Path())
+
+	// option indexes are restored along with the AST
+	assert.Nil(t, restoredRes.OptionIndex())
+	require.NoError(t, restoredRes.ReloadAST(context.Background()))
+	require.NotNil(t, restoredRes.AST())
+	origRes := res.Files[0].(linker.Result) //nolint:errcheck
+	require.Len(t, restoredRes.OptionIndex(), len(origRes.OptionIndex()))
+	for _, info := range restoredRes.OptionIndex() {
+		assert.Equal(t, []int32{3}, info.Path)
+	}
+	var fieldRefs []string
+	restoredRes.RangeFieldReferenceNodesWithDescriptors(func(_ ast.Node, desc protoreflect.FieldDescriptor) bool {
+		fieldRefs = append(fieldRefs, string(desc.FullName()))
+		return true
+	})
+	assert.Equal(t, []string{"google.protobuf.FieldOptions.deprecated"}, fieldRefs)
+	assert.NotEmpty(t, restoredRes.FindReferences(beeTwo.Options().ProtoReflect().Descriptor().Fields().ByName("deprecated")))
+
+	// restored results can be written to a new snapshot
+	var buf2 bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buf2, restored.CompileResult))
+	again, err := comp.RestoreSnapshot(context.Background(), &buf2)
+	require.NoError(t, err)
+	assert.Empty(t, again.Stale)
+
+	// changed files, and files that depend on them, are recompiled
+	contents["a/b/b2.proto"] = strings.Replace(contents["a/b/b2.proto"], "BeeTwo {", "BeeTwo {\n  string name = 2;", 1)
+	restored, err = comp.RestoreSnapshot(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ResolvedPath{"a/b/b2.proto", "c/c.proto"}, restored.Stale)
+	require.Len(t, restored.Files, 1)
+	assert.Positive(t, restored.Stats["c/c.proto"].SourceBytes)
+	beeTwoMsg := restored.Files[0].Messages().ByName("See").Fields().ByName("bee_two").Message()
+	assert.NotNil(t, beeTwoMsg.Fields().ByName("name"))
+	assert.Len(t, restored.SymbolIndex.Lookup("a.b.BeeTwo.name"), 1)
+	assert.Len(t, restored.SymbolIndex.Lookup("a.b.BeeOne"), 1)
+
+	_, err = comp.RestoreSnapshot(context.Background(), strings.NewReader("not a snapshot"))
+	require.Error(t, err)
+
+	_, err = (&Compiler{}).RestoreSnapshot(context.Background(), bytes.NewReader(data))
+	require.ErrorContains(t, err, "no resolver")
+}
+
+func TestRestoreSnapshotRetainResults(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{}
+	for k, v := range baseContents {
+		contents[k] = v
+	}
+	newCompiler := func() *Compiler {
+		return &Compiler{
+			Resolver:      WithStandardImports(mkResolver(contents)),
+			RetainResults: true,
+		}
+	}
+	res, err := newCompiler().Compile(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, WriteSnapshot(&buf, res))
+
+	comp := newCompiler()
+	restored, err := comp.RestoreSnapshot(context.Background(), &buf)
+	require.NoError(t, err)
+	require.Empty(t, restored.Stale)
+	// the restored results are in the compiler's symbol table
+	assert.NotNil(t, comp.Symbols().Lookup("a.b.BeeTwo"))
+
+	// recompiling the restored files replaces them
+	res, err = comp.Compile(context.Background(), "c/c.proto", "a/b/b1.proto")
+	require.NoError(t, err)
+	require.Len(t, res.Files, 2)
+	contents["a/b/b2.proto"] = strings.Replace(baseContents["a/b/b2.proto"], "BeeTwo", "BeeTwoRenamed", 1)
+	contents["c/c.proto"] = strings.ReplaceAll(baseContents["c/c.proto"], "BeeTwo", "BeeTwoRenamed")
+	res, err = comp.Compile(context.Background(), "a/b/b2.proto", "c/c.proto")
+	require.NoError(t, err)
+	require.Len(t, res.Files, 2)
+	assert.Nil(t, comp.Symbols().Lookup("a.b.BeeTwo"))
+	assert.NotNil(t, comp.Symbols().Lookup("a.b.BeeTwoRenamed"))
+}
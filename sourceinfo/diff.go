@@ -0,0 +1,166 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourceinfo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// LocationChangeKind describes how a location differs between two source code
+// info messages.
+type LocationChangeKind int
+
+const (
+	// LocationAdded indicates a location that is only present in the new
+	// source code info.
+	LocationAdded LocationChangeKind = iota + 1
+	// LocationRemoved indicates a location that is only present in the old
+	// source code info.
+	LocationRemoved
+	// LocationMoved indicates a location whose span differs.
+	LocationMoved
+	// LocationCommentsChanged indicates a location whose leading, trailing,
+	// or detached comments differ.
+	LocationCommentsChanged
+)
+
+func (k LocationChangeKind) String() string {
+	switch k {
+	case LocationAdded:
+		return "added"
+	case LocationRemoved:
+		return "removed"
+	case LocationMoved:
+		return "moved"
+	case LocationCommentsChanged:
+		return "comments changed"
+	default:
+		return "unknown"
+	}
+}
+
+// LocationChange is a single difference reported by Diff.
+type LocationChange struct {
+	Kind LocationChangeKind
+	Path protoreflect.SourcePath
+	// The location in the old source code info. Nil for LocationAdded.
+	Old *descriptorpb.SourceCodeInfo_Location
+	// The location in the new source code info. Nil for LocationRemoved.
+	New *descriptorpb.SourceCodeInfo_Location
+}
+
+func (c LocationChange) String() string {
+	switch c.Kind {
+	case LocationAdded:
+		return fmt.Sprintf("%v %v: %v", c.Path, c.Kind, formatSpan(c.New.Span))
+	case LocationRemoved:
+		return fmt.Sprintf("%v %v: %v", c.Path, c.Kind, formatSpan(c.Old.Span))
+	case LocationMoved:
+		return fmt.Sprintf("%v %v: %v -> %v", c.Path, c.Kind, formatSpan(c.Old.Span), formatSpan(c.New.Span))
+	default:
+		return fmt.Sprintf("%v %v", c.Path, c.Kind)
+	}
+}
+
+// SourceCodeInfoDiff is the result of Diff.
+type SourceCodeInfoDiff []LocationChange
+
+// Equal returns true if there are no differences.
+func (d SourceCodeInfoDiff) Equal() bool {
+	return len(d) == 0
+}
+
+// String returns a description of the differences, one per line.
+func (d SourceCodeInfoDiff) String() string {
+	var buf strings.Builder
+	for _, c := range d {
+		buf.WriteString(c.String())
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// Diff compares two source code info messages structurally. Locations are
+// matched by path rather than by their position in the list of locations, so
+// reordering locations is not a difference. When several locations have the
+// same path, they are matched in the order in which they appear.
+//
+// A location present in both that differs in both its span and its comments
+// is reported twice, once as moved and once with changed comments. Changes
+// are reported in the order of the locations in newInfo, followed by the
+// removed locations in the order of oldInfo.
+//
+// This is useful to verify that a refactor or a formatter run preserves the
+// locations in a file's descriptor.
+func Diff(oldInfo, newInfo *descriptorpb.SourceCodeInfo) SourceCodeInfoDiff {
+	oldLocs := map[string][]*descriptorpb.SourceCodeInfo_Location{}
+	for _, loc := range oldInfo.GetLocation() {
+		key := pathKey(loc.Path)
+		oldLocs[key] = append(oldLocs[key], loc)
+	}
+	var diff SourceCodeInfoDiff
+	matched := map[*descriptorpb.SourceCodeInfo_Location]struct{}{}
+	for _, newLoc := range newInfo.GetLocation() {
+		key := pathKey(newLoc.Path)
+		candidates := oldLocs[key]
+		if len(candidates) == 0 {
+			diff = append(diff, LocationChange{Kind: LocationAdded, Path: newLoc.Path, New: newLoc})
+			continue
+		}
+		oldLoc := candidates[0]
+		oldLocs[key] = candidates[1:]
+		matched[oldLoc] = struct{}{}
+		if !slices.Equal(oldLoc.Span, newLoc.Span) {
+			diff = append(diff, LocationChange{Kind: LocationMoved, Path: newLoc.Path, Old: oldLoc, New: newLoc})
+		}
+		if oldLoc.GetLeadingComments() != newLoc.GetLeadingComments() ||
+			oldLoc.GetTrailingComments() != newLoc.GetTrailingComments() ||
+			!slices.Equal(oldLoc.LeadingDetachedComments, newLoc.LeadingDetachedComments) {
+			diff = append(diff, LocationChange{Kind: LocationCommentsChanged, Path: newLoc.Path, Old: oldLoc, New: newLoc})
+		}
+	}
+	for _, oldLoc := range oldInfo.GetLocation() {
+		if _, ok := matched[oldLoc]; !ok {
+			diff = append(diff, LocationChange{Kind: LocationRemoved, Path: oldLoc.Path, Old: oldLoc})
+		}
+	}
+	return diff
+}
+
+func pathKey(path []int32) string {
+	var buf strings.Builder
+	for _, p := range path {
+		fmt.Fprintf(&buf, "%d,", p)
+	}
+	return buf.String()
+}
+
+// formatSpan formats a span as one-based line:col-line:col, or line:col-col
+// when the span is on a single line.
+func formatSpan(span []int32) string {
+	switch len(span) {
+	case 3:
+		return fmt.Sprintf("%d:%d-%d", span[0]+1, span[1]+1, span[2]+1)
+	case 4:
+		return fmt.Sprintf("%d:%d-%d:%d", span[0]+1, span[1]+1, span[2]+1, span[3]+1)
+	default:
+		return fmt.Sprint(span)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourceinfo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/sourceinfo"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	compile := func(source string) *descriptorpb.SourceCodeInfo {
+		compiler := protocompile.Compiler{
+			Resolver: &protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(map[string]string{"test.proto": source}),
+			},
+			SourceInfoMode: protocompile.SourceInfoStandard,
+		}
+		fds, err := compiler.Compile(context.Background(), "test.proto")
+		require.NoError(t, err)
+		return fds.Files[0].(linker.Result).FileDescriptorProto().SourceCodeInfo
+	}
+	before := compile(`syntax = "proto3";
+// A thing.
+message Thing {
+  string name = 1;
+  int32 id = 2;
+}
+`)
+
+	// identical and reordered locations are not differences
+	assert.True(t, sourceinfo.Diff(before, before).Equal())
+	reordered := proto.Clone(before).(*descriptorpb.SourceCodeInfo)
+	locs := reordered.Location
+	locs[0], locs[len(locs)-1] = locs[len(locs)-1], locs[0]
+	assert.True(t, sourceinfo.Diff(before, reordered).Equal())
+
+	after := compile(`syntax = "proto3";
+
+// A renamed thing.
+message Thing {
+  string name = 1;
+}
+`)
+	diff := sourceinfo.Diff(before, after)
+	assert.False(t, diff.Equal())
+	assert.Equal(t, `.message_type[0] moved: 3:1-6:2 -> 4:1-6:2
+.message_type[0] comments changed
+.message_type[0].name moved: 3:9-14 -> 4:9-14
+.message_type[0].field[0] moved: 4:3-19 -> 5:3-19
+.message_type[0].field[0].type moved: 4:3-9 -> 5:3-9
+.message_type[0].field[0].name moved: 4:10-14 -> 5:10-14
+.message_type[0].field[0].number moved: 4:17-18 -> 5:17-18
+.message_type[0].field[1] removed: 5:3-16
+.message_type[0].field[1].type removed: 5:3-8
+.message_type[0].field[1].name removed: 5:9-11
+.message_type[0].field[1].number removed: 5:14-15
+`, diff.String())
+
+	var added []string
+	for _, c := range sourceinfo.Diff(after, before) {
+		if c.Kind == sourceinfo.LocationAdded {
+			added = append(added, c.Path.String())
+		}
+	}
+	assert.Equal(t, []string{
+		".message_type[0].field[1]",
+		".message_type[0].field[1].type",
+		".message_type[0].field[1].name",
+		".message_type[0].field[1].number",
+	}, added)
+}
@@ -0,0 +1,152 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourceinfo
+
+import (
+	"slices"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// Merge adds the entries of other to idx, such as when options are
+// interpreted in more than one pass. If both contain an entry for the same
+// option, the entries are merged with OptionSourceInfo.Merge.
+func (idx OptionIndex) Merge(other OptionIndex) {
+	for opt, info := range other {
+		if existing, ok := idx[opt]; ok && existing != nil {
+			existing.Merge(info)
+			continue
+		}
+		idx[opt] = info
+	}
+}
+
+// Merge merges other into info. The path of other, if not empty, replaces
+// the path of info. If both have children for message literals, the fields
+// are merged; if both have children for array literals, the elements are
+// merged pairwise and any extra elements of other are appended. Otherwise,
+// the children of other, if any, replace those of info.
+func (info *OptionSourceInfo) Merge(other *OptionSourceInfo) {
+	if other == nil {
+		return
+	}
+	if len(other.Path) > 0 {
+		info.Path = other.Path
+	}
+	switch children := other.Children.(type) {
+	case *MessageLiteralSourceInfo:
+		existing, ok := info.Children.(*MessageLiteralSourceInfo)
+		if !ok {
+			info.Children = children
+			return
+		}
+		if existing.Fields == nil {
+			existing.Fields = map[*ast.MessageFieldNode]*OptionSourceInfo{}
+		}
+		for field, fieldInfo := range children.Fields {
+			if existingInfo, ok := existing.Fields[field]; ok && existingInfo != nil {
+				existingInfo.Merge(fieldInfo)
+				continue
+			}
+			existing.Fields[field] = fieldInfo
+		}
+	case *ArrayLiteralSourceInfo:
+		existing, ok := info.Children.(*ArrayLiteralSourceInfo)
+		if !ok {
+			info.Children = children
+			return
+		}
+		for i := range children.Elements {
+			if i < len(existing.Elements) {
+				existing.Elements[i].Merge(&children.Elements[i])
+			} else {
+				existing.Elements = append(existing.Elements, children.Elements[i])
+			}
+		}
+	}
+}
+
+// RebasePaths replaces every path in idx, including the paths of the elements
+// of message and array literals, with the result of calling fn. This is used
+// to keep the index consistent with a descriptor whose options were changed
+// after they were interpreted, such as by inserting or removing elements of a
+// repeated option. ShiftIndex can be used to implement fn for that case.
+//
+// Paths are relative to the options message of the element that the option
+// belongs to, so fn is given the option node that each path belongs to. If fn
+// returns nil, the value no longer appears in the descriptor: the option is
+// removed from the index, or, for an element of a literal, that element is
+// removed.
+func (idx OptionIndex) RebasePaths(fn func(opt *ast.OptionNode, path []int32) []int32) {
+	for opt, info := range idx {
+		if info == nil {
+			continue
+		}
+		if !info.rebasePaths(func(path []int32) []int32 { return fn(opt, path) }) {
+			delete(idx, opt)
+		}
+	}
+}
+
+// rebasePaths replaces the paths in info with the result of calling fn,
+// returning false if fn returned nil for the path of info itself.
+func (info *OptionSourceInfo) rebasePaths(fn func(path []int32) []int32) bool {
+	if len(info.Path) > 0 {
+		info.Path = fn(info.Path)
+		if info.Path == nil {
+			return false
+		}
+	}
+	switch children := info.Children.(type) {
+	case *MessageLiteralSourceInfo:
+		for field, fieldInfo := range children.Fields {
+			if fieldInfo != nil && !fieldInfo.rebasePaths(fn) {
+				delete(children.Fields, field)
+			}
+		}
+	case *ArrayLiteralSourceInfo:
+		elements := children.Elements[:0]
+		for _, elem := range children.Elements {
+			if elem.rebasePaths(fn) {
+				elements = append(elements, elem)
+			}
+		}
+		children.Elements = elements
+	}
+	return true
+}
+
+// ShiftIndex returns path adjusted for elements inserted into or removed from
+// the repeated field whose path is prefix. If path refers to an element of
+// that field, or a descendant of one, whose index is at least at, the index
+// is increased by delta. If delta is negative, elements are removed: a path
+// that refers to one of the removed elements, whose indexes are in the range
+// [at, at-delta), results in nil. Other paths are returned unchanged. A
+// shifted path does not share storage with the given path.
+func ShiftIndex(path, prefix []int32, at, delta int32) []int32 {
+	if len(path) <= len(prefix) || !slices.Equal(path[:len(prefix)], prefix) {
+		return path
+	}
+	index := path[len(prefix)]
+	if index < at {
+		return path
+	}
+	if delta < 0 && index < at-delta {
+		return nil
+	}
+	shifted := slices.Clone(path)
+	shifted[len(prefix)] = index + delta
+	return shifted
+}
@@ -0,0 +1,102 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourceinfo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/sourceinfo"
+)
+
+func TestOptionIndexMerge(t *testing.T) {
+	t.Parallel()
+	optA, optB := &ast.OptionNode{}, &ast.OptionNode{}
+	fieldA, fieldB := &ast.MessageFieldNode{}, &ast.MessageFieldNode{}
+	idx := sourceinfo.OptionIndex{
+		optA: {
+			Path: []int32{50000},
+			Children: &sourceinfo.MessageLiteralSourceInfo{Fields: map[*ast.MessageFieldNode]*sourceinfo.OptionSourceInfo{
+				fieldA: {Path: []int32{50000, 1}},
+			}},
+		},
+	}
+	idx.Merge(sourceinfo.OptionIndex{
+		optA: {
+			Children: &sourceinfo.MessageLiteralSourceInfo{Fields: map[*ast.MessageFieldNode]*sourceinfo.OptionSourceInfo{
+				fieldB: {Path: []int32{50000, 2}},
+			}},
+		},
+		optB: {Path: []int32{3}},
+	})
+	assert.Equal(t, []int32{50000}, idx[optA].Path)
+	fields := idx[optA].Children.(*sourceinfo.MessageLiteralSourceInfo).Fields
+	assert.Equal(t, []int32{50000, 1}, fields[fieldA].Path)
+	assert.Equal(t, []int32{50000, 2}, fields[fieldB].Path)
+	assert.Equal(t, []int32{3}, idx[optB].Path)
+}
+
+func TestOptionIndexRebasePaths(t *testing.T) {
+	t.Parallel()
+	// three options that set elements of a repeated option with number 50000,
+	// one of which is an array literal of messages, and one unrelated option
+	opt0, opt1, opt2, other := &ast.OptionNode{}, &ast.OptionNode{}, &ast.OptionNode{}, &ast.OptionNode{}
+	idx := sourceinfo.OptionIndex{
+		opt0: {Path: []int32{50000, 0}},
+		opt1: {Path: []int32{50000, 1}},
+		opt2: {
+			Path: []int32{50000, 2},
+			Children: &sourceinfo.ArrayLiteralSourceInfo{Elements: []sourceinfo.OptionSourceInfo{
+				{Path: []int32{50000, 2}},
+				{Path: []int32{50000, 3}},
+			}},
+		},
+		other: {Path: []int32{3}},
+	}
+	// a post-processing step removes the second element of the option
+	idx.RebasePaths(func(_ *ast.OptionNode, path []int32) []int32 {
+		return sourceinfo.ShiftIndex(path, []int32{50000}, 1, -1)
+	})
+	assert.Len(t, idx, 3)
+	assert.Equal(t, []int32{50000, 0}, idx[opt0].Path)
+	_, ok := idx[opt1]
+	assert.False(t, ok)
+	assert.Equal(t, []int32{50000, 1}, idx[opt2].Path)
+	assert.Equal(t, []sourceinfo.OptionSourceInfo{
+		{Path: []int32{50000, 1}},
+		{Path: []int32{50000, 2}},
+	}, idx[opt2].Children.(*sourceinfo.ArrayLiteralSourceInfo).Elements)
+	assert.Equal(t, []int32{3}, idx[other].Path)
+
+	// and then inserts two new elements at the front
+	idx.RebasePaths(func(_ *ast.OptionNode, path []int32) []int32 {
+		return sourceinfo.ShiftIndex(path, []int32{50000}, 0, 2)
+	})
+	assert.Equal(t, []int32{50000, 2}, idx[opt0].Path)
+	assert.Equal(t, []int32{50000, 3}, idx[opt2].Path)
+}
+
+func TestShiftIndex(t *testing.T) {
+	t.Parallel()
+	prefix := []int32{4, 0, 2}
+	assert.Equal(t, []int32{4, 0, 2, 5, 1}, sourceinfo.ShiftIndex([]int32{4, 0, 2, 3, 1}, prefix, 2, 2))
+	assert.Equal(t, []int32{4, 0, 2, 1, 1}, sourceinfo.ShiftIndex([]int32{4, 0, 2, 1, 1}, prefix, 2, 2))
+	assert.Equal(t, []int32{4, 1, 2, 3}, sourceinfo.ShiftIndex([]int32{4, 1, 2, 3}, prefix, 0, 1))
+	assert.Equal(t, []int32{4, 0, 2}, sourceinfo.ShiftIndex([]int32{4, 0, 2}, prefix, 0, 1))
+	assert.Nil(t, sourceinfo.ShiftIndex([]int32{4, 0, 2, 3}, prefix, 2, -2))
+	assert.Equal(t, []int32{4, 0, 2, 2}, sourceinfo.ShiftIndex([]int32{4, 0, 2, 4}, prefix, 2, -2))
+}
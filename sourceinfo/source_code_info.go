@@ -21,14 +21,15 @@ package sourceinfo
 
 import (
 	"bytes"
-	"fmt"
 	"strings"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
 	"github.com/kralicky/protocompile/parser"
 	"github.com/kralicky/protocompile/protointernal"
 )
@@ -47,6 +48,19 @@ type OptionDescriptorIndex struct {
 	EnumValueIdentNodesToEnumValueDescriptors      map[*ast.IdentNode]protoreflect.EnumValueDescriptor
 	OptionsToFieldDescriptors                      map[*descriptorpb.UninterpretedOption]protoreflect.FieldDescriptor
 	TypeReferenceURLsToMessageDescriptors          map[*ast.FieldReferenceNode]protoreflect.MessageDescriptor
+	MessageLiteralsToMessageDescriptors            map[*ast.MessageLiteralNode]protoreflect.MessageDescriptor
+	TypeReferenceURLsToAnyValues                   map[*ast.FieldReferenceNode]*anypb.Any
+	FieldDefaults                                  map[protoreflect.FullName]FieldDefault
+}
+
+// FieldDefault describes the default value of a field.
+type FieldDefault struct {
+	// The default value. This is not valid for fields that cannot have a
+	// default value, such as repeated and message fields.
+	Value protoreflect.Value
+	// The "default" pseudo-option that specified the value. This is nil if
+	// the value is the implicit default for the field's type.
+	Option *ast.OptionNode
 }
 
 func NewOptionDescriptorIndex() OptionDescriptorIndex {
@@ -56,6 +70,9 @@ func NewOptionDescriptorIndex() OptionDescriptorIndex {
 		EnumValueIdentNodesToEnumValueDescriptors:      make(map[*ast.IdentNode]protoreflect.EnumValueDescriptor),
 		OptionsToFieldDescriptors:                      make(map[*descriptorpb.UninterpretedOption]protoreflect.FieldDescriptor),
 		TypeReferenceURLsToMessageDescriptors:          make(map[*ast.FieldReferenceNode]protoreflect.MessageDescriptor),
+		MessageLiteralsToMessageDescriptors:            make(map[*ast.MessageLiteralNode]protoreflect.MessageDescriptor),
+		TypeReferenceURLsToAnyValues:                   make(map[*ast.FieldReferenceNode]*anypb.Any),
+		FieldDefaults:                                  make(map[protoreflect.FullName]FieldDefault),
 	}
 }
 
@@ -74,6 +91,9 @@ type OptionSourceInfo struct {
 	// used for field pseudo-options, so that the path indicates a field on
 	// the descriptor, which is a parent of the options message (since that
 	// is how the pseudo-options are actually stored).
+	//
+	// If empty, the option is not stored in the descriptor at all (such as
+	// custom field pseudo-options), so no location is generated for it.
 	Path []int32
 	// Children can be an *ArrayLiteralSourceInfo, a *MessageLiteralSourceInfo,
 	// or nil, depending on whether the option's value is an
@@ -192,10 +212,10 @@ func generateSourceInfoForFile(opts OptionIndex, sci *sourceCodeInfo) {
 	sci.newLocWithoutComments(sci.file, nil)
 
 	if sci.file.Syntax != nil {
-		sci.newLocWithComments(sci.file.Syntax, append(path, protointernal.FileSyntaxTag))
+		sci.newLocWithComments(sci.file.Syntax, append(path, descpath.FileSyntaxTag))
 	}
 	if sci.file.Edition != nil {
-		sci.newLocWithComments(sci.file.Edition, append(path, protointernal.FileEditionTag))
+		sci.newLocWithComments(sci.file.Edition, append(path, descpath.FileEditionTag))
 	}
 
 	var depIndex, pubDepIndex, weakDepIndex, optIndex, msgIndex, enumIndex, extendIndex, svcIndex int32
@@ -203,32 +223,32 @@ func generateSourceInfoForFile(opts OptionIndex, sci *sourceCodeInfo) {
 	for _, child := range sci.file.Decls {
 		switch child := child.Unwrap().(type) {
 		case *ast.ImportNode:
-			sci.newLocWithComments(child, append(path, protointernal.FileDependencyTag, depIndex))
+			sci.newLocWithComments(child, append(path, descpath.FileDependencyTag, depIndex))
 			depIndex++
 			if child.Public != nil {
-				sci.newLoc(child.Public, append(path, protointernal.FilePublicDependencyTag, pubDepIndex))
+				sci.newLoc(child.Public, append(path, descpath.FilePublicDependencyTag, pubDepIndex))
 				pubDepIndex++
 			} else if child.Weak != nil {
-				sci.newLoc(child.Weak, append(path, protointernal.FileWeakDependencyTag, weakDepIndex))
+				sci.newLoc(child.Weak, append(path, descpath.FileWeakDependencyTag, weakDepIndex))
 				weakDepIndex++
 			}
 		case *ast.PackageNode:
-			sci.newLocWithComments(child, append(path, protointernal.FilePackageTag))
+			sci.newLocWithComments(child, append(path, descpath.FilePackageTag))
 		case *ast.OptionNode:
-			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, protointernal.FileOptionsTag))
+			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, descpath.FileOptionsTag))
 		case *ast.MessageNode:
-			generateSourceCodeInfoForMessage(opts, sci, child, nil, append(path, protointernal.FileMessagesTag, msgIndex))
+			generateSourceCodeInfoForMessage(opts, sci, child, nil, append(path, descpath.FileMessagesTag, msgIndex))
 			msgIndex++
 		case *ast.EnumNode:
-			generateSourceCodeInfoForEnum(opts, sci, child, append(path, protointernal.FileEnumsTag, enumIndex))
+			generateSourceCodeInfoForEnum(opts, sci, child, append(path, descpath.FileEnumsTag, enumIndex))
 			enumIndex++
 		case *ast.ExtendNode:
-			extsPath := append(path, protointernal.FileExtensionsTag) //nolint:gocritic // intentionally creating new slice var
+			extsPath := append(path, descpath.FileExtensionsTag) //nolint:gocritic // intentionally creating new slice var
 			// we clone the path here so that append can't mutate extsPath, since they may share storage
-			msgsPath := append(protointernal.ClonePath(path), protointernal.FileMessagesTag)
+			msgsPath := append(protointernal.ClonePath(path), descpath.FileMessagesTag)
 			generateSourceCodeInfoForExtensions(opts, sci, child, &extendIndex, &msgIndex, extsPath, msgsPath)
 		case *ast.ServiceNode:
-			generateSourceCodeInfoForService(opts, sci, child, append(path, protointernal.FileServicesTag, svcIndex))
+			generateSourceCodeInfoForService(opts, sci, child, append(path, descpath.FileServicesTag, svcIndex))
 			svcIndex++
 		}
 	}
@@ -240,6 +260,10 @@ func generateSourceCodeInfoForOption(opts OptionIndex, sci *sourceCodeInfo, n *a
 	}
 	optInfo := opts[n]
 	if optInfo != nil {
+		if len(optInfo.Path) == 0 {
+			// the option was consumed without being stored in the descriptor
+			return
+		}
 		fullPath := combinePathsForOption(path, optInfo.Path)
 		if compact {
 			sci.newLoc(n, fullPath)
@@ -254,23 +278,23 @@ func generateSourceCodeInfoForOption(opts OptionIndex, sci *sourceCodeInfo, n *a
 
 	// it's an uninterpreted option
 	optPath := path
-	optPath = append(optPath, protointernal.UninterpretedOptionsTag, *uninterpIndex)
+	optPath = append(optPath, descpath.UninterpretedOptionsTag, *uninterpIndex)
 	*uninterpIndex++
 	sci.newLoc(n, optPath)
 	var valTag int32
 	switch n.Val.Unwrap().(type) {
 	case ast.AnyIdentValueNode:
-		valTag = protointernal.UninterpretedIdentTag
+		valTag = descpath.UninterpretedIdentTag
 	case *ast.NegativeIntLiteralNode:
-		valTag = protointernal.UninterpretedNegIntTag
+		valTag = descpath.UninterpretedNegIntTag
 	case ast.AnyIntValueNode:
-		valTag = protointernal.UninterpretedPosIntTag
+		valTag = descpath.UninterpretedPosIntTag
 	case ast.AnyFloatValueNode:
-		valTag = protointernal.UninterpretedDoubleTag
+		valTag = descpath.UninterpretedDoubleTag
 	case ast.AnyStringValueNode:
-		valTag = protointernal.UninterpretedStringTag
+		valTag = descpath.UninterpretedStringTag
 	case *ast.MessageLiteralNode:
-		valTag = protointernal.UninterpretedAggregateTag
+		valTag = descpath.UninterpretedAggregateTag
 	}
 	if valTag != 0 {
 		sci.newLoc(n.Val, append(optPath, valTag))
@@ -278,9 +302,9 @@ func generateSourceCodeInfoForOption(opts OptionIndex, sci *sourceCodeInfo, n *a
 	if n.Name != nil {
 		for j, nn := range n.Name.FilterFieldReferences() {
 			optNmPath := optPath
-			optNmPath = append(optNmPath, protointernal.UninterpretedNameTag, int32(j))
+			optNmPath = append(optNmPath, descpath.UninterpretedNameTag, int32(j))
 			sci.newLoc(nn, optNmPath)
-			sci.newLoc(nn.Name, append(optNmPath, protointernal.UninterpretedNameNameTag))
+			sci.newLoc(nn.Name, append(optNmPath, descpath.UninterpretedNameNameTag))
 		}
 	}
 }
@@ -318,12 +342,12 @@ func generateSourceInfoForOptionChildren(sci *sourceCodeInfo, n *ast.ValueNode,
 				}
 				fullPath := combinePathsForOption(pathPrefix, fieldInfo.Path)
 				locationNode := ast.Node(fieldNode)
-				if fieldNode.Name.IsAnyTypeReference() && fullPath[len(fullPath)-1] == protointernal.AnyValueTag {
+				if fieldNode.Name.IsAnyTypeReference() && fullPath[len(fullPath)-1] == descpath.AnyValueTag {
 					// This is a special expanded Any. So also insert a location
 					// for the type URL field.
 					typeURLPath := make([]int32, len(fullPath))
 					copy(typeURLPath, fullPath)
-					typeURLPath[len(typeURLPath)-1] = protointernal.AnyTypeURLTag
+					typeURLPath[len(typeURLPath)-1] = descpath.AnyTypeURLTag
 					sci.newLoc(fieldNode.Name, fullPath)
 					// And create the next location so it's just the value,
 					// not the full field definition.
@@ -372,11 +396,11 @@ func generateSourceCodeInfoForMessage(opts OptionIndex, sci *sourceCodeInfo, n a
 	}
 	sci.newBlockLocWithComments(n, openBrace, path)
 
-	sci.newLoc(n.GetName(), append(path, protointernal.MessageNameTag))
+	sci.newLoc(n.GetName(), append(path, descpath.MessageNameTag))
 	// matching protoc, which emits the corresponding field type name (for group fields)
 	// right after the source location for the group message name
 	if fieldPath != nil {
-		sci.newLoc(n.GetName(), append(fieldPath, protointernal.FieldTypeNameTag))
+		sci.newLoc(n.GetName(), append(fieldPath, descpath.FieldTypeNameTag))
 	}
 
 	var optIndex, fieldIndex, oneofIndex, extendIndex, nestedMsgIndex int32
@@ -384,47 +408,47 @@ func generateSourceCodeInfoForMessage(opts OptionIndex, sci *sourceCodeInfo, n a
 	for _, child := range decls {
 		switch child := child.Unwrap().(type) {
 		case *ast.OptionNode:
-			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, protointernal.MessageOptionsTag))
+			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, descpath.MessageOptionsTag))
 		case *ast.FieldNode:
-			generateSourceCodeInfoForField(opts, sci, child, append(path, protointernal.MessageFieldsTag, fieldIndex))
+			generateSourceCodeInfoForField(opts, sci, child, append(path, descpath.MessageFieldsTag, fieldIndex))
 			fieldIndex++
 		case *ast.GroupNode:
-			fldPath := append(path, protointernal.MessageFieldsTag, fieldIndex) //nolint:gocritic // intentionally creating new slice var
+			fldPath := append(path, descpath.MessageFieldsTag, fieldIndex) //nolint:gocritic // intentionally creating new slice var
 			generateSourceCodeInfoForField(opts, sci, child, fldPath)
 			fieldIndex++
 			// we clone the path here so that append can't mutate fldPath, since they may share storage
-			msgPath := append(protointernal.ClonePath(path), protointernal.MessageNestedMessagesTag, nestedMsgIndex)
+			msgPath := append(protointernal.ClonePath(path), descpath.MessageNestedMessagesTag, nestedMsgIndex)
 			generateSourceCodeInfoForMessage(opts, sci, child, fldPath, msgPath)
 			nestedMsgIndex++
 		case *ast.MapFieldNode:
-			generateSourceCodeInfoForField(opts, sci, child, append(path, protointernal.MessageFieldsTag, fieldIndex))
+			generateSourceCodeInfoForField(opts, sci, child, append(path, descpath.MessageFieldsTag, fieldIndex))
 			fieldIndex++
 			nestedMsgIndex++
 		case *ast.OneofNode:
-			fldsPath := append(path, protointernal.MessageFieldsTag) //nolint:gocritic // intentionally creating new slice var
+			fldsPath := append(path, descpath.MessageFieldsTag) //nolint:gocritic // intentionally creating new slice var
 			// we clone the path here and below so that append ops can't mutate
 			// fldPath or msgsPath, since they may otherwise share storage
-			msgsPath := append(protointernal.ClonePath(path), protointernal.MessageNestedMessagesTag)
-			ooPath := append(protointernal.ClonePath(path), protointernal.MessageOneofsTag, oneofIndex)
+			msgsPath := append(protointernal.ClonePath(path), descpath.MessageNestedMessagesTag)
+			ooPath := append(protointernal.ClonePath(path), descpath.MessageOneofsTag, oneofIndex)
 			generateSourceCodeInfoForOneof(opts, sci, child, &fieldIndex, &nestedMsgIndex, fldsPath, msgsPath, ooPath)
 			oneofIndex++
 		case *ast.MessageNode:
-			generateSourceCodeInfoForMessage(opts, sci, child, nil, append(path, protointernal.MessageNestedMessagesTag, nestedMsgIndex))
+			generateSourceCodeInfoForMessage(opts, sci, child, nil, append(path, descpath.MessageNestedMessagesTag, nestedMsgIndex))
 			nestedMsgIndex++
 		case *ast.EnumNode:
-			generateSourceCodeInfoForEnum(opts, sci, child, append(path, protointernal.MessageEnumsTag, nestedEnumIndex))
+			generateSourceCodeInfoForEnum(opts, sci, child, append(path, descpath.MessageEnumsTag, nestedEnumIndex))
 			nestedEnumIndex++
 		case *ast.ExtendNode:
-			extsPath := append(path, protointernal.MessageExtensionsTag) //nolint:gocritic // intentionally creating new slice var
+			extsPath := append(path, descpath.MessageExtensionsTag) //nolint:gocritic // intentionally creating new slice var
 			// we clone the path here so that append can't mutate extsPath, since they may share storage
-			msgsPath := append(protointernal.ClonePath(path), protointernal.MessageNestedMessagesTag)
+			msgsPath := append(protointernal.ClonePath(path), descpath.MessageNestedMessagesTag)
 			generateSourceCodeInfoForExtensions(opts, sci, child, &extendIndex, &nestedMsgIndex, extsPath, msgsPath)
 		case *ast.ExtensionRangeNode:
-			generateSourceCodeInfoForExtensionRanges(opts, sci, child, &extRangeIndex, append(path, protointernal.MessageExtensionRangesTag))
+			generateSourceCodeInfoForExtensionRanges(opts, sci, child, &extRangeIndex, append(path, descpath.MessageExtensionRangesTag))
 		case *ast.ReservedNode:
 			if len(child.FilterNames()) > 0 {
 				resPath := path
-				resPath = append(resPath, protointernal.MessageReservedNamesTag)
+				resPath = append(resPath, descpath.MessageReservedNamesTag)
 				sci.newLocWithComments(child, resPath)
 				for _, rn := range child.FilterNames() {
 					sci.newLoc(rn, append(resPath, reservedNameIndex))
@@ -433,7 +457,7 @@ func generateSourceCodeInfoForMessage(opts OptionIndex, sci *sourceCodeInfo, n a
 			}
 			if len(child.FilterRanges()) > 0 {
 				resPath := path
-				resPath = append(resPath, protointernal.MessageReservedRangesTag)
+				resPath = append(resPath, descpath.MessageReservedRangesTag)
 				sci.newLocWithComments(child, resPath)
 				for _, rr := range child.FilterRanges() {
 					generateSourceCodeInfoForReservedRange(sci, rr, append(resPath, reservedRangeIndex))
@@ -446,20 +470,20 @@ func generateSourceCodeInfoForMessage(opts OptionIndex, sci *sourceCodeInfo, n a
 
 func generateSourceCodeInfoForEnum(opts OptionIndex, sci *sourceCodeInfo, n *ast.EnumNode, path []int32) {
 	sci.newBlockLocWithComments(n, n.OpenBrace, path)
-	sci.newLoc(n.Name, append(path, protointernal.EnumNameTag))
+	sci.newLoc(n.Name, append(path, descpath.EnumNameTag))
 
 	var optIndex, valIndex, reservedNameIndex, reservedRangeIndex int32
 	for _, child := range n.Decls {
 		switch child := child.Unwrap().(type) {
 		case *ast.OptionNode:
-			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, protointernal.EnumOptionsTag))
+			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, descpath.EnumOptionsTag))
 		case *ast.EnumValueNode:
-			generateSourceCodeInfoForEnumValue(opts, sci, child, append(path, protointernal.EnumValuesTag, valIndex))
+			generateSourceCodeInfoForEnumValue(opts, sci, child, append(path, descpath.EnumValuesTag, valIndex))
 			valIndex++
 		case *ast.ReservedNode:
 			if len(child.FilterNames()) > 0 {
 				resPath := path
-				resPath = append(resPath, protointernal.EnumReservedNamesTag)
+				resPath = append(resPath, descpath.EnumReservedNamesTag)
 				sci.newLocWithComments(child, resPath)
 				for _, rn := range child.FilterNames() {
 					sci.newLoc(rn, append(resPath, reservedNameIndex))
@@ -468,7 +492,7 @@ func generateSourceCodeInfoForEnum(opts OptionIndex, sci *sourceCodeInfo, n *ast
 			}
 			if len(child.FilterRanges()) > 0 {
 				resPath := path
-				resPath = append(resPath, protointernal.EnumReservedRangesTag)
+				resPath = append(resPath, descpath.EnumReservedRangesTag)
 				sci.newLocWithComments(child, resPath)
 				for _, rr := range child.FilterRanges() {
 					generateSourceCodeInfoForReservedRange(sci, rr, append(resPath, reservedRangeIndex))
@@ -481,13 +505,13 @@ func generateSourceCodeInfoForEnum(opts OptionIndex, sci *sourceCodeInfo, n *ast
 
 func generateSourceCodeInfoForEnumValue(opts OptionIndex, sci *sourceCodeInfo, n *ast.EnumValueNode, path []int32) {
 	sci.newLocWithComments(n, path)
-	sci.newLoc(n.Name, append(path, protointernal.EnumValNameTag))
-	sci.newLoc(n.Number, append(path, protointernal.EnumValNumberTag))
+	sci.newLoc(n.Name, append(path, descpath.EnumValNameTag))
+	sci.newLoc(n.Number, append(path, descpath.EnumValNumberTag))
 
 	// enum value options
 	if n.Options != nil {
 		optsPath := path
-		optsPath = append(optsPath, protointernal.EnumValOptionsTag)
+		optsPath = append(optsPath, descpath.EnumValOptionsTag)
 		sci.newLoc(n.Options, optsPath)
 		var optIndex int32
 		for _, opt := range n.Options.GetElements() {
@@ -498,14 +522,14 @@ func generateSourceCodeInfoForEnumValue(opts OptionIndex, sci *sourceCodeInfo, n
 
 func generateSourceCodeInfoForReservedRange(sci *sourceCodeInfo, n *ast.RangeNode, path []int32) {
 	sci.newLoc(n, path)
-	sci.newLoc(n.StartVal, append(path, protointernal.ReservedRangeStartTag))
+	sci.newLoc(n.StartVal, append(path, descpath.ReservedRangeStartTag))
 	switch {
 	case n.EndVal != nil:
-		sci.newLoc(n.EndVal, append(path, protointernal.ReservedRangeEndTag))
+		sci.newLoc(n.EndVal, append(path, descpath.ReservedRangeEndTag))
 	case n.Max != nil:
-		sci.newLoc(n.Max, append(path, protointernal.ReservedRangeEndTag))
+		sci.newLoc(n.Max, append(path, descpath.ReservedRangeEndTag))
 	default:
-		sci.newLoc(n.StartVal, append(path, protointernal.ReservedRangeEndTag))
+		sci.newLoc(n.StartVal, append(path, descpath.ReservedRangeEndTag))
 	}
 }
 
@@ -531,13 +555,13 @@ func generateSourceCodeInfoForExtensions(opts OptionIndex, sci *sourceCodeInfo,
 
 func generateSourceCodeInfoForOneof(opts OptionIndex, sci *sourceCodeInfo, n *ast.OneofNode, fieldIndex, nestedMsgIndex *int32, fieldPath, nestedMsgPath, oneofPath []int32) {
 	sci.newBlockLocWithComments(n, n.OpenBrace, oneofPath)
-	sci.newLoc(n.Name, append(oneofPath, protointernal.OneofNameTag))
+	sci.newLoc(n.Name, append(oneofPath, descpath.OneofNameTag))
 
 	var optIndex int32
 	for _, child := range n.Decls {
 		switch child := child.Unwrap().(type) {
 		case *ast.OptionNode:
-			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(oneofPath, protointernal.OneofOptionsTag))
+			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(oneofPath, descpath.OneofOptionsTag))
 		case *ast.FieldNode:
 			generateSourceCodeInfoForField(opts, sci, child, append(fieldPath, *fieldIndex))
 			*fieldIndex++
@@ -563,23 +587,23 @@ func generateSourceCodeInfoForField(opts OptionIndex, sci *sourceCodeInfo, n ast
 		// comments will appear on group message
 		sci.newLocWithoutComments(n, path)
 		if fieldExtendee != nil {
-			sci.newLoc(fieldExtendee.GetExtendee(), append(path, protointernal.FieldExtendeeTag))
+			sci.newLoc(fieldExtendee.GetExtendee(), append(path, descpath.FieldExtendeeTag))
 		}
 		if n.GetLabel() != nil {
 			// no comments here either (label is first token for group, so we want
 			// to leave the comments to be associated with the group message instead)
-			sci.newLocWithoutComments(n.GetLabel(), append(path, protointernal.FieldLabelTag))
+			sci.newLocWithoutComments(n.GetLabel(), append(path, descpath.FieldLabelTag))
 		}
-		sci.newLoc(n.GetKeyword(), append(path, protointernal.FieldTypeTag))
+		sci.newLoc(n.GetKeyword(), append(path, descpath.FieldTypeTag))
 		// let the name comments be attributed to the group name
-		sci.newLocWithoutComments(n.GetName(), append(path, protointernal.FieldNameTag))
+		sci.newLocWithoutComments(n.GetName(), append(path, descpath.FieldNameTag))
 	default:
 		sci.newLocWithComments(n, path)
 		if fieldExtendee != nil {
-			sci.newLoc(fieldExtendee.GetExtendee(), append(path, protointernal.FieldExtendeeTag))
+			sci.newLoc(fieldExtendee.GetExtendee(), append(path, descpath.FieldExtendeeTag))
 		}
 		if n.GetLabel() != nil {
-			sci.newLoc(n.GetLabel(), append(path, protointernal.FieldLabelTag))
+			sci.newLoc(n.GetLabel(), append(path, descpath.FieldLabelTag))
 		}
 		var fieldType string
 		if f, ok := n.(*ast.FieldNode); ok {
@@ -587,20 +611,20 @@ func generateSourceCodeInfoForField(opts OptionIndex, sci *sourceCodeInfo, n ast
 		}
 		var tag int32
 		if _, isScalar := protointernal.FieldTypes[fieldType]; isScalar {
-			tag = protointernal.FieldTypeTag
+			tag = descpath.FieldTypeTag
 		} else {
 			// this is a message or an enum, so attribute type location
 			// to the type name field
-			tag = protointernal.FieldTypeNameTag
+			tag = descpath.FieldTypeNameTag
 		}
 		sci.newLoc(n.GetFieldTypeNode(), append(path, tag))
-		sci.newLoc(n.GetName(), append(path, protointernal.FieldNameTag))
+		sci.newLoc(n.GetName(), append(path, descpath.FieldNameTag))
 	}
-	sci.newLoc(n.GetTag(), append(path, protointernal.FieldNumberTag))
+	sci.newLoc(n.GetTag(), append(path, descpath.FieldNumberTag))
 
 	if n.GetOptions() != nil {
 		optsPath := path
-		optsPath = append(optsPath, protointernal.FieldOptionsTag)
+		optsPath = append(optsPath, descpath.FieldOptionsTag)
 		sci.newLoc(n.GetOptions(), optsPath)
 		var optIndex int32
 		for _, opt := range n.GetOptions().GetElements() {
@@ -616,14 +640,14 @@ func generateSourceCodeInfoForExtensionRanges(opts OptionIndex, sci *sourceCodeI
 		path := append(path, *extRangeIndex)
 		*extRangeIndex++
 		sci.newLoc(child, path)
-		sci.newLoc(child.StartVal, append(path, protointernal.ExtensionRangeStartTag))
+		sci.newLoc(child.StartVal, append(path, descpath.ExtensionRangeStartTag))
 		switch {
 		case child.EndVal != nil:
-			sci.newLoc(child.EndVal, append(path, protointernal.ExtensionRangeEndTag))
+			sci.newLoc(child.EndVal, append(path, descpath.ExtensionRangeEndTag))
 		case child.Max != nil:
-			sci.newLoc(child.Max, append(path, protointernal.ExtensionRangeEndTag))
+			sci.newLoc(child.Max, append(path, descpath.ExtensionRangeEndTag))
 		default:
-			sci.newLoc(child.StartVal, append(path, protointernal.ExtensionRangeEndTag))
+			sci.newLoc(child.StartVal, append(path, descpath.ExtensionRangeEndTag))
 		}
 	}
 	// options for all ranges go after the start+end values
@@ -632,7 +656,7 @@ func generateSourceCodeInfoForExtensionRanges(opts OptionIndex, sci *sourceCodeI
 		startExtRangeIndex++
 		if n.Options != nil {
 			optsPath := path
-			optsPath = append(optsPath, protointernal.ExtensionRangeOptionsTag)
+			optsPath = append(optsPath, descpath.ExtensionRangeOptionsTag)
 			sci.newLoc(n.Options, optsPath)
 			var optIndex int32
 			for _, opt := range n.Options.GetElements() {
@@ -644,14 +668,14 @@ func generateSourceCodeInfoForExtensionRanges(opts OptionIndex, sci *sourceCodeI
 
 func generateSourceCodeInfoForService(opts OptionIndex, sci *sourceCodeInfo, n *ast.ServiceNode, path []int32) {
 	sci.newBlockLocWithComments(n, n.OpenBrace, path)
-	sci.newLoc(n.Name, append(path, protointernal.ServiceNameTag))
+	sci.newLoc(n.Name, append(path, descpath.ServiceNameTag))
 	var optIndex, rpcIndex int32
 	for _, child := range n.Decls {
 		switch child := child.Unwrap().(type) {
 		case *ast.OptionNode:
-			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, protointernal.ServiceOptionsTag))
+			generateSourceCodeInfoForOption(opts, sci, child, false, &optIndex, append(path, descpath.ServiceOptionsTag))
 		case *ast.RPCNode:
-			generateSourceCodeInfoForMethod(opts, sci, child, append(path, protointernal.ServiceMethodsTag, rpcIndex))
+			generateSourceCodeInfoForMethod(opts, sci, child, append(path, descpath.ServiceMethodsTag, rpcIndex))
 			rpcIndex++
 		}
 	}
@@ -663,22 +687,22 @@ func generateSourceCodeInfoForMethod(opts OptionIndex, sci *sourceCodeInfo, n *a
 	} else {
 		sci.newLocWithComments(n, path)
 	}
-	sci.newLoc(n.Name, append(path, protointernal.MethodNameTag))
+	sci.newLoc(n.Name, append(path, descpath.MethodNameTag))
 	if n.Input.Stream != nil {
-		sci.newLoc(n.Input.Stream, append(path, protointernal.MethodInputStreamTag))
+		sci.newLoc(n.Input.Stream, append(path, descpath.MethodInputStreamTag))
 	}
 	if n.Input.MessageType != nil {
-		sci.newLoc(n.Input.MessageType, append(path, protointernal.MethodInputTag))
+		sci.newLoc(n.Input.MessageType, append(path, descpath.MethodInputTag))
 	}
 	if n.Output.Stream != nil {
-		sci.newLoc(n.Output.Stream, append(path, protointernal.MethodOutputStreamTag))
+		sci.newLoc(n.Output.Stream, append(path, descpath.MethodOutputStreamTag))
 	}
 	if n.Output.MessageType != nil {
-		sci.newLoc(n.Output.MessageType, append(path, protointernal.MethodOutputTag))
+		sci.newLoc(n.Output.MessageType, append(path, descpath.MethodOutputTag))
 	}
 
 	optsPath := path
-	optsPath = append(optsPath, protointernal.MethodOptionsTag)
+	optsPath = append(optsPath, descpath.MethodOptionsTag)
 	var optIndex int32
 	for _, decl := range n.Decls {
 		if opt := decl.GetOption(); opt != nil {
@@ -766,7 +790,7 @@ func (sci *sourceCodeInfo) newLocWithComments(n ast.Node, path []int32) {
 	sci.newLocWithGivenComments(nodeInfo, detachedComments, leadingComments, trailingComments, path)
 }
 
-func (sci *sourceCodeInfo) newLocWithGivenComments(nodeInfo ast.NodeInfo, detachedComments []comments, leadingComments comments, trailingComments comments, path []int32) {
+func (sci *sourceCodeInfo) newLocWithGivenComments(nodeInfo ast.NodeInfo, detachedComments []ast.Comments, leadingComments ast.Comments, trailingComments ast.Comments, path []int32) {
 	if (len(detachedComments) > 0 && sci.commentUsed(detachedComments[0])) ||
 		(len(detachedComments) == 0 && sci.commentUsed(leadingComments)) {
 		detachedComments = nil
@@ -800,28 +824,7 @@ func (sci *sourceCodeInfo) newLocWithGivenComments(nodeInfo ast.NodeInfo, detach
 	})
 }
 
-type comments interface {
-	Len() int
-	Index(int) ast.Comment
-}
-
-type subComments struct {
-	offs, n int
-	c       ast.Comments
-}
-
-func (s subComments) Len() int {
-	return s.n
-}
-
-func (s subComments) Index(i int) ast.Comment {
-	if i < 0 || i >= s.n {
-		panic(fmt.Errorf("runtime error: index out of range [%d] with length %d", i, s.n))
-	}
-	return s.c.Index(i + s.offs)
-}
-
-func (sci *sourceCodeInfo) getLeadingComments(n ast.Node) ([]comments, comments) {
+func (sci *sourceCodeInfo) getLeadingComments(n ast.Node) ([]ast.Comments, ast.Comments) {
 	s := n.Start()
 	info := sci.file.TokenInfo(s)
 	var prevInfo ast.NodeInfo
@@ -832,7 +835,7 @@ func (sci *sourceCodeInfo) getLeadingComments(n ast.Node) ([]comments, comments)
 	return d, l
 }
 
-func (sci *sourceCodeInfo) getTrailingComments(n ast.Node) comments {
+func (sci *sourceCodeInfo) getTrailingComments(n ast.Node) ast.Comments {
 	var e ast.Token
 	if s, ok := n.(interface{ SourceInfoEnd() ast.Token }); ok {
 		e = s.SourceInfoEnd()
@@ -849,84 +852,9 @@ func (sci *sourceCodeInfo) getTrailingComments(n ast.Node) comments {
 	return t
 }
 
-func (sci *sourceCodeInfo) attributeComments(prevInfo, info ast.NodeInfo) (t comments, d []comments, l comments) {
-	detached := groupComments(info.LeadingComments())
-	var trail comments
-	if prevInfo.IsValid() {
-		trail = comments(prevInfo.TrailingComments())
-		if trail.Len() == 0 {
-			trail, detached = sci.maybeDonate(prevInfo, info, detached)
-		}
-	} else {
-		trail = ast.EmptyComments
-	}
-	detached, lead := sci.maybeAttach(prevInfo, info, trail.Len() > 0, detached)
-	return trail, detached, lead
-}
-
-func (sci *sourceCodeInfo) maybeDonate(prevInfo ast.NodeInfo, info ast.NodeInfo, lead []comments) (t comments, l []comments) {
-	if len(lead) == 0 {
-		// nothing to donate
-		return ast.EmptyComments, nil
-	}
-	firstCommentPos := lead[0].Index(0)
-	if firstCommentPos.Start().Line > prevInfo.End().Line+1 {
-		// first comment is detached from previous token, so can't be a trailing comment
-		return ast.EmptyComments, lead
-	}
-	if len(lead) > 1 {
-		// multiple groups? then donate first comment to previous token
-		return lead[0], lead[1:]
-	}
-	// there is only one element in lead
-	comment := lead[0]
-	lastCommentPos := comment.Index(comment.Len() - 1)
-	if lastCommentPos.End().Line < info.Start().Line-1 {
-		// there is a blank line between the comments and subsequent token, so
-		// we can donate the comment to previous token
-		return comment, nil
-	}
-	if txt := info.RawText(); txt == "" || (len(txt) == 1 && strings.ContainsAny(txt, "}]),;")) {
-		// token is a symbol for the end of a scope or EOF, which doesn't need a leading comment
-		if !sci.extraComments && txt != "" &&
-			firstCommentPos.Start().Line == prevInfo.End().Line &&
-			lastCommentPos.End().Line == info.Start().Line {
-			// protoc does not donate if prev and next token are on the same line since it's
-			// ambiguous which one should get the comment; so we mirror that here
-			return ast.EmptyComments, lead
-		}
-		// But with extra comments, we always donate in this situation in order to capture
-		// more comments. Because otherwise, these comments are lost since these symbols
-		// don't map to a location in source code info.
-		return comment, nil
-	}
-	// cannot donate
-	return ast.EmptyComments, lead
-}
-
-func (sci *sourceCodeInfo) maybeAttach(prevInfo ast.NodeInfo, info ast.NodeInfo, hasTrail bool, lead []comments) (d []comments, l comments) {
-	if len(lead) == 0 {
-		return nil, ast.EmptyComments
-	}
-
-	if len(lead) == 1 && !hasTrail && prevInfo.IsValid() {
-		// If the one comment appears attached to both previous and next tokens,
-		// don't attach to either.
-		comment := lead[0]
-		attachedToPrevious := comment.Index(0).Start().Line == prevInfo.End().Line
-		attachedToNext := comment.Index(comment.Len()-1).End().Line == info.Start().Line
-		if attachedToPrevious && attachedToNext {
-			// Since attachment is ambiguous, leave it detached.
-			return lead, ast.EmptyComments
-		}
-	}
-
-	lastComment := lead[len(lead)-1]
-	if lastComment.Index(lastComment.Len()-1).End().Line >= info.Start().Line-1 {
-		return lead[:len(lead)-1], lastComment
-	}
-
-	return lead, ast.EmptyComments
+func (sci *sourceCodeInfo) attributeComments(prevInfo, info ast.NodeInfo) (t ast.Comments, d []ast.Comments, l ast.Comments) {
+	attr := ast.AttributeComments(prevInfo, info, sci.extraComments)
+	return attr.Trailing, attr.Detached, attr.Leading
 }
 
 func makeSpan(start, end ast.SourcePos) []int32 {
@@ -936,7 +864,7 @@ func makeSpan(start, end ast.SourcePos) []int32 {
 	return []int32{int32(start.Line) - 1, int32(start.Col) - 1, int32(end.Line) - 1, int32(end.Col) - 1}
 }
 
-func (sci *sourceCodeInfo) commentUsed(c comments) bool {
+func (sci *sourceCodeInfo) commentUsed(c ast.Comments) bool {
 	if c.Len() == 0 {
 		return false
 	}
@@ -949,31 +877,7 @@ func (sci *sourceCodeInfo) commentUsed(c comments) bool {
 	return false
 }
 
-func groupComments(cmts ast.Comments) []comments {
-	if cmts.Len() == 0 {
-		return nil
-	}
-	var groups []comments
-	singleLineStyle := cmts.Index(0).RawText()[:2] == "//"
-	line := cmts.Index(0).End().Line
-	start := 0
-	for i := 1; i < cmts.Len(); i++ {
-		c := cmts.Index(i)
-		prevSingleLine := singleLineStyle
-		singleLineStyle = strings.HasPrefix(c.RawText(), "//")
-		if !singleLineStyle || prevSingleLine != singleLineStyle || c.Start().Line > line+1 {
-			// new group!
-			groups = append(groups, subComments{offs: start, n: i - start, c: cmts})
-			start = i
-		}
-		line = c.End().Line
-	}
-	// don't forget last group
-	groups = append(groups, subComments{offs: start, n: cmts.Len() - start, c: cmts})
-	return groups
-}
-
-func (sci *sourceCodeInfo) combineComments(comments comments) string {
+func (sci *sourceCodeInfo) combineComments(comments ast.Comments) string {
 	if comments.Len() == 0 {
 		return ""
 	}
@@ -0,0 +1,104 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourceinfo
+
+import (
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+// SmallestContaining returns the location in info with the smallest span that
+// contains the given range, which identifies the innermost element of the file
+// that is selected. It returns nil if no location contains the range.
+//
+// The range is given with one-based lines and columns, as in the AST, and its
+// end is exclusive, like the spans in source code info. An empty range, whose
+// start and end are the same, can be used to query for the element at a
+// position. If more than one location has the same smallest span, the one with
+// the longest path is returned, and then the first in info.
+func SmallestContaining(info *descriptorpb.SourceCodeInfo, span ast.SourceSpan) *descriptorpb.SourceCodeInfo_Location {
+	start, end := spanPos(span.Start()), spanPos(span.End())
+	var smallest *descriptorpb.SourceCodeInfo_Location
+	var smallestStart, smallestEnd linePos
+	for _, loc := range info.GetLocation() {
+		locStart, locEnd, ok := locationSpan(loc)
+		if !ok || start.less(locStart) || locEnd.less(end) {
+			continue
+		}
+		// All locations that contain the range contain each other, unless
+		// they overlap, so a smaller one starts later or ends earlier.
+		if smallest == nil ||
+			smallestStart.less(locStart) || locEnd.less(smallestEnd) ||
+			(locStart == smallestStart && locEnd == smallestEnd && len(loc.Path) > len(smallest.Path)) {
+			smallest, smallestStart, smallestEnd = loc, locStart, locEnd
+		}
+	}
+	return smallest
+}
+
+// Intersecting returns the locations in info whose spans intersect the given
+// range, in the order they appear in info. The range is given the same way as
+// for SmallestContaining. An empty range intersects the locations that contain
+// it.
+func Intersecting(info *descriptorpb.SourceCodeInfo, span ast.SourceSpan) []*descriptorpb.SourceCodeInfo_Location {
+	start, end := spanPos(span.Start()), spanPos(span.End())
+	var locs []*descriptorpb.SourceCodeInfo_Location
+	for _, loc := range info.GetLocation() {
+		locStart, locEnd, ok := locationSpan(loc)
+		if !ok {
+			continue
+		}
+		var intersects bool
+		if start == end {
+			intersects = !start.less(locStart) && !locEnd.less(end)
+		} else {
+			intersects = locStart.less(end) && start.less(locEnd)
+		}
+		if intersects {
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// linePos is a zero-based line and column, as in source code info.
+type linePos struct {
+	line, col int32
+}
+
+func (p linePos) less(other linePos) bool {
+	if p.line != other.line {
+		return p.line < other.line
+	}
+	return p.col < other.col
+}
+
+func spanPos(pos ast.SourcePos) linePos {
+	return linePos{line: int32(pos.Line - 1), col: int32(pos.Col - 1)}
+}
+
+// locationSpan returns the start and end of loc's span. It returns false if
+// the span is malformed.
+func locationSpan(loc *descriptorpb.SourceCodeInfo_Location) (start, end linePos, ok bool) {
+	switch span := loc.GetSpan(); len(span) {
+	case 3:
+		return linePos{span[0], span[1]}, linePos{span[0], span[2]}, true
+	case 4:
+		return linePos{span[0], span[1]}, linePos{span[2], span[3]}, true
+	default:
+		return linePos{}, linePos{}, false
+	}
+}
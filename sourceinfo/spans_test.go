@@ -0,0 +1,81 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourceinfo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/sourceinfo"
+)
+
+func TestSpanQueries(t *testing.T) {
+	t.Parallel()
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"test.proto": `syntax = "proto3";
+message Thing {
+  string name = 1;
+  int32 id = 2;
+}
+`}),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	fds, err := compiler.Compile(context.Background(), "test.proto")
+	require.NoError(t, err)
+	info := fds.Files[0].(linker.Result).FileDescriptorProto().SourceCodeInfo
+
+	span := func(startLine, startCol, endLine, endCol int) ast.SourceSpan {
+		return ast.NewSourceSpan(
+			ast.SourcePos{Line: startLine, Col: startCol},
+			ast.SourcePos{Line: endLine, Col: endCol},
+		)
+	}
+
+	// cursor in a field's name
+	loc := sourceinfo.SmallestContaining(info, span(3, 11, 3, 11))
+	require.NotNil(t, loc)
+	assert.Equal(t, "message_type[0].field[0].name", protoreflect.SourcePath(loc.Path).String()[1:])
+
+	// selection of both fields
+	loc = sourceinfo.SmallestContaining(info, span(3, 3, 4, 16))
+	require.NotNil(t, loc)
+	assert.Equal(t, "message_type[0]", protoreflect.SourcePath(loc.Path).String()[1:])
+
+	// selection past the end of the file
+	assert.Nil(t, sourceinfo.SmallestContaining(info, span(1, 1, 10, 1)))
+
+	var paths []string
+	for _, loc := range sourceinfo.Intersecting(info, span(3, 12, 4, 5)) {
+		paths = append(paths, protoreflect.SourcePath(loc.Path).String())
+	}
+	assert.Equal(t, []string{
+		"",
+		".message_type[0]",
+		".message_type[0].field[0]",
+		".message_type[0].field[0].name",
+		".message_type[0].field[0].number",
+		".message_type[0].field[1]",
+		".message_type[0].field[1].type",
+	}, paths)
+}
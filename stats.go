@@ -0,0 +1,183 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Phase identifies a phase of compiling a single file.
+type Phase int
+
+const (
+	// PhaseParse is the phase in which source code is parsed into an AST and
+	// then into a descriptor proto.
+	PhaseParse Phase = iota
+	// PhaseLink is the phase in which references are resolved and the symbol
+	// table is updated.
+	PhaseLink
+	// PhaseOptions is the phase in which options are interpreted and validated.
+	PhaseOptions
+	// PhaseSourceInfo is the phase in which source code info is generated.
+	PhaseSourceInfo
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseParse:
+		return "parse"
+	case PhaseLink:
+		return "link"
+	case PhaseOptions:
+		return "options"
+	case PhaseSourceInfo:
+		return "sourceinfo"
+	default:
+		return "unknown"
+	}
+}
+
+// FileStats contains statistics about the compilation of a single file.
+// Durations are zero for phases that did not run, for example because the
+// resolver provided a descriptor proto instead of source code, or because
+// source code info was not requested.
+type FileStats struct {
+	Parse      time.Duration
+	Link       time.Duration
+	Options    time.Duration
+	SourceInfo time.Duration
+
+	// The size of the file's source code, in bytes. Zero if the file was not
+	// compiled from source.
+	SourceBytes int
+	// The SHA-256 hash of the file's source code. Zero if the file was not
+	// compiled from source.
+	SourceHash [sha256.Size]byte
+	// The number of tokens in the file's AST. Zero if the file has no AST.
+	Tokens int
+	// The number of symbols (messages, fields, oneofs, enums, enum values,
+	// extensions, services, and methods) declared in the file.
+	Symbols int
+}
+
+// Total returns the sum of the durations of all phases.
+func (s FileStats) Total() time.Duration {
+	return s.Parse + s.Link + s.Options + s.SourceInfo
+}
+
+func (s *FileStats) add(phase Phase, d time.Duration) {
+	switch phase {
+	case PhaseParse:
+		s.Parse += d
+	case PhaseLink:
+		s.Link += d
+	case PhaseOptions:
+		s.Options += d
+	case PhaseSourceInfo:
+		s.SourceInfo += d
+	}
+}
+
+// PprofLabels is a function that can be used for CompilerHooks.RunPhase. It
+// runs each phase with pprof labels "protocompile.file" and "protocompile.phase"
+// so that CPU profiles can be broken down by file and by phase.
+func PprofLabels(ctx context.Context, path ResolvedPath, phase Phase, run func(context.Context)) {
+	pprof.Do(ctx, pprof.Labels("protocompile.file", string(path), "protocompile.phase", phase.String()), run)
+}
+
+type statsCollector struct {
+	mu    sync.Mutex
+	files map[ResolvedPath]*FileStats
+}
+
+func (c *statsCollector) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = map[ResolvedPath]*FileStats{}
+}
+
+func (c *statsCollector) put(path ResolvedPath, stats *FileStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[path] = stats
+}
+
+func (c *statsCollector) snapshot() map[ResolvedPath]FileStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := make(map[ResolvedPath]FileStats, len(c.files))
+	for path, stats := range c.files {
+		snapshot[path] = *stats
+	}
+	return snapshot
+}
+
+// runPhase runs fn as the given phase of compiling this task's file,
+// recording its duration and invoking the RunPhase hook, if any.
+func (t *task) runPhase(ctx context.Context, phase Phase, fn func(context.Context)) {
+	tracing := t.e.logEnabled(ctx, LevelTrace)
+	if tracing {
+		t.e.log(ctx, LevelTrace, "phase started", slog.String("path", string(t.r.resolvedPath)), slog.String("phase", phase.String()))
+	}
+	start := time.Now()
+	if hook := t.e.hooks.RunPhase; hook != nil {
+		ran := t.e.invokeHook(ctx, t.h, "RunPhase", t.r.resolvedPath, fn, func(run func(context.Context)) {
+			hook(ctx, t.r.resolvedPath, phase, run)
+		})
+		if !ran {
+			// the hook is required to call run; don't silently skip the phase
+			fn(ctx)
+		}
+	} else {
+		fn(ctx)
+	}
+	elapsed := time.Since(start)
+	t.stats.add(phase, elapsed)
+	if tracing {
+		t.e.log(ctx, LevelTrace, "phase finished", slog.String("path", string(t.r.resolvedPath)), slog.String("phase", phase.String()), slog.Duration("elapsed", elapsed))
+	}
+}
+
+func countSymbols(fd *descriptorpb.FileDescriptorProto) int {
+	n := countEnumSymbols(fd.EnumType) + countMessageSymbols(fd.MessageType) + len(fd.Extension) + len(fd.Service)
+	for _, svc := range fd.Service {
+		n += len(svc.Method)
+	}
+	return n
+}
+
+func countMessageSymbols(msgs []*descriptorpb.DescriptorProto) int {
+	n := len(msgs)
+	for _, msg := range msgs {
+		n += len(msg.Field) + len(msg.OneofDecl) + len(msg.Extension)
+		n += countMessageSymbols(msg.NestedType) + countEnumSymbols(msg.EnumType)
+	}
+	return n
+}
+
+func countEnumSymbols(enums []*descriptorpb.EnumDescriptorProto) int {
+	n := len(enums)
+	for _, enum := range enums {
+		n += len(enum.Value)
+	}
+	return n
+}
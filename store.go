@@ -0,0 +1,238 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/kralicky/protocompile/linker"
+)
+
+// ResultStoreOptions configures the limits of a ResultStore. Zero values mean
+// no limit.
+type ResultStoreOptions struct {
+	// The maximum number of results that keep their ASTs.
+	MaxFull int
+	// The maximum estimated size, in bytes, of the ASTs kept by all results.
+	// See linker.Result.MemoryUsage.
+	MaxASTBytes int64
+}
+
+// ResultStore holds linked results for a set of files, but keeps the ASTs of
+// only the most recently used ones, within the configured limits. The other
+// results keep only their descriptors and indexes, and their ASTs are
+// reloaded when they are next accessed with Get. This bounds the memory used
+// by long-running processes, such as language servers, while keeping the
+// files that are being worked on readily available.
+//
+// ASTs are reloaded with linker.Result.ReloadAST, which works for results
+// produced by a Compiler from source code, as long as the compiler's resolver
+// still provides the same source. Reloading fails if the source has changed,
+// in which case the file should be compiled again and stored with Put.
+//
+// All methods are safe for concurrent use. However, a result's AST is removed
+// when it is evicted, which may happen during any call to Put or Get, so a
+// result must not be used concurrently with other calls to the store. Callers
+// that need a result's AST across such calls should call Get again.
+type ResultStore struct {
+	opts ResultStoreOptions
+
+	mu      sync.Mutex
+	entries map[ResolvedPath]*storeEntry
+	// entries whose results have ASTs, most recently used first
+	full     list.List
+	astBytes int64
+	stats    ResultStoreStats
+}
+
+type storeEntry struct {
+	res linker.Result
+	// held while the result's AST is reloaded or removed, so that reloading,
+	// which parses the file, does not need the store's lock
+	mu sync.Mutex
+	// the element in the store's list of full entries, or nil if the
+	// result's AST has been removed
+	elem     *list.Element
+	astBytes int64
+}
+
+// ResultStoreStats are counters that describe the activity of a ResultStore.
+type ResultStoreStats struct {
+	// The number of results in the store, and the number of them that have
+	// ASTs.
+	Files, Full int
+	// The estimated size, in bytes, of the ASTs kept by the store.
+	ASTBytes int64
+	// The number of times an AST has been removed to stay within the limits,
+	// and the number of times one has been reloaded.
+	Evictions, Reloads int
+}
+
+// NewResultStore returns an empty store with the given limits.
+func NewResultStore(opts ResultStoreOptions) *ResultStore {
+	return &ResultStore{
+		opts:    opts,
+		entries: map[ResolvedPath]*storeEntry{},
+	}
+}
+
+// Put adds the given results to the store, replacing any results for the same
+// files, and marks them as the most recently used, in order, so that the
+// last one is the most recent. Results that are not linker.Result values,
+// such as standard imports that were not compiled from source, are ignored.
+// Results with ASTs may cause the ASTs of the least recently used results to
+// be removed.
+func (s *ResultStore) Put(files ...linker.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range files {
+		res, ok := f.(linker.Result)
+		if !ok {
+			continue
+		}
+		path := ResolvedPath(res.Path())
+		if old := s.entries[path]; old != nil {
+			s.dropLocked(old)
+		}
+		entry := &storeEntry{res: res}
+		s.entries[path] = entry
+		if res.AST() != nil {
+			s.touchLocked(entry)
+		}
+	}
+	s.evictLocked()
+}
+
+// Get returns the result for the given file, reloading its AST if it was
+// removed, and marks it as the most recently used. It returns nil if the store
+// has no result for the file. If the AST cannot be reloaded, the result is
+// returned without an AST, along with the error. Other calls to the store are
+// not blocked while the AST is reloaded.
+func (s *ResultStore) Get(ctx context.Context, path ResolvedPath) (linker.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entries[path]
+	if entry == nil {
+		return nil, nil
+	}
+	if entry.elem == nil {
+		s.mu.Unlock()
+		reloaded, err := entry.reload(ctx)
+		s.mu.Lock()
+		if reloaded {
+			s.stats.Reloads++
+		}
+		if err != nil {
+			return entry.res, err
+		}
+		if s.entries[path] != entry {
+			// replaced or removed while reloading
+			return entry.res, nil
+		}
+	}
+	s.touchLocked(entry)
+	s.evictLocked()
+	return entry.res, nil
+}
+
+// reload reloads the entry's AST if it has none. It returns true if the AST
+// was reloaded by this call.
+func (e *storeEntry) reload(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.res.AST() != nil {
+		return false, nil
+	}
+	if err := e.res.ReloadAST(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Peek returns the result for the given file, or nil if there is none,
+// without reloading its AST or marking it as used.
+func (s *ResultStore) Peek(path ResolvedPath) linker.Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.entries[path]; entry != nil {
+		return entry.res
+	}
+	return nil
+}
+
+// Remove removes the result for the given file from the store.
+func (s *ResultStore) Remove(path ResolvedPath) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.entries[path]; entry != nil {
+		s.dropLocked(entry)
+		delete(s.entries, path)
+	}
+}
+
+// Stats returns the store's current statistics.
+func (s *ResultStore) Stats() ResultStoreStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Files = len(s.entries)
+	stats.Full = s.full.Len()
+	stats.ASTBytes = s.astBytes
+	return stats
+}
+
+// touchLocked marks the given entry, whose result has an AST, as the most
+// recently used.
+func (s *ResultStore) touchLocked(entry *storeEntry) {
+	if entry.elem != nil {
+		s.full.MoveToFront(entry.elem)
+		return
+	}
+	entry.elem = s.full.PushFront(entry)
+	entry.astBytes = entry.res.MemoryUsage().AST
+	s.astBytes += entry.astBytes
+}
+
+// dropLocked removes the given entry from the list of full entries, without
+// changing its result.
+func (s *ResultStore) dropLocked(entry *storeEntry) {
+	if entry.elem == nil {
+		return
+	}
+	s.full.Remove(entry.elem)
+	s.astBytes -= entry.astBytes
+	entry.elem = nil
+	entry.astBytes = 0
+}
+
+// evictLocked removes the ASTs of the least recently used results until the
+// store is within its limits. The most recently used result is never evicted.
+func (s *ResultStore) evictLocked() {
+	for s.full.Len() > 1 && s.overLimitLocked() {
+		entry := s.full.Back().Value.(*storeEntry) //nolint:errcheck // list only contains *storeEntry
+		s.dropLocked(entry)
+		entry.mu.Lock()
+		entry.res.RemoveAST()
+		entry.mu.Unlock()
+		s.stats.Evictions++
+	}
+}
+
+func (s *ResultStore) overLimitLocked() bool {
+	return (s.opts.MaxFull > 0 && s.full.Len() > s.opts.MaxFull) ||
+		(s.opts.MaxASTBytes > 0 && s.astBytes > s.opts.MaxASTBytes)
+}
@@ -0,0 +1,157 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kralicky/protocompile/linker"
+)
+
+func TestResultStore(t *testing.T) {
+	t.Parallel()
+	contents := map[UnresolvedPath]string{}
+	for path, src := range baseContents {
+		contents[path] = src
+	}
+	compile := func(t *testing.T) linker.Files {
+		t.Helper()
+		comp := Compiler{
+			Resolver:   WithStandardImports(mkResolver(contents)),
+			RetainASTs: true,
+		}
+		res, err := comp.Compile(context.Background(), "a/b/b1.proto", "a/b/b2.proto", "c/c.proto")
+		require.NoError(t, err)
+		return res.Files
+	}
+	files := compile(t)
+	ctx := context.Background()
+
+	store := NewResultStore(ResultStoreOptions{MaxFull: 2})
+	store.Put(files...)
+	stats := store.Stats()
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, 2, stats.Full)
+	assert.Equal(t, 1, stats.Evictions)
+	assert.Positive(t, stats.ASTBytes)
+	// the least recently used file was evicted, but keeps its descriptors
+	b1 := store.Peek("a/b/b1.proto")
+	require.NotNil(t, b1)
+	assert.Nil(t, b1.AST())
+	assert.NotNil(t, b1.Messages().ByName("BeeOne"))
+
+	// accessing it reloads its AST and evicts the next least recently used
+	res, err := store.Get(ctx, "a/b/b1.proto")
+	require.NoError(t, err)
+	assert.Same(t, b1, res)
+	assert.NotNil(t, res.AST())
+	assert.Nil(t, store.Peek("a/b/b2.proto").AST())
+	assert.NotNil(t, store.Peek("c/c.proto").AST())
+	stats = store.Stats()
+	assert.Equal(t, 2, stats.Full)
+	assert.Equal(t, 2, stats.Evictions)
+	assert.Equal(t, 1, stats.Reloads)
+
+	res, err = store.Get(ctx, "missing.proto")
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	// reloading fails if the source has changed
+	contents["a/b/b2.proto"] += "\n// changed\n"
+	res, err = store.Get(ctx, "a/b/b2.proto")
+	require.ErrorContains(t, err, "source has changed")
+	require.NotNil(t, res)
+	assert.Nil(t, res.AST())
+
+	// recompiled results replace the old ones
+	store.Put(compile(t)...)
+	res, err = store.Get(ctx, "a/b/b2.proto")
+	require.NoError(t, err)
+	assert.NotNil(t, res.AST())
+
+	store.Remove("a/b/b2.proto")
+	assert.Nil(t, store.Peek("a/b/b2.proto"))
+	assert.Equal(t, 2, store.Stats().Files)
+	assert.Equal(t, 1, store.Stats().Full)
+}
+
+func TestResultStoreMaxASTBytes(t *testing.T) {
+	t.Parallel()
+	comp := Compiler{
+		Resolver:   WithStandardImports(mkResolver(baseContents)),
+		RetainASTs: true,
+	}
+	res, err := comp.Compile(context.Background(), "a/b/b1.proto", "a/b/b2.proto", "c/c.proto")
+	require.NoError(t, err)
+	largest := int64(0)
+	for _, f := range res.Files {
+		largest = max(largest, f.(linker.Result).MemoryUsage().AST)
+	}
+
+	store := NewResultStore(ResultStoreOptions{MaxASTBytes: largest})
+	store.Put(res.Files...)
+	stats := store.Stats()
+	assert.Equal(t, 1, stats.Full)
+	assert.LessOrEqual(t, stats.ASTBytes, largest)
+	assert.NotNil(t, store.Peek("c/c.proto").AST())
+}
+
+func TestResultStoreReloadDoesNotBlock(t *testing.T) {
+	t.Parallel()
+	var blockReload atomic.Bool
+	reloading := make(chan struct{})
+	release := make(chan struct{})
+	resolver := mkResolver(baseContents)
+	comp := Compiler{
+		Resolver: WithStandardImports(ResolverFunc(func(path UnresolvedPath, whence ImportContext) (SearchResult, error) {
+			if path == "a/b/b1.proto" && blockReload.Load() {
+				close(reloading)
+				<-release
+			}
+			return resolver.FindFileByPath(path, whence)
+		})),
+		RetainASTs: true,
+	}
+	res, err := comp.Compile(context.Background(), "a/b/b1.proto", "c/c.proto")
+	require.NoError(t, err)
+
+	store := NewResultStore(ResultStoreOptions{MaxFull: 1})
+	store.Put(res.Files...)
+	require.Nil(t, store.Peek("a/b/b1.proto").AST())
+
+	blockReload.Store(true)
+	done := make(chan error)
+	go func() {
+		_, err := store.Get(context.Background(), "a/b/b1.proto")
+		done <- err
+	}()
+	<-reloading
+	// the store can be used while another file's AST is reloaded
+	c, err := store.Get(context.Background(), "c/c.proto")
+	require.NoError(t, err)
+	assert.NotNil(t, c.AST())
+	assert.Equal(t, 0, store.Stats().Reloads)
+	close(release)
+	require.NoError(t, <-done)
+
+	assert.NotNil(t, store.Peek("a/b/b1.proto").AST())
+	assert.Nil(t, store.Peek("c/c.proto").AST())
+	assert.Equal(t, 1, store.Stats().Reloads)
+}
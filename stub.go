@@ -0,0 +1,210 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/parser"
+	"github.com/kralicky/protocompile/reporter"
+)
+
+// MethodTemplate describes a method to synthesize with Workspace.StubMethod
+// or Workspace.StubService.
+type MethodTemplate struct {
+	Name string
+	// The request and response types, as they are written in the method
+	// declaration. They are resolved relative to the service, like other
+	// type names, and must be messages.
+	RequestType, ResponseType string
+	ClientStreaming           bool
+	ServerStreaming           bool
+}
+
+// MethodStub is a synthesized method declaration. See Workspace.StubMethod.
+type MethodStub struct {
+	// The method's descriptor, with fully-qualified request and response
+	// types, as in linked descriptors.
+	Method *descriptorpb.MethodDescriptorProto
+	// The method's declaration. It is parsed from Source, so positions in
+	// the node are not positions in the service's file.
+	Node *ast.RPCNode
+	// The formatted source of the declaration, without indentation or a
+	// trailing newline.
+	Source string
+	// The paths of the files that declare the request and response types and
+	// that the service's file does not already import, sorted.
+	Imports []string
+}
+
+// ServiceStub is a synthesized service declaration. See Workspace.StubService.
+type ServiceStub struct {
+	// The service's descriptor, with fully-qualified request and response
+	// types for its methods, as in linked descriptors.
+	Service *descriptorpb.ServiceDescriptorProto
+	// The service's declaration. It is parsed from Source, so positions in
+	// the node are not positions in the file that will contain it.
+	Node *ast.ServiceNode
+	// The formatted source of the declaration, without a trailing newline.
+	// Methods are indented with two spaces.
+	Source string
+	// The paths of the files that declare the request and response types and
+	// that the service's file does not already import, sorted.
+	Imports []string
+}
+
+// StubMethod synthesizes a new method for the service with the given name,
+// for "add RPC" code actions and scaffolding tools. It returns an error if
+// the service already has a method with the template's name, if the name is
+// not a valid identifier, or if the request or response type is not a
+// message in the workspace.
+func (w *Workspace) StubMethod(service protoreflect.FullName, tmpl MethodTemplate) (*MethodStub, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var sd protoreflect.ServiceDescriptor
+	for _, f := range w.files {
+		if d, ok := f.FindDescriptorByName(service).(protoreflect.ServiceDescriptor); ok {
+			sd = d
+			break
+		}
+	}
+	if sd == nil {
+		return nil, fmt.Errorf("service %s not found", service)
+	}
+	if sd.Methods().ByName(protoreflect.Name(tmpl.Name)) != nil {
+		return nil, fmt.Errorf("service %s already has a method named %s", service, tmpl.Name)
+	}
+	stub, err := w.stubService(sd.ParentFile(), string(sd.Name()), service, []MethodTemplate{tmpl})
+	if err != nil {
+		return nil, err
+	}
+	return &MethodStub{
+		Method:  stub.Service.GetMethod()[0],
+		Node:    stub.Node.GetDecls()[0].GetRpc(),
+		Source:  methodSource(tmpl),
+		Imports: stub.Imports,
+	}, nil
+}
+
+// StubService synthesizes a new service with the given name and methods, to
+// be declared in the given file. It returns an error if the file's package
+// already declares an element with the given name, if a name is not a valid
+// identifier, or if a request or response type is not a message in the
+// workspace.
+func (w *Workspace) StubService(path ResolvedPath, name string, methods ...MethodTemplate) (*ServiceStub, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	f, ok := w.files[path]
+	if !ok {
+		return nil, fmt.Errorf("%s has not been compiled", path)
+	}
+	fullName := protoreflect.FullName(name)
+	if f.Package() != "" {
+		fullName = f.Package() + "." + fullName
+	}
+	for _, other := range w.files {
+		if d := other.FindDescriptorByName(fullName); d != nil {
+			return nil, fmt.Errorf("%s is already declared in %s", fullName, d.ParentFile().Path())
+		}
+	}
+	return w.stubService(f, name, fullName, methods)
+}
+
+// stubService implements StubService and StubMethod. It synthesizes a
+// service in the given file with the given methods. w.mu must be held.
+func (w *Workspace) stubService(file protoreflect.FileDescriptor, name string, fullName protoreflect.FullName, methods []MethodTemplate) (*ServiceStub, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "service %s {", name)
+	for _, mtd := range methods {
+		sb.WriteString("\n  " + methodSource(mtd))
+	}
+	if len(methods) > 0 {
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('}')
+	source := sb.String()
+
+	// parse the declaration in a file of its own, which also validates names
+	const stubFile = "stub.proto"
+	handler := reporter.NewHandler(nil)
+	node, err := parser.Parse(stubFile, strings.NewReader("syntax = \"proto3\";\n"+source+"\n"), handler, 0)
+	if err != nil {
+		return nil, err
+	}
+	res, err := parser.ResultFromAST(node, true, handler)
+	if err != nil {
+		return nil, err
+	}
+	var svcNode *ast.ServiceNode
+	for _, decl := range node.GetDecls() {
+		if svc := decl.GetService(); svc != nil {
+			svcNode = svc
+		}
+	}
+	if svcNode == nil || len(res.FileDescriptorProto().GetService()) != 1 ||
+		res.FileDescriptorProto().GetService()[0].GetName() != name {
+		return nil, fmt.Errorf("%q is not a valid service name", name)
+	}
+	svc := res.FileDescriptorProto().GetService()[0]
+	if len(svc.GetMethod()) != len(methods) {
+		return nil, fmt.Errorf("invalid method declarations in service %s", name)
+	}
+
+	imports := map[string]struct{}{}
+	for i, mtd := range svc.GetMethod() {
+		if mtd.GetName() != methods[i].Name {
+			return nil, fmt.Errorf("%q is not a valid method name", methods[i].Name)
+		}
+		for _, typ := range []*string{mtd.InputType, mtd.OutputType} {
+			md, ok := w.resolveTypeName(fullName, *typ).(protoreflect.MessageDescriptor)
+			if !ok {
+				return nil, fmt.Errorf("method %s.%s: %s is not a message in the workspace", fullName, mtd.GetName(), *typ)
+			}
+			*typ = "." + string(md.FullName())
+			if path := md.ParentFile().Path(); path != file.Path() && !importsFile(file, path) {
+				imports[path] = struct{}{}
+			}
+		}
+	}
+	stub := &ServiceStub{Service: svc, Node: svcNode, Source: source}
+	for path := range imports {
+		stub.Imports = append(stub.Imports, path)
+	}
+	sort.Strings(stub.Imports)
+	return stub, nil
+}
+
+// methodSource returns the declaration of the given method.
+func methodSource(tmpl MethodTemplate) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "rpc %s(", tmpl.Name)
+	if tmpl.ClientStreaming {
+		sb.WriteString("stream ")
+	}
+	sb.WriteString(tmpl.RequestType + ") returns (")
+	if tmpl.ServerStreaming {
+		sb.WriteString("stream ")
+	}
+	sb.WriteString(tmpl.ResponseType + ");")
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceStubs(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{
+		Resolver: WithStandardImports(mkResolver(map[UnresolvedPath]string{
+			"a.proto": `syntax = "proto3";
+package foo;
+import "google/protobuf/empty.proto";
+message GetRequest {}
+enum Kind { KIND_UNSPECIFIED = 0; }
+service Svc {
+  rpc Get(GetRequest) returns (google.protobuf.Empty);
+}
+`,
+			"b.proto": `syntax = "proto3";
+package foo.sub;
+message Event {}
+`,
+		})),
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "a.proto", "b.proto"))
+
+	mtd, err := ws.StubMethod("foo.Svc", MethodTemplate{
+		Name:            "Watch",
+		RequestType:     "GetRequest",
+		ResponseType:    "sub.Event",
+		ServerStreaming: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "rpc Watch(GetRequest) returns (stream sub.Event);", mtd.Source)
+	assert.Equal(t, "Watch", mtd.Method.GetName())
+	assert.Equal(t, ".foo.GetRequest", mtd.Method.GetInputType())
+	assert.Equal(t, ".foo.sub.Event", mtd.Method.GetOutputType())
+	assert.False(t, mtd.Method.GetClientStreaming())
+	assert.True(t, mtd.Method.GetServerStreaming())
+	assert.Equal(t, "Watch", mtd.Node.GetName().GetVal())
+	assert.Equal(t, []string{"b.proto"}, mtd.Imports)
+
+	svc, err := ws.StubService("a.proto", "Admin",
+		MethodTemplate{Name: "Reset", RequestType: "google.protobuf.Empty", ResponseType: ".google.protobuf.Empty"},
+		MethodTemplate{Name: "Upload", RequestType: "GetRequest", ResponseType: "GetRequest", ClientStreaming: true},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `service Admin {
+  rpc Reset(google.protobuf.Empty) returns (.google.protobuf.Empty);
+  rpc Upload(stream GetRequest) returns (GetRequest);
+}`, svc.Source)
+	assert.Equal(t, "Admin", svc.Service.GetName())
+	require.Len(t, svc.Service.GetMethod(), 2)
+	assert.Equal(t, ".google.protobuf.Empty", svc.Service.GetMethod()[0].GetInputType())
+	assert.True(t, svc.Service.GetMethod()[1].GetClientStreaming())
+	assert.Len(t, svc.Node.GetDecls(), 2)
+	assert.Empty(t, svc.Imports)
+
+	_, err = ws.StubMethod("foo.Svc", MethodTemplate{Name: "Get", RequestType: "GetRequest", ResponseType: "GetRequest"})
+	assert.ErrorContains(t, err, "already has a method named Get")
+	_, err = ws.StubMethod("foo.Svc", MethodTemplate{Name: "List", RequestType: "Kind", ResponseType: "GetRequest"})
+	assert.ErrorContains(t, err, "Kind is not a message in the workspace")
+	_, err = ws.StubMethod("foo.Svc", MethodTemplate{Name: "Bad Name", RequestType: "GetRequest", ResponseType: "GetRequest"})
+	assert.Error(t, err)
+	_, err = ws.StubService("a.proto", "Svc")
+	assert.ErrorContains(t, err, "foo.Svc is already declared in a.proto")
+}
@@ -0,0 +1,285 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symindex
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/descpath"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/walk"
+)
+
+// Builder accumulates the symbols of a set of files and serializes them into
+// an index. A Builder can be seeded with the contents of an existing index,
+// so that only files that have changed need to be re-added:
+//
+//	b := symindex.NewBuilder(previous)
+//	for _, f := range changedFiles {
+//		b.AddFile(f)
+//	}
+//	for _, path := range deletedFiles {
+//		b.RemoveFile(path)
+//	}
+//	data := b.Bytes()
+type Builder struct {
+	files map[string][]Symbol
+}
+
+// NewBuilder creates a new Builder. If base is not nil, the builder starts
+// out with all of the symbols in base. The builder copies what it needs, so
+// base may be closed once NewBuilder returns.
+func NewBuilder(base *Index) *Builder {
+	b := &Builder{files: map[string][]Symbol{}}
+	if base == nil {
+		return b
+	}
+	for i := 0; i < base.nfiles; i++ {
+		// Ensure that files with no symbols are preserved.
+		b.files[base.file(i)] = nil
+	}
+	for i := 0; i < base.nsymbols; i++ {
+		sym := base.Symbol(i)
+		b.files[sym.File] = append(b.files[sym.File], sym)
+	}
+	return b
+}
+
+// AddFile adds the symbols defined in the given file, replacing any symbols
+// previously added for a file with the same path. Positions are taken from
+// the file's AST if it is a linker.Result that still has one, or otherwise
+// from its source code info, if present.
+func (b *Builder) AddFile(file linker.File) {
+	var syms []Symbol
+	_ = walk.Descriptors(file, func(d protoreflect.Descriptor) error {
+		kind := kindOf(d)
+		if kind == 0 {
+			return nil
+		}
+		sym := Symbol{Name: d.FullName(), Kind: kind, File: file.Path()}
+		sym.Line, sym.Col = namePosition(file, d)
+		syms = append(syms, sym)
+		if md, ok := d.(protoreflect.MessageDescriptor); ok {
+			syms = append(syms, extensionDeclarations(file, md)...)
+		}
+		return nil
+	})
+	b.files[file.Path()] = syms
+}
+
+// AddFiles adds the symbols defined in all of the given files. See AddFile.
+func (b *Builder) AddFiles(files linker.Files) {
+	for _, f := range files {
+		b.AddFile(f)
+	}
+}
+
+// RemoveFile removes all symbols previously added for the file with the given
+// path.
+func (b *Builder) RemoveFile(path string) {
+	delete(b.files, path)
+}
+
+// Bytes returns the serialized index, which can be opened with Open.
+func (b *Builder) Bytes() []byte {
+	var buf bytes.Buffer
+	_, _ = b.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo writes the serialized index to w. Output is deterministic: the same
+// set of symbols always produces the same bytes.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	paths := make([]string, 0, len(b.files))
+	var syms []Symbol
+	for path, fileSyms := range b.files {
+		paths = append(paths, path)
+		syms = append(syms, fileSyms...)
+	}
+	slices.Sort(paths)
+	slices.SortFunc(syms, func(a, b Symbol) int {
+		if c := cmp.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.File, b.File)
+	})
+
+	var strs []byte
+	offsets := map[string]uint32{}
+	addString := func(rec []byte, s string) {
+		off, ok := offsets[s]
+		if !ok {
+			off = uint32(len(strs))
+			offsets[s] = off
+			strs = append(strs, s...)
+		}
+		binary.LittleEndian.PutUint32(rec, off)
+		binary.LittleEndian.PutUint32(rec[4:], uint32(len(s)))
+	}
+
+	fileIndexes := make(map[string]uint32, len(paths))
+	fileTable := make([]byte, len(paths)*fileSize)
+	for i, path := range paths {
+		fileIndexes[path] = uint32(i)
+		addString(fileTable[i*fileSize:], path)
+	}
+	symbolTable := make([]byte, len(syms)*symbolSize)
+	for i, sym := range syms {
+		rec := symbolTable[i*symbolSize:]
+		addString(rec, string(sym.Name))
+		binary.LittleEndian.PutUint32(rec[8:], fileIndexes[sym.File])
+		binary.LittleEndian.PutUint32(rec[12:], clampUint32(sym.Line))
+		binary.LittleEndian.PutUint32(rec[16:], clampUint32(sym.Col))
+		rec[20] = byte(sym.Kind)
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.LittleEndian.PutUint32(header[4:], Version)
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(paths)))
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(syms)))
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(strs)))
+
+	var total int64
+	for _, chunk := range [][]byte{header, fileTable, symbolTable, strs} {
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func clampUint32(v int) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
+
+func kindOf(d protoreflect.Descriptor) Kind {
+	switch d := d.(type) {
+	case protoreflect.MessageDescriptor:
+		if d.IsMapEntry() {
+			return 0
+		}
+		return KindMessage
+	case protoreflect.FieldDescriptor:
+		if d.IsExtension() {
+			return KindExtension
+		}
+		if d.ContainingMessage().IsMapEntry() {
+			return 0
+		}
+		return KindField
+	case protoreflect.OneofDescriptor:
+		if d.IsSynthetic() {
+			return 0
+		}
+		return KindOneof
+	case protoreflect.EnumDescriptor:
+		return KindEnum
+	case protoreflect.EnumValueDescriptor:
+		return KindEnumValue
+	case protoreflect.ServiceDescriptor:
+		return KindService
+	case protoreflect.MethodDescriptor:
+		return KindMethod
+	default:
+		return 0
+	}
+}
+
+// extensionDeclarations returns symbols for the extensions that are declared
+// in the options of the given message's extension ranges. Reserved
+// declarations are omitted.
+func extensionDeclarations(file linker.File, md protoreflect.MessageDescriptor) []Symbol {
+	var syms []Symbol
+	ranges := md.ExtensionRanges()
+	for i := 0; i < ranges.Len(); i++ {
+		opts, _ := md.ExtensionRangeOptions(i).(*descriptorpb.ExtensionRangeOptions)
+		for _, decl := range opts.GetDeclaration() {
+			if decl.GetReserved() || !strings.HasPrefix(decl.GetFullName(), ".") {
+				continue
+			}
+			sym := Symbol{
+				Name: protoreflect.FullName(decl.GetFullName()[1:]),
+				Kind: KindExtensionDeclaration,
+				File: file.Path(),
+			}
+			sym.Line, sym.Col = extensionRangePosition(file, md, i)
+			syms = append(syms, sym)
+		}
+	}
+	return syms
+}
+
+func extensionRangePosition(file linker.File, md protoreflect.MessageDescriptor, index int) (line, col int) {
+	if res, ok := file.(linker.Result); ok && res.AST() != nil {
+		if msg, ok := protoutil.ProtoFromDescriptor(md).(*descriptorpb.DescriptorProto); ok && index < len(msg.ExtensionRange) {
+			if node := res.ExtensionRangeNode(msg.ExtensionRange[index]); node != nil {
+				pos := res.AST().NodeInfo(node).Start()
+				return pos.Line, pos.Col
+			}
+		}
+	}
+	path, ok := descpath.ForDescriptor(md)
+	if !ok {
+		return 0, 0
+	}
+	loc := file.SourceLocations().ByPath(append(path, descpath.MessageExtensionRangesTag, int32(index)))
+	if protointernal.IsZeroSourceLocation(loc) {
+		return 0, 0
+	}
+	// source locations are zero-based
+	return loc.StartLine + 1, loc.StartColumn + 1
+}
+
+func namePosition(file linker.File, d protoreflect.Descriptor) (line, col int) {
+	if res, ok := file.(linker.Result); ok && res.AST() != nil {
+		node := res.Node(protoutil.ProtoFromDescriptor(d))
+		if named, ok := node.(interface{ GetName() *ast.IdentNode }); ok && named.GetName() != nil {
+			pos := res.AST().NodeInfo(named.GetName()).Start()
+			return pos.Line, pos.Col
+		}
+	}
+	path, ok := descpath.ForDescriptor(d)
+	if !ok {
+		return 0, 0
+	}
+	// The name is field 1 in every kind of descriptor proto.
+	loc := file.SourceLocations().ByPath(append(path, descpath.MessageNameTag))
+	if protointernal.IsZeroSourceLocation(loc) {
+		return 0, 0
+	}
+	// source locations are zero-based
+	return loc.StartLine + 1, loc.StartColumn + 1
+}
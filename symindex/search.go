@@ -0,0 +1,232 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symindex
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Scores assigned to each kind of match by Score. A higher score is a better
+// match. Within each kind, scores are adjusted so that closer matches rank
+// higher, but never enough to overlap with another kind.
+const (
+	ScoreExact     = 1000
+	ScoreExactFold = 900
+	ScorePrefix    = 800
+	ScoreCamelHump = 700
+	ScoreSubstring = 500
+	ScoreFuzzy     = 200
+)
+
+// Match is a symbol that matched a search query.
+type Match struct {
+	Symbol
+	// The score computed by Score. Higher is better.
+	Score int
+}
+
+// Score computes how well the given name matches a search query, reporting
+// false if it does not match at all. If the query contains a dot, it is
+// matched against the whole fully-qualified name; otherwise it is matched
+// against the simple name (the last component). The following kinds of
+// matches are recognized, from best to worst:
+//
+//   - exact: the name equals the query
+//   - exact, ignoring case: "request" matches "Request"
+//   - prefix, ignoring case: "req" matches "Request"
+//   - camel-hump: each part of the query is a prefix, ignoring case, of
+//     consecutive words of the name, where words begin at upper case
+//     letters, digits, and after underscores: "FDS" and "FiDeSet" match
+//     "FileDescriptorSet", "HR" matches "HTTPRequest", and "TS" matches
+//     "TYPE_STRING"
+//   - substring, ignoring case: "script" matches "FileDescriptorSet"
+//   - fuzzy: the characters of the query appear in order in the name,
+//     ignoring case: "fdst" matches "FileDescriptorSet"
+//
+// Search tools should rank results using this function so that matching
+// behaves consistently across tools.
+func Score(name, query string) (int, bool) {
+	return score([]byte(name), query)
+}
+
+func score(name []byte, query string) (int, bool) {
+	if query == "" {
+		return 0, false
+	}
+	if !strings.Contains(query, ".") {
+		name = name[bytes.LastIndexByte(name, '.')+1:]
+	}
+	// Penalize longer names slightly so that, for matches of the same kind,
+	// names closer to the query rank first.
+	extra := min(len(name)-len(query), 99)
+	switch {
+	case string(name) == query:
+		return ScoreExact, true
+	case len(name) == len(query) && hasPrefixFold(name, query):
+		return ScoreExactFold, true
+	case hasPrefixFold(name, query):
+		return ScorePrefix - extra, true
+	case camelHump(name, query):
+		return ScoreCamelHump - extra, true
+	}
+	if i := indexFold(name, query); i >= 0 {
+		return ScoreSubstring - min(i+extra, 199), true
+	}
+	if gaps, ok := fuzzy(name, query); ok {
+		return ScoreFuzzy - min(gaps+extra, 199), true
+	}
+	return 0, false
+}
+
+// Find returns the symbols that match the given query, as determined by
+// Score, ranked from best to worst. Ties are broken by name and then by file
+// path. If limit is greater than zero, at most limit matches are returned.
+func (ix *Index) Find(query string, limit int) []Match {
+	var matches []Match
+	for i := 0; i < ix.nsymbols; i++ {
+		s, ok := score(ix.name(i), query)
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Symbol: ix.Symbol(i), Score: s})
+	}
+	// Symbols are already sorted by name and file, so a stable sort by score
+	// produces the documented order.
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func hasPrefixFold(s []byte, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if lowerASCII(s[i]) != lowerASCII(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexFold(s []byte, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isWordStart reports whether a new word begins at s[i].
+func isWordStart(s []byte, i int) bool {
+	if i == 0 {
+		return s[i] != '_'
+	}
+	c, prev := s[i], s[i-1]
+	switch {
+	case c == '_' || c == '.':
+		return false
+	case prev == '_' || prev == '.':
+		return true
+	case isDigit(c):
+		return !isDigit(prev)
+	case isUpper(c):
+		// A run of capitals is a single word, like the "HTTP" in
+		// HTTPRequest, whose last letter may begin the next word. In an
+		// all-caps name like TYPE_STRING, words are only delimited by
+		// underscores.
+		if isUpper(prev) || isDigit(prev) {
+			return i+1 < len(s) && isLower(s[i+1])
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// camelHump reports whether query can be split into parts that each match,
+// ignoring case, a prefix of consecutive words in name, starting with the
+// first word.
+func camelHump(name []byte, query string) bool {
+	if len(name) == 0 || !isWordStart(name, 0) {
+		return false
+	}
+	return camelHumpFrom(name, 0, query)
+}
+
+func camelHumpFrom(name []byte, start int, query string) bool {
+	if query == "" {
+		return true
+	}
+	// Consume as many characters of the word at start as possible, then
+	// backtrack to try continuing with fewer.
+	n := 0
+	for n < len(query) && start+n < len(name) && lowerASCII(name[start+n]) == lowerASCII(query[n]) &&
+		(n == 0 || !isWordStart(name, start+n)) {
+		n++
+	}
+	for ; n > 0; n-- {
+		if n == len(query) {
+			return true
+		}
+		for next := start + n; next < len(name); next++ {
+			if isWordStart(name, next) {
+				if camelHumpFrom(name, next, query[n:]) {
+					return true
+				}
+				break
+			}
+		}
+	}
+	return false
+}
+
+// fuzzy reports whether the characters of query appear in name in order,
+// ignoring case, along with the number of characters skipped between the
+// first and last matched characters.
+func fuzzy(name []byte, query string) (int, bool) {
+	first, gaps, qi := -1, 0, 0
+	for i := 0; i < len(name) && qi < len(query); i++ {
+		if lowerASCII(name[i]) == lowerASCII(query[qi]) {
+			if first < 0 {
+				first = i
+			}
+			qi++
+		} else if first >= 0 {
+			gaps++
+		}
+	}
+	return gaps, qi == len(query)
+}
+
+func isUpper(c byte) bool {
+	return 'A' <= c && c <= 'Z'
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,89 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestScore(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name, query string
+		want        int // zero means no match
+	}{
+		{"FileDescriptorSet", "FileDescriptorSet", ScoreExact},
+		{"FileDescriptorSet", "filedescriptorset", ScoreExactFold},
+		{"FileDescriptorSet", "filedesc", ScorePrefix - 9},
+		{"FileDescriptorSet", "FDS", ScoreCamelHump - 14},
+		{"FileDescriptorSet", "FiDeSet", ScoreCamelHump - 10},
+		{"FileDescriptorSet", "fds", ScoreCamelHump - 14},
+		{"FileDescriptorSet", "DS", ScoreFuzzy - 16},
+		{"FileDescriptorSet", "script", ScoreSubstring - 17},
+		{"FileDescriptorSet", "fdst", ScoreFuzzy - 21},
+		{"FileDescriptorSet", "FDX", 0},
+		{"HTTPRequest", "HR", ScoreCamelHump - 9},
+		{"HTTPRequest", "HTR", ScoreCamelHump - 8},
+		{"TYPE_STRING", "TS", ScoreCamelHump - 9},
+		{"TYPE_STRING", "ts", ScoreCamelHump - 9},
+		{"foo.bar.Baz", "baz", ScoreExactFold},
+		{"foo.bar.Baz", "bar", 0},
+		{"foo.bar.Baz", "bar.Baz", ScoreSubstring - 8},
+		{"foo.bar.Baz", "", 0},
+	}
+	for _, tc := range testCases {
+		got, ok := Score(tc.name, tc.query)
+		assert.Equal(t, tc.want != 0, ok, "%s ~ %q", tc.name, tc.query)
+		assert.Equal(t, tc.want, got, "%s ~ %q", tc.name, tc.query)
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder(nil)
+	b.files["a.proto"] = []Symbol{
+		{Name: "google.protobuf.FileDescriptorSet", Kind: KindMessage, File: "a.proto"},
+		{Name: "google.protobuf.FileDescriptorProto", Kind: KindMessage, File: "a.proto"},
+		{Name: "google.protobuf.FieldDescriptorProto", Kind: KindMessage, File: "a.proto"},
+		{Name: "foo.Fds", Kind: KindMessage, File: "a.proto"},
+		{Name: "foo.Unrelated", Kind: KindMessage, File: "a.proto"},
+	}
+	ix, err := Open(b.Bytes())
+	assert.NoError(t, err)
+
+	var got []protoreflect.FullName
+	for _, m := range ix.Find("FDS", 0) {
+		got = append(got, m.Name)
+	}
+	assert.Equal(t, []protoreflect.FullName{
+		"foo.Fds",                           // exact, ignoring case
+		"google.protobuf.FileDescriptorSet", // camel-hump
+		// fuzzy, shorter names first
+		"google.protobuf.FileDescriptorProto",
+		"google.protobuf.FieldDescriptorProto",
+	}, got)
+
+	got = nil
+	for _, m := range ix.Find("fdp", 2) {
+		got = append(got, m.Name)
+	}
+	assert.Equal(t, []protoreflect.FullName{
+		"google.protobuf.FileDescriptorProto",
+		"google.protobuf.FieldDescriptorProto",
+	}, got)
+}
@@ -0,0 +1,306 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package symindex implements a compact, versioned on-disk index of the
+// symbols defined in a set of files. It is intended for workspace-wide symbol
+// search: an index can be memory-mapped (see OpenFile) and queried in place,
+// without deserializing it, so that search is available immediately on cold
+// start, before anything has been compiled. Find searches an index using
+// ranked fuzzy and camel-hump matching, as described by Score.
+//
+// An index is created with a Builder, from linker results. When files change,
+// a new index can be built incrementally by seeding a Builder with the
+// previous index and replacing only the entries for the files that changed.
+//
+// # Format
+//
+// All integers are little-endian. An index consists of a fixed-size header,
+// followed by a table of files, a table of symbols, and a string table:
+//
+//	header:  magic "PCSI" | version u32 | file count u32 | symbol count u32 | string table size u32 | reserved u32
+//	files:   (path offset u32 | path length u32) ...                       sorted by path
+//	symbols: (name offset u32 | name length u32 | file u32 | line u32 | col u32 | kind u8 | padding [3]u8) ...
+//	                                                                        sorted by name, then file
+//	strings: the bytes of all paths and names
+//
+// Offsets are relative to the start of the string table. Line and column
+// numbers are one-based; zero means the position is unknown. Because the
+// symbol table is sorted by fully-qualified name, exact and prefix lookups
+// use binary search.
+package symindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Version is the version of the index format written by this package. Indexes
+// with a different version cannot be opened and must be rebuilt.
+const Version = 2
+
+const (
+	magic      = "PCSI"
+	headerSize = 24
+	fileSize   = 8
+	symbolSize = 24
+)
+
+// ErrInvalidIndex is returned by Open when the given data is not a valid
+// index.
+var ErrInvalidIndex = errors.New("invalid symbol index")
+
+// ErrVersionMismatch is returned by Open when the given data is an index
+// that was written using a different version of the format.
+var ErrVersionMismatch = errors.New("symbol index version mismatch")
+
+// Kind is the kind of element that a symbol refers to.
+type Kind uint8
+
+const (
+	KindMessage Kind = iota + 1
+	KindField
+	KindOneof
+	KindEnum
+	KindEnumValue
+	KindExtension
+	KindService
+	KindMethod
+	// KindExtensionDeclaration is an extension that is declared in the
+	// options of an extension range, but which may not be defined in any
+	// indexed file. The symbol's position is that of the extension range.
+	KindExtensionDeclaration
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindMessage:
+		return "message"
+	case KindField:
+		return "field"
+	case KindOneof:
+		return "oneof"
+	case KindEnum:
+		return "enum"
+	case KindEnumValue:
+		return "enum value"
+	case KindExtension:
+		return "extension"
+	case KindService:
+		return "service"
+	case KindMethod:
+		return "method"
+	case KindExtensionDeclaration:
+		return "extension declaration"
+	default:
+		return fmt.Sprintf("Kind(%d)", uint8(k))
+	}
+}
+
+// Symbol is an entry in an index.
+type Symbol struct {
+	Name protoreflect.FullName
+	Kind Kind
+	// The path of the file that defines the symbol.
+	File string
+	// The one-based position of the symbol's name in the file, or zero if
+	// the position is not known.
+	Line, Col int
+}
+
+// Index is a read-only symbol index. Its methods read directly from the
+// underlying data, so it is cheap to open even when very large. An Index is
+// safe for concurrent use.
+type Index struct {
+	data     []byte
+	files    []byte
+	symbols  []byte
+	strings  []byte
+	nfiles   int
+	nsymbols int
+	close    func() error
+}
+
+// Open returns an index backed by the given data, which is typically the
+// contents of a memory-mapped file. The data is not copied, so it must not be
+// modified while the index is in use. The structure of the index is validated,
+// but its contents are not otherwise decoded.
+func Open(data []byte) (*Index, error) {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return nil, ErrInvalidIndex
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != Version {
+		return nil, fmt.Errorf("%w: index has version %d, expecting %d", ErrVersionMismatch, v, Version)
+	}
+	nfiles := uint64(binary.LittleEndian.Uint32(data[8:]))
+	nsymbols := uint64(binary.LittleEndian.Uint32(data[12:]))
+	nstrings := uint64(binary.LittleEndian.Uint32(data[16:]))
+	if uint64(len(data)) != headerSize+nfiles*fileSize+nsymbols*symbolSize+nstrings {
+		return nil, ErrInvalidIndex
+	}
+	ix := &Index{data: data, nfiles: int(nfiles), nsymbols: int(nsymbols)}
+	rest := data[headerSize:]
+	ix.files, rest = rest[:nfiles*fileSize], rest[nfiles*fileSize:]
+	ix.symbols, ix.strings = rest[:nsymbols*symbolSize], rest[nsymbols*symbolSize:]
+
+	for i := 0; i < ix.nfiles; i++ {
+		rec := ix.files[i*fileSize:]
+		if !ix.validString(rec[0:], rec[4:]) {
+			return nil, ErrInvalidIndex
+		}
+	}
+	for i := 0; i < ix.nsymbols; i++ {
+		rec := ix.symbols[i*symbolSize:]
+		if !ix.validString(rec[0:], rec[4:]) || binary.LittleEndian.Uint32(rec[8:]) >= uint32(ix.nfiles) {
+			return nil, ErrInvalidIndex
+		}
+	}
+	return ix, nil
+}
+
+func (ix *Index) validString(off, length []byte) bool {
+	o := uint64(binary.LittleEndian.Uint32(off))
+	n := uint64(binary.LittleEndian.Uint32(length))
+	return o+n <= uint64(len(ix.strings))
+}
+
+func (ix *Index) stringAt(rec []byte) []byte {
+	off := binary.LittleEndian.Uint32(rec)
+	n := binary.LittleEndian.Uint32(rec[4:])
+	return ix.strings[off : off+n]
+}
+
+// Close releases the resources associated with the index. If the index was
+// opened with OpenFile, the file is unmapped, after which the index and any
+// data previously returned from it must no longer be used. Calling Close on
+// an index returned by Open is a no-op.
+func (ix *Index) Close() error {
+	if ix.close == nil {
+		return nil
+	}
+	closeFn := ix.close
+	ix.close = nil
+	return closeFn()
+}
+
+// Len returns the number of symbols in the index.
+func (ix *Index) Len() int {
+	return ix.nsymbols
+}
+
+// Files returns the paths of all files in the index, in sorted order.
+func (ix *Index) Files() []string {
+	files := make([]string, ix.nfiles)
+	for i := range files {
+		files[i] = ix.file(i)
+	}
+	return files
+}
+
+func (ix *Index) file(i int) string {
+	return string(ix.stringAt(ix.files[i*fileSize:]))
+}
+
+func (ix *Index) name(i int) []byte {
+	return ix.stringAt(ix.symbols[i*symbolSize:])
+}
+
+// Symbol returns the i-th symbol in the index. Symbols are sorted by name.
+func (ix *Index) Symbol(i int) Symbol {
+	rec := ix.symbols[i*symbolSize:]
+	return Symbol{
+		Name: protoreflect.FullName(ix.stringAt(rec)),
+		File: ix.file(int(binary.LittleEndian.Uint32(rec[8:]))),
+		Line: int(binary.LittleEndian.Uint32(rec[12:])),
+		Col:  int(binary.LittleEndian.Uint32(rec[16:])),
+		Kind: Kind(rec[20]),
+	}
+}
+
+// Lookup returns all symbols with the given fully-qualified name. There is
+// normally at most one, but an index of files that do not link together may
+// contain the same name defined in more than one file.
+func (ix *Index) Lookup(name protoreflect.FullName) []Symbol {
+	var syms []Symbol
+	start := sort.Search(ix.nsymbols, func(i int) bool {
+		return string(ix.name(i)) >= string(name)
+	})
+	for i := start; i < ix.nsymbols && string(ix.name(i)) == string(name); i++ {
+		syms = append(syms, ix.Symbol(i))
+	}
+	return syms
+}
+
+// RangePrefix calls fn for each symbol whose fully-qualified name starts with
+// the given prefix, in sorted order, until fn returns false. Passing a package
+// name followed by a dot visits every symbol in that package.
+func (ix *Index) RangePrefix(prefix string, fn func(Symbol) bool) {
+	start := sort.Search(ix.nsymbols, func(i int) bool {
+		return string(ix.name(i)) >= prefix
+	})
+	for i := start; i < ix.nsymbols && bytes.HasPrefix(ix.name(i), []byte(prefix)); i++ {
+		if !fn(ix.Symbol(i)) {
+			return
+		}
+	}
+}
+
+// Search calls fn for each symbol whose simple name (the last component of its
+// fully-qualified name) contains query, ignoring ASCII case, until fn returns
+// false. If query contains a dot, it is instead matched against the whole
+// fully-qualified name. Symbols are visited in sorted order. Only matching
+// symbols are decoded, so a search does not allocate for symbols that do
+// not match.
+func (ix *Index) Search(query string, fn func(Symbol) bool) {
+	qualified := strings.Contains(query, ".")
+	for i := 0; i < ix.nsymbols; i++ {
+		name := ix.name(i)
+		if !qualified {
+			name = name[bytes.LastIndexByte(name, '.')+1:]
+		}
+		if containsFold(name, query) && !fn(ix.Symbol(i)) {
+			return
+		}
+	}
+}
+
+// containsFold reports whether s contains substr, ignoring ASCII case. Proto
+// identifiers are always ASCII.
+func containsFold(s []byte, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		match := true
+		for j := 0; j < len(substr); j++ {
+			if lowerASCII(s[i+j]) != lowerASCII(substr[j]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,184 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symindex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/symindex"
+)
+
+func compile(t *testing.T, sources map[string]string, files ...protocompile.ResolvedPath) linker.Files {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	res, err := compiler.Compile(context.Background(), files...)
+	require.NoError(t, err)
+	return res.Files
+}
+
+func build(files linker.Files) []byte {
+	b := symindex.NewBuilder(nil)
+	b.AddFiles(files)
+	return b.Bytes()
+}
+
+func names(syms []symindex.Symbol) []protoreflect.FullName {
+	var result []protoreflect.FullName
+	for _, sym := range syms {
+		result = append(result, sym.Name)
+	}
+	return result
+}
+
+func TestBuildAndQuery(t *testing.T) {
+	t.Parallel()
+	files := compile(t, map[string]string{
+		"a.proto": `syntax = "proto3";
+package foo;
+message Request {
+  string name = 1;
+  map<string, string> labels = 2;
+  optional int32 count = 3;
+}
+enum Color { COLOR_UNSPECIFIED = 0; }
+service Greeter {
+  rpc Greet(Request) returns (Request);
+}`,
+		"b.proto": `syntax = "proto3";
+package bar;
+message Reply {
+  oneof value { string text = 1; }
+}`,
+	}, "a.proto", "b.proto")
+
+	ix, err := symindex.Open(build(files))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto", "b.proto"}, ix.Files())
+
+	var all []symindex.Symbol
+	for i := 0; i < ix.Len(); i++ {
+		all = append(all, ix.Symbol(i))
+	}
+	// map entry messages and synthetic oneofs are omitted
+	assert.Equal(t, []protoreflect.FullName{
+		"bar.Reply", "bar.Reply.text", "bar.Reply.value",
+		"foo.COLOR_UNSPECIFIED", "foo.Color",
+		"foo.Greeter", "foo.Greeter.Greet",
+		"foo.Request", "foo.Request.count", "foo.Request.labels", "foo.Request.name",
+	}, names(all))
+
+	syms := ix.Lookup("foo.Greeter.Greet")
+	require.Len(t, syms, 1)
+	assert.Equal(t, symindex.Symbol{Name: "foo.Greeter.Greet", Kind: symindex.KindMethod, File: "a.proto", Line: 10, Col: 7}, syms[0])
+	assert.Empty(t, ix.Lookup("foo.Greeter.Gree"))
+
+	var prefixed []symindex.Symbol
+	ix.RangePrefix("foo.Request.", func(sym symindex.Symbol) bool {
+		prefixed = append(prefixed, sym)
+		return true
+	})
+	assert.Equal(t, []protoreflect.FullName{"foo.Request.count", "foo.Request.labels", "foo.Request.name"}, names(prefixed))
+
+	var found []symindex.Symbol
+	ix.Search("re", func(sym symindex.Symbol) bool {
+		found = append(found, sym)
+		return true
+	})
+	assert.Equal(t, []protoreflect.FullName{"bar.Reply", "foo.Greeter", "foo.Greeter.Greet", "foo.Request"}, names(found))
+	found = nil
+	ix.Search("Request.N", func(sym symindex.Symbol) bool {
+		found = append(found, sym)
+		return true
+	})
+	assert.Equal(t, []protoreflect.FullName{"foo.Request.name"}, names(found))
+}
+
+func TestExtensionDeclarations(t *testing.T) {
+	t.Parallel()
+	files := compile(t, map[string]string{
+		"a.proto": `syntax = "proto2";
+package foo;
+message Extendable {
+  extensions 100 to 200 [
+    declaration = { number: 100, full_name: ".bar.ext", type: "string" },
+    declaration = { number: 101, reserved: true }
+  ];
+}`,
+	}, "a.proto")
+
+	ix, err := symindex.Open(build(files))
+	require.NoError(t, err)
+	syms := ix.Lookup("bar.ext")
+	require.Len(t, syms, 1)
+	assert.Equal(t, symindex.Symbol{Name: "bar.ext", Kind: symindex.KindExtensionDeclaration, File: "a.proto", Line: 4, Col: 14}, syms[0])
+	assert.Equal(t, "extension declaration", syms[0].Kind.String())
+	assert.Equal(t, 2, ix.Len())
+}
+
+func TestIncrementalMerge(t *testing.T) {
+	t.Parallel()
+	sources := map[string]string{
+		"a.proto": `syntax = "proto3"; message A {}`,
+		"b.proto": `syntax = "proto3"; message B {}`,
+		"c.proto": `syntax = "proto3";`,
+	}
+	files := compile(t, sources, "a.proto", "b.proto", "c.proto")
+	path := filepath.Join(t.TempDir(), "symbols.idx")
+	require.NoError(t, os.WriteFile(path, build(files), 0o666))
+
+	ix, err := symindex.OpenFile(path)
+	require.NoError(t, err)
+	sources["a.proto"] = `syntax = "proto3"; message A2 {}`
+	b := symindex.NewBuilder(ix)
+	b.AddFiles(compile(t, sources, "a.proto"))
+	b.RemoveFile("b.proto")
+	require.NoError(t, ix.Close())
+
+	merged, err := symindex.Open(b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto", "c.proto"}, merged.Files())
+	require.Equal(t, 1, merged.Len())
+	assert.Equal(t, symindex.Symbol{Name: "A2", Kind: symindex.KindMessage, File: "a.proto", Line: 1, Col: 28}, merged.Symbol(0))
+}
+
+func TestOpenInvalid(t *testing.T) {
+	t.Parallel()
+	data := symindex.NewBuilder(nil).Bytes()
+	_, err := symindex.Open(data)
+	require.NoError(t, err)
+
+	_, err = symindex.Open(data[:len(data)-1])
+	assert.ErrorIs(t, err, symindex.ErrInvalidIndex)
+	_, err = symindex.Open([]byte("not an index at all, really"))
+	assert.ErrorIs(t, err, symindex.ErrInvalidIndex)
+
+	data[4]++
+	_, err = symindex.Open(data)
+	assert.ErrorIs(t, err, symindex.ErrVersionMismatch)
+}
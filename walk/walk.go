@@ -39,7 +39,7 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 
-	"github.com/kralicky/protocompile/protointernal"
+	"github.com/kralicky/protocompile/descpath"
 )
 
 // Descriptors walks all descriptors in the given file using a depth-first
@@ -267,7 +267,7 @@ func (w *protoWalker) walkDescriptorProtos(file *descriptorpb.FileDescriptorProt
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.FileMessagesTag, int32(i))
+			p = append(p, descpath.FileMessagesTag, int32(i))
 		}
 		if err := w.walkDescriptorProto(prefix, p, msg); err != nil {
 			return err
@@ -277,7 +277,7 @@ func (w *protoWalker) walkDescriptorProtos(file *descriptorpb.FileDescriptorProt
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.FileEnumsTag, int32(i))
+			p = append(p, descpath.FileEnumsTag, int32(i))
 		}
 		if err := w.walkEnumDescriptorProto(prefix, p, en); err != nil {
 			return err
@@ -287,7 +287,7 @@ func (w *protoWalker) walkDescriptorProtos(file *descriptorpb.FileDescriptorProt
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.FileExtensionsTag, int32(i))
+			p = append(p, descpath.FileExtensionsTag, int32(i))
 		}
 		fqn := prefix + ext.GetName()
 		if err := w.enter(protoreflect.FullName(fqn), p, ext); err != nil {
@@ -303,7 +303,7 @@ func (w *protoWalker) walkDescriptorProtos(file *descriptorpb.FileDescriptorProt
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.FileServicesTag, int32(i))
+			p = append(p, descpath.FileServicesTag, int32(i))
 		}
 		fqn := prefix + svc.GetName()
 		if err := w.enter(protoreflect.FullName(fqn), p, svc); err != nil {
@@ -313,7 +313,7 @@ func (w *protoWalker) walkDescriptorProtos(file *descriptorpb.FileDescriptorProt
 			var mp protoreflect.SourcePath
 			if w.usePath {
 				mp = p
-				mp = append(mp, protointernal.ServiceMethodsTag, int32(j))
+				mp = append(mp, descpath.ServiceMethodsTag, int32(j))
 			}
 			mtdFqn := fqn + "." + mtd.GetName()
 			if err := w.enter(protoreflect.FullName(mtdFqn), mp, mtd); err != nil {
@@ -344,7 +344,7 @@ func (w *protoWalker) walkDescriptorProto(prefix string, path protoreflect.Sourc
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.MessageFieldsTag, int32(i))
+			p = append(p, descpath.MessageFieldsTag, int32(i))
 		}
 		fqn := prefix + fld.GetName()
 		if err := w.enter(protoreflect.FullName(fqn), p, fld); err != nil {
@@ -360,7 +360,7 @@ func (w *protoWalker) walkDescriptorProto(prefix string, path protoreflect.Sourc
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.MessageOneofsTag, int32(i))
+			p = append(p, descpath.MessageOneofsTag, int32(i))
 		}
 		fqn := prefix + oo.GetName()
 		if err := w.enter(protoreflect.FullName(fqn), p, oo); err != nil {
@@ -376,7 +376,7 @@ func (w *protoWalker) walkDescriptorProto(prefix string, path protoreflect.Sourc
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.MessageNestedMessagesTag, int32(i))
+			p = append(p, descpath.MessageNestedMessagesTag, int32(i))
 		}
 		if err := w.walkDescriptorProto(prefix, p, nested); err != nil {
 			return err
@@ -386,7 +386,7 @@ func (w *protoWalker) walkDescriptorProto(prefix string, path protoreflect.Sourc
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.MessageEnumsTag, int32(i))
+			p = append(p, descpath.MessageEnumsTag, int32(i))
 		}
 		if err := w.walkEnumDescriptorProto(prefix, p, en); err != nil {
 			return err
@@ -396,7 +396,7 @@ func (w *protoWalker) walkDescriptorProto(prefix string, path protoreflect.Sourc
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.MessageExtensionsTag, int32(i))
+			p = append(p, descpath.MessageExtensionsTag, int32(i))
 		}
 		fqn := prefix + ext.GetName()
 		if err := w.enter(protoreflect.FullName(fqn), p, ext); err != nil {
@@ -425,7 +425,7 @@ func (w *protoWalker) walkEnumDescriptorProto(prefix string, path protoreflect.S
 		var p protoreflect.SourcePath
 		if w.usePath {
 			p = path
-			p = append(p, protointernal.EnumValuesTag, int32(i))
+			p = append(p, descpath.EnumValuesTag, int32(i))
 		}
 		fqn := prefix + val.GetName()
 		if err := w.enter(protoreflect.FullName(fqn), p, val); err != nil {
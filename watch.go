@@ -0,0 +1,116 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ChangeKind is the kind of change described by a ChangeEvent.
+type ChangeKind int
+
+const (
+	// ChangeCreated indicates that a file was created.
+	ChangeCreated = ChangeKind(0)
+	// ChangeModified indicates that a file's contents changed.
+	ChangeModified = ChangeKind(1)
+	// ChangeDeleted indicates that a file was deleted, or renamed so that it
+	// is no longer available at its path.
+	ChangeDeleted = ChangeKind(2)
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeCreated:
+		return "created"
+	case ChangeModified:
+		return "modified"
+	case ChangeDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeEvent describes a change to a file provided by a Resolver.
+type ChangeEvent struct {
+	Path ResolvedPath
+	Kind ChangeKind
+}
+
+// WatchingResolver is an optional interface that a Resolver can implement to
+// report changes to the files it provides, so that compiled results can be
+// invalidated as soon as they become stale. See Workspace.Watch.
+type WatchingResolver interface {
+	Resolver
+	// Watch reports changes to files by calling the given function, until
+	// the given context is done or an error occurs. It blocks until then,
+	// and returns nil if the context is done. The function is never called
+	// concurrently. Events need not be debounced or coalesced: a file that is
+	// written several times may be reported once for each write.
+	Watch(ctx context.Context, fn func(ChangeEvent)) error
+}
+
+// watchingResolver adds the Watch method of another resolver to a resolver
+// that wraps it.
+type watchingResolver struct {
+	Resolver
+	watcher WatchingResolver
+}
+
+func (r watchingResolver) Watch(ctx context.Context, fn func(ChangeEvent)) error {
+	return r.watcher.Watch(ctx, fn)
+}
+
+// DefaultWatchDebounce is the default delay used by Workspace.Watch.
+const DefaultWatchDebounce = 100 * time.Millisecond
+
+// WatchOptions configure Workspace.Watch.
+type WatchOptions struct {
+	// How long to wait after a change for more changes before recompiling.
+	// Every change restarts the delay, so that a burst of changes, such as a
+	// branch checkout, results in a single recompilation. If zero,
+	// DefaultWatchDebounce is used. If negative, changes are applied as soon
+	// as they are reported.
+	Debounce time.Duration
+	// If not nil, called after each batch of changes is applied, with the
+	// changes, coalesced so that there is at most one per file, and the error
+	// from recompiling, if any.
+	OnUpdate func(changes []ChangeEvent, err error)
+}
+
+// ErrResolverCannotWatch is returned from Workspace.Watch if the compiler's
+// resolver does not implement WatchingResolver.
+var ErrResolverCannotWatch = errors.New("resolver does not implement WatchingResolver")
+
+// coalesceChange merges a new change to a file into a pending change to the
+// same file, if any.
+func coalesceChange(pending, next ChangeKind, hasPending bool) ChangeKind {
+	if !hasPending {
+		return next
+	}
+	switch {
+	case pending == ChangeCreated && next == ChangeModified:
+		// still a new file
+		return ChangeCreated
+	case pending == ChangeDeleted && next == ChangeCreated:
+		// replaced, for example by an editor's atomic save
+		return ChangeModified
+	default:
+		return next
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+)
+
+// Workspace maintains the compiled state of a set of files that changes over
+// time, such as the files open in an editor. It owns a compiler that retains
+// results, a set of in-memory overlays that take precedence over the files
+// provided by the compiler's resolver, and the latest results for every
+// compiled file.
+//
+// All methods are safe for concurrent use. Queries see a consistent view of
+// the results of the most recent update; updates are serialized.
+type Workspace struct {
+	// held for writing while compiling, and for reading by queries
+	mu       sync.RWMutex
+	compiler *Compiler
+	tracked  map[ResolvedPath]struct{}
+	files    map[ResolvedPath]linker.File
+	// files invalidated by the current compilation
+	invalidated map[ResolvedPath]struct{}
+	// tracked files that could not be fully linked by the last compilation
+	failed map[ResolvedPath]struct{}
+	// the compiler's original resolver, without overlays
+	source Resolver
+
+	// overlays are guarded separately since they are consulted by the
+	// resolver while mu is held for writing
+	overlayMu sync.RWMutex
+	overlays  map[ResolvedPath]string
+}
+
+// NewWorkspace returns a new workspace that compiles files using a copy of
+// the given compiler's configuration. The copy always retains results and
+// ASTs, since they are needed to incrementally recompile files and to answer
+// queries. It never pools descriptors, since results returned by the
+// workspace may be used by callers after later updates invalidate them. If
+// the given compiler has no resolver, only overlays and the standard imports
+// are available.
+func NewWorkspace(c *Compiler) *Workspace {
+	w := &Workspace{
+		tracked:  map[ResolvedPath]struct{}{},
+		files:    map[ResolvedPath]linker.File{},
+		failed:   map[ResolvedPath]struct{}{},
+		overlays: map[ResolvedPath]string{},
+		source:   c.Resolver,
+	}
+	var resolver Resolver = ResolverFunc(w.findOverlay)
+	if c.Resolver != nil {
+		resolver = CompositeResolver{resolver, c.Resolver}
+	} else {
+		resolver = WithStandardImports(resolver)
+	}
+	cc := *c
+	cc.Resolver = resolver
+	cc.RetainResults = true
+	cc.RetainASTs = true
+	cc.PoolDescriptors = false
+	cc.exec = nil
+	preInvalidate := c.Hooks.PreInvalidate
+	cc.Hooks.PreInvalidate = func(path ResolvedPath, reason string) {
+		// called synchronously from Compile, while w.mu is held
+		w.invalidated[path] = struct{}{}
+		if preInvalidate != nil {
+			preInvalidate(path, reason)
+		}
+	}
+	w.compiler = &cc
+	return w
+}
+
+func (w *Workspace) findOverlay(path UnresolvedPath, _ ImportContext) (SearchResult, error) {
+	w.overlayMu.RLock()
+	defer w.overlayMu.RUnlock()
+	contents, ok := w.overlays[ResolvedPath(path)]
+	if !ok {
+		return SearchResult{}, os.ErrNotExist
+	}
+	return SearchResult{
+		ResolvedPath: ResolvedPath(path),
+		Source:       strings.NewReader(contents),
+	}, nil
+}
+
+// Open adds the given files to the set of files tracked by the workspace and
+// compiles them, along with their dependencies. Files that are already
+// tracked are recompiled. The returned error is the error from compiling the
+// files, if any; results, including partial results, are retained either way.
+func (w *Workspace) Open(ctx context.Context, paths ...ResolvedPath) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, path := range paths {
+		w.tracked[path] = struct{}{}
+	}
+	return w.compileLocked(ctx, paths...)
+}
+
+// UpdateFile sets the contents of the given file to an in-memory overlay and
+// recompiles the file and all files that depend on it. The file is tracked
+// by the workspace, if it was not already.
+func (w *Workspace) UpdateFile(ctx context.Context, path ResolvedPath, contents string) error {
+	w.overlayMu.Lock()
+	w.overlays[path] = contents
+	w.overlayMu.Unlock()
+	return w.Open(ctx, path)
+}
+
+// RemoveOverlay discards the in-memory overlay for the given file, if any,
+// so that its contents are once again provided by the compiler's resolver,
+// and recompiles the file and all files that depend on it.
+func (w *Workspace) RemoveOverlay(ctx context.Context, path ResolvedPath) error {
+	w.overlayMu.Lock()
+	_, ok := w.overlays[path]
+	delete(w.overlays, path)
+	w.overlayMu.Unlock()
+	if !ok {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.compileLocked(ctx, path)
+}
+
+// compileLocked compiles the given files, which also recompiles any files
+// that depend on them, and records the results. w.mu must be held for
+// writing.
+func (w *Workspace) compileLocked(ctx context.Context, paths ...ResolvedPath) error {
+	// files that are recompiled are invalidated first, so drop their old
+	// results; any that still exist are replaced with new results below
+	w.invalidated = map[ResolvedPath]struct{}{}
+	res, err := w.compiler.Compile(ctx, paths...)
+	for path := range w.invalidated {
+		delete(w.files, path)
+	}
+	w.invalidated = nil
+	for _, path := range paths {
+		if _, ok := w.tracked[path]; ok {
+			w.failed[path] = struct{}{}
+		}
+	}
+	for _, f := range linker.ComputeReflexiveTransitiveClosure(res.Files) {
+		w.files[ResolvedPath(f.Path())] = f
+		delete(w.failed, ResolvedPath(f.Path()))
+	}
+	for path, f := range res.PartialLinkResults {
+		w.files[path] = f
+		if _, ok := w.tracked[path]; ok {
+			w.failed[path] = struct{}{}
+		}
+	}
+	for path := range res.UnlinkedParserResults {
+		if _, ok := w.tracked[path]; ok {
+			w.failed[path] = struct{}{}
+		}
+	}
+	return err
+}
+
+// ApplyChanges recompiles files affected by the given changes to files
+// provided by the compiler's resolver. Files that are modified or deleted
+// are recompiled, along with all files that depend on them, if they were
+// previously compiled. When a file is created, it is compiled if it is
+// tracked, and every tracked file that could not be fully linked is also
+// recompiled, since it may have imported the new file. Changes to files that
+// have in-memory overlays are ignored, since the overlays take precedence.
+//
+// This is typically called by Watch, but can also be called directly by
+// callers that observe changes some other way.
+func (w *Workspace) ApplyChanges(ctx context.Context, changes []ChangeEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.overlayMu.RLock()
+	overlays := w.overlays
+	seen := map[ResolvedPath]struct{}{}
+	var paths []ResolvedPath
+	add := func(path ResolvedPath) {
+		if _, ok := seen[path]; ok {
+			return
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+	var created bool
+	for _, change := range changes {
+		if _, ok := overlays[change.Path]; ok {
+			continue
+		}
+		_, tracked := w.tracked[change.Path]
+		_, compiled := w.files[change.Path]
+		if tracked || compiled {
+			add(change.Path)
+		}
+		if change.Kind == ChangeCreated {
+			created = true
+		}
+	}
+	w.overlayMu.RUnlock()
+	if created {
+		failed := make([]ResolvedPath, 0, len(w.failed))
+		for path := range w.failed {
+			failed = append(failed, path)
+		}
+		slices.Sort(failed)
+		for _, path := range failed {
+			add(path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return w.compileLocked(ctx, paths...)
+}
+
+// Watch recompiles files as they change, until the given context is done. It
+// requires that the resolver of the compiler given to NewWorkspace implement
+// WatchingResolver, and otherwise returns ErrResolverCannotWatch. Changes are
+// debounced and coalesced, as configured by opts, and then applied as if by
+// ApplyChanges. Watch blocks until the context is done, and then returns nil,
+// or until the resolver's Watch method returns an error, which is returned.
+func (w *Workspace) Watch(ctx context.Context, opts WatchOptions) error {
+	watcher, ok := w.source.(WatchingResolver)
+	if !ok {
+		return ErrResolverCannotWatch
+	}
+	debounce := opts.Debounce
+	if debounce == 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan ChangeEvent)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- watcher.Watch(watchCtx, func(event ChangeEvent) {
+			select {
+			case events <- event:
+			case <-watchCtx.Done():
+			}
+		})
+	}()
+
+	pending := map[ResolvedPath]ChangeKind{}
+	var order []ResolvedPath
+	var timer <-chan time.Time
+	flush := func() {
+		changes := make([]ChangeEvent, len(order))
+		for i, path := range order {
+			changes[i] = ChangeEvent{Path: path, Kind: pending[path]}
+		}
+		clear(pending)
+		order = order[:0]
+		err := w.ApplyChanges(ctx, changes)
+		if opts.OnUpdate != nil {
+			opts.OnUpdate(changes, err)
+		}
+	}
+	for {
+		select {
+		case event := <-events:
+			kind, ok := pending[event.Path]
+			if !ok {
+				order = append(order, event.Path)
+			}
+			pending[event.Path] = coalesceChange(kind, event.Kind, ok)
+			if debounce < 0 {
+				flush()
+				continue
+			}
+			timer = time.After(debounce)
+		case <-timer:
+			timer = nil
+			flush()
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			cancel()
+			return <-errs
+		}
+	}
+}
+
+// FileFor returns the latest result for the given file, or nil if the file
+// has not been compiled or could not be linked. The result may be a partial
+// result if the file had errors. Dependencies of tracked files are also
+// available.
+func (w *Workspace) FileFor(path ResolvedPath) linker.File {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.files[path]
+}
+
+// Files returns the latest results for all tracked files.
+func (w *Workspace) Files() linker.Files {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	files := make(linker.Files, 0, len(w.tracked))
+	for path := range w.tracked {
+		if f := w.files[path]; f != nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// MemoryUsage returns an estimate of the memory retained by the latest result
+// for each compiled file, including dependencies of tracked files. See
+// linker.Result.MemoryUsage.
+func (w *Workspace) MemoryUsage() map[ResolvedPath]linker.MemoryUsage {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	usage := make(map[ResolvedPath]linker.MemoryUsage, len(w.files))
+	for path, f := range w.files {
+		if res, ok := f.(linker.Result); ok {
+			usage[path] = res.MemoryUsage()
+		}
+	}
+	return usage
+}
+
+// Lookup returns the descriptor with the given fully-qualified name, which
+// may be defined in any compiled file, including dependencies of tracked
+// files. It returns nil if no such descriptor exists.
+func (w *Workspace) Lookup(name protoreflect.FullName) protoreflect.Descriptor {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, f := range w.files {
+		if d := f.FindDescriptorByName(name); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+// ResolveAt returns the descriptor that is referenced or declared at the given
+// position in the given file. For a reference, such as a field's type, this is
+// the descriptor it resolved to; for a declaration, the position must be in the
+// declared element's name. It returns nil if there is no such descriptor or the
+// file has no AST.
+func (w *Workspace) ResolveAt(path ResolvedPath, pos ast.SourcePos) protoreflect.Descriptor {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	res, ok := w.files[path].(linker.Result)
+	if !ok || res.AST() == nil {
+		return nil
+	}
+	return resolveAt(res, pos)
+}
+
+// resolveAt implements ResolveAt for a result that has an AST.
+func resolveAt(res linker.Result, pos ast.SourcePos) protoreflect.Descriptor {
+	var found protoreflect.Descriptor
+	res.RangeReferences(func(ref ast.NodeReference, to protoreflect.Descriptor) bool {
+		if spanContains(ref.NodeInfo, pos) {
+			found = to
+			return false
+		}
+		return true
+	})
+	if found != nil {
+		return found
+	}
+	_ = res.RangeDescriptors(context.Background(), func(d protoreflect.Descriptor) bool {
+		if md, ok := d.(protoreflect.MessageDescriptor); ok && md.IsMapEntry() {
+			// shares its declaration with the map field
+			return true
+		}
+		node := res.Node(protoutil.ProtoFromDescriptor(d))
+		named, ok := node.(interface{ GetName() *ast.IdentNode })
+		if !ok || named.GetName() == nil {
+			return true
+		}
+		if spanContains(res.AST().NodeInfo(named.GetName()), pos) {
+			found = d
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// spanContains reports whether pos is within the given span, whose end is
+// exclusive.
+func spanContains(span ast.SourceSpan, pos ast.SourcePos) bool {
+	start, end := span.Start(), span.End()
+	if pos.Line < start.Line || (pos.Line == start.Line && pos.Col < start.Col) {
+		return false
+	}
+	return pos.Line < end.Line || (pos.Line == end.Line && pos.Col < end.Col)
+}
@@ -0,0 +1,233 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocompile
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/kralicky/protocompile/ast"
+)
+
+func TestWorkspace(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{
+		Resolver:        WithStandardImports(mkResolver(baseContents)),
+		PoolDescriptors: true,
+	})
+	ctx := context.Background()
+	require.NoError(t, ws.Open(ctx, "c/c.proto"))
+	// results are handed out to callers, so their memory is never reused
+	assert.False(t, ws.compiler.PoolDescriptors)
+
+	assert.NotNil(t, ws.FileFor("c/c.proto"))
+	assert.NotNil(t, ws.FileFor("a/b/b2.proto"))
+	assert.Len(t, ws.Files(), 1)
+	beeTwo := ws.Lookup("a.b.BeeTwo")
+	require.NotNil(t, beeTwo)
+	assert.Equal(t, "a/b/b2.proto", beeTwo.ParentFile().Path())
+
+	// reference to a.b.BeeTwo in "a.b.BeeTwo bee_two = 2;" (line 12)
+	d := ws.ResolveAt("c/c.proto", ast.SourcePos{Line: 12, Col: 5})
+	require.NotNil(t, d)
+	assert.Equal(t, protoreflect.FullName("a.b.BeeTwo"), d.FullName())
+	// declaration of message See (line 10)
+	d = ws.ResolveAt("c/c.proto", ast.SourcePos{Line: 10, Col: 10})
+	require.NotNil(t, d)
+	assert.Equal(t, protoreflect.FullName("c.See"), d.FullName())
+	assert.Nil(t, ws.ResolveAt("c/c.proto", ast.SourcePos{Line: 1, Col: 1}))
+
+	// updating a dependency recompiles its dependents
+	require.NoError(t, ws.UpdateFile(ctx, "a/b/b2.proto", `
+syntax = "proto3";
+package a.b;
+message BeeTwo { string name = 1; }
+`))
+	assert.NotNil(t, ws.Lookup("a.b.BeeTwo.name"))
+	assert.NotSame(t, beeTwo, ws.Lookup("a.b.BeeTwo"))
+	// descriptors from before the update are still intact
+	assert.Equal(t, protoreflect.FullName("a.b.BeeTwo"), beeTwo.FullName())
+	assert.Equal(t, "a/b/b2.proto", beeTwo.ParentFile().Path())
+	field := ws.FileFor("c/c.proto").Messages().ByName("See").Fields().ByName("bee_two")
+	assert.NotNil(t, field.Message().Fields().ByName("name"))
+
+	// errors are reported, and dependents are recompiled again once fixed
+	require.Error(t, ws.UpdateFile(ctx, "a/b/b2.proto", `syntax = "proto3"; package a.b;`))
+	assert.Nil(t, ws.Lookup("a.b.BeeTwo"))
+	require.NoError(t, ws.RemoveOverlay(ctx, "a/b/b2.proto"))
+	assert.NotNil(t, ws.Lookup("a.b.BeeTwo"))
+	assert.Nil(t, ws.Lookup("a.b.BeeTwo.name"))
+
+	usage := ws.MemoryUsage()
+	assert.Contains(t, usage, ResolvedPath("a/b/b2.proto"))
+	assert.Positive(t, usage["c/c.proto"].AST)
+	assert.Positive(t, usage["c/c.proto"].Descriptors)
+
+	// queries may run concurrently with updates
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_ = ws.Lookup("c.See")
+				_ = ws.ResolveAt("c/c.proto", ast.SourcePos{Line: 12, Col: 5})
+				_ = ws.HighlightsAt("c/c.proto", ast.SourcePos{Line: 12, Col: 5})
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		_ = ws.UpdateFile(ctx, "a/b/b1.proto", baseContents["a/b/b1.proto"])
+	}
+	wg.Wait()
+}
+
+type fakeWatchingResolver struct {
+	mu       sync.Mutex
+	contents map[UnresolvedPath]string
+	events   chan ChangeEvent
+}
+
+func (r *fakeWatchingResolver) FindFileByPath(path UnresolvedPath, _ ImportContext) (SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.contents[path]
+	if !ok {
+		return SearchResult{}, os.ErrNotExist
+	}
+	return SearchResult{ResolvedPath: ResolvedPath(path), Source: strings.NewReader(s)}, nil
+}
+
+func (r *fakeWatchingResolver) Watch(ctx context.Context, fn func(ChangeEvent)) error {
+	for {
+		select {
+		case event := <-r.events:
+			fn(event)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (r *fakeWatchingResolver) set(path UnresolvedPath, contents string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if contents == "" {
+		delete(r.contents, path)
+	} else {
+		r.contents[path] = contents
+	}
+}
+
+func TestWorkspaceApplyChanges(t *testing.T) {
+	t.Parallel()
+	resolver := &fakeWatchingResolver{
+		contents: map[UnresolvedPath]string{
+			"a.proto": `syntax = "proto3"; import "b.proto"; message A { B b = 1; }`,
+		},
+	}
+	ws := NewWorkspace(&Compiler{Resolver: WithStandardImports(resolver)})
+	ctx := context.Background()
+	require.Error(t, ws.Open(ctx, "a.proto"))
+	assert.Nil(t, ws.FileFor("a.proto"))
+
+	// creating a missing import recompiles files that failed
+	resolver.set("b.proto", `syntax = "proto3"; message B {}`)
+	require.NoError(t, ws.ApplyChanges(ctx, []ChangeEvent{{Path: "b.proto", Kind: ChangeCreated}}))
+	require.NotNil(t, ws.FileFor("a.proto"))
+	assert.NotNil(t, ws.Lookup("B"))
+
+	// modifying a dependency recompiles it and its dependents
+	resolver.set("b.proto", `syntax = "proto3"; message B { string s = 1; }`)
+	require.NoError(t, ws.ApplyChanges(ctx, []ChangeEvent{{Path: "b.proto", Kind: ChangeModified}}))
+	assert.NotNil(t, ws.Lookup("B.s"))
+	assert.NotNil(t, ws.FileFor("a.proto").Messages().ByName("A").Fields().ByName("b").Message().Fields().ByName("s"))
+
+	// changes to unrelated files are ignored
+	require.NoError(t, ws.ApplyChanges(ctx, []ChangeEvent{{Path: "c.proto", Kind: ChangeModified}}))
+
+	// changes to files with overlays are ignored
+	require.NoError(t, ws.UpdateFile(ctx, "b.proto", `syntax = "proto3"; message B { int32 i = 1; }`))
+	resolver.set("b.proto", "")
+	require.NoError(t, ws.ApplyChanges(ctx, []ChangeEvent{{Path: "b.proto", Kind: ChangeDeleted}}))
+	assert.NotNil(t, ws.Lookup("B.i"))
+
+	// once the overlay is removed, the file is deleted, breaking dependents
+	require.Error(t, ws.RemoveOverlay(ctx, "b.proto"))
+	assert.Nil(t, ws.FileFor("b.proto"))
+	assert.Nil(t, ws.FileFor("a.proto"))
+}
+
+func TestWorkspaceWatch(t *testing.T) {
+	t.Parallel()
+	ws := NewWorkspace(&Compiler{Resolver: mkResolver(baseContents)})
+	require.ErrorIs(t, ws.Watch(context.Background(), WatchOptions{}), ErrResolverCannotWatch)
+
+	resolver := &fakeWatchingResolver{
+		contents: map[UnresolvedPath]string{
+			"a.proto": `syntax = "proto3"; import "b.proto"; message A { B b = 1; }`,
+			"b.proto": `syntax = "proto3"; message B {}`,
+		},
+		events: make(chan ChangeEvent),
+	}
+	ws = NewWorkspace(&Compiler{Resolver: WithStandardImports(resolver)})
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, ws.Open(ctx, "a.proto"))
+
+	type update struct {
+		changes []ChangeEvent
+		err     error
+	}
+	updates := make(chan update, 10)
+	done := make(chan error)
+	go func() {
+		done <- ws.Watch(ctx, WatchOptions{
+			Debounce: 50 * time.Millisecond,
+			OnUpdate: func(changes []ChangeEvent, err error) {
+				updates <- update{changes, err}
+			},
+		})
+	}()
+
+	// a burst of changes is applied at once, with one change per file
+	resolver.set("b.proto", `syntax = "proto3"; message B { string s = 1; }`)
+	resolver.events <- ChangeEvent{Path: "b.proto", Kind: ChangeDeleted}
+	resolver.events <- ChangeEvent{Path: "b.proto", Kind: ChangeCreated}
+	resolver.events <- ChangeEvent{Path: "a.proto", Kind: ChangeModified}
+	resolver.events <- ChangeEvent{Path: "b.proto", Kind: ChangeModified}
+	select {
+	case u := <-updates:
+		require.NoError(t, u.err)
+		assert.Equal(t, []ChangeEvent{
+			{Path: "b.proto", Kind: ChangeModified},
+			{Path: "a.proto", Kind: ChangeModified},
+		}, u.changes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for update")
+	}
+	assert.NotNil(t, ws.Lookup("B.s"))
+	assert.Empty(t, updates)
+
+	cancel()
+	require.NoError(t, <-done)
+}